@@ -2,8 +2,11 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log"
 	"net/http"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"dental_clinic/internal/config"
@@ -12,6 +15,9 @@ import (
 	"dental_clinic/internal/router"
 )
 
+// shutdownTimeout is how long in-flight requests get to finish on SIGTERM.
+const shutdownTimeout = 30 * time.Second
+
 // @title Dental Clinic API
 // @version 1.0
 // @description API for managing users, authentication, and clinic operations.
@@ -25,12 +31,41 @@ func main() {
 	db := database.ConnectDB(cfg.DB_DSN)
 	defer db.Close()
 
-	jobs.StartAppointmentStatusCron(context.Background(), db, time.Minute)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	jobs.StartAppointmentStatusCron(ctx, db, time.Minute)
+	jobs.StartRatingStatsCron(ctx, db, time.Hour)
+	jobs.StartLicenseExpiryCron(ctx, db, 24*time.Hour)
+	jobs.StartInventoryDigestCron(ctx, db, 24*time.Hour)
+
+	worker := jobs.NewQueueWorker(db, cfg)
+	workerDone := make(chan struct{})
+	go func() {
+		defer close(workerDone)
+		worker.Run(ctx)
+	}()
+
+	server := &http.Server{
+		Addr:    ":" + cfg.Port,
+		Handler: router.NewRouter(cfg, db),
+	}
+	go func() {
+		log.Printf("Server running on port %s", cfg.Port)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Println(err)
+			stop()
+		}
+	}()
 
-	r := router.NewRouter(cfg, db)
+	<-ctx.Done()
+	log.Println("Shutting down")
 
-	log.Printf("Server running on port %s", cfg.Port)
-	if err := http.ListenAndServe(":"+cfg.Port, r); err != nil {
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
 		log.Println(err)
 	}
+	// the worker finishes the jobs it is running before the pool is closed
+	<-workerDone
 }
@@ -18,6 +18,16 @@ type Config struct {
 	OpenAIModel  string
 	ResendAPIKey string
 	FrontendURL  string
+	ClamAVAddr   string
+	// QueueConcurrency is how many queued jobs an instance runs at a time.
+	QueueConcurrency string
+	// EmailProvider and SMSProvider pick how reminders are delivered;
+	// reminders over a channel without a provider are skipped
+	EmailProvider    string
+	SMSProvider      string
+	TwilioAccountSID string
+	TwilioAuthToken  string
+	TwilioFrom       string
 }
 
 func LoadConfig() *Config {
@@ -38,6 +48,15 @@ func LoadConfig() *Config {
 		OpenAIModel:  getEnv("OPENAI_MODEL", "gpt-4o-mini"),
 		ResendAPIKey: getEnv("ResendAPIKey", ""),
 		FrontendURL:  getEnv("FrontendURL", ""),
+		ClamAVAddr:   getEnv("CLAMAV_ADDR", ""),
+
+		QueueConcurrency: getEnv("QUEUE_CONCURRENCY", "4"),
+
+		EmailProvider:    getEnv("EMAIL_PROVIDER", "resend"),
+		SMSProvider:      getEnv("SMS_PROVIDER", ""),
+		TwilioAccountSID: getEnv("TWILIO_ACCOUNT_SID", ""),
+		TwilioAuthToken:  getEnv("TWILIO_AUTH_TOKEN", ""),
+		TwilioFrom:       getEnv("TWILIO_FROM", ""),
 	}
 
 	return cfg
@@ -0,0 +1,67 @@
+// Package geo builds great-circle distance queries over the latitude and
+// longitude stored on addresses. It uses the haversine formula in plain SQL so
+// no PostGIS extension is required; a bounding box on the indexed columns
+// narrows the rows before the exact distance is computed.
+package geo
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+const EarthRadiusKm = 6371.0
+
+var ErrInvalidPoint = errors.New("latitude must be between -90 and 90 and longitude between -180 and 180")
+
+type Point struct {
+	Lat float64
+	Lng float64
+}
+
+func (p Point) Valid() bool {
+	return p.Lat >= -90 && p.Lat <= 90 && p.Lng >= -180 && p.Lng <= 180
+}
+
+// DistanceSQL returns an expression for the distance in kilometres between
+// the columns latCol/lngCol and the point bound to the placeholders latArg
+// and lngArg.
+func DistanceSQL(latCol, lngCol, latArg, lngArg string) string {
+	return fmt.Sprintf(
+		"(2 * %v * ASIN(LEAST(1, SQRT(POWER(SIN(RADIANS(%s::float8 - %s::float8) / 2), 2) + COS(RADIANS(%s::float8)) * COS(RADIANS(%s::float8)) * POWER(SIN(RADIANS(%s::float8 - %s::float8) / 2), 2)))))",
+		EarthRadiusKm,
+		latCol, latArg,
+		latArg, latCol,
+		lngCol, lngArg,
+	)
+}
+
+// Box is a latitude/longitude rectangle that contains a circle.
+type Box struct {
+	MinLat, MaxLat float64
+	MinLng, MaxLng float64
+}
+
+// BoundingBox returns the rectangle around the circle of radiusKm centred on
+// p. Near the poles or the antimeridian it widens to the full longitude range.
+func BoundingBox(p Point, radiusKm float64) Box {
+	dLat := radiusKm / EarthRadiusKm * 180 / math.Pi
+	box := Box{
+		MinLat: math.Max(p.Lat-dLat, -90),
+		MaxLat: math.Min(p.Lat+dLat, 90),
+		MinLng: -180,
+		MaxLng: 180,
+	}
+
+	cosLat := math.Cos(p.Lat * math.Pi / 180)
+	if cosLat <= 0 || box.MinLat == -90 || box.MaxLat == 90 {
+		return box
+	}
+	dLng := dLat / cosLat
+	if p.Lng-dLng < -180 || p.Lng+dLng > 180 {
+		return box
+	}
+	box.MinLng = p.Lng - dLng
+	box.MaxLng = p.Lng + dLng
+	return box
+}
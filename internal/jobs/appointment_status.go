@@ -2,9 +2,15 @@ package jobs
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"math"
 	"time"
 
+	inventoryRepository "dental_clinic/internal/modules/inventory/repository"
+	treatmentPlanRepository "dental_clinic/internal/modules/treatment_plan/repository"
+	"dental_clinic/internal/queue"
+
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -14,6 +20,14 @@ type completedAppointment struct {
 	id              uuid.UUID
 	clinicAddressId uuid.UUID
 	clinicServiceId uuid.UUID
+	startTime       time.Time
+}
+
+type stockLot struct {
+	id       uuid.UUID
+	quantity float64
+	unitCost *float64
+	expired  bool
 }
 
 type serviceMaterial struct {
@@ -21,6 +35,16 @@ type serviceMaterial struct {
 	quantity  float64
 }
 
+// completeAppointmentsJob is the name the appointment completion job records
+// its runs and failed appointments under.
+const completeAppointmentsJob = "appointment_status"
+
+// completeAppointmentsBatch is how many expired appointments are picked up at
+// a time.
+const completeAppointmentsBatch = 100
+
+// StartAppointmentStatusCron completes booked appointments that have ended,
+// taking their materials out of stock.
 func StartAppointmentStatusCron(ctx context.Context, db *pgxpool.Pool, interval time.Duration) {
 	if db == nil {
 		return
@@ -29,102 +53,162 @@ func StartAppointmentStatusCron(ctx context.Context, db *pgxpool.Pool, interval
 		interval = time.Minute
 	}
 
-	go func() {
-		runAppointmentStatusJob(ctx, db)
+	startPeriodicJob(ctx, db, completeAppointmentsJob, interval, func(ctx context.Context) (jobResult, error) {
+		return runAppointmentStatusJob(ctx, db, queue.New(db))
+	})
+}
+
+func runAppointmentStatusJob(ctx context.Context, db *pgxpool.Pool, q *queue.Queue) (jobResult, error) {
+	result, consumedAt, err := completeExpiredAppointments(ctx, db)
+	if err != nil {
+		return result, err
+	}
+
+	alerted, err := checkInventoryAlerts(ctx, db, q, consumedAt)
+	if err != nil {
+		return result, fmt.Errorf("inventory alert check: %v", err)
+	}
+	if alerted > 0 {
+		log.Printf("inventory alert check reported %d item(s)", alerted)
+	}
+	return result, nil
+}
+
+// completeExpiredAppointments completes each expired appointment in its own
+// transaction, so an appointment that fails is retried on its own later and
+// the others still complete. It also returns the clinic addresses whose stock
+// was consumed so it can be checked against their reorder levels.
+func completeExpiredAppointments(ctx context.Context, db *pgxpool.Pool) (jobResult, []uuid.UUID, error) {
+	var result jobResult
+	consumedAt := make([]uuid.UUID, 0)
+	seen := make(map[uuid.UUID]bool)
+	plans := treatmentPlanRepository.NewTreatmentPlanRepository(db)
 
-		ticker := time.NewTicker(interval)
-		defer ticker.Stop()
+	for {
+		appointmentIds, err := getExpiredBookedAppointmentIds(ctx, db)
+		if err != nil {
+			return result, consumedAt, err
+		}
 
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case <-ticker.C:
-				runAppointmentStatusJob(ctx, db)
+		batch, err := processItems(ctx, db, completeAppointmentsJob, appointmentIds, func(ctx context.Context, tx pgx.Tx, appointmentId uuid.UUID) (bool, error) {
+			completed, consumed, err := completeAppointment(ctx, tx, plans, appointmentId)
+			if err != nil || completed == nil {
+				return false, err
+			}
+			if consumed && !seen[completed.clinicAddressId] {
+				seen[completed.clinicAddressId] = true
+				consumedAt = append(consumedAt, completed.clinicAddressId)
 			}
+			return true, nil
+		})
+		result.processed += batch.processed
+		result.failed += batch.failed
+		if err != nil {
+			return result, consumedAt, err
 		}
-	}()
-}
 
-func runAppointmentStatusJob(ctx context.Context, db *pgxpool.Pool) {
-	updated, err := completeExpiredAppointments(ctx, db)
-	if err != nil {
-		log.Printf("appointment status cron failed: %v", err)
-		return
-	}
-	if updated > 0 {
-		log.Printf("appointment status cron completed %d appointment(s)", updated)
+		// failed appointments wait for their retry, so a short batch means
+		// there is nothing left to do
+		if len(appointmentIds) < completeAppointmentsBatch || batch.processed+batch.failed == 0 {
+			return result, consumedAt, nil
+		}
 	}
 }
 
-func completeExpiredAppointments(ctx context.Context, db *pgxpool.Pool) (int64, error) {
-	tx, err := db.BeginTx(ctx, pgx.TxOptions{})
+// getExpiredBookedAppointmentIds lists booked appointments that have ended,
+// leaving out those waiting for a retry or given up on.
+func getExpiredBookedAppointmentIds(ctx context.Context, db *pgxpool.Pool) ([]uuid.UUID, error) {
+	query := `
+		SELECT a.id
+		FROM appointments a
+		WHERE a.status = 'booked'
+			AND a.end_time <= NOW()
+			AND ` + dueItemsFilter("a.id") + `
+		ORDER BY a.end_time
+		LIMIT $2
+	`
+	rows, err := db.Query(ctx, query, completeAppointmentsJob, completeAppointmentsBatch)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-	defer tx.Rollback(ctx)
+	defer rows.Close()
 
-	appointments, err := getExpiredBookedAppointments(ctx, tx)
-	if err != nil {
-		return 0, err
+	ids := make([]uuid.UUID, 0)
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
 	}
+	return ids, rows.Err()
+}
 
-	for _, appointment := range appointments {
-		if appointment.clinicServiceId != uuid.Nil {
-			materials, err := getServiceMaterials(ctx, tx, appointment.clinicServiceId)
-			if err != nil {
-				return 0, err
-			}
+// completeAppointment takes the materials of the appointment out of stock
+// and completes it, its reservations and the treatment plan items booked for
+// it. It returns nil when the appointment is no longer booked or is being
+// completed elsewhere, and whether any stock was consumed.
+func completeAppointment(ctx context.Context, tx pgx.Tx, plans treatmentPlanRepository.TreatmentPlanRepository, appointmentId uuid.UUID) (*completedAppointment, bool, error) {
+	appointment, err := lockExpiredBookedAppointment(ctx, tx, appointmentId)
+	if err != nil || appointment == nil {
+		return nil, false, err
+	}
 
-			for _, material := range materials {
-				if err := subtractInventoryMaterial(ctx, tx, appointment, material); err != nil {
-					return 0, err
-				}
-			}
+	consumed := false
+	if appointment.clinicServiceId != uuid.Nil {
+		materials, err := getServiceMaterials(ctx, tx, appointment.clinicServiceId)
+		if err != nil {
+			return nil, false, err
 		}
-
-		if err := markAppointmentCompleted(ctx, tx, appointment.id); err != nil {
-			return 0, err
+		for _, material := range materials {
+			if err := subtractInventoryMaterial(ctx, tx, *appointment, material); err != nil {
+				return nil, false, fmt.Errorf("product %s: %v", material.productId, err)
+			}
 		}
+		consumed = len(materials) > 0
 	}
 
-	if err := tx.Commit(ctx); err != nil {
-		return 0, err
+	if err := markAppointmentCompleted(ctx, tx, appointment.id); err != nil {
+		return nil, false, err
 	}
-
-	return int64(len(appointments)), nil
+	if err := consumeReservations(ctx, tx, appointment.id); err != nil {
+		return nil, false, err
+	}
+	if err := completeTreatmentPlanItems(ctx, tx, plans, appointment.id); err != nil {
+		return nil, false, err
+	}
+	return appointment, consumed, nil
 }
 
-func getExpiredBookedAppointments(ctx context.Context, tx pgx.Tx) ([]completedAppointment, error) {
+// lockExpiredBookedAppointment locks the appointment if it is still booked
+// and has ended. Appointments locked by another transaction are skipped.
+func lockExpiredBookedAppointment(ctx context.Context, tx pgx.Tx, appointmentId uuid.UUID) (*completedAppointment, error) {
 	query := `
 		SELECT
 			a.id,
 			a.clinic_address_id,
-			COALESCE(cs.id, '00000000-0000-0000-0000-000000000000'::uuid)
+			COALESCE(cs.id, '00000000-0000-0000-0000-000000000000'::uuid),
+			a.start_time
 		FROM appointments a
 		LEFT JOIN clinic_addresses ca ON ca.id = a.clinic_address_id
 		LEFT JOIN clinic_services cs ON cs.clinic_id = ca.clinic_id
 			AND cs.service_id = a.service_id
 			AND cs.is_active = true
-		WHERE a.status = 'booked'
+		WHERE a.id = $1
+			AND a.status = 'booked'
 			AND a.end_time <= NOW()
 		FOR UPDATE OF a SKIP LOCKED
 	`
-	rows, err := tx.Query(ctx, query)
+	var appointment completedAppointment
+	err := tx.QueryRow(ctx, query, appointmentId).
+		Scan(&appointment.id, &appointment.clinicAddressId, &appointment.clinicServiceId, &appointment.startTime)
 	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	appointments := make([]completedAppointment, 0)
-	for rows.Next() {
-		var appointment completedAppointment
-		if err := rows.Scan(&appointment.id, &appointment.clinicAddressId, &appointment.clinicServiceId); err != nil {
-			return nil, err
+		if err == pgx.ErrNoRows {
+			return nil, nil
 		}
-		appointments = append(appointments, appointment)
+		return nil, err
 	}
-	return appointments, rows.Err()
+	return &appointment, nil
 }
 
 func getServiceMaterials(ctx context.Context, tx pgx.Tx, clinicServiceId uuid.UUID) ([]serviceMaterial, error) {
@@ -150,33 +234,149 @@ func getServiceMaterials(ctx context.Context, tx pgx.Tx, clinicServiceId uuid.UU
 	return materials, rows.Err()
 }
 
+// subtractInventoryMaterial takes what the service used out of the address
+// stock, lot by lot in the clinic's consumption order, recording which lot
+// went into the appointment. Lots expired by the day of the appointment are
+// not used. Stock never goes below zero: a shortfall is logged and only the
+// amount actually taken is recorded as used.
 func subtractInventoryMaterial(ctx context.Context, tx pgx.Tx, appointment completedAppointment, material serviceMaterial) error {
-	query := `
-		UPDATE address_inventory
-		SET quantity = quantity - $3,
-			updated_at = NOW()
+	insertQuery := `
+		INSERT INTO address_inventory (id, clinic_address_id, product_id, quantity, updated_at)
+		VALUES ($1, $2, $3, 0, NOW())
+		ON CONFLICT (clinic_address_id, product_id) DO NOTHING
+	`
+	if _, err := tx.Exec(ctx, insertQuery, uuid.New(), appointment.clinicAddressId, material.productId); err != nil {
+		return err
+	}
+
+	var inventoryId uuid.UUID
+	var stock float64
+	lockQuery := `
+		SELECT id, quantity::float8
+		FROM address_inventory
 		WHERE clinic_address_id = $1
 			AND product_id = $2
+		FOR UPDATE
 	`
-	result, err := tx.Exec(ctx, query, appointment.clinicAddressId, material.productId, material.quantity)
+	if err := tx.QueryRow(ctx, lockQuery, appointment.clinicAddressId, material.productId).Scan(&inventoryId, &stock); err != nil {
+		return err
+	}
+
+	lots, err := getStockLots(ctx, tx, appointment, material.productId)
 	if err != nil {
 		return err
 	}
-	if result.RowsAffected() == 0 {
-		insertQuery := `
-			INSERT INTO address_inventory (id, clinic_address_id, product_id, quantity, updated_at)
-			VALUES ($1, $2, $3, $4, NOW())
-		`
-		if _, err := tx.Exec(ctx, insertQuery, uuid.New(), appointment.clinicAddressId, material.productId, -material.quantity); err != nil {
+
+	taken := 0.0
+	inLots := 0.0
+	skippedExpired := 0.0
+	for _, lot := range lots {
+		inLots += lot.quantity
+		if lot.expired {
+			skippedExpired += lot.quantity
+			continue
+		}
+		fromLot := math.Min(lot.quantity, material.quantity-taken)
+		if fromLot <= 0 {
+			continue
+		}
+		if _, err := tx.Exec(ctx, `UPDATE inventory_lots SET quantity = quantity - $2 WHERE id = $1`, lot.id, fromLot); err != nil {
 			return err
 		}
+		lotId := lot.id
+		if err := recordUsedMaterial(ctx, tx, appointment, material.productId, fromLot, &lotId, lot.unitCost); err != nil {
+			return err
+		}
+		taken += fromLot
+	}
+
+	// stock that is not in any lot
+	if untracked := math.Min(math.Max(stock-inLots, 0), material.quantity-taken); untracked > 0 {
+		if err := recordUsedMaterial(ctx, tx, appointment, material.productId, untracked, nil, nil); err != nil {
+			return err
+		}
+		taken += untracked
+	}
+
+	if taken < material.quantity {
+		log.Printf(
+			"appointment %s: short of product %s at clinic address %s, needed %g, had %g (%g of it expired)",
+			appointment.id, material.productId, appointment.clinicAddressId, material.quantity, stock, skippedExpired,
+		)
+	}
+	if taken <= 0 {
+		return nil
+	}
+
+	updateQuery := `
+		UPDATE address_inventory
+		SET quantity = GREATEST(quantity - $2, 0),
+			updated_at = NOW()
+		WHERE id = $1
+	`
+	_, err = tx.Exec(ctx, updateQuery, inventoryId, taken)
+	return err
+}
+
+// getStockLots locks the lots of the product at the appointment's address in
+// the order the clinic uses them.
+func getStockLots(ctx context.Context, tx pgx.Tx, appointment completedAppointment, productId uuid.UUID) ([]stockLot, error) {
+	var order string
+	orderQuery := `
+		SELECT COALESCE(s.consumption_order, 'fefo')
+		FROM clinic_addresses ca
+		LEFT JOIN clinic_inventory_settings s ON s.clinic_id = ca.clinic_id
+		WHERE ca.id = $1
+	`
+	if err := tx.QueryRow(ctx, orderQuery, appointment.clinicAddressId).Scan(&order); err != nil && err != pgx.ErrNoRows {
+		return nil, err
+	}
+
+	query := `
+		SELECT l.id, l.quantity::float8, l.unit_cost::float8, COALESCE(l.expires_at < $3::date, false)
+		FROM inventory_lots l
+		WHERE l.clinic_address_id = $1
+			AND l.product_id = $2
+			AND l.quantity > 0
+		ORDER BY ` + inventoryRepository.LotOrder(order) + `
+		FOR UPDATE
+	`
+	rows, err := tx.Query(ctx, query, appointment.clinicAddressId, productId, appointment.startTime)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lots := make([]stockLot, 0)
+	for rows.Next() {
+		var lot stockLot
+		if err := rows.Scan(&lot.id, &lot.quantity, &lot.unitCost, &lot.expired); err != nil {
+			return nil, err
+		}
+		lots = append(lots, lot)
 	}
+	return lots, rows.Err()
+}
 
-	transactionQuery := `
-		INSERT INTO inventory_transactions (id, clinic_address_id, product_id, quantity, transaction_type, appointment_id, created_at)
-		VALUES ($1, $2, $3, $4, 'used', $5, NOW())
+func recordUsedMaterial(ctx context.Context, tx pgx.Tx, appointment completedAppointment, productId uuid.UUID, quantity float64, lotId *uuid.UUID, unitCost *float64) error {
+	query := `
+		INSERT INTO inventory_transactions (id, clinic_address_id, product_id, quantity, transaction_type, appointment_id, lot_id, unit_cost, created_at)
+		VALUES ($1, $2, $3, $4, 'used', $5, $6, $7, NOW())
 	`
-	_, err = tx.Exec(ctx, transactionQuery, uuid.New(), appointment.clinicAddressId, material.productId, material.quantity, appointment.id)
+	_, err := tx.Exec(ctx, query, uuid.New(), appointment.clinicAddressId, productId, quantity, appointment.id, lotId, unitCost)
+	return err
+}
+
+// consumeReservations closes the materials held for the appointment now that
+// they were taken out of stock.
+func consumeReservations(ctx context.Context, tx pgx.Tx, appointmentId uuid.UUID) error {
+	query := `
+		UPDATE inventory_reservations
+		SET status = 'consumed', updated_at = NOW()
+		WHERE appointment_id = $1
+			AND status = 'reserved'
+	`
+	_, err := tx.Exec(ctx, query, appointmentId)
 	return err
 }
 
@@ -190,3 +390,39 @@ func markAppointmentCompleted(ctx context.Context, tx pgx.Tx, appointmentId uuid
 	}
 	return nil
 }
+
+// completeTreatmentPlanItems marks plan items booked for the appointment as
+// done and advances the progress of their plans.
+func completeTreatmentPlanItems(ctx context.Context, tx pgx.Tx, plans treatmentPlanRepository.TreatmentPlanRepository, appointmentId uuid.UUID) error {
+	query := `
+		UPDATE treatment_plan_items
+		SET status = 'completed', completed_at = NOW()
+		WHERE appointment_id = $1
+			AND status = 'scheduled'
+		RETURNING plan_id
+	`
+	rows, err := tx.Query(ctx, query, appointmentId)
+	if err != nil {
+		return err
+	}
+	planIds := make([]uuid.UUID, 0)
+	for rows.Next() {
+		var planId uuid.UUID
+		if err := rows.Scan(&planId); err != nil {
+			rows.Close()
+			return err
+		}
+		planIds = append(planIds, planId)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, planId := range planIds {
+		if err := plans.RefreshPlanStatusTx(planId, tx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,265 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"log"
+	"strings"
+	"time"
+
+	inventoryServices "dental_clinic/internal/modules/inventory/services"
+	"dental_clinic/internal/queue"
+	"dental_clinic/internal/utils"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type stockItem struct {
+	id           uuid.UUID
+	clinicId     uuid.UUID
+	clinicName   string
+	address      string
+	productName  string
+	productUnit  string
+	quantity     float64
+	minQuantity  float64
+	reorderLevel float64
+	alertedLevel int
+}
+
+func (item stockItem) status() string {
+	return inventoryServices.StockStatus(item.quantity, item.minQuantity, item.reorderLevel)
+}
+
+const stockItemSelect = `
+	SELECT
+		ai.id, ca.clinic_id, COALESCE(c.name, ''),
+		TRIM(BOTH ', ' FROM CONCAT_WS(', ', a.city, a.street, a.building)),
+		p.name, p.unit, ai.quantity::float8, ai.min_quantity::float8, ai.reorder_level::float8, ai.alerted_level
+	FROM address_inventory ai
+	JOIN clinic_addresses ca ON ca.id = ai.clinic_address_id
+	JOIN clinics c ON c.id = ca.clinic_id
+	LEFT JOIN addresses a ON a.id = ca.address_id
+	JOIN products p ON p.id = ai.product_id
+`
+
+// checkInventoryAlerts looks at the stock of the addresses after materials
+// were consumed there and emails the clinic admins who want immediate alerts
+// about every item that got worse since its last alert.
+func checkInventoryAlerts(ctx context.Context, db *pgxpool.Pool, q *queue.Queue, clinicAddressIds []uuid.UUID) (int, error) {
+	if len(clinicAddressIds) == 0 {
+		return 0, nil
+	}
+
+	items, err := getStockItems(ctx, db, `WHERE ai.clinic_address_id = ANY($1) ORDER BY c.name, p.name`, clinicAddressIds)
+	if err != nil {
+		return 0, err
+	}
+
+	worse := make(map[uuid.UUID][]stockItem)
+	clinicIds := make([]uuid.UUID, 0)
+	for _, item := range items {
+		severity := inventoryServices.StockSeverity(item.status())
+		switch {
+		case severity < item.alertedLevel:
+			if err := setAlertedLevel(ctx, db, item.id, severity); err != nil {
+				return 0, err
+			}
+		case severity > item.alertedLevel:
+			if _, ok := worse[item.clinicId]; !ok {
+				clinicIds = append(clinicIds, item.clinicId)
+			}
+			worse[item.clinicId] = append(worse[item.clinicId], item)
+		}
+	}
+
+	alerted := 0
+	for _, clinicId := range clinicIds {
+		clinicItems := worse[clinicId]
+		recipients, err := getInventoryAlertRecipients(ctx, db, clinicId, "immediate")
+		if err != nil {
+			return alerted, err
+		}
+
+		subject := fmt.Sprintf("Low stock at %s", clinicItems[0].clinicName)
+		if !sendToAll(ctx, q, recipients, subject, stockMessage("Stock needs attention", clinicItems), "inventory alert") {
+			// when no email could be queued the items are tried again on the
+			// next check
+			continue
+		}
+		for _, item := range clinicItems {
+			if err := setAlertedLevel(ctx, db, item.id, inventoryServices.StockSeverity(item.status())); err != nil {
+				return alerted, err
+			}
+		}
+		alerted += len(clinicItems)
+	}
+	return alerted, nil
+}
+
+// StartInventoryDigestCron sends clinic admins who chose the daily digest a
+// summary of every item at their clinic that needs restocking.
+func StartInventoryDigestCron(ctx context.Context, db *pgxpool.Pool, interval time.Duration) {
+	if db == nil {
+		return
+	}
+	if interval <= 0 {
+		interval = 24 * time.Hour
+	}
+
+	startPeriodicJob(ctx, db, "inventory_digest", interval, func(ctx context.Context) (jobResult, error) {
+		sent, err := sendInventoryDigests(ctx, db, queue.New(db))
+		return jobResult{processed: sent}, err
+	})
+}
+
+func sendInventoryDigests(ctx context.Context, db *pgxpool.Pool, q *queue.Queue) (int, error) {
+	items, err := getStockItems(ctx, db, `
+		WHERE EXISTS (
+			SELECT 1 FROM inventory_alert_preferences pref
+			JOIN clinic_admins adm ON adm.clinic_id = pref.clinic_id AND adm.user_id = pref.user_id
+			WHERE pref.clinic_id = ca.clinic_id AND pref.mode = 'daily_digest'
+		)
+		ORDER BY c.name, p.name
+	`)
+	if err != nil {
+		return 0, err
+	}
+
+	byClinic := make(map[uuid.UUID][]stockItem)
+	clinicIds := make([]uuid.UUID, 0)
+	for _, item := range items {
+		if item.status() == inventoryServices.StockOK {
+			continue
+		}
+		if _, ok := byClinic[item.clinicId]; !ok {
+			clinicIds = append(clinicIds, item.clinicId)
+		}
+		byClinic[item.clinicId] = append(byClinic[item.clinicId], item)
+	}
+
+	sent := 0
+	for _, clinicId := range clinicIds {
+		clinicItems := byClinic[clinicId]
+		recipients, err := getInventoryAlertRecipients(ctx, db, clinicId, "daily_digest")
+		if err != nil {
+			return sent, err
+		}
+
+		subject := fmt.Sprintf("Daily stock digest for %s", clinicItems[0].clinicName)
+		if sendToAll(ctx, q, recipients, subject, stockMessage("Items to restock", clinicItems), "inventory digest") {
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+func getStockItems(ctx context.Context, db *pgxpool.Pool, where string, args ...interface{}) ([]stockItem, error) {
+	rows, err := db.Query(ctx, stockItemSelect+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]stockItem, 0)
+	for rows.Next() {
+		var item stockItem
+		if err := rows.Scan(
+			&item.id,
+			&item.clinicId,
+			&item.clinicName,
+			&item.address,
+			&item.productName,
+			&item.productUnit,
+			&item.quantity,
+			&item.minQuantity,
+			&item.reorderLevel,
+			&item.alertedLevel,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// getInventoryAlertRecipients returns the emails of the clinic's admins who
+// receive stock alerts in the given mode. Admins who never chose get
+// immediate alerts.
+func getInventoryAlertRecipients(ctx context.Context, db *pgxpool.Pool, clinicId uuid.UUID, mode string) ([]string, error) {
+	query := `
+		SELECT DISTINCT u.email
+		FROM clinic_admins ca
+		JOIN users u ON u.id = ca.user_id
+		LEFT JOIN inventory_alert_preferences pref ON pref.clinic_id = ca.clinic_id AND pref.user_id = ca.user_id
+		WHERE ca.clinic_id = $1
+			AND COALESCE(pref.mode, 'immediate') = $2
+	`
+	rows, err := db.Query(ctx, query, clinicId, mode)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	emails := make([]string, 0)
+	for rows.Next() {
+		var email string
+		if err := rows.Scan(&email); err != nil {
+			return nil, err
+		}
+		emails = append(emails, email)
+	}
+	return emails, rows.Err()
+}
+
+func setAlertedLevel(ctx context.Context, db *pgxpool.Pool, id uuid.UUID, severity int) error {
+	_, err := db.Exec(ctx, `UPDATE address_inventory SET alerted_level = $2 WHERE id = $1`, id, severity)
+	return err
+}
+
+// sendToAll queues an email to every recipient and reports whether any of
+// them was queued. Recipients whose email could not be queued are only
+// logged, so retrying them never emails the others twice. The queue retries
+// the ones that fail to send.
+func sendToAll(ctx context.Context, q *queue.Queue, recipients []string, subject, message, job string) bool {
+	sent := false
+	for _, email := range recipients {
+		if err := utils.QueueEmail(ctx, q, email, subject, message); err != nil {
+			log.Printf("%s: failed to queue email to %s: %v", job, email, err)
+			continue
+		}
+		sent = true
+	}
+	return sent
+}
+
+func stockMessage(title string, items []stockItem) string {
+	var rows strings.Builder
+	for _, item := range items {
+		rows.WriteString(fmt.Sprintf(
+			"<tr><td>%s</td><td>%s</td><td>%g %s</td><td>%g / %g</td><td>%s</td></tr>",
+			html.EscapeString(item.productName),
+			html.EscapeString(item.address),
+			item.quantity,
+			html.EscapeString(item.productUnit),
+			item.minQuantity,
+			item.reorderLevel,
+			strings.ReplaceAll(item.status(), "_", " "),
+		))
+	}
+
+	return fmt.Sprintf(`
+		<h2>%s</h2>
+		<p><strong>Clinic:</strong> %s</p>
+		<table>
+			<tr><th>Product</th><th>Address</th><th>In stock</th><th>Minimum / reorder level</th><th>Status</th></tr>
+			%s
+		</table>
+	`,
+		html.EscapeString(title),
+		html.EscapeString(items[0].clinicName),
+		rows.String(),
+	)
+}
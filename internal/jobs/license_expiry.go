@@ -0,0 +1,183 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"time"
+
+	"dental_clinic/internal/queue"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// licenseWarningDays are the "days left" marks at which admins are warned
+// about a license; 0 means it has expired.
+var licenseWarningDays = []int{30, 7, 0}
+
+type expiringLicense struct {
+	id          uuid.UUID
+	title       string
+	number      string
+	expiresAt   time.Time
+	warnedDays  *int
+	doctorName  string
+	doctorId    uuid.UUID
+	doctorEmail string
+}
+
+// StartLicenseExpiryCron warns platform admins and the admins of the doctor's
+// clinics when a verified license gets close to its expiry date, once per mark
+// in licenseWarningDays.
+func StartLicenseExpiryCron(ctx context.Context, db *pgxpool.Pool, interval time.Duration) {
+	if db == nil {
+		return
+	}
+	if interval <= 0 {
+		interval = 24 * time.Hour
+	}
+
+	startPeriodicJob(ctx, db, "license_expiry", interval, func(ctx context.Context) (jobResult, error) {
+		warned, err := warnExpiringLicenses(ctx, db, queue.New(db))
+		return jobResult{processed: warned}, err
+	})
+}
+
+func warnExpiringLicenses(ctx context.Context, db *pgxpool.Pool, q *queue.Queue) (int, error) {
+	licenses, err := getExpiringLicenses(ctx, db)
+	if err != nil {
+		return 0, err
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	warned := 0
+	for _, license := range licenses {
+		daysLeft := int(license.expiresAt.Sub(today).Hours() / 24)
+		mark, ok := warningMark(daysLeft)
+		if !ok || (license.warnedDays != nil && *license.warnedDays <= mark) {
+			continue
+		}
+
+		recipients, err := getLicenseAdmins(ctx, db, license.doctorId)
+		if err != nil {
+			return warned, err
+		}
+
+		subject, message := licenseWarning(license, daysLeft)
+		// emails that fail to send are retried by the queue; ones that could
+		// not be queued are tried again on the next run
+		if !sendToAll(ctx, q, recipients, subject, message, "license expiry cron") {
+			continue
+		}
+
+		if _, err := db.Exec(ctx, `UPDATE doctor_credentials SET expiry_warning_days = $2 WHERE id = $1`, license.id, mark); err != nil {
+			return warned, err
+		}
+		warned++
+	}
+	return warned, nil
+}
+
+// warningMark returns the smallest mark daysLeft has reached.
+func warningMark(daysLeft int) (int, bool) {
+	mark, ok := 0, false
+	for _, days := range licenseWarningDays {
+		if daysLeft <= days && (!ok || days < mark) {
+			mark, ok = days, true
+		}
+	}
+	return mark, ok
+}
+
+func getExpiringLicenses(ctx context.Context, db *pgxpool.Pool) ([]expiringLicense, error) {
+	query := `
+		SELECT c.id, c.title, c.number, c.expires_at, c.expiry_warning_days, d.name, d.id, d.email
+		FROM doctor_credentials c
+		JOIN doctors d ON d.id = c.doctor_id
+		WHERE c.type = 'license'
+			AND c.status = 'verified'
+			AND c.expires_at IS NOT NULL
+			AND c.expires_at <= CURRENT_DATE + $1::int
+			AND d.is_deleted = 0
+		ORDER BY c.expires_at
+	`
+
+	rows, err := db.Query(ctx, query, licenseWarningDays[0])
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	licenses := make([]expiringLicense, 0)
+	for rows.Next() {
+		var license expiringLicense
+		if err := rows.Scan(
+			&license.id,
+			&license.title,
+			&license.number,
+			&license.expiresAt,
+			&license.warnedDays,
+			&license.doctorName,
+			&license.doctorId,
+			&license.doctorEmail,
+		); err != nil {
+			return nil, err
+		}
+		licenses = append(licenses, license)
+	}
+	return licenses, rows.Err()
+}
+
+// getLicenseAdmins returns the emails of platform admins and of the admins of
+// the clinics the doctor currently works at.
+func getLicenseAdmins(ctx context.Context, db *pgxpool.Pool, doctorId uuid.UUID) ([]string, error) {
+	query := `
+		SELECT DISTINCT u.email
+		FROM users u
+		WHERE u.role = 'admin'
+			OR EXISTS (
+				SELECT 1 FROM clinic_admins ca
+				JOIN doctor_clinics dc ON dc.clinic_id = ca.clinic_id
+				WHERE ca.user_id = u.id AND dc.doctor_id = $1 AND dc.status = 'active'
+			)
+	`
+
+	rows, err := db.Query(ctx, query, doctorId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	emails := make([]string, 0)
+	for rows.Next() {
+		var email string
+		if err := rows.Scan(&email); err != nil {
+			return nil, err
+		}
+		emails = append(emails, email)
+	}
+	return emails, rows.Err()
+}
+
+func licenseWarning(license expiringLicense, daysLeft int) (string, string) {
+	status := fmt.Sprintf("expires in %d day(s), on %s", daysLeft, license.expiresAt.Format("2006-01-02"))
+	if daysLeft <= 0 {
+		status = fmt.Sprintf("expired on %s", license.expiresAt.Format("2006-01-02"))
+	}
+
+	subject := fmt.Sprintf("License of %s %s", license.doctorName, status)
+	message := fmt.Sprintf(`
+		<h2>Doctor license %s</h2>
+		<p><strong>Doctor:</strong> %s (%s)</p>
+		<p><strong>License:</strong> %s %s</p>
+		<p>Please ask the doctor to upload the renewed license.</p>
+	`,
+		html.EscapeString(status),
+		html.EscapeString(license.doctorName),
+		html.EscapeString(license.doctorEmail),
+		html.EscapeString(license.title),
+		html.EscapeString(license.number),
+	)
+	return subject, message
+}
@@ -0,0 +1,33 @@
+package jobs
+
+import (
+	"strconv"
+
+	"dental_clinic/internal/config"
+	"dental_clinic/internal/modules/reminders"
+	"dental_clinic/internal/modules/reports"
+	"dental_clinic/internal/modules/schedule"
+	"dental_clinic/internal/queue"
+	"dental_clinic/internal/utils"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// defaultQueueConcurrency is used when QUEUE_CONCURRENCY is not a number.
+const defaultQueueConcurrency = 4
+
+// NewQueueWorker returns the worker of the job queue with the jobs and
+// schedules of every module registered. It does nothing until it is Run.
+func NewQueueWorker(db *pgxpool.Pool, cfg *config.Config) *queue.Worker {
+	concurrency, err := strconv.Atoi(cfg.QueueConcurrency)
+	if err != nil || concurrency < 1 {
+		concurrency = defaultQueueConcurrency
+	}
+
+	w := queue.NewWorker(db, concurrency)
+	utils.RegisterEmailJobs(w, cfg)
+	schedule.RegisterJobs(w, db, cfg)
+	reports.RegisterJobs(w, db)
+	reminders.RegisterJobs(w, db, cfg)
+	return w
+}
@@ -0,0 +1,28 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	reviewRepository "dental_clinic/internal/modules/reviews/repository"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// StartRatingStatsCron periodically recomputes rating aggregates. Counters
+// are kept up to date as reviews come in; this only moves the recent-trend
+// windows forward and repairs any drift.
+func StartRatingStatsCron(ctx context.Context, db *pgxpool.Pool, interval time.Duration) {
+	if db == nil {
+		return
+	}
+	if interval <= 0 {
+		interval = time.Hour
+	}
+
+	repo := reviewRepository.NewReviewRepository(db)
+
+	startPeriodicJob(ctx, db, "rating_stats", interval, func(ctx context.Context) (jobResult, error) {
+		return jobResult{}, repo.RefreshRatingStats(ctx)
+	})
+}
@@ -0,0 +1,276 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// maxItemAttempts is how often a job tries an item before it gives up on
+	// it and moves it to job_dead_letters.
+	maxItemAttempts = 5
+	// itemRetryBackoff is the wait before the first retry of a failed item;
+	// it doubles with every attempt up to maxItemRetryBackoff.
+	itemRetryBackoff    = time.Minute
+	maxItemRetryBackoff = time.Hour
+)
+
+// jobResult is what a run of a job got through: the items it handled and the
+// items that failed and were left for a retry.
+type jobResult struct {
+	processed int
+	failed    int
+}
+
+// startPeriodicJob runs the job now and then every interval until ctx is
+// done. Every app instance starts the ticker, but a run only goes ahead on
+// the instance that holds the job's advisory lock and only when no instance
+// has run the job within the interval, so the job runs once per interval
+// however many instances there are. Each run is recorded in job_runs.
+func startPeriodicJob(ctx context.Context, db *pgxpool.Pool, name string, interval time.Duration, run func(ctx context.Context) (jobResult, error)) {
+	go func() {
+		runPeriodicJob(ctx, db, name, interval, run)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				runPeriodicJob(ctx, db, name, interval, run)
+			}
+		}
+	}()
+}
+
+func runPeriodicJob(ctx context.Context, db *pgxpool.Pool, name string, interval time.Duration, run func(ctx context.Context) (jobResult, error)) {
+	conn, err := db.Acquire(ctx)
+	if err != nil {
+		log.Printf("%s job: %v", name, err)
+		return
+	}
+	defer conn.Release()
+
+	var locked bool
+	if err := conn.QueryRow(ctx, `SELECT pg_try_advisory_lock(hashtext($1))`, "job:"+name).Scan(&locked); err != nil {
+		log.Printf("%s job: %v", name, err)
+		return
+	}
+	if !locked {
+		// another instance is running it
+		return
+	}
+	defer func() {
+		if _, err := conn.Exec(context.Background(), `SELECT pg_advisory_unlock(hashtext($1))`, "job:"+name); err != nil {
+			log.Printf("%s job: unlock: %v", name, err)
+		}
+	}()
+
+	// runs left running by an instance that stopped mid-run
+	interruptedQuery := `
+		UPDATE job_runs
+		SET status = 'failed', error = 'interrupted', finished_at = NOW()
+		WHERE job_name = $1
+			AND status = 'running'
+	`
+	if _, err := conn.Exec(ctx, interruptedQuery, name); err != nil {
+		log.Printf("%s job: %v", name, err)
+		return
+	}
+
+	// a tenth of the interval is allowed for instances whose tickers are
+	// slightly out of step
+	var recent bool
+	recentQuery := `
+		SELECT EXISTS (
+			SELECT 1 FROM job_runs
+			WHERE job_name = $1
+				AND started_at > NOW() - $2 * INTERVAL '1 second'
+		)
+	`
+	if err := conn.QueryRow(ctx, recentQuery, name, (interval - interval/10).Seconds()).Scan(&recent); err != nil {
+		log.Printf("%s job: %v", name, err)
+		return
+	}
+	if recent {
+		return
+	}
+
+	instance, _ := os.Hostname()
+	var runId uuid.UUID
+	startQuery := `INSERT INTO job_runs (job_name, instance) VALUES ($1, $2) RETURNING id`
+	if err := conn.QueryRow(ctx, startQuery, name, instance).Scan(&runId); err != nil {
+		log.Printf("%s job: %v", name, err)
+		return
+	}
+
+	result, runErr := run(ctx)
+
+	status := "succeeded"
+	message := ""
+	if runErr != nil {
+		status = "failed"
+		message = runErr.Error()
+		log.Printf("%s job failed: %v", name, runErr)
+	}
+	if result.processed > 0 || result.failed > 0 {
+		log.Printf("%s job processed %d item(s), %d failed", name, result.processed, result.failed)
+	}
+
+	finishQuery := `
+		UPDATE job_runs
+		SET status = $2, processed = $3, failed = $4, error = $5, finished_at = NOW()
+		WHERE id = $1
+	`
+	if _, err := conn.Exec(context.Background(), finishQuery, runId, status, result.processed, result.failed, message); err != nil {
+		log.Printf("%s job: %v", name, err)
+	}
+}
+
+// recordItemFailure schedules the item for another attempt after a backoff
+// that doubles with every attempt. Once the item has failed maxItemAttempts
+// times it is moved to job_dead_letters instead, which it reports.
+func recordItemFailure(ctx context.Context, db *pgxpool.Pool, name string, itemId uuid.UUID, itemErr error) (bool, error) {
+	tx, err := db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	var attempts int
+	var firstFailedAt time.Time
+	upsertQuery := `
+		INSERT INTO job_item_failures (job_name, item_id, last_error, next_attempt_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (job_name, item_id) DO UPDATE
+		SET attempts = job_item_failures.attempts + 1,
+			last_error = EXCLUDED.last_error,
+			updated_at = NOW()
+		RETURNING attempts, first_failed_at
+	`
+	if err := tx.QueryRow(ctx, upsertQuery, name, itemId, itemErr.Error()).Scan(&attempts, &firstFailedAt); err != nil {
+		return false, err
+	}
+
+	dead := attempts >= maxItemAttempts
+	if dead {
+		deadQuery := `
+			INSERT INTO job_dead_letters (job_name, item_id, attempts, last_error, first_failed_at)
+			VALUES ($1, $2, $3, $4, $5)
+		`
+		if _, err := tx.Exec(ctx, deadQuery, name, itemId, attempts, itemErr.Error(), firstFailedAt); err != nil {
+			return false, err
+		}
+		if _, err := tx.Exec(ctx, `DELETE FROM job_item_failures WHERE job_name = $1 AND item_id = $2`, name, itemId); err != nil {
+			return false, err
+		}
+	} else {
+		backoff := itemRetryBackoff << (attempts - 1)
+		if backoff > maxItemRetryBackoff {
+			backoff = maxItemRetryBackoff
+		}
+		retryQuery := `
+			UPDATE job_item_failures
+			SET next_attempt_at = NOW() + $3 * INTERVAL '1 second'
+			WHERE job_name = $1 AND item_id = $2
+		`
+		if _, err := tx.Exec(ctx, retryQuery, name, itemId, backoff.Seconds()); err != nil {
+			return false, err
+		}
+	}
+
+	return dead, tx.Commit(ctx)
+}
+
+// clearItemFailure forgets the failed attempts of an item that went through.
+func clearItemFailure(ctx context.Context, db *pgxpool.Pool, name string, itemId uuid.UUID) error {
+	_, err := db.Exec(ctx, `DELETE FROM job_item_failures WHERE job_name = $1 AND item_id = $2`, name, itemId)
+	return err
+}
+
+// processItems runs process on every item in its own transaction, so one
+// failing item is retried later on its own instead of failing the others.
+// Items process reports as not handled, such as ones another instance got to
+// first, are not counted.
+func processItems(ctx context.Context, db *pgxpool.Pool, name string, itemIds []uuid.UUID, process func(ctx context.Context, tx pgx.Tx, itemId uuid.UUID) (bool, error)) (jobResult, error) {
+	var result jobResult
+	for _, itemId := range itemIds {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		handled, err := processItem(ctx, db, itemId, process)
+		if err != nil {
+			result.failed++
+			dead, recordErr := recordItemFailure(ctx, db, name, itemId, err)
+			if recordErr != nil {
+				return result, fmt.Errorf("item %s: %v (recording the failure: %v)", itemId, err, recordErr)
+			}
+			if dead {
+				log.Printf("%s job gave up on item %s after %d attempts: %v", name, itemId, maxItemAttempts, err)
+			} else {
+				log.Printf("%s job failed on item %s, will retry: %v", name, itemId, err)
+			}
+			continue
+		}
+		if !handled {
+			continue
+		}
+		result.processed++
+		if err := clearItemFailure(ctx, db, name, itemId); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+func processItem(ctx context.Context, db *pgxpool.Pool, itemId uuid.UUID, process func(ctx context.Context, tx pgx.Tx, itemId uuid.UUID) (bool, error)) (handled bool, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			handled, err = false, fmt.Errorf("panic: %v", recovered)
+		}
+	}()
+
+	tx, err := db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	handled, err = process(ctx, tx, itemId)
+	if err != nil {
+		return false, err
+	}
+	if !handled {
+		return false, nil
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// dueItemsFilter leaves out items of the job that are waiting for their next
+// attempt or were given up on. $1 is the job name and item the column holding
+// the item id.
+func dueItemsFilter(item string) string {
+	return `
+		NOT EXISTS (
+			SELECT 1 FROM job_item_failures f
+			WHERE f.job_name = $1 AND f.item_id = ` + item + ` AND f.next_attempt_at > NOW()
+		)
+		AND NOT EXISTS (
+			SELECT 1 FROM job_dead_letters d
+			WHERE d.job_name = $1 AND d.item_id = ` + item + ` AND d.requeued_at IS NULL
+		)
+	`
+}
@@ -9,6 +9,10 @@ type ChatRequest struct {
 	Message    string `json:"message"`
 	ChoiceType string `json:"choice_type"`
 	ChoiceID   string `json:"choice_id"`
+	// Latitude and Longitude are the patient's location, if the app shares
+	// it. Clinics closest to it are offered first.
+	Latitude  *float64 `json:"latitude,omitempty"`
+	Longitude *float64 `json:"longitude,omitempty"`
 }
 
 type ChatResponse struct {
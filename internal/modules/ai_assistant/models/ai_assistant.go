@@ -1,6 +1,10 @@
 package models
 
-import "github.com/google/uuid"
+import (
+	"dental_clinic/internal/geo"
+
+	"github.com/google/uuid"
+)
 
 type ChatSession struct {
 	Id     uuid.UUID
@@ -22,6 +26,17 @@ type BookingState struct {
 	Date            string `json:"date,omitempty"`
 	Time            string `json:"time,omitempty"`
 	Step            string `json:"step,omitempty"`
+
+	Latitude  *float64 `json:"latitude,omitempty"`
+	Longitude *float64 `json:"longitude,omitempty"`
+}
+
+// Location returns the patient's location, if known.
+func (s BookingState) Location() *geo.Point {
+	if s.Latitude == nil || s.Longitude == nil {
+		return nil
+	}
+	return &geo.Point{Lat: *s.Latitude, Lng: *s.Longitude}
 }
 
 func (s BookingState) IsComplete() bool {
@@ -47,6 +62,8 @@ type ClinicOption struct {
 	Price           float64 `json:"price"`
 	Duration        int     `json:"duration"`
 	Rating          float64 `json:"rating"`
+	// DistanceKm is set when the patient's location is known
+	DistanceKm *float64 `json:"distance_km,omitempty"`
 }
 
 type DoctorOption struct {
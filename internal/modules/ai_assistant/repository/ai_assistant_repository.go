@@ -3,7 +3,9 @@ package repository
 import (
 	"context"
 
+	"dental_clinic/internal/geo"
 	"dental_clinic/internal/modules/ai_assistant/models"
+	"dental_clinic/internal/search"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -19,7 +21,7 @@ type AIAssistantRepository interface {
 	SaveState(state *models.BookingState) error
 	ClearState(userID uuid.UUID) error
 	SearchServices(query string) ([]models.ServiceOption, error)
-	GetClinicOptions(serviceID string) ([]models.ClinicOption, error)
+	GetClinicOptions(serviceID string, location *geo.Point) ([]models.ClinicOption, error)
 	GetDoctorOptions(serviceID, clinicAddressID string) ([]models.DoctorOption, error)
 }
 
@@ -106,7 +108,9 @@ func (r *aiAssistantRepo) GetOrCreateState(userID uuid.UUID) (*models.BookingSta
 			COALESCE(clinic_address_id::text, ''),
 			COALESCE(date::text, ''),
 			COALESCE(time::text, ''),
-			COALESCE(step, '')
+			COALESCE(step, ''),
+			latitude,
+			longitude
 		FROM ai_booking_state
 		WHERE user_id = $1
 	`
@@ -118,6 +122,8 @@ func (r *aiAssistantRepo) GetOrCreateState(userID uuid.UUID) (*models.BookingSta
 		&state.Date,
 		&state.Time,
 		&state.Step,
+		&state.Latitude,
+		&state.Longitude,
 	)
 	if err == nil {
 		return state, nil
@@ -134,8 +140,8 @@ func (r *aiAssistantRepo) GetOrCreateState(userID uuid.UUID) (*models.BookingSta
 
 func (r *aiAssistantRepo) SaveState(state *models.BookingState) error {
 	query := `
-		INSERT INTO ai_booking_state (user_id, doctor_id, service_id, clinic_address_id, date, time, step, updated_at)
-		VALUES ($1::uuid, NULLIF($2, '')::uuid, NULLIF($3, '')::uuid, NULLIF($4, '')::uuid, NULLIF($5, '')::date, NULLIF($6, '')::time, $7, NOW())
+		INSERT INTO ai_booking_state (user_id, doctor_id, service_id, clinic_address_id, date, time, step, latitude, longitude, updated_at)
+		VALUES ($1::uuid, NULLIF($2, '')::uuid, NULLIF($3, '')::uuid, NULLIF($4, '')::uuid, NULLIF($5, '')::date, NULLIF($6, '')::time, $7, $8, $9, NOW())
 		ON CONFLICT (user_id) DO UPDATE SET
 			doctor_id = EXCLUDED.doctor_id,
 			service_id = EXCLUDED.service_id,
@@ -143,6 +149,8 @@ func (r *aiAssistantRepo) SaveState(state *models.BookingState) error {
 			date = EXCLUDED.date,
 			time = EXCLUDED.time,
 			step = EXCLUDED.step,
+			latitude = EXCLUDED.latitude,
+			longitude = EXCLUDED.longitude,
 			updated_at = NOW()
 	`
 	_, err := r.db.Exec(
@@ -155,6 +163,8 @@ func (r *aiAssistantRepo) SaveState(state *models.BookingState) error {
 		state.Date,
 		state.Time,
 		state.Step,
+		state.Latitude,
+		state.Longitude,
 	)
 	return err
 }
@@ -191,26 +201,42 @@ func (r *aiAssistantRepo) SearchServices(query string) ([]models.ServiceOption,
 	return options, rows.Err()
 }
 
-func (r *aiAssistantRepo) GetClinicOptions(serviceID string) ([]models.ClinicOption, error) {
+// GetClinicOptions lists the clinic addresses offering a service. With a
+// location the closest addresses come first and addresses without
+// coordinates last; otherwise, and among equally distant ones, the best rated.
+func (r *aiAssistantRepo) GetClinicOptions(serviceID string, location *geo.Point) ([]models.ClinicOption, error) {
+	distance := "NULL::float8"
+	args := []any{serviceID}
+	if location != nil {
+		distance = geo.DistanceSQL("a.latitude", "a.longitude", "$2", "$3")
+		args = append(args, location.Lat, location.Lng)
+	}
+
 	query := `
+		WITH prior AS (
+			SELECT COALESCE(SUM(rating_sum)::float8 / NULLIF(SUM(rating_count), 0), 0) AS mean
+			FROM clinic_rating_stats
+		)
 		SELECT
 			cs.clinic_id::text,
 			ca.id::text,
 			c.name,
 			cs.price,
 			cs.duration_minutes,
-			COALESCE(ROUND(AVG(cr.rating)::numeric, 2), 0)::float8 AS rating
+			COALESCE(ROUND(s.rating_sum::numeric / NULLIF(s.rating_count, 0), 2), 0)::float8 AS rating,
+			ROUND((` + distance + `)::numeric, 1)::float8 AS distance_km
 		FROM clinic_services cs
 		JOIN clinics c ON c.id = cs.clinic_id
 		JOIN clinic_addresses ca ON ca.clinic_id = cs.clinic_id
-		LEFT JOIN clinic_reviews cr ON cr.clinic_id = c.id
+		LEFT JOIN addresses a ON a.id = ca.address_id
+		LEFT JOIN clinic_rating_stats s ON s.clinic_id = c.id
+		CROSS JOIN prior
 		WHERE cs.service_id = $1
 			AND cs.is_active = true
 			AND c.is_active = true
-		GROUP BY cs.clinic_id, ca.id, c.name, cs.price, cs.duration_minutes
-		ORDER BY rating DESC, c.name
+		ORDER BY distance_km NULLS LAST, ` + search.RatingScore + ` DESC, c.name
 	`
-	rows, err := r.db.Query(context.Background(), query, serviceID)
+	rows, err := r.db.Query(context.Background(), query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -219,7 +245,7 @@ func (r *aiAssistantRepo) GetClinicOptions(serviceID string) ([]models.ClinicOpt
 	options := make([]models.ClinicOption, 0)
 	for rows.Next() {
 		var option models.ClinicOption
-		if err := rows.Scan(&option.ClinicID, &option.ClinicAddressID, &option.ClinicName, &option.Price, &option.Duration, &option.Rating); err != nil {
+		if err := rows.Scan(&option.ClinicID, &option.ClinicAddressID, &option.ClinicName, &option.Price, &option.Duration, &option.Rating, &option.DistanceKm); err != nil {
 			return nil, err
 		}
 		options = append(options, option)
@@ -227,24 +253,33 @@ func (r *aiAssistantRepo) GetClinicOptions(serviceID string) ([]models.ClinicOpt
 	return options, rows.Err()
 }
 
+// GetDoctorOptions returns the doctors working at the clinic of the address
+// who are assigned to the service, best rated first.
 func (r *aiAssistantRepo) GetDoctorOptions(serviceID, clinicAddressID string) ([]models.DoctorOption, error) {
 	query := `
-		SELECT
+		WITH prior AS (
+			SELECT COALESCE(SUM(rating_sum)::float8 / NULLIF(SUM(rating_count), 0), 0) AS mean
+			FROM doctor_rating_stats
+		)
+		SELECT DISTINCT
 			d.id::text,
 			d.name,
 			d.specialization,
 			d.experience,
-			COALESCE(ROUND(AVG(dr.rating)::numeric, 2), 0)::float8 AS rating
+			COALESCE(ROUND(s.rating_sum::numeric / NULLIF(s.rating_count, 0), 2), 0)::float8 AS rating,
+			` + search.RatingScore + ` AS score
 		FROM doctors d
-		JOIN clinic_addresses ca ON ca.clinic_id = d.clinic_id
-		JOIN clinic_services cs ON cs.clinic_id = d.clinic_id
-		LEFT JOIN doctor_ratings dr ON dr.doctor_id = d.id
+		JOIN doctor_clinics dc ON dc.doctor_id = d.id AND dc.status = 'active'
+		JOIN clinic_addresses ca ON ca.clinic_id = dc.clinic_id
+		JOIN clinic_services cs ON cs.clinic_id = dc.clinic_id
+		LEFT JOIN doctor_rating_stats s ON s.doctor_id = d.id
+		CROSS JOIN prior
 		WHERE ca.id = $1
 			AND cs.service_id = $2
 			AND d.is_available = true
 			AND d.is_deleted = 0
-		GROUP BY d.id, d.name, d.specialization, d.experience
-		ORDER BY rating DESC, d.name
+			AND EXISTS (SELECT 1 FROM doctor_services ds WHERE ds.doctor_id = d.id AND ds.clinic_service_id = cs.id)
+		ORDER BY score DESC, d.name
 	`
 	rows, err := r.db.Query(context.Background(), query, clinicAddressID, serviceID)
 	if err != nil {
@@ -255,7 +290,8 @@ func (r *aiAssistantRepo) GetDoctorOptions(serviceID, clinicAddressID string) ([
 	options := make([]models.DoctorOption, 0)
 	for rows.Next() {
 		var option models.DoctorOption
-		if err := rows.Scan(&option.Id, &option.Name, &option.Specialization, &option.Experience, &option.Rating); err != nil {
+		var score float64
+		if err := rows.Scan(&option.Id, &option.Name, &option.Specialization, &option.Experience, &option.Rating, &score); err != nil {
 			return nil, err
 		}
 		options = append(options, option)
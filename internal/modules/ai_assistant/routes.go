@@ -5,6 +5,7 @@ import (
 	"dental_clinic/internal/modules/ai_assistant/handlers"
 	aiRepository "dental_clinic/internal/modules/ai_assistant/repository"
 	"dental_clinic/internal/modules/ai_assistant/services"
+	"dental_clinic/internal/queue"
 
 	addressRepository "dental_clinic/internal/modules/address/repository"
 	addressServices "dental_clinic/internal/modules/address/services"
@@ -12,14 +13,20 @@ import (
 	appointmentServices "dental_clinic/internal/modules/appointment/services"
 	clinicRepository "dental_clinic/internal/modules/clinic/repository"
 	clinicServices "dental_clinic/internal/modules/clinic/services"
+	inventoryRepository "dental_clinic/internal/modules/inventory/repository"
+	inventoryServices "dental_clinic/internal/modules/inventory/services"
 	medicalRecordRepository "dental_clinic/internal/modules/medical_record/repository"
 	medicalRecordServices "dental_clinic/internal/modules/medical_record/services"
+	pricingRepository "dental_clinic/internal/modules/pricing/repository"
+	pricingServices "dental_clinic/internal/modules/pricing/services"
 	reviewRepository "dental_clinic/internal/modules/reviews/repository"
 	reviewServices "dental_clinic/internal/modules/reviews/services"
 	scheduleRepository "dental_clinic/internal/modules/schedule/repository"
 	scheduleServices "dental_clinic/internal/modules/schedule/services"
 	serviceRepository "dental_clinic/internal/modules/services/repository"
 	serviceServices "dental_clinic/internal/modules/services/services"
+	treatmentPlanRepository "dental_clinic/internal/modules/treatment_plan/repository"
+	treatmentPlanServices "dental_clinic/internal/modules/treatment_plan/services"
 	userRepository "dental_clinic/internal/modules/user/repository"
 	userServices "dental_clinic/internal/modules/user/services"
 
@@ -28,6 +35,8 @@ import (
 )
 
 func RegisterPrivateRoutes(r *mux.Router, db *pgxpool.Pool, cfg *config.Config) {
+	jobQueue := queue.New(db)
+
 	addressRepo := addressRepository.NewAddressRepository(db)
 	addressService := addressServices.NewAddressService(addressRepo, *cfg)
 
@@ -38,7 +47,7 @@ func RegisterPrivateRoutes(r *mux.Router, db *pgxpool.Pool, cfg *config.Config)
 	serviceService := serviceServices.NewServiceService(serviceRepo, *clinicService)
 
 	scheduleRepo := scheduleRepository.NewScheduleRepository(db)
-	scheduleService := scheduleServices.NewScheduleService(scheduleRepo, *cfg, *serviceService, *clinicService)
+	scheduleService := scheduleServices.NewScheduleService(scheduleRepo, *cfg, *serviceService, *clinicService, jobQueue)
 
 	medicalRecordRepo := medicalRecordRepository.NewMedicalRecordRepository(db)
 	medicalRecordService := medicalRecordServices.NewMedicalRecordService(medicalRecordRepo)
@@ -46,11 +55,20 @@ func RegisterPrivateRoutes(r *mux.Router, db *pgxpool.Pool, cfg *config.Config)
 	reviewRepo := reviewRepository.NewReviewRepository(db)
 	reviewService := reviewServices.NewReviewService(reviewRepo)
 
+	treatmentPlanRepo := treatmentPlanRepository.NewTreatmentPlanRepository(db)
+	treatmentPlanService := treatmentPlanServices.NewTreatmentPlanService(treatmentPlanRepo, db, *cfg, *medicalRecordService)
+
+	pricingRepo := pricingRepository.NewPricingRepository(db)
+	pricingService := pricingServices.NewPricingService(pricingRepo, db, *serviceService, *clinicService)
+
+	inventoryRepo := inventoryRepository.NewInventoryRepository(db)
+	inventoryService := inventoryServices.NewInventoryService(inventoryRepo, db)
+
 	appointmentRepo := appointmentRepository.NewAppointmentRepository(db)
-	appointmentService := appointmentServices.NewAppointmentService(appointmentRepo, db, *cfg, *scheduleService, *serviceService, *medicalRecordService, *clinicService, reviewService)
+	appointmentService := appointmentServices.NewAppointmentService(appointmentRepo, db, *cfg, *scheduleService, *serviceService, *medicalRecordService, *clinicService, reviewService, treatmentPlanService, pricingService, inventoryService)
 
 	userRepo := userRepository.NewUserRepository(db)
-	userService := userServices.NewUserService(userRepo, *cfg)
+	userService := userServices.NewUserService(userRepo, *cfg, jobQueue)
 
 	assistantRepo := aiRepository.NewAIAssistantRepository(db)
 	llmClient := services.NewOpenAIClient(*cfg)
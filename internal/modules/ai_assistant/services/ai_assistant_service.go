@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"dental_clinic/internal/config"
+	"dental_clinic/internal/geo"
 	aiDto "dental_clinic/internal/modules/ai_assistant/dto"
 	"dental_clinic/internal/modules/ai_assistant/models"
 	"dental_clinic/internal/modules/ai_assistant/repository"
@@ -79,6 +80,13 @@ func (s *AIAssistantService) ProcessMessage(userID uuid.UUID, tokenStr string, r
 	if err != nil {
 		return response, err
 	}
+	if req.Latitude != nil && req.Longitude != nil {
+		if !(geo.Point{Lat: *req.Latitude, Lng: *req.Longitude}).Valid() {
+			return response, geo.ErrInvalidPoint
+		}
+		state.Latitude = req.Latitude
+		state.Longitude = req.Longitude
+	}
 
 	extraction := BookingExtraction{}
 	if req.ChoiceID != "" {
@@ -244,7 +252,7 @@ func (s *AIAssistantService) nextStep(userID uuid.UUID, tokenStr string, session
 	}
 
 	if state.ClinicAddressID == "" {
-		clinics, err := s.repo.GetClinicOptions(state.ServiceID)
+		clinics, err := s.repo.GetClinicOptions(state.ServiceID, state.Location())
 		if err != nil {
 			return response, err
 		}
@@ -259,6 +267,9 @@ func (s *AIAssistantService) nextStep(userID uuid.UUID, tokenStr string, session
 		} else {
 			state.Step = "collect_clinic"
 			response.Reply = "Which clinic do you prefer?"
+			if state.Location() != nil {
+				response.Reply = "Which clinic do you prefer? The closest ones are listed first."
+			}
 			response.ChoiceRequired = true
 			response.ChoiceType = "clinic"
 			response.Clinics = clinics
@@ -10,29 +10,50 @@ type CreateAppointmentRequest struct {
 	Date              string `json:"date"`
 	Name              string `json:"name"`
 	Email             string `json:"email"`
+
+	// TreatmentPlanItemId books an accepted item of the patient's treatment plan
+	TreatmentPlanItemId string `json:"treatment_plan_item_id"`
+
+	PromoCode string `json:"promo_code"`
 }
 
 type CreateAppointmentResponse struct {
-	Success        string `json:"success"`
-	Message        string `json:"message"`
-	Appointment_id string `json:"appointment_id"`
+	Success        string  `json:"success"`
+	Message        string  `json:"message"`
+	Appointment_id string  `json:"appointment_id"`
+	ChargedPrice   float64 `json:"charged_price"`
+
+	// Warnings name required materials that are not available at the clinic
+	// address
+	Warnings []string `json:"warnings,omitempty"`
 }
 
 type GetAppointmentsResponse struct {
-	Id                string `json:"id"`
-	Doctor_id         string `json:"doctor_id"`
-	Clinic_address_id string `json:"clinic_address_id"`
-	Service_id        string `json:"service_id"`
-	User_id           string `json:"user_id"`
-	Start_time        string `json:"start_time"`
-	End_time          string `json:"end_time"`
-	Status            string `json:"status"`
-	Name              string `json:"name"`
-	Email             string `json:"email"`
-	IsReviewed        bool   `json:"is_reviewed"`
-	DoctorRating      int    `json:"doctor_rating"`
-	ClinicRating      int    `json:"clinic_rating"`
-	ClinicComment     string `json:"clinic_comment"`
+	Id                string   `json:"id"`
+	Doctor_id         string   `json:"doctor_id"`
+	Clinic_address_id string   `json:"clinic_address_id"`
+	Service_id        string   `json:"service_id"`
+	User_id           string   `json:"user_id"`
+	Start_time        string   `json:"start_time"`
+	End_time          string   `json:"end_time"`
+	Status            string   `json:"status"`
+	Confirmed_at      string   `json:"confirmed_at,omitempty"`
+	Name              string   `json:"name"`
+	Email             string   `json:"email"`
+	IsReviewed        bool     `json:"is_reviewed"`
+	ListPrice         float64  `json:"list_price"`
+	DiscountAmount    float64  `json:"discount_amount"`
+	Warnings          []string `json:"warnings,omitempty"`
+
+	MaterialsUsed []UsedMaterialResponse `json:"materials_used,omitempty"`
+	// MaterialCost is estimated from the service's materials until the
+	// materials are used
+	MaterialCost          *float64 `json:"material_cost,omitempty"`
+	MaterialCostEstimated bool     `json:"material_cost_estimated,omitempty"`
+	ChargedPrice          float64  `json:"charged_price"`
+	DoctorRating          int      `json:"doctor_rating"`
+	ClinicRating          int      `json:"clinic_rating"`
+	ClinicComment         string   `json:"clinic_comment"`
 }
 
 type AppointmentResponse struct {
@@ -60,7 +81,59 @@ type GetMedicalRecordAppointmentResponse struct {
 }
 
 type CreateAppointmentReviewRequest struct {
-	DoctorRating  int    `json:"doctor_rating"`
-	ClinicRating  int    `json:"clinic_rating"`
-	ClinicComment string `json:"clinic_comment"`
+	DoctorRating  int            `json:"doctor_rating"`
+	DoctorComment string         `json:"doctor_comment"`
+	ClinicRating  int            `json:"clinic_rating"`
+	ClinicComment string         `json:"clinic_comment"`
+	Dimensions    map[string]int `json:"dimensions"`
+}
+
+type AppointmentReviewEditResponse struct {
+	DoctorRating  int            `json:"doctor_rating"`
+	DoctorComment string         `json:"doctor_comment"`
+	ClinicRating  int            `json:"clinic_rating"`
+	ClinicComment string         `json:"clinic_comment"`
+	Dimensions    map[string]int `json:"dimensions"`
+	EditedAt      string         `json:"edited_at"`
+}
+
+type AppointmentReviewResponse struct {
+	AppointmentId string                          `json:"appointment_id"`
+	DoctorRating  int                             `json:"doctor_rating"`
+	DoctorComment string                          `json:"doctor_comment"`
+	ClinicRating  int                             `json:"clinic_rating"`
+	ClinicComment string                          `json:"clinic_comment"`
+	Dimensions    map[string]int                  `json:"dimensions"`
+	CreatedAt     string                          `json:"created_at"`
+	UpdatedAt     string                          `json:"updated_at,omitempty"`
+	EditableUntil string                          `json:"editable_until"`
+	Editable      bool                            `json:"editable"`
+	Edits         []AppointmentReviewEditResponse `json:"edits"`
+}
+
+type UsedMaterialResponse struct {
+	ProductName string   `json:"product_name"`
+	ProductUnit string   `json:"product_unit"`
+	LotNumber   string   `json:"lot_number,omitempty"`
+	ExpiresAt   string   `json:"expires_at,omitempty"`
+	Quantity    float64  `json:"quantity"`
+	UnitCost    *float64 `json:"unit_cost,omitempty"`
+	Cost        float64  `json:"cost"`
+}
+
+// AppointmentActionRequest carries the token of a confirm or cancel link
+// from a reminder.
+type AppointmentActionRequest struct {
+	Token string `json:"token"`
+}
+
+type AppointmentActionResponse struct {
+	Success        string `json:"success"`
+	Message        string `json:"message"`
+	Appointment_id string `json:"appointment_id,omitempty"`
+	// Action is confirm or cancel
+	Action       string `json:"action,omitempty"`
+	Status       string `json:"status,omitempty"`
+	Start_time   string `json:"start_time,omitempty"`
+	Confirmed_at string `json:"confirmed_at,omitempty"`
 }
@@ -8,6 +8,7 @@ import (
 
 	"dental_clinic/internal/config"
 	"dental_clinic/internal/modules/appointment/dto"
+	"dental_clinic/internal/modules/appointment/models"
 	"dental_clinic/internal/modules/appointment/services"
 	"dental_clinic/internal/utils"
 
@@ -30,7 +31,7 @@ func NewAppointmentHandler(s *services.AppointmentService, cfg config.Config) *A
 
 // CreateAppointment godoc
 // @Summary Create new appointment
-// @Description Creates a new appointment
+// @Description Creates a new appointment. The price, after price rules and the first-visit or promo code discount, is locked on the appointment. The service's materials are reserved at the clinic address; when they are not available the booking is refused or comes back with warnings, depending on the clinic's shortage policy.
 // @Tags Appointment
 // @Accept  json
 // @Produce  json
@@ -66,6 +67,8 @@ func (h *AppointmentHandler) CreateAppointment(w http.ResponseWriter, r *http.Re
 	response.Success = "1"
 	response.Message = "successfully created"
 	response.Appointment_id = appointment.Id.String()
+	response.ChargedPrice = appointment.ChargedPrice
+	response.Warnings = appointment.StockWarnings
 
 	w.WriteHeader(http.StatusOK)
 	_ = json.NewEncoder(w).Encode(response)
@@ -98,7 +101,7 @@ func (h *AppointmentHandler) GetAllAppointments(w http.ResponseWriter, r *http.R
 
 // GetAppointmentByID godoc
 // @Summary Get appointment by ID
-// @Description Returns a single appointment by UUID
+// @Description Returns a single appointment by UUID with the materials used on it and the lots they came from
 // @Tags Appointment
 // @Security BearerAuth
 // @Produce json
@@ -123,7 +126,7 @@ func (h *AppointmentHandler) GetAppointmentByID(w http.ResponseWriter, r *http.R
 
 // UpdateAppointment godoc
 // @Summary Update appointment
-// @Description Updates an existing appointment by UUID
+// @Description Updates an existing appointment by UUID. Cancelling it releases its reserved materials; moving it to another address or service reserves them anew. Changing the doctor, address or service prices the appointment again, keeping its promo code
 // @Tags Appointment
 // @Security BearerAuth
 // @Accept json
@@ -246,6 +249,66 @@ func (h *AppointmentHandler) GetMedicalRecord(w http.ResponseWriter, r *http.Req
 	_ = json.NewEncoder(w).Encode(medical_record)
 }
 
+// GetAppointmentAction godoc
+// @Summary Preview a reminder link
+// @Description Shows the appointment a confirm or cancel link from a reminder is for and what the link would do. Links stop working when the appointment starts or is moved.
+// @Tags Appointment
+// @Produce json
+// @Param token query string true "Token from the reminder link"
+// @Success 200 {object} dto.AppointmentActionResponse
+// @Failure 400 {object} dto.AppointmentActionResponse
+// @Router /api/appointments/respond [get]
+func (h *AppointmentHandler) GetAppointmentAction(w http.ResponseWriter, r *http.Request) {
+	appointment, action, err := h.service.GetAppointmentAction(r.URL.Query().Get("token"))
+	h.respondAppointmentAction(w, appointment, action, err)
+}
+
+// RespondToAppointment godoc
+// @Summary Confirm or cancel from a reminder
+// @Description Confirms or cancels a booked appointment with the token of a link from a reminder. Cancelling frees the doctor's slots and releases the reserved materials.
+// @Tags Appointment
+// @Accept json
+// @Produce json
+// @Param request body dto.AppointmentActionRequest true "Token from the reminder link"
+// @Success 200 {object} dto.AppointmentActionResponse
+// @Failure 400 {object} dto.AppointmentActionResponse
+// @Router /api/appointments/respond [post]
+func (h *AppointmentHandler) RespondToAppointment(w http.ResponseWriter, r *http.Request) {
+	var req dto.AppointmentActionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondAppointmentAction(w, nil, "", errors.New("Invalid request body"))
+		return
+	}
+	defer r.Body.Close()
+
+	appointment, action, err := h.service.RespondToAppointment(req.Token, r.Context())
+	h.respondAppointmentAction(w, appointment, action, err)
+}
+
+func (h *AppointmentHandler) respondAppointmentAction(w http.ResponseWriter, appointment *models.Appointment, action string, err error) {
+	response := dto.AppointmentActionResponse{
+		Success: "0",
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		response.Message = err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	appointmentResponse := services.ToAppointmentResponse(*appointment)
+	response.Success = "1"
+	response.Message = "ok"
+	response.Appointment_id = appointmentResponse.Id
+	response.Action = action
+	response.Status = appointmentResponse.Status
+	response.Start_time = appointmentResponse.Start_time
+	response.Confirmed_at = appointmentResponse.Confirmed_at
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(response)
+}
+
 // CreateAppointmentReview godoc
 // @Summary Create appointment review
 // @Description Creates doctor rating and clinic review for an appointment
@@ -289,3 +352,73 @@ func (h *AppointmentHandler) CreateAppointmentReview(w http.ResponseWriter, r *h
 	w.WriteHeader(http.StatusOK)
 	_ = json.NewEncoder(w).Encode(response)
 }
+
+// UpdateAppointmentReview godoc
+// @Summary Edit appointment review
+// @Description Replaces the patient's review of an appointment within 14 days of submitting it. The previous version is kept in the edit history.
+// @Tags Appointment
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param appointmentId path string true "Appointment ID"
+// @Param request body dto.CreateAppointmentReviewRequest true "Appointment review data"
+// @Success 200 {object} dto.AppointmentResponse
+// @Failure 400 {object} dto.AppointmentResponse
+// @Router /api/appointments/{appointmentId}/review [put]
+func (h *AppointmentHandler) UpdateAppointmentReview(w http.ResponseWriter, r *http.Request) {
+	response := dto.AppointmentResponse{
+		Success: "0",
+		Message: "",
+	}
+
+	vars := mux.Vars(r)
+	appointmentId := vars["appointmentId"]
+
+	var req dto.CreateAppointmentReviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Message = "Invalid request body"
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(response)
+		return
+	}
+	defer r.Body.Close()
+
+	tokenStr := utils.GetToken(r)
+	if err := h.service.UpdateAppointmentReview(tokenStr, appointmentId, req, r.Context()); err != nil {
+		response.Message = err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response.Success = "1"
+	response.Message = "successfully updated"
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// GetAppointmentReview godoc
+// @Summary Get appointment review
+// @Description Returns the patient's review of an appointment with dimension scores and edit history
+// @Tags Appointment
+// @Security BearerAuth
+// @Produce json
+// @Param appointmentId path string true "Appointment ID"
+// @Success 200 {object} dto.AppointmentReviewResponse
+// @Failure 400 {object} dto.AppointmentResponse
+// @Router /api/appointments/{appointmentId}/review [get]
+func (h *AppointmentHandler) GetAppointmentReview(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	appointmentId := vars["appointmentId"]
+
+	tokenStr := utils.GetToken(r)
+	review, err := h.service.GetAppointmentReview(tokenStr, appointmentId)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(dto.AppointmentResponse{Success: "0", Message: err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(review)
+}
@@ -22,7 +22,50 @@ type Appointment struct {
 	Email      string
 	IsReviewed bool
 
+	// ConfirmedAt is when the patient confirmed from a reminder
+	ConfirmedAt *time.Time
+
+	// prices locked at booking time
+	ListPrice      float64
+	DiscountAmount float64
+	ChargedPrice   float64
+	PriceRuleId    *uuid.UUID
+	PromoCodeId    *uuid.UUID
+	DiscountReason string
+
+	// StockWarnings name materials the appointment needs more of than the
+	// clinic address has available; they are not stored
+	StockWarnings []string
+
+	// MaterialsUsed is filled for a single appointment once it is completed
+	MaterialsUsed []UsedMaterial
+	// MaterialCost is filled for a single appointment: what the materials
+	// used cost or, before any are used, what the service's materials are
+	// expected to cost (MaterialCostEstimated)
+	MaterialCost          *float64
+	MaterialCostEstimated bool
+
 	DoctorRating  int
 	ClinicRating  int
 	ClinicComment string
 }
+
+// UsedMaterial is a material that went into the appointment and the lot it
+// was taken from. LotNumber is empty for stock not tracked by lot. UnitCost
+// is nil when the cost of the stock is not known.
+type UsedMaterial struct {
+	ProductName string
+	ProductUnit string
+	LotNumber   string
+	ExpiresAt   *time.Time
+	Quantity    float64
+	UnitCost    *float64
+}
+
+// Cost is what the quantity used cost, 0 when the unit cost is not known.
+func (m UsedMaterial) Cost() float64 {
+	if m.UnitCost == nil {
+		return 0
+	}
+	return m.Quantity * *m.UnitCost
+}
@@ -17,11 +17,17 @@ type AppointmentRepository interface {
 	CreateTx(appointment *models.Appointment, tx pgx.Tx) (*models.Appointment, error)
 	GetAll() ([]models.Appointment, error)
 	GetByID(id string) (*models.Appointment, error)
-	Update(appointment *models.Appointment) (*models.Appointment, error)
+	UpdateTx(appointment *models.Appointment, tx pgx.Tx) (*models.Appointment, error)
+	UpdatePriceTx(appointment *models.Appointment, tx pgx.Tx) error
 	Delete(id string) error
 	GetMyAppointments(userId string) ([]models.Appointment, error)
 	MarkReviewedTx(id string, tx pgx.Tx) error
 	MarkExpiredBookedCompleted(ctx context.Context) (int64, error)
+	Confirm(id string) error
+	CancelTx(id string, tx pgx.Tx) error
+	FreeSlotsTx(appointment *models.Appointment, tx pgx.Tx) error
+	GetUsedMaterials(id string) ([]models.UsedMaterial, error)
+	GetExpectedMaterialCost(id string) (float64, error)
 }
 
 type appointmentRepo struct {
@@ -41,9 +47,11 @@ func (r *appointmentRepo) Create(appointment *models.Appointment) (*models.Appoi
 }
 
 func (r *appointmentRepo) CreateTx(appointment *models.Appointment, tx pgx.Tx) (*models.Appointment, error) {
-	query := `INSERT INTO appointments (id, doctor_id, clinic_address_id, service_id, user_id, start_time, end_time, status, created_at, name, email)
-			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id`
-	err := tx.QueryRow(context.Background(), query, appointment.Id, appointment.Doctor_id, appointment.Clinic_address_id, appointment.Service_id, appointment.User_id, appointment.Start_time, appointment.End_time, appointment.Status, appointment.Created_at, appointment.Name, appointment.Email).
+	query := `INSERT INTO appointments (id, doctor_id, clinic_address_id, service_id, user_id, start_time, end_time, status, created_at, name, email,
+				list_price, discount_amount, charged_price, price_rule_id, promo_code_id, discount_reason)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, NULLIF($17, '')) RETURNING id`
+	err := tx.QueryRow(context.Background(), query, appointment.Id, appointment.Doctor_id, appointment.Clinic_address_id, appointment.Service_id, appointment.User_id, appointment.Start_time, appointment.End_time, appointment.Status, appointment.Created_at, appointment.Name, appointment.Email,
+		appointment.ListPrice, appointment.DiscountAmount, appointment.ChargedPrice, appointment.PriceRuleId, appointment.PromoCodeId, appointment.DiscountReason).
 		Scan(&appointment.Id)
 
 	if err != nil {
@@ -67,6 +75,9 @@ func (r *appointmentRepo) GetAll() ([]models.Appointment, error) {
 			a.name,
 			a.email,
 			a.is_reviewed,
+			COALESCE(a.list_price, 0)::float8,
+			a.discount_amount::float8,
+			COALESCE(a.charged_price, 0)::float8,
 			COALESCE(dr.rating, 0),
 			COALESCE(cr.rating, 0),
 			COALESCE(cr.comment, '')
@@ -84,7 +95,7 @@ func (r *appointmentRepo) GetAll() ([]models.Appointment, error) {
 	var appointments []models.Appointment
 	for rows.Next() {
 		var appointment models.Appointment
-		if err := rows.Scan(&appointment.Id, &appointment.Doctor_id, &appointment.Clinic_address_id, &appointment.Service_id, &appointment.User_id, &appointment.Start_time, &appointment.End_time, &appointment.Status, &appointment.Name, &appointment.Email, &appointment.IsReviewed, &appointment.DoctorRating, &appointment.ClinicRating, &appointment.ClinicComment); err != nil {
+		if err := rows.Scan(&appointment.Id, &appointment.Doctor_id, &appointment.Clinic_address_id, &appointment.Service_id, &appointment.User_id, &appointment.Start_time, &appointment.End_time, &appointment.Status, &appointment.Name, &appointment.Email, &appointment.IsReviewed, &appointment.ListPrice, &appointment.DiscountAmount, &appointment.ChargedPrice, &appointment.DoctorRating, &appointment.ClinicRating, &appointment.ClinicComment); err != nil {
 			return nil, err
 		}
 		appointments = append(appointments, appointment)
@@ -111,9 +122,14 @@ func (r *appointmentRepo) GetByID(id string) (*models.Appointment, error) {
 			a.name,
 			a.email,
 			a.is_reviewed,
+			COALESCE(a.list_price, 0)::float8,
+			a.discount_amount::float8,
+			COALESCE(a.charged_price, 0)::float8,
+			a.promo_code_id,
 			COALESCE(dr.rating, 0),
 			COALESCE(cr.rating, 0),
-			COALESCE(cr.comment, '')
+			COALESCE(cr.comment, ''),
+			a.confirmed_at
 		FROM appointments a
 		LEFT JOIN doctor_ratings dr ON dr.appointment_id = a.id
 		LEFT JOIN clinic_reviews cr ON cr.appointment_id = a.id
@@ -125,7 +141,9 @@ func (r *appointmentRepo) GetByID(id string) (*models.Appointment, error) {
 		&appointment.Id, &appointment.Doctor_id, &appointment.Clinic_address_id, &appointment.Service_id,
 		&appointment.User_id, &appointment.Start_time, &appointment.End_time, &appointment.Status,
 		&appointment.Name, &appointment.Email, &appointment.IsReviewed,
+		&appointment.ListPrice, &appointment.DiscountAmount, &appointment.ChargedPrice, &appointment.PromoCodeId,
 		&appointment.DoctorRating, &appointment.ClinicRating, &appointment.ClinicComment,
+		&appointment.ConfirmedAt,
 	)
 	if err != nil {
 		if err == pgx.ErrNoRows {
@@ -136,21 +154,22 @@ func (r *appointmentRepo) GetByID(id string) (*models.Appointment, error) {
 	return &appointment, nil
 }
 
-func (r *appointmentRepo) Update(appointment *models.Appointment) (*models.Appointment, error) {
+func (r *appointmentRepo) UpdateTx(appointment *models.Appointment, tx pgx.Tx) (*models.Appointment, error) {
 	query := `
 		UPDATE appointments
-		SET doctor_id=$1, clinic_address_id=$2, service_id=$3, start_time=$4, end_time=$5, status=$6, name=$7, email=$8
+		SET doctor_id=$1, clinic_address_id=$2, service_id=$3, start_time=$4, end_time=$5, status=$6, name=$7, email=$8,
+			confirmed_at = CASE WHEN start_time = $4 THEN confirmed_at END
 		WHERE id=$9
-		RETURNING id, doctor_id, clinic_address_id, service_id, user_id, start_time, end_time, status, name, email, is_reviewed
+		RETURNING id, doctor_id, clinic_address_id, service_id, user_id, start_time, end_time, status, name, email, is_reviewed, confirmed_at
 	`
-	err := r.db.QueryRow(context.Background(), query,
+	err := tx.QueryRow(context.Background(), query,
 		appointment.Doctor_id, appointment.Clinic_address_id, appointment.Service_id,
 		appointment.Start_time, appointment.End_time, appointment.Status,
 		appointment.Name, appointment.Email, appointment.Id,
 	).Scan(
 		&appointment.Id, &appointment.Doctor_id, &appointment.Clinic_address_id, &appointment.Service_id,
 		&appointment.User_id, &appointment.Start_time, &appointment.End_time, &appointment.Status,
-		&appointment.Name, &appointment.Email, &appointment.IsReviewed,
+		&appointment.Name, &appointment.Email, &appointment.IsReviewed, &appointment.ConfirmedAt,
 	)
 	if err != nil {
 		if err == pgx.ErrNoRows {
@@ -161,6 +180,26 @@ func (r *appointmentRepo) Update(appointment *models.Appointment) (*models.Appoi
 	return appointment, nil
 }
 
+// UpdatePriceTx replaces the locked price of the appointment.
+func (r *appointmentRepo) UpdatePriceTx(appointment *models.Appointment, tx pgx.Tx) error {
+	query := `
+		UPDATE appointments
+		SET list_price=$1, discount_amount=$2, charged_price=$3, price_rule_id=$4, promo_code_id=$5, discount_reason=NULLIF($6, '')
+		WHERE id=$7
+	`
+	result, err := tx.Exec(context.Background(), query,
+		appointment.ListPrice, appointment.DiscountAmount, appointment.ChargedPrice,
+		appointment.PriceRuleId, appointment.PromoCodeId, appointment.DiscountReason, appointment.Id,
+	)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
 func (r *appointmentRepo) Delete(id string) error {
 	query := `DELETE FROM appointments WHERE id=$1`
 	result, err := r.db.Exec(context.Background(), query, id)
@@ -187,6 +226,9 @@ func (r *appointmentRepo) GetMyAppointments(userId string) ([]models.Appointment
 				a.name,
 				a.email,
 				a.is_reviewed,
+				COALESCE(a.list_price, 0)::float8,
+				a.discount_amount::float8,
+				COALESCE(a.charged_price, 0)::float8,
 				COALESCE(dr.rating, 0),
 				COALESCE(cr.rating, 0),
 				COALESCE(cr.comment, '')
@@ -205,7 +247,7 @@ func (r *appointmentRepo) GetMyAppointments(userId string) ([]models.Appointment
 	var appointments []models.Appointment
 	for rows.Next() {
 		var appointment models.Appointment
-		if err := rows.Scan(&appointment.Id, &appointment.Doctor_id, &appointment.Clinic_address_id, &appointment.Service_id, &appointment.User_id, &appointment.Start_time, &appointment.End_time, &appointment.Status, &appointment.Name, &appointment.Email, &appointment.IsReviewed, &appointment.DoctorRating, &appointment.ClinicRating, &appointment.ClinicComment); err != nil {
+		if err := rows.Scan(&appointment.Id, &appointment.Doctor_id, &appointment.Clinic_address_id, &appointment.Service_id, &appointment.User_id, &appointment.Start_time, &appointment.End_time, &appointment.Status, &appointment.Name, &appointment.Email, &appointment.IsReviewed, &appointment.ListPrice, &appointment.DiscountAmount, &appointment.ChargedPrice, &appointment.DoctorRating, &appointment.ClinicRating, &appointment.ClinicComment); err != nil {
 			return nil, err
 		}
 		appointments = append(appointments, appointment)
@@ -243,3 +285,102 @@ func (r *appointmentRepo) MarkExpiredBookedCompleted(ctx context.Context) (int64
 	}
 	return result.RowsAffected(), nil
 }
+
+// Confirm records that the patient confirmed the booked appointment. It
+// keeps the time of the first confirmation.
+func (r *appointmentRepo) Confirm(id string) error {
+	query := `
+		UPDATE appointments
+		SET confirmed_at = COALESCE(confirmed_at, NOW())
+		WHERE id = $1 AND status = 'booked'
+	`
+	result, err := r.db.Exec(context.Background(), query, id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *appointmentRepo) CancelTx(id string, tx pgx.Tx) error {
+	query := `UPDATE appointments SET status = 'cancelled' WHERE id = $1 AND status = 'booked'`
+	result, err := tx.Exec(context.Background(), query, id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// FreeSlotsTx makes the doctor's slots the appointment took available again.
+func (r *appointmentRepo) FreeSlotsTx(appointment *models.Appointment, tx pgx.Tx) error {
+	query := `
+		UPDATE doctor_time_slots
+		SET status = 'available'
+		WHERE doctor_id = $1
+			AND clinic_address_id = $2
+			AND slot_start >= $3
+			AND slot_end <= $4
+			AND status = 'booked'
+	`
+	_, err := tx.Exec(context.Background(), query, appointment.Doctor_id, appointment.Clinic_address_id, appointment.Start_time, appointment.End_time)
+	return err
+}
+
+// GetUsedMaterials lists the materials taken out of stock for the
+// appointment with the lots they came from. Stock used without a cost is
+// costed at the average cost of the product at the address.
+func (r *appointmentRepo) GetUsedMaterials(id string) ([]models.UsedMaterial, error) {
+	query := `
+		SELECT p.name, p.unit, COALESCE(l.lot_number, ''), l.expires_at, ABS(it.quantity)::float8,
+			COALESCE(it.unit_cost, pac.unit_cost)::float8
+		FROM inventory_transactions it
+		JOIN products p ON p.id = it.product_id
+		LEFT JOIN inventory_lots l ON l.id = it.lot_id
+		LEFT JOIN product_average_costs pac ON pac.clinic_address_id = it.clinic_address_id
+			AND pac.product_id = it.product_id
+		WHERE it.appointment_id = $1
+			AND it.transaction_type = 'used'
+		ORDER BY p.name, l.lot_number
+	`
+	rows, err := r.db.Query(context.Background(), query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	materials := make([]models.UsedMaterial, 0)
+	for rows.Next() {
+		var material models.UsedMaterial
+		if err := rows.Scan(&material.ProductName, &material.ProductUnit, &material.LotNumber, &material.ExpiresAt, &material.Quantity, &material.UnitCost); err != nil {
+			return nil, err
+		}
+		materials = append(materials, material)
+	}
+	return materials, rows.Err()
+}
+
+// GetExpectedMaterialCost costs the materials the appointment's service
+// requires at their average cost at the address. Materials without a known
+// cost count as free.
+func (r *appointmentRepo) GetExpectedMaterialCost(id string) (float64, error) {
+	query := `
+		SELECT COALESCE(SUM(sm.quantity_required * pac.unit_cost), 0)::float8
+		FROM appointments a
+		JOIN clinic_addresses ca ON ca.id = a.clinic_address_id
+		JOIN clinic_services cs ON cs.clinic_id = ca.clinic_id
+			AND cs.service_id = a.service_id
+			AND cs.is_active = true
+		JOIN service_materials sm ON sm.service_id = cs.id
+		LEFT JOIN product_average_costs pac ON pac.clinic_address_id = a.clinic_address_id
+			AND pac.product_id = sm.product_id
+		WHERE a.id = $1
+	`
+	var cost float64
+	err := r.db.QueryRow(context.Background(), query, id).Scan(&cost)
+	return cost, err
+}
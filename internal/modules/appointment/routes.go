@@ -7,6 +7,7 @@ import (
 	"dental_clinic/internal/modules/appointment/handlers"
 	"dental_clinic/internal/modules/appointment/repository"
 	"dental_clinic/internal/modules/appointment/services"
+	"dental_clinic/internal/queue"
 
 	scheduleRepository "dental_clinic/internal/modules/schedule/repository"
 	scheduleServices "dental_clinic/internal/modules/schedule/services"
@@ -26,10 +27,21 @@ import (
 	reviewRepository "dental_clinic/internal/modules/reviews/repository"
 	reviewServices "dental_clinic/internal/modules/reviews/services"
 
+	treatment_planRepository "dental_clinic/internal/modules/treatment_plan/repository"
+	treatment_planServices "dental_clinic/internal/modules/treatment_plan/services"
+
+	pricingRepository "dental_clinic/internal/modules/pricing/repository"
+	pricingServices "dental_clinic/internal/modules/pricing/services"
+
+	inventoryRepository "dental_clinic/internal/modules/inventory/repository"
+	inventoryServices "dental_clinic/internal/modules/inventory/services"
+
 	"github.com/gorilla/mux"
 )
 
 func RegisterPublicRoutes(r *mux.Router, db *pgxpool.Pool, cfg *config.Config) {
+	jobQueue := queue.New(db)
+
 	repo := repository.NewAppointmentRepository(db)
 
 	addressRepo := addressRepository.NewAddressRepository(db)
@@ -42,7 +54,7 @@ func RegisterPublicRoutes(r *mux.Router, db *pgxpool.Pool, cfg *config.Config) {
 	serviceService := serviceServices.NewServiceService(serviceRepo, *clinicService)
 
 	scheduleRepo := scheduleRepository.NewScheduleRepository(db)
-	scheduleService := scheduleServices.NewScheduleService(scheduleRepo, *cfg, *serviceService, *clinicService)
+	scheduleService := scheduleServices.NewScheduleService(scheduleRepo, *cfg, *serviceService, *clinicService, jobQueue)
 
 	medical_recordRepo := medical_recordRepository.NewMedicalRecordRepository(db)
 	medical_recordService := medical_recordServices.NewMedicalRecordService(medical_recordRepo)
@@ -50,13 +62,26 @@ func RegisterPublicRoutes(r *mux.Router, db *pgxpool.Pool, cfg *config.Config) {
 	reviewRepo := reviewRepository.NewReviewRepository(db)
 	reviewService := reviewServices.NewReviewService(reviewRepo)
 
-	service := services.NewAppointmentService(repo, db, *cfg, *scheduleService, *serviceService, *medical_recordService, *clinicService, reviewService)
+	treatment_planRepo := treatment_planRepository.NewTreatmentPlanRepository(db)
+	treatment_planService := treatment_planServices.NewTreatmentPlanService(treatment_planRepo, db, *cfg, *medical_recordService)
+
+	pricingRepo := pricingRepository.NewPricingRepository(db)
+	pricingService := pricingServices.NewPricingService(pricingRepo, db, *serviceService, *clinicService)
+
+	inventoryRepo := inventoryRepository.NewInventoryRepository(db)
+	inventoryService := inventoryServices.NewInventoryService(inventoryRepo, db)
+
+	service := services.NewAppointmentService(repo, db, *cfg, *scheduleService, *serviceService, *medical_recordService, *clinicService, reviewService, treatment_planService, pricingService, inventoryService)
 	handler := handlers.NewAppointmentHandler(service, *cfg)
 
 	r.HandleFunc("/appointment", handler.CreateAppointment).Methods("POST")
+	r.HandleFunc("/appointments/respond", handler.GetAppointmentAction).Methods("GET")
+	r.HandleFunc("/appointments/respond", handler.RespondToAppointment).Methods("POST")
 }
 
 func RegisterPrivateRoutes(r *mux.Router, db *pgxpool.Pool, cfg *config.Config) {
+	jobQueue := queue.New(db)
+
 	repo := repository.NewAppointmentRepository(db)
 
 	addressRepo := addressRepository.NewAddressRepository(db)
@@ -69,7 +94,7 @@ func RegisterPrivateRoutes(r *mux.Router, db *pgxpool.Pool, cfg *config.Config)
 	serviceService := serviceServices.NewServiceService(serviceRepo, *clinicService)
 
 	scheduleRepo := scheduleRepository.NewScheduleRepository(db)
-	scheduleService := scheduleServices.NewScheduleService(scheduleRepo, *cfg, *serviceService, *clinicService)
+	scheduleService := scheduleServices.NewScheduleService(scheduleRepo, *cfg, *serviceService, *clinicService, jobQueue)
 
 	medical_recordRepo := medical_recordRepository.NewMedicalRecordRepository(db)
 	medical_recordService := medical_recordServices.NewMedicalRecordService(medical_recordRepo)
@@ -77,7 +102,16 @@ func RegisterPrivateRoutes(r *mux.Router, db *pgxpool.Pool, cfg *config.Config)
 	reviewRepo := reviewRepository.NewReviewRepository(db)
 	reviewService := reviewServices.NewReviewService(reviewRepo)
 
-	service := services.NewAppointmentService(repo, db, *cfg, *scheduleService, *serviceService, *medical_recordService, *clinicService, reviewService)
+	treatment_planRepo := treatment_planRepository.NewTreatmentPlanRepository(db)
+	treatment_planService := treatment_planServices.NewTreatmentPlanService(treatment_planRepo, db, *cfg, *medical_recordService)
+
+	pricingRepo := pricingRepository.NewPricingRepository(db)
+	pricingService := pricingServices.NewPricingService(pricingRepo, db, *serviceService, *clinicService)
+
+	inventoryRepo := inventoryRepository.NewInventoryRepository(db)
+	inventoryService := inventoryServices.NewInventoryService(inventoryRepo, db)
+
+	service := services.NewAppointmentService(repo, db, *cfg, *scheduleService, *serviceService, *medical_recordService, *clinicService, reviewService, treatment_planService, pricingService, inventoryService)
 
 	handler := handlers.NewAppointmentHandler(service, *cfg)
 
@@ -90,5 +124,7 @@ func RegisterPrivateRoutes(r *mux.Router, db *pgxpool.Pool, cfg *config.Config)
 	r.HandleFunc("/appointment/{id}", handler.UpdateAppointment).Methods("PUT")
 	r.HandleFunc("/appointment/{id}", handler.DeleteAppointment).Methods("DELETE")
 	r.HandleFunc("/appointments/{appointmentId}/review", handler.CreateAppointmentReview).Methods("POST")
+	r.HandleFunc("/appointments/{appointmentId}/review", handler.UpdateAppointmentReview).Methods("PUT")
+	r.HandleFunc("/appointments/{appointmentId}/review", handler.GetAppointmentReview).Methods("GET")
 
 }
@@ -6,20 +6,30 @@ import (
 	"dental_clinic/internal/modules/appointment/dto"
 	"dental_clinic/internal/modules/appointment/models"
 	"dental_clinic/internal/modules/appointment/repository"
+	"dental_clinic/internal/queue"
 	"dental_clinic/internal/utils"
 
 	// "fmt"
 
+	"math"
 	"time"
 
 	clinicServices "dental_clinic/internal/modules/clinic/services"
+	inventoryModels "dental_clinic/internal/modules/inventory/models"
+	inventoryServices "dental_clinic/internal/modules/inventory/services"
 	medical_recordServices "dental_clinic/internal/modules/medical_record/services"
+	pricingModels "dental_clinic/internal/modules/pricing/models"
+	pricingServices "dental_clinic/internal/modules/pricing/services"
+	reviewModels "dental_clinic/internal/modules/reviews/models"
 	reviewServices "dental_clinic/internal/modules/reviews/services"
 	scheduleServices "dental_clinic/internal/modules/schedule/services"
+	serviceModels "dental_clinic/internal/modules/services/models"
 	serviceServices "dental_clinic/internal/modules/services/services"
+	treatment_planModels "dental_clinic/internal/modules/treatment_plan/models"
+	treatment_planServices "dental_clinic/internal/modules/treatment_plan/services"
 
 	"errors"
-	// "fmt"
+	"fmt"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -35,9 +45,13 @@ type AppointmentService struct {
 	medical_recordSrv medical_recordServices.MedicalRecordService
 	clinicSrv         clinicServices.ClinicService
 	reviewSrv         *reviewServices.ReviewService
+	treatmentPlanSrv  *treatment_planServices.TreatmentPlanService
+	pricingSrv        *pricingServices.PricingService
+	inventorySrv      *inventoryServices.InventoryService
+	queue             *queue.Queue
 }
 
-func NewAppointmentService(r repository.AppointmentRepository, db *pgxpool.Pool, cfx config.Config, scheduleSrv scheduleServices.ScheduleService, serviceSrv serviceServices.ServiceService, medical_recordSrv medical_recordServices.MedicalRecordService, clinicSrv clinicServices.ClinicService, reviewSrv *reviewServices.ReviewService) *AppointmentService {
+func NewAppointmentService(r repository.AppointmentRepository, db *pgxpool.Pool, cfx config.Config, scheduleSrv scheduleServices.ScheduleService, serviceSrv serviceServices.ServiceService, medical_recordSrv medical_recordServices.MedicalRecordService, clinicSrv clinicServices.ClinicService, reviewSrv *reviewServices.ReviewService, treatmentPlanSrv *treatment_planServices.TreatmentPlanService, pricingSrv *pricingServices.PricingService, inventorySrv *inventoryServices.InventoryService) *AppointmentService {
 	return &AppointmentService{
 		repo:              r,
 		db:                db,
@@ -47,6 +61,10 @@ func NewAppointmentService(r repository.AppointmentRepository, db *pgxpool.Pool,
 		medical_recordSrv: medical_recordSrv,
 		clinicSrv:         clinicSrv,
 		reviewSrv:         reviewSrv,
+		treatmentPlanSrv:  treatmentPlanSrv,
+		pricingSrv:        pricingSrv,
+		inventorySrv:      inventorySrv,
+		queue:             queue.New(db),
 	}
 }
 
@@ -85,7 +103,7 @@ func (s *AppointmentService) CreateAppointment(tokenStr string, req dto.CreateAp
 	if err != nil {
 		return nil, err
 	}
-	serviceInfo, err := s.serviceSrv.GetByClinicIDAndServiceID(clinic_id, service.Id.String())
+	serviceInfo, err := s.serviceSrv.GetDoctorOffer(doctorId.String(), clinic_id, service.Id.String())
 
 	if err != nil {
 		return nil, err
@@ -96,6 +114,20 @@ func (s *AppointmentService) CreateAppointment(tokenStr string, req dto.CreateAp
 		return nil, err
 	}
 
+	var planItem *treatment_planModels.PlanItemContext
+	if req.TreatmentPlanItemId != "" {
+		if userId == uuid.Nil {
+			return nil, errors.New("login required to book a treatment plan item")
+		}
+		planItem, err = s.treatmentPlanSrv.GetBookableItem(userId, req.TreatmentPlanItemId)
+		if err != nil {
+			return nil, err
+		}
+		if planItem.ServiceId != serviceId || planItem.ClinicId.String() != clinic_id {
+			return nil, errors.New("service or clinic does not match the treatment plan item")
+		}
+	}
+
 	slotId, err := uuid.Parse(req.Slot_id)
 	if err != nil {
 		return nil, errors.New("invalid slotId")
@@ -156,11 +188,43 @@ func (s *AppointmentService) CreateAppointment(tokenStr string, req dto.CreateAp
 		Email:             req.Email,
 	}
 
+	// the price is locked now so later price list changes do not touch it
+	quote, err := s.pricingSrv.QuoteTx(*serviceInfo, appointment.Start_time, pricingModels.Patient{UserId: userId, Email: req.Email}, req.PromoCode, tx)
+	if err != nil {
+		return nil, err
+	}
+	appointment.ListPrice = quote.ListPrice
+	appointment.DiscountAmount = quote.Discount
+	appointment.ChargedPrice = quote.Total
+	appointment.DiscountReason = quote.DiscountReason
+	if quote.PriceRule != nil {
+		appointment.PriceRuleId = &quote.PriceRule.Id
+	}
+	if quote.PromoCode != nil {
+		appointment.PromoCodeId = &quote.PromoCode.Id
+	}
+
 	appointment, err = s.repo.CreateTx(appointment, tx)
 	if err != nil {
 		return nil, err
 	}
 
+	clinicUUID, err := uuid.Parse(clinic_id)
+	if err != nil {
+		return nil, errors.New("invalid clinic id")
+	}
+	shortages, err := s.inventorySrv.ReserveMaterialsTx(clinicUUID, serviceInfo.Id, clinic_addressId, appointment.Id, true, tx)
+	if err != nil {
+		return nil, err
+	}
+	appointment.StockWarnings = stockWarnings(shortages)
+
+	if planItem != nil {
+		if err := s.treatmentPlanSrv.LinkAppointmentTx(planItem, appointment.Id, tx); err != nil {
+			return nil, err
+		}
+	}
+
 	_, err = s.medical_recordSrv.CreateMedicalRecordTx(
 		appointment.Id,
 		appointment.Doctor_id,
@@ -177,7 +241,7 @@ func (s *AppointmentService) CreateAppointment(tokenStr string, req dto.CreateAp
 		return nil, err
 	}
 
-	_ = utils.SendEmail(&s.cfx, appointment.Email, "Appointment was created", "Appointment was created")
+	_ = utils.QueueEmail(ctx, s.queue, appointment.Email, "Appointment was created", "Appointment was created")
 
 	return appointment, nil
 }
@@ -194,6 +258,24 @@ func (s *AppointmentService) GetAppointmentByID(id string) (*models.Appointment,
 	if appointment == nil {
 		return nil, errors.New("appointment not found")
 	}
+	appointment.MaterialsUsed, err = s.repo.GetUsedMaterials(id)
+	if err != nil {
+		return nil, err
+	}
+
+	cost := 0.0
+	if len(appointment.MaterialsUsed) > 0 {
+		for _, material := range appointment.MaterialsUsed {
+			cost += material.Cost()
+		}
+	} else if appointment.Status != "cancelled" {
+		if cost, err = s.repo.GetExpectedMaterialCost(id); err != nil {
+			return nil, err
+		}
+		appointment.MaterialCostEstimated = true
+	}
+	cost = math.Round(cost*100) / 100
+	appointment.MaterialCost = &cost
 	return appointment, nil
 }
 
@@ -205,6 +287,7 @@ func (s *AppointmentService) UpdateAppointment(id string, req dto.UpdateAppointm
 	if appointment == nil {
 		return nil, errors.New("appointment not found")
 	}
+	previous := *appointment
 
 	if req.Doctor_id != "" {
 		doctorId, err := uuid.Parse(req.Doctor_id)
@@ -258,28 +341,166 @@ func (s *AppointmentService) UpdateAppointment(id string, req dto.UpdateAppointm
 		appointment.Email = req.Email
 	}
 
-	return s.repo.Update(appointment)
+	ctx := context.Background()
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	updated, err := s.repo.UpdateTx(appointment, tx)
+	if err != nil {
+		return nil, err
+	}
+	if updated == nil {
+		return nil, errors.New("appointment not found")
+	}
+	if err := s.requoteTx(&previous, updated, tx); err != nil {
+		return nil, err
+	}
+	if err := s.syncReservationsTx(&previous, updated, tx); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// syncReservationsTx releases the materials of an appointment that is no
+// longer booked and reserves them anew when a booked appointment moves to
+// another clinic address or service. It runs in the transaction of the
+// update, so the appointment and its reservations change together.
+func (s *AppointmentService) syncReservationsTx(previous, updated *models.Appointment, tx pgx.Tx) error {
+	if updated.Status != "booked" {
+		if previous.Status == "booked" {
+			return s.inventorySrv.ReleaseReservationsTx(updated.Id, tx)
+		}
+		return nil
+	}
+	if previous.Status == "booked" && previous.Clinic_address_id == updated.Clinic_address_id && previous.Service_id == updated.Service_id {
+		return nil
+	}
+
+	offer, err := s.doctorOffer(updated)
+	if err != nil {
+		return err
+	}
+	shortages, err := s.inventorySrv.MoveReservationsTx(offer.ClinicID, offer.Id, updated.Clinic_address_id, updated.Id, tx)
+	if err != nil {
+		return err
+	}
+	updated.StockWarnings = stockWarnings(shortages)
+	return nil
+}
+
+// requoteTx prices the appointment again when it moves to another doctor,
+// clinic address or service, since the price locked at booking belonged to
+// the old offer. The promo code it was booked with stays applied.
+func (s *AppointmentService) requoteTx(previous, updated *models.Appointment, tx pgx.Tx) error {
+	if previous.Doctor_id == updated.Doctor_id && previous.Clinic_address_id == updated.Clinic_address_id && previous.Service_id == updated.Service_id {
+		return nil
+	}
+
+	offer, err := s.doctorOffer(updated)
+	if err != nil {
+		return err
+	}
+	patient := pricingModels.Patient{UserId: updated.User_id, Email: updated.Email, AppointmentId: updated.Id}
+	quote, err := s.pricingSrv.RequoteTx(*offer, updated.Start_time, patient, previous.PromoCodeId, tx)
+	if err != nil {
+		return err
+	}
+
+	updated.ListPrice = quote.ListPrice
+	updated.DiscountAmount = quote.Discount
+	updated.ChargedPrice = quote.Total
+	updated.DiscountReason = quote.DiscountReason
+	updated.PriceRuleId = nil
+	if quote.PriceRule != nil {
+		updated.PriceRuleId = &quote.PriceRule.Id
+	}
+	updated.PromoCodeId = nil
+	if quote.PromoCode != nil {
+		updated.PromoCodeId = &quote.PromoCode.Id
+	}
+	return s.repo.UpdatePriceTx(updated, tx)
+}
+
+// doctorOffer returns the clinic service the appointment is booked for, as
+// performed by its doctor.
+func (s *AppointmentService) doctorOffer(appointment *models.Appointment) (*serviceModels.Clinic_Service, error) {
+	clinicId, err := s.clinicSrv.GetClinicByAddressId(appointment.Clinic_address_id)
+	if err != nil {
+		return nil, err
+	}
+	return s.serviceSrv.GetDoctorOffer(appointment.Doctor_id.String(), clinicId, appointment.Service_id.String())
+}
+
+func stockWarnings(shortages []inventoryModels.MaterialShortage) []string {
+	warnings := make([]string, 0, len(shortages))
+	for _, shortage := range shortages {
+		warnings = append(warnings, fmt.Sprintf("not enough %s at this clinic address: %g %s needed, %g available", shortage.ProductName, shortage.Required, shortage.ProductUnit, shortage.Available))
+	}
+	return warnings
 }
 
 func ToAppointmentResponse(appointment models.Appointment) dto.GetAppointmentsResponse {
 	return dto.GetAppointmentsResponse{
-		Id:                appointment.Id.String(),
-		Doctor_id:         appointment.Doctor_id.String(),
-		Clinic_address_id: appointment.Clinic_address_id.String(),
-		Service_id:        appointment.Service_id.String(),
-		User_id:           appointment.User_id.String(),
-		Start_time:        appointment.Start_time.Format("2006-01-02 15:04:05"),
-		End_time:          appointment.End_time.Format("2006-01-02 15:04:05"),
-		Status:            appointment.Status,
-		Name:              appointment.Name,
-		Email:             appointment.Email,
-		IsReviewed:        appointment.IsReviewed,
-		DoctorRating:      appointment.DoctorRating,
-		ClinicRating:      appointment.ClinicRating,
-		ClinicComment:     appointment.ClinicComment,
+		Id:                    appointment.Id.String(),
+		Doctor_id:             appointment.Doctor_id.String(),
+		Clinic_address_id:     appointment.Clinic_address_id.String(),
+		Service_id:            appointment.Service_id.String(),
+		User_id:               appointment.User_id.String(),
+		Start_time:            appointment.Start_time.Format("2006-01-02 15:04:05"),
+		End_time:              appointment.End_time.Format("2006-01-02 15:04:05"),
+		Status:                appointment.Status,
+		Confirmed_at:          formatConfirmedAt(appointment.ConfirmedAt),
+		Name:                  appointment.Name,
+		Email:                 appointment.Email,
+		IsReviewed:            appointment.IsReviewed,
+		Warnings:              appointment.StockWarnings,
+		MaterialsUsed:         toUsedMaterialResponseList(appointment.MaterialsUsed),
+		MaterialCost:          appointment.MaterialCost,
+		MaterialCostEstimated: appointment.MaterialCostEstimated,
+		ListPrice:             appointment.ListPrice,
+		DiscountAmount:        appointment.DiscountAmount,
+		ChargedPrice:          appointment.ChargedPrice,
+		DoctorRating:          appointment.DoctorRating,
+		ClinicRating:          appointment.ClinicRating,
+		ClinicComment:         appointment.ClinicComment,
 	}
 }
 
+func formatConfirmedAt(confirmedAt *time.Time) string {
+	if confirmedAt == nil {
+		return ""
+	}
+	return confirmedAt.Format("2006-01-02 15:04:05")
+}
+
+func toUsedMaterialResponseList(materials []models.UsedMaterial) []dto.UsedMaterialResponse {
+	if len(materials) == 0 {
+		return nil
+	}
+	result := make([]dto.UsedMaterialResponse, 0, len(materials))
+	for _, material := range materials {
+		response := dto.UsedMaterialResponse{
+			ProductName: material.ProductName,
+			ProductUnit: material.ProductUnit,
+			LotNumber:   material.LotNumber,
+			Quantity:    material.Quantity,
+			UnitCost:    material.UnitCost,
+			Cost:        math.Round(material.Cost()*100) / 100,
+		}
+		if material.ExpiresAt != nil {
+			response.ExpiresAt = material.ExpiresAt.Format("2006-01-02")
+		}
+		result = append(result, response)
+	}
+	return result
+}
+
 func ToAppointmentResponseList(appointments []models.Appointment) []dto.GetAppointmentsResponse {
 	result := make([]dto.GetAppointmentsResponse, 0, len(appointments))
 	for _, u := range appointments {
@@ -288,6 +509,95 @@ func ToAppointmentResponseList(appointments []models.Appointment) []dto.GetAppoi
 	return result
 }
 
+const (
+	ActionConfirm = "confirm"
+	ActionCancel  = "cancel"
+)
+
+// appointmentForAction returns the appointment a confirm or cancel link is
+// for. The link no longer works once the appointment has been moved.
+func (s *AppointmentService) appointmentForAction(token string) (*models.Appointment, string, error) {
+	claims, err := utils.ParseAppointmentActionToken(token, s.cfx.JWTSecret)
+	if err != nil {
+		return nil, "", err
+	}
+	if claims.Action != ActionConfirm && claims.Action != ActionCancel {
+		return nil, "", errors.New("invalid or expired link")
+	}
+	appointment, err := s.repo.GetByID(claims.AppointmentID)
+	if err != nil {
+		return nil, "", err
+	}
+	if appointment == nil {
+		return nil, "", errors.New("appointment not found")
+	}
+	if appointment.Start_time.Unix() != claims.StartTime {
+		return nil, "", errors.New("appointment has been rescheduled, this link is no longer valid")
+	}
+	return appointment, claims.Action, nil
+}
+
+// GetAppointmentAction shows what a confirm or cancel link from a reminder
+// would do, so the patient can look before they act.
+func (s *AppointmentService) GetAppointmentAction(token string) (*models.Appointment, string, error) {
+	return s.appointmentForAction(token)
+}
+
+// RespondToAppointment confirms or cancels the appointment as the link from
+// the reminder says. Cancelling frees the doctor's slots and releases the
+// reserved materials. Confirming twice is not an error.
+func (s *AppointmentService) RespondToAppointment(token string, ctx context.Context) (*models.Appointment, string, error) {
+	appointment, action, err := s.appointmentForAction(token)
+	if err != nil {
+		return nil, "", err
+	}
+	if appointment.Status != "booked" {
+		return nil, "", fmt.Errorf("appointment is already %s", appointment.Status)
+	}
+
+	id := appointment.Id.String()
+	switch action {
+	case ActionConfirm:
+		if err := s.repo.Confirm(id); err != nil {
+			if err == pgx.ErrNoRows {
+				return nil, "", errors.New("appointment is no longer booked")
+			}
+			return nil, "", err
+		}
+	case ActionCancel:
+		tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+		if err != nil {
+			return nil, "", err
+		}
+		defer tx.Rollback(ctx)
+
+		if err := s.repo.CancelTx(id, tx); err != nil {
+			if err == pgx.ErrNoRows {
+				return nil, "", errors.New("appointment is no longer booked")
+			}
+			return nil, "", err
+		}
+		if err := s.repo.FreeSlotsTx(appointment, tx); err != nil {
+			return nil, "", err
+		}
+		if err := s.inventorySrv.ReleaseReservationsTx(appointment.Id, tx); err != nil {
+			return nil, "", err
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return nil, "", err
+		}
+	}
+
+	updated, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, "", err
+	}
+	if updated == nil {
+		return nil, "", errors.New("appointment not found")
+	}
+	return updated, action, nil
+}
+
 func (s *AppointmentService) DeleteAppointment(id string) error {
 	appointment, err := s.repo.GetByID(id)
 	if err != nil {
@@ -338,39 +648,59 @@ func (s *AppointmentService) GetMedicalRecord(id string) (dto.GetMedicalRecordAp
 	return response, nil
 }
 
-func (s *AppointmentService) CreateAppointmentReview(tokenStr, appointmentId string, req dto.CreateAppointmentReviewRequest, ctx context.Context) error {
+// reviewableAppointment loads an appointment for its patient, checking that
+// the token belongs to the person who booked it.
+func (s *AppointmentService) reviewableAppointment(tokenStr, appointmentId string) (*models.Appointment, uuid.UUID, error) {
 	appointmentUUID, err := uuid.Parse(appointmentId)
 	if err != nil {
-		return errors.New("invalid appointmentId")
+		return nil, uuid.Nil, errors.New("invalid appointmentId")
 	}
 
 	claims, err := utils.GetClaims(tokenStr, s.cfx.JWTSecret)
 	if err != nil {
-		return err
+		return nil, uuid.Nil, err
 	}
 
 	userIDStr, _ := claims["user_id"].(string)
 	if userIDStr == "" {
-		return errors.New("No user Id")
+		return nil, uuid.Nil, errors.New("No user Id")
 	}
 
 	userId, err := uuid.Parse(userIDStr)
 	if err != nil {
-		return errors.New("invalid UserID")
+		return nil, uuid.Nil, errors.New("invalid UserID")
 	}
 
 	appointment, err := s.repo.GetByID(appointmentId)
 	if err != nil {
-		return err
+		return nil, uuid.Nil, err
 	}
 	if appointment == nil {
-		return errors.New("appointment not found")
+		return nil, uuid.Nil, errors.New("appointment not found")
 	}
 	if appointment.Id != appointmentUUID {
-		return errors.New("appointment not found")
+		return nil, uuid.Nil, errors.New("appointment not found")
 	}
 	if appointment.User_id != uuid.Nil && appointment.User_id != userId {
-		return errors.New("appointment does not belong to user")
+		return nil, uuid.Nil, errors.New("appointment does not belong to user")
+	}
+	return appointment, userId, nil
+}
+
+func reviewInput(req dto.CreateAppointmentReviewRequest) reviewModels.ReviewInput {
+	return reviewModels.ReviewInput{
+		DoctorRating:  req.DoctorRating,
+		DoctorComment: req.DoctorComment,
+		ClinicRating:  req.ClinicRating,
+		ClinicComment: req.ClinicComment,
+		Dimensions:    req.Dimensions,
+	}
+}
+
+func (s *AppointmentService) CreateAppointmentReview(tokenStr, appointmentId string, req dto.CreateAppointmentReviewRequest, ctx context.Context) error {
+	appointment, userId, err := s.reviewableAppointment(tokenStr, appointmentId)
+	if err != nil {
+		return err
 	}
 	if appointment.IsReviewed {
 		return errors.New("appointment already reviewed")
@@ -396,9 +726,7 @@ func (s *AppointmentService) CreateAppointmentReview(tokenStr, appointmentId str
 		appointment.Doctor_id,
 		clinicId,
 		userId,
-		req.DoctorRating,
-		req.ClinicRating,
-		req.ClinicComment,
+		reviewInput(req),
 		tx,
 	); err != nil {
 		return err
@@ -410,3 +738,68 @@ func (s *AppointmentService) CreateAppointmentReview(tokenStr, appointmentId str
 
 	return tx.Commit(ctx)
 }
+
+func (s *AppointmentService) UpdateAppointmentReview(tokenStr, appointmentId string, req dto.CreateAppointmentReviewRequest, ctx context.Context) error {
+	appointment, userId, err := s.reviewableAppointment(tokenStr, appointmentId)
+	if err != nil {
+		return err
+	}
+	if !appointment.IsReviewed {
+		return errors.New("appointment not reviewed yet")
+	}
+
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := s.reviewSrv.UpdateAppointmentReviewTx(appointment.Id, userId, reviewInput(req), tx); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (s *AppointmentService) GetAppointmentReview(tokenStr, appointmentId string) (*dto.AppointmentReviewResponse, error) {
+	appointment, _, err := s.reviewableAppointment(tokenStr, appointmentId)
+	if err != nil {
+		return nil, err
+	}
+
+	review, err := s.reviewSrv.GetAppointmentReview(appointment.Id)
+	if err != nil {
+		return nil, err
+	}
+	if review == nil {
+		return nil, errors.New("review not found")
+	}
+
+	editableUntil := reviewServices.EditableUntil(review)
+	response := &dto.AppointmentReviewResponse{
+		AppointmentId: review.AppointmentId.String(),
+		DoctorRating:  review.DoctorRating,
+		DoctorComment: review.DoctorComment,
+		ClinicRating:  review.ClinicRating,
+		ClinicComment: review.ClinicComment,
+		Dimensions:    review.Dimensions,
+		CreatedAt:     review.CreatedAt.Format(time.RFC3339),
+		EditableUntil: editableUntil.Format(time.RFC3339),
+		Editable:      time.Now().Before(editableUntil),
+		Edits:         make([]dto.AppointmentReviewEditResponse, 0, len(review.Edits)),
+	}
+	if review.UpdatedAt.Valid {
+		response.UpdatedAt = review.UpdatedAt.Time.Format(time.RFC3339)
+	}
+	for _, edit := range review.Edits {
+		response.Edits = append(response.Edits, dto.AppointmentReviewEditResponse{
+			DoctorRating:  edit.DoctorRating,
+			DoctorComment: edit.DoctorComment,
+			ClinicRating:  edit.ClinicRating,
+			ClinicComment: edit.ClinicComment,
+			Dimensions:    edit.Dimensions,
+			EditedAt:      edit.EditedAt.Format(time.RFC3339),
+		})
+	}
+	return response, nil
+}
@@ -33,3 +33,32 @@ type ClinicAddressImageResponse struct {
 	Id       string `json:"id"`
 	ImageURL string `json:"image_url"`
 }
+
+type UpdateAddressProfileRequest struct {
+	Amenities            []string `json:"amenities"`
+	PaymentMethods       []string `json:"payment_methods"`
+	Languages            []string `json:"languages"`
+	Parking              string   `json:"parking"`
+	ParkingNotes         string   `json:"parking_notes"`
+	WheelchairAccessible bool     `json:"wheelchair_accessible"`
+	Accessibility        []string `json:"accessibility"`
+	AccessibilityNotes   string   `json:"accessibility_notes"`
+}
+
+type OpeningHoursRequest struct {
+	DayOfWeek int    `json:"day_of_week"`
+	OpensAt   string `json:"opens_at"`
+	ClosesAt  string `json:"closes_at"`
+}
+
+type SetOpeningHoursRequest struct {
+	Hours []OpeningHoursRequest `json:"hours"`
+}
+
+type SpecialHoursRequest struct {
+	Date     string `json:"date"`
+	IsClosed bool   `json:"is_closed"`
+	OpensAt  string `json:"opens_at"`
+	ClosesAt string `json:"closes_at"`
+	Note     string `json:"note"`
+}
@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	"dental_clinic/internal/modules/clinic/dto"
+	"dental_clinic/internal/modules/clinic/services"
+)
+
+// GetClinicAddressProfile godoc
+// @Summary Get clinic address profile
+// @Description Returns a clinic address with its amenities, payment methods, languages, parking and accessibility, weekly opening hours and special hours for the next 90 days. day_of_week is 0 for Sunday to 6 for Saturday.
+// @Tags Clinics
+// @Produce json
+// @Param id path string true "Clinic address ID (UUID)"
+// @Success 200 {object} SuccessResponse "OK"
+// @Failure 400 {object} ErrorResponse "Bad Request"
+// @Failure 404 {object} ErrorResponse "Clinic address not found"
+// @Router /api/clinic-addresses/{id}/profile [get]
+func (h *ClinicHandler) GetClinicAddressProfile(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid clinic address ID format")
+		return
+	}
+
+	if _, err := h.service.GetClinicAddressByID(id); err != nil {
+		respondError(w, http.StatusNotFound, "Clinic address not found")
+		return
+	}
+
+	details, err := h.service.GetClinicAddressDetails(id)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, SuccessResponse{Data: details})
+}
+
+// UpdateClinicAddressProfile godoc
+// @Summary Update clinic address profile
+// @Description Replaces the amenities, payment methods, languages, parking and accessibility information of a clinic address. parking is one of unknown, none, free, paid or street; payment_methods are cash, card, kaspi, insurance, installments or bank_transfer.
+// @Tags Clinics
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Clinic address ID (UUID)"
+// @Param request body dto.UpdateAddressProfileRequest true "Profile"
+// @Success 200 {object} SuccessResponse "OK"
+// @Failure 400 {object} ErrorResponse "Bad Request"
+// @Failure 404 {object} ErrorResponse "Clinic address not found"
+// @Router /api/clinic-addresses/{id}/profile [put]
+func (h *ClinicHandler) UpdateClinicAddressProfile(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid clinic address ID format")
+		return
+	}
+
+	var req dto.UpdateAddressProfileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if _, err := h.service.GetClinicAddressByID(id); err != nil {
+		respondError(w, http.StatusNotFound, "Clinic address not found")
+		return
+	}
+
+	profile, err := h.service.UpdateAddressProfile(id, req)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, SuccessResponse{
+		Message: "Clinic address profile updated successfully",
+		Data:    profile,
+	})
+}
+
+// SetClinicAddressOpeningHours godoc
+// @Summary Set clinic address opening hours
+// @Description Replaces the weekly opening hours of a clinic address. A day may have several intervals; times are HH:MM or HH:MM:SS and day_of_week is 0 for Sunday to 6 for Saturday. The hours are refused when a doctor's working hours at the address fall outside them. An empty list removes the opening hours.
+// @Tags Clinics
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Clinic address ID (UUID)"
+// @Param request body dto.SetOpeningHoursRequest true "Weekly opening hours"
+// @Success 200 {object} SuccessResponse "OK"
+// @Failure 400 {object} ErrorResponse "Bad Request"
+// @Failure 404 {object} ErrorResponse "Clinic address not found"
+// @Failure 409 {object} ErrorResponse "Doctors work outside the new hours"
+// @Router /api/clinic-addresses/{id}/opening-hours [put]
+func (h *ClinicHandler) SetClinicAddressOpeningHours(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid clinic address ID format")
+		return
+	}
+
+	var req dto.SetOpeningHoursRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if _, err := h.service.GetClinicAddressByID(id); err != nil {
+		respondError(w, http.StatusNotFound, "Clinic address not found")
+		return
+	}
+
+	hours, err := h.service.SetOpeningHours(id, req.Hours)
+	if err != nil {
+		if errors.Is(err, services.ErrOutsideOpeningHours) {
+			respondError(w, http.StatusConflict, err.Error())
+			return
+		}
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, SuccessResponse{
+		Message: "Opening hours updated successfully",
+		Data:    hours,
+	})
+}
+
+// SetClinicAddressSpecialHours godoc
+// @Summary Set special hours for a date
+// @Description Sets the hours of a clinic address on one date, e.g. a holiday, replacing the weekly hours for that day. Free slots outside the special hours are removed; booked appointments are kept.
+// @Tags Clinics
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Clinic address ID (UUID)"
+// @Param request body dto.SpecialHoursRequest true "Special hours"
+// @Success 200 {object} SuccessResponse "OK"
+// @Failure 400 {object} ErrorResponse "Bad Request"
+// @Failure 404 {object} ErrorResponse "Clinic address not found"
+// @Router /api/clinic-addresses/{id}/special-hours [post]
+func (h *ClinicHandler) SetClinicAddressSpecialHours(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid clinic address ID format")
+		return
+	}
+
+	var req dto.SpecialHoursRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if _, err := h.service.GetClinicAddressByID(id); err != nil {
+		respondError(w, http.StatusNotFound, "Clinic address not found")
+		return
+	}
+
+	hours, removed, err := h.service.SetSpecialHours(id, req)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, SuccessResponse{
+		Message: "Special hours saved successfully",
+		Data: map[string]interface{}{
+			"special_hours": hours,
+			"removed_slots": removed,
+		},
+	})
+}
+
+// DeleteClinicAddressSpecialHours godoc
+// @Summary Delete special hours for a date
+// @Description Restores the weekly opening hours of a clinic address on the given date
+// @Tags Clinics
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Clinic address ID (UUID)"
+// @Param date path string true "Date (YYYY-MM-DD)"
+// @Success 200 {object} SuccessResponse "OK"
+// @Failure 400 {object} ErrorResponse "Bad Request"
+// @Router /api/clinic-addresses/{id}/special-hours/{date} [delete]
+func (h *ClinicHandler) DeleteClinicAddressSpecialHours(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid clinic address ID format")
+		return
+	}
+
+	if err := h.service.DeleteSpecialHours(id, vars["date"]); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, SuccessResponse{Message: "Special hours deleted successfully"})
+}
@@ -2,32 +2,33 @@ package handlers
 
 import (
 	"encoding/json"
-	"fmt"
-	"io"
 	"net/http"
 	"os"
-	"path/filepath"
-	"strings"
-	"time"
+	"strconv"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 
 	"dental_clinic/internal/config"
+	"dental_clinic/internal/geo"
 	"dental_clinic/internal/modules/clinic/dto"
 	"dental_clinic/internal/modules/clinic/models"
 	"dental_clinic/internal/modules/clinic/services"
+	"dental_clinic/internal/search"
+	"dental_clinic/internal/upload"
 )
 
 type ClinicHandler struct {
-	service *services.ClinicService
-	cfg     config.Config
+	service  *services.ClinicService
+	cfg      config.Config
+	uploader *upload.Uploader
 }
 
 func NewClinicHandler(s *services.ClinicService, cfg config.Config) *ClinicHandler {
 	return &ClinicHandler{
-		service: s,
-		cfg:     cfg,
+		service:  s,
+		cfg:      cfg,
+		uploader: upload.NewUploader(cfg),
 	}
 }
 
@@ -52,95 +53,133 @@ func respondError(w http.ResponseWriter, statusCode int, message string) {
 	respondJSON(w, statusCode, ErrorResponse{Error: message})
 }
 
-func saveUploadedImage(r *http.Request, fieldName, uploadDir, ownerID string) (string, string, error) {
-	if err := r.ParseMultipartForm(5 << 20); err != nil {
-		return "", "", fmt.Errorf("invalid request body")
+// saveUploadedImage stores the image sent in fieldName and returns its public
+// URL and local path.
+func (h *ClinicHandler) saveUploadedImage(w http.ResponseWriter, r *http.Request, fieldName, uploadDir, ownerID string) (string, string, error) {
+	saved, err := h.uploader.SaveFormFile(w, r, fieldName, upload.ImagePolicy, uploadDir, ownerID)
+	if err != nil {
+		return "", "", err
 	}
+	return saved.URL, saved.Path, nil
+}
+
+func removeUploadedFile(fileURL string) {
+	upload.RemoveByURL(fileURL)
+}
 
-	file, fileHeader, err := r.FormFile(fieldName)
+// GetClinics godoc
+// @Summary Search clinics
+// @Description Returns a page of active clinics. q searches clinic names, descriptions and the names of offered services. Pass next_cursor back as cursor to get the following page.
+// @Tags Clinics
+// @Security BearerAuth
+// @Produce json
+// @Param q query string false "Text to search for"
+// @Param city query string false "City of one of the clinic's addresses"
+// @Param service_id query string false "Only clinics offering this service"
+// @Param price_min query number false "Minimum service price"
+// @Param price_max query number false "Maximum service price"
+// @Param min_rating query number false "Minimum average rating"
+// @Param language query string false "Language spoken by one of the clinic's doctors"
+// @Param specialization query string false "Specialization of one of the clinic's doctors"
+// @Param available_within query int false "Has a free slot within this many days"
+// @Param sort query string false "relevance, rating, name, newest or price"
+// @Param cursor query string false "Cursor returned by the previous page"
+// @Param limit query int false "Page size, at most 100"
+// @Success 200 {object} SuccessResponse "OK"
+// @Failure 400 {object} ErrorResponse "Invalid filters"
+// @Failure 500 {object} ErrorResponse "Internal Server Error"
+// @Router /api/clinics [get]
+func (h *ClinicHandler) GetClinics(w http.ResponseWriter, r *http.Request) {
+	params, err := search.ParseParams(r.URL.Query())
 	if err != nil {
-		return "", "", fmt.Errorf("%s is required", fieldName)
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
 	}
-	defer file.Close()
 
-	buffer := make([]byte, 512)
-	n, err := file.Read(buffer)
+	result, err := h.service.SearchClinics(params)
 	if err != nil {
-		return "", "", fmt.Errorf("failed to read image")
-	}
-	contentType := http.DetectContentType(buffer[:n])
-	if !strings.HasPrefix(contentType, "image/") {
-		return "", "", fmt.Errorf("%s must be an image", fieldName)
-	}
-	if _, err := file.Seek(0, io.SeekStart); err != nil {
-		return "", "", fmt.Errorf("failed to read image")
+		if search.IsInvalid(err) {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
 	}
+	respondJSON(w, http.StatusOK, SuccessResponse{
+		Data: search.Page{
+			Items:      result.Items,
+			NextCursor: result.NextCursor,
+			Limit:      params.Limit,
+		},
+	})
+}
 
-	if err := os.MkdirAll(uploadDir, os.ModePerm); err != nil {
-		return "", "", fmt.Errorf("failed to create upload directory")
-	}
+// GetNearbyClinicAddresses godoc
+// @Summary Find clinics near a location
+// @Description Returns clinic addresses within radius_km of the given point, closest first, with the next available slot. With service_id only clinics offering the service are returned, with its price and duration.
+// @Tags Clinics
+// @Produce json
+// @Param lat query number true "Latitude"
+// @Param lng query number true "Longitude"
+// @Param radius_km query number false "Search radius in km, default 10, at most 100"
+// @Param service_id query string false "Service ID (UUID)"
+// @Param limit query int false "Maximum number of results, default 20, at most 100"
+// @Success 200 {object} SuccessResponse "OK"
+// @Failure 400 {object} ErrorResponse "Invalid parameters"
+// @Failure 500 {object} ErrorResponse "Internal Server Error"
+// @Router /api/clinic-addresses/nearby [get]
+func (h *ClinicHandler) GetNearbyClinicAddresses(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
 
-	ext := strings.ToLower(filepath.Ext(fileHeader.Filename))
-	if ext == "" {
-		ext = ".jpg"
-	}
-	filename := fmt.Sprintf("%s_%d%s", ownerID, time.Now().UnixNano(), ext)
-	filePath := filepath.Join(uploadDir, filename)
-	dst, err := os.Create(filePath)
+	lat, err := strconv.ParseFloat(query.Get("lat"), 64)
 	if err != nil {
-		return "", "", fmt.Errorf("failed to save image")
+		respondError(w, http.StatusBadRequest, "Invalid lat")
+		return
 	}
-	if _, err := io.Copy(dst, file); err != nil {
-		_ = dst.Close()
-		return "", "", fmt.Errorf("failed to save image")
+	lng, err := strconv.ParseFloat(query.Get("lng"), 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid lng")
+		return
 	}
-	_ = dst.Close()
 
-	return mediaURLFromPath(filePath), filePath, nil
-}
+	radiusKm := 0.0
+	if v := query.Get("radius_km"); v != "" {
+		if radiusKm, err = strconv.ParseFloat(v, 64); err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid radius_km")
+			return
+		}
+	}
 
-func removeUploadedFile(fileURL string) {
-	if fileURL == "" {
-		return
+	limit := 0
+	if v := query.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid limit")
+			return
+		}
 	}
-	_ = os.Remove(filepath.FromSlash("." + fileURL))
-}
 
-func mediaURLFromPath(filePath string) string {
-	urlPath := filepath.ToSlash(filePath)
-	urlPath = strings.TrimPrefix(urlPath, ".")
-	if !strings.HasPrefix(urlPath, "/") {
-		urlPath = "/" + urlPath
+	serviceId := uuid.Nil
+	if v := query.Get("service_id"); v != "" {
+		if serviceId, err = uuid.Parse(v); err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid service ID format")
+			return
+		}
 	}
-	return urlPath
-}
 
-// GetClinics godoc
-// @Summary Get all clinics
-// @Description Returns a list of all clinics
-// @Tags Clinics
-// @Security BearerAuth
-// @Produce json
-// @Success 200 {object} SuccessResponse "OK"
-// @Failure 500 {object} ErrorResponse "Internal Server Error"
-// @Router /api/clinics [get]
-func (h *ClinicHandler) GetClinics(w http.ResponseWriter, r *http.Request) {
-	clinics, err := h.service.GetAllClinics()
+	addresses, err := h.service.GetNearbyAddresses(geo.Point{Lat: lat, Lng: lng}, radiusKm, serviceId, limit)
 	if err != nil {
-		respondError(w, http.StatusInternalServerError, err.Error())
+		respondError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	if clinics == nil {
-		clinics = []*models.Clinic{}
-	}
+
 	respondJSON(w, http.StatusOK, SuccessResponse{
-		Data: clinics,
+		Data: addresses,
 	})
 }
 
 // GetClinic godoc
 // @Summary Get clinic by ID
-// @Description Returns a single clinic by its UUID
+// @Description Returns a single clinic by its UUID with its addresses: profile (amenities, payment methods, languages, parking, accessibility), weekly opening hours and special hours for the next 90 days
 // @Tags Clinics
 // @Security BearerAuth
 // @Produce json
@@ -148,6 +187,7 @@ func (h *ClinicHandler) GetClinics(w http.ResponseWriter, r *http.Request) {
 // @Success 200 {object} SuccessResponse "OK"
 // @Failure 400 {object} ErrorResponse "Invalid clinic ID format"
 // @Failure 404 {object} ErrorResponse "Clinic not found"
+// @Failure 500 {object} ErrorResponse "Internal Server Error"
 // @Router /api/clinics/{id} [get]
 func (h *ClinicHandler) GetClinic(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -165,8 +205,14 @@ func (h *ClinicHandler) GetClinic(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	details, err := h.service.GetClinicDetails(clinic)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
 	respondJSON(w, http.StatusOK, SuccessResponse{
-		Data: clinic,
+		Data: details,
 	})
 }
 
@@ -323,9 +369,9 @@ func (h *ClinicHandler) UpdateClinicLogo(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	logoURL, filePath, err := saveUploadedImage(r, "logo", "./uploads/clinics", id.String())
+	logoURL, filePath, err := h.saveUploadedImage(w, r, "logo", "./uploads/clinics", id.String())
 	if err != nil {
-		respondError(w, http.StatusBadRequest, err.Error())
+		respondError(w, upload.HTTPStatus(err), err.Error())
 		return
 	}
 
@@ -551,9 +597,9 @@ func (h *ClinicHandler) UpdateClinicAddressCover(w http.ResponseWriter, r *http.
 		return
 	}
 
-	coverURL, filePath, err := saveUploadedImage(r, "cover", "./uploads/clinic-addresses/covers", id.String())
+	coverURL, filePath, err := h.saveUploadedImage(w, r, "cover", "./uploads/clinic-addresses/covers", id.String())
 	if err != nil {
-		respondError(w, http.StatusBadRequest, err.Error())
+		respondError(w, upload.HTTPStatus(err), err.Error())
 		return
 	}
 
@@ -622,9 +668,9 @@ func (h *ClinicHandler) AddClinicAddressGalleryImage(w http.ResponseWriter, r *h
 		return
 	}
 
-	imageURL, filePath, err := saveUploadedImage(r, "image", "./uploads/clinic-addresses/gallery", id.String())
+	imageURL, filePath, err := h.saveUploadedImage(w, r, "image", "./uploads/clinic-addresses/gallery", id.String())
 	if err != nil {
-		respondError(w, http.StatusBadRequest, err.Error())
+		respondError(w, upload.HTTPStatus(err), err.Error())
 		return
 	}
 
@@ -715,9 +761,9 @@ func (h *ClinicHandler) UpdateClinicAddressGalleryImage(w http.ResponseWriter, r
 		return
 	}
 
-	imageURL, filePath, err := saveUploadedImage(r, "image", "./uploads/clinic-addresses/gallery", currentImage.ClinicAddressId.String())
+	imageURL, filePath, err := h.saveUploadedImage(w, r, "image", "./uploads/clinic-addresses/gallery", currentImage.ClinicAddressId.String())
 	if err != nil {
-		respondError(w, http.StatusBadRequest, err.Error())
+		respondError(w, upload.HTTPStatus(err), err.Error())
 		return
 	}
 
@@ -17,4 +17,12 @@ type Clinic struct {
 	CreatedAt   time.Time `json:"created_at"`
 	Rating      float64   `json:"rating"`
 	LogoURL     string    `json:"logo_url"`
+
+	RatingCount        int     `json:"rating_count"`
+	RatingDistribution [5]int  `json:"rating_distribution"`
+	RecentRating       float64 `json:"recent_rating"`
+	RatingTrend        float64 `json:"rating_trend"`
+
+	// MinPrice is filled by search: the lowest price of the matching services
+	MinPrice *float64 `json:"min_price,omitempty"`
 }
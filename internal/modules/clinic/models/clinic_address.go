@@ -1,6 +1,8 @@
 package models
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 )
 
@@ -28,3 +30,24 @@ type ClinicAddressWithNames struct {
 	CoverImageURL   string                      `json:"cover_image_url"`
 	Gallery         []ClinicAddressGalleryImage `json:"gallery"`
 }
+
+// NearbyClinicAddress is a clinic address found by a distance search. Price
+// and Duration are set when a service was requested.
+type NearbyClinicAddress struct {
+	ClinicId          uuid.UUID  `json:"clinic_id"`
+	ClinicName        string     `json:"clinic_name"`
+	LogoURL           string     `json:"logo_url"`
+	ClinicAddressId   uuid.UUID  `json:"clinic_address_id"`
+	Country           string     `json:"country"`
+	City              string     `json:"city"`
+	Street            string     `json:"street"`
+	Building          string     `json:"building"`
+	Latitude          float64    `json:"latitude"`
+	Longitude         float64    `json:"longitude"`
+	DistanceKm        float64    `json:"distance_km"`
+	Rating            float64    `json:"rating"`
+	RatingCount       int        `json:"rating_count"`
+	Price             *float64   `json:"price,omitempty"`
+	Duration          *int       `json:"duration,omitempty"`
+	NextAvailableSlot *time.Time `json:"next_available_slot,omitempty"`
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ClinicAddressProfile describes what a clinic address offers its patients.
+type ClinicAddressProfile struct {
+	ClinicAddressId      uuid.UUID  `json:"clinic_address_id"`
+	Amenities            []string   `json:"amenities"`
+	PaymentMethods       []string   `json:"payment_methods"`
+	Languages            []string   `json:"languages"`
+	Parking              string     `json:"parking"`
+	ParkingNotes         string     `json:"parking_notes"`
+	WheelchairAccessible bool       `json:"wheelchair_accessible"`
+	Accessibility        []string   `json:"accessibility"`
+	AccessibilityNotes   string     `json:"accessibility_notes"`
+	UpdatedAt            *time.Time `json:"updated_at,omitempty"`
+}
+
+// OpeningHours is one interval of the weekly opening hours. DayOfWeek follows
+// time.Weekday (0 = Sunday) like the doctors' working hours; times are
+// "15:04:05".
+type OpeningHours struct {
+	DayOfWeek int    `json:"day_of_week"`
+	OpensAt   string `json:"opens_at"`
+	ClosesAt  string `json:"closes_at"`
+}
+
+// SpecialHours replaces the weekly hours on one date. OpensAt and ClosesAt are
+// empty when the address is closed.
+type SpecialHours struct {
+	Id              uuid.UUID `json:"id"`
+	ClinicAddressId uuid.UUID `json:"clinic_address_id"`
+	Date            string    `json:"date"`
+	IsClosed        bool      `json:"is_closed"`
+	OpensAt         string    `json:"opens_at,omitempty"`
+	ClosesAt        string    `json:"closes_at,omitempty"`
+	Note            string    `json:"note"`
+}
+
+// WorkingHours is a doctor's weekly working interval at a clinic address.
+type WorkingHours struct {
+	DoctorId  uuid.UUID
+	DayOfWeek int
+	StartTime string
+	EndTime   string
+}
+
+// ClinicAddressDetails is a clinic address with everything the clinic page
+// shows about it.
+type ClinicAddressDetails struct {
+	ClinicAddressWithNames
+	Country      string                `json:"country"`
+	City         string                `json:"city"`
+	Latitude     float64               `json:"latitude"`
+	Longitude    float64               `json:"longitude"`
+	Profile      *ClinicAddressProfile `json:"profile"`
+	OpeningHours []OpeningHours        `json:"opening_hours"`
+	SpecialHours []SpecialHours        `json:"special_hours"`
+}
+
+// ClinicDetails is a clinic with its addresses, returned by GET /clinics/{id}.
+type ClinicDetails struct {
+	*Clinic
+	Addresses []ClinicAddressDetails `json:"addresses"`
+}
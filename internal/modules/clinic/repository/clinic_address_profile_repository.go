@@ -0,0 +1,285 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"dental_clinic/internal/modules/clinic/models"
+)
+
+func (r *clinicRepo) GetAddressProfile(clinicAddressId uuid.UUID) (*models.ClinicAddressProfile, error) {
+	query := `
+		SELECT clinic_address_id, amenities, payment_methods, languages, parking, parking_notes,
+			wheelchair_accessible, accessibility, accessibility_notes, updated_at
+		FROM clinic_address_profiles
+		WHERE clinic_address_id = $1
+	`
+
+	var p models.ClinicAddressProfile
+	err := r.db.QueryRow(context.Background(), query, clinicAddressId).Scan(
+		&p.ClinicAddressId,
+		&p.Amenities,
+		&p.PaymentMethods,
+		&p.Languages,
+		&p.Parking,
+		&p.ParkingNotes,
+		&p.WheelchairAccessible,
+		&p.Accessibility,
+		&p.AccessibilityNotes,
+		&p.UpdatedAt,
+	)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get clinic address profile: %w", err)
+	}
+	return &p, nil
+}
+
+func (r *clinicRepo) UpsertAddressProfile(profile *models.ClinicAddressProfile) error {
+	query := `
+		INSERT INTO clinic_address_profiles (clinic_address_id, amenities, payment_methods, languages, parking,
+			parking_notes, wheelchair_accessible, accessibility, accessibility_notes, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW())
+		ON CONFLICT (clinic_address_id) DO UPDATE
+		SET amenities = EXCLUDED.amenities,
+			payment_methods = EXCLUDED.payment_methods,
+			languages = EXCLUDED.languages,
+			parking = EXCLUDED.parking,
+			parking_notes = EXCLUDED.parking_notes,
+			wheelchair_accessible = EXCLUDED.wheelchair_accessible,
+			accessibility = EXCLUDED.accessibility,
+			accessibility_notes = EXCLUDED.accessibility_notes,
+			updated_at = NOW()
+		RETURNING updated_at
+	`
+
+	err := r.db.QueryRow(
+		context.Background(),
+		query,
+		profile.ClinicAddressId,
+		profile.Amenities,
+		profile.PaymentMethods,
+		profile.Languages,
+		profile.Parking,
+		profile.ParkingNotes,
+		profile.WheelchairAccessible,
+		profile.Accessibility,
+		profile.AccessibilityNotes,
+	).Scan(&profile.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save clinic address profile: %w", err)
+	}
+	return nil
+}
+
+func (r *clinicRepo) GetOpeningHours(clinicAddressId uuid.UUID) ([]models.OpeningHours, error) {
+	query := `
+		SELECT day_of_week, opens_at::text, closes_at::text
+		FROM clinic_address_opening_hours
+		WHERE clinic_address_id = $1
+		ORDER BY day_of_week, opens_at
+	`
+
+	rows, err := r.db.Query(context.Background(), query, clinicAddressId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get opening hours: %w", err)
+	}
+	defer rows.Close()
+
+	hours := make([]models.OpeningHours, 0)
+	for rows.Next() {
+		var h models.OpeningHours
+		if err := rows.Scan(&h.DayOfWeek, &h.OpensAt, &h.ClosesAt); err != nil {
+			return nil, fmt.Errorf("failed to scan opening hours: %w", err)
+		}
+		hours = append(hours, h)
+	}
+	return hours, rows.Err()
+}
+
+// ReplaceOpeningHours swaps the whole weekly schedule of the address for hours.
+func (r *clinicRepo) ReplaceOpeningHours(clinicAddressId uuid.UUID, hours []models.OpeningHours) error {
+	ctx := context.Background()
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM clinic_address_opening_hours WHERE clinic_address_id = $1`, clinicAddressId); err != nil {
+		return fmt.Errorf("failed to clear opening hours: %w", err)
+	}
+
+	query := `
+		INSERT INTO clinic_address_opening_hours (id, clinic_address_id, day_of_week, opens_at, closes_at)
+		VALUES ($1, $2, $3, $4::time, $5::time)
+	`
+	for _, h := range hours {
+		if _, err := tx.Exec(ctx, query, uuid.New(), clinicAddressId, h.DayOfWeek, h.OpensAt, h.ClosesAt); err != nil {
+			return fmt.Errorf("failed to save opening hours: %w", err)
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+const specialHoursColumns = `id, clinic_address_id, date::text, is_closed, COALESCE(opens_at::text, ''), COALESCE(closes_at::text, ''), note`
+
+func scanSpecialHours(row pgx.Row) (models.SpecialHours, error) {
+	var h models.SpecialHours
+	err := row.Scan(&h.Id, &h.ClinicAddressId, &h.Date, &h.IsClosed, &h.OpensAt, &h.ClosesAt, &h.Note)
+	return h, err
+}
+
+// GetSpecialHours returns the special hours of the address between from and
+// to, both inclusive.
+func (r *clinicRepo) GetSpecialHours(clinicAddressId uuid.UUID, from, to time.Time) ([]models.SpecialHours, error) {
+	query := `
+		SELECT ` + specialHoursColumns + `
+		FROM clinic_address_special_hours
+		WHERE clinic_address_id = $1 AND date BETWEEN $2::date AND $3::date
+		ORDER BY date
+	`
+
+	rows, err := r.db.Query(context.Background(), query, clinicAddressId, from.Format("2006-01-02"), to.Format("2006-01-02"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get special hours: %w", err)
+	}
+	defer rows.Close()
+
+	hours := make([]models.SpecialHours, 0)
+	for rows.Next() {
+		h, err := scanSpecialHours(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan special hours: %w", err)
+		}
+		hours = append(hours, h)
+	}
+	return hours, rows.Err()
+}
+
+func (r *clinicRepo) GetSpecialHoursByDate(clinicAddressId uuid.UUID, date time.Time) (*models.SpecialHours, error) {
+	query := `
+		SELECT ` + specialHoursColumns + `
+		FROM clinic_address_special_hours
+		WHERE clinic_address_id = $1 AND date = $2::date
+	`
+
+	h, err := scanSpecialHours(r.db.QueryRow(context.Background(), query, clinicAddressId, date.Format("2006-01-02")))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get special hours: %w", err)
+	}
+	return &h, nil
+}
+
+// UpsertSpecialHours saves the special hours for their date and removes the
+// free slots of that date that fall outside them. Booked slots are kept. It
+// returns the number of removed slots.
+func (r *clinicRepo) UpsertSpecialHours(hours *models.SpecialHours) (int64, error) {
+	ctx := context.Background()
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO clinic_address_special_hours (id, clinic_address_id, date, is_closed, opens_at, closes_at, note)
+		VALUES ($1, $2, $3::date, $4, NULLIF($5, '')::time, NULLIF($6, '')::time, $7)
+		ON CONFLICT (clinic_address_id, date) DO UPDATE
+		SET is_closed = EXCLUDED.is_closed,
+			opens_at = EXCLUDED.opens_at,
+			closes_at = EXCLUDED.closes_at,
+			note = EXCLUDED.note
+		RETURNING id
+	`
+	err = tx.QueryRow(
+		ctx,
+		query,
+		hours.Id,
+		hours.ClinicAddressId,
+		hours.Date,
+		hours.IsClosed,
+		hours.OpensAt,
+		hours.ClosesAt,
+		hours.Note,
+	).Scan(&hours.Id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to save special hours: %w", err)
+	}
+
+	result, err := tx.Exec(ctx, `
+		DELETE FROM doctor_time_slots
+		WHERE clinic_address_id = $1
+			AND status = 'available'
+			AND slot_start::date = $2::date
+			AND ($3::boolean OR slot_start::time < $4::time OR slot_end::time > $5::time)
+	`, hours.ClinicAddressId, hours.Date, hours.IsClosed, nullableTime(hours.OpensAt), nullableTime(hours.ClosesAt))
+	if err != nil {
+		return 0, fmt.Errorf("failed to remove slots outside special hours: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+func (r *clinicRepo) DeleteSpecialHours(clinicAddressId uuid.UUID, date time.Time) error {
+	result, err := r.db.Exec(
+		context.Background(),
+		`DELETE FROM clinic_address_special_hours WHERE clinic_address_id = $1 AND date = $2::date`,
+		clinicAddressId,
+		date.Format("2006-01-02"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete special hours: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// GetWorkingHoursAtAddress returns the weekly working hours of every doctor
+// working at the address.
+func (r *clinicRepo) GetWorkingHoursAtAddress(clinicAddressId uuid.UUID) ([]models.WorkingHours, error) {
+	query := `
+		SELECT doctor_id, day_of_week, start_time::text, end_time::text
+		FROM doctor_working_hours
+		WHERE clinic_address_id = $1
+		ORDER BY day_of_week, start_time
+	`
+
+	rows, err := r.db.Query(context.Background(), query, clinicAddressId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get working hours: %w", err)
+	}
+	defer rows.Close()
+
+	hours := make([]models.WorkingHours, 0)
+	for rows.Next() {
+		var h models.WorkingHours
+		if err := rows.Scan(&h.DoctorId, &h.DayOfWeek, &h.StartTime, &h.EndTime); err != nil {
+			return nil, fmt.Errorf("failed to scan working hours: %w", err)
+		}
+		hours = append(hours, h)
+	}
+	return hours, rows.Err()
+}
+
+func nullableTime(v string) any {
+	if v == "" {
+		return nil
+	}
+	return v
+}
@@ -3,19 +3,22 @@ package repository
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 
 	// "dental_clinic/internal"
 
+	"dental_clinic/internal/geo"
 	"dental_clinic/internal/modules/clinic/models"
+	"dental_clinic/internal/search"
 	// "github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type ClinicRepository interface {
 	Create(clinic *models.Clinic) (*models.Clinic, error)
-	GetAll() ([]*models.Clinic, error)
+	Search(p search.Params, sortName string, sort search.Sort) (search.Result[*models.Clinic], error)
 	GetByID(id uuid.UUID) (*models.Clinic, error)
 	Update(clinic *models.Clinic) (*models.Clinic, error)
 	Delete(id uuid.UUID) error
@@ -25,6 +28,7 @@ type ClinicRepository interface {
 	GetClinicAddress(id uuid.UUID) ([]models.ClinicAddress, error)
 	GetClinicAddressByID(id uuid.UUID) (*models.ClinicAddress, error)
 	GetClinicByAddressId(id uuid.UUID) (string, error)
+	IsDoctorAtAddress(doctorId, clinicAddressId uuid.UUID) (bool, error)
 	DeleteAddress(id, address_id uuid.UUID) error
 	UpdateAddressCover(id uuid.UUID, coverURL string) error
 	DeleteAddressCover(id uuid.UUID) error
@@ -33,6 +37,16 @@ type ClinicRepository interface {
 	GetGalleryImage(id uuid.UUID) (*models.ClinicAddressGalleryImage, error)
 	UpdateGalleryImage(id uuid.UUID, imageURL string) error
 	DeleteGalleryImage(id uuid.UUID) error
+	GetNearbyAddresses(point geo.Point, radiusKm float64, serviceId uuid.UUID, limit int) ([]models.NearbyClinicAddress, error)
+	GetAddressProfile(clinicAddressId uuid.UUID) (*models.ClinicAddressProfile, error)
+	UpsertAddressProfile(profile *models.ClinicAddressProfile) error
+	GetOpeningHours(clinicAddressId uuid.UUID) ([]models.OpeningHours, error)
+	ReplaceOpeningHours(clinicAddressId uuid.UUID, hours []models.OpeningHours) error
+	GetSpecialHours(clinicAddressId uuid.UUID, from, to time.Time) ([]models.SpecialHours, error)
+	GetSpecialHoursByDate(clinicAddressId uuid.UUID, date time.Time) (*models.SpecialHours, error)
+	UpsertSpecialHours(hours *models.SpecialHours) (int64, error)
+	DeleteSpecialHours(clinicAddressId uuid.UUID, date time.Time) error
+	GetWorkingHoursAtAddress(clinicAddressId uuid.UUID) ([]models.WorkingHours, error)
 }
 
 type clinicRepo struct {
@@ -68,35 +82,124 @@ func (r *clinicRepo) Create(clinic *models.Clinic) (*models.Clinic, error) {
 	return clinic, nil
 }
 
-func (r *clinicRepo) GetAll() ([]*models.Clinic, error) {
+// ClinicSorts are the orderings offered by Search.
+var ClinicSorts = map[string]search.Sort{
+	"relevance": {Expr: "ts_rank(c.search_vector, query)::float8", Type: "float8", Desc: true},
+	"rating":    {Expr: "(" + search.RatingScore + ")::float8", Type: "float8", Desc: true},
+	"name":      {Expr: "LOWER(COALESCE(c.name, ''))", Type: "text"},
+	"newest":    {Expr: "EXTRACT(EPOCH FROM COALESCE(c.created_at, 'epoch'))::float8", Type: "float8", Desc: true},
+	"price":     {Expr: "COALESCE(price.min_price::float8, 'Infinity'::float8)", Type: "float8"},
+}
+
+// Search returns one page of active clinics matching p, ordered by sort.
+// The text query matches the clinic's name and description as well as the
+// names of the services it offers. Price filters and min_price apply to the
+// requested service, or to any service when none is given.
+func (r *clinicRepo) Search(p search.Params, sortName string, sort search.Sort) (search.Result[*models.Clinic], error) {
+	b := search.NewBuilder()
+	textQuery := b.TextQuery(p.Query)
+
+	offer := "cs.clinic_id = c.id AND cs.is_active = true"
+	if p.ServiceID != uuid.Nil {
+		offer += " AND cs.service_id = " + b.Arg(p.ServiceID)
+	}
+	if p.PriceMin != nil {
+		offer += " AND cs.price >= " + b.Arg(*p.PriceMin)
+	}
+	if p.PriceMax != nil {
+		offer += " AND cs.price <= " + b.Arg(*p.PriceMax)
+	}
+
+	b.Where("c.is_active = true")
+	if p.Query != "" {
+		b.Where(`(c.search_vector @@ query OR EXISTS (
+				SELECT 1 FROM clinic_services cs
+				JOIN services sv ON sv.id = cs.service_id
+				WHERE cs.clinic_id = c.id AND cs.is_active = true AND sv.search_vector @@ query
+			))`)
+	}
+	if p.ServiceID != uuid.Nil || p.PriceMin != nil || p.PriceMax != nil {
+		b.Where("price.min_price IS NOT NULL")
+	}
+	if p.City != "" {
+		b.Where(`EXISTS (
+				SELECT 1 FROM clinic_addresses ca
+				JOIN addresses a ON a.id = ca.address_id
+				WHERE ca.clinic_id = c.id AND LOWER(a.city) = LOWER(` + b.Arg(p.City) + `)
+			)`)
+	}
+	if p.MinRating > 0 {
+		b.Where("COALESCE(s.rating_sum::float8 / NULLIF(s.rating_count, 0), 0) >= " + b.Arg(p.MinRating))
+	}
+	if p.Language != "" || p.Specialization != "" {
+		doctor := `d.is_deleted = 0 AND EXISTS (
+				SELECT 1 FROM doctor_clinics dc
+				WHERE dc.doctor_id = d.id AND dc.clinic_id = c.id AND dc.status = 'active'
+			)`
+		if p.Language != "" {
+			doctor += " AND " + b.Arg(p.Language) + " = ANY(d.languages)"
+		}
+		if p.Specialization != "" {
+			specialization := b.Arg(p.Specialization)
+			doctor += ` AND (LOWER(d.specialization) = LOWER(` + specialization + `) OR EXISTS (
+					SELECT 1 FROM doctor_specializations sp
+					WHERE sp.doctor_id = d.id AND LOWER(sp.specialization) = LOWER(` + specialization + `)
+				))`
+		}
+		b.Where("EXISTS (SELECT 1 FROM doctors d WHERE " + doctor + ")")
+	}
+	if p.AvailableWithin > 0 {
+		b.Where(`EXISTS (
+				SELECT 1 FROM doctor_time_slots ts
+				JOIN clinic_addresses ca ON ca.id = ts.clinic_address_id
+				WHERE ca.clinic_id = c.id
+					AND ts.status = 'available'
+					AND ts.slot_start > NOW()
+					AND ts.slot_start < NOW() + make_interval(days => ` + b.Arg(p.AvailableWithin) + `)
+			)`)
+	}
+	page := b.Page(sort, "c.id", p.Cursor, p.Limit)
+
 	query := `
+		WITH prior AS (
+			SELECT COALESCE(SUM(rating_sum)::float8 / NULLIF(SUM(rating_count), 0), 0) AS mean
+			FROM clinic_rating_stats
+		)
 		SELECT
 			c.id,
-			c.name,
-			c.description,
-			c.phone,
-			c.email,
-			c.website,
+			COALESCE(c.name, ''),
+			COALESCE(c.description, ''),
+			COALESCE(c.phone, ''),
+			COALESCE(c.email, ''),
+			COALESCE(c.website, ''),
 			c.is_active,
 			c.created_at,
 			COALESCE(c.logo_url, ''),
-			COALESCE(ROUND(AVG(cr.rating)::numeric, 2), 0)::float8 AS rating
+			` + clinicRatingColumns + `,
+			price.min_price::float8,
+			(` + sort.Expr + `)::text
 		FROM clinics c
-		LEFT JOIN clinic_reviews cr ON cr.clinic_id = c.id
-		WHERE c.is_active = true
-		GROUP BY c.id, c.name, c.description, c.phone, c.email, c.website, c.is_active, c.created_at, c.logo_url
-		ORDER BY rating DESC, c.created_at DESC
-	`
-
-	rows, err := r.db.Query(context.Background(), query)
+		LEFT JOIN clinic_rating_stats s ON s.clinic_id = c.id
+		CROSS JOIN prior
+		` + textQuery + `
+		LEFT JOIN LATERAL (
+			SELECT MIN(cs.price) AS min_price FROM clinic_services cs WHERE ` + offer + `
+		) price ON TRUE
+		WHERE ` + b.WhereSQL() + `
+		` + page
+
+	rows, err := r.db.Query(context.Background(), query, b.Args()...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get clinics: %w", err)
+		return search.Result[*models.Clinic]{}, fmt.Errorf("failed to search clinics: %w", err)
 	}
 	defer rows.Close()
 
-	var clinics []*models.Clinic
+	clinics := make([]*models.Clinic, 0)
+	keys := make([]string, 0)
+	ids := make([]uuid.UUID, 0)
 	for rows.Next() {
 		clinic := &models.Clinic{}
+		var key string
 		err := rows.Scan(
 			&clinic.Id,
 			&clinic.Name,
@@ -108,18 +211,30 @@ func (r *clinicRepo) GetAll() ([]*models.Clinic, error) {
 			&clinic.CreatedAt,
 			&clinic.LogoURL,
 			&clinic.Rating,
+			&clinic.RatingCount,
+			&clinic.RatingDistribution[0],
+			&clinic.RatingDistribution[1],
+			&clinic.RatingDistribution[2],
+			&clinic.RatingDistribution[3],
+			&clinic.RatingDistribution[4],
+			&clinic.RecentRating,
+			&clinic.RatingTrend,
+			&clinic.MinPrice,
+			&key,
 		)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan clinic: %w", err)
+			return search.Result[*models.Clinic]{}, fmt.Errorf("failed to scan clinic: %w", err)
 		}
 		clinics = append(clinics, clinic)
+		keys = append(keys, key)
+		ids = append(ids, clinic.Id)
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating clinics: %w", err)
+		return search.Result[*models.Clinic]{}, fmt.Errorf("error iterating clinics: %w", err)
 	}
 
-	return clinics, nil
+	return search.Trim(clinics, keys, ids, sortName, p.Limit), nil
 }
 
 func (r *clinicRepo) GetByID(id uuid.UUID) (*models.Clinic, error) {
@@ -134,11 +249,10 @@ func (r *clinicRepo) GetByID(id uuid.UUID) (*models.Clinic, error) {
 			c.is_active,
 			c.created_at,
 			COALESCE(c.logo_url, ''),
-			COALESCE(ROUND(AVG(cr.rating)::numeric, 2), 0)::float8 AS rating
+			` + clinicRatingColumns + `
 		FROM clinics c
-		LEFT JOIN clinic_reviews cr ON cr.clinic_id = c.id
+		LEFT JOIN clinic_rating_stats s ON s.clinic_id = c.id
 		WHERE c.id = $1
-		GROUP BY c.id, c.name, c.description, c.phone, c.email, c.website, c.is_active, c.created_at, c.logo_url
 	`
 
 	clinic := &models.Clinic{}
@@ -153,6 +267,14 @@ func (r *clinicRepo) GetByID(id uuid.UUID) (*models.Clinic, error) {
 		&clinic.CreatedAt,
 		&clinic.LogoURL,
 		&clinic.Rating,
+		&clinic.RatingCount,
+		&clinic.RatingDistribution[0],
+		&clinic.RatingDistribution[1],
+		&clinic.RatingDistribution[2],
+		&clinic.RatingDistribution[3],
+		&clinic.RatingDistribution[4],
+		&clinic.RecentRating,
+		&clinic.RatingTrend,
 	)
 
 	if err != nil {
@@ -455,3 +577,141 @@ func (r *clinicRepo) GetClinicByAddressId(id uuid.UUID) (string, error) {
 
 	return clinic_id, nil
 }
+
+// IsDoctorAtAddress reports whether the doctor currently works at the clinic
+// the address belongs to.
+func (r *clinicRepo) IsDoctorAtAddress(doctorId, clinicAddressId uuid.UUID) (bool, error) {
+	var works bool
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM clinic_addresses ca
+			JOIN doctor_clinics dc ON dc.clinic_id = ca.clinic_id
+			WHERE ca.id = $2 AND dc.doctor_id = $1 AND dc.status = 'active'
+		)
+	`
+	err := r.db.QueryRow(context.Background(), query, doctorId, clinicAddressId).Scan(&works)
+	return works, err
+}
+
+// clinicRatingColumns reads the aggregate joined as s: mean, count, the
+// distribution of 1-5 stars, the mean of the last 90 days and its change
+// against the 90 days before.
+const clinicRatingColumns = `
+	COALESCE(ROUND(s.rating_sum::numeric / NULLIF(s.rating_count, 0), 2), 0)::float8,
+	COALESCE(s.rating_count, 0),
+	COALESCE(s.count_1, 0),
+	COALESCE(s.count_2, 0),
+	COALESCE(s.count_3, 0),
+	COALESCE(s.count_4, 0),
+	COALESCE(s.count_5, 0),
+	COALESCE(ROUND(s.recent_sum::numeric / NULLIF(s.recent_count, 0), 2), 0)::float8,
+	CASE WHEN s.recent_count > 0 AND s.previous_count > 0
+		THEN ROUND(s.recent_sum::numeric / s.recent_count - s.previous_sum::numeric / s.previous_count, 2)
+		ELSE 0
+	END::float8
+`
+
+// GetNearbyAddresses returns addresses of active clinics within radiusKm of
+// point, closest first, with the next free slot of the address. When
+// serviceId is set only clinics offering that service are returned, together
+// with its price and duration.
+func (r *clinicRepo) GetNearbyAddresses(point geo.Point, radiusKm float64, serviceId uuid.UUID, limit int) ([]models.NearbyClinicAddress, error) {
+	box := geo.BoundingBox(point, radiusKm)
+	distance := geo.DistanceSQL("a.latitude", "a.longitude", "$1", "$2")
+
+	query := `
+		SELECT
+			c.id,
+			COALESCE(c.name, ''),
+			COALESCE(c.logo_url, ''),
+			ca.id,
+			COALESCE(a.country, ''),
+			COALESCE(a.city, ''),
+			COALESCE(a.street, ''),
+			COALESCE(a.building, ''),
+			a.latitude::float8,
+			a.longitude::float8,
+			` + distance + ` AS distance_km,
+			COALESCE(ROUND(s.rating_sum::numeric / NULLIF(s.rating_count, 0), 2), 0)::float8,
+			COALESCE(s.rating_count, 0),
+			offer.price,
+			offer.duration_minutes,
+			slot.next_slot
+		FROM clinic_addresses ca
+		JOIN clinics c ON c.id = ca.clinic_id AND c.is_active = true
+		JOIN addresses a ON a.id = ca.address_id
+		LEFT JOIN clinic_rating_stats s ON s.clinic_id = c.id
+		LEFT JOIN LATERAL (
+			SELECT cs.price::float8 AS price, cs.duration_minutes
+			FROM clinic_services cs
+			WHERE cs.clinic_id = c.id AND cs.service_id = $8 AND cs.is_active = true
+			LIMIT 1
+		) offer ON TRUE
+		LEFT JOIN LATERAL (
+			SELECT MIN(ts.slot_start) AS next_slot
+			FROM doctor_time_slots ts
+			JOIN doctors d ON d.id = ts.doctor_id AND d.is_deleted = 0 AND d.is_available = true
+			WHERE ts.clinic_address_id = ca.id
+				AND ts.status = 'available'
+				AND ts.slot_start > NOW()
+		) slot ON TRUE
+		WHERE a.latitude IS NOT NULL
+			AND a.longitude IS NOT NULL
+			AND a.latitude BETWEEN $3 AND $4
+			AND a.longitude BETWEEN $5 AND $6
+			AND ` + distance + ` <= $7
+			AND ($8::uuid IS NULL OR offer.price IS NOT NULL)
+		ORDER BY distance_km, c.name
+		LIMIT $9
+	`
+
+	var service any
+	if serviceId != uuid.Nil {
+		service = serviceId
+	}
+
+	rows, err := r.db.Query(
+		context.Background(),
+		query,
+		point.Lat,
+		point.Lng,
+		box.MinLat,
+		box.MaxLat,
+		box.MinLng,
+		box.MaxLng,
+		radiusKm,
+		service,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get nearby clinics: %w", err)
+	}
+	defer rows.Close()
+
+	addresses := make([]models.NearbyClinicAddress, 0)
+	for rows.Next() {
+		var a models.NearbyClinicAddress
+		if err := rows.Scan(
+			&a.ClinicId,
+			&a.ClinicName,
+			&a.LogoURL,
+			&a.ClinicAddressId,
+			&a.Country,
+			&a.City,
+			&a.Street,
+			&a.Building,
+			&a.Latitude,
+			&a.Longitude,
+			&a.DistanceKm,
+			&a.Rating,
+			&a.RatingCount,
+			&a.Price,
+			&a.Duration,
+			&a.NextAvailableSlot,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan nearby clinic: %w", err)
+		}
+		addresses = append(addresses, a)
+	}
+	return addresses, rows.Err()
+}
@@ -29,7 +29,8 @@ func RegisterPublicRoutes(r *mux.Router, db *pgxpool.Pool, cfg *config.Config) {
 	r.HandleFunc("/clinics/{id}/address", handler.GetClinicAddress).Methods("GET")
 	r.HandleFunc("/clinics/{id}/address-names", handler.GetClinicAddress).Methods("GET")
 	r.HandleFunc("/clinic-addresses/{id}/gallery", handler.GetClinicAddressGallery).Methods("GET")
-
+	r.HandleFunc("/clinic-addresses/nearby", handler.GetNearbyClinicAddresses).Methods("GET")
+	r.HandleFunc("/clinic-addresses/{id}/profile", handler.GetClinicAddressProfile).Methods("GET")
 }
 
 func RegisterPrivateRoutes(r *mux.Router, db *pgxpool.Pool, cfg *config.Config) {
@@ -55,4 +56,8 @@ func RegisterPrivateRoutes(r *mux.Router, db *pgxpool.Pool, cfg *config.Config)
 	r.HandleFunc("/clinic-addresses/{id}/gallery", handler.AddClinicAddressGalleryImage).Methods("POST")
 	r.HandleFunc("/clinic-addresses/{id}/gallery/{imageId}", handler.UpdateClinicAddressGalleryImage).Methods("PUT")
 	r.HandleFunc("/clinic-addresses/{id}/gallery/{imageId}", handler.DeleteClinicAddressGalleryImage).Methods("DELETE")
+	r.HandleFunc("/clinic-addresses/{id}/profile", handler.UpdateClinicAddressProfile).Methods("PUT")
+	r.HandleFunc("/clinic-addresses/{id}/opening-hours", handler.SetClinicAddressOpeningHours).Methods("PUT")
+	r.HandleFunc("/clinic-addresses/{id}/special-hours", handler.SetClinicAddressSpecialHours).Methods("POST")
+	r.HandleFunc("/clinic-addresses/{id}/special-hours/{date}", handler.DeleteClinicAddressSpecialHours).Methods("DELETE")
 }
@@ -0,0 +1,327 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"dental_clinic/internal/modules/clinic/dto"
+	"dental_clinic/internal/modules/clinic/models"
+)
+
+// ErrOutsideOpeningHours is returned when a doctor would work at an address
+// while it is closed.
+var ErrOutsideOpeningHours = errors.New("working hours fall outside the clinic opening hours")
+
+var parkingOptions = map[string]bool{
+	"unknown": true,
+	"none":    true,
+	"free":    true,
+	"paid":    true,
+	"street":  true,
+}
+
+var paymentMethods = map[string]bool{
+	"cash":          true,
+	"card":          true,
+	"kaspi":         true,
+	"insurance":     true,
+	"installments":  true,
+	"bank_transfer": true,
+}
+
+// upcomingSpecialHoursDays is how far ahead the clinic page lists special hours.
+const upcomingSpecialHoursDays = 90
+
+// GetClinicDetails returns the clinic with its addresses, their profiles,
+// weekly opening hours and upcoming special hours.
+func (s *ClinicService) GetClinicDetails(clinic *models.Clinic) (*models.ClinicDetails, error) {
+	clinicAddresses, err := s.repo.GetClinicAddress(clinic.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	details := &models.ClinicDetails{
+		Clinic:    clinic,
+		Addresses: make([]models.ClinicAddressDetails, 0, len(clinicAddresses)),
+	}
+	for _, clinicAddress := range clinicAddresses {
+		d, err := s.addressDetails(clinicAddress)
+		if err != nil {
+			return nil, err
+		}
+		details.Addresses = append(details.Addresses, *d)
+	}
+	return details, nil
+}
+
+// GetClinicAddressDetails returns one clinic address the way GetClinicDetails
+// lists it.
+func (s *ClinicService) GetClinicAddressDetails(clinicAddressId uuid.UUID) (*models.ClinicAddressDetails, error) {
+	clinicAddress, err := s.repo.GetClinicAddressByID(clinicAddressId)
+	if err != nil {
+		return nil, err
+	}
+	return s.addressDetails(*clinicAddress)
+}
+
+func (s *ClinicService) addressDetails(clinicAddress models.ClinicAddress) (*models.ClinicAddressDetails, error) {
+	address, err := s.addressSrv.GetAddressByID(clinicAddress.AddressId.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get address: %w", err)
+	}
+
+	d := &models.ClinicAddressDetails{
+		ClinicAddressWithNames: models.ClinicAddressWithNames{
+			Id:              clinicAddress.Id,
+			ClinicId:        clinicAddress.ClinicId,
+			AddressId:       clinicAddress.AddressId,
+			AddressName:     address.Street,
+			AddressBuilding: address.Building,
+			IsMain:          clinicAddress.IsMain,
+			CoverImageURL:   clinicAddress.CoverImageURL,
+		},
+		Country:   address.Country,
+		City:      address.City,
+		Latitude:  address.Latitude,
+		Longitude: address.Longitude,
+	}
+	if d.Gallery, err = s.repo.GetGalleryImages(clinicAddress.Id); err != nil {
+		return nil, fmt.Errorf("failed to get clinic address gallery: %w", err)
+	}
+	if d.Profile, err = s.GetAddressProfile(clinicAddress.Id); err != nil {
+		return nil, err
+	}
+	if d.OpeningHours, err = s.repo.GetOpeningHours(clinicAddress.Id); err != nil {
+		return nil, err
+	}
+	today := time.Now().UTC()
+	if d.SpecialHours, err = s.repo.GetSpecialHours(clinicAddress.Id, today, today.AddDate(0, 0, upcomingSpecialHoursDays)); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (s *ClinicService) GetAddressProfile(clinicAddressId uuid.UUID) (*models.ClinicAddressProfile, error) {
+	profile, err := s.repo.GetAddressProfile(clinicAddressId)
+	if err != nil {
+		return nil, err
+	}
+	if profile == nil {
+		// not filled in yet
+		profile = &models.ClinicAddressProfile{
+			ClinicAddressId: clinicAddressId,
+			Amenities:       []string{},
+			PaymentMethods:  []string{},
+			Languages:       []string{},
+			Parking:         "unknown",
+			Accessibility:   []string{},
+		}
+	}
+	return profile, nil
+}
+
+func (s *ClinicService) UpdateAddressProfile(clinicAddressId uuid.UUID, req dto.UpdateAddressProfileRequest) (*models.ClinicAddressProfile, error) {
+	parking := strings.ToLower(strings.TrimSpace(req.Parking))
+	if parking == "" {
+		parking = "unknown"
+	}
+	if !parkingOptions[parking] {
+		return nil, fmt.Errorf("invalid parking: %s", req.Parking)
+	}
+
+	methods := normalizeTags(req.PaymentMethods)
+	for _, method := range methods {
+		if !paymentMethods[method] {
+			return nil, fmt.Errorf("invalid payment method: %s", method)
+		}
+	}
+
+	profile := &models.ClinicAddressProfile{
+		ClinicAddressId:      clinicAddressId,
+		Amenities:            normalizeTags(req.Amenities),
+		PaymentMethods:       methods,
+		Languages:            normalizeTags(req.Languages),
+		Parking:              parking,
+		ParkingNotes:         strings.TrimSpace(req.ParkingNotes),
+		WheelchairAccessible: req.WheelchairAccessible,
+		Accessibility:        normalizeTags(req.Accessibility),
+		AccessibilityNotes:   strings.TrimSpace(req.AccessibilityNotes),
+	}
+	if err := s.repo.UpsertAddressProfile(profile); err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+func (s *ClinicService) GetOpeningHours(clinicAddressId uuid.UUID) ([]models.OpeningHours, error) {
+	return s.repo.GetOpeningHours(clinicAddressId)
+}
+
+// SetOpeningHours replaces the weekly opening hours of the address. It is
+// refused while a doctor's working hours at the address would fall outside
+// them. An empty list removes the opening hours altogether.
+func (s *ClinicService) SetOpeningHours(clinicAddressId uuid.UUID, req []dto.OpeningHoursRequest) ([]models.OpeningHours, error) {
+	hours := make([]models.OpeningHours, 0, len(req))
+	for _, r := range req {
+		if r.DayOfWeek < 0 || r.DayOfWeek > 6 {
+			return nil, errors.New("day_of_week must be between 0 (Sunday) and 6 (Saturday)")
+		}
+		opensAt, closesAt, err := parseInterval(r.OpensAt, r.ClosesAt)
+		if err != nil {
+			return nil, err
+		}
+		hours = append(hours, models.OpeningHours{DayOfWeek: r.DayOfWeek, OpensAt: opensAt, ClosesAt: closesAt})
+	}
+
+	sort.Slice(hours, func(i, j int) bool {
+		if hours[i].DayOfWeek != hours[j].DayOfWeek {
+			return hours[i].DayOfWeek < hours[j].DayOfWeek
+		}
+		return hours[i].OpensAt < hours[j].OpensAt
+	})
+	for i := 1; i < len(hours); i++ {
+		if hours[i].DayOfWeek == hours[i-1].DayOfWeek && hours[i].OpensAt < hours[i-1].ClosesAt {
+			return nil, fmt.Errorf("opening hours overlap on day %d", hours[i].DayOfWeek)
+		}
+	}
+
+	if len(hours) > 0 {
+		working, err := s.repo.GetWorkingHoursAtAddress(clinicAddressId)
+		if err != nil {
+			return nil, err
+		}
+		for _, w := range working {
+			if !withinOpeningHours(hours, w.DayOfWeek, w.StartTime, w.EndTime) {
+				return nil, fmt.Errorf("%w: doctor %s works %s-%s on day %d", ErrOutsideOpeningHours, w.DoctorId, w.StartTime, w.EndTime, w.DayOfWeek)
+			}
+		}
+	}
+
+	if err := s.repo.ReplaceOpeningHours(clinicAddressId, hours); err != nil {
+		return nil, err
+	}
+	return hours, nil
+}
+
+// CheckWorkingHours tells whether a doctor may work from start to end on
+// dayOfWeek at the address. Addresses without opening hours accept any
+// working hours.
+func (s *ClinicService) CheckWorkingHours(clinicAddressId uuid.UUID, dayOfWeek int, start, end string) error {
+	start, end, err := parseInterval(start, end)
+	if err != nil {
+		return err
+	}
+
+	hours, err := s.repo.GetOpeningHours(clinicAddressId)
+	if err != nil {
+		return err
+	}
+	if len(hours) == 0 {
+		return nil
+	}
+	if !withinOpeningHours(hours, dayOfWeek, start, end) {
+		return ErrOutsideOpeningHours
+	}
+	return nil
+}
+
+// GetSpecialHours returns the special hours of the address between from and
+// to, both inclusive.
+func (s *ClinicService) GetSpecialHours(clinicAddressId uuid.UUID, from, to time.Time) ([]models.SpecialHours, error) {
+	return s.repo.GetSpecialHours(clinicAddressId, from, to)
+}
+
+// SetSpecialHours saves the hours of one date, replacing earlier special hours
+// for it. Free slots outside them are removed; the number removed is returned.
+func (s *ClinicService) SetSpecialHours(clinicAddressId uuid.UUID, req dto.SpecialHoursRequest) (*models.SpecialHours, int64, error) {
+	date, err := time.Parse("2006-01-02", req.Date)
+	if err != nil {
+		return nil, 0, errors.New("date must be YYYY-MM-DD")
+	}
+
+	hours := &models.SpecialHours{
+		Id:              uuid.New(),
+		ClinicAddressId: clinicAddressId,
+		Date:            date.Format("2006-01-02"),
+		IsClosed:        req.IsClosed,
+		Note:            strings.TrimSpace(req.Note),
+	}
+	if !req.IsClosed {
+		if hours.OpensAt, hours.ClosesAt, err = parseInterval(req.OpensAt, req.ClosesAt); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	removed, err := s.repo.UpsertSpecialHours(hours)
+	if err != nil {
+		return nil, 0, err
+	}
+	return hours, removed, nil
+}
+
+func (s *ClinicService) DeleteSpecialHours(clinicAddressId uuid.UUID, date string) error {
+	day, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return errors.New("date must be YYYY-MM-DD")
+	}
+	if err := s.repo.DeleteSpecialHours(clinicAddressId, day); err != nil {
+		return errors.New("special hours not found")
+	}
+	return nil
+}
+
+// parseInterval validates an opening interval given as "15:04" or "15:04:05"
+// and returns both ends as "15:04:05".
+func parseInterval(start, end string) (string, string, error) {
+	startTime, err := parseClock(start)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid time: %q", start)
+	}
+	endTime, err := parseClock(end)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid time: %q", end)
+	}
+	if !endTime.After(startTime) {
+		return "", "", errors.New("closing time must be after opening time")
+	}
+	return startTime.Format("15:04:05"), endTime.Format("15:04:05"), nil
+}
+
+func parseClock(v string) (time.Time, error) {
+	v = strings.TrimSpace(v)
+	if t, err := time.Parse("15:04:05", v); err == nil {
+		return t, nil
+	}
+	return time.Parse("15:04", v)
+}
+
+// withinOpeningHours reports whether start-end fits in one opening interval of
+// the day. Times are "15:04:05", so they compare as strings.
+func withinOpeningHours(hours []models.OpeningHours, dayOfWeek int, start, end string) bool {
+	for _, h := range hours {
+		if h.DayOfWeek == dayOfWeek && h.OpensAt <= start && end <= h.ClosesAt {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeTags lower-cases tags and drops blanks and duplicates.
+func normalizeTags(tags []string) []string {
+	result := make([]string, 0, len(tags))
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		result = append(result, tag)
+	}
+	return result
+}
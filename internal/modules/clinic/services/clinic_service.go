@@ -5,14 +5,20 @@ import (
 	"fmt"
 
 	"dental_clinic/internal/config"
+	"dental_clinic/internal/geo"
 	"dental_clinic/internal/modules/address/services"
 	"dental_clinic/internal/modules/clinic/dto"
 	"dental_clinic/internal/modules/clinic/models"
 	"dental_clinic/internal/modules/clinic/repository"
+	"dental_clinic/internal/search"
 
 	"github.com/google/uuid"
 )
 
+// ErrDoctorNotAtClinic is returned when a doctor is scheduled at a clinic they
+// are not an active member of.
+var ErrDoctorNotAtClinic = errors.New("doctor does not work at this clinic")
+
 type ClinicService struct {
 	repo       repository.ClinicRepository
 	cfx        config.Config
@@ -42,8 +48,14 @@ func (s *ClinicService) CreateClinic(clinic *models.Clinic) (*models.Clinic, err
 	return s.repo.Create(clinic)
 }
 
-func (s *ClinicService) GetAllClinics() ([]*models.Clinic, error) {
-	return s.repo.GetAll()
+// SearchClinics returns one page of active clinics. Without a sort the most
+// relevant clinics come first when searching by text, the best rated otherwise.
+func (s *ClinicService) SearchClinics(p search.Params) (search.Result[*models.Clinic], error) {
+	sortName, sort, err := search.ResolveSort(repository.ClinicSorts, p.Sort, "rating", p)
+	if err != nil {
+		return search.Result[*models.Clinic]{}, err
+	}
+	return s.repo.Search(p, sortName, sort)
 }
 
 func (s *ClinicService) GetClinicByID(id uuid.UUID) (*models.Clinic, error) {
@@ -261,3 +273,47 @@ func (s *ClinicService) GetClinicAddressWithName(id uuid.UUID) ([]models.ClinicA
 func (s *ClinicService) GetClinicByAddressId(id uuid.UUID) (string, error) {
 	return s.repo.GetClinicByAddressId(id)
 }
+
+// CheckDoctorAtAddress returns ErrDoctorNotAtClinic unless the doctor has an
+// active membership at the clinic of the address.
+func (s *ClinicService) CheckDoctorAtAddress(doctorId, clinicAddressId uuid.UUID) error {
+	works, err := s.repo.IsDoctorAtAddress(doctorId, clinicAddressId)
+	if err != nil {
+		return err
+	}
+	if !works {
+		return ErrDoctorNotAtClinic
+	}
+	return nil
+}
+
+const (
+	defaultNearbyRadiusKm = 10.0
+	maxNearbyRadiusKm     = 100.0
+	defaultNearbyLimit    = 20
+	maxNearbyLimit        = 100
+)
+
+// GetNearbyAddresses finds clinic addresses around point, closest first.
+// A zero radius or limit falls back to the defaults.
+func (s *ClinicService) GetNearbyAddresses(point geo.Point, radiusKm float64, serviceId uuid.UUID, limit int) ([]models.NearbyClinicAddress, error) {
+	if !point.Valid() {
+		return nil, geo.ErrInvalidPoint
+	}
+	if radiusKm == 0 {
+		radiusKm = defaultNearbyRadiusKm
+	}
+	if radiusKm < 0 || radiusKm > maxNearbyRadiusKm {
+		return nil, fmt.Errorf("radius_km must be between 0 and %.0f", maxNearbyRadiusKm)
+	}
+	if limit == 0 {
+		limit = defaultNearbyLimit
+	}
+	if limit < 0 {
+		return nil, errors.New("invalid limit")
+	}
+	if limit > maxNearbyLimit {
+		limit = maxNearbyLimit
+	}
+	return s.repo.GetNearbyAddresses(point, radiusKm, serviceId, limit)
+}
@@ -7,16 +7,19 @@ import (
 	"dental_clinic/internal/modules/clinic_admin/services"
 	userRepository "dental_clinic/internal/modules/user/repository"
 	userServices "dental_clinic/internal/modules/user/services"
+	"dental_clinic/internal/queue"
 
 	"github.com/gorilla/mux"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 func RegisterPrivateRoutes(r *mux.Router, db *pgxpool.Pool, cfg *config.Config) {
+	jobQueue := queue.New(db)
+
 	repo := repository.NewClinicAdminRepository(db)
 
 	userRepo := userRepository.NewUserRepository(db)
-	userService := userServices.NewUserService(userRepo, *cfg)
+	userService := userServices.NewUserService(userRepo, *cfg, jobQueue)
 
 	service := services.NewClinicAdminService(repo, *userService)
 	handler := handlers.NewClinicAdminHandler(service)
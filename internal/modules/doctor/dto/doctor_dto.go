@@ -5,34 +5,53 @@ type CreateDoctorRequest struct {
 	Email          string `json:"email"`
 	Specialization string `json:"specialization"`
 	Experience     int    `json:"experience"`
-	ClinicID       string `json:"clinic_id"`
 	Bio            string `json:"bio"`
 	IsAvailable    bool   `json:"is_available"`
 	Password       string `json:"password"`
 	Is_active      bool   `json:"is_active"`
+	// Languages spoken, as codes such as "ru", "kk", "en"
+	Languages []string `json:"languages"`
+	// Specializations besides the primary one in Specialization
+	Specializations []string `json:"specializations"`
+	// ClinicIDs are the clinics the doctor starts working at
+	ClinicIDs []string `json:"clinic_ids"`
 }
 
 type UpdateDoctorRequest struct {
 	Specialization string `json:"specialization"`
 	Experience     int    `json:"experience"`
 	// ClinicID       string `json:"clinic_id"`
-	Bio         string `json:"bio"`
-	IsAvailable bool   `json:"is_available"`
-	NewPassword string `json:"new_password"`
-	Is_active   bool   `json:"is_active"`
+	Bio         string   `json:"bio"`
+	IsAvailable bool     `json:"is_available"`
+	NewPassword string   `json:"new_password"`
+	Is_active   bool     `json:"is_active"`
+	Languages   []string `json:"languages"`
+	// Specializations replaces the additional specializations when present
+	Specializations []string `json:"specializations"`
 }
 
 type DoctorResponse struct {
-	Id             string  `json:"id"`
-	Specialization string  `json:"specialization"`
-	Name           string  `json:"name"`
-	Email          string  `json:"email"`
-	Experience     int     `json:"experience"`
-	ClinicID       string  `json:"clinic_id"`
-	Bio            string  `json:"bio"`
-	IsAvailable    bool    `json:"is_available"`
-	Rating         float64 `json:"rating"`
-	PhotoURL       string  `json:"photo_url"`
+	Id             string   `json:"id"`
+	Specialization string   `json:"specialization"`
+	Name           string   `json:"name"`
+	Email          string   `json:"email"`
+	Experience     int      `json:"experience"`
+	Bio            string   `json:"bio"`
+	IsAvailable    bool     `json:"is_available"`
+	Rating         float64  `json:"rating"`
+	PhotoURL       string   `json:"photo_url"`
+	Languages      []string `json:"languages"`
+	// Specializations lists every specialization, the primary one first
+	Specializations []string `json:"specializations"`
+	// ClinicIDs are the clinics the doctor currently works at
+	ClinicIDs []string `json:"clinic_ids"`
+
+	RatingCount        int     `json:"rating_count"`
+	RatingDistribution [5]int  `json:"rating_distribution"`
+	RecentRating       float64 `json:"recent_rating"`
+	RatingTrend        float64 `json:"rating_trend"`
+
+	MinPrice *float64 `json:"min_price,omitempty"`
 }
 
 type DoctorActionResponse struct {
@@ -55,3 +74,121 @@ type GetMedicalRecordDoctorResponse struct {
 type DoctorPhotoRequest struct {
 	PhotoURL string `json:"photo_url"`
 }
+
+type EducationRequest struct {
+	Institution  string `json:"institution"`
+	Degree       string `json:"degree"`
+	FieldOfStudy string `json:"field_of_study"`
+	StartYear    *int   `json:"start_year"`
+	EndYear      *int   `json:"end_year"`
+}
+
+type EducationResponse struct {
+	Id           string `json:"id"`
+	Institution  string `json:"institution"`
+	Degree       string `json:"degree"`
+	FieldOfStudy string `json:"field_of_study"`
+	StartYear    *int   `json:"start_year,omitempty"`
+	EndYear      *int   `json:"end_year,omitempty"`
+}
+
+// CredentialRequest holds the form fields sent with a credential document.
+// Dates are YYYY-MM-DD.
+type CredentialRequest struct {
+	Type      string
+	Title     string
+	Issuer    string
+	Number    string
+	IssuedAt  string
+	ExpiresAt string
+}
+
+type CredentialResponse struct {
+	Id           string `json:"id"`
+	DoctorId     string `json:"doctor_id"`
+	Type         string `json:"type"`
+	Title        string `json:"title"`
+	Issuer       string `json:"issuer"`
+	Number       string `json:"number,omitempty"`
+	IssuedAt     string `json:"issued_at,omitempty"`
+	ExpiresAt    string `json:"expires_at,omitempty"`
+	Status       string `json:"status"`
+	HasDocument  bool   `json:"has_document"`
+	DocumentName string `json:"document_name,omitempty"`
+	ReviewNote   string `json:"review_note,omitempty"`
+	ReviewedAt   string `json:"reviewed_at,omitempty"`
+	CreatedAt    string `json:"created_at"`
+}
+
+type ReviewCredentialRequest struct {
+	Note string `json:"note"`
+}
+
+// DoctorProfileResponse is the public doctor page: the doctor, education and
+// verified credentials.
+type DoctorProfileResponse struct {
+	Doctor      DoctorResponse       `json:"doctor"`
+	Education   []EducationResponse  `json:"education"`
+	Credentials []CredentialResponse `json:"credentials"`
+}
+
+// ClinicMembershipRequest adds a doctor to a clinic. StartedAt is YYYY-MM-DD
+// and defaults to today.
+type ClinicMembershipRequest struct {
+	ClinicID          string   `json:"clinic_id"`
+	CommissionPercent float64  `json:"commission_percent"`
+	CommissionFixed   float64  `json:"commission_fixed"`
+	StartedAt         string   `json:"started_at"`
+	ClinicServiceIDs  []string `json:"clinic_service_ids"`
+}
+
+type UpdateClinicMembershipRequest struct {
+	// Status is active, suspended or ended
+	Status            string  `json:"status"`
+	CommissionPercent float64 `json:"commission_percent"`
+	CommissionFixed   float64 `json:"commission_fixed"`
+	// ClinicServiceIDs replaces the doctor's services at the clinic when present
+	ClinicServiceIDs []string `json:"clinic_service_ids"`
+}
+
+type ClinicMembershipResponse struct {
+	Id                string   `json:"id"`
+	ClinicID          string   `json:"clinic_id"`
+	ClinicName        string   `json:"clinic_name"`
+	Status            string   `json:"status"`
+	CommissionPercent float64  `json:"commission_percent"`
+	CommissionFixed   float64  `json:"commission_fixed"`
+	StartedAt         string   `json:"started_at"`
+	EndedAt           string   `json:"ended_at,omitempty"`
+	ClinicServiceIDs  []string `json:"clinic_service_ids"`
+}
+
+// ServiceAssignmentRequest assigns a doctor to a clinic service. Price and
+// Duration override the clinic's when set.
+type ServiceAssignmentRequest struct {
+	ClinicServiceID string   `json:"clinic_service_id"`
+	Price           *float64 `json:"price"`
+	Duration        *int     `json:"duration"`
+}
+
+// UpdateServiceAssignmentRequest replaces the overrides; null falls back to
+// the clinic's price or duration.
+type UpdateServiceAssignmentRequest struct {
+	Price    *float64 `json:"price"`
+	Duration *int     `json:"duration"`
+}
+
+type ServiceAssignmentResponse struct {
+	Id              string `json:"id"`
+	ClinicServiceID string `json:"clinic_service_id"`
+	ClinicID        string `json:"clinic_id"`
+	ClinicName      string `json:"clinic_name"`
+	ServiceID       string `json:"service_id"`
+	ServiceName     string `json:"service_name"`
+	// Price and Duration are what patients are charged and booked for
+	Price            float64  `json:"price"`
+	Duration         int      `json:"duration"`
+	PriceOverride    *float64 `json:"price_override,omitempty"`
+	DurationOverride *int     `json:"duration_override,omitempty"`
+	Bookable         bool     `json:"bookable"`
+}
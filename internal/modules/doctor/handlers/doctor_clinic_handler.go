@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"dental_clinic/internal/modules/doctor/dto"
+	"dental_clinic/internal/modules/doctor/services"
+
+	"github.com/gorilla/mux"
+)
+
+// GetDoctorClinics godoc
+// @Summary List doctor clinics
+// @Description Lists the clinics a doctor works or has worked at with status, commission terms and services. Platform admins and the doctor see all of them, clinic admins only their own clinics.
+// @Tags Doctors
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Doctor ID"
+// @Success 200 {array} dto.ClinicMembershipResponse
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/doctors/{id}/clinics [get]
+func (h *DoctorHandler) GetDoctorClinics(w http.ResponseWriter, r *http.Request) {
+	userId, role := h.currentUser(r)
+	memberships, err := h.service.GetClinicMemberships(mux.Vars(r)["id"], userId, role)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, services.ToClinicMembershipResponseList(memberships))
+}
+
+// AddDoctorClinic godoc
+// @Summary Add doctor to clinic
+// @Description Starts a doctor working at a clinic with commission terms and the clinic services they perform. Allowed for admins of the clinic and platform admins.
+// @Tags Doctors
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Doctor ID"
+// @Param request body dto.ClinicMembershipRequest true "Membership"
+// @Success 201 {object} dto.ClinicMembershipResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/doctors/{id}/clinics [post]
+func (h *DoctorHandler) AddDoctorClinic(w http.ResponseWriter, r *http.Request) {
+	var req dto.ClinicMembershipRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	userId, role := h.currentUser(r)
+	membership, err := h.service.AddClinicMembership(mux.Vars(r)["id"], userId, role, req)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusCreated, services.ToClinicMembershipResponse(*membership))
+}
+
+// UpdateDoctorClinic godoc
+// @Summary Update doctor membership
+// @Description Changes the status, commission or services of a doctor at a clinic. Suspending or ending the membership removes the doctor's free future slots at the clinic.
+// @Tags Doctors
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Doctor ID"
+// @Param clinicId path string true "Clinic ID"
+// @Param request body dto.UpdateClinicMembershipRequest true "Membership"
+// @Success 200 {object} dto.ClinicMembershipResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/doctors/{id}/clinics/{clinicId} [put]
+func (h *DoctorHandler) UpdateDoctorClinic(w http.ResponseWriter, r *http.Request) {
+	var req dto.UpdateClinicMembershipRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	vars := mux.Vars(r)
+	userId, role := h.currentUser(r)
+	membership, err := h.service.UpdateClinicMembership(vars["id"], vars["clinicId"], userId, role, req)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, services.ToClinicMembershipResponse(*membership))
+}
+
+// EndDoctorClinic godoc
+// @Summary Remove doctor from clinic
+// @Description Ends a doctor's membership at a clinic. The membership is kept for reports.
+// @Tags Doctors
+// @Security BearerAuth
+// @Param id path string true "Doctor ID"
+// @Param clinicId path string true "Clinic ID"
+// @Success 204
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/doctors/{id}/clinics/{clinicId} [delete]
+func (h *DoctorHandler) EndDoctorClinic(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userId, role := h.currentUser(r)
+	if err := h.service.EndClinicMembership(vars["id"], vars["clinicId"], userId, role); err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
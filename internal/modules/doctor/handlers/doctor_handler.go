@@ -4,15 +4,13 @@ import (
 	"database/sql"
 	"dental_clinic/internal/config"
 	"dental_clinic/internal/middleware"
+	"dental_clinic/internal/search"
+	"dental_clinic/internal/upload"
 	"encoding/json"
 	"errors"
-	"fmt"
-	"io"
 	"net/http"
 	"os"
 	"path/filepath"
-	"strings"
-	"time"
 
 	"dental_clinic/internal/modules/doctor/dto"
 	"dental_clinic/internal/modules/doctor/services"
@@ -22,14 +20,16 @@ import (
 )
 
 type DoctorHandler struct {
-	service *services.DoctorService
-	cfg     config.Config
+	service  *services.DoctorService
+	cfg      config.Config
+	uploader *upload.Uploader
 }
 
 func NewDoctorHandler(s *services.DoctorService, cfg config.Config) *DoctorHandler {
 	return &DoctorHandler{
-		service: s,
-		cfg:     cfg,
+		service:  s,
+		cfg:      cfg,
+		uploader: upload.NewUploader(cfg),
 	}
 }
 
@@ -78,23 +78,51 @@ func (h *DoctorHandler) CreateDoctor(w http.ResponseWriter, r *http.Request) {
 }
 
 // GetAllDoctors godoc
-// @Summary Get all doctors
-// @Description Returns a list of all doctors
+// @Summary Search doctors
+// @Description Returns a page of doctors. q searches names, specializations, bios and the names of the services a doctor performs. Pass next_cursor back as cursor to get the following page.
 // @Tags Doctors
 // @Security BearerAuth
 // @Produce json
-// @Success 200 {array} dto.DoctorResponse
+// @Param q query string false "Text to search for"
+// @Param city query string false "City of one of the doctor's clinic addresses"
+// @Param clinic_id query string false "Only doctors of this clinic"
+// @Param service_id query string false "Only doctors performing this service"
+// @Param price_min query number false "Minimum service price"
+// @Param price_max query number false "Maximum service price"
+// @Param min_rating query number false "Minimum average rating"
+// @Param language query string false "Language spoken, e.g. ru, kk, en"
+// @Param specialization query string false "Specialization"
+// @Param available_within query int false "Has a free slot within this many days"
+// @Param sort query string false "relevance, rating, name, experience or price"
+// @Param cursor query string false "Cursor returned by the previous page"
+// @Param limit query int false "Page size, at most 100"
+// @Success 200 {object} search.Page
+// @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /api/doctors [get]
 func (h *DoctorHandler) GetAllDoctors(w http.ResponseWriter, r *http.Request) {
-	doctors, err := h.service.GetAllDoctors()
+	params, err := search.ParseParams(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.service.SearchDoctors(params)
 	if err != nil {
+		if search.IsInvalid(err) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(services.ToDoctorResponseList(doctors))
+	_ = json.NewEncoder(w).Encode(search.Page{
+		Items:      services.ToDoctorResponseList(result.Items),
+		NextCursor: result.NextCursor,
+		Limit:      params.Limit,
+	})
 }
 
 // GetDoctorByID godoc
@@ -208,74 +236,16 @@ func (h *DoctorHandler) UpdateDoctorPhoto(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	if err := r.ParseMultipartForm(5 << 20); err != nil {
-		response.Message = "Invalid request body"
-		w.WriteHeader(http.StatusBadRequest)
-		_ = json.NewEncoder(w).Encode(response)
-		return
-	}
-
-	file, fileHeader, err := r.FormFile("photo")
-	if err != nil {
-		response.Message = "photo is required"
-		w.WriteHeader(http.StatusBadRequest)
-		_ = json.NewEncoder(w).Encode(response)
-		return
-	}
-	defer file.Close()
-
-	buffer := make([]byte, 512)
-	n, err := file.Read(buffer)
-	if err != nil {
-		response.Message = "failed to read photo"
-		w.WriteHeader(http.StatusBadRequest)
-		_ = json.NewEncoder(w).Encode(response)
-		return
-	}
-	contentType := http.DetectContentType(buffer[:n])
-	if !strings.HasPrefix(contentType, "image/") {
-		response.Message = "photo must be an image"
-		w.WriteHeader(http.StatusBadRequest)
-		_ = json.NewEncoder(w).Encode(response)
-		return
-	}
-	if _, err := file.Seek(0, io.SeekStart); err != nil {
-		response.Message = "failed to read photo"
-		w.WriteHeader(http.StatusBadRequest)
-		_ = json.NewEncoder(w).Encode(response)
-		return
-	}
-
-	if err := os.MkdirAll("./uploads/doctors", os.ModePerm); err != nil {
-		response.Message = "failed to create upload directory"
-		w.WriteHeader(http.StatusInternalServerError)
-		_ = json.NewEncoder(w).Encode(response)
-		return
-	}
-
-	ext := strings.ToLower(filepath.Ext(fileHeader.Filename))
-	if ext == "" {
-		ext = ".jpg"
-	}
-	filename := fmt.Sprintf("%s_%d%s", doctorID, time.Now().UnixNano(), ext)
-	filePath := filepath.Join(".", "uploads", "doctors", filename)
-	dst, err := os.Create(filePath)
+	saved, err := h.uploader.SaveFormFile(w, r, "photo", upload.ImagePolicy, "./uploads/doctors", doctorID)
 	if err != nil {
-		response.Message = "failed to save photo"
-		w.WriteHeader(http.StatusInternalServerError)
-		_ = json.NewEncoder(w).Encode(response)
-		return
-	}
-	if _, err := io.Copy(dst, file); err != nil {
-		_ = dst.Close()
-		response.Message = "failed to save photo"
-		w.WriteHeader(http.StatusInternalServerError)
+		response.Message = err.Error()
+		w.WriteHeader(upload.HTTPStatus(err))
 		_ = json.NewEncoder(w).Encode(response)
 		return
 	}
-	_ = dst.Close()
+	filePath := saved.Path
 
-	photoURL := "/uploads/doctors/" + filename
+	photoURL := saved.URL
 	if err := h.service.UpdateDoctorPhoto(doctorID, dto.DoctorPhotoRequest{PhotoURL: photoURL}); err != nil {
 		_ = os.Remove(filePath)
 		response.Message = err.Error()
@@ -284,9 +254,7 @@ func (h *DoctorHandler) UpdateDoctorPhoto(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	if currentDoctor.PhotoURL != "" {
-		_ = os.Remove(filepath.FromSlash("." + currentDoctor.PhotoURL))
-	}
+	upload.RemoveByURL(currentDoctor.PhotoURL)
 
 	response.Success = "1"
 	response.Message = "doctor photo updated successfully"
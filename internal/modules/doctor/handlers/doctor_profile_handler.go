@@ -0,0 +1,394 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"dental_clinic/internal/modules/doctor/dto"
+	"dental_clinic/internal/modules/doctor/models"
+	"dental_clinic/internal/modules/doctor/services"
+	"dental_clinic/internal/upload"
+	"dental_clinic/internal/utils"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+type CredentialQueuePage struct {
+	Items []dto.CredentialResponse `json:"items"`
+	Page  int                      `json:"page"`
+	Limit int                      `json:"limit"`
+	Total int                      `json:"total"`
+}
+
+// GetDoctorProfile godoc
+// @Summary Get doctor profile
+// @Description Returns the doctor with all specializations and languages, education and verified credentials
+// @Tags Doctors
+// @Produce json
+// @Param id path string true "Doctor ID"
+// @Success 200 {object} dto.DoctorProfileResponse
+// @Failure 404 {object} map[string]string
+// @Router /api/doctors/{id}/profile [get]
+func (h *DoctorHandler) GetDoctorProfile(w http.ResponseWriter, r *http.Request) {
+	profile, err := h.service.GetDoctorProfile(mux.Vars(r)["id"])
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, profile)
+}
+
+// AddDoctorEducation godoc
+// @Summary Add education
+// @Description Adds a degree or course to a doctor's profile. Allowed for the doctor, admins of the doctor's clinics and platform admins.
+// @Tags Doctors
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Doctor ID"
+// @Param request body dto.EducationRequest true "Education"
+// @Success 201 {object} dto.EducationResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /api/doctors/{id}/education [post]
+func (h *DoctorHandler) AddDoctorEducation(w http.ResponseWriter, r *http.Request) {
+	var req dto.EducationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	userId, role := h.currentUser(r)
+	education, err := h.service.AddEducation(mux.Vars(r)["id"], userId, role, req)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusCreated, services.ToEducationResponse(*education))
+}
+
+// UpdateDoctorEducation godoc
+// @Summary Update education
+// @Description Replaces an education entry of a doctor
+// @Tags Doctors
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Doctor ID"
+// @Param educationId path string true "Education ID"
+// @Param request body dto.EducationRequest true "Education"
+// @Success 200 {object} dto.EducationResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/doctors/{id}/education/{educationId} [put]
+func (h *DoctorHandler) UpdateDoctorEducation(w http.ResponseWriter, r *http.Request) {
+	var req dto.EducationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	vars := mux.Vars(r)
+	userId, role := h.currentUser(r)
+	education, err := h.service.UpdateEducation(vars["id"], vars["educationId"], userId, role, req)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, services.ToEducationResponse(*education))
+}
+
+// DeleteDoctorEducation godoc
+// @Summary Delete education
+// @Description Removes an education entry of a doctor
+// @Tags Doctors
+// @Security BearerAuth
+// @Param id path string true "Doctor ID"
+// @Param educationId path string true "Education ID"
+// @Success 204
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/doctors/{id}/education/{educationId} [delete]
+func (h *DoctorHandler) DeleteDoctorEducation(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userId, role := h.currentUser(r)
+	if err := h.service.DeleteEducation(vars["id"], vars["educationId"], userId, role); err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AddDoctorCredential godoc
+// @Summary Add credential
+// @Description Uploads a license, certificate or diploma with its scan. The credential stays pending until a platform admin verifies it; only verified credentials are shown to patients.
+// @Tags Doctors
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path string true "Doctor ID"
+// @Param type formData string true "license, certificate, diploma or other"
+// @Param title formData string true "Title"
+// @Param issuer formData string false "Issuing body"
+// @Param number formData string false "License or certificate number"
+// @Param issued_at formData string false "Issue date (YYYY-MM-DD)"
+// @Param expires_at formData string false "Expiry date (YYYY-MM-DD)"
+// @Param document formData file true "Scan of the document (JPEG, PNG or PDF)"
+// @Success 201 {object} dto.CredentialResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /api/doctors/{id}/credentials [post]
+func (h *DoctorHandler) AddDoctorCredential(w http.ResponseWriter, r *http.Request) {
+	userId, role := h.currentUser(r)
+	doctor, err := h.service.CanManageDoctor(mux.Vars(r)["id"], userId, role)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+
+	if err := h.uploader.ParseForm(w, r, upload.CredentialPolicy); err != nil {
+		respondError(w, upload.HTTPStatus(err), err.Error())
+		return
+	}
+
+	req := dto.CredentialRequest{
+		Type:      r.FormValue("type"),
+		Title:     r.FormValue("title"),
+		Issuer:    r.FormValue("issuer"),
+		Number:    r.FormValue("number"),
+		IssuedAt:  r.FormValue("issued_at"),
+		ExpiresAt: r.FormValue("expires_at"),
+	}
+	if err := services.ValidateCredential(req); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	files := r.MultipartForm.File["document"]
+	if len(files) == 0 {
+		respondError(w, http.StatusBadRequest, upload.ErrMissing.Error())
+		return
+	}
+	saved, err := h.uploader.SaveFiles(r.Context(), "document", files, upload.CredentialPolicy, "./uploads/doctor_credentials", doctor.Id.String())
+	if err != nil {
+		respondError(w, upload.HTTPStatus(err), err.Error())
+		return
+	}
+	document := saved[0]
+
+	credential, err := h.service.CreateCredential(doctor, req, document.Path, document.Name, document.MimeType)
+	if err != nil {
+		_ = os.Remove(document.Path)
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusCreated, services.ToCredentialResponse(*credential, true))
+}
+
+// GetDoctorCredentials godoc
+// @Summary Get doctor credentials
+// @Description Lists a doctor's credentials. The doctor, admins of the doctor's clinics and platform admins also see pending and rejected credentials with license numbers and review notes; everyone else only verified ones.
+// @Tags Doctors
+// @Produce json
+// @Param id path string true "Doctor ID"
+// @Success 200 {array} dto.CredentialResponse
+// @Failure 404 {object} map[string]string
+// @Router /api/doctors/{id}/credentials [get]
+func (h *DoctorHandler) GetDoctorCredentials(w http.ResponseWriter, r *http.Request) {
+	userId, role := h.currentUser(r)
+	credentials, private, err := h.service.GetCredentials(mux.Vars(r)["id"], userId, role)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, services.ToCredentialResponseList(credentials, private))
+}
+
+// DeleteDoctorCredential godoc
+// @Summary Delete credential
+// @Description Removes a credential and its document
+// @Tags Doctors
+// @Security BearerAuth
+// @Param id path string true "Doctor ID"
+// @Param credentialId path string true "Credential ID"
+// @Success 204
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/doctors/{id}/credentials/{credentialId} [delete]
+func (h *DoctorHandler) DeleteDoctorCredential(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userId, role := h.currentUser(r)
+	credential, err := h.service.DeleteCredential(vars["id"], vars["credentialId"], userId, role)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	if credential.DocumentPath != "" {
+		_ = os.Remove(credential.DocumentPath)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetCredentialDocument godoc
+// @Summary Download credential document
+// @Description Returns the scan of a credential. Allowed for the doctor, admins of the doctor's clinics and platform admins.
+// @Tags Doctors
+// @Security BearerAuth
+// @Produce octet-stream
+// @Param id path string true "Credential ID"
+// @Success 200 {file} file
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/doctor-credentials/{id}/document [get]
+func (h *DoctorHandler) GetCredentialDocument(w http.ResponseWriter, r *http.Request) {
+	userId, role := h.currentUser(r)
+	credential, err := h.service.GetCredentialDocument(mux.Vars(r)["id"], userId, role)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", credential.DocumentType)
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="%s"`, credential.DocumentName))
+	http.ServeFile(w, r, credential.DocumentPath)
+}
+
+// GetCredentialQueue godoc
+// @Summary Get credential verification queue
+// @Description Platform admins list credentials in the given status (pending by default), oldest first
+// @Tags Doctors
+// @Security BearerAuth
+// @Produce json
+// @Param status query string false "pending, verified or rejected"
+// @Param page query int false "Page number, starting at 1"
+// @Param limit query int false "Page size, up to 100"
+// @Success 200 {object} CredentialQueuePage
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /api/doctor-credentials [get]
+func (h *DoctorHandler) GetCredentialQueue(w http.ResponseWriter, r *http.Request) {
+	_, role := h.currentUser(r)
+	page, limit := pagination(r)
+	credentials, total, err := h.service.GetCredentialQueue(role, r.URL.Query().Get("status"), page, limit)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, CredentialQueuePage{
+		Items: services.ToCredentialResponseList(credentials, true),
+		Page:  page,
+		Limit: limit,
+		Total: total,
+	})
+}
+
+// VerifyCredential godoc
+// @Summary Verify credential
+// @Description Platform admin confirms a credential after checking the document; it becomes visible on the doctor's profile
+// @Tags Doctors
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Credential ID"
+// @Param request body dto.ReviewCredentialRequest false "Note"
+// @Success 200 {object} dto.CredentialResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/doctor-credentials/{id}/verify [post]
+func (h *DoctorHandler) VerifyCredential(w http.ResponseWriter, r *http.Request) {
+	h.reviewCredential(w, r, h.service.VerifyCredential)
+}
+
+// RejectCredential godoc
+// @Summary Reject credential
+// @Description Platform admin turns a credential down; the note tells the doctor why
+// @Tags Doctors
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Credential ID"
+// @Param request body dto.ReviewCredentialRequest true "Reason"
+// @Success 200 {object} dto.CredentialResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/doctor-credentials/{id}/reject [post]
+func (h *DoctorHandler) RejectCredential(w http.ResponseWriter, r *http.Request) {
+	h.reviewCredential(w, r, h.service.RejectCredential)
+}
+
+func (h *DoctorHandler) reviewCredential(w http.ResponseWriter, r *http.Request, action func(credentialId, userId, role, note string) (*models.Credential, error)) {
+	var req dto.ReviewCredentialRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	userId, role := h.currentUser(r)
+	credential, err := action(mux.Vars(r)["id"], userId, role, req.Note)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, services.ToCredentialResponse(*credential, true))
+}
+
+func (h *DoctorHandler) currentUser(r *http.Request) (string, string) {
+	claims, err := utils.GetClaims(utils.GetToken(r), h.cfg.JWTSecret)
+	if err != nil {
+		return "", ""
+	}
+	userId, _ := claims["user_id"].(string)
+	role, _ := claims["role"].(string)
+	return userId, role
+}
+
+func pagination(r *http.Request) (int, int) {
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit < 1 {
+		limit = defaultPageLimit
+	}
+	if limit > maxPageLimit {
+		limit = maxPageLimit
+	}
+	return page, limit
+}
+
+func errorStatus(err error) int {
+	switch {
+	case err.Error() == "do not have rights":
+		return http.StatusForbidden
+	case strings.HasSuffix(err.Error(), "not found"):
+		return http.StatusNotFound
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+func respondJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+func respondError(w http.ResponseWriter, status int, message string) {
+	respondJSON(w, status, map[string]string{"error": message})
+}
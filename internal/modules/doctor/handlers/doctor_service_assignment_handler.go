@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"dental_clinic/internal/modules/doctor/dto"
+	"dental_clinic/internal/modules/doctor/services"
+
+	"github.com/gorilla/mux"
+)
+
+// GetDoctorServices godoc
+// @Summary List doctor services
+// @Description Lists the clinic services a doctor performs with the price and duration patients are booked for. The doctor, admins of the doctor's clinics and platform admins also see services that cannot be booked right now.
+// @Tags Doctors
+// @Produce json
+// @Param id path string true "Doctor ID"
+// @Success 200 {array} dto.ServiceAssignmentResponse
+// @Failure 404 {object} map[string]string
+// @Router /api/doctors/{id}/services [get]
+func (h *DoctorHandler) GetDoctorServices(w http.ResponseWriter, r *http.Request) {
+	userId, role := h.currentUser(r)
+	assignments, err := h.service.GetServiceAssignments(mux.Vars(r)["id"], userId, role)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, services.ToServiceAssignmentResponseList(assignments))
+}
+
+// AssignDoctorService godoc
+// @Summary Assign service to doctor
+// @Description Lets a doctor perform a service of one of their clinics, optionally at their own price or duration. Allowed for admins of that clinic and platform admins.
+// @Tags Doctors
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Doctor ID"
+// @Param request body dto.ServiceAssignmentRequest true "Assignment"
+// @Success 201 {object} dto.ServiceAssignmentResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/doctors/{id}/services [post]
+func (h *DoctorHandler) AssignDoctorService(w http.ResponseWriter, r *http.Request) {
+	var req dto.ServiceAssignmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	userId, role := h.currentUser(r)
+	assignment, err := h.service.AssignService(mux.Vars(r)["id"], userId, role, req)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusCreated, services.ToServiceAssignmentResponse(*assignment))
+}
+
+// UpdateDoctorService godoc
+// @Summary Update doctor service
+// @Description Replaces the doctor's price and duration overrides; null falls back to the clinic's
+// @Tags Doctors
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Doctor ID"
+// @Param assignmentId path string true "Assignment ID"
+// @Param request body dto.UpdateServiceAssignmentRequest true "Overrides"
+// @Success 200 {object} dto.ServiceAssignmentResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/doctors/{id}/services/{assignmentId} [put]
+func (h *DoctorHandler) UpdateDoctorService(w http.ResponseWriter, r *http.Request) {
+	var req dto.UpdateServiceAssignmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	vars := mux.Vars(r)
+	userId, role := h.currentUser(r)
+	assignment, err := h.service.UpdateServiceAssignment(vars["id"], vars["assignmentId"], userId, role, req)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, services.ToServiceAssignmentResponse(*assignment))
+}
+
+// UnassignDoctorService godoc
+// @Summary Unassign service from doctor
+// @Description Stops a doctor from performing a clinic service. Existing appointments are kept.
+// @Tags Doctors
+// @Security BearerAuth
+// @Param id path string true "Doctor ID"
+// @Param assignmentId path string true "Assignment ID"
+// @Success 204
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/doctors/{id}/services/{assignmentId} [delete]
+func (h *DoctorHandler) UnassignDoctorService(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userId, role := h.currentUser(r)
+	if err := h.service.UnassignService(vars["id"], vars["assignmentId"], userId, role); err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
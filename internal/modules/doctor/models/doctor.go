@@ -8,10 +8,24 @@ type Doctor struct {
 	Name           string
 	Email          string
 	Experience     int
-	ClinicID       uuid.UUID
 	Bio            string
 	IsAvailable    bool
 	UserId         uuid.UUID
 	Rating         float64
 	PhotoURL       string
+	// Languages holds lower-case language codes, e.g. "ru", "kk", "en"
+	Languages []string
+	// Specializations lists every specialization, Specialization first
+	Specializations []string
+	// ClinicIDs lists the clinics the doctor currently works at
+	ClinicIDs []uuid.UUID
+
+	RatingCount int
+	// RatingDistribution holds the number of 1 to 5 star ratings
+	RatingDistribution [5]int
+	RecentRating       float64
+	RatingTrend        float64
+
+	// MinPrice is filled by search: the lowest price of the matching services
+	MinPrice *float64
 }
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Education struct {
+	Id           uuid.UUID
+	DoctorId     uuid.UUID
+	Institution  string
+	Degree       string
+	FieldOfStudy string
+	StartYear    *int
+	EndYear      *int
+}
+
+// Credential is a license, certificate or diploma of a doctor. Patients only
+// see verified credentials; the document is visible to the doctor, the
+// clinic's admins and platform admins.
+type Credential struct {
+	Id           uuid.UUID
+	DoctorId     uuid.UUID
+	Type         string
+	Title        string
+	Issuer       string
+	Number       string
+	IssuedAt     *time.Time
+	ExpiresAt    *time.Time
+	DocumentPath string
+	DocumentName string
+	DocumentType string
+	Status       string
+	ReviewedBy   *uuid.UUID
+	ReviewedAt   *time.Time
+	ReviewNote   string
+	CreatedAt    time.Time
+}
+
+// ClinicMembership is a doctor working at a clinic. For every completed
+// appointment the doctor earns CommissionPercent of the price plus
+// CommissionFixed.
+type ClinicMembership struct {
+	Id                uuid.UUID
+	DoctorId          uuid.UUID
+	ClinicId          uuid.UUID
+	ClinicName        string
+	Status            string
+	CommissionPercent float64
+	CommissionFixed   float64
+	StartedAt         time.Time
+	EndedAt           *time.Time
+	// ClinicServiceIds are the services of the clinic the doctor performs
+	ClinicServiceIds []uuid.UUID
+}
+
+// ServiceAssignment is a clinic service a doctor performs. Price and Duration
+// override the clinic's price and duration when set.
+type ServiceAssignment struct {
+	Id              uuid.UUID
+	DoctorId        uuid.UUID
+	ClinicServiceId uuid.UUID
+	ClinicId        uuid.UUID
+	ClinicName      string
+	ServiceId       uuid.UUID
+	ServiceName     string
+	ClinicPrice     float64
+	ClinicDuration  int
+	Price           *float64
+	Duration        *int
+	// Bookable is false while the clinic service is inactive or the doctor
+	// does not currently work at the clinic
+	Bookable  bool
+	CreatedAt time.Time
+}
@@ -0,0 +1,197 @@
+package repository
+
+import (
+	"context"
+
+	"dental_clinic/internal/modules/doctor/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const membershipColumns = `
+	m.id, m.doctor_id, m.clinic_id, COALESCE(c.name, ''), m.status,
+	m.commission_percent::float8, m.commission_fixed::float8, m.started_at, m.ended_at,
+	ARRAY(
+		SELECT ds.clinic_service_id FROM doctor_services ds
+		JOIN clinic_services cs ON cs.id = ds.clinic_service_id
+		WHERE ds.doctor_id = m.doctor_id AND cs.clinic_id = m.clinic_id
+		ORDER BY ds.clinic_service_id
+	)
+`
+
+func scanMembership(row pgx.Row) (models.ClinicMembership, error) {
+	var m models.ClinicMembership
+	err := row.Scan(
+		&m.Id,
+		&m.DoctorId,
+		&m.ClinicId,
+		&m.ClinicName,
+		&m.Status,
+		&m.CommissionPercent,
+		&m.CommissionFixed,
+		&m.StartedAt,
+		&m.EndedAt,
+		&m.ClinicServiceIds,
+	)
+	return m, err
+}
+
+// GetClinicMemberships returns every clinic the doctor works or has worked at,
+// current ones first.
+func (r *doctorRepo) GetClinicMemberships(doctorId uuid.UUID) ([]models.ClinicMembership, error) {
+	query := `
+		SELECT ` + membershipColumns + `
+		FROM doctor_clinics m
+		JOIN clinics c ON c.id = m.clinic_id
+		WHERE m.doctor_id = $1
+		ORDER BY m.status = 'active' DESC, m.started_at, c.name
+	`
+
+	rows, err := r.db.Query(context.Background(), query, doctorId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	memberships := make([]models.ClinicMembership, 0)
+	for rows.Next() {
+		m, err := scanMembership(rows)
+		if err != nil {
+			return nil, err
+		}
+		memberships = append(memberships, m)
+	}
+	return memberships, rows.Err()
+}
+
+func (r *doctorRepo) GetClinicMembership(doctorId, clinicId uuid.UUID) (*models.ClinicMembership, error) {
+	query := `
+		SELECT ` + membershipColumns + `
+		FROM doctor_clinics m
+		JOIN clinics c ON c.id = m.clinic_id
+		WHERE m.doctor_id = $1 AND m.clinic_id = $2
+	`
+
+	m, err := scanMembership(r.db.QueryRow(context.Background(), query, doctorId, clinicId))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &m, nil
+}
+
+// SaveClinicMembership creates or updates the membership of the doctor at the
+// clinic. A non-nil ClinicServiceIds replaces the doctor's services at the
+// clinic. Once the doctor stops working there their free future slots at
+// the clinic's addresses are removed.
+func (r *doctorRepo) SaveClinicMembership(m *models.ClinicMembership) error {
+	ctx := context.Background()
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO doctor_clinics (id, doctor_id, clinic_id, status, commission_percent, commission_fixed, started_at, ended_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (doctor_id, clinic_id) DO UPDATE
+		SET status = EXCLUDED.status,
+			commission_percent = EXCLUDED.commission_percent,
+			commission_fixed = EXCLUDED.commission_fixed,
+			started_at = EXCLUDED.started_at,
+			ended_at = EXCLUDED.ended_at,
+			updated_at = NOW()
+		RETURNING id
+	`
+	err = tx.QueryRow(ctx, query, m.Id, m.DoctorId, m.ClinicId, m.Status, m.CommissionPercent, m.CommissionFixed, m.StartedAt, m.EndedAt).
+		Scan(&m.Id)
+	if err != nil {
+		return err
+	}
+
+	if m.ClinicServiceIds != nil {
+		_, err = tx.Exec(ctx, `
+			DELETE FROM doctor_services ds
+			USING clinic_services cs
+			WHERE cs.id = ds.clinic_service_id
+				AND ds.doctor_id = $1
+				AND cs.clinic_id = $2
+				AND NOT ds.clinic_service_id = ANY($3)
+		`, m.DoctorId, m.ClinicId, m.ClinicServiceIds)
+		if err != nil {
+			return err
+		}
+		// kept services keep their price and duration overrides
+		_, err = tx.Exec(ctx, `
+			INSERT INTO doctor_services (id, doctor_id, clinic_service_id)
+			SELECT gen_random_uuid(), $1, cs.id
+			FROM clinic_services cs
+			WHERE cs.clinic_id = $2 AND cs.id = ANY($3)
+			ON CONFLICT (doctor_id, clinic_service_id) DO NOTHING
+		`, m.DoctorId, m.ClinicId, m.ClinicServiceIds)
+		if err != nil {
+			return err
+		}
+	}
+
+	if m.Status != "active" {
+		_, err = tx.Exec(ctx, `
+			DELETE FROM doctor_time_slots ts
+			USING clinic_addresses ca
+			WHERE ca.id = ts.clinic_address_id
+				AND ts.doctor_id = $1
+				AND ca.clinic_id = $2
+				AND ts.status = 'available'
+				AND ts.slot_start > NOW()
+		`, m.DoctorId, m.ClinicId)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// GetClinicServiceIDs returns the ids of the services offered by the clinic.
+func (r *doctorRepo) GetClinicServiceIDs(clinicId uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := r.db.Query(context.Background(), `SELECT id FROM clinic_services WHERE clinic_id = $1`, clinicId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]uuid.UUID, 0)
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (r *doctorRepo) ClinicExists(clinicId uuid.UUID) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(context.Background(), `SELECT EXISTS (SELECT 1 FROM clinics WHERE id = $1)`, clinicId).Scan(&exists)
+	return exists, err
+}
+
+// IsDoctorClinicAdmin reports whether the user administers one of the clinics
+// the doctor currently works at.
+func (r *doctorRepo) IsDoctorClinicAdmin(doctorId, userId uuid.UUID) (bool, error) {
+	var isAdmin bool
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM clinic_admins ca
+			JOIN doctor_clinics dc ON dc.clinic_id = ca.clinic_id
+			WHERE dc.doctor_id = $1 AND dc.status = 'active' AND ca.user_id = $2
+		)
+	`
+	err := r.db.QueryRow(context.Background(), query, doctorId, userId).Scan(&isAdmin)
+	return isAdmin, err
+}
@@ -0,0 +1,246 @@
+package repository
+
+import (
+	"context"
+
+	"dental_clinic/internal/modules/doctor/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+func (r *doctorRepo) GetEducation(doctorId uuid.UUID) ([]models.Education, error) {
+	query := `
+		SELECT id, doctor_id, institution, degree, field_of_study, start_year, end_year
+		FROM doctor_education
+		WHERE doctor_id = $1
+		ORDER BY end_year DESC NULLS FIRST, start_year DESC NULLS LAST, created_at
+	`
+
+	rows, err := r.db.Query(context.Background(), query, doctorId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	education := make([]models.Education, 0)
+	for rows.Next() {
+		var e models.Education
+		if err := rows.Scan(&e.Id, &e.DoctorId, &e.Institution, &e.Degree, &e.FieldOfStudy, &e.StartYear, &e.EndYear); err != nil {
+			return nil, err
+		}
+		education = append(education, e)
+	}
+	return education, rows.Err()
+}
+
+func (r *doctorRepo) GetEducationByID(id uuid.UUID) (*models.Education, error) {
+	query := `
+		SELECT id, doctor_id, institution, degree, field_of_study, start_year, end_year
+		FROM doctor_education
+		WHERE id = $1
+	`
+
+	var e models.Education
+	err := r.db.QueryRow(context.Background(), query, id).
+		Scan(&e.Id, &e.DoctorId, &e.Institution, &e.Degree, &e.FieldOfStudy, &e.StartYear, &e.EndYear)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (r *doctorRepo) CreateEducation(e *models.Education) error {
+	query := `
+		INSERT INTO doctor_education (id, doctor_id, institution, degree, field_of_study, start_year, end_year)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	_, err := r.db.Exec(context.Background(), query, e.Id, e.DoctorId, e.Institution, e.Degree, e.FieldOfStudy, e.StartYear, e.EndYear)
+	return err
+}
+
+func (r *doctorRepo) UpdateEducation(e *models.Education) error {
+	query := `
+		UPDATE doctor_education
+		SET institution = $2, degree = $3, field_of_study = $4, start_year = $5, end_year = $6
+		WHERE id = $1
+	`
+	result, err := r.db.Exec(context.Background(), query, e.Id, e.Institution, e.Degree, e.FieldOfStudy, e.StartYear, e.EndYear)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *doctorRepo) DeleteEducation(id uuid.UUID) error {
+	result, err := r.db.Exec(context.Background(), `DELETE FROM doctor_education WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+const credentialColumns = `
+	id, doctor_id, type, title, issuer, number, issued_at, expires_at,
+	document_path, document_name, document_type, status,
+	reviewed_by, reviewed_at, COALESCE(review_note, ''), created_at
+`
+
+func scanCredential(row pgx.Row) (models.Credential, error) {
+	var c models.Credential
+	err := row.Scan(
+		&c.Id,
+		&c.DoctorId,
+		&c.Type,
+		&c.Title,
+		&c.Issuer,
+		&c.Number,
+		&c.IssuedAt,
+		&c.ExpiresAt,
+		&c.DocumentPath,
+		&c.DocumentName,
+		&c.DocumentType,
+		&c.Status,
+		&c.ReviewedBy,
+		&c.ReviewedAt,
+		&c.ReviewNote,
+		&c.CreatedAt,
+	)
+	return c, err
+}
+
+func (r *doctorRepo) CreateCredential(c *models.Credential) error {
+	query := `
+		INSERT INTO doctor_credentials (id, doctor_id, type, title, issuer, number, issued_at, expires_at,
+			document_path, document_name, document_type, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING created_at
+	`
+	return r.db.QueryRow(
+		context.Background(),
+		query,
+		c.Id,
+		c.DoctorId,
+		c.Type,
+		c.Title,
+		c.Issuer,
+		c.Number,
+		c.IssuedAt,
+		c.ExpiresAt,
+		c.DocumentPath,
+		c.DocumentName,
+		c.DocumentType,
+		c.Status,
+	).Scan(&c.CreatedAt)
+}
+
+// GetCredentials returns the credentials of a doctor, newest first. With
+// verifiedOnly pending and rejected ones are left out.
+func (r *doctorRepo) GetCredentials(doctorId uuid.UUID, verifiedOnly bool) ([]models.Credential, error) {
+	query := `
+		SELECT ` + credentialColumns + `
+		FROM doctor_credentials
+		WHERE doctor_id = $1 AND (NOT $2::boolean OR status = 'verified')
+		ORDER BY created_at DESC
+	`
+	return r.queryCredentials(query, doctorId, verifiedOnly)
+}
+
+func (r *doctorRepo) GetCredentialByID(id uuid.UUID) (*models.Credential, error) {
+	query := `SELECT ` + credentialColumns + ` FROM doctor_credentials WHERE id = $1`
+
+	c, err := scanCredential(r.db.QueryRow(context.Background(), query, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &c, nil
+}
+
+// GetCredentialsByStatus pages through credentials in status, oldest first so
+// the verification queue is worked in order.
+func (r *doctorRepo) GetCredentialsByStatus(status string, page, limit int) ([]models.Credential, int, error) {
+	var total int
+	if err := r.db.QueryRow(context.Background(), `SELECT COUNT(*) FROM doctor_credentials WHERE status = $1`, status).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT ` + credentialColumns + `
+		FROM doctor_credentials
+		WHERE status = $1
+		ORDER BY created_at, id
+		LIMIT $2 OFFSET $3
+	`
+	credentials, err := r.queryCredentials(query, status, limit, (page-1)*limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	return credentials, total, nil
+}
+
+func (r *doctorRepo) queryCredentials(query string, args ...any) ([]models.Credential, error) {
+	rows, err := r.db.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	credentials := make([]models.Credential, 0)
+	for rows.Next() {
+		c, err := scanCredential(rows)
+		if err != nil {
+			return nil, err
+		}
+		credentials = append(credentials, c)
+	}
+	return credentials, rows.Err()
+}
+
+// SetCredentialStatus records a verification decision. Verifying again resets
+// the expiry warnings so a renewed license is tracked from scratch.
+func (r *doctorRepo) SetCredentialStatus(id uuid.UUID, status string, reviewerId uuid.UUID, note string) error {
+	query := `
+		UPDATE doctor_credentials
+		SET status = $2, reviewed_by = $3, reviewed_at = NOW(), review_note = NULLIF($4, ''),
+			expiry_warning_days = NULL, updated_at = NOW()
+		WHERE id = $1
+	`
+	result, err := r.db.Exec(context.Background(), query, id, status, reviewerId, note)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *doctorRepo) DeleteCredential(id uuid.UUID) error {
+	result, err := r.db.Exec(context.Background(), `DELETE FROM doctor_credentials WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *doctorRepo) IsClinicAdmin(clinicId, userId uuid.UUID) (bool, error) {
+	var isAdmin bool
+	query := `SELECT EXISTS (SELECT 1 FROM clinic_admins WHERE clinic_id = $1 AND user_id = $2)`
+	err := r.db.QueryRow(context.Background(), query, clinicId, userId).Scan(&isAdmin)
+	return isAdmin, err
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 
 	"dental_clinic/internal/modules/doctor/models"
+	"dental_clinic/internal/search"
 
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -13,11 +15,37 @@ type DoctorRepository interface {
 	Create(doctor *models.Doctor) (*models.Doctor, error)
 	GetByID(id string) (*models.Doctor, error)
 	GetByUserID(id string) (*models.Doctor, error)
-	GetAll() ([]models.Doctor, error)
+	Search(p search.Params, sortName string, sort search.Sort) (search.Result[models.Doctor], error)
 	Update(id string, doctor *models.Doctor) (*models.Doctor, error)
 	Delete(id string) error
 	UpdatePhoto(id, photoURL string) error
 	DeletePhoto(id string) error
+	ReplaceSpecializations(doctorId uuid.UUID, specializations []string) error
+	GetEducation(doctorId uuid.UUID) ([]models.Education, error)
+	GetEducationByID(id uuid.UUID) (*models.Education, error)
+	CreateEducation(e *models.Education) error
+	UpdateEducation(e *models.Education) error
+	DeleteEducation(id uuid.UUID) error
+	CreateCredential(c *models.Credential) error
+	GetCredentials(doctorId uuid.UUID, verifiedOnly bool) ([]models.Credential, error)
+	GetCredentialByID(id uuid.UUID) (*models.Credential, error)
+	GetCredentialsByStatus(status string, page, limit int) ([]models.Credential, int, error)
+	SetCredentialStatus(id uuid.UUID, status string, reviewerId uuid.UUID, note string) error
+	DeleteCredential(id uuid.UUID) error
+	IsClinicAdmin(clinicId, userId uuid.UUID) (bool, error)
+	IsDoctorClinicAdmin(doctorId, userId uuid.UUID) (bool, error)
+	GetClinicMemberships(doctorId uuid.UUID) ([]models.ClinicMembership, error)
+	GetClinicMembership(doctorId, clinicId uuid.UUID) (*models.ClinicMembership, error)
+	SaveClinicMembership(m *models.ClinicMembership) error
+	GetClinicServiceIDs(clinicId uuid.UUID) ([]uuid.UUID, error)
+	ClinicExists(clinicId uuid.UUID) (bool, error)
+	GetServiceAssignments(doctorId uuid.UUID) ([]models.ServiceAssignment, error)
+	GetServiceAssignmentByID(id uuid.UUID) (*models.ServiceAssignment, error)
+	GetServiceAssignment(doctorId, clinicServiceId uuid.UUID) (*models.ServiceAssignment, error)
+	GetClinicServiceClinic(clinicServiceId uuid.UUID) (uuid.UUID, error)
+	CreateServiceAssignment(a *models.ServiceAssignment) error
+	UpdateServiceAssignment(a *models.ServiceAssignment) error
+	DeleteServiceAssignment(id uuid.UUID) error
 }
 
 type doctorRepo struct {
@@ -30,7 +58,7 @@ func NewDoctorRepository(db *pgxpool.Pool) DoctorRepository {
 
 func (r *doctorRepo) Create(doctor *models.Doctor) (*models.Doctor, error) {
 	query := `
-		INSERT INTO doctors (specialization, experience, clinic_id, bio, is_available, name, email, user_id)
+		INSERT INTO doctors (specialization, experience, bio, is_available, name, email, user_id, languages)
 		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 		RETURNING id
 	`
@@ -39,56 +67,154 @@ func (r *doctorRepo) Create(doctor *models.Doctor) (*models.Doctor, error) {
 		query,
 		doctor.Specialization,
 		doctor.Experience,
-		doctor.ClinicID,
 		doctor.Bio,
 		doctor.IsAvailable,
 		doctor.Name,
 		doctor.Email,
 		doctor.UserId,
+		doctor.Languages,
 	).Scan(&doctor.Id)
 	return doctor, err
 }
 
-func (r *doctorRepo) GetAll() ([]models.Doctor, error) {
+// DoctorSorts are the orderings offered by Search.
+var DoctorSorts = map[string]search.Sort{
+	"relevance":  {Expr: "ts_rank(d.search_vector, query)::float8", Type: "float8", Desc: true},
+	"rating":     {Expr: "(" + search.RatingScore + ")::float8", Type: "float8", Desc: true},
+	"name":       {Expr: "LOWER(d.name)", Type: "text"},
+	"experience": {Expr: "d.experience::float8", Type: "float8", Desc: true},
+	"price":      {Expr: "COALESCE(price.min_price::float8, 'Infinity'::float8)", Type: "float8"},
+}
+
+// Search returns one page of doctors matching p, ordered by sort. The text
+// query matches name, specialization and bio as well as the names of the
+// services the doctor performs. Price filters and min_price apply to those
+// services, narrowed to the requested one if given.
+func (r *doctorRepo) Search(p search.Params, sortName string, sort search.Sort) (search.Result[models.Doctor], error) {
+	b := search.NewBuilder()
+	textQuery := b.TextQuery(p.Query)
+
+	offer := "ds.doctor_id = d.id AND cs.is_active = true AND " + doctorServiceMembership
+	if p.ServiceID != uuid.Nil {
+		offer += " AND cs.service_id = " + b.Arg(p.ServiceID)
+	}
+	if p.PriceMin != nil {
+		offer += " AND COALESCE(ds.price, cs.price) >= " + b.Arg(*p.PriceMin)
+	}
+	if p.PriceMax != nil {
+		offer += " AND COALESCE(ds.price, cs.price) <= " + b.Arg(*p.PriceMax)
+	}
+
+	b.Where("d.is_deleted = 0")
+	if p.Query != "" {
+		b.Where(`(d.search_vector @@ query OR EXISTS (
+				SELECT 1 FROM doctor_services ds
+				JOIN clinic_services cs ON cs.id = ds.clinic_service_id
+				JOIN services sv ON sv.id = cs.service_id
+				WHERE ds.doctor_id = d.id AND cs.is_active = true AND ` + doctorServiceMembership + ` AND sv.search_vector @@ query
+			))`)
+	}
+	if p.ServiceID != uuid.Nil || p.PriceMin != nil || p.PriceMax != nil {
+		b.Where("price.min_price IS NOT NULL")
+	}
+	if p.ClinicID != uuid.Nil {
+		b.Where(`EXISTS (
+				SELECT 1 FROM doctor_clinics dc
+				WHERE dc.doctor_id = d.id AND dc.status = 'active' AND dc.clinic_id = ` + b.Arg(p.ClinicID) + `
+			)`)
+	}
+	if p.City != "" {
+		b.Where(`EXISTS (
+				SELECT 1 FROM doctor_clinics dc
+				JOIN clinic_addresses ca ON ca.clinic_id = dc.clinic_id
+				JOIN addresses a ON a.id = ca.address_id
+				WHERE dc.doctor_id = d.id AND dc.status = 'active' AND LOWER(a.city) = LOWER(` + b.Arg(p.City) + `)
+			)`)
+	}
+	if p.Specialization != "" {
+		specialization := b.Arg(p.Specialization)
+		b.Where(`(LOWER(d.specialization) = LOWER(` + specialization + `) OR EXISTS (
+				SELECT 1 FROM doctor_specializations sp
+				WHERE sp.doctor_id = d.id AND LOWER(sp.specialization) = LOWER(` + specialization + `)
+			))`)
+	}
+	if p.Language != "" {
+		b.Where(b.Arg(p.Language) + " = ANY(d.languages)")
+	}
+	if p.MinRating > 0 {
+		b.Where("COALESCE(s.rating_sum::float8 / NULLIF(s.rating_count, 0), 0) >= " + b.Arg(p.MinRating))
+	}
+	if p.AvailableWithin > 0 {
+		b.Where(`EXISTS (
+				SELECT 1 FROM doctor_time_slots ts
+				WHERE ts.doctor_id = d.id
+					AND ts.status = 'available'
+					AND ts.slot_start > NOW()
+					AND ts.slot_start < NOW() + make_interval(days => ` + b.Arg(p.AvailableWithin) + `)
+			)`)
+	}
+	page := b.Page(sort, "d.id", p.Cursor, p.Limit)
+
 	query := `
+		WITH prior AS (
+			SELECT COALESCE(SUM(rating_sum)::float8 / NULLIF(SUM(rating_count), 0), 0) AS mean
+			FROM doctor_rating_stats
+		)
 		SELECT
 			d.id,
 			d.specialization,
 			d.experience,
-			d.clinic_id,
-			d.bio,
+			COALESCE(d.bio, ''),
 			d.is_available,
 			d.name,
 			d.email,
 			COALESCE(d.photo_url, ''),
-			COALESCE(ROUND(AVG(dr.rating)::numeric, 2), 0)::float8 AS rating
+			d.languages,
+			` + doctorSpecializationsColumn + `,
+			` + doctorClinicsColumn + `,
+			` + doctorRatingColumns + `,
+			price.min_price::float8,
+			(` + sort.Expr + `)::text
 		FROM doctors d
-		LEFT JOIN doctor_ratings dr ON dr.doctor_id = d.id
-		WHERE d.is_deleted=0
-		GROUP BY d.id, d.specialization, d.experience, d.clinic_id, d.bio, d.is_available, d.name, d.email, d.photo_url
-		ORDER BY rating DESC, d.name
-	`
+		LEFT JOIN doctor_rating_stats s ON s.doctor_id = d.id
+		CROSS JOIN prior
+		` + textQuery + `
+		LEFT JOIN LATERAL (
+			SELECT MIN(COALESCE(ds.price, cs.price)) AS min_price
+			FROM doctor_services ds
+			JOIN clinic_services cs ON cs.id = ds.clinic_service_id
+			WHERE ` + offer + `
+		) price ON TRUE
+		WHERE ` + b.WhereSQL() + `
+		` + page
 
-	rows, err := r.db.Query(context.Background(), query)
+	rows, err := r.db.Query(context.Background(), query, b.Args()...)
 	if err != nil {
-		return nil, err
+		return search.Result[models.Doctor]{}, err
 	}
 	defer rows.Close()
 
-	var doctors []models.Doctor
+	doctors := make([]models.Doctor, 0)
+	keys := make([]string, 0)
+	ids := make([]uuid.UUID, 0)
 	for rows.Next() {
 		var d models.Doctor
-		if err := rows.Scan(&d.Id, &d.Specialization, &d.Experience, &d.ClinicID, &d.Bio, &d.IsAvailable, &d.Name, &d.Email, &d.PhotoURL, &d.Rating); err != nil {
-			return nil, err
+		var key string
+		dest := append([]any{&d.Id, &d.Specialization, &d.Experience, &d.Bio, &d.IsAvailable, &d.Name, &d.Email, &d.PhotoURL, &d.Languages, &d.Specializations, &d.ClinicIDs}, ratingScanDest(&d)...)
+		dest = append(dest, &d.MinPrice, &key)
+		if err := rows.Scan(dest...); err != nil {
+			return search.Result[models.Doctor]{}, err
 		}
 		doctors = append(doctors, d)
+		keys = append(keys, key)
+		ids = append(ids, d.Id)
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, err
+		return search.Result[models.Doctor]{}, err
 	}
 
-	return doctors, nil
+	return search.Trim(doctors, keys, ids, sortName, p.Limit), nil
 }
 
 func (r *doctorRepo) GetByID(id string) (*models.Doctor, error) {
@@ -97,22 +223,23 @@ func (r *doctorRepo) GetByID(id string) (*models.Doctor, error) {
 			d.id,
 			d.specialization,
 			d.experience,
-			d.clinic_id,
 			d.bio,
 			d.is_available,
 			d.name,
 			d.email,
 			d.user_id,
 			COALESCE(d.photo_url, ''),
-			COALESCE(ROUND(AVG(dr.rating)::numeric, 2), 0)::float8 AS rating
+			d.languages,
+			` + doctorSpecializationsColumn + `,
+			` + doctorClinicsColumn + `,
+			` + doctorRatingColumns + `
 		FROM doctors d
-		LEFT JOIN doctor_ratings dr ON dr.doctor_id = d.id
+		LEFT JOIN doctor_rating_stats s ON s.doctor_id = d.id
 		WHERE d.id = $1 AND d.is_deleted=0
-		GROUP BY d.id, d.specialization, d.experience, d.clinic_id, d.bio, d.is_available, d.name, d.email, d.user_id, d.photo_url
 	`
 	var d models.Doctor
-	err := r.db.QueryRow(context.Background(), query, id).
-		Scan(&d.Id, &d.Specialization, &d.Experience, &d.ClinicID, &d.Bio, &d.IsAvailable, &d.Name, &d.Email, &d.UserId, &d.PhotoURL, &d.Rating)
+	dest := append([]any{&d.Id, &d.Specialization, &d.Experience, &d.Bio, &d.IsAvailable, &d.Name, &d.Email, &d.UserId, &d.PhotoURL, &d.Languages, &d.Specializations, &d.ClinicIDs}, ratingScanDest(&d)...)
+	err := r.db.QueryRow(context.Background(), query, id).Scan(dest...)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, nil
@@ -125,20 +252,20 @@ func (r *doctorRepo) GetByID(id string) (*models.Doctor, error) {
 func (r *doctorRepo) Update(id string, doctor *models.Doctor) (*models.Doctor, error) {
 	query := `
 		UPDATE doctors
-		SET specialization=$1, experience=$2, clinic_id=$3, bio=$4, is_available=$5
-		WHERE id=$6
-		RETURNING id, specialization, experience, clinic_id, bio, is_available
+		SET specialization=$1, experience=$2, bio=$3, is_available=$4, languages=$6
+		WHERE id=$5
+		RETURNING id, specialization, experience, bio, is_available, languages
 	`
 	err := r.db.QueryRow(
 		context.Background(),
 		query,
 		doctor.Specialization,
 		doctor.Experience,
-		doctor.ClinicID,
 		doctor.Bio,
 		doctor.IsAvailable,
 		id,
-	).Scan(&doctor.Id, &doctor.Specialization, &doctor.Experience, &doctor.ClinicID, &doctor.Bio, &doctor.IsAvailable)
+		doctor.Languages,
+	).Scan(&doctor.Id, &doctor.Specialization, &doctor.Experience, &doctor.Bio, &doctor.IsAvailable, &doctor.Languages)
 
 	if err != nil {
 		if err == pgx.ErrNoRows {
@@ -171,22 +298,23 @@ func (r *doctorRepo) GetByUserID(id string) (*models.Doctor, error) {
 			d.id,
 			d.specialization,
 			d.experience,
-			d.clinic_id,
 			d.bio,
 			d.is_available,
 			d.name,
 			d.email,
 			d.user_id,
 			COALESCE(d.photo_url, ''),
-			COALESCE(ROUND(AVG(dr.rating)::numeric, 2), 0)::float8 AS rating
+			d.languages,
+			` + doctorSpecializationsColumn + `,
+			` + doctorClinicsColumn + `,
+			` + doctorRatingColumns + `
 		FROM doctors d
-		LEFT JOIN doctor_ratings dr ON dr.doctor_id = d.id
+		LEFT JOIN doctor_rating_stats s ON s.doctor_id = d.id
 		WHERE d.user_id = $1 AND d.is_deleted=0
-		GROUP BY d.id, d.specialization, d.experience, d.clinic_id, d.bio, d.is_available, d.name, d.email, d.user_id, d.photo_url
 	`
 	var d models.Doctor
-	err := r.db.QueryRow(context.Background(), query, id).
-		Scan(&d.Id, &d.Specialization, &d.Experience, &d.ClinicID, &d.Bio, &d.IsAvailable, &d.Name, &d.Email, &d.UserId, &d.PhotoURL, &d.Rating)
+	dest := append([]any{&d.Id, &d.Specialization, &d.Experience, &d.Bio, &d.IsAvailable, &d.Name, &d.Email, &d.UserId, &d.PhotoURL, &d.Languages, &d.Specializations, &d.ClinicIDs}, ratingScanDest(&d)...)
+	err := r.db.QueryRow(context.Background(), query, id).Scan(dest...)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, nil
@@ -217,3 +345,80 @@ func (r *doctorRepo) DeletePhoto(id string) error {
 	}
 	return nil
 }
+
+// doctorSpecializationsColumn lists the specializations of d, primary first.
+const doctorSpecializationsColumn = `ARRAY(
+		SELECT sp.specialization FROM doctor_specializations sp
+		WHERE sp.doctor_id = d.id
+		ORDER BY sp.position, sp.specialization
+	)`
+
+const doctorClinicsColumn = `ARRAY(
+		SELECT dc.clinic_id FROM doctor_clinics dc
+		WHERE dc.doctor_id = d.id AND dc.status = 'active'
+		ORDER BY dc.started_at, dc.created_at
+	)`
+
+// doctorServiceMembership limits doctor_services ds joined with
+// clinic_services cs to the clinics the doctor currently works at.
+const doctorServiceMembership = `EXISTS (
+		SELECT 1 FROM doctor_clinics dc
+		WHERE dc.doctor_id = ds.doctor_id AND dc.clinic_id = cs.clinic_id AND dc.status = 'active'
+	)`
+
+// ReplaceSpecializations stores specializations in the given order; the first
+// one is the primary specialization.
+func (r *doctorRepo) ReplaceSpecializations(doctorId uuid.UUID, specializations []string) error {
+	ctx := context.Background()
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM doctor_specializations WHERE doctor_id = $1`, doctorId); err != nil {
+		return err
+	}
+	for i, specialization := range specializations {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO doctor_specializations (doctor_id, specialization, position)
+			VALUES ($1, $2, $3)
+		`, doctorId, specialization, i)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// doctorRatingColumns reads the aggregate joined as s: mean, count, the
+// distribution of 1-5 stars, the mean of the last 90 days and its change
+// against the 90 days before.
+const doctorRatingColumns = `
+	COALESCE(ROUND(s.rating_sum::numeric / NULLIF(s.rating_count, 0), 2), 0)::float8,
+	COALESCE(s.rating_count, 0),
+	COALESCE(s.count_1, 0),
+	COALESCE(s.count_2, 0),
+	COALESCE(s.count_3, 0),
+	COALESCE(s.count_4, 0),
+	COALESCE(s.count_5, 0),
+	COALESCE(ROUND(s.recent_sum::numeric / NULLIF(s.recent_count, 0), 2), 0)::float8,
+	CASE WHEN s.recent_count > 0 AND s.previous_count > 0
+		THEN ROUND(s.recent_sum::numeric / s.recent_count - s.previous_sum::numeric / s.previous_count, 2)
+		ELSE 0
+	END::float8
+`
+
+func ratingScanDest(d *models.Doctor) []any {
+	return []any{
+		&d.Rating,
+		&d.RatingCount,
+		&d.RatingDistribution[0],
+		&d.RatingDistribution[1],
+		&d.RatingDistribution[2],
+		&d.RatingDistribution[3],
+		&d.RatingDistribution[4],
+		&d.RecentRating,
+		&d.RatingTrend,
+	}
+}
@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+
+	"dental_clinic/internal/modules/doctor/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const assignmentSelect = `
+	SELECT
+		ds.id, ds.doctor_id, ds.clinic_service_id, cs.clinic_id, COALESCE(c.name, ''),
+		cs.service_id, sv.name, COALESCE(cs.price, 0)::float8, COALESCE(cs.duration_minutes, 0),
+		ds.price::float8, ds.duration_minutes,
+		COALESCE(cs.is_active, false) AND EXISTS (
+			SELECT 1 FROM doctor_clinics dc
+			WHERE dc.doctor_id = ds.doctor_id AND dc.clinic_id = cs.clinic_id AND dc.status = 'active'
+		),
+		ds.created_at
+	FROM doctor_services ds
+	JOIN clinic_services cs ON cs.id = ds.clinic_service_id
+	JOIN clinics c ON c.id = cs.clinic_id
+	JOIN services sv ON sv.id = cs.service_id
+`
+
+func scanAssignment(row pgx.Row) (models.ServiceAssignment, error) {
+	var a models.ServiceAssignment
+	err := row.Scan(
+		&a.Id,
+		&a.DoctorId,
+		&a.ClinicServiceId,
+		&a.ClinicId,
+		&a.ClinicName,
+		&a.ServiceId,
+		&a.ServiceName,
+		&a.ClinicPrice,
+		&a.ClinicDuration,
+		&a.Price,
+		&a.Duration,
+		&a.Bookable,
+		&a.CreatedAt,
+	)
+	return a, err
+}
+
+// GetServiceAssignments returns the clinic services the doctor is assigned
+// to, grouped by clinic.
+func (r *doctorRepo) GetServiceAssignments(doctorId uuid.UUID) ([]models.ServiceAssignment, error) {
+	query := assignmentSelect + `
+		WHERE ds.doctor_id = $1
+		ORDER BY c.name, sv.name
+	`
+
+	rows, err := r.db.Query(context.Background(), query, doctorId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	assignments := make([]models.ServiceAssignment, 0)
+	for rows.Next() {
+		a, err := scanAssignment(rows)
+		if err != nil {
+			return nil, err
+		}
+		assignments = append(assignments, a)
+	}
+	return assignments, rows.Err()
+}
+
+func (r *doctorRepo) GetServiceAssignmentByID(id uuid.UUID) (*models.ServiceAssignment, error) {
+	a, err := scanAssignment(r.db.QueryRow(context.Background(), assignmentSelect+` WHERE ds.id = $1`, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (r *doctorRepo) GetServiceAssignment(doctorId, clinicServiceId uuid.UUID) (*models.ServiceAssignment, error) {
+	query := assignmentSelect + ` WHERE ds.doctor_id = $1 AND ds.clinic_service_id = $2`
+
+	a, err := scanAssignment(r.db.QueryRow(context.Background(), query, doctorId, clinicServiceId))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &a, nil
+}
+
+// GetClinicServiceClinic returns the clinic offering the clinic service, or
+// uuid.Nil when there is no such service.
+func (r *doctorRepo) GetClinicServiceClinic(clinicServiceId uuid.UUID) (uuid.UUID, error) {
+	var clinicId uuid.UUID
+	err := r.db.QueryRow(context.Background(), `SELECT clinic_id FROM clinic_services WHERE id = $1`, clinicServiceId).Scan(&clinicId)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return uuid.Nil, nil
+		}
+		return uuid.Nil, err
+	}
+	return clinicId, nil
+}
+
+func (r *doctorRepo) CreateServiceAssignment(a *models.ServiceAssignment) error {
+	query := `
+		INSERT INTO doctor_services (id, doctor_id, clinic_service_id, price, duration_minutes)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	_, err := r.db.Exec(context.Background(), query, a.Id, a.DoctorId, a.ClinicServiceId, a.Price, a.Duration)
+	return err
+}
+
+func (r *doctorRepo) UpdateServiceAssignment(a *models.ServiceAssignment) error {
+	query := `UPDATE doctor_services SET price = $2, duration_minutes = $3 WHERE id = $1`
+	result, err := r.db.Exec(context.Background(), query, a.Id, a.Price, a.Duration)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *doctorRepo) DeleteServiceAssignment(id uuid.UUID) error {
+	result, err := r.db.Exec(context.Background(), `DELETE FROM doctor_services WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
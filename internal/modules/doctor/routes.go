@@ -5,6 +5,7 @@ import (
 	"dental_clinic/internal/modules/doctor/handlers"
 	"dental_clinic/internal/modules/doctor/repository"
 	"dental_clinic/internal/modules/doctor/services"
+	"dental_clinic/internal/queue"
 
 	userRepository "dental_clinic/internal/modules/user/repository"
 	userServices "dental_clinic/internal/modules/user/services"
@@ -17,31 +18,38 @@ import (
 )
 
 func RegisterPublicRoutes(r *mux.Router, db *pgxpool.Pool, cfg *config.Config) {
+	jobQueue := queue.New(db)
+
 	repo := repository.NewDoctorRepository(db)
 
 	userRepo := userRepository.NewUserRepository(db)
-	userService := userServices.NewUserService(userRepo, *cfg)
+	userService := userServices.NewUserService(userRepo, *cfg, jobQueue)
 
 	medical_recordRepo := medical_recordRepository.NewMedicalRecordRepository(db)
 	medical_recordService := medical_recordServices.NewMedicalRecordService(medical_recordRepo)
 
-	service := services.NewDoctorService(repo, *userService, *medical_recordService, *cfg)
+	service := services.NewDoctorService(repo, *userService, *medical_recordService, *cfg, jobQueue)
 	handler := handlers.NewDoctorHandler(service, *cfg)
 
 	r.HandleFunc("/doctors", handler.GetAllDoctors).Methods("GET")
 	r.HandleFunc("/doctors/{id}", handler.GetDoctorByID).Methods("GET")
+	r.HandleFunc("/doctors/{id}/profile", handler.GetDoctorProfile).Methods("GET")
+	r.HandleFunc("/doctors/{id}/credentials", handler.GetDoctorCredentials).Methods("GET")
+	r.HandleFunc("/doctors/{id}/services", handler.GetDoctorServices).Methods("GET")
 }
 
 func RegisterPrivateRoutes(r *mux.Router, db *pgxpool.Pool, cfg *config.Config) {
+	jobQueue := queue.New(db)
+
 	repo := repository.NewDoctorRepository(db)
 
 	userRepo := userRepository.NewUserRepository(db)
-	userService := userServices.NewUserService(userRepo, *cfg)
+	userService := userServices.NewUserService(userRepo, *cfg, jobQueue)
 
 	medical_recordRepo := medical_recordRepository.NewMedicalRecordRepository(db)
 	medical_recordService := medical_recordServices.NewMedicalRecordService(medical_recordRepo)
 
-	service := services.NewDoctorService(repo, *userService, *medical_recordService, *cfg)
+	service := services.NewDoctorService(repo, *userService, *medical_recordService, *cfg, jobQueue)
 	handler := handlers.NewDoctorHandler(service, *cfg)
 
 	r.HandleFunc("/doctors", handler.CreateDoctor).Methods("POST")
@@ -52,4 +60,21 @@ func RegisterPrivateRoutes(r *mux.Router, db *pgxpool.Pool, cfg *config.Config)
 	r.HandleFunc("/doctors/{id}", handler.UpdateDoctor).Methods("PUT")
 	r.HandleFunc("/doctors/{id}", handler.DeleteDoctor).Methods("DELETE")
 	r.HandleFunc("/doctors/{id}", handler.GetDoctorByID).Methods("GET")
+
+	r.HandleFunc("/doctors/{id}/education", handler.AddDoctorEducation).Methods("POST")
+	r.HandleFunc("/doctors/{id}/education/{educationId}", handler.UpdateDoctorEducation).Methods("PUT")
+	r.HandleFunc("/doctors/{id}/education/{educationId}", handler.DeleteDoctorEducation).Methods("DELETE")
+	r.HandleFunc("/doctors/{id}/clinics", handler.GetDoctorClinics).Methods("GET")
+	r.HandleFunc("/doctors/{id}/clinics", handler.AddDoctorClinic).Methods("POST")
+	r.HandleFunc("/doctors/{id}/clinics/{clinicId}", handler.UpdateDoctorClinic).Methods("PUT")
+	r.HandleFunc("/doctors/{id}/clinics/{clinicId}", handler.EndDoctorClinic).Methods("DELETE")
+	r.HandleFunc("/doctors/{id}/services", handler.AssignDoctorService).Methods("POST")
+	r.HandleFunc("/doctors/{id}/services/{assignmentId}", handler.UpdateDoctorService).Methods("PUT")
+	r.HandleFunc("/doctors/{id}/services/{assignmentId}", handler.UnassignDoctorService).Methods("DELETE")
+	r.HandleFunc("/doctors/{id}/credentials", handler.AddDoctorCredential).Methods("POST")
+	r.HandleFunc("/doctors/{id}/credentials/{credentialId}", handler.DeleteDoctorCredential).Methods("DELETE")
+	r.HandleFunc("/doctor-credentials", handler.GetCredentialQueue).Methods("GET")
+	r.HandleFunc("/doctor-credentials/{id}/document", handler.GetCredentialDocument).Methods("GET")
+	r.HandleFunc("/doctor-credentials/{id}/verify", handler.VerifyCredential).Methods("POST")
+	r.HandleFunc("/doctor-credentials/{id}/reject", handler.RejectCredential).Methods("POST")
 }
@@ -0,0 +1,301 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"dental_clinic/internal/modules/doctor/dto"
+	"dental_clinic/internal/modules/doctor/models"
+
+	"github.com/google/uuid"
+)
+
+var membershipStatuses = map[string]bool{
+	"active":    true,
+	"suspended": true,
+	"ended":     true,
+}
+
+// GetClinicMemberships lists the clinics of a doctor. Platform admins and the
+// doctor see every membership, clinic admins only those of their clinics.
+func (s *DoctorService) GetClinicMemberships(doctorId, userId, role string) ([]models.ClinicMembership, error) {
+	doctor, err := s.GetDoctorByID(doctorId)
+	if err != nil {
+		return nil, err
+	}
+	memberships, err := s.repo.GetClinicMemberships(doctor.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	switch role {
+	case "admin":
+		return memberships, nil
+	case "doctor":
+		if doctor.UserId.String() == userId {
+			return memberships, nil
+		}
+	case "clinic_admin":
+		visible := make([]models.ClinicMembership, 0)
+		for _, m := range memberships {
+			if err := s.canManageClinic(m.ClinicId, userId, role); err == nil {
+				visible = append(visible, m)
+			}
+		}
+		if len(visible) > 0 {
+			return visible, nil
+		}
+	}
+	return nil, errors.New("do not have rights")
+}
+
+// AddClinicMembership starts the doctor working at a clinic, or brings back a
+// suspended or ended membership with the new terms.
+func (s *DoctorService) AddClinicMembership(doctorId, userId, role string, req dto.ClinicMembershipRequest) (*models.ClinicMembership, error) {
+	doctor, err := s.GetDoctorByID(doctorId)
+	if err != nil {
+		return nil, err
+	}
+	clinicId, err := uuid.Parse(req.ClinicID)
+	if err != nil {
+		return nil, errors.New("invalid clinic_id")
+	}
+	if err := s.canManageClinic(clinicId, userId, role); err != nil {
+		return nil, err
+	}
+	if err := s.checkClinic(clinicId); err != nil {
+		return nil, err
+	}
+	if err := validateCommission(req.CommissionPercent, req.CommissionFixed); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.repo.GetClinicMembership(doctor.Id, clinicId)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil && existing.Status == "active" {
+		return nil, errors.New("doctor already works at this clinic")
+	}
+
+	startedAt := today()
+	if req.StartedAt != "" {
+		startedAt, err = time.Parse("2006-01-02", req.StartedAt)
+		if err != nil {
+			return nil, errors.New("started_at must be YYYY-MM-DD")
+		}
+	}
+	serviceIds, err := s.clinicServiceIDs(clinicId, req.ClinicServiceIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	membership := &models.ClinicMembership{
+		Id:                uuid.New(),
+		DoctorId:          doctor.Id,
+		ClinicId:          clinicId,
+		Status:            "active",
+		CommissionPercent: req.CommissionPercent,
+		CommissionFixed:   req.CommissionFixed,
+		StartedAt:         startedAt,
+		ClinicServiceIds:  serviceIds,
+	}
+	if err := s.repo.SaveClinicMembership(membership); err != nil {
+		return nil, err
+	}
+	return s.getClinicMembership(doctor.Id, clinicId)
+}
+
+// UpdateClinicMembership changes the status, commission or services of a
+// doctor at a clinic. Suspending or ending the membership frees the doctor's
+// future slots there, and ending it removes their services.
+func (s *DoctorService) UpdateClinicMembership(doctorId, clinicId, userId, role string, req dto.UpdateClinicMembershipRequest) (*models.ClinicMembership, error) {
+	membership, err := s.manageableMembership(doctorId, clinicId, userId, role)
+	if err != nil {
+		return nil, err
+	}
+	if !membershipStatuses[req.Status] {
+		return nil, errors.New("status must be active, suspended or ended")
+	}
+	if err := validateCommission(req.CommissionPercent, req.CommissionFixed); err != nil {
+		return nil, err
+	}
+	serviceIds, err := s.clinicServiceIDs(membership.ClinicId, req.ClinicServiceIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	membership.CommissionPercent = req.CommissionPercent
+	membership.CommissionFixed = req.CommissionFixed
+	membership.ClinicServiceIds = serviceIds
+	setMembershipStatus(membership, req.Status)
+	if err := s.repo.SaveClinicMembership(membership); err != nil {
+		return nil, err
+	}
+	return s.getClinicMembership(membership.DoctorId, membership.ClinicId)
+}
+
+// EndClinicMembership records that the doctor no longer works at the clinic
+// and removes their services there, so they cannot be found or booked for
+// them. The membership is kept for reports.
+func (s *DoctorService) EndClinicMembership(doctorId, clinicId, userId, role string) error {
+	membership, err := s.manageableMembership(doctorId, clinicId, userId, role)
+	if err != nil {
+		return err
+	}
+	if membership.Status == "ended" {
+		return errors.New("membership already ended")
+	}
+	setMembershipStatus(membership, "ended")
+	return s.repo.SaveClinicMembership(membership)
+}
+
+func (s *DoctorService) manageableMembership(doctorId, clinicId, userId, role string) (*models.ClinicMembership, error) {
+	doctor, err := s.GetDoctorByID(doctorId)
+	if err != nil {
+		return nil, err
+	}
+	clinicUUID, err := uuid.Parse(clinicId)
+	if err != nil {
+		return nil, errors.New("invalid clinic id")
+	}
+	if err := s.canManageClinic(clinicUUID, userId, role); err != nil {
+		return nil, err
+	}
+	return s.getClinicMembership(doctor.Id, clinicUUID)
+}
+
+func (s *DoctorService) getClinicMembership(doctorId, clinicId uuid.UUID) (*models.ClinicMembership, error) {
+	membership, err := s.repo.GetClinicMembership(doctorId, clinicId)
+	if err != nil {
+		return nil, err
+	}
+	if membership == nil {
+		return nil, errors.New("membership not found")
+	}
+	return membership, nil
+}
+
+// canManageClinic allows platform admins and the admins of the clinic.
+func (s *DoctorService) canManageClinic(clinicId uuid.UUID, userId, role string) error {
+	switch role {
+	case "admin":
+		return nil
+	case "clinic_admin":
+		userUUID, err := uuid.Parse(userId)
+		if err != nil {
+			return errors.New("invalid user id")
+		}
+		isAdmin, err := s.repo.IsClinicAdmin(clinicId, userUUID)
+		if err != nil {
+			return err
+		}
+		if isAdmin {
+			return nil
+		}
+	}
+	return errors.New("do not have rights")
+}
+
+func (s *DoctorService) checkClinic(clinicId uuid.UUID) error {
+	exists, err := s.repo.ClinicExists(clinicId)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errors.New("clinic not found")
+	}
+	return nil
+}
+
+// clinicServiceIDs parses ids and checks that they are services of the
+// clinic. A nil ids is passed on as nil so the services stay as they are.
+func (s *DoctorService) clinicServiceIDs(clinicId uuid.UUID, ids []string) ([]uuid.UUID, error) {
+	if ids == nil {
+		return nil, nil
+	}
+	offered, err := s.repo.GetClinicServiceIDs(clinicId)
+	if err != nil {
+		return nil, err
+	}
+	isOffered := make(map[uuid.UUID]bool, len(offered))
+	for _, id := range offered {
+		isOffered[id] = true
+	}
+
+	result := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		serviceId, err := uuid.Parse(id)
+		if err != nil {
+			return nil, errors.New("invalid clinic_service_id")
+		}
+		if !isOffered[serviceId] {
+			return nil, errors.New("clinic service not found")
+		}
+		result = append(result, serviceId)
+	}
+	return result, nil
+}
+
+// setMembershipStatus sets the status and end date of the membership. Ending
+// it also removes the doctor's services at the clinic, so they can no longer
+// be found or booked for them.
+func setMembershipStatus(m *models.ClinicMembership, status string) {
+	m.Status = status
+	if status != "ended" {
+		m.EndedAt = nil
+		return
+	}
+	m.ClinicServiceIds = []uuid.UUID{}
+	if m.EndedAt == nil {
+		endedAt := today()
+		if endedAt.Before(m.StartedAt) {
+			endedAt = m.StartedAt
+		}
+		m.EndedAt = &endedAt
+	}
+}
+
+func validateCommission(percent, fixed float64) error {
+	if percent < 0 || percent > 100 {
+		return errors.New("commission_percent must be between 0 and 100")
+	}
+	if fixed < 0 {
+		return errors.New("commission_fixed cannot be negative")
+	}
+	return nil
+}
+
+func today() time.Time {
+	return time.Now().UTC().Truncate(24 * time.Hour)
+}
+
+func ToClinicMembershipResponse(m models.ClinicMembership) dto.ClinicMembershipResponse {
+	serviceIds := make([]string, 0, len(m.ClinicServiceIds))
+	for _, id := range m.ClinicServiceIds {
+		serviceIds = append(serviceIds, id.String())
+	}
+
+	response := dto.ClinicMembershipResponse{
+		Id:                m.Id.String(),
+		ClinicID:          m.ClinicId.String(),
+		ClinicName:        m.ClinicName,
+		Status:            m.Status,
+		CommissionPercent: m.CommissionPercent,
+		CommissionFixed:   m.CommissionFixed,
+		StartedAt:         m.StartedAt.Format("2006-01-02"),
+		ClinicServiceIDs:  serviceIds,
+	}
+	if m.EndedAt != nil {
+		response.EndedAt = m.EndedAt.Format("2006-01-02")
+	}
+	return response
+}
+
+func ToClinicMembershipResponseList(memberships []models.ClinicMembership) []dto.ClinicMembershipResponse {
+	result := make([]dto.ClinicMembershipResponse, 0, len(memberships))
+	for _, m := range memberships {
+		result = append(result, ToClinicMembershipResponse(m))
+	}
+	return result
+}
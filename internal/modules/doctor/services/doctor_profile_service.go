@@ -0,0 +1,406 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"dental_clinic/internal/modules/doctor/dto"
+	"dental_clinic/internal/modules/doctor/models"
+
+	"github.com/google/uuid"
+)
+
+var credentialTypes = map[string]bool{
+	"license":     true,
+	"certificate": true,
+	"diploma":     true,
+	"other":       true,
+}
+
+var credentialStatuses = map[string]bool{
+	"pending":  true,
+	"verified": true,
+	"rejected": true,
+}
+
+// GetDoctorProfile returns the public doctor page: the doctor with education
+// and verified credentials.
+func (s *DoctorService) GetDoctorProfile(id string) (*dto.DoctorProfileResponse, error) {
+	doctor, err := s.GetDoctorByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	education, err := s.repo.GetEducation(doctor.Id)
+	if err != nil {
+		return nil, err
+	}
+	credentials, err := s.repo.GetCredentials(doctor.Id, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.DoctorProfileResponse{
+		Doctor:      ToDoctorResponse(*doctor),
+		Education:   ToEducationResponseList(education),
+		Credentials: ToCredentialResponseList(credentials, false),
+	}, nil
+}
+
+// CanManageDoctor returns the doctor when the user may edit their profile:
+// the doctor themselves, an admin of one of the doctor's clinics or a
+// platform admin.
+func (s *DoctorService) CanManageDoctor(doctorId, userId, role string) (*models.Doctor, error) {
+	doctor, err := s.GetDoctorByID(doctorId)
+	if err != nil {
+		return nil, err
+	}
+
+	switch role {
+	case "admin":
+		return doctor, nil
+	case "doctor":
+		if doctor.UserId.String() == userId {
+			return doctor, nil
+		}
+	case "clinic_admin":
+		userUUID, err := uuid.Parse(userId)
+		if err != nil {
+			return nil, errors.New("invalid user id")
+		}
+		isAdmin, err := s.repo.IsDoctorClinicAdmin(doctor.Id, userUUID)
+		if err != nil {
+			return nil, err
+		}
+		if isAdmin {
+			return doctor, nil
+		}
+	}
+	return nil, errors.New("do not have rights")
+}
+
+func (s *DoctorService) AddEducation(doctorId, userId, role string, req dto.EducationRequest) (*models.Education, error) {
+	doctor, err := s.CanManageDoctor(doctorId, userId, role)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateEducation(req); err != nil {
+		return nil, err
+	}
+
+	education := &models.Education{
+		Id:           uuid.New(),
+		DoctorId:     doctor.Id,
+		Institution:  strings.TrimSpace(req.Institution),
+		Degree:       strings.TrimSpace(req.Degree),
+		FieldOfStudy: strings.TrimSpace(req.FieldOfStudy),
+		StartYear:    req.StartYear,
+		EndYear:      req.EndYear,
+	}
+	if err := s.repo.CreateEducation(education); err != nil {
+		return nil, err
+	}
+	return education, nil
+}
+
+func (s *DoctorService) UpdateEducation(doctorId, educationId, userId, role string, req dto.EducationRequest) (*models.Education, error) {
+	doctor, err := s.CanManageDoctor(doctorId, userId, role)
+	if err != nil {
+		return nil, err
+	}
+	education, err := s.getDoctorEducation(doctor, educationId)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateEducation(req); err != nil {
+		return nil, err
+	}
+
+	education.Institution = strings.TrimSpace(req.Institution)
+	education.Degree = strings.TrimSpace(req.Degree)
+	education.FieldOfStudy = strings.TrimSpace(req.FieldOfStudy)
+	education.StartYear = req.StartYear
+	education.EndYear = req.EndYear
+	if err := s.repo.UpdateEducation(education); err != nil {
+		return nil, err
+	}
+	return education, nil
+}
+
+func (s *DoctorService) DeleteEducation(doctorId, educationId, userId, role string) error {
+	doctor, err := s.CanManageDoctor(doctorId, userId, role)
+	if err != nil {
+		return err
+	}
+	education, err := s.getDoctorEducation(doctor, educationId)
+	if err != nil {
+		return err
+	}
+	return s.repo.DeleteEducation(education.Id)
+}
+
+func (s *DoctorService) getDoctorEducation(doctor *models.Doctor, educationId string) (*models.Education, error) {
+	id, err := uuid.Parse(educationId)
+	if err != nil {
+		return nil, errors.New("invalid education id")
+	}
+	education, err := s.repo.GetEducationByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if education == nil || education.DoctorId != doctor.Id {
+		return nil, errors.New("education not found")
+	}
+	return education, nil
+}
+
+func validateEducation(req dto.EducationRequest) error {
+	if strings.TrimSpace(req.Institution) == "" {
+		return errors.New("institution is required")
+	}
+	maxYear := time.Now().Year() + 10
+	for _, year := range []*int{req.StartYear, req.EndYear} {
+		if year != nil && (*year < 1900 || *year > maxYear) {
+			return errors.New("invalid year")
+		}
+	}
+	if req.StartYear != nil && req.EndYear != nil && *req.EndYear < *req.StartYear {
+		return errors.New("end_year must not be before start_year")
+	}
+	return nil
+}
+
+// ValidateCredential checks the form fields of a new credential before its
+// document is stored.
+func ValidateCredential(req dto.CredentialRequest) error {
+	if !credentialTypes[strings.ToLower(strings.TrimSpace(req.Type))] {
+		return errors.New("type must be license, certificate, diploma or other")
+	}
+	if strings.TrimSpace(req.Title) == "" {
+		return errors.New("title is required")
+	}
+	issuedAt, err := parseOptionalDate(req.IssuedAt)
+	if err != nil {
+		return errors.New("issued_at must be YYYY-MM-DD")
+	}
+	expiresAt, err := parseOptionalDate(req.ExpiresAt)
+	if err != nil {
+		return errors.New("expires_at must be YYYY-MM-DD")
+	}
+	if issuedAt != nil && expiresAt != nil && !expiresAt.After(*issuedAt) {
+		return errors.New("expires_at must be after issued_at")
+	}
+	return nil
+}
+
+// CreateCredential stores a credential pending verification by an admin.
+// documentPath, documentName and documentType describe the uploaded file.
+func (s *DoctorService) CreateCredential(doctor *models.Doctor, req dto.CredentialRequest, documentPath, documentName, documentType string) (*models.Credential, error) {
+	if err := ValidateCredential(req); err != nil {
+		return nil, err
+	}
+	issuedAt, _ := parseOptionalDate(req.IssuedAt)
+	expiresAt, _ := parseOptionalDate(req.ExpiresAt)
+
+	credential := &models.Credential{
+		Id:           uuid.New(),
+		DoctorId:     doctor.Id,
+		Type:         strings.ToLower(strings.TrimSpace(req.Type)),
+		Title:        strings.TrimSpace(req.Title),
+		Issuer:       strings.TrimSpace(req.Issuer),
+		Number:       strings.TrimSpace(req.Number),
+		IssuedAt:     issuedAt,
+		ExpiresAt:    expiresAt,
+		DocumentPath: documentPath,
+		DocumentName: documentName,
+		DocumentType: documentType,
+		Status:       "pending",
+	}
+	if err := s.repo.CreateCredential(credential); err != nil {
+		return nil, err
+	}
+	return credential, nil
+}
+
+// GetCredentials lists a doctor's credentials. Users who may manage the doctor
+// see pending and rejected ones too, everyone else only verified ones.
+func (s *DoctorService) GetCredentials(doctorId, userId, role string) ([]models.Credential, bool, error) {
+	doctor, err := s.GetDoctorByID(doctorId)
+	if err != nil {
+		return nil, false, err
+	}
+	_, err = s.CanManageDoctor(doctorId, userId, role)
+	manager := err == nil
+
+	credentials, err := s.repo.GetCredentials(doctor.Id, !manager)
+	if err != nil {
+		return nil, false, err
+	}
+	return credentials, manager, nil
+}
+
+// GetCredentialDocument returns the credential whose document the user asks
+// for, if they may manage its doctor.
+func (s *DoctorService) GetCredentialDocument(credentialId, userId, role string) (*models.Credential, error) {
+	credential, err := s.getCredential(credentialId)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.CanManageDoctor(credential.DoctorId.String(), userId, role); err != nil {
+		return nil, err
+	}
+	if credential.DocumentPath == "" {
+		return nil, errors.New("credential has no document")
+	}
+	return credential, nil
+}
+
+// DeleteCredential removes a credential and returns it so its document can be
+// deleted from storage.
+func (s *DoctorService) DeleteCredential(doctorId, credentialId, userId, role string) (*models.Credential, error) {
+	doctor, err := s.CanManageDoctor(doctorId, userId, role)
+	if err != nil {
+		return nil, err
+	}
+	credential, err := s.getCredential(credentialId)
+	if err != nil {
+		return nil, err
+	}
+	if credential.DoctorId != doctor.Id {
+		return nil, errors.New("credential not found")
+	}
+	if err := s.repo.DeleteCredential(credential.Id); err != nil {
+		return nil, err
+	}
+	return credential, nil
+}
+
+// GetCredentialQueue pages through credentials awaiting (or past) verification.
+// Only platform admins verify credentials.
+func (s *DoctorService) GetCredentialQueue(role, status string, page, limit int) ([]models.Credential, int, error) {
+	if role != "admin" {
+		return nil, 0, errors.New("do not have rights")
+	}
+	if status == "" {
+		status = "pending"
+	}
+	if !credentialStatuses[status] {
+		return nil, 0, errors.New("invalid status")
+	}
+	return s.repo.GetCredentialsByStatus(status, page, limit)
+}
+
+func (s *DoctorService) VerifyCredential(credentialId, userId, role, note string) (*models.Credential, error) {
+	return s.reviewCredential(credentialId, userId, role, "verified", note)
+}
+
+// RejectCredential turns a credential down; the note tells the doctor why.
+func (s *DoctorService) RejectCredential(credentialId, userId, role, note string) (*models.Credential, error) {
+	if strings.TrimSpace(note) == "" {
+		return nil, errors.New("note is required")
+	}
+	return s.reviewCredential(credentialId, userId, role, "rejected", note)
+}
+
+func (s *DoctorService) reviewCredential(credentialId, userId, role, status, note string) (*models.Credential, error) {
+	if role != "admin" {
+		return nil, errors.New("do not have rights")
+	}
+	reviewerId, err := uuid.Parse(userId)
+	if err != nil {
+		return nil, errors.New("invalid user id")
+	}
+	credential, err := s.getCredential(credentialId)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.SetCredentialStatus(credential.Id, status, reviewerId, strings.TrimSpace(note)); err != nil {
+		return nil, err
+	}
+	return s.repo.GetCredentialByID(credential.Id)
+}
+
+func (s *DoctorService) getCredential(credentialId string) (*models.Credential, error) {
+	id, err := uuid.Parse(credentialId)
+	if err != nil {
+		return nil, errors.New("invalid credential id")
+	}
+	credential, err := s.repo.GetCredentialByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if credential == nil {
+		return nil, errors.New("credential not found")
+	}
+	return credential, nil
+}
+
+func parseOptionalDate(v string) (*time.Time, error) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return nil, nil
+	}
+	t, err := time.Parse("2006-01-02", v)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func ToEducationResponseList(education []models.Education) []dto.EducationResponse {
+	result := make([]dto.EducationResponse, 0, len(education))
+	for _, e := range education {
+		result = append(result, ToEducationResponse(e))
+	}
+	return result
+}
+
+func ToEducationResponse(e models.Education) dto.EducationResponse {
+	return dto.EducationResponse{
+		Id:           e.Id.String(),
+		Institution:  e.Institution,
+		Degree:       e.Degree,
+		FieldOfStudy: e.FieldOfStudy,
+		StartYear:    e.StartYear,
+		EndYear:      e.EndYear,
+	}
+}
+
+// ToCredentialResponse leaves out the license number and the review details
+// unless private is set.
+func ToCredentialResponse(c models.Credential, private bool) dto.CredentialResponse {
+	response := dto.CredentialResponse{
+		Id:          c.Id.String(),
+		DoctorId:    c.DoctorId.String(),
+		Type:        c.Type,
+		Title:       c.Title,
+		Issuer:      c.Issuer,
+		Status:      c.Status,
+		HasDocument: c.DocumentPath != "",
+		CreatedAt:   c.CreatedAt.Format(time.RFC3339),
+	}
+	if c.IssuedAt != nil {
+		response.IssuedAt = c.IssuedAt.Format("2006-01-02")
+	}
+	if c.ExpiresAt != nil {
+		response.ExpiresAt = c.ExpiresAt.Format("2006-01-02")
+	}
+	if private {
+		response.Number = c.Number
+		response.DocumentName = c.DocumentName
+		response.ReviewNote = c.ReviewNote
+		if c.ReviewedAt != nil {
+			response.ReviewedAt = c.ReviewedAt.Format(time.RFC3339)
+		}
+	}
+	return response
+}
+
+func ToCredentialResponseList(credentials []models.Credential, private bool) []dto.CredentialResponse {
+	result := make([]dto.CredentialResponse, 0, len(credentials))
+	for _, c := range credentials {
+		result = append(result, ToCredentialResponse(c, private))
+	}
+	return result
+}
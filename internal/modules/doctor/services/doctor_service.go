@@ -1,15 +1,19 @@
 package services
 
 import (
+	"context"
 	"dental_clinic/internal/config"
+	"dental_clinic/internal/queue"
 	"dental_clinic/internal/utils"
 	"errors"
 	"fmt"
 	"math/rand"
+	"strings"
 
 	"dental_clinic/internal/modules/doctor/dto"
 	"dental_clinic/internal/modules/doctor/models"
 	"dental_clinic/internal/modules/doctor/repository"
+	"dental_clinic/internal/search"
 
 	medical_recordModels "dental_clinic/internal/modules/medical_record/models"
 	medical_recordServices "dental_clinic/internal/modules/medical_record/services"
@@ -24,14 +28,16 @@ type DoctorService struct {
 	userSrv           userServices.UserService
 	medical_recordSrv medical_recordServices.MedicalRecordService
 	cfg               config.Config
+	queue             *queue.Queue
 }
 
-func NewDoctorService(r repository.DoctorRepository, userSrv userServices.UserService, medical_recordSrv medical_recordServices.MedicalRecordService, cfg config.Config) *DoctorService {
+func NewDoctorService(r repository.DoctorRepository, userSrv userServices.UserService, medical_recordSrv medical_recordServices.MedicalRecordService, cfg config.Config, q *queue.Queue) *DoctorService {
 	return &DoctorService{
 		repo:              r,
 		userSrv:           userSrv,
 		medical_recordSrv: medical_recordSrv,
 		cfg:               cfg,
+		queue:             q,
 	}
 }
 func generateConfirmationCode() string {
@@ -57,9 +63,19 @@ func (s *DoctorService) CreateDoctor(req dto.CreateDoctorRequest) (*CreateDoctor
 		return nil, errors.New("email is empty")
 	}
 
-	clinicID, err := uuid.Parse(req.ClinicID)
-	if err != nil {
-		return nil, errors.New("invalid clinic_id")
+	if len(req.ClinicIDs) == 0 {
+		return nil, errors.New("clinic_ids is required")
+	}
+	clinicIDs := make([]uuid.UUID, 0, len(req.ClinicIDs))
+	for _, id := range req.ClinicIDs {
+		clinicID, err := uuid.Parse(id)
+		if err != nil {
+			return nil, errors.New("invalid clinic_id")
+		}
+		if err := s.checkClinic(clinicID); err != nil {
+			return nil, err
+		}
+		clinicIDs = append(clinicIDs, clinicID)
 	}
 
 	doctor := &models.Doctor{
@@ -67,10 +83,11 @@ func (s *DoctorService) CreateDoctor(req dto.CreateDoctorRequest) (*CreateDoctor
 		Email:          req.Email,
 		Specialization: req.Specialization,
 		Experience:     req.Experience,
-		ClinicID:       clinicID,
 		Bio:            req.Bio,
 		IsAvailable:    req.IsAvailable,
+		Languages:      normalizeLanguages(req.Languages),
 	}
+	doctor.Specializations = normalizeSpecializations(doctor.Specialization, req.Specializations)
 
 	user, err := s.userSrv.CreateUser(doctor.Email, req.Password, doctor.Name, "doctor", req.Is_active)
 	if err != nil {
@@ -84,8 +101,26 @@ func (s *DoctorService) CreateDoctor(req dto.CreateDoctorRequest) (*CreateDoctor
 		return nil, err
 	}
 
+	if err := s.repo.ReplaceSpecializations(doctor.Id, doctor.Specializations); err != nil {
+		return nil, err
+	}
+
+	for _, clinicID := range clinicIDs {
+		membership := &models.ClinicMembership{
+			Id:        uuid.New(),
+			DoctorId:  doctor.Id,
+			ClinicId:  clinicID,
+			Status:    "active",
+			StartedAt: today(),
+		}
+		if err := s.repo.SaveClinicMembership(membership); err != nil {
+			return nil, err
+		}
+		doctor.ClinicIDs = append(doctor.ClinicIDs, clinicID)
+	}
+
 	confirmationCode := generateConfirmationCode()
-	_ = utils.SendDoctorWelcomeEmail(&s.cfg, doctor.Email, doctor.Name, confirmationCode)
+	_ = utils.QueueDoctorWelcomeEmail(context.Background(), s.queue, doctor.Email, doctor.Name, confirmationCode)
 
 	return &CreateDoctorResult{
 		Doctor:           doctor,
@@ -93,8 +128,46 @@ func (s *DoctorService) CreateDoctor(req dto.CreateDoctorRequest) (*CreateDoctor
 	}, nil
 }
 
-func (s *DoctorService) GetAllDoctors() ([]models.Doctor, error) {
-	return s.repo.GetAll()
+// SearchDoctors returns one page of doctors. Without a sort the most
+// relevant doctors come first when searching by text, the best rated otherwise.
+func (s *DoctorService) SearchDoctors(p search.Params) (search.Result[models.Doctor], error) {
+	sortName, sort, err := search.ResolveSort(repository.DoctorSorts, p.Sort, "rating", p)
+	if err != nil {
+		return search.Result[models.Doctor]{}, err
+	}
+	return s.repo.Search(p, sortName, sort)
+}
+
+// normalizeLanguages lower-cases language codes and drops blanks and duplicates.
+func normalizeLanguages(languages []string) []string {
+	result := make([]string, 0, len(languages))
+	seen := make(map[string]bool)
+	for _, language := range languages {
+		language = strings.ToLower(strings.TrimSpace(language))
+		if language == "" || seen[language] {
+			continue
+		}
+		seen[language] = true
+		result = append(result, language)
+	}
+	return result
+}
+
+// normalizeSpecializations puts primary first and drops blanks and
+// case-insensitive duplicates.
+func normalizeSpecializations(primary string, specializations []string) []string {
+	result := make([]string, 0, len(specializations)+1)
+	seen := make(map[string]bool)
+	for _, specialization := range append([]string{primary}, specializations...) {
+		specialization = strings.TrimSpace(specialization)
+		key := strings.ToLower(specialization)
+		if specialization == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, specialization)
+	}
+	return result
 }
 
 func (s *DoctorService) GetDoctorByID(id string) (*models.Doctor, error) {
@@ -144,8 +217,23 @@ func (s *DoctorService) UpdateDoctor(id string, req dto.UpdateDoctorRequest) (*m
 	// doctor.ClinicID = clinicID
 	doctor.Bio = req.Bio
 	doctor.IsAvailable = req.IsAvailable
+	if req.Languages != nil {
+		doctor.Languages = normalizeLanguages(req.Languages)
+	}
+	specializations := doctor.Specializations
+	if req.Specializations != nil {
+		specializations = req.Specializations
+	}
+	doctor.Specializations = normalizeSpecializations(doctor.Specialization, specializations)
 
-	return s.repo.Update(id, doctor)
+	updated, err := s.repo.Update(id, doctor)
+	if err != nil || updated == nil {
+		return updated, err
+	}
+	if err := s.repo.ReplaceSpecializations(updated.Id, doctor.Specializations); err != nil {
+		return nil, err
+	}
+	return updated, nil
 }
 
 func (s *DoctorService) DeleteDoctor(id string) error {
@@ -165,19 +253,36 @@ func (s *DoctorService) DeleteDoctor(id string) error {
 
 func ToDoctorResponse(d models.Doctor) dto.DoctorResponse {
 	return dto.DoctorResponse{
-		Id:             d.Id.String(),
-		Specialization: d.Specialization,
-		Experience:     d.Experience,
-		ClinicID:       d.ClinicID.String(),
-		Bio:            d.Bio,
-		IsAvailable:    d.IsAvailable,
-		Name:           d.Name,
-		Email:          d.Email,
-		Rating:         d.Rating,
-		PhotoURL:       d.PhotoURL,
+		Id:              d.Id.String(),
+		Specialization:  d.Specialization,
+		Experience:      d.Experience,
+		Bio:             d.Bio,
+		IsAvailable:     d.IsAvailable,
+		Name:            d.Name,
+		Email:           d.Email,
+		Rating:          d.Rating,
+		PhotoURL:        d.PhotoURL,
+		Languages:       d.Languages,
+		Specializations: d.Specializations,
+		ClinicIDs:       clinicIDStrings(d.ClinicIDs),
+
+		RatingCount:        d.RatingCount,
+		RatingDistribution: d.RatingDistribution,
+		RecentRating:       d.RecentRating,
+		RatingTrend:        d.RatingTrend,
+
+		MinPrice: d.MinPrice,
 	}
 }
 
+func clinicIDStrings(ids []uuid.UUID) []string {
+	result := make([]string, 0, len(ids))
+	for _, id := range ids {
+		result = append(result, id.String())
+	}
+	return result
+}
+
 func ToDoctorResponseList(doctors []models.Doctor) []dto.DoctorResponse {
 	result := make([]dto.DoctorResponse, 0, len(doctors))
 	for _, d := range doctors {
@@ -0,0 +1,185 @@
+package services
+
+import (
+	"errors"
+
+	"dental_clinic/internal/modules/doctor/dto"
+	"dental_clinic/internal/modules/doctor/models"
+
+	"github.com/google/uuid"
+)
+
+// GetServiceAssignments lists the services a doctor performs. Patients only
+// see the bookable ones; the doctor, admins of the doctor's clinics and
+// platform admins see all of them.
+func (s *DoctorService) GetServiceAssignments(doctorId, userId, role string) ([]models.ServiceAssignment, error) {
+	doctor, err := s.GetDoctorByID(doctorId)
+	if err != nil {
+		return nil, err
+	}
+	assignments, err := s.repo.GetServiceAssignments(doctor.Id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.CanManageDoctor(doctorId, userId, role); err == nil {
+		return assignments, nil
+	}
+
+	bookable := make([]models.ServiceAssignment, 0, len(assignments))
+	for _, a := range assignments {
+		if a.Bookable {
+			bookable = append(bookable, a)
+		}
+	}
+	return bookable, nil
+}
+
+// AssignService lets the doctor perform a service of one of their clinics.
+// Allowed for admins of that clinic and platform admins.
+func (s *DoctorService) AssignService(doctorId, userId, role string, req dto.ServiceAssignmentRequest) (*models.ServiceAssignment, error) {
+	doctor, err := s.GetDoctorByID(doctorId)
+	if err != nil {
+		return nil, err
+	}
+	clinicServiceId, err := uuid.Parse(req.ClinicServiceID)
+	if err != nil {
+		return nil, errors.New("invalid clinic_service_id")
+	}
+	clinicId, err := s.repo.GetClinicServiceClinic(clinicServiceId)
+	if err != nil {
+		return nil, err
+	}
+	if clinicId == uuid.Nil {
+		return nil, errors.New("clinic service not found")
+	}
+	if err := s.canManageClinic(clinicId, userId, role); err != nil {
+		return nil, err
+	}
+	if err := validateOverrides(req.Price, req.Duration); err != nil {
+		return nil, err
+	}
+
+	membership, err := s.repo.GetClinicMembership(doctor.Id, clinicId)
+	if err != nil {
+		return nil, err
+	}
+	if membership == nil || membership.Status == "ended" {
+		return nil, errors.New("doctor does not belong to the clinic of this service")
+	}
+	existing, err := s.repo.GetServiceAssignment(doctor.Id, clinicServiceId)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, errors.New("service is already assigned to the doctor")
+	}
+
+	assignment := &models.ServiceAssignment{
+		Id:              uuid.New(),
+		DoctorId:        doctor.Id,
+		ClinicServiceId: clinicServiceId,
+		Price:           req.Price,
+		Duration:        req.Duration,
+	}
+	if err := s.repo.CreateServiceAssignment(assignment); err != nil {
+		return nil, err
+	}
+	return s.getServiceAssignment(doctor, assignment.Id)
+}
+
+func (s *DoctorService) UpdateServiceAssignment(doctorId, assignmentId, userId, role string, req dto.UpdateServiceAssignmentRequest) (*models.ServiceAssignment, error) {
+	doctor, assignment, err := s.manageableAssignment(doctorId, assignmentId, userId, role)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateOverrides(req.Price, req.Duration); err != nil {
+		return nil, err
+	}
+
+	assignment.Price = req.Price
+	assignment.Duration = req.Duration
+	if err := s.repo.UpdateServiceAssignment(assignment); err != nil {
+		return nil, err
+	}
+	return s.getServiceAssignment(doctor, assignment.Id)
+}
+
+func (s *DoctorService) UnassignService(doctorId, assignmentId, userId, role string) error {
+	_, assignment, err := s.manageableAssignment(doctorId, assignmentId, userId, role)
+	if err != nil {
+		return err
+	}
+	return s.repo.DeleteServiceAssignment(assignment.Id)
+}
+
+func (s *DoctorService) manageableAssignment(doctorId, assignmentId, userId, role string) (*models.Doctor, *models.ServiceAssignment, error) {
+	doctor, err := s.GetDoctorByID(doctorId)
+	if err != nil {
+		return nil, nil, err
+	}
+	id, err := uuid.Parse(assignmentId)
+	if err != nil {
+		return nil, nil, errors.New("invalid assignment id")
+	}
+	assignment, err := s.getServiceAssignment(doctor, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.canManageClinic(assignment.ClinicId, userId, role); err != nil {
+		return nil, nil, err
+	}
+	return doctor, assignment, nil
+}
+
+func (s *DoctorService) getServiceAssignment(doctor *models.Doctor, id uuid.UUID) (*models.ServiceAssignment, error) {
+	assignment, err := s.repo.GetServiceAssignmentByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if assignment == nil || assignment.DoctorId != doctor.Id {
+		return nil, errors.New("assignment not found")
+	}
+	return assignment, nil
+}
+
+func validateOverrides(price *float64, duration *int) error {
+	if price != nil && *price < 0 {
+		return errors.New("price cannot be negative")
+	}
+	if duration != nil && *duration <= 0 {
+		return errors.New("duration must be greater than 0")
+	}
+	return nil
+}
+
+func ToServiceAssignmentResponse(a models.ServiceAssignment) dto.ServiceAssignmentResponse {
+	price, duration := a.ClinicPrice, a.ClinicDuration
+	if a.Price != nil {
+		price = *a.Price
+	}
+	if a.Duration != nil {
+		duration = *a.Duration
+	}
+
+	return dto.ServiceAssignmentResponse{
+		Id:               a.Id.String(),
+		ClinicServiceID:  a.ClinicServiceId.String(),
+		ClinicID:         a.ClinicId.String(),
+		ClinicName:       a.ClinicName,
+		ServiceID:        a.ServiceId.String(),
+		ServiceName:      a.ServiceName,
+		Price:            price,
+		Duration:         duration,
+		PriceOverride:    a.Price,
+		DurationOverride: a.Duration,
+		Bookable:         a.Bookable,
+	}
+}
+
+func ToServiceAssignmentResponseList(assignments []models.ServiceAssignment) []dto.ServiceAssignmentResponse {
+	result := make([]dto.ServiceAssignmentResponse, 0, len(assignments))
+	for _, a := range assignments {
+		result = append(result, ToServiceAssignmentResponse(a))
+	}
+	return result
+}
@@ -12,13 +12,32 @@ type ProductResponse struct {
 	CreatedAt string `json:"created_at"`
 }
 
+// InventoryQuantityRequest receives stock. Stock with a lot_number is kept
+// apart by lot; expires_at is a date like 2006-01-02 and unit_cost what one
+// unit cost.
 type InventoryQuantityRequest struct {
-	ProductId string  `json:"product_id"`
-	Quantity  float64 `json:"quantity"`
+	ProductId string   `json:"product_id"`
+	Quantity  float64  `json:"quantity"`
+	LotNumber string   `json:"lot_number"`
+	ExpiresAt string   `json:"expires_at"`
+	UnitCost  *float64 `json:"unit_cost"`
 }
 
+// UpdateInventoryRequest overwrites the quantity in stock. reason_code says
+// why it differed: damaged, expired, lost, theft, found, entry_error or
+// other.
 type UpdateInventoryRequest struct {
-	Quantity float64 `json:"quantity"`
+	Quantity   float64 `json:"quantity"`
+	ReasonCode string  `json:"reason_code"`
+}
+
+// InventoryLevelsRequest sets the stock levels of a product at an address.
+// Below min_quantity the stock is low; at or below reorder_level it is time to
+// order more.
+type InventoryLevelsRequest struct {
+	ProductId    string  `json:"product_id"`
+	MinQuantity  float64 `json:"min_quantity"`
+	ReorderLevel float64 `json:"reorder_level"`
 }
 
 type InventoryResponse struct {
@@ -28,6 +47,10 @@ type InventoryResponse struct {
 	ProductName     string  `json:"product_name"`
 	ProductUnit     string  `json:"product_unit"`
 	Quantity        float64 `json:"quantity"`
+	Reserved        float64 `json:"reserved"`
+	Available       float64 `json:"available"`
+	MinQuantity     float64 `json:"min_quantity"`
+	ReorderLevel    float64 `json:"reorder_level"`
 	UpdatedAt       string  `json:"updated_at"`
 }
 
@@ -39,10 +62,72 @@ type InventoryStatusResponse struct {
 	ProductName     string  `json:"product_name"`
 	ProductUnit     string  `json:"product_unit"`
 	Quantity        float64 `json:"quantity"`
+	Reserved        float64 `json:"reserved"`
+	Available       float64 `json:"available"`
+	MinQuantity     float64 `json:"min_quantity"`
+	ReorderLevel    float64 `json:"reorder_level"`
+	Status          string  `json:"status"`
 	Color           string  `json:"color"`
 	Message         string  `json:"message"`
 }
 
+// AlertPreferenceRequest chooses how low stock is reported: immediate emails,
+// a daily_digest, or off.
+type AlertPreferenceRequest struct {
+	Mode string `json:"mode"`
+}
+
+type AlertPreferenceResponse struct {
+	ClinicId  string `json:"clinic_id"`
+	Mode      string `json:"mode"`
+	UpdatedAt string `json:"updated_at,omitempty"`
+}
+
+// InventorySettingsRequest chooses whether a booking whose materials are not
+// available is only warned about (warn) or refused (block), and whether lots
+// are used first expired, first out (fefo) or first in, first out (fifo), and
+// whether stock is valued at its average cost (weighted_average) or at the
+// cost of its lots (fifo). Fields left empty keep their current value.
+type InventorySettingsRequest struct {
+	ShortagePolicy   string `json:"shortage_policy"`
+	ConsumptionOrder string `json:"consumption_order"`
+	ValuationMethod  string `json:"valuation_method"`
+}
+
+type InventorySettingsResponse struct {
+	ClinicId         string `json:"clinic_id"`
+	ShortagePolicy   string `json:"shortage_policy"`
+	ConsumptionOrder string `json:"consumption_order"`
+	ValuationMethod  string `json:"valuation_method"`
+	UpdatedAt        string `json:"updated_at,omitempty"`
+}
+
+type LotQuantityRequest struct {
+	Quantity   float64 `json:"quantity"`
+	ReasonCode string  `json:"reason_code"`
+}
+
+type LotResponse struct {
+	Id              string   `json:"id"`
+	ClinicAddressId string   `json:"clinic_address_id"`
+	ProductId       string   `json:"product_id"`
+	ProductName     string   `json:"product_name"`
+	ProductUnit     string   `json:"product_unit"`
+	LotNumber       string   `json:"lot_number"`
+	ExpiresAt       string   `json:"expires_at,omitempty"`
+	Quantity        float64  `json:"quantity"`
+	UnitCost        *float64 `json:"unit_cost,omitempty"`
+	ReceivedAt      string   `json:"received_at"`
+}
+
+// ExpiringLotResponse is a lot on the expiring-soon report. DaysLeft is
+// negative for lots that already expired.
+type ExpiringLotResponse struct {
+	LotResponse
+	DaysLeft int  `json:"days_left"`
+	Expired  bool `json:"expired"`
+}
+
 type AttachMaterialRequest struct {
 	ProductId        string  `json:"product_id"`
 	QuantityRequired float64 `json:"quantity_required"`
@@ -58,14 +143,107 @@ type ServiceMaterialResponse struct {
 }
 
 type InventoryTransactionResponse struct {
-	Id              string  `json:"id"`
-	ClinicAddressId string  `json:"clinic_address_id"`
+	Id              string   `json:"id"`
+	ClinicAddressId string   `json:"clinic_address_id"`
+	ProductId       string   `json:"product_id"`
+	ProductName     string   `json:"product_name"`
+	Quantity        float64  `json:"quantity"`
+	TransactionType string   `json:"transaction_type"`
+	AppointmentId   string   `json:"appointment_id,omitempty"`
+	LotId           string   `json:"lot_id,omitempty"`
+	LotNumber       string   `json:"lot_number,omitempty"`
+	ExpiresAt       string   `json:"expires_at,omitempty"`
+	UnitCost        *float64 `json:"unit_cost,omitempty"`
+	GoodsReceiptId  string   `json:"goods_receipt_id,omitempty"`
+	StockTransferId string   `json:"stock_transfer_id,omitempty"`
+	ReasonCode      string   `json:"reason_code,omitempty"`
+	StockCountId    string   `json:"stock_count_id,omitempty"`
+	CreatedAt       string   `json:"created_at"`
+}
+
+// StockTransferRequest moves stock from one address of a clinic to another.
+// The stock leaves the sending address at once; with receive set it also
+// arrives at once instead of staying in transit.
+type StockTransferRequest struct {
+	FromClinicAddressId string                     `json:"from_clinic_address_id"`
+	ToClinicAddressId   string                     `json:"to_clinic_address_id"`
+	Notes               string                     `json:"notes"`
+	Receive             bool                       `json:"receive"`
+	Items               []StockTransferItemRequest `json:"items"`
+}
+
+type StockTransferItemRequest struct {
+	ProductId string  `json:"product_id"`
+	Quantity  float64 `json:"quantity"`
+}
+
+type StockTransferResponse struct {
+	Id                  string                      `json:"id"`
+	ClinicId            string                      `json:"clinic_id"`
+	FromClinicAddressId string                      `json:"from_clinic_address_id"`
+	ToClinicAddressId   string                      `json:"to_clinic_address_id"`
+	Status              string                      `json:"status"`
+	Notes               string                      `json:"notes"`
+	CreatedAt           string                      `json:"created_at"`
+	ReceivedAt          string                      `json:"received_at,omitempty"`
+	Items               []StockTransferItemResponse `json:"items"`
+}
+
+type StockTransferItemResponse struct {
+	Id          string   `json:"id"`
+	ProductId   string   `json:"product_id"`
+	ProductName string   `json:"product_name"`
+	ProductUnit string   `json:"product_unit"`
+	LotNumber   string   `json:"lot_number,omitempty"`
+	ExpiresAt   string   `json:"expires_at,omitempty"`
+	UnitCost    *float64 `json:"unit_cost,omitempty"`
+	Quantity    float64  `json:"quantity"`
+}
+
+// StockCountRequest starts a count of the stock at a clinic address.
+type StockCountRequest struct {
+	ClinicAddressId string `json:"clinic_address_id"`
+	Notes           string `json:"notes"`
+}
+
+// StockCountItemsRequest enters counted quantities. Products counted again
+// replace the earlier count.
+type StockCountItemsRequest struct {
+	Items []StockCountItemRequest `json:"items"`
+}
+
+// StockCountItemRequest is what was counted of a product. reason_code is
+// required to post a count that differs from the expected one.
+type StockCountItemRequest struct {
 	ProductId       string  `json:"product_id"`
-	ProductName     string  `json:"product_name"`
-	Quantity        float64 `json:"quantity"`
-	TransactionType string  `json:"transaction_type"`
-	AppointmentId   string  `json:"appointment_id,omitempty"`
-	CreatedAt       string  `json:"created_at"`
+	CountedQuantity float64 `json:"counted_quantity"`
+	ReasonCode      string  `json:"reason_code"`
+	Note            string  `json:"note"`
+}
+
+type StockCountResponse struct {
+	Id              string                   `json:"id"`
+	ClinicId        string                   `json:"clinic_id"`
+	ClinicAddressId string                   `json:"clinic_address_id"`
+	Status          string                   `json:"status"`
+	Notes           string                   `json:"notes"`
+	CountedItems    int                      `json:"counted_items"`
+	VarianceItems   int                      `json:"variance_items"`
+	CreatedAt       string                   `json:"created_at"`
+	PostedAt        string                   `json:"posted_at,omitempty"`
+	Items           []StockCountItemResponse `json:"items"`
+}
+
+type StockCountItemResponse struct {
+	Id               string   `json:"id"`
+	ProductId        string   `json:"product_id"`
+	ProductName      string   `json:"product_name"`
+	ProductUnit      string   `json:"product_unit"`
+	ExpectedQuantity float64  `json:"expected_quantity"`
+	CountedQuantity  *float64 `json:"counted_quantity"`
+	Variance         *float64 `json:"variance"`
+	ReasonCode       string   `json:"reason_code,omitempty"`
+	Note             string   `json:"note,omitempty"`
 }
 
 type ActionResponse struct {
@@ -6,10 +6,14 @@ import (
 	"errors"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
+	"dental_clinic/internal/config"
 	"dental_clinic/internal/modules/inventory/dto"
 	"dental_clinic/internal/modules/inventory/models"
 	"dental_clinic/internal/modules/inventory/services"
+	"dental_clinic/internal/utils"
 
 	"github.com/jackc/pgx/v5"
 
@@ -18,10 +22,11 @@ import (
 
 type InventoryHandler struct {
 	service *services.InventoryService
+	cfg     config.Config
 }
 
-func NewInventoryHandler(service *services.InventoryService) *InventoryHandler {
-	return &InventoryHandler{service: service}
+func NewInventoryHandler(service *services.InventoryService, cfg config.Config) *InventoryHandler {
+	return &InventoryHandler{service: service, cfg: cfg}
 }
 
 // CreateProduct godoc
@@ -142,7 +147,7 @@ func (h *InventoryHandler) DeleteProduct(w http.ResponseWriter, r *http.Request)
 
 // AddStock godoc
 // @Summary Add stock
-// @Description Adds product stock to a clinic address inventory and records a restocked transaction
+// @Description Adds product stock to a clinic address inventory and records a restocked transaction. Stock with a lot_number and expires_at is tracked by lot, and unit_cost records what it cost
 // @Tags Inventory
 // @Security BearerAuth
 // @Accept json
@@ -170,7 +175,7 @@ func (h *InventoryHandler) AddStock(w http.ResponseWriter, r *http.Request) {
 
 // GetInventory godoc
 // @Summary Get clinic address inventory
-// @Description Returns inventory for a clinic address
+// @Description Returns inventory for a clinic address with the quantity reserved for booked appointments and what is still available
 // @Tags Inventory
 // @Security BearerAuth
 // @Produce json
@@ -189,13 +194,13 @@ func (h *InventoryHandler) GetInventory(w http.ResponseWriter, r *http.Request)
 
 // GetInventoryStatus godoc
 // @Summary Get inventory status notifications
-// @Description Returns inventory status notifications for a clinic address. Color values: red means finished, yellow means running low, green means available.
+// @Description Returns inventory status notifications for a clinic address. Status is ok, reorder (at or below the reorder level), low_stock (below the minimum) or out_of_stock. Color values: red means finished, yellow means running low or due for reorder, green means available.
 // @Tags Inventory
 // @Security BearerAuth
 // @Produce json
 // @Param clinicId path string true "Clinic ID"
 // @Param addressId path string true "Clinic address ID"
-// @Param threshold query number false "Yellow threshold. Default is 5"
+// @Param threshold query number false "Reorder level for products without levels set. Default is 5"
 // @Success 200 {array} dto.InventoryStatusResponse
 // @Failure 400 {object} map[string]string
 // @Router /api/clinics/{clinicId}/clinic-addresses/{addressId}/inventory-status [get]
@@ -221,7 +226,7 @@ func (h *InventoryHandler) GetInventoryStatus(w http.ResponseWriter, r *http.Req
 
 // UpdateInventory godoc
 // @Summary Update inventory quantity
-// @Description Sets inventory quantity for a clinic address inventory item and records a manual adjustment transaction
+// @Description Sets inventory quantity for a clinic address inventory item and records a manual adjustment transaction with an optional reason_code (damaged, expired, lost, theft, found, entry_error or other). Stock removed comes out of the lots in the clinic's consumption order
 // @Tags Inventory
 // @Security BearerAuth
 // @Accept json
@@ -249,6 +254,233 @@ func (h *InventoryHandler) UpdateInventory(w http.ResponseWriter, r *http.Reques
 	respondJSON(w, http.StatusOK, toInventoryResponse(*item))
 }
 
+// SetInventoryLevels godoc
+// @Summary Set inventory levels
+// @Description Sets the minimum and reorder levels of a product at a clinic address. Clinic admins are alerted when consumption takes the stock to these levels.
+// @Tags Inventory
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Clinic address ID"
+// @Param request body dto.InventoryLevelsRequest true "Inventory levels"
+// @Success 200 {object} dto.InventoryResponse
+// @Failure 400 {object} map[string]string
+// @Router /api/clinic-addresses/{id}/inventory-levels [put]
+func (h *InventoryHandler) SetInventoryLevels(w http.ResponseWriter, r *http.Request) {
+	var req dto.InventoryLevelsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	defer r.Body.Close()
+
+	item, err := h.service.SetInventoryLevels(mux.Vars(r)["id"], req)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, toInventoryResponse(*item))
+}
+
+// GetAlertPreference godoc
+// @Summary Get low stock alert preference
+// @Description Returns how the current clinic admin is told about low stock at the clinic: immediate, daily_digest or off
+// @Tags Inventory
+// @Security BearerAuth
+// @Produce json
+// @Param clinicId path string true "Clinic ID"
+// @Success 200 {object} dto.AlertPreferenceResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /api/clinics/{clinicId}/inventory-alerts [get]
+func (h *InventoryHandler) GetAlertPreference(w http.ResponseWriter, r *http.Request) {
+	userId, role := h.currentUser(r)
+	preference, err := h.service.GetAlertPreference(mux.Vars(r)["clinicId"], userId, role)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, toAlertPreferenceResponse(*preference))
+}
+
+// UpdateAlertPreference godoc
+// @Summary Update low stock alert preference
+// @Description Chooses between an email on every stock drop, one daily digest, or no stock emails for the clinic
+// @Tags Inventory
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param clinicId path string true "Clinic ID"
+// @Param request body dto.AlertPreferenceRequest true "Alert preference"
+// @Success 200 {object} dto.AlertPreferenceResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /api/clinics/{clinicId}/inventory-alerts [put]
+func (h *InventoryHandler) UpdateAlertPreference(w http.ResponseWriter, r *http.Request) {
+	var req dto.AlertPreferenceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	defer r.Body.Close()
+
+	userId, role := h.currentUser(r)
+	preference, err := h.service.UpdateAlertPreference(mux.Vars(r)["clinicId"], userId, role, req)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, toAlertPreferenceResponse(*preference))
+}
+
+// GetLots godoc
+// @Summary Get inventory lots
+// @Description Returns the lots in stock at a clinic address with their expiry dates, first to expire first
+// @Tags Inventory
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Clinic address ID"
+// @Param product_id query string false "Product ID"
+// @Success 200 {array} dto.LotResponse
+// @Failure 400 {object} map[string]string
+// @Router /api/clinic-addresses/{id}/inventory-lots [get]
+func (h *InventoryHandler) GetLots(w http.ResponseWriter, r *http.Request) {
+	lots, err := h.service.GetLots(mux.Vars(r)["id"], r.URL.Query().Get("product_id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	result := make([]dto.LotResponse, 0, len(lots))
+	for _, lot := range lots {
+		result = append(result, toLotResponse(lot))
+	}
+	respondJSON(w, http.StatusOK, result)
+}
+
+// UpdateLot godoc
+// @Summary Update inventory lot quantity
+// @Description Corrects the quantity of a lot, with an optional reason_code; the clinic address total changes with it
+// @Tags Inventory
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Clinic address ID"
+// @Param lotId path string true "Lot ID"
+// @Param request body dto.LotQuantityRequest true "Lot quantity"
+// @Success 200 {object} dto.LotResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/clinic-addresses/{id}/inventory-lots/{lotId} [put]
+func (h *InventoryHandler) UpdateLot(w http.ResponseWriter, r *http.Request) {
+	var req dto.LotQuantityRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	defer r.Body.Close()
+
+	vars := mux.Vars(r)
+	lot, err := h.service.UpdateLot(r.Context(), vars["id"], vars["lotId"], req)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, toLotResponse(*lot))
+}
+
+// GetExpiringLots godoc
+// @Summary Get expiring inventory lots
+// @Description Reports lots at the clinic that expire within the given number of days, and expired lots still in stock
+// @Tags Inventory
+// @Security BearerAuth
+// @Produce json
+// @Param clinicId path string true "Clinic ID"
+// @Param days query int false "Days ahead. Default is 30"
+// @Param clinic_address_id query string false "Clinic address ID"
+// @Success 200 {array} dto.ExpiringLotResponse
+// @Failure 400 {object} map[string]string
+// @Router /api/clinics/{clinicId}/inventory-expiring [get]
+func (h *InventoryHandler) GetExpiringLots(w http.ResponseWriter, r *http.Request) {
+	days := 30
+	if rawDays := r.URL.Query().Get("days"); rawDays != "" {
+		parsedDays, err := strconv.Atoi(rawDays)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "invalid days")
+			return
+		}
+		days = parsedDays
+	}
+
+	lots, err := h.service.GetExpiringLots(mux.Vars(r)["clinicId"], r.URL.Query().Get("clinic_address_id"), days)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	result := make([]dto.ExpiringLotResponse, 0, len(lots))
+	for _, lot := range lots {
+		daysLeft := int(lot.ExpiresAt.Sub(today).Hours() / 24)
+		result = append(result, dto.ExpiringLotResponse{
+			LotResponse: toLotResponse(lot),
+			DaysLeft:    daysLeft,
+			Expired:     daysLeft < 0,
+		})
+	}
+	respondJSON(w, http.StatusOK, result)
+}
+
+// GetInventorySettings godoc
+// @Summary Get clinic inventory settings
+// @Description Returns the clinic's material shortage policy (warn books appointments whose materials are not available with a warning, block refuses them), the order lots are used in (fefo or fifo) and how stock on hand is valued (weighted_average or fifo)
+// @Tags Inventory
+// @Security BearerAuth
+// @Produce json
+// @Param clinicId path string true "Clinic ID"
+// @Success 200 {object} dto.InventorySettingsResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /api/clinics/{clinicId}/inventory-settings [get]
+func (h *InventoryHandler) GetInventorySettings(w http.ResponseWriter, r *http.Request) {
+	userId, role := h.currentUser(r)
+	settings, err := h.service.GetInventorySettings(mux.Vars(r)["clinicId"], userId, role)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, toInventorySettingsResponse(*settings))
+}
+
+// UpdateInventorySettings godoc
+// @Summary Update clinic inventory settings
+// @Description Sets whether bookings whose materials are not available at the clinic address are warned about or refused, whether lots are used first expired, first out (fefo) or first in, first out (fifo), and whether stock is valued at its average cost (weighted_average) or at the cost of its lots (fifo)
+// @Tags Inventory
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param clinicId path string true "Clinic ID"
+// @Param request body dto.InventorySettingsRequest true "Inventory settings"
+// @Success 200 {object} dto.InventorySettingsResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /api/clinics/{clinicId}/inventory-settings [put]
+func (h *InventoryHandler) UpdateInventorySettings(w http.ResponseWriter, r *http.Request) {
+	var req dto.InventorySettingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	defer r.Body.Close()
+
+	userId, role := h.currentUser(r)
+	settings, err := h.service.UpdateInventorySettings(mux.Vars(r)["clinicId"], userId, role, req)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, toInventorySettingsResponse(*settings))
+}
+
 // AttachMaterial godoc
 // @Summary Attach material to clinic service
 // @Description Attaches required product material to a clinic service
@@ -296,9 +528,336 @@ func (h *InventoryHandler) GetServiceMaterials(w http.ResponseWriter, r *http.Re
 	respondJSON(w, http.StatusOK, toServiceMaterialResponseList(materials))
 }
 
+// CreateTransfer godoc
+// @Summary Transfer stock between clinic addresses
+// @Description Moves stock from one address of a clinic to another, recording transfer_out at the sending address. Stock held by booked appointments and expired lots are not sent. The transfer stays in_transit until it is received, unless receive is set.
+// @Tags Inventory
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param clinicId path string true "Clinic ID"
+// @Param request body dto.StockTransferRequest true "Stock transfer"
+// @Success 201 {object} dto.StockTransferResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/clinics/{clinicId}/stock-transfers [post]
+func (h *InventoryHandler) CreateTransfer(w http.ResponseWriter, r *http.Request) {
+	var req dto.StockTransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	defer r.Body.Close()
+
+	userId, role := h.currentUser(r)
+	transfer, err := h.service.CreateTransfer(r.Context(), mux.Vars(r)["clinicId"], userId, role, req)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusCreated, toTransferResponse(*transfer))
+}
+
+// GetTransfers godoc
+// @Summary List stock transfers
+// @Description Lists the stock transfers between the addresses of a clinic, newest first
+// @Tags Inventory
+// @Security BearerAuth
+// @Produce json
+// @Param clinicId path string true "Clinic ID"
+// @Param status query string false "in_transit, received or cancelled"
+// @Success 200 {array} dto.StockTransferResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /api/clinics/{clinicId}/stock-transfers [get]
+func (h *InventoryHandler) GetTransfers(w http.ResponseWriter, r *http.Request) {
+	userId, role := h.currentUser(r)
+	transfers, err := h.service.GetTransfers(mux.Vars(r)["clinicId"], userId, role, r.URL.Query().Get("status"))
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+
+	result := make([]dto.StockTransferResponse, 0, len(transfers))
+	for _, transfer := range transfers {
+		result = append(result, toTransferResponse(transfer))
+	}
+	respondJSON(w, http.StatusOK, result)
+}
+
+// GetTransfer godoc
+// @Summary Get stock transfer
+// @Tags Inventory
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Stock transfer ID"
+// @Success 200 {object} dto.StockTransferResponse
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/stock-transfers/{id} [get]
+func (h *InventoryHandler) GetTransfer(w http.ResponseWriter, r *http.Request) {
+	userId, role := h.currentUser(r)
+	transfer, err := h.service.GetTransfer(mux.Vars(r)["id"], userId, role)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, toTransferResponse(*transfer))
+}
+
+// ReceiveTransfer godoc
+// @Summary Receive stock transfer
+// @Description Books a transfer in transit into the stock of the receiving address, recording transfer_in
+// @Tags Inventory
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Stock transfer ID"
+// @Success 200 {object} dto.StockTransferResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/stock-transfers/{id}/receive [post]
+func (h *InventoryHandler) ReceiveTransfer(w http.ResponseWriter, r *http.Request) {
+	userId, role := h.currentUser(r)
+	transfer, err := h.service.ReceiveTransfer(r.Context(), mux.Vars(r)["id"], userId, role)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, toTransferResponse(*transfer))
+}
+
+// CancelTransfer godoc
+// @Summary Cancel stock transfer
+// @Description Returns the stock of a transfer in transit to the sending address, recording transfer_in there
+// @Tags Inventory
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Stock transfer ID"
+// @Success 200 {object} dto.StockTransferResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/stock-transfers/{id}/cancel [post]
+func (h *InventoryHandler) CancelTransfer(w http.ResponseWriter, r *http.Request) {
+	userId, role := h.currentUser(r)
+	transfer, err := h.service.CancelTransfer(r.Context(), mux.Vars(r)["id"], userId, role)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, toTransferResponse(*transfer))
+}
+
+// StartStockCount godoc
+// @Summary Start stock count
+// @Description Opens a physical count at a clinic address, freezing what is in stock there as the expected quantities. An address has one open count at a time.
+// @Tags Inventory
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param clinicId path string true "Clinic ID"
+// @Param request body dto.StockCountRequest true "Stock count"
+// @Success 201 {object} dto.StockCountResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/clinics/{clinicId}/stock-counts [post]
+func (h *InventoryHandler) StartStockCount(w http.ResponseWriter, r *http.Request) {
+	var req dto.StockCountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	defer r.Body.Close()
+
+	userId, role := h.currentUser(r)
+	count, err := h.service.StartStockCount(r.Context(), mux.Vars(r)["clinicId"], userId, role, req)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusCreated, toStockCountResponse(*count))
+}
+
+// GetStockCounts godoc
+// @Summary List stock counts
+// @Description Lists the stock counts of a clinic, newest first
+// @Tags Inventory
+// @Security BearerAuth
+// @Produce json
+// @Param clinicId path string true "Clinic ID"
+// @Param status query string false "open, posted or cancelled"
+// @Success 200 {array} dto.StockCountResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /api/clinics/{clinicId}/stock-counts [get]
+func (h *InventoryHandler) GetStockCounts(w http.ResponseWriter, r *http.Request) {
+	userId, role := h.currentUser(r)
+	counts, err := h.service.GetStockCounts(mux.Vars(r)["clinicId"], userId, role, r.URL.Query().Get("status"))
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+
+	result := make([]dto.StockCountResponse, 0, len(counts))
+	for _, count := range counts {
+		result = append(result, toStockCountResponse(count))
+	}
+	respondJSON(w, http.StatusOK, result)
+}
+
+// GetStockCount godoc
+// @Summary Get stock count
+// @Description Returns a count with the expected and counted quantity and the variance of each product
+// @Tags Inventory
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Stock count ID"
+// @Success 200 {object} dto.StockCountResponse
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/stock-counts/{id} [get]
+func (h *InventoryHandler) GetStockCount(w http.ResponseWriter, r *http.Request) {
+	userId, role := h.currentUser(r)
+	count, err := h.service.GetStockCount(mux.Vars(r)["id"], userId, role)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, toStockCountResponse(*count))
+}
+
+// RecordCounts godoc
+// @Summary Enter counted quantities
+// @Description Enters counted quantities on an open count. Products counted again replace the earlier count; products not expected at the address are added.
+// @Tags Inventory
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Stock count ID"
+// @Param request body dto.StockCountItemsRequest true "Counted quantities"
+// @Success 200 {object} dto.StockCountResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/stock-counts/{id}/items [put]
+func (h *InventoryHandler) RecordCounts(w http.ResponseWriter, r *http.Request) {
+	var req dto.StockCountItemsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	defer r.Body.Close()
+
+	userId, role := h.currentUser(r)
+	count, err := h.service.RecordCounts(r.Context(), mux.Vars(r)["id"], userId, role, req.Items)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, toStockCountResponse(*count))
+}
+
+// GetCountSheet godoc
+// @Summary Download count sheet
+// @Description Returns the products of a count as a CSV sheet to count on offline and import back. Expected quantities are left out.
+// @Tags Inventory
+// @Security BearerAuth
+// @Produce text/csv
+// @Param id path string true "Stock count ID"
+// @Success 200 {string} string "CSV count sheet"
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/stock-counts/{id}/sheet [get]
+func (h *InventoryHandler) GetCountSheet(w http.ResponseWriter, r *http.Request) {
+	userId, role := h.currentUser(r)
+	body, err := h.service.CountSheet(mux.Vars(r)["id"], userId, role)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", `attachment; filename="count-sheet.csv"`)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(body)
+}
+
+// ImportCounts godoc
+// @Summary Import counted quantities
+// @Description Enters counted quantities in bulk from a CSV count sheet sent as the request body. Columns product_id and counted_quantity are required, reason_code and note are optional; rows without a counted quantity are skipped.
+// @Tags Inventory
+// @Security BearerAuth
+// @Accept text/csv
+// @Produce json
+// @Param id path string true "Stock count ID"
+// @Param sheet body string true "CSV count sheet"
+// @Success 200 {object} dto.StockCountResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/stock-counts/{id}/import [post]
+func (h *InventoryHandler) ImportCounts(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, 5<<20)
+	defer r.Body.Close()
+
+	userId, role := h.currentUser(r)
+	count, err := h.service.ImportCounts(r.Context(), mux.Vars(r)["id"], userId, role, r.Body)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, toStockCountResponse(*count))
+}
+
+// PostStockCount godoc
+// @Summary Post stock count
+// @Description Books the variances of an open count into stock as stock_count transactions. Every counted product that differs from the expected quantity needs a reason_code: damaged, expired, lost, theft, found, entry_error or other. Products left uncounted are not changed.
+// @Tags Inventory
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Stock count ID"
+// @Success 200 {object} dto.StockCountResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/stock-counts/{id}/post [post]
+func (h *InventoryHandler) PostStockCount(w http.ResponseWriter, r *http.Request) {
+	userId, role := h.currentUser(r)
+	count, err := h.service.PostStockCount(r.Context(), mux.Vars(r)["id"], userId, role)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, toStockCountResponse(*count))
+}
+
+// CancelStockCount godoc
+// @Summary Cancel stock count
+// @Description Drops an open count without changing the stock
+// @Tags Inventory
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Stock count ID"
+// @Success 200 {object} dto.StockCountResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/stock-counts/{id}/cancel [post]
+func (h *InventoryHandler) CancelStockCount(w http.ResponseWriter, r *http.Request) {
+	userId, role := h.currentUser(r)
+	count, err := h.service.CancelStockCount(r.Context(), mux.Vars(r)["id"], userId, role)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, toStockCountResponse(*count))
+}
+
 // GetTransactions godoc
 // @Summary Get inventory transactions
-// @Description Returns inventory transactions for a clinic address. Optional transaction_type values: restocked, used, manual_adjustment.
+// @Description Returns inventory transactions for a clinic address. Optional transaction_type values: restocked, used, manual_adjustment, transfer_out, transfer_in, stock_count.
 // @Tags Inventory
 // @Security BearerAuth
 // @Produce json
@@ -316,6 +875,26 @@ func (h *InventoryHandler) GetTransactions(w http.ResponseWriter, r *http.Reques
 	respondJSON(w, http.StatusOK, toTransactionResponseList(transactions))
 }
 
+func (h *InventoryHandler) currentUser(r *http.Request) (string, string) {
+	claims, err := utils.GetClaims(utils.GetToken(r), h.cfg.JWTSecret)
+	if err != nil {
+		return "", ""
+	}
+	userId, _ := claims["user_id"].(string)
+	role, _ := claims["role"].(string)
+	return userId, role
+}
+
+func errorStatus(err error) int {
+	if err.Error() == "do not have rights" {
+		return http.StatusForbidden
+	}
+	if strings.HasSuffix(err.Error(), "not found") {
+		return http.StatusNotFound
+	}
+	return http.StatusBadRequest
+}
+
 func respondJSON(w http.ResponseWriter, status int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -351,6 +930,10 @@ func toInventoryResponse(item models.AddressInventory) dto.InventoryResponse {
 		ProductName:     item.ProductName,
 		ProductUnit:     item.ProductUnit,
 		Quantity:        item.Quantity,
+		Reserved:        item.Reserved,
+		Available:       item.Quantity - item.Reserved,
+		MinQuantity:     item.MinQuantity,
+		ReorderLevel:    item.ReorderLevel,
 		UpdatedAt:       item.UpdatedAt.Format("2006-01-02 15:04:05"),
 	}
 }
@@ -363,13 +946,26 @@ func toInventoryResponseList(inventory []models.AddressInventory) []dto.Inventor
 	return result
 }
 
+// toInventoryStatusResponse rates the stock available after reservations
+// against the item's levels. Items without levels fall back to threshold as
+// their reorder level.
 func toInventoryStatusResponse(item models.AddressInventory, threshold float64) dto.InventoryStatusResponse {
+	reorderLevel := item.ReorderLevel
+	if item.MinQuantity == 0 && item.ReorderLevel == 0 {
+		reorderLevel = threshold
+	}
+	status := services.StockStatus(item.Quantity-item.Reserved, item.MinQuantity, reorderLevel)
+
 	color := "green"
 	message := "product is available"
-	if item.Quantity <= 0 {
+	switch status {
+	case services.StockOutOfStock:
 		color = "red"
 		message = "product is finished"
-	} else if item.Quantity <= threshold {
+	case services.StockLow:
+		color = "yellow"
+		message = "product is below the minimum level"
+	case services.StockReorder:
 		color = "yellow"
 		message = "product is running low"
 	}
@@ -382,6 +978,11 @@ func toInventoryStatusResponse(item models.AddressInventory, threshold float64)
 		ProductName:     item.ProductName,
 		ProductUnit:     item.ProductUnit,
 		Quantity:        item.Quantity,
+		Reserved:        item.Reserved,
+		Available:       item.Quantity - item.Reserved,
+		MinQuantity:     item.MinQuantity,
+		ReorderLevel:    item.ReorderLevel,
+		Status:          status,
 		Color:           color,
 		Message:         message,
 	}
@@ -422,11 +1023,29 @@ func toTransactionResponse(transaction models.InventoryTransaction) dto.Inventor
 		ProductName:     transaction.ProductName,
 		Quantity:        transaction.Quantity,
 		TransactionType: transaction.TransactionType,
+		ReasonCode:      transaction.ReasonCode,
 		CreatedAt:       transaction.CreatedAt.Format("2006-01-02 15:04:05"),
 	}
 	if transaction.AppointmentId.String() != "00000000-0000-0000-0000-000000000000" {
 		response.AppointmentId = transaction.AppointmentId.String()
 	}
+	if transaction.LotId != nil {
+		response.LotId = transaction.LotId.String()
+		response.LotNumber = transaction.LotNumber
+	}
+	if transaction.ExpiresAt != nil {
+		response.ExpiresAt = transaction.ExpiresAt.Format("2006-01-02")
+	}
+	response.UnitCost = transaction.UnitCost
+	if transaction.GoodsReceiptId != nil {
+		response.GoodsReceiptId = transaction.GoodsReceiptId.String()
+	}
+	if transaction.StockTransferId != nil {
+		response.StockTransferId = transaction.StockTransferId.String()
+	}
+	if transaction.StockCountId != nil {
+		response.StockCountId = transaction.StockCountId.String()
+	}
 	return response
 }
 
@@ -437,3 +1056,116 @@ func toTransactionResponseList(transactions []models.InventoryTransaction) []dto
 	}
 	return result
 }
+
+func toAlertPreferenceResponse(preference models.AlertPreference) dto.AlertPreferenceResponse {
+	response := dto.AlertPreferenceResponse{
+		ClinicId: preference.ClinicId.String(),
+		Mode:     preference.Mode,
+	}
+	if preference.UpdatedAt != nil {
+		response.UpdatedAt = preference.UpdatedAt.Format("2006-01-02 15:04:05")
+	}
+	return response
+}
+
+func toInventorySettingsResponse(settings models.InventorySettings) dto.InventorySettingsResponse {
+	response := dto.InventorySettingsResponse{
+		ClinicId:         settings.ClinicId.String(),
+		ShortagePolicy:   settings.ShortagePolicy,
+		ConsumptionOrder: settings.ConsumptionOrder,
+		ValuationMethod:  settings.ValuationMethod,
+	}
+	if settings.UpdatedAt != nil {
+		response.UpdatedAt = settings.UpdatedAt.Format("2006-01-02 15:04:05")
+	}
+	return response
+}
+
+func toLotResponse(lot models.Lot) dto.LotResponse {
+	response := dto.LotResponse{
+		Id:              lot.Id.String(),
+		ClinicAddressId: lot.ClinicAddressId.String(),
+		ProductId:       lot.ProductId.String(),
+		ProductName:     lot.ProductName,
+		ProductUnit:     lot.ProductUnit,
+		LotNumber:       lot.LotNumber,
+		Quantity:        lot.Quantity,
+		UnitCost:        lot.UnitCost,
+		ReceivedAt:      lot.ReceivedAt.Format("2006-01-02 15:04:05"),
+	}
+	if lot.ExpiresAt != nil {
+		response.ExpiresAt = lot.ExpiresAt.Format("2006-01-02")
+	}
+	return response
+}
+
+func toTransferResponse(transfer models.StockTransfer) dto.StockTransferResponse {
+	items := make([]dto.StockTransferItemResponse, 0, len(transfer.Items))
+	for _, item := range transfer.Items {
+		response := dto.StockTransferItemResponse{
+			Id:          item.Id.String(),
+			ProductId:   item.ProductId.String(),
+			ProductName: item.ProductName,
+			ProductUnit: item.ProductUnit,
+			LotNumber:   item.LotNumber,
+			UnitCost:    item.UnitCost,
+			Quantity:    item.Quantity,
+		}
+		if item.ExpiresAt != nil {
+			response.ExpiresAt = item.ExpiresAt.Format("2006-01-02")
+		}
+		items = append(items, response)
+	}
+
+	response := dto.StockTransferResponse{
+		Id:                  transfer.Id.String(),
+		ClinicId:            transfer.ClinicId.String(),
+		FromClinicAddressId: transfer.FromAddressId.String(),
+		ToClinicAddressId:   transfer.ToAddressId.String(),
+		Status:              transfer.Status,
+		Notes:               transfer.Notes,
+		CreatedAt:           transfer.CreatedAt.Format("2006-01-02 15:04:05"),
+		Items:               items,
+	}
+	if transfer.ReceivedAt != nil {
+		response.ReceivedAt = transfer.ReceivedAt.Format("2006-01-02 15:04:05")
+	}
+	return response
+}
+
+func toStockCountResponse(count models.StockCount) dto.StockCountResponse {
+	response := dto.StockCountResponse{
+		Id:              count.Id.String(),
+		ClinicId:        count.ClinicId.String(),
+		ClinicAddressId: count.ClinicAddressId.String(),
+		Status:          count.Status,
+		Notes:           count.Notes,
+		CreatedAt:       count.CreatedAt.Format("2006-01-02 15:04:05"),
+		Items:           make([]dto.StockCountItemResponse, 0, len(count.Items)),
+	}
+	if count.PostedAt != nil {
+		response.PostedAt = count.PostedAt.Format("2006-01-02 15:04:05")
+	}
+
+	for _, item := range count.Items {
+		itemResponse := dto.StockCountItemResponse{
+			Id:               item.Id.String(),
+			ProductId:        item.ProductId.String(),
+			ProductName:      item.ProductName,
+			ProductUnit:      item.ProductUnit,
+			ExpectedQuantity: item.ExpectedQuantity,
+			CountedQuantity:  item.CountedQuantity,
+			ReasonCode:       item.ReasonCode,
+			Note:             item.Note,
+		}
+		if variance, counted := item.Variance(); counted {
+			itemResponse.Variance = &variance
+			response.CountedItems++
+			if variance != 0 {
+				response.VarianceItems++
+			}
+		}
+		response.Items = append(response.Items, itemResponse)
+	}
+	return response
+}
@@ -21,9 +21,155 @@ type AddressInventory struct {
 	ProductName     string
 	ProductUnit     string
 	Quantity        float64
+	Reserved        float64 // held for booked appointments
+	MinQuantity     float64
+	ReorderLevel    float64
 	UpdatedAt       time.Time
 }
 
+// AlertPreference is how a clinic admin hears about low stock: "immediate",
+// "daily_digest" or "off".
+type AlertPreference struct {
+	ClinicId  uuid.UUID
+	UserId    uuid.UUID
+	Mode      string
+	UpdatedAt *time.Time
+}
+
+// Reservation holds materials of a service for a booked appointment. It is
+// "reserved" until the appointment is completed ("consumed") or cancelled
+// ("released").
+type Reservation struct {
+	Id              uuid.UUID
+	AppointmentId   uuid.UUID
+	ClinicAddressId uuid.UUID
+	ProductId       uuid.UUID
+	Quantity        float64
+	Status          string
+}
+
+// MaterialShortage is a material a booking needs more of than is available.
+type MaterialShortage struct {
+	ProductId   uuid.UUID
+	ProductName string
+	ProductUnit string
+	Required    float64
+	Available   float64
+}
+
+// InventorySettings holds what happens to bookings whose materials are not
+// available (ShortagePolicy: "warn" books them anyway, "block" refuses them)
+// the order lots are used in (ConsumptionOrder: "fefo" takes the lot that
+// expires first, "fifo" the one received first) and how stock on hand is
+// valued (ValuationMethod: "weighted_average" or "fifo").
+type InventorySettings struct {
+	ClinicId         uuid.UUID
+	ShortagePolicy   string
+	ConsumptionOrder string
+	ValuationMethod  string
+	UpdatedAt        *time.Time
+}
+
+// Lot is the stock of a product at an address sharing a lot number and
+// expiry date. Stock received without a lot number has an empty LotNumber.
+type Lot struct {
+	Id              uuid.UUID
+	ClinicAddressId uuid.UUID
+	ProductId       uuid.UUID
+	ProductName     string
+	ProductUnit     string
+	LotNumber       string
+	ExpiresAt       *time.Time
+	Quantity        float64
+	UnitCost        *float64 // averaged over the deliveries of the lot
+	ReceivedAt      time.Time
+}
+
+// StockReceipt is stock delivered to an address, from a goods receipt of a
+// purchase order when GoodsReceiptId is set, or from another address of the
+// clinic when StockTransferId is.
+type StockReceipt struct {
+	ClinicAddressId uuid.UUID
+	ProductId       uuid.UUID
+	Quantity        float64
+	LotNumber       string
+	ExpiresAt       *time.Time
+	UnitCost        *float64
+	GoodsReceiptId  *uuid.UUID
+	StockTransferId *uuid.UUID
+}
+
+// StockTransfer moves stock between two addresses of a clinic. It is
+// "in_transit" from when the stock leaves the sending address until it is
+// "received" at the other, or "cancelled" and returned.
+type StockTransfer struct {
+	Id            uuid.UUID
+	ClinicId      uuid.UUID
+	FromAddressId uuid.UUID
+	ToAddressId   uuid.UUID
+	Status        string
+	Notes         string
+	CreatedBy     *uuid.UUID
+	ReceivedBy    *uuid.UUID
+	CreatedAt     time.Time
+	ReceivedAt    *time.Time
+	Items         []StockTransferItem
+}
+
+// StockTransferItem is what was taken of a product from one lot at the
+// sending address.
+type StockTransferItem struct {
+	Id              uuid.UUID
+	StockTransferId uuid.UUID
+	ProductId       uuid.UUID
+	ProductName     string
+	ProductUnit     string
+	LotNumber       string
+	ExpiresAt       *time.Time
+	UnitCost        *float64
+	Quantity        float64
+}
+
+// StockCount is a physical count of the stock at an address. The expected
+// quantities are frozen when it is "open"ed; it is "posted" once the
+// variances are booked, or "cancelled".
+type StockCount struct {
+	Id              uuid.UUID
+	ClinicId        uuid.UUID
+	ClinicAddressId uuid.UUID
+	Status          string
+	Notes           string
+	CreatedBy       *uuid.UUID
+	PostedBy        *uuid.UUID
+	CreatedAt       time.Time
+	PostedAt        *time.Time
+	Items           []StockCountItem
+}
+
+// StockCountItem is a product on a count. CountedQuantity is nil until it
+// has been counted.
+type StockCountItem struct {
+	Id               uuid.UUID
+	StockCountId     uuid.UUID
+	ProductId        uuid.UUID
+	ProductName      string
+	ProductUnit      string
+	ExpectedQuantity float64
+	CountedQuantity  *float64
+	ReasonCode       string
+	Note             string
+	CountedAt        *time.Time
+}
+
+// Variance is how much more was counted than expected, and false when the
+// product has not been counted.
+func (i StockCountItem) Variance() (float64, bool) {
+	if i.CountedQuantity == nil {
+		return 0, false
+	}
+	return *i.CountedQuantity - i.ExpectedQuantity, true
+}
+
 type InventoryTransaction struct {
 	Id              uuid.UUID
 	ClinicAddressId uuid.UUID
@@ -32,6 +178,14 @@ type InventoryTransaction struct {
 	Quantity        float64
 	TransactionType string
 	AppointmentId   uuid.UUID
+	LotId           *uuid.UUID
+	LotNumber       string
+	ExpiresAt       *time.Time
+	UnitCost        *float64
+	GoodsReceiptId  *uuid.UUID
+	StockTransferId *uuid.UUID
+	ReasonCode      string
+	StockCountId    *uuid.UUID
 	CreatedAt       time.Time
 }
 
@@ -24,13 +24,65 @@ type InventoryRepository interface {
 	GetInventoryByAddressAndProduct(clinicAddressId, productId uuid.UUID, tx pgx.Tx) (*models.AddressInventory, error)
 	CreateInventoryTx(inventory *models.AddressInventory, tx pgx.Tx) (*models.AddressInventory, error)
 	UpdateInventoryQuantityTx(id uuid.UUID, quantity float64, tx pgx.Tx) (*models.AddressInventory, error)
+	SaveInventoryLevels(item *models.AddressInventory) (uuid.UUID, error)
+	LowerAlertLevelTx(id uuid.UUID, severity int, tx pgx.Tx) error
 	CreateTransactionTx(transaction *models.InventoryTransaction, tx pgx.Tx) error
 	GetTransactions(clinicAddressId uuid.UUID, transactionType string) ([]models.InventoryTransaction, error)
 
+	LockStockTx(clinicAddressId, productId uuid.UUID, tx pgx.Tx) (*models.AddressInventory, error)
+	ReserveTx(reservation *models.Reservation, tx pgx.Tx) error
+	ReleaseReservationsTx(appointmentId uuid.UUID, tx pgx.Tx) error
+
+	AddToLotTx(lot *models.Lot, tx pgx.Tx) (*models.Lot, error)
+	GetLotByID(id uuid.UUID) (*models.Lot, error)
+	GetLotsTx(clinicAddressId, productId uuid.UUID, consumptionOrder string, tx pgx.Tx) ([]models.Lot, error)
+	SetLotQuantityTx(id uuid.UUID, quantity float64, tx pgx.Tx) error
+	GetLots(clinicAddressId, productId uuid.UUID) ([]models.Lot, error)
+	GetExpiringLots(clinicId, clinicAddressId uuid.UUID, before time.Time) ([]models.Lot, error)
+	GetConsumptionOrderTx(clinicAddressId uuid.UUID, tx pgx.Tx) (string, error)
+
+	GetAddressClinic(clinicAddressId uuid.UUID) (uuid.UUID, error)
+	CreateTransferTx(transfer *models.StockTransfer, tx pgx.Tx) error
+	CreateTransferItemTx(item *models.StockTransferItem, tx pgx.Tx) error
+	GetTransferByID(id uuid.UUID) (*models.StockTransfer, error)
+	GetTransferForUpdateTx(id uuid.UUID, tx pgx.Tx) (*models.StockTransfer, error)
+	GetTransfers(clinicId uuid.UUID, status string) ([]models.StockTransfer, error)
+	SetTransferStatusTx(id uuid.UUID, status string, receivedBy *uuid.UUID, tx pgx.Tx) error
+
+	GetOpenStockCountID(clinicAddressId uuid.UUID) (uuid.UUID, error)
+	CreateStockCountTx(count *models.StockCount, tx pgx.Tx) error
+	GetStockCountByID(id uuid.UUID) (*models.StockCount, error)
+	GetStockCountForUpdateTx(id uuid.UUID, tx pgx.Tx) (*models.StockCount, error)
+	GetStockCounts(clinicId uuid.UUID, status string) ([]models.StockCount, error)
+	SaveStockCountItemTx(item *models.StockCountItem, tx pgx.Tx) error
+	SetStockCountStatusTx(id uuid.UUID, status string, postedBy *uuid.UUID, tx pgx.Tx) error
+
+	IsClinicAdmin(clinicId, userId uuid.UUID) (bool, error)
+	GetAlertPreference(clinicId, userId uuid.UUID) (*models.AlertPreference, error)
+	SaveAlertPreference(preference *models.AlertPreference) error
+	GetInventorySettings(clinicId uuid.UUID) (*models.InventorySettings, error)
+	GetInventorySettingsTx(clinicId uuid.UUID, tx pgx.Tx) (*models.InventorySettings, error)
+	SaveInventorySettings(settings *models.InventorySettings) error
+
 	CreateServiceMaterial(material *models.ServiceMaterial) (*models.ServiceMaterial, error)
 	GetServiceMaterials(clinicServiceId uuid.UUID) ([]models.ServiceMaterial, error)
+	GetServiceMaterialsTx(clinicServiceId uuid.UUID, tx pgx.Tx) ([]models.ServiceMaterial, error)
 }
 
+// reservedQuantity sums what booked appointments hold of an address_inventory
+// row aliased ai.
+const reservedQuantity = `
+	COALESCE((
+		SELECT SUM(r.quantity)
+		FROM inventory_reservations r
+		JOIN appointments a ON a.id = r.appointment_id
+		WHERE r.clinic_address_id = ai.clinic_address_id
+			AND r.product_id = ai.product_id
+			AND r.status = 'reserved'
+			AND a.status = 'booked'
+	), 0)
+`
+
 type inventoryRepo struct {
 	db *pgxpool.Pool
 }
@@ -104,7 +156,7 @@ func (r *inventoryRepo) DeleteProduct(id uuid.UUID) error {
 
 func (r *inventoryRepo) GetInventoryByAddress(clinicAddressId uuid.UUID) ([]models.AddressInventory, error) {
 	query := `
-		SELECT ai.id, ai.clinic_address_id, ai.product_id, p.name, p.unit, ai.quantity, ai.updated_at
+		SELECT ai.id, ai.clinic_address_id, ai.product_id, p.name, p.unit, ai.quantity, ` + reservedQuantity + `, ai.min_quantity, ai.reorder_level, ai.updated_at
 		FROM address_inventory ai
 		JOIN products p ON p.id = ai.product_id
 		WHERE ai.clinic_address_id = $1
@@ -119,7 +171,7 @@ func (r *inventoryRepo) GetInventoryByAddress(clinicAddressId uuid.UUID) ([]mode
 	inventory := make([]models.AddressInventory, 0)
 	for rows.Next() {
 		var item models.AddressInventory
-		if err := rows.Scan(&item.Id, &item.ClinicAddressId, &item.ProductId, &item.ProductName, &item.ProductUnit, &item.Quantity, &item.UpdatedAt); err != nil {
+		if err := rows.Scan(&item.Id, &item.ClinicAddressId, &item.ProductId, &item.ProductName, &item.ProductUnit, &item.Quantity, &item.Reserved, &item.MinQuantity, &item.ReorderLevel, &item.UpdatedAt); err != nil {
 			return nil, err
 		}
 		inventory = append(inventory, item)
@@ -129,7 +181,7 @@ func (r *inventoryRepo) GetInventoryByAddress(clinicAddressId uuid.UUID) ([]mode
 
 func (r *inventoryRepo) GetInventoryStatus(clinicId, clinicAddressId uuid.UUID) ([]models.AddressInventory, error) {
 	query := `
-		SELECT ai.id, ca.clinic_id, ai.clinic_address_id, ai.product_id, p.name, p.unit, ai.quantity, ai.updated_at
+		SELECT ai.id, ca.clinic_id, ai.clinic_address_id, ai.product_id, p.name, p.unit, ai.quantity, ` + reservedQuantity + `, ai.min_quantity, ai.reorder_level, ai.updated_at
 		FROM address_inventory ai
 		JOIN clinic_addresses ca ON ca.id = ai.clinic_address_id
 		JOIN products p ON p.id = ai.product_id
@@ -145,7 +197,7 @@ func (r *inventoryRepo) GetInventoryStatus(clinicId, clinicAddressId uuid.UUID)
 	inventory := make([]models.AddressInventory, 0)
 	for rows.Next() {
 		var item models.AddressInventory
-		if err := rows.Scan(&item.Id, &item.ClinicId, &item.ClinicAddressId, &item.ProductId, &item.ProductName, &item.ProductUnit, &item.Quantity, &item.UpdatedAt); err != nil {
+		if err := rows.Scan(&item.Id, &item.ClinicId, &item.ClinicAddressId, &item.ProductId, &item.ProductName, &item.ProductUnit, &item.Quantity, &item.Reserved, &item.MinQuantity, &item.ReorderLevel, &item.UpdatedAt); err != nil {
 			return nil, err
 		}
 		inventory = append(inventory, item)
@@ -155,13 +207,13 @@ func (r *inventoryRepo) GetInventoryStatus(clinicId, clinicAddressId uuid.UUID)
 
 func (r *inventoryRepo) GetInventoryByID(id uuid.UUID) (*models.AddressInventory, error) {
 	query := `
-		SELECT ai.id, ai.clinic_address_id, ai.product_id, p.name, p.unit, ai.quantity, ai.updated_at
+		SELECT ai.id, ai.clinic_address_id, ai.product_id, p.name, p.unit, ai.quantity, ` + reservedQuantity + `, ai.min_quantity, ai.reorder_level, ai.updated_at
 		FROM address_inventory ai
 		JOIN products p ON p.id = ai.product_id
 		WHERE ai.id = $1
 	`
 	item := &models.AddressInventory{}
-	err := r.db.QueryRow(context.Background(), query, id).Scan(&item.Id, &item.ClinicAddressId, &item.ProductId, &item.ProductName, &item.ProductUnit, &item.Quantity, &item.UpdatedAt)
+	err := r.db.QueryRow(context.Background(), query, id).Scan(&item.Id, &item.ClinicAddressId, &item.ProductId, &item.ProductName, &item.ProductUnit, &item.Quantity, &item.Reserved, &item.MinQuantity, &item.ReorderLevel, &item.UpdatedAt)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, nil
@@ -173,13 +225,13 @@ func (r *inventoryRepo) GetInventoryByID(id uuid.UUID) (*models.AddressInventory
 
 func (r *inventoryRepo) GetInventoryByAddressAndProduct(clinicAddressId, productId uuid.UUID, tx pgx.Tx) (*models.AddressInventory, error) {
 	query := `
-		SELECT id, clinic_address_id, product_id, quantity, updated_at
+		SELECT id, clinic_address_id, product_id, quantity, min_quantity, reorder_level, updated_at
 		FROM address_inventory
 		WHERE clinic_address_id = $1 AND product_id = $2
 		FOR UPDATE
 	`
 	item := &models.AddressInventory{}
-	err := tx.QueryRow(context.Background(), query, clinicAddressId, productId).Scan(&item.Id, &item.ClinicAddressId, &item.ProductId, &item.Quantity, &item.UpdatedAt)
+	err := tx.QueryRow(context.Background(), query, clinicAddressId, productId).Scan(&item.Id, &item.ClinicAddressId, &item.ProductId, &item.Quantity, &item.MinQuantity, &item.ReorderLevel, &item.UpdatedAt)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, nil
@@ -205,11 +257,11 @@ func (r *inventoryRepo) UpdateInventoryQuantityTx(id uuid.UUID, quantity float64
 		UPDATE address_inventory
 		SET quantity = $2, updated_at = $3
 		WHERE id = $1
-		RETURNING id, clinic_address_id, product_id, quantity, updated_at
+		RETURNING id, clinic_address_id, product_id, quantity, min_quantity, reorder_level, updated_at
 	`
 	item := &models.AddressInventory{}
 	err := tx.QueryRow(context.Background(), query, id, quantity, time.Now()).
-		Scan(&item.Id, &item.ClinicAddressId, &item.ProductId, &item.Quantity, &item.UpdatedAt)
+		Scan(&item.Id, &item.ClinicAddressId, &item.ProductId, &item.Quantity, &item.MinQuantity, &item.ReorderLevel, &item.UpdatedAt)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, nil
@@ -219,24 +271,260 @@ func (r *inventoryRepo) UpdateInventoryQuantityTx(id uuid.UUID, quantity float64
 	return item, nil
 }
 
+// SaveInventoryLevels sets the minimum and reorder levels of a product at an
+// address, adding the product with no stock when the address has none yet.
+// New levels are alerted on afresh.
+func (r *inventoryRepo) SaveInventoryLevels(item *models.AddressInventory) (uuid.UUID, error) {
+	query := `
+		INSERT INTO address_inventory (id, clinic_address_id, product_id, quantity, min_quantity, reorder_level, updated_at)
+		VALUES ($1, $2, $3, 0, $4, $5, NOW())
+		ON CONFLICT (clinic_address_id, product_id) DO UPDATE
+		SET min_quantity = EXCLUDED.min_quantity,
+			reorder_level = EXCLUDED.reorder_level,
+			alerted_level = 0,
+			updated_at = EXCLUDED.updated_at
+		RETURNING id
+	`
+	var id uuid.UUID
+	err := r.db.QueryRow(context.Background(), query, item.Id, item.ClinicAddressId, item.ProductId, item.MinQuantity, item.ReorderLevel).Scan(&id)
+	return id, err
+}
+
+// LowerAlertLevelTx lets an item that recovered alert again the next time it
+// runs low.
+func (r *inventoryRepo) LowerAlertLevelTx(id uuid.UUID, severity int, tx pgx.Tx) error {
+	_, err := tx.Exec(context.Background(), `UPDATE address_inventory SET alerted_level = LEAST(alerted_level, $2) WHERE id = $1`, id, severity)
+	return err
+}
+
+// LockStockTx locks the stock of a product at an address, adding it with no
+// stock when the address never had it, so bookings reserve it one at a time.
+func (r *inventoryRepo) LockStockTx(clinicAddressId, productId uuid.UUID, tx pgx.Tx) (*models.AddressInventory, error) {
+	insertQuery := `
+		INSERT INTO address_inventory (id, clinic_address_id, product_id, quantity, updated_at)
+		VALUES ($1, $2, $3, 0, NOW())
+		ON CONFLICT (clinic_address_id, product_id) DO NOTHING
+	`
+	if _, err := tx.Exec(context.Background(), insertQuery, uuid.New(), clinicAddressId, productId); err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT ai.id, ai.clinic_address_id, ai.product_id, p.name, p.unit, ai.quantity, ` + reservedQuantity + `, ai.min_quantity, ai.reorder_level, ai.updated_at
+		FROM address_inventory ai
+		JOIN products p ON p.id = ai.product_id
+		WHERE ai.clinic_address_id = $1 AND ai.product_id = $2
+		FOR UPDATE OF ai
+	`
+	item := &models.AddressInventory{}
+	err := tx.QueryRow(context.Background(), query, clinicAddressId, productId).
+		Scan(&item.Id, &item.ClinicAddressId, &item.ProductId, &item.ProductName, &item.ProductUnit, &item.Quantity, &item.Reserved, &item.MinQuantity, &item.ReorderLevel, &item.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+func (r *inventoryRepo) ReserveTx(reservation *models.Reservation, tx pgx.Tx) error {
+	query := `
+		INSERT INTO inventory_reservations (id, appointment_id, clinic_address_id, product_id, quantity, status, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, 'reserved', NOW(), NOW())
+		ON CONFLICT (appointment_id, product_id) DO UPDATE
+		SET clinic_address_id = EXCLUDED.clinic_address_id,
+			quantity = EXCLUDED.quantity,
+			status = 'reserved',
+			updated_at = EXCLUDED.updated_at
+	`
+	_, err := tx.Exec(context.Background(), query, reservation.Id, reservation.AppointmentId, reservation.ClinicAddressId, reservation.ProductId, reservation.Quantity)
+	return err
+}
+
+func (r *inventoryRepo) ReleaseReservationsTx(appointmentId uuid.UUID, tx pgx.Tx) error {
+	query := `
+		UPDATE inventory_reservations
+		SET status = 'released', updated_at = NOW()
+		WHERE appointment_id = $1 AND status = 'reserved'
+	`
+	_, err := tx.Exec(context.Background(), query, appointmentId)
+	return err
+}
+
+// LotOrder is the ORDER BY that puts the lot to use next first. Lots are
+// aliased l.
+func LotOrder(consumptionOrder string) string {
+	if consumptionOrder == "fifo" {
+		return "l.received_at, l.expires_at NULLS LAST, l.lot_number"
+	}
+	return "l.expires_at NULLS LAST, l.received_at, l.lot_number"
+}
+
+const lotSelect = `
+	SELECT l.id, l.clinic_address_id, l.product_id, p.name, p.unit, l.lot_number, l.expires_at, l.quantity, l.unit_cost::float8, l.received_at
+	FROM inventory_lots l
+	JOIN products p ON p.id = l.product_id
+`
+
+func scanLots(rows pgx.Rows) ([]models.Lot, error) {
+	defer rows.Close()
+
+	lots := make([]models.Lot, 0)
+	for rows.Next() {
+		var lot models.Lot
+		if err := rows.Scan(&lot.Id, &lot.ClinicAddressId, &lot.ProductId, &lot.ProductName, &lot.ProductUnit, &lot.LotNumber, &lot.ExpiresAt, &lot.Quantity, &lot.UnitCost, &lot.ReceivedAt); err != nil {
+			return nil, err
+		}
+		lots = append(lots, lot)
+	}
+	return lots, rows.Err()
+}
+
+// AddToLotTx adds stock to a lot, creating it on its first delivery. The
+// lot's unit cost becomes the average over what is in stock. A lot delivered
+// again with another expiry date is not added to and nil is returned.
+func (r *inventoryRepo) AddToLotTx(lot *models.Lot, tx pgx.Tx) (*models.Lot, error) {
+	query := `
+		INSERT INTO inventory_lots (id, clinic_address_id, product_id, lot_number, expires_at, quantity, unit_cost, received_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+		ON CONFLICT (clinic_address_id, product_id, lot_number) DO UPDATE
+		SET quantity = inventory_lots.quantity + EXCLUDED.quantity,
+			expires_at = COALESCE(inventory_lots.expires_at, EXCLUDED.expires_at),
+			unit_cost = CASE
+				WHEN EXCLUDED.unit_cost IS NULL THEN inventory_lots.unit_cost
+				WHEN inventory_lots.unit_cost IS NULL OR inventory_lots.quantity = 0 THEN EXCLUDED.unit_cost
+				ELSE (inventory_lots.quantity * inventory_lots.unit_cost + EXCLUDED.quantity * EXCLUDED.unit_cost)
+					/ (inventory_lots.quantity + EXCLUDED.quantity)
+			END
+		WHERE inventory_lots.expires_at IS NULL
+			OR EXCLUDED.expires_at IS NULL
+			OR inventory_lots.expires_at = EXCLUDED.expires_at
+		RETURNING id, expires_at, quantity, unit_cost::float8, received_at
+	`
+	err := tx.QueryRow(context.Background(), query, lot.Id, lot.ClinicAddressId, lot.ProductId, lot.LotNumber, lot.ExpiresAt, lot.Quantity, lot.UnitCost).
+		Scan(&lot.Id, &lot.ExpiresAt, &lot.Quantity, &lot.UnitCost, &lot.ReceivedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return lot, nil
+}
+
+func (r *inventoryRepo) GetLotByID(id uuid.UUID) (*models.Lot, error) {
+	rows, err := r.db.Query(context.Background(), lotSelect+`WHERE l.id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+	lots, err := scanLots(rows)
+	if err != nil || len(lots) == 0 {
+		return nil, err
+	}
+	return &lots[0], nil
+}
+
+// GetLotsTx locks the lots of a product at an address that still have stock,
+// the one to use next first.
+func (r *inventoryRepo) GetLotsTx(clinicAddressId, productId uuid.UUID, consumptionOrder string, tx pgx.Tx) ([]models.Lot, error) {
+	query := lotSelect + `
+		WHERE l.clinic_address_id = $1
+			AND l.product_id = $2
+			AND l.quantity > 0
+		ORDER BY ` + LotOrder(consumptionOrder) + `
+		FOR UPDATE OF l
+	`
+	rows, err := tx.Query(context.Background(), query, clinicAddressId, productId)
+	if err != nil {
+		return nil, err
+	}
+	return scanLots(rows)
+}
+
+func (r *inventoryRepo) SetLotQuantityTx(id uuid.UUID, quantity float64, tx pgx.Tx) error {
+	result, err := tx.Exec(context.Background(), `UPDATE inventory_lots SET quantity = $2 WHERE id = $1`, id, quantity)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// GetLots lists the lots in stock at an address, of one product when
+// productId is set.
+func (r *inventoryRepo) GetLots(clinicAddressId, productId uuid.UUID) ([]models.Lot, error) {
+	query := lotSelect + `
+		WHERE l.clinic_address_id = $1
+			AND ($2 = '00000000-0000-0000-0000-000000000000'::uuid OR l.product_id = $2)
+			AND l.quantity > 0
+		ORDER BY p.name, ` + LotOrder("fefo")
+	rows, err := r.db.Query(context.Background(), query, clinicAddressId, productId)
+	if err != nil {
+		return nil, err
+	}
+	return scanLots(rows)
+}
+
+// GetExpiringLots lists the lots in stock at the clinic that expire before
+// the given day, already expired ones included, at one address when
+// clinicAddressId is set.
+func (r *inventoryRepo) GetExpiringLots(clinicId, clinicAddressId uuid.UUID, before time.Time) ([]models.Lot, error) {
+	query := lotSelect + `
+		JOIN clinic_addresses ca ON ca.id = l.clinic_address_id
+		WHERE ca.clinic_id = $1
+			AND ($2 = '00000000-0000-0000-0000-000000000000'::uuid OR l.clinic_address_id = $2)
+			AND l.quantity > 0
+			AND l.expires_at < $3::date
+		ORDER BY l.expires_at, p.name, l.lot_number
+	`
+	rows, err := r.db.Query(context.Background(), query, clinicId, clinicAddressId, before)
+	if err != nil {
+		return nil, err
+	}
+	return scanLots(rows)
+}
+
+// GetConsumptionOrderTx returns the order lots are used in at the clinic of
+// the address, "fefo" until it is set.
+func (r *inventoryRepo) GetConsumptionOrderTx(clinicAddressId uuid.UUID, tx pgx.Tx) (string, error) {
+	query := `
+		SELECT COALESCE(s.consumption_order, 'fefo')
+		FROM clinic_addresses ca
+		LEFT JOIN clinic_inventory_settings s ON s.clinic_id = ca.clinic_id
+		WHERE ca.id = $1
+	`
+	var order string
+	err := tx.QueryRow(context.Background(), query, clinicAddressId).Scan(&order)
+	if err == pgx.ErrNoRows {
+		return "fefo", nil
+	}
+	return order, err
+}
+
 func (r *inventoryRepo) CreateTransactionTx(transaction *models.InventoryTransaction, tx pgx.Tx) error {
 	query := `
-		INSERT INTO inventory_transactions (id, clinic_address_id, product_id, quantity, transaction_type, appointment_id, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO inventory_transactions (id, clinic_address_id, product_id, quantity, transaction_type, appointment_id, lot_id, unit_cost, goods_receipt_id, stock_transfer_id, reason_code, stock_count_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 	`
 	var appointmentId interface{}
 	if transaction.AppointmentId != uuid.Nil {
 		appointmentId = transaction.AppointmentId
 	}
-	_, err := tx.Exec(context.Background(), query, transaction.Id, transaction.ClinicAddressId, transaction.ProductId, transaction.Quantity, transaction.TransactionType, appointmentId, transaction.CreatedAt)
+	var reasonCode interface{}
+	if transaction.ReasonCode != "" {
+		reasonCode = transaction.ReasonCode
+	}
+	_, err := tx.Exec(context.Background(), query, transaction.Id, transaction.ClinicAddressId, transaction.ProductId, transaction.Quantity, transaction.TransactionType, appointmentId, transaction.LotId, transaction.UnitCost, transaction.GoodsReceiptId, transaction.StockTransferId, reasonCode, transaction.StockCountId, transaction.CreatedAt)
 	return err
 }
 
 func (r *inventoryRepo) GetTransactions(clinicAddressId uuid.UUID, transactionType string) ([]models.InventoryTransaction, error) {
 	query := `
-		SELECT it.id, it.clinic_address_id, it.product_id, p.name, it.quantity, it.transaction_type, COALESCE(it.appointment_id, '00000000-0000-0000-0000-000000000000'::uuid), it.created_at
+		SELECT it.id, it.clinic_address_id, it.product_id, p.name, it.quantity, it.transaction_type, COALESCE(it.appointment_id, '00000000-0000-0000-0000-000000000000'::uuid),
+			it.lot_id, COALESCE(l.lot_number, ''), l.expires_at, it.unit_cost::float8, it.goods_receipt_id, it.stock_transfer_id, COALESCE(it.reason_code, ''), it.stock_count_id, it.created_at
 		FROM inventory_transactions it
 		JOIN products p ON p.id = it.product_id
+		LEFT JOIN inventory_lots l ON l.id = it.lot_id
 		WHERE it.clinic_address_id = $1
 			AND ($2 = '' OR it.transaction_type = $2)
 		ORDER BY it.created_at DESC
@@ -250,7 +538,7 @@ func (r *inventoryRepo) GetTransactions(clinicAddressId uuid.UUID, transactionTy
 	transactions := make([]models.InventoryTransaction, 0)
 	for rows.Next() {
 		var transaction models.InventoryTransaction
-		if err := rows.Scan(&transaction.Id, &transaction.ClinicAddressId, &transaction.ProductId, &transaction.ProductName, &transaction.Quantity, &transaction.TransactionType, &transaction.AppointmentId, &transaction.CreatedAt); err != nil {
+		if err := rows.Scan(&transaction.Id, &transaction.ClinicAddressId, &transaction.ProductId, &transaction.ProductName, &transaction.Quantity, &transaction.TransactionType, &transaction.AppointmentId, &transaction.LotId, &transaction.LotNumber, &transaction.ExpiresAt, &transaction.UnitCost, &transaction.GoodsReceiptId, &transaction.StockTransferId, &transaction.ReasonCode, &transaction.StockCountId, &transaction.CreatedAt); err != nil {
 			return nil, err
 		}
 		transactions = append(transactions, transaction)
@@ -258,6 +546,82 @@ func (r *inventoryRepo) GetTransactions(clinicAddressId uuid.UUID, transactionTy
 	return transactions, rows.Err()
 }
 
+func (r *inventoryRepo) IsClinicAdmin(clinicId, userId uuid.UUID) (bool, error) {
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM clinic_admins WHERE clinic_id = $1 AND user_id = $2)`
+	err := r.db.QueryRow(context.Background(), query, clinicId, userId).Scan(&exists)
+	return exists, err
+}
+
+func (r *inventoryRepo) GetAlertPreference(clinicId, userId uuid.UUID) (*models.AlertPreference, error) {
+	preference := &models.AlertPreference{}
+	query := `SELECT clinic_id, user_id, mode, updated_at FROM inventory_alert_preferences WHERE clinic_id = $1 AND user_id = $2`
+	err := r.db.QueryRow(context.Background(), query, clinicId, userId).
+		Scan(&preference.ClinicId, &preference.UserId, &preference.Mode, &preference.UpdatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return preference, nil
+}
+
+func (r *inventoryRepo) SaveAlertPreference(preference *models.AlertPreference) error {
+	query := `
+		INSERT INTO inventory_alert_preferences (clinic_id, user_id, mode, updated_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (clinic_id, user_id) DO UPDATE
+		SET mode = EXCLUDED.mode, updated_at = EXCLUDED.updated_at
+		RETURNING updated_at
+	`
+	return r.db.QueryRow(context.Background(), query, preference.ClinicId, preference.UserId, preference.Mode).
+		Scan(&preference.UpdatedAt)
+}
+
+func (r *inventoryRepo) GetInventorySettings(clinicId uuid.UUID) (*models.InventorySettings, error) {
+	settings := &models.InventorySettings{}
+	query := `SELECT clinic_id, shortage_policy, consumption_order, valuation_method, updated_at FROM clinic_inventory_settings WHERE clinic_id = $1`
+	err := r.db.QueryRow(context.Background(), query, clinicId).
+		Scan(&settings.ClinicId, &settings.ShortagePolicy, &settings.ConsumptionOrder, &settings.ValuationMethod, &settings.UpdatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return settings, nil
+}
+
+func (r *inventoryRepo) GetInventorySettingsTx(clinicId uuid.UUID, tx pgx.Tx) (*models.InventorySettings, error) {
+	settings := &models.InventorySettings{}
+	query := `SELECT clinic_id, shortage_policy, consumption_order, valuation_method, updated_at FROM clinic_inventory_settings WHERE clinic_id = $1`
+	err := tx.QueryRow(context.Background(), query, clinicId).
+		Scan(&settings.ClinicId, &settings.ShortagePolicy, &settings.ConsumptionOrder, &settings.ValuationMethod, &settings.UpdatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return settings, nil
+}
+
+func (r *inventoryRepo) SaveInventorySettings(settings *models.InventorySettings) error {
+	query := `
+		INSERT INTO clinic_inventory_settings (clinic_id, shortage_policy, consumption_order, valuation_method, updated_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (clinic_id) DO UPDATE
+		SET shortage_policy = EXCLUDED.shortage_policy,
+			consumption_order = EXCLUDED.consumption_order,
+			valuation_method = EXCLUDED.valuation_method,
+			updated_at = EXCLUDED.updated_at
+		RETURNING updated_at
+	`
+	return r.db.QueryRow(context.Background(), query, settings.ClinicId, settings.ShortagePolicy, settings.ConsumptionOrder, settings.ValuationMethod).
+		Scan(&settings.UpdatedAt)
+}
+
 func (r *inventoryRepo) CreateServiceMaterial(material *models.ServiceMaterial) (*models.ServiceMaterial, error) {
 	query := `
 		INSERT INTO service_materials (id, service_id, product_id, quantity_required)
@@ -293,3 +657,402 @@ func (r *inventoryRepo) GetServiceMaterials(clinicServiceId uuid.UUID) ([]models
 	}
 	return materials, rows.Err()
 }
+
+func (r *inventoryRepo) GetServiceMaterialsTx(clinicServiceId uuid.UUID, tx pgx.Tx) ([]models.ServiceMaterial, error) {
+	query := `
+		SELECT sm.id, sm.service_id, sm.product_id, p.name, p.unit, sm.quantity_required
+		FROM service_materials sm
+		JOIN products p ON p.id = sm.product_id
+		WHERE sm.service_id = $1
+		ORDER BY p.name
+	`
+	rows, err := tx.Query(context.Background(), query, clinicServiceId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	materials := make([]models.ServiceMaterial, 0)
+	for rows.Next() {
+		var material models.ServiceMaterial
+		if err := rows.Scan(&material.Id, &material.ClinicServiceId, &material.ProductId, &material.ProductName, &material.ProductUnit, &material.QuantityRequired); err != nil {
+			return nil, err
+		}
+		materials = append(materials, material)
+	}
+	return materials, rows.Err()
+}
+
+// GetAddressClinic returns the clinic at the address, or uuid.Nil when there
+// is no such address.
+func (r *inventoryRepo) GetAddressClinic(clinicAddressId uuid.UUID) (uuid.UUID, error) {
+	var clinicId uuid.UUID
+	err := r.db.QueryRow(context.Background(), `SELECT clinic_id FROM clinic_addresses WHERE id = $1`, clinicAddressId).Scan(&clinicId)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return uuid.Nil, nil
+		}
+		return uuid.Nil, err
+	}
+	return clinicId, nil
+}
+
+func (r *inventoryRepo) CreateTransferTx(transfer *models.StockTransfer, tx pgx.Tx) error {
+	query := `
+		INSERT INTO stock_transfers (id, clinic_id, from_address_id, to_address_id, status, notes, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+	_, err := tx.Exec(context.Background(), query, transfer.Id, transfer.ClinicId, transfer.FromAddressId, transfer.ToAddressId, transfer.Status, transfer.Notes, transfer.CreatedBy, transfer.CreatedAt)
+	return err
+}
+
+func (r *inventoryRepo) CreateTransferItemTx(item *models.StockTransferItem, tx pgx.Tx) error {
+	query := `
+		INSERT INTO stock_transfer_items (id, stock_transfer_id, product_id, lot_number, expires_at, unit_cost, quantity)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	_, err := tx.Exec(context.Background(), query, item.Id, item.StockTransferId, item.ProductId, item.LotNumber, item.ExpiresAt, item.UnitCost, item.Quantity)
+	return err
+}
+
+const transferSelect = `
+	SELECT id, clinic_id, from_address_id, to_address_id, status, notes, created_by, received_by, created_at, received_at
+	FROM stock_transfers
+`
+
+const transferItemSelect = `
+	SELECT i.id, i.stock_transfer_id, i.product_id, p.name, p.unit, i.lot_number, i.expires_at, i.unit_cost::float8, i.quantity
+	FROM stock_transfer_items i
+	JOIN products p ON p.id = i.product_id
+	WHERE i.stock_transfer_id = ANY($1)
+	ORDER BY p.name, i.expires_at NULLS LAST, i.lot_number
+`
+
+func scanTransfers(rows pgx.Rows) ([]models.StockTransfer, error) {
+	defer rows.Close()
+
+	transfers := make([]models.StockTransfer, 0)
+	for rows.Next() {
+		var transfer models.StockTransfer
+		if err := rows.Scan(&transfer.Id, &transfer.ClinicId, &transfer.FromAddressId, &transfer.ToAddressId, &transfer.Status, &transfer.Notes, &transfer.CreatedBy, &transfer.ReceivedBy, &transfer.CreatedAt, &transfer.ReceivedAt); err != nil {
+			return nil, err
+		}
+		transfer.Items = make([]models.StockTransferItem, 0)
+		transfers = append(transfers, transfer)
+	}
+	return transfers, rows.Err()
+}
+
+// addTransferItems fills in the items of the transfers from rows of
+// transferItemSelect.
+func addTransferItems(transfers []models.StockTransfer, rows pgx.Rows) error {
+	defer rows.Close()
+
+	index := make(map[uuid.UUID]int, len(transfers))
+	for i, transfer := range transfers {
+		index[transfer.Id] = i
+	}
+	for rows.Next() {
+		var item models.StockTransferItem
+		if err := rows.Scan(&item.Id, &item.StockTransferId, &item.ProductId, &item.ProductName, &item.ProductUnit, &item.LotNumber, &item.ExpiresAt, &item.UnitCost, &item.Quantity); err != nil {
+			return err
+		}
+		i := index[item.StockTransferId]
+		transfers[i].Items = append(transfers[i].Items, item)
+	}
+	return rows.Err()
+}
+
+func transferIds(transfers []models.StockTransfer) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(transfers))
+	for _, transfer := range transfers {
+		ids = append(ids, transfer.Id)
+	}
+	return ids
+}
+
+func (r *inventoryRepo) GetTransferByID(id uuid.UUID) (*models.StockTransfer, error) {
+	rows, err := r.db.Query(context.Background(), transferSelect+`WHERE id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+	transfers, err := scanTransfers(rows)
+	if err != nil || len(transfers) == 0 {
+		return nil, err
+	}
+
+	itemRows, err := r.db.Query(context.Background(), transferItemSelect, transferIds(transfers))
+	if err != nil {
+		return nil, err
+	}
+	if err := addTransferItems(transfers, itemRows); err != nil {
+		return nil, err
+	}
+	return &transfers[0], nil
+}
+
+// GetTransferForUpdateTx locks the transfer so it is received or cancelled
+// once.
+func (r *inventoryRepo) GetTransferForUpdateTx(id uuid.UUID, tx pgx.Tx) (*models.StockTransfer, error) {
+	rows, err := tx.Query(context.Background(), transferSelect+`WHERE id = $1 FOR UPDATE`, id)
+	if err != nil {
+		return nil, err
+	}
+	transfers, err := scanTransfers(rows)
+	if err != nil || len(transfers) == 0 {
+		return nil, err
+	}
+
+	itemRows, err := tx.Query(context.Background(), transferItemSelect, transferIds(transfers))
+	if err != nil {
+		return nil, err
+	}
+	if err := addTransferItems(transfers, itemRows); err != nil {
+		return nil, err
+	}
+	return &transfers[0], nil
+}
+
+// GetTransfers lists the transfers of the clinic, newest first. An empty
+// status lists transfers in every status.
+func (r *inventoryRepo) GetTransfers(clinicId uuid.UUID, status string) ([]models.StockTransfer, error) {
+	query := transferSelect + `
+		WHERE clinic_id = $1 AND ($2 = '' OR status = $2)
+		ORDER BY created_at DESC
+	`
+	rows, err := r.db.Query(context.Background(), query, clinicId, status)
+	if err != nil {
+		return nil, err
+	}
+	transfers, err := scanTransfers(rows)
+	if err != nil || len(transfers) == 0 {
+		return transfers, err
+	}
+
+	itemRows, err := r.db.Query(context.Background(), transferItemSelect, transferIds(transfers))
+	if err != nil {
+		return nil, err
+	}
+	if err := addTransferItems(transfers, itemRows); err != nil {
+		return nil, err
+	}
+	return transfers, nil
+}
+
+// SetTransferStatusTx closes a transfer in transit, noting who received it
+// when it arrived.
+func (r *inventoryRepo) SetTransferStatusTx(id uuid.UUID, status string, receivedBy *uuid.UUID, tx pgx.Tx) error {
+	query := `
+		UPDATE stock_transfers
+		SET status = $2,
+			received_by = CASE WHEN $2 = 'received' THEN $3 ELSE received_by END,
+			received_at = CASE WHEN $2 = 'received' THEN NOW() ELSE received_at END
+		WHERE id = $1
+	`
+	result, err := tx.Exec(context.Background(), query, id, status, receivedBy)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// GetOpenStockCountID returns the count open at the address, or uuid.Nil
+// when there is none.
+func (r *inventoryRepo) GetOpenStockCountID(clinicAddressId uuid.UUID) (uuid.UUID, error) {
+	var id uuid.UUID
+	err := r.db.QueryRow(context.Background(), `SELECT id FROM stock_counts WHERE clinic_address_id = $1 AND status = 'open'`, clinicAddressId).Scan(&id)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return uuid.Nil, nil
+		}
+		return uuid.Nil, err
+	}
+	return id, nil
+}
+
+// CreateStockCountTx opens the count with the quantities in stock at the
+// address as expected.
+func (r *inventoryRepo) CreateStockCountTx(count *models.StockCount, tx pgx.Tx) error {
+	query := `
+		INSERT INTO stock_counts (id, clinic_id, clinic_address_id, status, notes, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	if _, err := tx.Exec(context.Background(), query, count.Id, count.ClinicId, count.ClinicAddressId, count.Status, count.Notes, count.CreatedBy, count.CreatedAt); err != nil {
+		return err
+	}
+
+	snapshotQuery := `
+		INSERT INTO stock_count_items (id, stock_count_id, product_id, expected_quantity)
+		SELECT gen_random_uuid(), $1, product_id, quantity
+		FROM address_inventory
+		WHERE clinic_address_id = $2
+	`
+	_, err := tx.Exec(context.Background(), snapshotQuery, count.Id, count.ClinicAddressId)
+	return err
+}
+
+const stockCountSelect = `
+	SELECT id, clinic_id, clinic_address_id, status, notes, created_by, posted_by, created_at, posted_at
+	FROM stock_counts
+`
+
+const stockCountItemSelect = `
+	SELECT i.id, i.stock_count_id, i.product_id, p.name, p.unit, i.expected_quantity, i.counted_quantity, COALESCE(i.reason_code, ''), i.note, i.counted_at
+	FROM stock_count_items i
+	JOIN products p ON p.id = i.product_id
+	WHERE i.stock_count_id = ANY($1)
+	ORDER BY p.name
+`
+
+func scanStockCounts(rows pgx.Rows) ([]models.StockCount, error) {
+	defer rows.Close()
+
+	counts := make([]models.StockCount, 0)
+	for rows.Next() {
+		var count models.StockCount
+		if err := rows.Scan(&count.Id, &count.ClinicId, &count.ClinicAddressId, &count.Status, &count.Notes, &count.CreatedBy, &count.PostedBy, &count.CreatedAt, &count.PostedAt); err != nil {
+			return nil, err
+		}
+		count.Items = make([]models.StockCountItem, 0)
+		counts = append(counts, count)
+	}
+	return counts, rows.Err()
+}
+
+// addStockCountItems fills in the items of the counts from rows of
+// stockCountItemSelect.
+func addStockCountItems(counts []models.StockCount, rows pgx.Rows) error {
+	defer rows.Close()
+
+	index := make(map[uuid.UUID]int, len(counts))
+	for i, count := range counts {
+		index[count.Id] = i
+	}
+	for rows.Next() {
+		var item models.StockCountItem
+		if err := rows.Scan(&item.Id, &item.StockCountId, &item.ProductId, &item.ProductName, &item.ProductUnit, &item.ExpectedQuantity, &item.CountedQuantity, &item.ReasonCode, &item.Note, &item.CountedAt); err != nil {
+			return err
+		}
+		i := index[item.StockCountId]
+		counts[i].Items = append(counts[i].Items, item)
+	}
+	return rows.Err()
+}
+
+func stockCountIds(counts []models.StockCount) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(counts))
+	for _, count := range counts {
+		ids = append(ids, count.Id)
+	}
+	return ids
+}
+
+func (r *inventoryRepo) GetStockCountByID(id uuid.UUID) (*models.StockCount, error) {
+	rows, err := r.db.Query(context.Background(), stockCountSelect+`WHERE id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := scanStockCounts(rows)
+	if err != nil || len(counts) == 0 {
+		return nil, err
+	}
+
+	itemRows, err := r.db.Query(context.Background(), stockCountItemSelect, stockCountIds(counts))
+	if err != nil {
+		return nil, err
+	}
+	if err := addStockCountItems(counts, itemRows); err != nil {
+		return nil, err
+	}
+	return &counts[0], nil
+}
+
+// GetStockCountForUpdateTx locks the count so counts entered and its posting
+// do not interleave.
+func (r *inventoryRepo) GetStockCountForUpdateTx(id uuid.UUID, tx pgx.Tx) (*models.StockCount, error) {
+	rows, err := tx.Query(context.Background(), stockCountSelect+`WHERE id = $1 FOR UPDATE`, id)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := scanStockCounts(rows)
+	if err != nil || len(counts) == 0 {
+		return nil, err
+	}
+
+	itemRows, err := tx.Query(context.Background(), stockCountItemSelect, stockCountIds(counts))
+	if err != nil {
+		return nil, err
+	}
+	if err := addStockCountItems(counts, itemRows); err != nil {
+		return nil, err
+	}
+	return &counts[0], nil
+}
+
+// GetStockCounts lists the counts of the clinic, newest first. An empty
+// status lists counts in every status.
+func (r *inventoryRepo) GetStockCounts(clinicId uuid.UUID, status string) ([]models.StockCount, error) {
+	query := stockCountSelect + `
+		WHERE clinic_id = $1 AND ($2 = '' OR status = $2)
+		ORDER BY created_at DESC
+	`
+	rows, err := r.db.Query(context.Background(), query, clinicId, status)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := scanStockCounts(rows)
+	if err != nil || len(counts) == 0 {
+		return counts, err
+	}
+
+	itemRows, err := r.db.Query(context.Background(), stockCountItemSelect, stockCountIds(counts))
+	if err != nil {
+		return nil, err
+	}
+	if err := addStockCountItems(counts, itemRows); err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// SaveStockCountItemTx records what was counted of a product. A product that
+// was not in stock when the count started is expected at 0.
+func (r *inventoryRepo) SaveStockCountItemTx(item *models.StockCountItem, tx pgx.Tx) error {
+	query := `
+		INSERT INTO stock_count_items (id, stock_count_id, product_id, expected_quantity, counted_quantity, reason_code, note, counted_at)
+		VALUES ($1, $2, $3, 0, $4, $5, $6, $7)
+		ON CONFLICT (stock_count_id, product_id) DO UPDATE
+		SET counted_quantity = EXCLUDED.counted_quantity,
+			reason_code = EXCLUDED.reason_code,
+			note = EXCLUDED.note,
+			counted_at = EXCLUDED.counted_at
+	`
+	var reasonCode interface{}
+	if item.ReasonCode != "" {
+		reasonCode = item.ReasonCode
+	}
+	_, err := tx.Exec(context.Background(), query, item.Id, item.StockCountId, item.ProductId, item.CountedQuantity, reasonCode, item.Note, item.CountedAt)
+	return err
+}
+
+// SetStockCountStatusTx closes an open count, noting who posted it.
+func (r *inventoryRepo) SetStockCountStatusTx(id uuid.UUID, status string, postedBy *uuid.UUID, tx pgx.Tx) error {
+	query := `
+		UPDATE stock_counts
+		SET status = $2,
+			posted_by = CASE WHEN $2 = 'posted' THEN $3 ELSE posted_by END,
+			posted_at = CASE WHEN $2 = 'posted' THEN NOW() ELSE posted_at END
+		WHERE id = $1
+	`
+	result, err := tx.Exec(context.Background(), query, id, status, postedBy)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
@@ -11,10 +11,9 @@ import (
 )
 
 func RegisterPrivateRoutes(r *mux.Router, db *pgxpool.Pool, cfg *config.Config) {
-	_ = cfg
 	repo := repository.NewInventoryRepository(db)
 	service := services.NewInventoryService(repo, db)
-	handler := handlers.NewInventoryHandler(service)
+	handler := handlers.NewInventoryHandler(service, *cfg)
 
 	r.HandleFunc("/products", handler.CreateProduct).Methods("POST")
 	r.HandleFunc("/products", handler.GetProducts).Methods("GET")
@@ -27,6 +26,29 @@ func RegisterPrivateRoutes(r *mux.Router, db *pgxpool.Pool, cfg *config.Config)
 	r.HandleFunc("/clinics/{clinicId}/clinic-addresses/{addressId}/inventory-status", handler.GetInventoryStatus).Methods("GET")
 	r.HandleFunc("/clinic-addresses/{id}/inventory/{inventoryId}", handler.UpdateInventory).Methods("PUT")
 	r.HandleFunc("/clinic-addresses/{id}/inventory-transactions", handler.GetTransactions).Methods("GET")
+	r.HandleFunc("/clinic-addresses/{id}/inventory-levels", handler.SetInventoryLevels).Methods("PUT")
+	r.HandleFunc("/clinic-addresses/{id}/inventory-lots", handler.GetLots).Methods("GET")
+	r.HandleFunc("/clinic-addresses/{id}/inventory-lots/{lotId}", handler.UpdateLot).Methods("PUT")
+	r.HandleFunc("/clinics/{clinicId}/inventory-expiring", handler.GetExpiringLots).Methods("GET")
+	r.HandleFunc("/clinics/{clinicId}/inventory-alerts", handler.GetAlertPreference).Methods("GET")
+	r.HandleFunc("/clinics/{clinicId}/inventory-alerts", handler.UpdateAlertPreference).Methods("PUT")
+	r.HandleFunc("/clinics/{clinicId}/inventory-settings", handler.GetInventorySettings).Methods("GET")
+	r.HandleFunc("/clinics/{clinicId}/inventory-settings", handler.UpdateInventorySettings).Methods("PUT")
+
+	r.HandleFunc("/clinics/{clinicId}/stock-transfers", handler.CreateTransfer).Methods("POST")
+	r.HandleFunc("/clinics/{clinicId}/stock-transfers", handler.GetTransfers).Methods("GET")
+	r.HandleFunc("/stock-transfers/{id}", handler.GetTransfer).Methods("GET")
+	r.HandleFunc("/stock-transfers/{id}/receive", handler.ReceiveTransfer).Methods("POST")
+	r.HandleFunc("/stock-transfers/{id}/cancel", handler.CancelTransfer).Methods("POST")
+
+	r.HandleFunc("/clinics/{clinicId}/stock-counts", handler.StartStockCount).Methods("POST")
+	r.HandleFunc("/clinics/{clinicId}/stock-counts", handler.GetStockCounts).Methods("GET")
+	r.HandleFunc("/stock-counts/{id}", handler.GetStockCount).Methods("GET")
+	r.HandleFunc("/stock-counts/{id}/items", handler.RecordCounts).Methods("PUT")
+	r.HandleFunc("/stock-counts/{id}/sheet", handler.GetCountSheet).Methods("GET")
+	r.HandleFunc("/stock-counts/{id}/import", handler.ImportCounts).Methods("POST")
+	r.HandleFunc("/stock-counts/{id}/post", handler.PostStockCount).Methods("POST")
+	r.HandleFunc("/stock-counts/{id}/cancel", handler.CancelStockCount).Methods("POST")
 
 	r.HandleFunc("/clinic-services/{id}/materials", handler.AttachMaterial).Methods("POST")
 	r.HandleFunc("/clinic-services/{id}/materials", handler.GetServiceMaterials).Methods("GET")
@@ -0,0 +1,383 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"dental_clinic/internal/modules/inventory/dto"
+	"dental_clinic/internal/modules/inventory/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const reasonCodeError = "reason_code must be damaged, expired, lost, theft, found, entry_error or other"
+
+// validReasonCode reports whether code is one of the reasons a stock
+// quantity can be corrected for.
+func validReasonCode(code string) bool {
+	switch code {
+	case "damaged", "expired", "lost", "theft", "found", "entry_error", "other":
+		return true
+	}
+	return false
+}
+
+// StartStockCount opens a count at an address of the clinic, freezing what
+// is in stock there as the expected quantities. An address has one open
+// count at a time.
+func (s *InventoryService) StartStockCount(ctx context.Context, clinicId, userId, role string, req dto.StockCountRequest) (*models.StockCount, error) {
+	clinicUUID, err := s.managedClinic(clinicId, userId, role)
+	if err != nil {
+		return nil, err
+	}
+	addressId, err := uuid.Parse(req.ClinicAddressId)
+	if err != nil {
+		return nil, errors.New("invalid clinic address id")
+	}
+	addressClinic, err := s.repo.GetAddressClinic(addressId)
+	if err != nil {
+		return nil, err
+	}
+	if addressClinic != clinicUUID {
+		return nil, errors.New("clinic address not found")
+	}
+	openId, err := s.repo.GetOpenStockCountID(addressId)
+	if err != nil {
+		return nil, err
+	}
+	if openId != uuid.Nil {
+		return nil, errors.New("a stock count is already open at this address")
+	}
+
+	count := &models.StockCount{
+		Id:              uuid.New(),
+		ClinicId:        clinicUUID,
+		ClinicAddressId: addressId,
+		Status:          "open",
+		Notes:           strings.TrimSpace(req.Notes),
+		CreatedAt:       time.Now(),
+	}
+	if userUUID, err := uuid.Parse(userId); err == nil {
+		count.CreatedBy = &userUUID
+	}
+
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := s.repo.CreateStockCountTx(count, tx); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return s.repo.GetStockCountByID(count.Id)
+}
+
+// RecordCounts enters counted quantities on an open count.
+func (s *InventoryService) RecordCounts(ctx context.Context, id, userId, role string, items []dto.StockCountItemRequest) (*models.StockCount, error) {
+	count, err := s.managedStockCount(id, userId, role)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, errors.New("items are required")
+	}
+
+	now := time.Now()
+	counted := make([]models.StockCountItem, 0, len(items))
+	for _, item := range items {
+		productId, err := uuid.Parse(item.ProductId)
+		if err != nil {
+			return nil, errors.New("invalid product id")
+		}
+		if math.IsNaN(item.CountedQuantity) || math.IsInf(item.CountedQuantity, 0) {
+			return nil, errors.New("invalid counted_quantity")
+		}
+		if item.CountedQuantity < 0 {
+			return nil, errors.New("counted_quantity cannot be negative")
+		}
+		if item.ReasonCode != "" && !validReasonCode(item.ReasonCode) {
+			return nil, errors.New(reasonCodeError)
+		}
+		quantity := item.CountedQuantity
+		counted = append(counted, models.StockCountItem{
+			Id:              uuid.New(),
+			StockCountId:    count.Id,
+			ProductId:       productId,
+			CountedQuantity: &quantity,
+			ReasonCode:      item.ReasonCode,
+			Note:            strings.TrimSpace(item.Note),
+			CountedAt:       &now,
+		})
+	}
+
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if count, err = s.openStockCountTx(count.Id, tx); err != nil {
+		return nil, err
+	}
+	expected := make(map[uuid.UUID]bool, len(count.Items))
+	for _, item := range count.Items {
+		expected[item.ProductId] = true
+	}
+	for _, item := range counted {
+		if !expected[item.ProductId] {
+			product, err := s.repo.GetProductByID(item.ProductId)
+			if err != nil {
+				return nil, err
+			}
+			if product == nil {
+				return nil, errors.New("product not found")
+			}
+		}
+		if err := s.repo.SaveStockCountItemTx(&item, tx); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return s.repo.GetStockCountByID(count.Id)
+}
+
+// ImportCounts enters counted quantities from a CSV count sheet. The header
+// names the columns: product_id and counted_quantity are required,
+// reason_code and note are optional and other columns are ignored. Rows
+// left without a counted quantity are skipped.
+func (s *InventoryService) ImportCounts(ctx context.Context, id, userId, role string, sheet io.Reader) (*models.StockCount, error) {
+	reader := csv.NewReader(sheet)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, errors.New("count sheet is empty or not CSV")
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["product_id"]; !ok {
+		return nil, errors.New("count sheet has no product_id column")
+	}
+	if _, ok := columns["counted_quantity"]; !ok {
+		return nil, errors.New("count sheet has no counted_quantity column")
+	}
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	items := make([]dto.StockCountItemRequest, 0)
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		rawQuantity := field(record, "counted_quantity")
+		if rawQuantity == "" {
+			continue
+		}
+		quantity, err := strconv.ParseFloat(rawQuantity, 64)
+		if err != nil || math.IsNaN(quantity) || math.IsInf(quantity, 0) {
+			return nil, fmt.Errorf("line %d: invalid counted_quantity", line)
+		}
+		items = append(items, dto.StockCountItemRequest{
+			ProductId:       field(record, "product_id"),
+			CountedQuantity: quantity,
+			ReasonCode:      field(record, "reason_code"),
+			Note:            field(record, "note"),
+		})
+	}
+	if len(items) == 0 {
+		return nil, errors.New("count sheet has no counted quantities")
+	}
+	return s.RecordCounts(ctx, id, userId, role, items)
+}
+
+// CountSheet returns the products of a count as a CSV sheet to count on and
+// import back. The expected quantities are left out so they do not steer the
+// count.
+func (s *InventoryService) CountSheet(id, userId, role string) ([]byte, error) {
+	count, err := s.managedStockCount(id, userId, role)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	_ = writer.Write([]string{"product_id", "product_name", "unit", "counted_quantity", "reason_code", "note"})
+	for _, item := range count.Items {
+		counted := ""
+		if item.CountedQuantity != nil {
+			counted = strconv.FormatFloat(*item.CountedQuantity, 'f', -1, 64)
+		}
+		_ = writer.Write([]string{item.ProductId.String(), item.ProductName, item.ProductUnit, counted, item.ReasonCode, item.Note})
+	}
+	writer.Flush()
+	return buf.Bytes(), writer.Error()
+}
+
+// PostStockCount books the variances of an open count. Each counted product
+// that differs from what was expected needs a reason code. The variance is
+// applied to the stock as it is now, so stock used or received while
+// counting is kept. Products left uncounted are not changed.
+func (s *InventoryService) PostStockCount(ctx context.Context, id, userId, role string) (*models.StockCount, error) {
+	count, err := s.managedStockCount(id, userId, role)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if count, err = s.openStockCountTx(count.Id, tx); err != nil {
+		return nil, err
+	}
+
+	for _, item := range count.Items {
+		variance, counted := item.Variance()
+		if !counted || variance == 0 {
+			continue
+		}
+		if item.ReasonCode == "" {
+			return nil, fmt.Errorf("reason_code is required for %s: %g %s counted, %g expected", item.ProductName, *item.CountedQuantity, item.ProductUnit, item.ExpectedQuantity)
+		}
+
+		stock, err := s.repo.LockStockTx(count.ClinicAddressId, item.ProductId, tx)
+		if err != nil {
+			return nil, err
+		}
+		quantity := max(stock.Quantity+variance, 0)
+		updated, err := s.repo.UpdateInventoryQuantityTx(stock.Id, quantity, tx)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.repo.LowerAlertLevelTx(stock.Id, StockSeverity(StockStatus(updated.Quantity, updated.MinQuantity, updated.ReorderLevel)), tx); err != nil {
+			return nil, err
+		}
+		if err := s.adjustLotsTx(models.InventoryTransaction{
+			ClinicAddressId: count.ClinicAddressId,
+			ProductId:       item.ProductId,
+			Quantity:        quantity - stock.Quantity,
+			TransactionType: "stock_count",
+			ReasonCode:      item.ReasonCode,
+			StockCountId:    &count.Id,
+		}, tx); err != nil {
+			return nil, err
+		}
+	}
+
+	var userUUID *uuid.UUID
+	if parsed, err := uuid.Parse(userId); err == nil {
+		userUUID = &parsed
+	}
+	if err := s.repo.SetStockCountStatusTx(count.Id, "posted", userUUID, tx); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return s.repo.GetStockCountByID(count.Id)
+}
+
+// CancelStockCount drops an open count without changing the stock.
+func (s *InventoryService) CancelStockCount(ctx context.Context, id, userId, role string) (*models.StockCount, error) {
+	count, err := s.managedStockCount(id, userId, role)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if count, err = s.openStockCountTx(count.Id, tx); err != nil {
+		return nil, err
+	}
+	if err := s.repo.SetStockCountStatusTx(count.Id, "cancelled", nil, tx); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return s.repo.GetStockCountByID(count.Id)
+}
+
+func (s *InventoryService) GetStockCounts(clinicId, userId, role, status string) ([]models.StockCount, error) {
+	clinicUUID, err := s.managedClinic(clinicId, userId, role)
+	if err != nil {
+		return nil, err
+	}
+	switch status {
+	case "", "open", "posted", "cancelled":
+	default:
+		return nil, errors.New("status must be open, posted or cancelled")
+	}
+	return s.repo.GetStockCounts(clinicUUID, status)
+}
+
+func (s *InventoryService) GetStockCount(id, userId, role string) (*models.StockCount, error) {
+	return s.managedStockCount(id, userId, role)
+}
+
+// openStockCountTx locks the count and checks it is still open.
+func (s *InventoryService) openStockCountTx(id uuid.UUID, tx pgx.Tx) (*models.StockCount, error) {
+	count, err := s.repo.GetStockCountForUpdateTx(id, tx)
+	if err != nil {
+		return nil, err
+	}
+	if count == nil {
+		return nil, errors.New("stock count not found")
+	}
+	if count.Status != "open" {
+		return nil, fmt.Errorf("stock count is already %s", count.Status)
+	}
+	return count, nil
+}
+
+func (s *InventoryService) managedStockCount(id, userId, role string) (*models.StockCount, error) {
+	countId, err := uuid.Parse(id)
+	if err != nil {
+		return nil, errors.New("invalid stock count id")
+	}
+	count, err := s.repo.GetStockCountByID(countId)
+	if err != nil {
+		return nil, err
+	}
+	if count == nil {
+		return nil, errors.New("stock count not found")
+	}
+	if _, err := s.managedClinic(count.ClinicId.String(), userId, role); err != nil {
+		return nil, err
+	}
+	return count, nil
+}
@@ -3,6 +3,7 @@ package services
 import (
 	"context"
 	"errors"
+	"strings"
 	"time"
 
 	"dental_clinic/internal/modules/inventory/dto"
@@ -101,8 +102,13 @@ func (s *InventoryService) AddStock(ctx context.Context, clinicAddressId string,
 	if err != nil {
 		return nil, errors.New("invalid product id")
 	}
-	if req.Quantity <= 0 {
-		return nil, errors.New("quantity must be greater than 0")
+	var expiresAt *time.Time
+	if req.ExpiresAt != "" {
+		parsed, err := time.Parse("2006-01-02", req.ExpiresAt)
+		if err != nil {
+			return nil, errors.New("invalid expires_at format, use: 2006-01-02")
+		}
+		expiresAt = &parsed
 	}
 
 	if _, err := s.GetProductByID(req.ProductId); err != nil {
@@ -115,43 +121,98 @@ func (s *InventoryService) AddStock(ctx context.Context, clinicAddressId string,
 	}
 	defer tx.Rollback(ctx)
 
-	item, err := s.repo.GetInventoryByAddressAndProduct(addressId, productId, tx)
+	item, _, err := s.ReceiveStockTx(models.StockReceipt{
+		ClinicAddressId: addressId,
+		ProductId:       productId,
+		Quantity:        req.Quantity,
+		LotNumber:       req.LotNumber,
+		ExpiresAt:       expiresAt,
+		UnitCost:        req.UnitCost,
+	}, tx)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetInventoryByID(item.Id)
+}
+
+// ReceiveStockTx puts delivered stock into the address inventory and its lot
+// and records a restocked transaction, or transfer_in for a transfer, with
+// what the stock cost. It returns the inventory item and the lot the stock
+// went into.
+func (s *InventoryService) ReceiveStockTx(receipt models.StockReceipt, tx pgx.Tx) (*models.AddressInventory, *models.Lot, error) {
+	if receipt.Quantity <= 0 {
+		return nil, nil, errors.New("quantity must be greater than 0")
+	}
+	if receipt.UnitCost != nil && *receipt.UnitCost < 0 {
+		return nil, nil, errors.New("unit_cost cannot be negative")
+	}
+
+	item, err := s.repo.GetInventoryByAddressAndProduct(receipt.ClinicAddressId, receipt.ProductId, tx)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	now := time.Now()
 	if item == nil {
 		item, err = s.repo.CreateInventoryTx(&models.AddressInventory{
 			Id:              uuid.New(),
-			ClinicAddressId: addressId,
-			ProductId:       productId,
-			Quantity:        req.Quantity,
+			ClinicAddressId: receipt.ClinicAddressId,
+			ProductId:       receipt.ProductId,
+			Quantity:        receipt.Quantity,
 			UpdatedAt:       now,
 		}, tx)
 	} else {
-		item, err = s.repo.UpdateInventoryQuantityTx(item.Id, item.Quantity+req.Quantity, tx)
+		item, err = s.repo.UpdateInventoryQuantityTx(item.Id, item.Quantity+receipt.Quantity, tx)
 	}
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	if err := s.repo.LowerAlertLevelTx(item.Id, StockSeverity(StockStatus(item.Quantity, item.MinQuantity, item.ReorderLevel)), tx); err != nil {
+		return nil, nil, err
+	}
+
+	transactionType := "restocked"
+	if receipt.StockTransferId != nil {
+		transactionType = "transfer_in"
+	}
+
+	lot, err := s.repo.AddToLotTx(&models.Lot{
+		Id:              uuid.New(),
+		ClinicAddressId: receipt.ClinicAddressId,
+		ProductId:       receipt.ProductId,
+		LotNumber:       strings.TrimSpace(receipt.LotNumber),
+		ExpiresAt:       receipt.ExpiresAt,
+		Quantity:        receipt.Quantity,
+		UnitCost:        receipt.UnitCost,
+	}, tx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if lot == nil {
+		return nil, nil, errors.New("lot is already in stock with another expiry date")
 	}
 
 	if err := s.repo.CreateTransactionTx(&models.InventoryTransaction{
 		Id:              uuid.New(),
-		ClinicAddressId: addressId,
-		ProductId:       productId,
-		Quantity:        req.Quantity,
-		TransactionType: "restocked",
+		ClinicAddressId: receipt.ClinicAddressId,
+		ProductId:       receipt.ProductId,
+		Quantity:        receipt.Quantity,
+		TransactionType: transactionType,
+		LotId:           &lot.Id,
+		UnitCost:        receipt.UnitCost,
+		GoodsReceiptId:  receipt.GoodsReceiptId,
+		StockTransferId: receipt.StockTransferId,
 		CreatedAt:       now,
 	}, tx); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-
-	if err := tx.Commit(ctx); err != nil {
-		return nil, err
-	}
-
-	return s.repo.GetInventoryByID(item.Id)
+	return item, lot, nil
 }
 
 func (s *InventoryService) GetInventory(clinicAddressId string) ([]models.AddressInventory, error) {
@@ -186,6 +247,9 @@ func (s *InventoryService) UpdateInventory(ctx context.Context, clinicAddressId,
 	if req.Quantity < 0 {
 		return nil, errors.New("quantity cannot be negative")
 	}
+	if req.ReasonCode != "" && !validReasonCode(req.ReasonCode) {
+		return nil, errors.New(reasonCodeError)
+	}
 
 	current, err := s.repo.GetInventoryByID(itemId)
 	if err != nil {
@@ -208,14 +272,16 @@ func (s *InventoryService) UpdateInventory(ctx context.Context, clinicAddressId,
 	if updated == nil {
 		return nil, errors.New("inventory not found")
 	}
+	if err := s.repo.LowerAlertLevelTx(itemId, StockSeverity(StockStatus(updated.Quantity, updated.MinQuantity, updated.ReorderLevel)), tx); err != nil {
+		return nil, err
+	}
 
-	if err := s.repo.CreateTransactionTx(&models.InventoryTransaction{
-		Id:              uuid.New(),
+	if err := s.adjustLotsTx(models.InventoryTransaction{
 		ClinicAddressId: addressId,
 		ProductId:       current.ProductId,
 		Quantity:        req.Quantity - current.Quantity,
 		TransactionType: "manual_adjustment",
-		CreatedAt:       time.Now(),
+		ReasonCode:      req.ReasonCode,
 	}, tx); err != nil {
 		return nil, err
 	}
@@ -227,6 +293,99 @@ func (s *InventoryService) UpdateInventory(ctx context.Context, clinicAddressId,
 	return s.repo.GetInventoryByID(itemId)
 }
 
+// SetInventoryLevels sets the minimum and reorder levels of a product at a
+// clinic address.
+func (s *InventoryService) SetInventoryLevels(clinicAddressId string, req dto.InventoryLevelsRequest) (*models.AddressInventory, error) {
+	addressId, err := uuid.Parse(clinicAddressId)
+	if err != nil {
+		return nil, errors.New("invalid clinic address id")
+	}
+	productId, err := uuid.Parse(req.ProductId)
+	if err != nil {
+		return nil, errors.New("invalid product id")
+	}
+	if req.MinQuantity < 0 || req.ReorderLevel < 0 {
+		return nil, errors.New("levels cannot be negative")
+	}
+	if req.ReorderLevel < req.MinQuantity {
+		return nil, errors.New("reorder_level cannot be below min_quantity")
+	}
+	if _, err := s.GetProductByID(req.ProductId); err != nil {
+		return nil, err
+	}
+
+	itemId, err := s.repo.SaveInventoryLevels(&models.AddressInventory{
+		Id:              uuid.New(),
+		ClinicAddressId: addressId,
+		ProductId:       productId,
+		MinQuantity:     req.MinQuantity,
+		ReorderLevel:    req.ReorderLevel,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetInventoryByID(itemId)
+}
+
+// GetAlertPreference returns how the clinic admin is told about low stock.
+// Admins who never chose are emailed immediately.
+func (s *InventoryService) GetAlertPreference(clinicId, userId, role string) (*models.AlertPreference, error) {
+	clinicUUID, userUUID, err := s.alertRecipient(clinicId, userId, role)
+	if err != nil {
+		return nil, err
+	}
+	preference, err := s.repo.GetAlertPreference(clinicUUID, userUUID)
+	if err != nil {
+		return nil, err
+	}
+	if preference == nil {
+		return &models.AlertPreference{ClinicId: clinicUUID, UserId: userUUID, Mode: "immediate"}, nil
+	}
+	return preference, nil
+}
+
+func (s *InventoryService) UpdateAlertPreference(clinicId, userId, role string, req dto.AlertPreferenceRequest) (*models.AlertPreference, error) {
+	clinicUUID, userUUID, err := s.alertRecipient(clinicId, userId, role)
+	if err != nil {
+		return nil, err
+	}
+	switch req.Mode {
+	case "immediate", "daily_digest", "off":
+	default:
+		return nil, errors.New("mode must be immediate, daily_digest or off")
+	}
+
+	preference := &models.AlertPreference{ClinicId: clinicUUID, UserId: userUUID, Mode: req.Mode}
+	if err := s.repo.SaveAlertPreference(preference); err != nil {
+		return nil, err
+	}
+	return preference, nil
+}
+
+// alertRecipient checks that the user is an admin of the clinic; only they
+// receive its stock alerts.
+func (s *InventoryService) alertRecipient(clinicId, userId, role string) (uuid.UUID, uuid.UUID, error) {
+	clinicUUID, err := uuid.Parse(clinicId)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, errors.New("invalid clinic id")
+	}
+	userUUID, err := uuid.Parse(userId)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, errors.New("invalid user id")
+	}
+	if role != "clinic_admin" {
+		return uuid.Nil, uuid.Nil, errors.New("do not have rights")
+	}
+	isAdmin, err := s.repo.IsClinicAdmin(clinicUUID, userUUID)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+	if !isAdmin {
+		return uuid.Nil, uuid.Nil, errors.New("do not have rights")
+	}
+	return clinicUUID, userUUID, nil
+}
+
 func (s *InventoryService) AttachMaterial(clinicServiceId string, req dto.AttachMaterialRequest) (*models.ServiceMaterial, error) {
 	serviceId, err := uuid.Parse(clinicServiceId)
 	if err != nil {
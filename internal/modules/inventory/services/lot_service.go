@@ -0,0 +1,196 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"dental_clinic/internal/modules/inventory/dto"
+	"dental_clinic/internal/modules/inventory/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// adjustLotsTx books a change of the stock of a product at an address into
+// its lots, recording a transaction like entry for each lot changed; entry
+// holds the address, product, change and transaction type. Stock added goes
+// to the lot without a lot number; stock removed comes out of the lots in the
+// clinic's consumption order.
+func (s *InventoryService) adjustLotsTx(entry models.InventoryTransaction, tx pgx.Tx) error {
+	entry.CreatedAt = time.Now()
+	if entry.Quantity > 0 {
+		lot, err := s.repo.AddToLotTx(&models.Lot{
+			Id:              uuid.New(),
+			ClinicAddressId: entry.ClinicAddressId,
+			ProductId:       entry.ProductId,
+			Quantity:        entry.Quantity,
+		}, tx)
+		if err != nil {
+			return err
+		}
+		if lot == nil {
+			return errors.New("lot is already in stock with another expiry date")
+		}
+		entry.Id = uuid.New()
+		entry.LotId = &lot.Id
+		return s.repo.CreateTransactionTx(&entry, tx)
+	}
+
+	order, err := s.repo.GetConsumptionOrderTx(entry.ClinicAddressId, tx)
+	if err != nil {
+		return err
+	}
+	lots, err := s.repo.GetLotsTx(entry.ClinicAddressId, entry.ProductId, order, tx)
+	if err != nil {
+		return err
+	}
+
+	remaining := -entry.Quantity
+	for _, lot := range lots {
+		if remaining <= 0 {
+			break
+		}
+		taken := math.Min(lot.Quantity, remaining)
+		if err := s.repo.SetLotQuantityTx(lot.Id, lot.Quantity-taken, tx); err != nil {
+			return err
+		}
+		lotEntry := entry
+		lotEntry.Id = uuid.New()
+		lotEntry.Quantity = -taken
+		lotEntry.LotId = &lot.Id
+		lotEntry.UnitCost = lot.UnitCost
+		if err := s.repo.CreateTransactionTx(&lotEntry, tx); err != nil {
+			return err
+		}
+		remaining -= taken
+	}
+
+	// stock that was never in a lot
+	if remaining > 0 {
+		entry.Id = uuid.New()
+		entry.Quantity = -remaining
+		return s.repo.CreateTransactionTx(&entry, tx)
+	}
+	return nil
+}
+
+func (s *InventoryService) GetLots(clinicAddressId, productId string) ([]models.Lot, error) {
+	addressId, err := uuid.Parse(clinicAddressId)
+	if err != nil {
+		return nil, errors.New("invalid clinic address id")
+	}
+	productUUID := uuid.Nil
+	if productId != "" {
+		if productUUID, err = uuid.Parse(productId); err != nil {
+			return nil, errors.New("invalid product id")
+		}
+	}
+	return s.repo.GetLots(addressId, productUUID)
+}
+
+// UpdateLot corrects the quantity of a lot, as after counting it, and the
+// address total with it.
+func (s *InventoryService) UpdateLot(ctx context.Context, clinicAddressId, lotId string, req dto.LotQuantityRequest) (*models.Lot, error) {
+	addressId, err := uuid.Parse(clinicAddressId)
+	if err != nil {
+		return nil, errors.New("invalid clinic address id")
+	}
+	lotUUID, err := uuid.Parse(lotId)
+	if err != nil {
+		return nil, errors.New("invalid lot id")
+	}
+	if req.Quantity < 0 {
+		return nil, errors.New("quantity cannot be negative")
+	}
+	if req.ReasonCode != "" && !validReasonCode(req.ReasonCode) {
+		return nil, errors.New(reasonCodeError)
+	}
+
+	lot, err := s.repo.GetLotByID(lotUUID)
+	if err != nil {
+		return nil, err
+	}
+	if lot == nil || lot.ClinicAddressId != addressId {
+		return nil, errors.New("lot not found")
+	}
+
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	item, err := s.repo.GetInventoryByAddressAndProduct(addressId, lot.ProductId, tx)
+	if err != nil {
+		return nil, err
+	}
+	if item == nil {
+		return nil, errors.New("inventory not found")
+	}
+	lots, err := s.repo.GetLotsTx(addressId, lot.ProductId, "fefo", tx)
+	if err != nil {
+		return nil, err
+	}
+	current := 0.0
+	for _, locked := range lots {
+		if locked.Id == lot.Id {
+			current = locked.Quantity
+		}
+	}
+	change := req.Quantity - current
+	if change == 0 {
+		return lot, nil
+	}
+
+	if err := s.repo.SetLotQuantityTx(lot.Id, req.Quantity, tx); err != nil {
+		return nil, err
+	}
+	updated, err := s.repo.UpdateInventoryQuantityTx(item.Id, math.Max(item.Quantity+change, 0), tx)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.LowerAlertLevelTx(item.Id, StockSeverity(StockStatus(updated.Quantity, updated.MinQuantity, updated.ReorderLevel)), tx); err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateTransactionTx(&models.InventoryTransaction{
+		Id:              uuid.New(),
+		ClinicAddressId: addressId,
+		ProductId:       lot.ProductId,
+		Quantity:        change,
+		TransactionType: "manual_adjustment",
+		LotId:           &lot.Id,
+		UnitCost:        lot.UnitCost,
+		ReasonCode:      req.ReasonCode,
+		CreatedAt:       time.Now(),
+	}, tx); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return s.repo.GetLotByID(lot.Id)
+}
+
+// GetExpiringLots reports the lots at the clinic that expire within days,
+// and those already expired that are still in stock.
+func (s *InventoryService) GetExpiringLots(clinicId, clinicAddressId string, days int) ([]models.Lot, error) {
+	clinicUUID, err := uuid.Parse(clinicId)
+	if err != nil {
+		return nil, errors.New("invalid clinic id")
+	}
+	addressId := uuid.Nil
+	if clinicAddressId != "" {
+		if addressId, err = uuid.Parse(clinicAddressId); err != nil {
+			return nil, errors.New("invalid clinic address id")
+		}
+	}
+	if days < 0 {
+		return nil, errors.New("days cannot be negative")
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	return s.repo.GetExpiringLots(clinicUUID, addressId, today.AddDate(0, 0, days+1))
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"dental_clinic/internal/modules/inventory/dto"
+	"dental_clinic/internal/modules/inventory/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// ReserveMaterialsTx holds the materials of the clinic service at the clinic
+// address for the appointment. Stock is available when it is not held by other
+// booked appointments. When enforce is set and the clinic's policy is "block",
+// a shortage refuses the booking with an error. Otherwise the materials are
+// reserved even when short, and the shortages are returned as warnings.
+func (s *InventoryService) ReserveMaterialsTx(clinicId, clinicServiceId, clinicAddressId, appointmentId uuid.UUID, enforce bool, tx pgx.Tx) ([]models.MaterialShortage, error) {
+	materials, err := s.repo.GetServiceMaterialsTx(clinicServiceId, tx)
+	if err != nil {
+		return nil, err
+	}
+	if len(materials) == 0 {
+		return nil, nil
+	}
+
+	required := make(map[uuid.UUID]float64)
+	productIds := make([]uuid.UUID, 0)
+	for _, material := range materials {
+		if material.QuantityRequired <= 0 {
+			continue
+		}
+		if _, ok := required[material.ProductId]; !ok {
+			productIds = append(productIds, material.ProductId)
+		}
+		required[material.ProductId] += material.QuantityRequired
+	}
+
+	policy := "warn"
+	if enforce {
+		settings, err := s.repo.GetInventorySettingsTx(clinicId, tx)
+		if err != nil {
+			return nil, err
+		}
+		if settings != nil {
+			policy = settings.ShortagePolicy
+		}
+	}
+
+	shortages := make([]models.MaterialShortage, 0)
+	for _, productId := range productIds {
+		stock, err := s.repo.LockStockTx(clinicAddressId, productId, tx)
+		if err != nil {
+			return nil, err
+		}
+
+		available := stock.Quantity - stock.Reserved
+		if available < required[productId] {
+			if policy == "block" {
+				return nil, fmt.Errorf("not enough %s at this clinic address: %g %s needed, %g available", stock.ProductName, required[productId], stock.ProductUnit, max(available, 0))
+			}
+			shortages = append(shortages, models.MaterialShortage{
+				ProductId:   productId,
+				ProductName: stock.ProductName,
+				ProductUnit: stock.ProductUnit,
+				Required:    required[productId],
+				Available:   max(available, 0),
+			})
+		}
+
+		if err := s.repo.ReserveTx(&models.Reservation{
+			Id:              uuid.New(),
+			AppointmentId:   appointmentId,
+			ClinicAddressId: clinicAddressId,
+			ProductId:       productId,
+			Quantity:        required[productId],
+		}, tx); err != nil {
+			return nil, err
+		}
+	}
+	return shortages, nil
+}
+
+// ReleaseReservationsTx gives the materials of an appointment back, as when
+// it is cancelled. It runs in tx, so they are only released if the change
+// that frees them commits.
+func (s *InventoryService) ReleaseReservationsTx(appointmentId uuid.UUID, tx pgx.Tx) error {
+	return s.repo.ReleaseReservationsTx(appointmentId, tx)
+}
+
+// MoveReservationsTx releases what the appointment held and reserves the
+// materials of its new clinic service and address in tx, together with the
+// change that moves the appointment. A move made by staff is not refused for
+// a shortage.
+func (s *InventoryService) MoveReservationsTx(clinicId, clinicServiceId, clinicAddressId, appointmentId uuid.UUID, tx pgx.Tx) ([]models.MaterialShortage, error) {
+	if err := s.repo.ReleaseReservationsTx(appointmentId, tx); err != nil {
+		return nil, err
+	}
+	return s.ReserveMaterialsTx(clinicId, clinicServiceId, clinicAddressId, appointmentId, false, tx)
+}
+
+// GetInventorySettings returns the clinic's material shortage policy, lot
+// consumption order and valuation method, "warn", "fefo" and
+// "weighted_average" until they are set.
+func (s *InventoryService) GetInventorySettings(clinicId, userId, role string) (*models.InventorySettings, error) {
+	clinicUUID, err := s.managedClinic(clinicId, userId, role)
+	if err != nil {
+		return nil, err
+	}
+	settings, err := s.repo.GetInventorySettings(clinicUUID)
+	if err != nil {
+		return nil, err
+	}
+	if settings == nil {
+		return &models.InventorySettings{ClinicId: clinicUUID, ShortagePolicy: "warn", ConsumptionOrder: "fefo", ValuationMethod: "weighted_average"}, nil
+	}
+	return settings, nil
+}
+
+func (s *InventoryService) UpdateInventorySettings(clinicId, userId, role string, req dto.InventorySettingsRequest) (*models.InventorySettings, error) {
+	settings, err := s.GetInventorySettings(clinicId, userId, role)
+	if err != nil {
+		return nil, err
+	}
+	if req.ShortagePolicy != "" {
+		settings.ShortagePolicy = req.ShortagePolicy
+	}
+	if req.ConsumptionOrder != "" {
+		settings.ConsumptionOrder = req.ConsumptionOrder
+	}
+	if req.ValuationMethod != "" {
+		settings.ValuationMethod = req.ValuationMethod
+	}
+	switch settings.ShortagePolicy {
+	case "warn", "block":
+	default:
+		return nil, errors.New("shortage_policy must be warn or block")
+	}
+	switch settings.ConsumptionOrder {
+	case "fefo", "fifo":
+	default:
+		return nil, errors.New("consumption_order must be fefo or fifo")
+	}
+	switch settings.ValuationMethod {
+	case "weighted_average", "fifo":
+	default:
+		return nil, errors.New("valuation_method must be weighted_average or fifo")
+	}
+
+	if err := s.repo.SaveInventorySettings(settings); err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// managedClinic checks that the user is an admin or an admin of the clinic.
+func (s *InventoryService) managedClinic(clinicId, userId, role string) (uuid.UUID, error) {
+	clinicUUID, err := uuid.Parse(clinicId)
+	if err != nil {
+		return uuid.Nil, errors.New("invalid clinic id")
+	}
+	if role == "admin" {
+		return clinicUUID, nil
+	}
+	if role != "clinic_admin" {
+		return uuid.Nil, errors.New("do not have rights")
+	}
+	userUUID, err := uuid.Parse(userId)
+	if err != nil {
+		return uuid.Nil, errors.New("invalid user id")
+	}
+	isAdmin, err := s.repo.IsClinicAdmin(clinicUUID, userUUID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if !isAdmin {
+		return uuid.Nil, errors.New("do not have rights")
+	}
+	return clinicUUID, nil
+}
@@ -0,0 +1,36 @@
+package services
+
+// Stock statuses of an inventory item, from best to worst.
+const (
+	StockOK         = "ok"
+	StockReorder    = "reorder"
+	StockLow        = "low_stock"
+	StockOutOfStock = "out_of_stock"
+)
+
+var stockSeverity = map[string]int{
+	StockOK:         0,
+	StockReorder:    1,
+	StockLow:        2,
+	StockOutOfStock: 3,
+}
+
+// StockStatus places a quantity against the item's levels: at or below the
+// reorder level it is time to order, below the minimum the stock is low.
+func StockStatus(quantity, minQuantity, reorderLevel float64) string {
+	switch {
+	case quantity <= 0:
+		return StockOutOfStock
+	case quantity < minQuantity:
+		return StockLow
+	case quantity <= reorderLevel:
+		return StockReorder
+	default:
+		return StockOK
+	}
+}
+
+// StockSeverity ranks a status so alerts are only sent when stock gets worse.
+func StockSeverity(status string) int {
+	return stockSeverity[status]
+}
@@ -0,0 +1,304 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"dental_clinic/internal/modules/inventory/dto"
+	"dental_clinic/internal/modules/inventory/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// CreateTransfer sends stock from one address of the clinic to another. The
+// stock taken is what booked appointments do not hold, out of the unexpired
+// lots in the clinic's consumption order, and each lot arrives as the same
+// lot. The transfer stays in transit until it is received unless req.Receive
+// is set.
+func (s *InventoryService) CreateTransfer(ctx context.Context, clinicId, userId, role string, req dto.StockTransferRequest) (*models.StockTransfer, error) {
+	clinicUUID, err := s.managedClinic(clinicId, userId, role)
+	if err != nil {
+		return nil, err
+	}
+
+	fromId, err := uuid.Parse(req.FromClinicAddressId)
+	if err != nil {
+		return nil, errors.New("invalid from clinic address id")
+	}
+	toId, err := uuid.Parse(req.ToClinicAddressId)
+	if err != nil {
+		return nil, errors.New("invalid to clinic address id")
+	}
+	if fromId == toId {
+		return nil, errors.New("stock cannot be transferred to the same address")
+	}
+	fromClinic, err := s.repo.GetAddressClinic(fromId)
+	if err != nil {
+		return nil, err
+	}
+	toClinic, err := s.repo.GetAddressClinic(toId)
+	if err != nil {
+		return nil, err
+	}
+	if fromClinic == uuid.Nil || toClinic == uuid.Nil {
+		return nil, errors.New("clinic address not found")
+	}
+	if fromClinic != toClinic {
+		return nil, errors.New("stock can only be transferred between addresses of the same clinic")
+	}
+	if fromClinic != clinicUUID {
+		return nil, errors.New("clinic address not found")
+	}
+
+	if len(req.Items) == 0 {
+		return nil, errors.New("items are required")
+	}
+	quantities := make(map[uuid.UUID]float64)
+	productIds := make([]uuid.UUID, 0, len(req.Items))
+	for _, item := range req.Items {
+		productId, err := uuid.Parse(item.ProductId)
+		if err != nil {
+			return nil, errors.New("invalid product id")
+		}
+		if item.Quantity <= 0 {
+			return nil, errors.New("quantity must be greater than 0")
+		}
+		if _, ok := quantities[productId]; ok {
+			return nil, errors.New("each product can be transferred once")
+		}
+		quantities[productId] = item.Quantity
+		productIds = append(productIds, productId)
+	}
+
+	transfer := &models.StockTransfer{
+		Id:            uuid.New(),
+		ClinicId:      clinicUUID,
+		FromAddressId: fromId,
+		ToAddressId:   toId,
+		Status:        "in_transit",
+		Notes:         strings.TrimSpace(req.Notes),
+		CreatedAt:     time.Now(),
+	}
+	var userUUID *uuid.UUID
+	if parsed, err := uuid.Parse(userId); err == nil {
+		userUUID = &parsed
+	}
+	transfer.CreatedBy = userUUID
+
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := s.repo.CreateTransferTx(transfer, tx); err != nil {
+		return nil, err
+	}
+
+	order, err := s.repo.GetConsumptionOrderTx(fromId, tx)
+	if err != nil {
+		return nil, err
+	}
+	for _, productId := range productIds {
+		items, err := s.sendStockTx(transfer, productId, quantities[productId], order, tx)
+		if err != nil {
+			return nil, err
+		}
+		transfer.Items = append(transfer.Items, items...)
+	}
+
+	if req.Receive {
+		if err := s.receiveTransferTx(transfer, transfer.ToAddressId, "received", userUUID, tx); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return s.repo.GetTransferByID(transfer.Id)
+}
+
+// sendStockTx takes the quantity of the product out of the sending address
+// lot by lot, recording a transfer_out for each.
+func (s *InventoryService) sendStockTx(transfer *models.StockTransfer, productId uuid.UUID, quantity float64, order string, tx pgx.Tx) ([]models.StockTransferItem, error) {
+	stock, err := s.repo.LockStockTx(transfer.FromAddressId, productId, tx)
+	if err != nil {
+		return nil, err
+	}
+	available := stock.Quantity - stock.Reserved
+	if available < quantity {
+		return nil, fmt.Errorf("not enough %s at the sending address: %g %s needed, %g available", stock.ProductName, quantity, stock.ProductUnit, max(available, 0))
+	}
+
+	lots, err := s.repo.GetLotsTx(transfer.FromAddressId, productId, order, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	items := make([]models.StockTransferItem, 0)
+	remaining := quantity
+	for _, lot := range lots {
+		if remaining <= 0 {
+			break
+		}
+		if lot.ExpiresAt != nil && lot.ExpiresAt.Before(today) {
+			continue
+		}
+		taken := math.Min(lot.Quantity, remaining)
+		if err := s.repo.SetLotQuantityTx(lot.Id, lot.Quantity-taken, tx); err != nil {
+			return nil, err
+		}
+		if err := s.repo.CreateTransactionTx(&models.InventoryTransaction{
+			Id:              uuid.New(),
+			ClinicAddressId: transfer.FromAddressId,
+			ProductId:       productId,
+			Quantity:        -taken,
+			TransactionType: "transfer_out",
+			LotId:           &lot.Id,
+			UnitCost:        lot.UnitCost,
+			StockTransferId: &transfer.Id,
+			CreatedAt:       transfer.CreatedAt,
+		}, tx); err != nil {
+			return nil, err
+		}
+
+		item := models.StockTransferItem{
+			Id:              uuid.New(),
+			StockTransferId: transfer.Id,
+			ProductId:       productId,
+			ProductName:     stock.ProductName,
+			ProductUnit:     stock.ProductUnit,
+			LotNumber:       lot.LotNumber,
+			ExpiresAt:       lot.ExpiresAt,
+			UnitCost:        lot.UnitCost,
+			Quantity:        taken,
+		}
+		if err := s.repo.CreateTransferItemTx(&item, tx); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		remaining -= taken
+	}
+	if remaining > 0 {
+		return nil, fmt.Errorf("not enough unexpired %s at the sending address: %g %s short", stock.ProductName, remaining, stock.ProductUnit)
+	}
+
+	if _, err := s.repo.UpdateInventoryQuantityTx(stock.Id, stock.Quantity-quantity, tx); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// ReceiveTransfer books a transfer in transit into the stock of the
+// receiving address.
+func (s *InventoryService) ReceiveTransfer(ctx context.Context, id, userId, role string) (*models.StockTransfer, error) {
+	return s.closeTransfer(ctx, id, userId, role, "received")
+}
+
+// CancelTransfer returns the stock of a transfer in transit to the sending
+// address.
+func (s *InventoryService) CancelTransfer(ctx context.Context, id, userId, role string) (*models.StockTransfer, error) {
+	return s.closeTransfer(ctx, id, userId, role, "cancelled")
+}
+
+func (s *InventoryService) closeTransfer(ctx context.Context, id, userId, role, status string) (*models.StockTransfer, error) {
+	transfer, err := s.managedTransfer(id, userId, role)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if transfer, err = s.repo.GetTransferForUpdateTx(transfer.Id, tx); err != nil {
+		return nil, err
+	}
+	if transfer == nil {
+		return nil, errors.New("stock transfer not found")
+	}
+	if transfer.Status != "in_transit" {
+		return nil, fmt.Errorf("stock transfer is already %s", transfer.Status)
+	}
+
+	var userUUID *uuid.UUID
+	if parsed, err := uuid.Parse(userId); err == nil {
+		userUUID = &parsed
+	}
+	addressId := transfer.ToAddressId
+	if status == "cancelled" {
+		addressId = transfer.FromAddressId
+	}
+	if err := s.receiveTransferTx(transfer, addressId, status, userUUID, tx); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return s.repo.GetTransferByID(transfer.Id)
+}
+
+// receiveTransferTx puts the items of the transfer into the stock of the
+// address, each into its lot, recording a transfer_in for each, and closes
+// the transfer with the status.
+func (s *InventoryService) receiveTransferTx(transfer *models.StockTransfer, clinicAddressId uuid.UUID, status string, userId *uuid.UUID, tx pgx.Tx) error {
+	for _, item := range transfer.Items {
+		if _, _, err := s.ReceiveStockTx(models.StockReceipt{
+			ClinicAddressId: clinicAddressId,
+			ProductId:       item.ProductId,
+			Quantity:        item.Quantity,
+			LotNumber:       item.LotNumber,
+			ExpiresAt:       item.ExpiresAt,
+			UnitCost:        item.UnitCost,
+			StockTransferId: &transfer.Id,
+		}, tx); err != nil {
+			return err
+		}
+	}
+	return s.repo.SetTransferStatusTx(transfer.Id, status, userId, tx)
+}
+
+func (s *InventoryService) GetTransfers(clinicId, userId, role, status string) ([]models.StockTransfer, error) {
+	clinicUUID, err := s.managedClinic(clinicId, userId, role)
+	if err != nil {
+		return nil, err
+	}
+	switch status {
+	case "", "in_transit", "received", "cancelled":
+	default:
+		return nil, errors.New("status must be in_transit, received or cancelled")
+	}
+	return s.repo.GetTransfers(clinicUUID, status)
+}
+
+func (s *InventoryService) GetTransfer(id, userId, role string) (*models.StockTransfer, error) {
+	return s.managedTransfer(id, userId, role)
+}
+
+func (s *InventoryService) managedTransfer(id, userId, role string) (*models.StockTransfer, error) {
+	transferId, err := uuid.Parse(id)
+	if err != nil {
+		return nil, errors.New("invalid stock transfer id")
+	}
+	transfer, err := s.repo.GetTransferByID(transferId)
+	if err != nil {
+		return nil, err
+	}
+	if transfer == nil {
+		return nil, errors.New("stock transfer not found")
+	}
+	if _, err := s.managedClinic(transfer.ClinicId.String(), userId, role); err != nil {
+		return nil, err
+	}
+	return transfer, nil
+}
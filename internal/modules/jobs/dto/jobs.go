@@ -0,0 +1,83 @@
+package dto
+
+import "encoding/json"
+
+type JobRunResponse struct {
+	Id         string `json:"id"`
+	JobName    string `json:"job_name"`
+	Instance   string `json:"instance"`
+	Status     string `json:"status"`
+	Processed  int    `json:"processed"`
+	Failed     int    `json:"failed"`
+	Error      string `json:"error,omitempty"`
+	StartedAt  string `json:"started_at"`
+	FinishedAt string `json:"finished_at,omitempty"`
+	DurationMs int64  `json:"duration_ms,omitempty"`
+}
+
+type JobRunPage struct {
+	Items []JobRunResponse `json:"items"`
+	Page  int              `json:"page"`
+	Limit int              `json:"limit"`
+	Total int              `json:"total"`
+}
+
+// JobStatsResponse covers the last 24 hours of a job's runs.
+type JobStatsResponse struct {
+	JobName           string  `json:"job_name"`
+	Runs              int     `json:"runs"`
+	FailedRuns        int     `json:"failed_runs"`
+	Processed         int     `json:"processed"`
+	Failed            int     `json:"failed"`
+	AverageDurationMs float64 `json:"average_duration_ms"`
+	LastRunAt         string  `json:"last_run_at,omitempty"`
+	LastStatus        string  `json:"last_status"`
+	LastError         string  `json:"last_error,omitempty"`
+	LastSucceededAt   string  `json:"last_succeeded_at,omitempty"`
+	PendingRetries    int     `json:"pending_retries"`
+	DeadLetters       int     `json:"dead_letters"`
+}
+
+type DeadLetterResponse struct {
+	Id            string `json:"id"`
+	JobName       string `json:"job_name"`
+	ItemId        string `json:"item_id"`
+	Attempts      int    `json:"attempts"`
+	LastError     string `json:"last_error"`
+	FirstFailedAt string `json:"first_failed_at"`
+	DeadAt        string `json:"dead_at"`
+	RequeuedAt    string `json:"requeued_at,omitempty"`
+	RequeuedBy    string `json:"requeued_by,omitempty"`
+}
+
+type QueuedJobResponse struct {
+	Id          string          `json:"id"`
+	Type        string          `json:"type"`
+	Payload     json.RawMessage `json:"payload"`
+	Priority    int             `json:"priority"`
+	Status      string          `json:"status"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"max_attempts"`
+	RunAt       string          `json:"run_at"`
+	LockedBy    string          `json:"locked_by,omitempty"`
+	LockedAt    string          `json:"locked_at,omitempty"`
+	LastError   string          `json:"last_error,omitempty"`
+	CreatedAt   string          `json:"created_at"`
+	FinishedAt  string          `json:"finished_at,omitempty"`
+}
+
+type QueuedJobPage struct {
+	Items []QueuedJobResponse `json:"items"`
+	Page  int                 `json:"page"`
+	Limit int                 `json:"limit"`
+	Total int                 `json:"total"`
+}
+
+type JobScheduleResponse struct {
+	Name      string `json:"name"`
+	Spec      string `json:"spec"`
+	JobType   string `json:"job_type"`
+	Priority  int    `json:"priority"`
+	NextRunAt string `json:"next_run_at"`
+	LastRunAt string `json:"last_run_at,omitempty"`
+}
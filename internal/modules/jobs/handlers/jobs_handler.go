@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"dental_clinic/internal/config"
+	"dental_clinic/internal/modules/jobs/dto"
+	"dental_clinic/internal/modules/jobs/services"
+	"dental_clinic/internal/utils"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+type JobsHandler struct {
+	service *services.JobsService
+	cfg     config.Config
+}
+
+func NewJobsHandler(service *services.JobsService, cfg config.Config) *JobsHandler {
+	return &JobsHandler{service: service, cfg: cfg}
+}
+
+// GetJobRuns godoc
+// @Summary List background job runs
+// @Description Platform admins page through the runs of the background jobs, newest first
+// @Tags Jobs
+// @Security BearerAuth
+// @Produce json
+// @Param job query string false "Job name, like appointment_status"
+// @Param status query string false "running, succeeded or failed"
+// @Param page query int false "Page number, starting at 1"
+// @Param limit query int false "Page size, up to 100"
+// @Success 200 {object} dto.JobRunPage
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /api/jobs/runs [get]
+func (h *JobsHandler) GetJobRuns(w http.ResponseWriter, r *http.Request) {
+	_, role := h.currentUser(r)
+	page, limit := pagination(r)
+	query := r.URL.Query()
+	runs, total, err := h.service.GetRuns(role, query.Get("job"), query.Get("status"), page, limit)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, dto.JobRunPage{
+		Items: services.ToJobRunResponseList(runs),
+		Page:  page,
+		Limit: limit,
+		Total: total,
+	})
+}
+
+// GetJobStats godoc
+// @Summary Get background job metrics
+// @Description Platform admins see, per job, the runs, failures, items processed and average duration over the last 24 hours, the latest run and the items waiting for a retry or given up on
+// @Tags Jobs
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} dto.JobStatsResponse
+// @Failure 403 {object} map[string]string
+// @Router /api/jobs/stats [get]
+func (h *JobsHandler) GetJobStats(w http.ResponseWriter, r *http.Request) {
+	_, role := h.currentUser(r)
+	stats, err := h.service.GetStats(role)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, services.ToJobStatsResponseList(stats))
+}
+
+// GetDeadLetters godoc
+// @Summary List dead letters
+// @Description Platform admins list the items background jobs gave up on after failing them too often. requeued=true lists the ones already handed back.
+// @Tags Jobs
+// @Security BearerAuth
+// @Produce json
+// @Param job query string false "Job name"
+// @Param requeued query bool false "List requeued dead letters instead"
+// @Success 200 {array} dto.DeadLetterResponse
+// @Failure 403 {object} map[string]string
+// @Router /api/jobs/dead-letters [get]
+func (h *JobsHandler) GetDeadLetters(w http.ResponseWriter, r *http.Request) {
+	_, role := h.currentUser(r)
+	query := r.URL.Query()
+	requeued, _ := strconv.ParseBool(query.Get("requeued"))
+	letters, err := h.service.GetDeadLetters(role, query.Get("job"), requeued)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, services.ToDeadLetterResponseList(letters))
+}
+
+// RequeueDeadLetter godoc
+// @Summary Requeue dead letter
+// @Description Hands an item a job gave up on back to the job, which tries it again on its next run
+// @Tags Jobs
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Dead letter ID"
+// @Success 200 {object} dto.DeadLetterResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/jobs/dead-letters/{id}/requeue [post]
+func (h *JobsHandler) RequeueDeadLetter(w http.ResponseWriter, r *http.Request) {
+	userId, role := h.currentUser(r)
+	letter, err := h.service.RequeueDeadLetter(mux.Vars(r)["id"], userId, role)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, services.ToDeadLetterResponse(*letter))
+}
+
+// GetQueuedJobs godoc
+// @Summary List queued jobs
+// @Description Platform admins page through the jobs of the job queue, newest first
+// @Tags Jobs
+// @Security BearerAuth
+// @Produce json
+// @Param type query string false "Job type, like email.send"
+// @Param status query string false "queued, running, succeeded or dead"
+// @Param page query int false "Page number, starting at 1"
+// @Param limit query int false "Page size, up to 100"
+// @Success 200 {object} dto.QueuedJobPage
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /api/jobs/queue [get]
+func (h *JobsHandler) GetQueuedJobs(w http.ResponseWriter, r *http.Request) {
+	_, role := h.currentUser(r)
+	page, limit := pagination(r)
+	query := r.URL.Query()
+	jobs, total, err := h.service.GetQueuedJobs(role, query.Get("type"), query.Get("status"), page, limit)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, dto.QueuedJobPage{
+		Items: services.ToQueuedJobResponseList(jobs),
+		Page:  page,
+		Limit: limit,
+		Total: total,
+	})
+}
+
+// RetryQueuedJob godoc
+// @Summary Retry dead job
+// @Description Queues a job the queue gave up on again with a fresh set of attempts
+// @Tags Jobs
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Job ID"
+// @Success 200 {object} dto.QueuedJobResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/jobs/queue/{id}/retry [post]
+func (h *JobsHandler) RetryQueuedJob(w http.ResponseWriter, r *http.Request) {
+	_, role := h.currentUser(r)
+	job, err := h.service.RetryQueuedJob(mux.Vars(r)["id"], role)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, services.ToQueuedJobResponse(*job))
+}
+
+// GetJobSchedules godoc
+// @Summary List job schedules
+// @Description Platform admins list the cron schedules that queue jobs, with their next and last runs
+// @Tags Jobs
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} dto.JobScheduleResponse
+// @Failure 403 {object} map[string]string
+// @Router /api/jobs/schedules [get]
+func (h *JobsHandler) GetJobSchedules(w http.ResponseWriter, r *http.Request) {
+	_, role := h.currentUser(r)
+	schedules, err := h.service.GetSchedules(role)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, services.ToJobScheduleResponseList(schedules))
+}
+
+func (h *JobsHandler) currentUser(r *http.Request) (string, string) {
+	claims, err := utils.GetClaims(utils.GetToken(r), h.cfg.JWTSecret)
+	if err != nil {
+		return "", ""
+	}
+	userId, _ := claims["user_id"].(string)
+	role, _ := claims["role"].(string)
+	return userId, role
+}
+
+func pagination(r *http.Request) (int, int) {
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit < 1 {
+		limit = defaultPageLimit
+	}
+	if limit > maxPageLimit {
+		limit = maxPageLimit
+	}
+	return page, limit
+}
+
+func errorStatus(err error) int {
+	switch {
+	case err.Error() == "do not have rights":
+		return http.StatusForbidden
+	case strings.HasSuffix(err.Error(), "not found"):
+		return http.StatusNotFound
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+func respondJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+func respondError(w http.ResponseWriter, status int, message string) {
+	respondJSON(w, status, map[string]string{"error": message})
+}
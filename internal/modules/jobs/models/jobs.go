@@ -0,0 +1,80 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// JobRun is one run of a background job. Status is "running", "succeeded"
+// or "failed"; Processed and Failed count the items the run got through.
+type JobRun struct {
+	Id         uuid.UUID
+	JobName    string
+	Instance   string
+	Status     string
+	Processed  int
+	Failed     int
+	Error      string
+	StartedAt  time.Time
+	FinishedAt *time.Time
+}
+
+// JobStats sums up the runs of a job over the last 24 hours along with its
+// latest run and the items it is retrying or gave up on.
+type JobStats struct {
+	JobName           string
+	Runs              int
+	FailedRuns        int
+	Processed         int
+	Failed            int
+	AverageDurationMs float64
+	LastRunAt         *time.Time
+	LastStatus        string
+	LastError         string
+	LastSucceededAt   *time.Time
+	PendingRetries    int
+	DeadLetters       int
+}
+
+// DeadLetter is an item a job gave up on after failing it too often. It is
+// left alone until it is requeued.
+type DeadLetter struct {
+	Id            uuid.UUID
+	JobName       string
+	ItemId        uuid.UUID
+	Attempts      int
+	LastError     string
+	FirstFailedAt time.Time
+	DeadAt        time.Time
+	RequeuedAt    *time.Time
+	RequeuedBy    *uuid.UUID
+}
+
+// QueuedJob is a job of the job queue. Status is "queued", "running",
+// "succeeded" or "dead" once it ran out of attempts.
+type QueuedJob struct {
+	Id          uuid.UUID
+	Type        string
+	Payload     []byte
+	Priority    int
+	Status      string
+	Attempts    int
+	MaxAttempts int
+	RunAt       time.Time
+	LockedBy    string
+	LockedAt    *time.Time
+	LastError   string
+	CreatedAt   time.Time
+	FinishedAt  *time.Time
+}
+
+// JobSchedule is a cron schedule that queues a job whenever it is due.
+type JobSchedule struct {
+	Name      string
+	Spec      string
+	JobType   string
+	Priority  int
+	NextRunAt time.Time
+	LastRunAt *time.Time
+}
@@ -0,0 +1,279 @@
+package repository
+
+import (
+	"context"
+
+	"dental_clinic/internal/modules/jobs/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type JobsRepository interface {
+	GetRuns(jobName, status string, page, limit int) ([]models.JobRun, int, error)
+	GetStats() ([]models.JobStats, error)
+	GetDeadLetters(jobName string, requeued bool) ([]models.DeadLetter, error)
+	GetDeadLetterByID(id uuid.UUID) (*models.DeadLetter, error)
+	RequeueDeadLetter(id uuid.UUID, userId *uuid.UUID) error
+	GetQueuedJobs(jobType, status string, page, limit int) ([]models.QueuedJob, int, error)
+	GetQueuedJobByID(id uuid.UUID) (*models.QueuedJob, error)
+	RetryQueuedJob(id uuid.UUID) error
+	GetSchedules() ([]models.JobSchedule, error)
+}
+
+type jobsRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewJobsRepository(db *pgxpool.Pool) JobsRepository {
+	return &jobsRepo{db: db}
+}
+
+func (r *jobsRepo) GetRuns(jobName, status string, page, limit int) ([]models.JobRun, int, error) {
+	filter := `
+		WHERE ($1 = '' OR job_name = $1)
+			AND ($2 = '' OR status = $2)
+	`
+	var total int
+	if err := r.db.QueryRow(context.Background(), `SELECT COUNT(*) FROM job_runs`+filter, jobName, status).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT id, job_name, instance, status, processed, failed, error, started_at, finished_at
+		FROM job_runs
+	` + filter + `
+		ORDER BY started_at DESC, id
+		LIMIT $3 OFFSET $4
+	`
+	rows, err := r.db.Query(context.Background(), query, jobName, status, limit, (page-1)*limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	runs := make([]models.JobRun, 0)
+	for rows.Next() {
+		var run models.JobRun
+		if err := rows.Scan(&run.Id, &run.JobName, &run.Instance, &run.Status, &run.Processed, &run.Failed, &run.Error, &run.StartedAt, &run.FinishedAt); err != nil {
+			return nil, 0, err
+		}
+		runs = append(runs, run)
+	}
+	return runs, total, rows.Err()
+}
+
+// GetStats covers every job that has run, with counts over the last 24 hours.
+func (r *jobsRepo) GetStats() ([]models.JobStats, error) {
+	query := `
+		SELECT
+			j.job_name,
+			COUNT(r.id)::int,
+			COUNT(r.id) FILTER (WHERE r.status = 'failed')::int,
+			COALESCE(SUM(r.processed), 0)::int,
+			COALESCE(SUM(r.failed), 0)::int,
+			COALESCE(AVG(EXTRACT(EPOCH FROM r.finished_at - r.started_at) * 1000) FILTER (WHERE r.finished_at IS NOT NULL), 0)::float8,
+			latest.started_at,
+			COALESCE(latest.status, ''),
+			COALESCE(latest.error, ''),
+			(SELECT MAX(s.finished_at) FROM job_runs s WHERE s.job_name = j.job_name AND s.status = 'succeeded'),
+			(SELECT COUNT(*) FROM job_item_failures f WHERE f.job_name = j.job_name)::int,
+			(SELECT COUNT(*) FROM job_dead_letters d WHERE d.job_name = j.job_name AND d.requeued_at IS NULL)::int
+		FROM (
+			SELECT job_name FROM job_runs
+			UNION
+			SELECT job_name FROM job_dead_letters WHERE requeued_at IS NULL
+		) j
+		LEFT JOIN job_runs r ON r.job_name = j.job_name
+			AND r.started_at > NOW() - INTERVAL '24 hours'
+		LEFT JOIN LATERAL (
+			SELECT l.started_at, l.status, l.error
+			FROM job_runs l
+			WHERE l.job_name = j.job_name
+			ORDER BY l.started_at DESC
+			LIMIT 1
+		) latest ON true
+		GROUP BY j.job_name, latest.started_at, latest.status, latest.error
+		ORDER BY j.job_name
+	`
+	rows, err := r.db.Query(context.Background(), query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := make([]models.JobStats, 0)
+	for rows.Next() {
+		var s models.JobStats
+		if err := rows.Scan(&s.JobName, &s.Runs, &s.FailedRuns, &s.Processed, &s.Failed, &s.AverageDurationMs,
+			&s.LastRunAt, &s.LastStatus, &s.LastError, &s.LastSucceededAt, &s.PendingRetries, &s.DeadLetters); err != nil {
+			return nil, err
+		}
+		stats = append(stats, s)
+	}
+	return stats, rows.Err()
+}
+
+const deadLetterColumns = `id, job_name, item_id, attempts, last_error, first_failed_at, dead_at, requeued_at, requeued_by`
+
+func scanDeadLetter(row pgx.Row) (models.DeadLetter, error) {
+	var d models.DeadLetter
+	err := row.Scan(&d.Id, &d.JobName, &d.ItemId, &d.Attempts, &d.LastError, &d.FirstFailedAt, &d.DeadAt, &d.RequeuedAt, &d.RequeuedBy)
+	return d, err
+}
+
+// GetDeadLetters lists the items jobs gave up on, or the ones already
+// requeued when requeued is set.
+func (r *jobsRepo) GetDeadLetters(jobName string, requeued bool) ([]models.DeadLetter, error) {
+	query := `
+		SELECT ` + deadLetterColumns + `
+		FROM job_dead_letters
+		WHERE ($1 = '' OR job_name = $1)
+			AND (requeued_at IS NOT NULL) = $2
+		ORDER BY dead_at DESC, id
+	`
+	rows, err := r.db.Query(context.Background(), query, jobName, requeued)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	letters := make([]models.DeadLetter, 0)
+	for rows.Next() {
+		letter, err := scanDeadLetter(rows)
+		if err != nil {
+			return nil, err
+		}
+		letters = append(letters, letter)
+	}
+	return letters, rows.Err()
+}
+
+func (r *jobsRepo) GetDeadLetterByID(id uuid.UUID) (*models.DeadLetter, error) {
+	query := `SELECT ` + deadLetterColumns + ` FROM job_dead_letters WHERE id = $1`
+	letter, err := scanDeadLetter(r.db.QueryRow(context.Background(), query, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &letter, nil
+}
+
+// RequeueDeadLetter hands the item back to its job, which tries it again on
+// its next run with a fresh set of attempts.
+func (r *jobsRepo) RequeueDeadLetter(id uuid.UUID, userId *uuid.UUID) error {
+	query := `
+		UPDATE job_dead_letters
+		SET requeued_at = NOW(), requeued_by = $2
+		WHERE id = $1
+			AND requeued_at IS NULL
+	`
+	result, err := r.db.Exec(context.Background(), query, id, userId)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+const queuedJobColumns = `id, type, payload, priority, status, attempts, max_attempts, run_at, locked_by, locked_at, last_error, created_at, finished_at`
+
+func scanQueuedJob(row pgx.Row) (models.QueuedJob, error) {
+	var j models.QueuedJob
+	err := row.Scan(&j.Id, &j.Type, &j.Payload, &j.Priority, &j.Status, &j.Attempts, &j.MaxAttempts, &j.RunAt,
+		&j.LockedBy, &j.LockedAt, &j.LastError, &j.CreatedAt, &j.FinishedAt)
+	return j, err
+}
+
+func (r *jobsRepo) GetQueuedJobs(jobType, status string, page, limit int) ([]models.QueuedJob, int, error) {
+	filter := `
+		WHERE ($1 = '' OR type = $1)
+			AND ($2 = '' OR status = $2)
+	`
+	var total int
+	if err := r.db.QueryRow(context.Background(), `SELECT COUNT(*) FROM queued_jobs`+filter, jobType, status).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT ` + queuedJobColumns + `
+		FROM queued_jobs
+	` + filter + `
+		ORDER BY created_at DESC, id
+		LIMIT $3 OFFSET $4
+	`
+	rows, err := r.db.Query(context.Background(), query, jobType, status, limit, (page-1)*limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	jobs := make([]models.QueuedJob, 0)
+	for rows.Next() {
+		job, err := scanQueuedJob(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, total, rows.Err()
+}
+
+func (r *jobsRepo) GetQueuedJobByID(id uuid.UUID) (*models.QueuedJob, error) {
+	query := `SELECT ` + queuedJobColumns + ` FROM queued_jobs WHERE id = $1`
+	job, err := scanQueuedJob(r.db.QueryRow(context.Background(), query, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+// RetryQueuedJob queues a dead job again right away with a fresh set of
+// attempts.
+func (r *jobsRepo) RetryQueuedJob(id uuid.UUID) error {
+	query := `
+		UPDATE queued_jobs
+		SET status = 'queued', attempts = 0, run_at = NOW(), finished_at = NULL
+		WHERE id = $1
+			AND status = 'dead'
+	`
+	result, err := r.db.Exec(context.Background(), query, id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *jobsRepo) GetSchedules() ([]models.JobSchedule, error) {
+	query := `
+		SELECT name, spec, job_type, priority, next_run_at, last_run_at
+		FROM job_schedules
+		ORDER BY name
+	`
+	rows, err := r.db.Query(context.Background(), query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	schedules := make([]models.JobSchedule, 0)
+	for rows.Next() {
+		var s models.JobSchedule
+		if err := rows.Scan(&s.Name, &s.Spec, &s.JobType, &s.Priority, &s.NextRunAt, &s.LastRunAt); err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, s)
+	}
+	return schedules, rows.Err()
+}
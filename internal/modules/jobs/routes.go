@@ -0,0 +1,25 @@
+package jobs
+
+import (
+	"dental_clinic/internal/config"
+	"dental_clinic/internal/modules/jobs/handlers"
+	"dental_clinic/internal/modules/jobs/repository"
+	"dental_clinic/internal/modules/jobs/services"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func RegisterPrivateRoutes(r *mux.Router, db *pgxpool.Pool, cfg *config.Config) {
+	repo := repository.NewJobsRepository(db)
+	service := services.NewJobsService(repo)
+	handler := handlers.NewJobsHandler(service, *cfg)
+
+	r.HandleFunc("/jobs/runs", handler.GetJobRuns).Methods("GET")
+	r.HandleFunc("/jobs/stats", handler.GetJobStats).Methods("GET")
+	r.HandleFunc("/jobs/dead-letters", handler.GetDeadLetters).Methods("GET")
+	r.HandleFunc("/jobs/dead-letters/{id}/requeue", handler.RequeueDeadLetter).Methods("POST")
+	r.HandleFunc("/jobs/queue", handler.GetQueuedJobs).Methods("GET")
+	r.HandleFunc("/jobs/queue/{id}/retry", handler.RetryQueuedJob).Methods("POST")
+	r.HandleFunc("/jobs/schedules", handler.GetJobSchedules).Methods("GET")
+}
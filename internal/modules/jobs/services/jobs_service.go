@@ -0,0 +1,251 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"dental_clinic/internal/modules/jobs/dto"
+	"dental_clinic/internal/modules/jobs/models"
+	"dental_clinic/internal/modules/jobs/repository"
+
+	"github.com/google/uuid"
+)
+
+// JobsService lets platform admins look into the background jobs.
+type JobsService struct {
+	repo repository.JobsRepository
+}
+
+func NewJobsService(repo repository.JobsRepository) *JobsService {
+	return &JobsService{repo: repo}
+}
+
+func (s *JobsService) GetRuns(role, jobName, status string, page, limit int) ([]models.JobRun, int, error) {
+	if role != "admin" {
+		return nil, 0, errors.New("do not have rights")
+	}
+	switch status {
+	case "", "running", "succeeded", "failed":
+	default:
+		return nil, 0, errors.New("status must be running, succeeded or failed")
+	}
+	return s.repo.GetRuns(jobName, status, page, limit)
+}
+
+func (s *JobsService) GetStats(role string) ([]models.JobStats, error) {
+	if role != "admin" {
+		return nil, errors.New("do not have rights")
+	}
+	return s.repo.GetStats()
+}
+
+func (s *JobsService) GetDeadLetters(role, jobName string, requeued bool) ([]models.DeadLetter, error) {
+	if role != "admin" {
+		return nil, errors.New("do not have rights")
+	}
+	return s.repo.GetDeadLetters(jobName, requeued)
+}
+
+// RequeueDeadLetter hands an item a job gave up on back to the job, as after
+// fixing the data it failed on.
+func (s *JobsService) RequeueDeadLetter(id, userId, role string) (*models.DeadLetter, error) {
+	if role != "admin" {
+		return nil, errors.New("do not have rights")
+	}
+	letterId, err := uuid.Parse(id)
+	if err != nil {
+		return nil, errors.New("invalid dead letter id")
+	}
+	letter, err := s.repo.GetDeadLetterByID(letterId)
+	if err != nil {
+		return nil, err
+	}
+	if letter == nil {
+		return nil, errors.New("dead letter not found")
+	}
+	if letter.RequeuedAt != nil {
+		return nil, errors.New("dead letter is already requeued")
+	}
+
+	var userUUID *uuid.UUID
+	if parsed, err := uuid.Parse(userId); err == nil {
+		userUUID = &parsed
+	}
+	if err := s.repo.RequeueDeadLetter(letterId, userUUID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetDeadLetterByID(letterId)
+}
+
+func (s *JobsService) GetQueuedJobs(role, jobType, status string, page, limit int) ([]models.QueuedJob, int, error) {
+	if role != "admin" {
+		return nil, 0, errors.New("do not have rights")
+	}
+	switch status {
+	case "", "queued", "running", "succeeded", "dead":
+	default:
+		return nil, 0, errors.New("status must be queued, running, succeeded or dead")
+	}
+	return s.repo.GetQueuedJobs(jobType, status, page, limit)
+}
+
+// RetryQueuedJob queues a job the queue gave up on again, as after fixing
+// what it failed on.
+func (s *JobsService) RetryQueuedJob(id, role string) (*models.QueuedJob, error) {
+	if role != "admin" {
+		return nil, errors.New("do not have rights")
+	}
+	jobId, err := uuid.Parse(id)
+	if err != nil {
+		return nil, errors.New("invalid job id")
+	}
+	job, err := s.repo.GetQueuedJobByID(jobId)
+	if err != nil {
+		return nil, err
+	}
+	if job == nil {
+		return nil, errors.New("job not found")
+	}
+	if job.Status != "dead" {
+		return nil, errors.New("only dead jobs can be retried")
+	}
+	if err := s.repo.RetryQueuedJob(jobId); err != nil {
+		return nil, err
+	}
+	return s.repo.GetQueuedJobByID(jobId)
+}
+
+func (s *JobsService) GetSchedules(role string) ([]models.JobSchedule, error) {
+	if role != "admin" {
+		return nil, errors.New("do not have rights")
+	}
+	return s.repo.GetSchedules()
+}
+
+func ToJobRunResponse(run models.JobRun) dto.JobRunResponse {
+	response := dto.JobRunResponse{
+		Id:        run.Id.String(),
+		JobName:   run.JobName,
+		Instance:  run.Instance,
+		Status:    run.Status,
+		Processed: run.Processed,
+		Failed:    run.Failed,
+		Error:     run.Error,
+		StartedAt: run.StartedAt.Format(time.RFC3339),
+	}
+	if run.FinishedAt != nil {
+		response.FinishedAt = run.FinishedAt.Format(time.RFC3339)
+		response.DurationMs = run.FinishedAt.Sub(run.StartedAt).Milliseconds()
+	}
+	return response
+}
+
+func ToJobRunResponseList(runs []models.JobRun) []dto.JobRunResponse {
+	result := make([]dto.JobRunResponse, 0, len(runs))
+	for _, run := range runs {
+		result = append(result, ToJobRunResponse(run))
+	}
+	return result
+}
+
+func ToJobStatsResponseList(stats []models.JobStats) []dto.JobStatsResponse {
+	result := make([]dto.JobStatsResponse, 0, len(stats))
+	for _, s := range stats {
+		response := dto.JobStatsResponse{
+			JobName:           s.JobName,
+			Runs:              s.Runs,
+			FailedRuns:        s.FailedRuns,
+			Processed:         s.Processed,
+			Failed:            s.Failed,
+			AverageDurationMs: s.AverageDurationMs,
+			LastStatus:        s.LastStatus,
+			LastError:         s.LastError,
+			PendingRetries:    s.PendingRetries,
+			DeadLetters:       s.DeadLetters,
+		}
+		if s.LastRunAt != nil {
+			response.LastRunAt = s.LastRunAt.Format(time.RFC3339)
+		}
+		if s.LastSucceededAt != nil {
+			response.LastSucceededAt = s.LastSucceededAt.Format(time.RFC3339)
+		}
+		result = append(result, response)
+	}
+	return result
+}
+
+func ToDeadLetterResponse(letter models.DeadLetter) dto.DeadLetterResponse {
+	response := dto.DeadLetterResponse{
+		Id:            letter.Id.String(),
+		JobName:       letter.JobName,
+		ItemId:        letter.ItemId.String(),
+		Attempts:      letter.Attempts,
+		LastError:     letter.LastError,
+		FirstFailedAt: letter.FirstFailedAt.Format(time.RFC3339),
+		DeadAt:        letter.DeadAt.Format(time.RFC3339),
+	}
+	if letter.RequeuedAt != nil {
+		response.RequeuedAt = letter.RequeuedAt.Format(time.RFC3339)
+	}
+	if letter.RequeuedBy != nil {
+		response.RequeuedBy = letter.RequeuedBy.String()
+	}
+	return response
+}
+
+func ToDeadLetterResponseList(letters []models.DeadLetter) []dto.DeadLetterResponse {
+	result := make([]dto.DeadLetterResponse, 0, len(letters))
+	for _, letter := range letters {
+		result = append(result, ToDeadLetterResponse(letter))
+	}
+	return result
+}
+
+func ToQueuedJobResponse(job models.QueuedJob) dto.QueuedJobResponse {
+	response := dto.QueuedJobResponse{
+		Id:          job.Id.String(),
+		Type:        job.Type,
+		Payload:     job.Payload,
+		Priority:    job.Priority,
+		Status:      job.Status,
+		Attempts:    job.Attempts,
+		MaxAttempts: job.MaxAttempts,
+		RunAt:       job.RunAt.Format(time.RFC3339),
+		LockedBy:    job.LockedBy,
+		LastError:   job.LastError,
+		CreatedAt:   job.CreatedAt.Format(time.RFC3339),
+	}
+	if job.LockedAt != nil {
+		response.LockedAt = job.LockedAt.Format(time.RFC3339)
+	}
+	if job.FinishedAt != nil {
+		response.FinishedAt = job.FinishedAt.Format(time.RFC3339)
+	}
+	return response
+}
+
+func ToQueuedJobResponseList(jobs []models.QueuedJob) []dto.QueuedJobResponse {
+	result := make([]dto.QueuedJobResponse, 0, len(jobs))
+	for _, job := range jobs {
+		result = append(result, ToQueuedJobResponse(job))
+	}
+	return result
+}
+
+func ToJobScheduleResponseList(schedules []models.JobSchedule) []dto.JobScheduleResponse {
+	result := make([]dto.JobScheduleResponse, 0, len(schedules))
+	for _, s := range schedules {
+		response := dto.JobScheduleResponse{
+			Name:      s.Name,
+			Spec:      s.Spec,
+			JobType:   s.JobType,
+			Priority:  s.Priority,
+			NextRunAt: s.NextRunAt.Format(time.RFC3339),
+		}
+		if s.LastRunAt != nil {
+			response.LastRunAt = s.LastRunAt.Format(time.RFC3339)
+		}
+		result = append(result, response)
+	}
+	return result
+}
@@ -0,0 +1,531 @@
+// Package dicom reads the parts of a DICOM (Part 10) file the clinic needs:
+// study/series identifiers, modality, patient identity and the first frame of
+// pixel data for building a PNG preview. It is not a general purpose DICOM
+// toolkit and only understands little endian transfer syntaxes.
+package dicom
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	preambleSize = 128
+	magic        = "DICM"
+
+	undefinedLength = 0xFFFFFFFF
+
+	// maxElementSize protects against corrupt lengths on non-pixel elements.
+	maxElementSize = 64 << 20
+	// maxPixelDataSize caps the pixel data, native or all fragments together.
+	maxPixelDataSize = 256 << 20
+	// maxSequenceDepth caps how deeply undefined length sequences may nest.
+	maxSequenceDepth = 32
+)
+
+const (
+	TransferSyntaxImplicitLE = "1.2.840.10008.1.2"
+	TransferSyntaxExplicitLE = "1.2.840.10008.1.2.1"
+	TransferSyntaxExplicitBE = "1.2.840.10008.1.2.2"
+	TransferSyntaxDeflatedLE = "1.2.840.10008.1.2.1.99"
+	TransferSyntaxJPEGBase   = "1.2.840.10008.1.2.4.50"
+)
+
+var (
+	ErrNotDICOM              = errors.New("not a DICOM file")
+	ErrUnsupportedSyntax     = errors.New("unsupported DICOM transfer syntax")
+	ErrNoPixelData           = errors.New("DICOM file has no pixel data")
+	ErrUnsupportedPixelData  = errors.New("unsupported DICOM pixel data")
+	ErrImageTooLarge         = errors.New("DICOM image is too large")
+	errUnexpectedDelimitator = errors.New("unexpected delimitation item")
+	errSequenceTooDeep       = errors.New("DICOM sequences are nested too deeply")
+)
+
+type tag struct {
+	group, element uint16
+}
+
+var (
+	tagTransferSyntax      = tag{0x0002, 0x0010}
+	tagStudyDate           = tag{0x0008, 0x0020}
+	tagModality            = tag{0x0008, 0x0060}
+	tagStudyDescription    = tag{0x0008, 0x1030}
+	tagSOPInstanceUID      = tag{0x0008, 0x0018}
+	tagPatientName         = tag{0x0010, 0x0010}
+	tagPatientID           = tag{0x0010, 0x0020}
+	tagStudyInstanceUID    = tag{0x0020, 0x000D}
+	tagSeriesInstanceUID   = tag{0x0020, 0x000E}
+	tagInstanceNumber      = tag{0x0020, 0x0013}
+	tagSamplesPerPixel     = tag{0x0028, 0x0002}
+	tagPhotometric         = tag{0x0028, 0x0004}
+	tagPlanarConfiguration = tag{0x0028, 0x0006}
+	tagNumberOfFrames      = tag{0x0028, 0x0008}
+	tagRows                = tag{0x0028, 0x0010}
+	tagColumns             = tag{0x0028, 0x0011}
+	tagBitsAllocated       = tag{0x0028, 0x0100}
+	tagBitsStored          = tag{0x0028, 0x0101}
+	tagPixelRepresentation = tag{0x0028, 0x0103}
+	tagWindowCenter        = tag{0x0028, 0x1050}
+	tagWindowWidth         = tag{0x0028, 0x1051}
+	tagRescaleIntercept    = tag{0x0028, 0x1052}
+	tagRescaleSlope        = tag{0x0028, 0x1053}
+	tagPixelData           = tag{0x7FE0, 0x0010}
+
+	tagItem              = tag{0xFFFE, 0xE000}
+	tagItemDelimitation  = tag{0xFFFE, 0xE00D}
+	tagSequenceDelimiter = tag{0xFFFE, 0xE0DD}
+)
+
+// implicitVR lists the VRs of the elements we read when the file uses the
+// implicit VR transfer syntax. Everything else is skipped by length.
+var implicitVR = map[tag]string{
+	tagStudyDate:           "DA",
+	tagModality:            "CS",
+	tagStudyDescription:    "LO",
+	tagSOPInstanceUID:      "UI",
+	tagPatientName:         "PN",
+	tagPatientID:           "LO",
+	tagStudyInstanceUID:    "UI",
+	tagSeriesInstanceUID:   "UI",
+	tagInstanceNumber:      "IS",
+	tagSamplesPerPixel:     "US",
+	tagPhotometric:         "CS",
+	tagPlanarConfiguration: "US",
+	tagNumberOfFrames:      "IS",
+	tagRows:                "US",
+	tagColumns:             "US",
+	tagBitsAllocated:       "US",
+	tagBitsStored:          "US",
+	tagPixelRepresentation: "US",
+	tagWindowCenter:        "DS",
+	tagWindowWidth:         "DS",
+	tagRescaleIntercept:    "DS",
+	tagRescaleSlope:        "DS",
+	tagPixelData:           "OW",
+}
+
+// Metadata is the subset of DICOM attributes stored alongside a medical file.
+type Metadata struct {
+	TransferSyntax    string
+	Modality          string
+	StudyDate         time.Time
+	StudyDescription  string
+	StudyInstanceUID  string
+	SeriesInstanceUID string
+	SOPInstanceUID    string
+	InstanceNumber    int
+	PatientID         string
+	PatientName       string
+	Rows              int
+	Columns           int
+}
+
+// File is a parsed DICOM object. Pixel data holds the raw value of
+// (7FE0,0010); for encapsulated syntaxes Fragments holds the frame fragments.
+type File struct {
+	Metadata
+
+	samplesPerPixel     int
+	photometric         string
+	planarConfiguration int
+	numberOfFrames      int
+	bitsAllocated       int
+	bitsStored          int
+	pixelRepresentation int
+	windowCenter        float64
+	windowWidth         float64
+	hasWindow           bool
+	rescaleIntercept    float64
+	rescaleSlope        float64
+
+	pixelData []byte
+	fragments [][]byte
+}
+
+// IsDICOM reports whether the header carries the DICM marker after the
+// 128-byte preamble.
+func IsDICOM(header []byte) bool {
+	return len(header) >= preambleSize+len(magic) &&
+		string(header[preambleSize:preambleSize+len(magic)]) == magic
+}
+
+// Parse reads a DICOM Part 10 stream of size bytes up to and including the
+// pixel data. Lengths that run past the end of the stream are rejected
+// before anything is allocated for them.
+func Parse(r io.Reader, size int64) (*File, error) {
+	src := &countingReader{r: r}
+	br := bufio.NewReader(src)
+
+	header := make([]byte, preambleSize+len(magic))
+	if _, err := io.ReadFull(br, header); err != nil || !IsDICOM(header) {
+		return nil, ErrNotDICOM
+	}
+
+	f := &File{rescaleSlope: 1, samplesPerPixel: 1, numberOfFrames: 1}
+
+	// File meta information is always explicit VR little endian.
+	p := &parser{r: br, src: src, size: size, explicit: true}
+	for {
+		next, err := br.Peek(2)
+		if err != nil {
+			return nil, ErrNotDICOM
+		}
+		if binary.LittleEndian.Uint16(next) != 0x0002 {
+			break
+		}
+		if _, err := p.readElement(f); err != nil {
+			return nil, err
+		}
+	}
+
+	switch f.TransferSyntax {
+	case TransferSyntaxImplicitLE:
+		p.explicit = false
+	case TransferSyntaxExplicitBE, TransferSyntaxDeflatedLE:
+		return nil, ErrUnsupportedSyntax
+	case "":
+		// Some writers omit the meta header value; implicit VR is the default.
+		f.TransferSyntax = TransferSyntaxImplicitLE
+		p.explicit = false
+	}
+
+	for {
+		done, err := p.readElement(f)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if done {
+			break
+		}
+	}
+
+	return f, nil
+}
+
+type parser struct {
+	r        *bufio.Reader
+	src      *countingReader
+	size     int64
+	explicit bool
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(b []byte) (int, error) {
+	n, err := c.r.Read(b)
+	c.n += int64(n)
+	return n, err
+}
+
+// remaining is how many bytes of the stream have not been consumed yet.
+func (p *parser) remaining() int64 {
+	return p.size - (p.src.n - int64(p.r.Buffered()))
+}
+
+// checkLength rejects a value longer than limit or than what is left of the
+// stream, so a corrupt or crafted length cannot make us allocate it.
+func (p *parser) checkLength(t tag, length uint32, limit int64) error {
+	if int64(length) > limit || int64(length) > p.remaining() {
+		return fmt.Errorf("dicom: element %04X,%04X is too large", t.group, t.element)
+	}
+	return nil
+}
+
+func (p *parser) readTag() (tag, error) {
+	var buf [4]byte
+	if _, err := io.ReadFull(p.r, buf[:]); err != nil {
+		return tag{}, err
+	}
+	return tag{
+		group:   binary.LittleEndian.Uint16(buf[0:2]),
+		element: binary.LittleEndian.Uint16(buf[2:4]),
+	}, nil
+}
+
+func (p *parser) readUint32() (uint32, error) {
+	var buf [4]byte
+	if _, err := io.ReadFull(p.r, buf[:]); err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint32(buf[:]), nil
+}
+
+// readHeader reads the VR and value length following a tag.
+func (p *parser) readHeader(t tag) (string, uint32, error) {
+	// Items and delimiters never carry a VR.
+	if t.group == 0xFFFE || !p.explicit && t.group != 0x0002 {
+		length, err := p.readUint32()
+		return implicitVR[t], length, err
+	}
+
+	var buf [4]byte
+	if _, err := io.ReadFull(p.r, buf[:]); err != nil {
+		return "", 0, err
+	}
+	vr := string(buf[0:2])
+	switch vr {
+	case "OB", "OD", "OF", "OL", "OV", "OW", "SQ", "SV", "UC", "UN", "UR", "UT", "UV":
+		length, err := p.readUint32()
+		return vr, length, err
+	default:
+		return vr, uint32(binary.LittleEndian.Uint16(buf[2:4])), nil
+	}
+}
+
+// readElement consumes one data element, storing the values we care about.
+// It returns true once pixel data has been read.
+func (p *parser) readElement(f *File) (bool, error) {
+	t, err := p.readTag()
+	if err != nil {
+		return false, err
+	}
+	vr, length, err := p.readHeader(t)
+	if err != nil {
+		return false, unexpected(err)
+	}
+
+	if t == tagPixelData {
+		if length == undefinedLength {
+			f.fragments, err = p.readFragments()
+			return true, err
+		}
+		if err := p.checkLength(t, length, maxPixelDataSize); err != nil {
+			return false, err
+		}
+		f.pixelData = make([]byte, length)
+		if _, err := io.ReadFull(p.r, f.pixelData); err != nil {
+			return false, unexpected(err)
+		}
+		return true, nil
+	}
+
+	if vr == "SQ" || length == undefinedLength {
+		return false, p.skipSequence(length, 1)
+	}
+
+	if _, wanted := implicitVR[t]; !wanted && t != tagTransferSyntax {
+		return false, p.skip(length)
+	}
+	if err := p.checkLength(t, length, maxElementSize); err != nil {
+		return false, err
+	}
+
+	value := make([]byte, length)
+	if _, err := io.ReadFull(p.r, value); err != nil {
+		return false, unexpected(err)
+	}
+	f.set(t, vr, value)
+	return false, nil
+}
+
+func (p *parser) skip(length uint32) error {
+	_, err := p.r.Discard(int(length))
+	return unexpected(err)
+}
+
+// skipSequence skips a sequence value, walking nested items when the length
+// is undefined. depth counts the sequences it is nested in, itself included.
+func (p *parser) skipSequence(length uint32, depth int) error {
+	if length != undefinedLength {
+		return p.skip(length)
+	}
+	if depth > maxSequenceDepth {
+		return errSequenceTooDeep
+	}
+	for {
+		t, err := p.readTag()
+		if err != nil {
+			return unexpected(err)
+		}
+		itemLength, err := p.readUint32()
+		if err != nil {
+			return unexpected(err)
+		}
+		switch t {
+		case tagSequenceDelimiter:
+			return nil
+		case tagItem:
+			if itemLength != undefinedLength {
+				if err := p.skip(itemLength); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := p.skipItem(depth); err != nil {
+				return err
+			}
+		default:
+			return errUnexpectedDelimitator
+		}
+	}
+}
+
+// skipItem skips the elements of an item with undefined length inside a
+// sequence nested depth deep.
+func (p *parser) skipItem(depth int) error {
+	for {
+		t, err := p.readTag()
+		if err != nil {
+			return unexpected(err)
+		}
+		if t == tagItemDelimitation {
+			_, err := p.readUint32()
+			return unexpected(err)
+		}
+		vr, length, err := p.readHeader(t)
+		if err != nil {
+			return unexpected(err)
+		}
+		if vr == "SQ" || length == undefinedLength {
+			if err := p.skipSequence(length, depth+1); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := p.skip(length); err != nil {
+			return err
+		}
+	}
+}
+
+// readFragments reads encapsulated pixel data. The basic offset table (the
+// first item) is dropped.
+func (p *parser) readFragments() ([][]byte, error) {
+	fragments := make([][]byte, 0)
+	first := true
+	var total int64
+	for {
+		t, err := p.readTag()
+		if err != nil {
+			return nil, unexpected(err)
+		}
+		length, err := p.readUint32()
+		if err != nil {
+			return nil, unexpected(err)
+		}
+		if t == tagSequenceDelimiter {
+			return fragments, nil
+		}
+		if t != tagItem || length == undefinedLength {
+			return nil, errUnexpectedDelimitator
+		}
+		if err := p.checkLength(tagPixelData, length, maxPixelDataSize-total); err != nil {
+			return nil, err
+		}
+		total += int64(length)
+		data := make([]byte, length)
+		if _, err := io.ReadFull(p.r, data); err != nil {
+			return nil, unexpected(err)
+		}
+		if first {
+			first = false
+			continue
+		}
+		fragments = append(fragments, data)
+	}
+}
+
+func (f *File) set(t tag, vr string, value []byte) {
+	switch t {
+	case tagTransferSyntax:
+		f.TransferSyntax = text(value)
+	case tagModality:
+		f.Modality = text(value)
+	case tagStudyDate:
+		if d, err := time.Parse("20060102", text(value)); err == nil {
+			f.StudyDate = d
+		}
+	case tagStudyDescription:
+		f.StudyDescription = text(value)
+	case tagSOPInstanceUID:
+		f.SOPInstanceUID = text(value)
+	case tagPatientName:
+		f.PatientName = strings.TrimSpace(strings.ReplaceAll(text(value), "^", " "))
+	case tagPatientID:
+		f.PatientID = text(value)
+	case tagStudyInstanceUID:
+		f.StudyInstanceUID = text(value)
+	case tagSeriesInstanceUID:
+		f.SeriesInstanceUID = text(value)
+	case tagInstanceNumber:
+		f.InstanceNumber, _ = strconv.Atoi(text(value))
+	case tagNumberOfFrames:
+		if n, err := strconv.Atoi(text(value)); err == nil && n > 0 {
+			f.numberOfFrames = n
+		}
+	case tagPhotometric:
+		f.photometric = text(value)
+	case tagSamplesPerPixel:
+		f.samplesPerPixel = uint16Value(vr, value)
+	case tagPlanarConfiguration:
+		f.planarConfiguration = uint16Value(vr, value)
+	case tagRows:
+		f.Rows = uint16Value(vr, value)
+	case tagColumns:
+		f.Columns = uint16Value(vr, value)
+	case tagBitsAllocated:
+		f.bitsAllocated = uint16Value(vr, value)
+	case tagBitsStored:
+		f.bitsStored = uint16Value(vr, value)
+	case tagPixelRepresentation:
+		f.pixelRepresentation = uint16Value(vr, value)
+	case tagWindowCenter:
+		if v, ok := decimal(value); ok {
+			f.windowCenter = v
+			f.hasWindow = true
+		}
+	case tagWindowWidth:
+		if v, ok := decimal(value); ok && v > 0 {
+			f.windowWidth = v
+		} else {
+			f.hasWindow = false
+		}
+	case tagRescaleIntercept:
+		f.rescaleIntercept, _ = decimal(value)
+	case tagRescaleSlope:
+		if v, ok := decimal(value); ok && v != 0 {
+			f.rescaleSlope = v
+		}
+	}
+}
+
+func text(value []byte) string {
+	return strings.TrimRight(string(bytes.TrimRight(value, "\x00")), " ")
+}
+
+func uint16Value(vr string, value []byte) int {
+	if vr != "US" && vr != "" || len(value) < 2 {
+		return 0
+	}
+	return int(binary.LittleEndian.Uint16(value))
+}
+
+// decimal parses the first value of a (possibly multi-valued) DS element.
+func decimal(value []byte) (float64, bool) {
+	s := text(value)
+	if i := strings.IndexByte(s, '\\'); i >= 0 {
+		s = s[:i]
+	}
+	v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	return v, err == nil
+}
+
+func unexpected(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
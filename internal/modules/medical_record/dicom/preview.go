@@ -0,0 +1,157 @@
+package dicom
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"math"
+
+	"dental_clinic/internal/upload"
+)
+
+// Preview renders the first frame of the file as an 8-bit image. Native
+// grayscale and RGB pixel data and baseline JPEG are supported. Images with
+// more pixels than an uploaded image may have are not decoded.
+func (f *File) Preview() (image.Image, error) {
+	if len(f.fragments) > 0 {
+		if f.TransferSyntax != TransferSyntaxJPEGBase {
+			return nil, ErrUnsupportedPixelData
+		}
+		data := bytes.Join(f.fragments, nil)
+		config, err := jpeg.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		if int64(config.Width)*int64(config.Height) > upload.MaxImagePixels {
+			return nil, ErrImageTooLarge
+		}
+		return jpeg.Decode(bytes.NewReader(data))
+	}
+	if len(f.pixelData) == 0 {
+		return nil, ErrNoPixelData
+	}
+	if f.Rows == 0 || f.Columns == 0 {
+		return nil, ErrUnsupportedPixelData
+	}
+	if int64(f.Rows)*int64(f.Columns) > upload.MaxImagePixels {
+		return nil, ErrImageTooLarge
+	}
+
+	switch {
+	case f.samplesPerPixel == 1 && (f.bitsAllocated == 8 || f.bitsAllocated == 16):
+		return f.grayscale()
+	case f.samplesPerPixel == 3 && f.bitsAllocated == 8:
+		return f.rgb()
+	default:
+		return nil, ErrUnsupportedPixelData
+	}
+}
+
+// WritePNG encodes the preview as PNG.
+func (f *File) WritePNG(w io.Writer) error {
+	img, err := f.Preview()
+	if err != nil {
+		return err
+	}
+	return png.Encode(w, img)
+}
+
+func (f *File) grayscale() (image.Image, error) {
+	pixels := f.Rows * f.Columns
+	bytesPerPixel := f.bitsAllocated / 8
+	if len(f.pixelData) < pixels*bytesPerPixel {
+		return nil, ErrUnsupportedPixelData
+	}
+
+	values := make([]float64, pixels)
+	for i := range values {
+		var raw float64
+		if bytesPerPixel == 1 {
+			raw = float64(f.pixelData[i])
+		} else {
+			v := binary.LittleEndian.Uint16(f.pixelData[i*2:])
+			if f.bitsStored > 0 && f.bitsStored < 16 {
+				v &= 1<<f.bitsStored - 1
+			}
+			if f.pixelRepresentation == 1 {
+				raw = float64(signExtend(v, f.bitsStored))
+			} else {
+				raw = float64(v)
+			}
+		}
+		values[i] = raw*f.rescaleSlope + f.rescaleIntercept
+	}
+
+	low, high := f.window(values)
+	invert := f.photometric == "MONOCHROME1"
+
+	img := image.NewGray(image.Rect(0, 0, f.Columns, f.Rows))
+	for i, v := range values {
+		level := scale(v, low, high)
+		if invert {
+			level = 255 - level
+		}
+		img.Pix[i] = level
+	}
+	return img, nil
+}
+
+func (f *File) rgb() (image.Image, error) {
+	pixels := f.Rows * f.Columns
+	if len(f.pixelData) < pixels*3 {
+		return nil, ErrUnsupportedPixelData
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, f.Columns, f.Rows))
+	for i := 0; i < pixels; i++ {
+		var r, g, b uint8
+		if f.planarConfiguration == 1 {
+			r, g, b = f.pixelData[i], f.pixelData[pixels+i], f.pixelData[2*pixels+i]
+		} else {
+			r, g, b = f.pixelData[i*3], f.pixelData[i*3+1], f.pixelData[i*3+2]
+		}
+		img.Set(i%f.Columns, i/f.Columns, color.RGBA{R: r, G: g, B: b, A: 255})
+	}
+	return img, nil
+}
+
+// window returns the value range mapped to black and white, using the VOI
+// window from the file when present and the pixel range otherwise.
+func (f *File) window(values []float64) (float64, float64) {
+	if f.hasWindow && f.windowWidth > 0 {
+		return f.windowCenter - f.windowWidth/2, f.windowCenter + f.windowWidth/2
+	}
+	low, high := math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		low = math.Min(low, v)
+		high = math.Max(high, v)
+	}
+	return low, high
+}
+
+func scale(v, low, high float64) uint8 {
+	if high <= low {
+		return 0
+	}
+	switch {
+	case v <= low:
+		return 0
+	case v >= high:
+		return 255
+	}
+	return uint8((v - low) / (high - low) * 255)
+}
+
+func signExtend(v uint16, bits int) int16 {
+	if bits <= 0 || bits >= 16 {
+		return int16(v)
+	}
+	if v&(1<<(bits-1)) != 0 {
+		return int16(v | ^uint16(1<<bits-1))
+	}
+	return int16(v)
+}
@@ -10,26 +10,61 @@ type UpdateMedicalRecordRequest struct {
 }
 
 type MedicalRecordResponse struct {
-	Success string `json:"success"`
-	Message string `json:"message"`
+	Success  string   `json:"success"`
+	Message  string   `json:"message"`
+	Warnings []string `json:"warnings,omitempty"`
 }
 
 type GetMedicalRecordResponse struct {
-	Status     string                `json:"status"`
-	Message    string                `json:"message"`
-	Diagnosis  string                `json:"diagnosis"`
-	Notes      string                `json:"notes"`
-	Is_checked bool                  `json:"is_checked"`
-	Files      []MedicalFileResponse `json:"files"`
+	Status     string                 `json:"status"`
+	Message    string                 `json:"message"`
+	Diagnosis  string                 `json:"diagnosis"`
+	Notes      string                 `json:"notes"`
+	Is_checked bool                   `json:"is_checked"`
+	Files      []MedicalFileResponse  `json:"files"`
+	Studies    []MedicalStudyResponse `json:"studies"`
 }
 
 type MedicalFileResponse struct {
-	ID       string `json:"id"`
-	Name     string `json:"name"`
-	MimeType string `json:"mime_type"`
+	ID             string `json:"id"`
+	Name           string `json:"name"`
+	MimeType       string `json:"mime_type"`
+	SeriesUID      string `json:"series_uid,omitempty"`
+	InstanceNumber int    `json:"instance_number,omitempty"`
+	HasPreview     bool   `json:"has_preview,omitempty"`
+}
+
+type MedicalStudyResponse struct {
+	StudyUID      string                `json:"study_uid"`
+	Modality      string                `json:"modality"`
+	StudyDate     string                `json:"study_date,omitempty"`
+	SeriesCount   int                   `json:"series_count"`
+	ImageCount    int                   `json:"image_count"`
+	PreviewFileID string                `json:"preview_file_id"`
+	Files         []MedicalFileResponse `json:"files"`
 }
 
 type UpdateMedicalRecordResponse struct {
 	Success string `json:"success"`
 	Message string `json:"message"`
 }
+
+type MedicalFileAccessLogResponse struct {
+	ID           string `json:"id"`
+	FileID       string `json:"file_id,omitempty"`
+	FileName     string `json:"file_name"`
+	RecordID     string `json:"medical_record_id"`
+	AccessedBy   string `json:"accessed_by"`
+	AccessorName string `json:"accessor_name"`
+	Role         string `json:"role"`
+	Action       string `json:"action"`
+	IPAddress    string `json:"ip_address"`
+	Granted      bool   `json:"granted"`
+	AccessedAt   string `json:"accessed_at"`
+}
+
+type GetMedicalFileAccessLogsResponse struct {
+	Status  string                         `json:"status"`
+	Message string                         `json:"message"`
+	Logs    []MedicalFileAccessLogResponse `json:"logs"`
+}
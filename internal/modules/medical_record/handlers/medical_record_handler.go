@@ -1,26 +1,31 @@
 package handlers
 
 import (
+	"dental_clinic/internal/config"
 	"dental_clinic/internal/modules/medical_record/dto"
 	"dental_clinic/internal/modules/medical_record/models"
 	"dental_clinic/internal/modules/medical_record/services"
 	"encoding/json"
 	"fmt"
-	"io"
+	"net"
 	"net/http"
 	"os"
-	"time"
+
+	"dental_clinic/internal/upload"
+	"dental_clinic/internal/utils"
 
 	//"github.com/google/uuid"
 	"github.com/gorilla/mux"
 )
 
 type MedicalRecordHandler struct {
-	service *services.MedicalRecordService
+	service  *services.MedicalRecordService
+	cfg      config.Config
+	uploader *upload.Uploader
 }
 
-func NewMedicalRecordHandler(s *services.MedicalRecordService) *MedicalRecordHandler {
-	return &MedicalRecordHandler{service: s}
+func NewMedicalRecordHandler(s *services.MedicalRecordService, cfg config.Config) *MedicalRecordHandler {
+	return &MedicalRecordHandler{service: s, cfg: cfg, uploader: upload.NewUploader(cfg)}
 }
 
 // UpdateMedicalRecord godoc
@@ -34,9 +39,11 @@ func NewMedicalRecordHandler(s *services.MedicalRecordService) *MedicalRecordHan
 // @Param diagnosis formData string false "Diagnosis"
 // @Param notes formData string false "Notes"
 // @Param is_checked formData bool false "Is checked"
-// @Param files formData file false "Files"
+// @Param files formData file false "Files (JPEG, PNG, PDF or DICOM, up to 50 MB each)"
 // @Success 200 {object} dto.MedicalRecordResponse
 // @Failure 400 {object} map[string]string
+// @Failure 413 {object} dto.MedicalRecordResponse
+// @Failure 415 {object} dto.MedicalRecordResponse
 // @Failure 404 {object} map[string]string
 // @Router /api/medical-records/{id} [put]
 func (h *MedicalRecordHandler) UpdateMedicalRecord(w http.ResponseWriter, r *http.Request) {
@@ -47,10 +54,9 @@ func (h *MedicalRecordHandler) UpdateMedicalRecord(w http.ResponseWriter, r *htt
 	vars := mux.Vars(r)
 	id := vars["id"]
 
-	// парсим multipart form (макс 10MB)
-	if err := r.ParseMultipartForm(10 << 20); err != nil {
-		response.Message = "Invalid request body"
-		w.WriteHeader(http.StatusBadRequest)
+	if err := h.uploader.ParseForm(w, r, upload.MedicalFilePolicy); err != nil {
+		response.Message = err.Error()
+		w.WriteHeader(upload.HTTPStatus(err))
 		_ = json.NewEncoder(w).Encode(response)
 		return
 	}
@@ -62,58 +68,28 @@ func (h *MedicalRecordHandler) UpdateMedicalRecord(w http.ResponseWriter, r *htt
 	}
 
 	// сохраняем файлы локально
-	files := r.MultipartForm.File["files"]
-	var medicalFiles []models.MedicalFile
-	for _, fileHeader := range files {
-		file, err := fileHeader.Open()
-		if err != nil {
-			continue
-		}
-		defer file.Close()
-
-		buffer := make([]byte, 512)
-
-		_, err = file.Read(buffer)
-		if err != nil {
-			continue
-		}
-
-		mimeType := http.DetectContentType(buffer)
-
-		_, err = file.Seek(0, io.SeekStart)
-		if err != nil {
-			continue
-		}
-
-		// создаём папку если нет
-		os.MkdirAll("./uploads/medical_records", os.ModePerm)
-
-		filePath := fmt.Sprintf("./uploads/medical_records/%d_%s", time.Now().UnixNano(), fileHeader.Filename)
-		dst, err := os.Create(filePath)
-		if err != nil {
-			continue
-		}
-
-		_, err = io.Copy(dst, file)
-
-		dst.Close()
-		file.Close()
-
-		if err != nil {
-			continue
-		}
-
-		medicalFile := models.MedicalFile{
-			Filename: fileHeader.Filename,
-			FilePath: filePath,
-			MimeType: mimeType,
-		}
+	saved, err := h.uploader.SaveFiles(r.Context(), "files", r.MultipartForm.File["files"], upload.MedicalFilePolicy, "./uploads/medical_records", id)
+	if err != nil {
+		response.Message = err.Error()
+		w.WriteHeader(upload.HTTPStatus(err))
+		_ = json.NewEncoder(w).Encode(response)
+		return
+	}
 
-		medicalFiles = append(medicalFiles, medicalFile)
+	medicalFiles := make([]models.MedicalFile, 0, len(saved))
+	for _, f := range saved {
+		medicalFiles = append(medicalFiles, models.MedicalFile{
+			Filename: f.Name,
+			FilePath: f.Path,
+			MimeType: f.MimeType,
+		})
 	}
 
-	_, err := h.service.UpdateMedicalRecord(id, req, medicalFiles)
+	_, warnings, err := h.service.UpdateMedicalRecord(id, req, medicalFiles)
 	if err != nil {
+		for _, f := range saved {
+			_ = os.Remove(f.Path)
+		}
 		response.Message = err.Error()
 		w.WriteHeader(http.StatusBadRequest)
 		_ = json.NewEncoder(w).Encode(response)
@@ -122,13 +98,14 @@ func (h *MedicalRecordHandler) UpdateMedicalRecord(w http.ResponseWriter, r *htt
 
 	response.Success = "1"
 	response.Message = "successfully updated"
+	response.Warnings = warnings
 	w.WriteHeader(http.StatusOK)
 	_ = json.NewEncoder(w).Encode(response)
 }
 
 // GetMedicalRecord godoc
 // @Summary Get MedicalRecord
-// @Description gets an existing MedicalRecord's information
+// @Description gets an existing MedicalRecord's information. Only the patient, the treating doctor and clinic admins have access; every attempt is logged
 // @Tags MedicalRecord
 // @Security BearerAuth
 // @Accept  json
@@ -136,7 +113,7 @@ func (h *MedicalRecordHandler) UpdateMedicalRecord(w http.ResponseWriter, r *htt
 // @Param id path string true "MedicalRecord ID"
 // @Success 200 {object} dto.GetMedicalRecordResponse
 // @Failure 400 {object} map[string]string
-// @Failure 404 {object} map[string]string
+// @Failure 403 {object} map[string]string
 // @Router /api/medical-records/{id} [get]
 func (h *MedicalRecordHandler) GetMedicalRecord(w http.ResponseWriter, r *http.Request) {
 	response := dto.GetMedicalRecordResponse{
@@ -146,15 +123,21 @@ func (h *MedicalRecordHandler) GetMedicalRecord(w http.ResponseWriter, r *http.R
 	vars := mux.Vars(r)
 	id := vars["id"]
 
-	medical_record, err := h.service.GetMedicalRecord(id)
+	userID, role := h.currentUser(r)
+	medical_record, err := h.service.AuthorizeRecordAccess(id, userID, role, clientIP(r))
 	if err != nil {
 		response.Message = err.Error()
-		w.WriteHeader(http.StatusBadRequest)
+		switch err.Error() {
+		case "access denied", "invalid user id":
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
 		_ = json.NewEncoder(w).Encode(response)
 		return
 	}
 
-	medical_files, err := h.service.GetMedicalRecordFiles(id)
+	medical_files, studies, err := h.service.GetMedicalRecordFiles(id)
 	if err != nil {
 		response.Message = err.Error()
 		w.WriteHeader(http.StatusBadRequest)
@@ -168,6 +151,7 @@ func (h *MedicalRecordHandler) GetMedicalRecord(w http.ResponseWriter, r *http.R
 	response.Notes = medical_record.Notes
 	response.Is_checked = medical_record.Is_checked
 	response.Files = medical_files
+	response.Studies = studies
 
 	w.WriteHeader(http.StatusOK)
 	_ = json.NewEncoder(w).Encode(response)
@@ -175,70 +159,32 @@ func (h *MedicalRecordHandler) GetMedicalRecord(w http.ResponseWriter, r *http.R
 
 // PreviewMedicalFile godoc
 // @Summary Preview medical file
-// @Description Preview medical file in browser
+// @Description Preview medical file in browser. DICOM images are returned as PNG previews. Only the patient, the treating doctor and clinic admins have access; every attempt is logged
 // @Tags MedicalRecord
 // @Security BearerAuth
 // @Produce octet-stream
 // @Param id path string true "File ID"
 // @Success 200 {file} file
+// @Failure 403 {object} map[string]string
 // @Failure 404 {object} map[string]string
 // @Router /api/files/medical-records/{id} [get]
 func (h *MedicalRecordHandler) GetPreviewMedicalRecordFile(w http.ResponseWriter, r *http.Request) {
-	id := mux.Vars(r)["id"]
-
-	file, err := h.service.GetFileByID(id)
-	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusNotFound)
-
-		_ = json.NewEncoder(w).Encode(map[string]string{
-			"error": "file not found",
-		})
-		return
-	}
-
-	w.Header().Set("Content-Type", file.MimeType)
-
-	w.Header().Set(
-		"Content-Disposition",
-		fmt.Sprintf(`inline; filename="%s"`, file.Filename),
-	)
-
-	http.ServeFile(w, r, file.FilePath)
+	h.serveMedicalFile(w, r, "inline", "view")
 }
 
 // DownloadMedicalFile godoc
 // @Summary Download medical file
-// @Description Download medical file
+// @Description Download medical file. Only the patient, the treating doctor and clinic admins have access; every attempt is logged
 // @Tags MedicalRecord
 // @Security BearerAuth
 // @Produce octet-stream
 // @Param id path string true "File ID"
 // @Success 200 {file} file
+// @Failure 403 {object} map[string]string
 // @Failure 404 {object} map[string]string
 // @Router /api/files/medical-records/{id}/download [get]
 func (h *MedicalRecordHandler) DownloadMedicalRecordFile(w http.ResponseWriter, r *http.Request) {
-	id := mux.Vars(r)["id"]
-
-	file, err := h.service.GetFileByID(id)
-	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusNotFound)
-
-		_ = json.NewEncoder(w).Encode(map[string]string{
-			"error": "file not found",
-		})
-		return
-	}
-
-	w.Header().Set("Content-Type", file.MimeType)
-
-	w.Header().Set(
-		"Content-Disposition",
-		fmt.Sprintf(`attachment; filename="%s"`, file.Filename),
-	)
-
-	http.ServeFile(w, r, file.FilePath)
+	h.serveMedicalFile(w, r, "attachment", "download")
 }
 
 // DeleteMedicalFile godoc
@@ -275,6 +221,9 @@ func (h *MedicalRecordHandler) DeleteRecordFile(w http.ResponseWriter, r *http.R
 		})
 		return
 	}
+	if file.PreviewPath != "" {
+		_ = os.Remove(file.PreviewPath)
+	}
 
 	err = h.service.DeleteFile(id)
 	if err != nil {
@@ -289,3 +238,93 @@ func (h *MedicalRecordHandler) DeleteRecordFile(w http.ResponseWriter, r *http.R
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// GetMyFileAccessLogs godoc
+// @Summary Medical file access history
+// @Description Returns who viewed the current patient's medical records and who viewed or downloaded their files
+// @Tags MedicalRecord
+// @Security BearerAuth
+// @Produce  json
+// @Success 200 {object} dto.GetMedicalFileAccessLogsResponse
+// @Failure 400 {object} dto.GetMedicalFileAccessLogsResponse
+// @Router /api/medical-records/access-logs [get]
+func (h *MedicalRecordHandler) GetMyFileAccessLogs(w http.ResponseWriter, r *http.Request) {
+	response := dto.GetMedicalFileAccessLogsResponse{
+		Status:  "0",
+		Message: "",
+	}
+
+	userID, _ := h.currentUser(r)
+	logs, err := h.service.GetFileAccessLogsByPatient(userID)
+	if err != nil {
+		response.Message = err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response.Status = "1"
+	response.Message = "successfully retrieved"
+	response.Logs = logs
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+func (h *MedicalRecordHandler) serveMedicalFile(w http.ResponseWriter, r *http.Request, disposition, action string) {
+	id := mux.Vars(r)["id"]
+
+	file, err := h.service.GetFileByID(id)
+	if err != nil {
+		writeFileError(w, http.StatusNotFound, "file not found")
+		return
+	}
+
+	userID, role := h.currentUser(r)
+	if err := h.service.AuthorizeFileAccess(file, userID, role, action, clientIP(r)); err != nil {
+		writeFileError(w, http.StatusForbidden, err.Error())
+		return
+	}
+
+	mimeType, filename, path := file.MimeType, upload.SanitizeFilename(file.Filename), file.FilePath
+	// browsers cannot render DICOM, so previews use the generated PNG
+	if disposition == "inline" && file.PreviewPath != "" {
+		mimeType, filename, path = "image/png", filename+".png", file.PreviewPath
+	}
+
+	w.Header().Set("Content-Type", mimeType)
+	w.Header().Set("Cache-Control", "no-store")
+
+	w.Header().Set(
+		"Content-Disposition",
+		fmt.Sprintf(`%s; filename="%s"`, disposition, filename),
+	)
+
+	http.ServeFile(w, r, path)
+}
+
+func (h *MedicalRecordHandler) currentUser(r *http.Request) (string, string) {
+	claims, err := utils.GetClaims(utils.GetToken(r), h.cfg.JWTSecret)
+	if err != nil {
+		return "", ""
+	}
+	userID, _ := claims["user_id"].(string)
+	role, _ := claims["role"].(string)
+	return userID, role
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func writeFileError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"error": message,
+	})
+}
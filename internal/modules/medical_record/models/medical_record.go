@@ -30,4 +30,29 @@ type MedicalFile struct {
 	FilePath        string
 	MimeType        string
 	Created_at      time.Time
+
+	// DICOM metadata, empty for other file types
+	Modality          string
+	StudyDate         sql.NullTime
+	StudyInstanceUID  string
+	SeriesInstanceUID string
+	InstanceNumber    int
+	DicomPatientID    string
+	DicomPatientName  string
+	PreviewPath       string
+}
+
+type MedicalFileAccessLog struct {
+	Id              uuid.UUID
+	MedicalFileId   uuid.UUID
+	MedicalRecordId uuid.UUID
+	UserId          uuid.UUID
+	Role            string
+	Action          string
+	IPAddress       string
+	Granted         bool
+	AccessedAt      time.Time
+
+	FileName string
+	UserName string
 }
@@ -17,10 +17,13 @@ type MedicalRecordRepository interface {
 	//GetAll() ([]models.Doctor, error)
 	Update(id string, doctor *models.MedicalRecord) (*models.MedicalRecord, error)
 	//Delete(id string) error
-	SaveMedicalFile(medicalRecordID, fileURL, fileName, mimeType string) error
+	SaveMedicalFile(medicalRecordID string, file *models.MedicalFile) error
 	GetMedicalFiles(id string) ([]models.MedicalFile, error)
 	GetFileByID(id string) (*models.MedicalFile, error)
 	DeleteFileByID(id string) error
+	CanAccessRecord(recordID, userID string) (bool, error)
+	LogFileAccess(log *models.MedicalFileAccessLog) error
+	GetFileAccessLogsByPatient(patientID string) ([]models.MedicalFileAccessLog, error)
 }
 type medical_report_Repo struct {
 	db *pgxpool.Pool
@@ -79,9 +82,14 @@ func (r *medical_report_Repo) CreateTx(medical_record *models.MedicalRecord, tx
 }
 
 func (r *medical_report_Repo) GetByID(id string) (*models.MedicalRecord, error) {
-	query := `SELECT appointment_id, doctor_id, patient_id, diagnosis, notes, is_checked, created_at, updated_at FROM medical_records WHERE id = $1`
+	query := `
+		SELECT mr.id, mr.appointment_id, mr.doctor_id, mr.patient_id, mr.diagnosis, mr.notes, mr.is_checked, mr.created_at, mr.updated_at, COALESCE(a.name, '')
+		FROM medical_records mr
+		LEFT JOIN appointments a ON a.id = mr.appointment_id
+		WHERE mr.id = $1
+	`
 	var medical_record models.MedicalRecord
-	err := r.db.QueryRow(context.Background(), query, id).Scan(&medical_record.Appointment_id, &medical_record.Doctor_id, &medical_record.Patient_id, &medical_record.Diagnosis, &medical_record.Notes, &medical_record.Is_checked, &medical_record.Created_at, &medical_record.Updated_at)
+	err := r.db.QueryRow(context.Background(), query, id).Scan(&medical_record.Id, &medical_record.Appointment_id, &medical_record.Doctor_id, &medical_record.Patient_id, &medical_record.Diagnosis, &medical_record.Notes, &medical_record.Is_checked, &medical_record.Created_at, &medical_record.Updated_at, &medical_record.AppointmentName)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, nil
@@ -191,15 +199,41 @@ func (r *medical_report_Repo) GetMedicalRecordsByDoctorId(id string) ([]models.M
 	return medical_records, nil
 }
 
-func (r *medical_report_Repo) SaveMedicalFile(medicalRecordID, fileURL, fileName, mimeType string) error {
-	query := `INSERT INTO medical_files (id, medical_record_id, file_url, created_at, file_name, mime_type) 
-              VALUES (gen_random_uuid(), $1, $2, NOW(), $3, $4)`
-	_, err := r.db.Exec(context.Background(), query, medicalRecordID, fileURL, fileName, mimeType)
-	return err
+func (r *medical_report_Repo) SaveMedicalFile(medicalRecordID string, file *models.MedicalFile) error {
+	query := `INSERT INTO medical_files (id, medical_record_id, file_url, created_at, file_name, mime_type,
+                  modality, study_date, study_instance_uid, series_instance_uid, instance_number,
+                  dicom_patient_id, dicom_patient_name, preview_url)
+              VALUES (gen_random_uuid(), $1, $2, NOW(), $3, $4,
+                  NULLIF($5, ''), $6, NULLIF($7, ''), NULLIF($8, ''), NULLIF($9, 0),
+                  NULLIF($10, ''), NULLIF($11, ''), NULLIF($12, ''))
+              RETURNING id`
+	return r.db.QueryRow(
+		context.Background(),
+		query,
+		medicalRecordID,
+		file.FilePath,
+		file.Filename,
+		file.MimeType,
+		file.Modality,
+		file.StudyDate,
+		file.StudyInstanceUID,
+		file.SeriesInstanceUID,
+		file.InstanceNumber,
+		file.DicomPatientID,
+		file.DicomPatientName,
+		file.PreviewPath,
+	).Scan(&file.Id)
 }
 
 func (r *medical_report_Repo) GetMedicalFiles(id string) ([]models.MedicalFile, error) {
-	query := `SELECT id, file_name, mime_type FROM medical_files WHERE medical_record_id = $1`
+	query := `
+		SELECT id, file_name, mime_type,
+		       COALESCE(modality, ''), study_date, COALESCE(study_instance_uid, ''), COALESCE(series_instance_uid, ''),
+		       COALESCE(instance_number, 0), COALESCE(dicom_patient_id, ''), COALESCE(dicom_patient_name, ''), COALESCE(preview_url, '')
+		FROM medical_files
+		WHERE medical_record_id = $1
+		ORDER BY created_at
+	`
 
 	rows, err := r.db.Query(context.Background(), query, id)
 	if err != nil {
@@ -210,7 +244,19 @@ func (r *medical_report_Repo) GetMedicalFiles(id string) ([]models.MedicalFile,
 	var medical_files []models.MedicalFile
 	for rows.Next() {
 		var medical_file models.MedicalFile
-		if err := rows.Scan(&medical_file.Id, &medical_file.Filename, &medical_file.MimeType); err != nil {
+		if err := rows.Scan(
+			&medical_file.Id,
+			&medical_file.Filename,
+			&medical_file.MimeType,
+			&medical_file.Modality,
+			&medical_file.StudyDate,
+			&medical_file.StudyInstanceUID,
+			&medical_file.SeriesInstanceUID,
+			&medical_file.InstanceNumber,
+			&medical_file.DicomPatientID,
+			&medical_file.DicomPatientName,
+			&medical_file.PreviewPath,
+		); err != nil {
 			return nil, err
 		}
 		medical_files = append(medical_files, medical_file)
@@ -224,9 +270,30 @@ func (r *medical_report_Repo) GetMedicalFiles(id string) ([]models.MedicalFile,
 }
 
 func (r *medical_report_Repo) GetFileByID(id string) (*models.MedicalFile, error) {
-	query := `SELECT id, medical_record_id, file_url, created_at, file_name, mime_type FROM medical_files WHERE id = $1`
+	query := `
+		SELECT id, medical_record_id, file_url, created_at, file_name, mime_type,
+		       COALESCE(modality, ''), study_date, COALESCE(study_instance_uid, ''), COALESCE(series_instance_uid, ''),
+		       COALESCE(instance_number, 0), COALESCE(dicom_patient_id, ''), COALESCE(dicom_patient_name, ''), COALESCE(preview_url, '')
+		FROM medical_files
+		WHERE id = $1
+	`
 	var medical_file models.MedicalFile
-	err := r.db.QueryRow(context.Background(), query, id).Scan(&medical_file.Id, &medical_file.MedicalRecordId, &medical_file.FilePath, &medical_file.Created_at, &medical_file.Filename, &medical_file.MimeType)
+	err := r.db.QueryRow(context.Background(), query, id).Scan(
+		&medical_file.Id,
+		&medical_file.MedicalRecordId,
+		&medical_file.FilePath,
+		&medical_file.Created_at,
+		&medical_file.Filename,
+		&medical_file.MimeType,
+		&medical_file.Modality,
+		&medical_file.StudyDate,
+		&medical_file.StudyInstanceUID,
+		&medical_file.SeriesInstanceUID,
+		&medical_file.InstanceNumber,
+		&medical_file.DicomPatientID,
+		&medical_file.DicomPatientName,
+		&medical_file.PreviewPath,
+	)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, nil
@@ -251,3 +318,102 @@ func (r *medical_report_Repo) DeleteFileByID(id string) error {
 
 	return nil
 }
+
+// CanAccessRecord reports whether the user is the patient, the treating doctor
+// or an admin of the clinic where the appointment took place.
+func (r *medical_report_Repo) CanAccessRecord(recordID, userID string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM medical_records mr
+			LEFT JOIN doctors d ON d.id = mr.doctor_id
+			LEFT JOIN appointments a ON a.id = mr.appointment_id
+			LEFT JOIN clinic_addresses ca ON ca.id = a.clinic_address_id
+			WHERE mr.id = $1
+			  AND (
+				mr.patient_id = $2
+				OR d.user_id = $2
+				OR EXISTS (
+					SELECT 1 FROM clinic_admins cad
+					WHERE cad.clinic_id = ca.clinic_id AND cad.user_id = $2
+				)
+			  )
+		)
+	`
+	var allowed bool
+	err := r.db.QueryRow(context.Background(), query, recordID, userID).Scan(&allowed)
+	return allowed, err
+}
+
+func (r *medical_report_Repo) LogFileAccess(log *models.MedicalFileAccessLog) error {
+	query := `
+		INSERT INTO medical_file_access_logs (id, medical_file_id, medical_record_id, user_id, role, action, ip_address, granted, accessed_at)
+		VALUES ($1, NULLIF($2, '00000000-0000-0000-0000-000000000000'::uuid), $3, $4, $5, $6, $7, $8, $9)
+	`
+	_, err := r.db.Exec(
+		context.Background(),
+		query,
+		log.Id,
+		log.MedicalFileId,
+		log.MedicalRecordId,
+		log.UserId,
+		log.Role,
+		log.Action,
+		log.IPAddress,
+		log.Granted,
+		log.AccessedAt,
+	)
+	return err
+}
+
+func (r *medical_report_Repo) GetFileAccessLogsByPatient(patientID string) ([]models.MedicalFileAccessLog, error) {
+	query := `
+		SELECT
+			l.id,
+			COALESCE(l.medical_file_id, '00000000-0000-0000-0000-000000000000'::uuid),
+			l.medical_record_id,
+			l.user_id,
+			COALESCE(l.role, ''),
+			l.action,
+			COALESCE(l.ip_address, ''),
+			l.granted,
+			l.accessed_at,
+			COALESCE(mf.file_name, ''),
+			COALESCE(u.name, '')
+		FROM medical_file_access_logs l
+		JOIN medical_records mr ON mr.id = l.medical_record_id
+		LEFT JOIN medical_files mf ON mf.id = l.medical_file_id
+		LEFT JOIN users u ON u.id = l.user_id
+		WHERE mr.patient_id = $1
+		ORDER BY l.accessed_at DESC
+	`
+
+	rows, err := r.db.Query(context.Background(), query, patientID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	logs := make([]models.MedicalFileAccessLog, 0)
+	for rows.Next() {
+		var log models.MedicalFileAccessLog
+		if err := rows.Scan(
+			&log.Id,
+			&log.MedicalFileId,
+			&log.MedicalRecordId,
+			&log.UserId,
+			&log.Role,
+			&log.Action,
+			&log.IPAddress,
+			&log.Granted,
+			&log.AccessedAt,
+			&log.FileName,
+			&log.UserName,
+		); err != nil {
+			return nil, err
+		}
+		logs = append(logs, log)
+	}
+
+	return logs, rows.Err()
+}
@@ -19,11 +19,12 @@ func RegisterPublicRoutes(r *mux.Router, db *pgxpool.Pool) {
 	//r.HandleFunc("/doctors/{id}", handler.GetDoctorByID).Methods("GET")
 }
 
-func RegisterPrivateRoutes(r *mux.Router, db *pgxpool.Pool) {
+func RegisterPrivateRoutes(r *mux.Router, db *pgxpool.Pool, cfg *config.Config) {
 	repo := repository.NewMedicalRecordRepository(db)
 	service := services.NewMedicalRecordService(repo)
-	handler := handlers.NewMedicalRecordHandler(service)
+	handler := handlers.NewMedicalRecordHandler(service, *cfg)
 
+	r.HandleFunc("/medical-records/access-logs", handler.GetMyFileAccessLogs).Methods("GET")
 	r.HandleFunc("/medical-records/{id}", handler.GetMedicalRecord).Methods("GET")
 	r.HandleFunc("/files/medical-records/{id}", handler.GetPreviewMedicalRecordFile).Methods("GET")
 	r.HandleFunc("/files/medical-records/{id}/download", handler.DownloadMedicalRecordFile).Methods("GET")
@@ -32,7 +33,7 @@ func RegisterPrivateRoutes(r *mux.Router, db *pgxpool.Pool) {
 func RegisterDoctorRoutes(r *mux.Router, db *pgxpool.Pool, cfg *config.Config) {
 	repo := repository.NewMedicalRecordRepository(db)
 	service := services.NewMedicalRecordService(repo)
-	handler := handlers.NewMedicalRecordHandler(service)
+	handler := handlers.NewMedicalRecordHandler(service, *cfg)
 
 	//r.HandleFunc("/doctors", handler.CreateDoctor).Methods("POST")
 	r.HandleFunc("/medical-records/{id}", handler.UpdateMedicalRecord).Methods("PUT")
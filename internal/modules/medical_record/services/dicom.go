@@ -0,0 +1,147 @@
+package services
+
+import (
+	"database/sql"
+	"dental_clinic/internal/modules/medical_record/dicom"
+	"dental_clinic/internal/modules/medical_record/dto"
+	"dental_clinic/internal/modules/medical_record/models"
+	"dental_clinic/internal/upload"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+const DicomMimeType = upload.MimeDICOM
+
+// processDicomFile reads the DICOM header of an uploaded file, fills in its
+// metadata and writes a PNG preview next to it. Problems that should not
+// block the upload are returned as warnings.
+func processDicomFile(file *models.MedicalFile, record *models.MedicalRecord, knownPatientIDs map[string]bool) []string {
+	warnings := make([]string, 0)
+
+	src, err := os.Open(file.FilePath)
+	if err != nil {
+		return append(warnings, fmt.Sprintf("%s: could not read DICOM file", file.Filename))
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return append(warnings, fmt.Sprintf("%s: could not read DICOM file", file.Filename))
+	}
+
+	parsed, err := dicom.Parse(src, info.Size())
+	if err != nil {
+		return append(warnings, fmt.Sprintf("%s: %v", file.Filename, err))
+	}
+
+	file.Modality = parsed.Modality
+	if !parsed.StudyDate.IsZero() {
+		file.StudyDate = sql.NullTime{Time: parsed.StudyDate, Valid: true}
+	}
+	file.StudyInstanceUID = parsed.StudyInstanceUID
+	file.SeriesInstanceUID = parsed.SeriesInstanceUID
+	file.InstanceNumber = parsed.InstanceNumber
+	file.DicomPatientID = parsed.PatientID
+	file.DicomPatientName = parsed.PatientName
+
+	if parsed.PatientID != "" {
+		for known := range knownPatientIDs {
+			if known != parsed.PatientID {
+				warnings = append(warnings, fmt.Sprintf("%s: DICOM patient ID %q differs from %q already attached to this record", file.Filename, parsed.PatientID, known))
+				break
+			}
+		}
+		knownPatientIDs[parsed.PatientID] = true
+	}
+	if !samePatientName(parsed.PatientName, record.AppointmentName) {
+		warnings = append(warnings, fmt.Sprintf("%s: DICOM patient name %q does not match appointment name %q", file.Filename, parsed.PatientName, record.AppointmentName))
+	}
+
+	previewPath := file.FilePath + ".png"
+	dst, err := os.Create(previewPath)
+	if err != nil {
+		return append(warnings, fmt.Sprintf("%s: could not create preview", file.Filename))
+	}
+	err = parsed.WritePNG(dst)
+	dst.Close()
+	if err != nil {
+		os.Remove(previewPath)
+		return append(warnings, fmt.Sprintf("%s: preview not available: %v", file.Filename, err))
+	}
+	file.PreviewPath = previewPath
+
+	return warnings
+}
+
+// samePatientName compares names token by token, ignoring order and case,
+// since DICOM stores them as FAMILY^GIVEN. Missing names are not reported.
+func samePatientName(dicomName, appointmentName string) bool {
+	if dicomName == "" || appointmentName == "" {
+		return true
+	}
+	tokens := make(map[string]bool)
+	for _, t := range strings.Fields(strings.ToLower(dicomName)) {
+		tokens[t] = true
+	}
+	for _, t := range strings.Fields(strings.ToLower(appointmentName)) {
+		if tokens[t] {
+			return true
+		}
+	}
+	return false
+}
+
+// groupDicomStudies splits files into plain files and DICOM studies, so a
+// multi-image series is shown as one item.
+func groupDicomStudies(files []models.MedicalFile) ([]dto.MedicalFileResponse, []dto.MedicalStudyResponse) {
+	plain := make([]dto.MedicalFileResponse, 0)
+	studies := make([]dto.MedicalStudyResponse, 0)
+	index := make(map[string]int)
+	series := make(map[string]map[string]bool)
+
+	for _, f := range files {
+		if f.MimeType != DicomMimeType {
+			plain = append(plain, ToMedicalFileResponse(f))
+			continue
+		}
+
+		key := f.StudyInstanceUID
+		if key == "" {
+			key = f.Id.String()
+		}
+		i, ok := index[key]
+		if !ok {
+			study := dto.MedicalStudyResponse{
+				StudyUID: f.StudyInstanceUID,
+				Modality: f.Modality,
+				Files:    make([]dto.MedicalFileResponse, 0),
+			}
+			if f.StudyDate.Valid {
+				study.StudyDate = f.StudyDate.Time.Format("2006-01-02")
+			}
+			studies = append(studies, study)
+			i = len(studies) - 1
+			index[key] = i
+			series[key] = make(map[string]bool)
+		}
+
+		series[key][f.SeriesInstanceUID] = true
+		studies[i].Files = append(studies[i].Files, ToMedicalFileResponse(f))
+		studies[i].SeriesCount = len(series[key])
+		studies[i].ImageCount = len(studies[i].Files)
+	}
+
+	for i := range studies {
+		sort.SliceStable(studies[i].Files, func(a, b int) bool {
+			fa, fb := studies[i].Files[a], studies[i].Files[b]
+			if fa.SeriesUID != fb.SeriesUID {
+				return fa.SeriesUID < fb.SeriesUID
+			}
+			return fa.InstanceNumber < fb.InstanceNumber
+		})
+		studies[i].PreviewFileID = studies[i].Files[0].ID
+	}
+
+	return plain, studies
+}
@@ -3,6 +3,7 @@ package services
 import (
 	"dental_clinic/internal/modules/medical_record/dto"
 	"errors"
+	"os"
 
 	//"dental_clinic/internal/modules/medical_record/dto"
 	"dental_clinic/internal/modules/medical_record/models"
@@ -57,14 +58,15 @@ func (s *MedicalRecordService) CreateMedicalRecordTx(appointment_id, doctor_id,
 	return s.repo.CreateTx(medical_record, tx)
 }
 
-func (s *MedicalRecordService) UpdateMedicalRecord(id string, req dto.UpdateMedicalRecordRequest, medicalFiles []models.MedicalFile) (*models.MedicalRecord, error) {
+func (s *MedicalRecordService) UpdateMedicalRecord(id string, req dto.UpdateMedicalRecordRequest, medicalFiles []models.MedicalFile) (*models.MedicalRecord, []string, error) {
 	medical_record, err := s.repo.GetByID(id)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	if medical_record == nil {
-		return nil, errors.New("medical_record not found")
+		return nil, nil, errors.New("medical_record not found")
 	}
+	appointmentName := medical_record.AppointmentName
 
 	medical_record.Diagnosis = req.Diagnosis
 	medical_record.Notes = req.Notes
@@ -73,15 +75,59 @@ func (s *MedicalRecordService) UpdateMedicalRecord(id string, req dto.UpdateMedi
 
 	updated, err := s.repo.Update(id, medical_record)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	updated.AppointmentName = appointmentName
+
+	warnings := make([]string, 0)
+	knownPatientIDs := make(map[string]bool)
+	if hasDicom(medicalFiles) {
+		existing, err := s.repo.GetMedicalFiles(id)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, f := range existing {
+			if f.DicomPatientID != "" {
+				knownPatientIDs[f.DicomPatientID] = true
+			}
+		}
 	}
 
 	// сохраняем пути файлов в БД
-	for _, medicalFile := range medicalFiles {
-		_ = s.repo.SaveMedicalFile(id, medicalFile.FilePath, medicalFile.Filename, medicalFile.MimeType)
+	for i := range medicalFiles {
+		if medicalFiles[i].MimeType == DicomMimeType {
+			warnings = append(warnings, processDicomFile(&medicalFiles[i], updated, knownPatientIDs)...)
+		}
+		if err := s.repo.SaveMedicalFile(id, &medicalFiles[i]); err != nil {
+			s.discardMedicalFiles(medicalFiles[:i+1])
+			return nil, nil, err
+		}
 	}
 
-	return updated, nil
+	return updated, warnings, nil
+}
+
+// discardMedicalFiles undoes an upload that failed part way: it deletes the
+// rows saved for the files and their previews. The caller removes the
+// uploaded files themselves.
+func (s *MedicalRecordService) discardMedicalFiles(files []models.MedicalFile) {
+	for _, f := range files {
+		if f.Id != uuid.Nil {
+			_ = s.repo.DeleteFileByID(f.Id.String())
+		}
+		if f.PreviewPath != "" {
+			_ = os.Remove(f.PreviewPath)
+		}
+	}
+}
+
+func hasDicom(files []models.MedicalFile) bool {
+	for _, f := range files {
+		if f.MimeType == DicomMimeType {
+			return true
+		}
+	}
+	return false
 }
 
 func (s *MedicalRecordService) GetMedicalRecord(id string) (*models.MedicalRecord, error) {
@@ -95,20 +141,26 @@ func (s *MedicalRecordService) GetMedicalRecordsByDoctorId(id string) ([]models.
 	return s.repo.GetMedicalRecordsByDoctorId(id)
 }
 
-func (s *MedicalRecordService) GetMedicalRecordFiles(id string) ([]dto.MedicalFileResponse, error) {
+// GetMedicalRecordFiles returns the regular files of a record and its DICOM
+// images grouped by study.
+func (s *MedicalRecordService) GetMedicalRecordFiles(id string) ([]dto.MedicalFileResponse, []dto.MedicalStudyResponse, error) {
 	medical_files, err := s.repo.GetMedicalFiles(id)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return ToMedicalFileResponseList(medical_files), nil
+	files, studies := groupDicomStudies(medical_files)
+	return files, studies, nil
 }
 
 func ToMedicalFileResponse(s models.MedicalFile) dto.MedicalFileResponse {
 	return dto.MedicalFileResponse{
-		ID:       s.Id.String(),
-		Name:     s.Filename,
-		MimeType: s.MimeType,
+		ID:             s.Id.String(),
+		Name:           s.Filename,
+		MimeType:       s.MimeType,
+		SeriesUID:      s.SeriesInstanceUID,
+		InstanceNumber: s.InstanceNumber,
+		HasPreview:     s.PreviewPath != "",
 		// ClinicName: ,
 	}
 }
@@ -122,9 +174,139 @@ func ToMedicalFileResponseList(services []models.MedicalFile) []dto.MedicalFileR
 }
 
 func (s *MedicalRecordService) GetFileByID(id string) (*models.MedicalFile, error) {
-	return s.repo.GetFileByID(id)
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, errors.New("invalid file id")
+	}
+	file, err := s.repo.GetFileByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if file == nil {
+		return nil, errors.New("file not found")
+	}
+	return file, nil
 }
 
 func (s *MedicalRecordService) DeleteFile(id string) error {
 	return s.repo.DeleteFileByID(id)
 }
+
+func (s *MedicalRecordService) CanAccessMedicalRecord(recordID, userID string) (bool, error) {
+	if _, err := uuid.Parse(userID); err != nil {
+		return false, nil
+	}
+	return s.repo.CanAccessRecord(recordID, userID)
+}
+
+// AuthorizeFileAccess checks that the user may read the file and records the
+// attempt in the access log, whether it was granted or not.
+func (s *MedicalRecordService) AuthorizeFileAccess(file *models.MedicalFile, userID, role, action, ip string) error {
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return errors.New("invalid user id")
+	}
+
+	allowed, err := s.repo.CanAccessRecord(file.MedicalRecordId.String(), userUUID.String())
+	if err != nil {
+		return err
+	}
+
+	entry := &models.MedicalFileAccessLog{
+		Id:              uuid.New(),
+		MedicalFileId:   file.Id,
+		MedicalRecordId: file.MedicalRecordId,
+		UserId:          userUUID,
+		Role:            role,
+		Action:          action,
+		IPAddress:       ip,
+		Granted:         allowed,
+		AccessedAt:      time.Now(),
+	}
+	if err := s.repo.LogFileAccess(entry); err != nil {
+		return err
+	}
+
+	if !allowed {
+		return errors.New("access denied")
+	}
+	return nil
+}
+
+// AuthorizeRecordAccess checks that the user may read the record and logs
+// the view, whether it was granted or not. A missing record is reported as
+// denied so its existence is not revealed.
+func (s *MedicalRecordService) AuthorizeRecordAccess(recordID, userID, role, ip string) (*models.MedicalRecord, error) {
+	if _, err := uuid.Parse(recordID); err != nil {
+		return nil, errors.New("invalid medical record id")
+	}
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, errors.New("invalid user id")
+	}
+
+	record, err := s.repo.GetByID(recordID)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, errors.New("access denied")
+	}
+
+	allowed, err := s.repo.CanAccessRecord(recordID, userUUID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &models.MedicalFileAccessLog{
+		Id:              uuid.New(),
+		MedicalRecordId: record.Id,
+		UserId:          userUUID,
+		Role:            role,
+		Action:          "view",
+		IPAddress:       ip,
+		Granted:         allowed,
+		AccessedAt:      time.Now(),
+	}
+	if err := s.repo.LogFileAccess(entry); err != nil {
+		return nil, err
+	}
+
+	if !allowed {
+		return nil, errors.New("access denied")
+	}
+	return record, nil
+}
+
+func (s *MedicalRecordService) GetFileAccessLogsByPatient(patientID string) ([]dto.MedicalFileAccessLogResponse, error) {
+	if _, err := uuid.Parse(patientID); err != nil {
+		return nil, errors.New("invalid user id")
+	}
+
+	logs, err := s.repo.GetFileAccessLogsByPatient(patientID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]dto.MedicalFileAccessLogResponse, 0, len(logs))
+	for _, l := range logs {
+		// views of the record itself are not tied to a file
+		fileID := ""
+		if l.MedicalFileId != uuid.Nil {
+			fileID = l.MedicalFileId.String()
+		}
+		result = append(result, dto.MedicalFileAccessLogResponse{
+			ID:           l.Id.String(),
+			FileID:       fileID,
+			FileName:     l.FileName,
+			RecordID:     l.MedicalRecordId.String(),
+			AccessedBy:   l.UserId.String(),
+			AccessorName: l.UserName,
+			Role:         l.Role,
+			Action:       l.Action,
+			IPAddress:    l.IPAddress,
+			Granted:      l.Granted,
+			AccessedAt:   l.AccessedAt.Format(time.RFC3339),
+		})
+	}
+	return result, nil
+}
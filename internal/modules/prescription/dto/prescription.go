@@ -0,0 +1,51 @@
+package dto
+
+type PrescriptionItemRequest struct {
+	DrugName  string `json:"drug_name"`
+	Dose      string `json:"dose"`
+	Frequency string `json:"frequency"`
+	Duration  string `json:"duration"`
+	Notes     string `json:"notes"`
+}
+
+type CreatePrescriptionRequest struct {
+	Items        []PrescriptionItemRequest `json:"items"`
+	Instructions string                    `json:"instructions"`
+	TemplateId   string                    `json:"template_id"`
+}
+
+type PrescriptionItemResponse struct {
+	Id        string `json:"id"`
+	DrugName  string `json:"drug_name"`
+	Dose      string `json:"dose"`
+	Frequency string `json:"frequency"`
+	Duration  string `json:"duration"`
+	Notes     string `json:"notes,omitempty"`
+}
+
+type PrescriptionResponse struct {
+	Id              string                     `json:"id"`
+	MedicalRecordId string                     `json:"medical_record_id"`
+	DoctorId        string                     `json:"doctor_id"`
+	DoctorName      string                     `json:"doctor_name"`
+	PatientId       string                     `json:"patient_id"`
+	Instructions    string                     `json:"instructions"`
+	TemplateId      string                     `json:"template_id,omitempty"`
+	Items           []PrescriptionItemResponse `json:"items"`
+	Signature       string                     `json:"signature"`
+	SignedAt        string                     `json:"signed_at"`
+}
+
+type InstructionTemplateRequest struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+type InstructionTemplateResponse struct {
+	Id              string `json:"id"`
+	ClinicServiceId string `json:"clinic_service_id"`
+	ServiceName     string `json:"service_name"`
+	Title           string `json:"title"`
+	Body            string `json:"body"`
+	UpdatedAt       string `json:"updated_at"`
+}
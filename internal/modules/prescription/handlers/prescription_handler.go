@@ -0,0 +1,313 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"dental_clinic/internal/config"
+	"dental_clinic/internal/modules/prescription/dto"
+	"dental_clinic/internal/modules/prescription/models"
+	"dental_clinic/internal/modules/prescription/services"
+	"dental_clinic/internal/utils"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5"
+)
+
+type PrescriptionHandler struct {
+	service *services.PrescriptionService
+	cfg     config.Config
+}
+
+func NewPrescriptionHandler(service *services.PrescriptionService, cfg config.Config) *PrescriptionHandler {
+	return &PrescriptionHandler{service: service, cfg: cfg}
+}
+
+// CreatePrescription godoc
+// @Summary Issue prescription
+// @Description Creates and signs a prescription for a medical record. Only the treating doctor can issue it. When template_id is set and instructions are empty, the template text is used.
+// @Tags Prescriptions
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Medical record ID"
+// @Param request body dto.CreatePrescriptionRequest true "Prescription"
+// @Success 201 {object} dto.PrescriptionResponse
+// @Failure 400 {object} map[string]string
+// @Router /api/medical-records/{id}/prescriptions [post]
+func (h *PrescriptionHandler) CreatePrescription(w http.ResponseWriter, r *http.Request) {
+	var req dto.CreatePrescriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	defer r.Body.Close()
+
+	userId, _ := h.currentUser(r)
+	prescription, err := h.service.CreatePrescription(r.Context(), userId, mux.Vars(r)["id"], req)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusCreated, toPrescriptionResponse(*prescription))
+}
+
+// GetRecordPrescriptions godoc
+// @Summary Get prescriptions of a medical record
+// @Description Returns prescriptions issued for the medical record
+// @Tags Prescriptions
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Medical record ID"
+// @Success 200 {array} dto.PrescriptionResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /api/medical-records/{id}/prescriptions [get]
+func (h *PrescriptionHandler) GetRecordPrescriptions(w http.ResponseWriter, r *http.Request) {
+	userId, _ := h.currentUser(r)
+	prescriptions, err := h.service.GetPrescriptionsByRecord(userId, mux.Vars(r)["id"])
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, toPrescriptionResponseList(prescriptions))
+}
+
+// GetPrescription godoc
+// @Summary Get prescription
+// @Description Returns one prescription with its medication items
+// @Tags Prescriptions
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Prescription ID"
+// @Success 200 {object} dto.PrescriptionResponse
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/prescriptions/{id} [get]
+func (h *PrescriptionHandler) GetPrescription(w http.ResponseWriter, r *http.Request) {
+	userId, _ := h.currentUser(r)
+	prescription, err := h.service.GetPrescription(userId, mux.Vars(r)["id"])
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, toPrescriptionResponse(*prescription))
+}
+
+// DownloadPrescriptionPDF godoc
+// @Summary Download prescription PDF
+// @Description Returns the signed prescription as a PDF document
+// @Tags Prescriptions
+// @Security BearerAuth
+// @Produce application/pdf
+// @Param id path string true "Prescription ID"
+// @Success 200 {file} file
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/prescriptions/{id}/pdf [get]
+func (h *PrescriptionHandler) DownloadPrescriptionPDF(w http.ResponseWriter, r *http.Request) {
+	userId, _ := h.currentUser(r)
+	content, prescription, err := h.service.GetPrescriptionPDF(userId, mux.Vars(r)["id"])
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="prescription-%s.pdf"`, prescription.Id.String()[:8]))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(content)
+}
+
+// CreateInstructionTemplate godoc
+// @Summary Create instruction template
+// @Description Creates a reusable post-treatment instruction template for a clinic service. Allowed for admins of the clinic and doctors actively working there.
+// @Tags Prescriptions
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Clinic service ID"
+// @Param request body dto.InstructionTemplateRequest true "Template"
+// @Success 201 {object} dto.InstructionTemplateResponse
+// @Failure 400 {object} map[string]string
+// @Router /api/clinic-services/{id}/instruction-templates [post]
+func (h *PrescriptionHandler) CreateInstructionTemplate(w http.ResponseWriter, r *http.Request) {
+	var req dto.InstructionTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	defer r.Body.Close()
+
+	userId, role := h.currentUser(r)
+	template, err := h.service.CreateTemplate(userId, role, mux.Vars(r)["id"], req)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusCreated, toTemplateResponse(*template))
+}
+
+// GetInstructionTemplates godoc
+// @Summary Get instruction templates
+// @Description Returns instruction templates of a clinic service
+// @Tags Prescriptions
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Clinic service ID"
+// @Success 200 {array} dto.InstructionTemplateResponse
+// @Failure 400 {object} map[string]string
+// @Router /api/clinic-services/{id}/instruction-templates [get]
+func (h *PrescriptionHandler) GetInstructionTemplates(w http.ResponseWriter, r *http.Request) {
+	templates, err := h.service.GetTemplatesByClinicService(mux.Vars(r)["id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, toTemplateResponseList(templates))
+}
+
+// UpdateInstructionTemplate godoc
+// @Summary Update instruction template
+// @Description Updates title and text of an instruction template
+// @Tags Prescriptions
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Template ID"
+// @Param request body dto.InstructionTemplateRequest true "Template"
+// @Success 200 {object} dto.InstructionTemplateResponse
+// @Failure 400 {object} map[string]string
+// @Router /api/instruction-templates/{id} [put]
+func (h *PrescriptionHandler) UpdateInstructionTemplate(w http.ResponseWriter, r *http.Request) {
+	var req dto.InstructionTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	defer r.Body.Close()
+
+	userId, role := h.currentUser(r)
+	template, err := h.service.UpdateTemplate(userId, role, mux.Vars(r)["id"], req)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, toTemplateResponse(*template))
+}
+
+// DeleteInstructionTemplate godoc
+// @Summary Delete instruction template
+// @Description Deletes an instruction template
+// @Tags Prescriptions
+// @Security BearerAuth
+// @Param id path string true "Template ID"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/instruction-templates/{id} [delete]
+func (h *PrescriptionHandler) DeleteInstructionTemplate(w http.ResponseWriter, r *http.Request) {
+	userId, role := h.currentUser(r)
+	if err := h.service.DeleteTemplate(userId, role, mux.Vars(r)["id"]); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			respondError(w, http.StatusNotFound, "instruction template not found")
+			return
+		}
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *PrescriptionHandler) currentUser(r *http.Request) (string, string) {
+	claims, err := utils.GetClaims(utils.GetToken(r), h.cfg.JWTSecret)
+	if err != nil {
+		return "", ""
+	}
+	userId, _ := claims["user_id"].(string)
+	role, _ := claims["role"].(string)
+	return userId, role
+}
+
+func errorStatus(err error) int {
+	switch err.Error() {
+	case "access denied":
+		return http.StatusForbidden
+	case "prescription not found":
+		return http.StatusNotFound
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+func respondJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+func respondError(w http.ResponseWriter, status int, message string) {
+	respondJSON(w, status, map[string]string{"error": message})
+}
+
+func toPrescriptionResponse(p models.Prescription) dto.PrescriptionResponse {
+	items := make([]dto.PrescriptionItemResponse, 0, len(p.Items))
+	for _, item := range p.Items {
+		items = append(items, dto.PrescriptionItemResponse{
+			Id:        item.Id.String(),
+			DrugName:  item.DrugName,
+			Dose:      item.Dose,
+			Frequency: item.Frequency,
+			Duration:  item.Duration,
+			Notes:     item.Notes,
+		})
+	}
+
+	response := dto.PrescriptionResponse{
+		Id:              p.Id.String(),
+		MedicalRecordId: p.MedicalRecordId.String(),
+		DoctorId:        p.DoctorId.String(),
+		DoctorName:      p.DoctorName,
+		PatientId:       p.PatientId.String(),
+		Instructions:    p.Instructions,
+		Items:           items,
+		Signature:       p.Signature,
+		SignedAt:        p.SignedAt.Format(time.RFC3339),
+	}
+	if p.TemplateId != uuid.Nil {
+		response.TemplateId = p.TemplateId.String()
+	}
+	return response
+}
+
+func toPrescriptionResponseList(prescriptions []models.Prescription) []dto.PrescriptionResponse {
+	result := make([]dto.PrescriptionResponse, 0, len(prescriptions))
+	for _, p := range prescriptions {
+		result = append(result, toPrescriptionResponse(p))
+	}
+	return result
+}
+
+func toTemplateResponse(t models.InstructionTemplate) dto.InstructionTemplateResponse {
+	return dto.InstructionTemplateResponse{
+		Id:              t.Id.String(),
+		ClinicServiceId: t.ClinicServiceId.String(),
+		ServiceName:     t.ServiceName,
+		Title:           t.Title,
+		Body:            t.Body,
+		UpdatedAt:       t.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+func toTemplateResponseList(templates []models.InstructionTemplate) []dto.InstructionTemplateResponse {
+	result := make([]dto.InstructionTemplateResponse, 0, len(templates))
+	for _, t := range templates {
+		result = append(result, toTemplateResponse(t))
+	}
+	return result
+}
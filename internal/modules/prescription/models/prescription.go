@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Prescription struct {
+	Id              uuid.UUID
+	MedicalRecordId uuid.UUID
+	DoctorId        uuid.UUID
+	PatientId       uuid.UUID
+	Instructions    string
+	TemplateId      uuid.UUID
+	Signature       string
+	SignedAt        time.Time
+	CreatedAt       time.Time
+
+	DoctorName string
+	Items      []PrescriptionItem
+}
+
+type PrescriptionItem struct {
+	Id             uuid.UUID
+	PrescriptionId uuid.UUID
+	DrugName       string
+	Dose           string
+	Frequency      string
+	Duration       string
+	Notes          string
+	Position       int
+}
+
+type InstructionTemplate struct {
+	Id              uuid.UUID
+	ClinicServiceId uuid.UUID
+	ServiceName     string
+	Title           string
+	Body            string
+	CreatedBy       uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+// RecordContext is what a prescription needs to know about the visit it was
+// issued for: who treated whom, where and for which service.
+type RecordContext struct {
+	MedicalRecordId      uuid.UUID
+	DoctorId             uuid.UUID
+	DoctorUserId         uuid.UUID
+	DoctorName           string
+	DoctorSpecialization string
+	PatientId            uuid.UUID
+	PatientName          string
+	ClinicName           string
+	ServiceName          string
+	Diagnosis            string
+	AppointmentDate      time.Time
+}
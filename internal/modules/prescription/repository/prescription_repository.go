@@ -0,0 +1,374 @@
+package repository
+
+import (
+	"context"
+
+	"dental_clinic/internal/modules/prescription/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type PrescriptionRepository interface {
+	GetRecordContext(medicalRecordId uuid.UUID) (*models.RecordContext, error)
+
+	CreatePrescriptionTx(prescription *models.Prescription, tx pgx.Tx) error
+	CreatePrescriptionItemTx(item *models.PrescriptionItem, tx pgx.Tx) error
+	GetPrescriptionByID(id uuid.UUID) (*models.Prescription, error)
+	GetPrescriptionsByRecord(medicalRecordId uuid.UUID) ([]models.Prescription, error)
+
+	CreateTemplate(template *models.InstructionTemplate) (*models.InstructionTemplate, error)
+	GetTemplateByID(id uuid.UUID) (*models.InstructionTemplate, error)
+	GetTemplatesByClinicService(clinicServiceId uuid.UUID) ([]models.InstructionTemplate, error)
+	UpdateTemplate(template *models.InstructionTemplate) (*models.InstructionTemplate, error)
+	DeleteTemplate(id uuid.UUID) error
+	GetClinicServiceClinic(clinicServiceId uuid.UUID) (uuid.UUID, error)
+	GetTemplateClinic(templateId uuid.UUID) (uuid.UUID, error)
+	IsClinicAdmin(clinicId, userId uuid.UUID) (bool, error)
+	IsActiveClinicDoctor(clinicId, userId uuid.UUID) (bool, error)
+}
+
+type prescriptionRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewPrescriptionRepository(db *pgxpool.Pool) PrescriptionRepository {
+	return &prescriptionRepo{db: db}
+}
+
+func (r *prescriptionRepo) GetRecordContext(medicalRecordId uuid.UUID) (*models.RecordContext, error) {
+	query := `
+		SELECT
+			mr.id,
+			mr.doctor_id,
+			COALESCE(d.user_id, '00000000-0000-0000-0000-000000000000'::uuid),
+			COALESCE(d.name, ''),
+			COALESCE(d.specialization, ''),
+			COALESCE(mr.patient_id, '00000000-0000-0000-0000-000000000000'::uuid),
+			COALESCE(a.name, ''),
+			COALESCE(c.name, ''),
+			COALESCE(s.name, ''),
+			COALESCE(mr.diagnosis, ''),
+			COALESCE(a.start_time, mr.created_at)
+		FROM medical_records mr
+		LEFT JOIN doctors d ON d.id = mr.doctor_id
+		LEFT JOIN appointments a ON a.id = mr.appointment_id
+		LEFT JOIN clinic_addresses ca ON ca.id = a.clinic_address_id
+		LEFT JOIN clinics c ON c.id = ca.clinic_id
+		LEFT JOIN services s ON s.id = a.service_id
+		WHERE mr.id = $1
+	`
+	record := &models.RecordContext{}
+	err := r.db.QueryRow(context.Background(), query, medicalRecordId).Scan(
+		&record.MedicalRecordId,
+		&record.DoctorId,
+		&record.DoctorUserId,
+		&record.DoctorName,
+		&record.DoctorSpecialization,
+		&record.PatientId,
+		&record.PatientName,
+		&record.ClinicName,
+		&record.ServiceName,
+		&record.Diagnosis,
+		&record.AppointmentDate,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return record, nil
+}
+
+func (r *prescriptionRepo) CreatePrescriptionTx(prescription *models.Prescription, tx pgx.Tx) error {
+	query := `
+		INSERT INTO prescriptions (id, medical_record_id, doctor_id, patient_id, instructions, template_id, signature, signed_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, '00000000-0000-0000-0000-000000000000'::uuid), $7, $8, $9)
+	`
+	_, err := tx.Exec(
+		context.Background(),
+		query,
+		prescription.Id,
+		prescription.MedicalRecordId,
+		prescription.DoctorId,
+		prescription.PatientId,
+		prescription.Instructions,
+		prescription.TemplateId,
+		prescription.Signature,
+		prescription.SignedAt,
+		prescription.CreatedAt,
+	)
+	return err
+}
+
+func (r *prescriptionRepo) CreatePrescriptionItemTx(item *models.PrescriptionItem, tx pgx.Tx) error {
+	query := `
+		INSERT INTO prescription_items (id, prescription_id, drug_name, dose, frequency, duration, notes, position)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+	_, err := tx.Exec(context.Background(), query, item.Id, item.PrescriptionId, item.DrugName, item.Dose, item.Frequency, item.Duration, item.Notes, item.Position)
+	return err
+}
+
+const prescriptionColumns = `
+	p.id,
+	p.medical_record_id,
+	p.doctor_id,
+	COALESCE(p.patient_id, '00000000-0000-0000-0000-000000000000'::uuid),
+	COALESCE(p.instructions, ''),
+	COALESCE(p.template_id, '00000000-0000-0000-0000-000000000000'::uuid),
+	p.signature,
+	p.signed_at,
+	p.created_at,
+	COALESCE(d.name, '')
+`
+
+func scanPrescription(row pgx.Row, p *models.Prescription) error {
+	return row.Scan(
+		&p.Id,
+		&p.MedicalRecordId,
+		&p.DoctorId,
+		&p.PatientId,
+		&p.Instructions,
+		&p.TemplateId,
+		&p.Signature,
+		&p.SignedAt,
+		&p.CreatedAt,
+		&p.DoctorName,
+	)
+}
+
+func (r *prescriptionRepo) GetPrescriptionByID(id uuid.UUID) (*models.Prescription, error) {
+	query := `SELECT ` + prescriptionColumns + `
+		FROM prescriptions p
+		LEFT JOIN doctors d ON d.id = p.doctor_id
+		WHERE p.id = $1
+	`
+	prescription := &models.Prescription{}
+	if err := scanPrescription(r.db.QueryRow(context.Background(), query, id), prescription); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	items, err := r.getItems([]uuid.UUID{prescription.Id})
+	if err != nil {
+		return nil, err
+	}
+	prescription.Items = items[prescription.Id]
+	return prescription, nil
+}
+
+func (r *prescriptionRepo) GetPrescriptionsByRecord(medicalRecordId uuid.UUID) ([]models.Prescription, error) {
+	query := `SELECT ` + prescriptionColumns + `
+		FROM prescriptions p
+		LEFT JOIN doctors d ON d.id = p.doctor_id
+		WHERE p.medical_record_id = $1
+		ORDER BY p.signed_at DESC
+	`
+	rows, err := r.db.Query(context.Background(), query, medicalRecordId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prescriptions := make([]models.Prescription, 0)
+	ids := make([]uuid.UUID, 0)
+	for rows.Next() {
+		var prescription models.Prescription
+		if err := scanPrescription(rows, &prescription); err != nil {
+			return nil, err
+		}
+		prescriptions = append(prescriptions, prescription)
+		ids = append(ids, prescription.Id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	items, err := r.getItems(ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range prescriptions {
+		prescriptions[i].Items = items[prescriptions[i].Id]
+	}
+	return prescriptions, nil
+}
+
+func (r *prescriptionRepo) getItems(prescriptionIds []uuid.UUID) (map[uuid.UUID][]models.PrescriptionItem, error) {
+	result := make(map[uuid.UUID][]models.PrescriptionItem)
+	if len(prescriptionIds) == 0 {
+		return result, nil
+	}
+
+	query := `
+		SELECT id, prescription_id, drug_name, dose, frequency, duration, COALESCE(notes, ''), position
+		FROM prescription_items
+		WHERE prescription_id = ANY($1)
+		ORDER BY position
+	`
+	rows, err := r.db.Query(context.Background(), query, prescriptionIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item models.PrescriptionItem
+		if err := rows.Scan(&item.Id, &item.PrescriptionId, &item.DrugName, &item.Dose, &item.Frequency, &item.Duration, &item.Notes, &item.Position); err != nil {
+			return nil, err
+		}
+		result[item.PrescriptionId] = append(result[item.PrescriptionId], item)
+	}
+	return result, rows.Err()
+}
+
+func (r *prescriptionRepo) CreateTemplate(template *models.InstructionTemplate) (*models.InstructionTemplate, error) {
+	query := `
+		INSERT INTO instruction_templates (id, clinic_service_id, title, body, created_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	_, err := r.db.Exec(context.Background(), query, template.Id, template.ClinicServiceId, template.Title, template.Body, template.CreatedBy, template.CreatedAt, template.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return r.GetTemplateByID(template.Id)
+}
+
+const templateColumns = `
+	t.id, t.clinic_service_id, COALESCE(s.name, ''), t.title, t.body,
+	COALESCE(t.created_by, '00000000-0000-0000-0000-000000000000'::uuid), t.created_at, t.updated_at
+`
+
+func (r *prescriptionRepo) GetTemplateByID(id uuid.UUID) (*models.InstructionTemplate, error) {
+	query := `SELECT ` + templateColumns + `
+		FROM instruction_templates t
+		LEFT JOIN clinic_services cs ON cs.id = t.clinic_service_id
+		LEFT JOIN services s ON s.id = cs.service_id
+		WHERE t.id = $1
+	`
+	template := &models.InstructionTemplate{}
+	err := r.db.QueryRow(context.Background(), query, id).Scan(
+		&template.Id, &template.ClinicServiceId, &template.ServiceName, &template.Title, &template.Body,
+		&template.CreatedBy, &template.CreatedAt, &template.UpdatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return template, nil
+}
+
+func (r *prescriptionRepo) GetTemplatesByClinicService(clinicServiceId uuid.UUID) ([]models.InstructionTemplate, error) {
+	query := `SELECT ` + templateColumns + `
+		FROM instruction_templates t
+		LEFT JOIN clinic_services cs ON cs.id = t.clinic_service_id
+		LEFT JOIN services s ON s.id = cs.service_id
+		WHERE t.clinic_service_id = $1
+		ORDER BY t.title
+	`
+	rows, err := r.db.Query(context.Background(), query, clinicServiceId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	templates := make([]models.InstructionTemplate, 0)
+	for rows.Next() {
+		var template models.InstructionTemplate
+		if err := rows.Scan(
+			&template.Id, &template.ClinicServiceId, &template.ServiceName, &template.Title, &template.Body,
+			&template.CreatedBy, &template.CreatedAt, &template.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		templates = append(templates, template)
+	}
+	return templates, rows.Err()
+}
+
+func (r *prescriptionRepo) UpdateTemplate(template *models.InstructionTemplate) (*models.InstructionTemplate, error) {
+	query := `UPDATE instruction_templates SET title = $2, body = $3, updated_at = $4 WHERE id = $1`
+	result, err := r.db.Exec(context.Background(), query, template.Id, template.Title, template.Body, template.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if result.RowsAffected() == 0 {
+		return nil, nil
+	}
+	return r.GetTemplateByID(template.Id)
+}
+
+func (r *prescriptionRepo) DeleteTemplate(id uuid.UUID) error {
+	result, err := r.db.Exec(context.Background(), `DELETE FROM instruction_templates WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// GetClinicServiceClinic returns the clinic offering the clinic service, or
+// uuid.Nil when there is no such clinic service.
+func (r *prescriptionRepo) GetClinicServiceClinic(clinicServiceId uuid.UUID) (uuid.UUID, error) {
+	var clinicId uuid.UUID
+	err := r.db.QueryRow(context.Background(), `SELECT clinic_id FROM clinic_services WHERE id = $1`, clinicServiceId).Scan(&clinicId)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return uuid.Nil, nil
+		}
+		return uuid.Nil, err
+	}
+	return clinicId, nil
+}
+
+// GetTemplateClinic returns the clinic the template's clinic service
+// belongs to, or uuid.Nil when there is no such template.
+func (r *prescriptionRepo) GetTemplateClinic(templateId uuid.UUID) (uuid.UUID, error) {
+	query := `
+		SELECT cs.clinic_id
+		FROM instruction_templates it
+		JOIN clinic_services cs ON cs.id = it.clinic_service_id
+		WHERE it.id = $1
+	`
+	var clinicId uuid.UUID
+	err := r.db.QueryRow(context.Background(), query, templateId).Scan(&clinicId)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return uuid.Nil, nil
+		}
+		return uuid.Nil, err
+	}
+	return clinicId, nil
+}
+
+func (r *prescriptionRepo) IsClinicAdmin(clinicId, userId uuid.UUID) (bool, error) {
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM clinic_admins WHERE clinic_id = $1 AND user_id = $2)`
+	err := r.db.QueryRow(context.Background(), query, clinicId, userId).Scan(&exists)
+	return exists, err
+}
+
+// IsActiveClinicDoctor reports whether the user is a doctor with an active
+// membership at the clinic.
+func (r *prescriptionRepo) IsActiveClinicDoctor(clinicId, userId uuid.UUID) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM doctor_clinics dc
+			JOIN doctors d ON d.id = dc.doctor_id
+			WHERE dc.clinic_id = $1 AND d.user_id = $2 AND dc.status = 'active'
+		)
+	`
+	var exists bool
+	err := r.db.QueryRow(context.Background(), query, clinicId, userId).Scan(&exists)
+	return exists, err
+}
@@ -0,0 +1,43 @@
+package prescription
+
+import (
+	"dental_clinic/internal/config"
+	"dental_clinic/internal/modules/prescription/handlers"
+	"dental_clinic/internal/modules/prescription/repository"
+	"dental_clinic/internal/modules/prescription/services"
+
+	medical_recordRepository "dental_clinic/internal/modules/medical_record/repository"
+	medical_recordServices "dental_clinic/internal/modules/medical_record/services"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func newHandler(db *pgxpool.Pool, cfg *config.Config) *handlers.PrescriptionHandler {
+	repo := repository.NewPrescriptionRepository(db)
+
+	medical_recordRepo := medical_recordRepository.NewMedicalRecordRepository(db)
+	medical_recordService := medical_recordServices.NewMedicalRecordService(medical_recordRepo)
+
+	service := services.NewPrescriptionService(repo, db, *cfg, *medical_recordService)
+	return handlers.NewPrescriptionHandler(service, *cfg)
+}
+
+func RegisterPrivateRoutes(r *mux.Router, db *pgxpool.Pool, cfg *config.Config) {
+	handler := newHandler(db, cfg)
+
+	r.HandleFunc("/medical-records/{id}/prescriptions", handler.GetRecordPrescriptions).Methods("GET")
+	r.HandleFunc("/prescriptions/{id}", handler.GetPrescription).Methods("GET")
+	r.HandleFunc("/prescriptions/{id}/pdf", handler.DownloadPrescriptionPDF).Methods("GET")
+
+	r.HandleFunc("/clinic-services/{id}/instruction-templates", handler.GetInstructionTemplates).Methods("GET")
+	r.HandleFunc("/clinic-services/{id}/instruction-templates", handler.CreateInstructionTemplate).Methods("POST")
+	r.HandleFunc("/instruction-templates/{id}", handler.UpdateInstructionTemplate).Methods("PUT")
+	r.HandleFunc("/instruction-templates/{id}", handler.DeleteInstructionTemplate).Methods("DELETE")
+}
+
+func RegisterDoctorRoutes(r *mux.Router, db *pgxpool.Pool, cfg *config.Config) {
+	handler := newHandler(db, cfg)
+
+	r.HandleFunc("/medical-records/{id}/prescriptions", handler.CreatePrescription).Methods("POST")
+}
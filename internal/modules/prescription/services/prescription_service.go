@@ -0,0 +1,397 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"dental_clinic/internal/config"
+	"dental_clinic/internal/modules/prescription/dto"
+	"dental_clinic/internal/modules/prescription/models"
+	"dental_clinic/internal/modules/prescription/repository"
+	reportModels "dental_clinic/internal/modules/reports/models"
+	reportServices "dental_clinic/internal/modules/reports/services"
+
+	medical_recordServices "dental_clinic/internal/modules/medical_record/services"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// prescriptionValidity is printed on the document as the "valid until" date.
+const prescriptionValidity = 30 * 24 * time.Hour
+
+type PrescriptionService struct {
+	repo              repository.PrescriptionRepository
+	db                *pgxpool.Pool
+	cfx               config.Config
+	medical_recordSrv medical_recordServices.MedicalRecordService
+}
+
+func NewPrescriptionService(repo repository.PrescriptionRepository, db *pgxpool.Pool, cfx config.Config, medical_recordSrv medical_recordServices.MedicalRecordService) *PrescriptionService {
+	return &PrescriptionService{
+		repo:              repo,
+		db:                db,
+		cfx:               cfx,
+		medical_recordSrv: medical_recordSrv,
+	}
+}
+
+// CreatePrescription issues and signs a prescription. Only the doctor who
+// owns the medical record may do so.
+func (s *PrescriptionService) CreatePrescription(ctx context.Context, userId, medicalRecordId string, req dto.CreatePrescriptionRequest) (*models.Prescription, error) {
+	recordId, err := uuid.Parse(medicalRecordId)
+	if err != nil {
+		return nil, errors.New("invalid medical record id")
+	}
+	userUUID, err := uuid.Parse(userId)
+	if err != nil {
+		return nil, errors.New("invalid user id")
+	}
+
+	record, err := s.repo.GetRecordContext(recordId)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, errors.New("medical record not found")
+	}
+	if record.DoctorUserId != userUUID {
+		return nil, errors.New("only the treating doctor can issue prescriptions")
+	}
+
+	instructions := strings.TrimSpace(req.Instructions)
+	var templateId uuid.UUID
+	if req.TemplateId != "" {
+		templateId, err = uuid.Parse(req.TemplateId)
+		if err != nil {
+			return nil, errors.New("invalid template id")
+		}
+		template, err := s.repo.GetTemplateByID(templateId)
+		if err != nil {
+			return nil, err
+		}
+		if template == nil {
+			return nil, errors.New("instruction template not found")
+		}
+		if instructions == "" {
+			instructions = template.Body
+		}
+	}
+
+	if len(req.Items) == 0 && instructions == "" {
+		return nil, errors.New("prescription must contain medication or instructions")
+	}
+
+	// timestamps are stored without zone and with microsecond precision, so
+	// sign exactly what will be read back
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	prescription := &models.Prescription{
+		Id:              uuid.New(),
+		MedicalRecordId: record.MedicalRecordId,
+		DoctorId:        record.DoctorId,
+		PatientId:       record.PatientId,
+		Instructions:    instructions,
+		TemplateId:      templateId,
+		SignedAt:        now,
+		CreatedAt:       now,
+		DoctorName:      record.DoctorName,
+		Items:           make([]models.PrescriptionItem, 0, len(req.Items)),
+	}
+
+	for i, item := range req.Items {
+		drug := strings.TrimSpace(item.DrugName)
+		dose := strings.TrimSpace(item.Dose)
+		frequency := strings.TrimSpace(item.Frequency)
+		duration := strings.TrimSpace(item.Duration)
+		if drug == "" || dose == "" || frequency == "" || duration == "" {
+			return nil, errors.New("drug_name, dose, frequency and duration are required for every item")
+		}
+		prescription.Items = append(prescription.Items, models.PrescriptionItem{
+			Id:             uuid.New(),
+			PrescriptionId: prescription.Id,
+			DrugName:       drug,
+			Dose:           dose,
+			Frequency:      frequency,
+			Duration:       duration,
+			Notes:          strings.TrimSpace(item.Notes),
+			Position:       i,
+		})
+	}
+
+	prescription.Signature = s.sign(prescription)
+
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := s.repo.CreatePrescriptionTx(prescription, tx); err != nil {
+		return nil, err
+	}
+	for i := range prescription.Items {
+		if err := s.repo.CreatePrescriptionItemTx(&prescription.Items[i], tx); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return prescription, nil
+}
+
+func (s *PrescriptionService) GetPrescriptionsByRecord(userId, medicalRecordId string) ([]models.Prescription, error) {
+	recordId, err := uuid.Parse(medicalRecordId)
+	if err != nil {
+		return nil, errors.New("invalid medical record id")
+	}
+	if err := s.checkAccess(recordId, userId); err != nil {
+		return nil, err
+	}
+	return s.repo.GetPrescriptionsByRecord(recordId)
+}
+
+func (s *PrescriptionService) GetPrescription(userId, id string) (*models.Prescription, error) {
+	prescriptionId, err := uuid.Parse(id)
+	if err != nil {
+		return nil, errors.New("invalid prescription id")
+	}
+	prescription, err := s.repo.GetPrescriptionByID(prescriptionId)
+	if err != nil {
+		return nil, err
+	}
+	if prescription == nil {
+		return nil, errors.New("prescription not found")
+	}
+	if err := s.checkAccess(prescription.MedicalRecordId, userId); err != nil {
+		return nil, err
+	}
+	return prescription, nil
+}
+
+// GetPrescriptionPDF renders the signed prescription for download.
+func (s *PrescriptionService) GetPrescriptionPDF(userId, id string) ([]byte, *models.Prescription, error) {
+	prescription, err := s.GetPrescription(userId, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !s.VerifySignature(prescription) {
+		return nil, nil, errors.New("prescription signature is invalid")
+	}
+
+	record, err := s.repo.GetRecordContext(prescription.MedicalRecordId)
+	if err != nil {
+		return nil, nil, err
+	}
+	if record == nil {
+		return nil, nil, errors.New("medical record not found")
+	}
+
+	doc := reportModels.PrescriptionDocument{
+		Number:               strings.ToUpper(prescription.Id.String()[:8]),
+		ClinicName:           record.ClinicName,
+		DoctorName:           record.DoctorName,
+		DoctorSpecialization: record.DoctorSpecialization,
+		PatientName:          record.PatientName,
+		ServiceName:          record.ServiceName,
+		Diagnosis:            record.Diagnosis,
+		VisitDate:            record.AppointmentDate,
+		SignedAt:             prescription.SignedAt,
+		ValidUntil:           prescription.SignedAt.Add(prescriptionValidity),
+		Instructions:         prescription.Instructions,
+		Signature:            prescription.Signature,
+		Items:                make([]reportModels.PrescriptionDocumentItem, 0, len(prescription.Items)),
+	}
+	for _, item := range prescription.Items {
+		doc.Items = append(doc.Items, reportModels.PrescriptionDocumentItem{
+			DrugName:  item.DrugName,
+			Dose:      item.Dose,
+			Frequency: item.Frequency,
+			Duration:  item.Duration,
+			Notes:     item.Notes,
+		})
+	}
+
+	content, err := reportServices.BuildPrescriptionPDF(doc)
+	if err != nil {
+		return nil, nil, err
+	}
+	return content, prescription, nil
+}
+
+// VerifySignature recomputes the signature from the stored content, so any
+// change made to the prescription after signing is detected.
+func (s *PrescriptionService) VerifySignature(prescription *models.Prescription) bool {
+	return hmac.Equal([]byte(s.sign(prescription)), []byte(prescription.Signature))
+}
+
+// sign covers who prescribed what to whom and when, down to every item.
+func (s *PrescriptionService) sign(p *models.Prescription) string {
+	mac := hmac.New(sha256.New, []byte(s.cfx.JWTSecret+":prescription-signature"))
+	parts := []string{
+		p.Id.String(),
+		p.MedicalRecordId.String(),
+		p.DoctorId.String(),
+		p.PatientId.String(),
+		p.SignedAt.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano),
+		p.Instructions,
+	}
+	for _, item := range p.Items {
+		parts = append(parts, item.DrugName, item.Dose, item.Frequency, item.Duration, item.Notes)
+	}
+	mac.Write([]byte(strings.Join(parts, "\x1f")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *PrescriptionService) checkAccess(recordId uuid.UUID, userId string) error {
+	allowed, err := s.medical_recordSrv.CanAccessMedicalRecord(recordId.String(), userId)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return errors.New("access denied")
+	}
+	return nil
+}
+
+// ── Instruction templates ─────────────────────────────────────────────────────
+
+func (s *PrescriptionService) CreateTemplate(userId, role, clinicServiceId string, req dto.InstructionTemplateRequest) (*models.InstructionTemplate, error) {
+	serviceId, err := uuid.Parse(clinicServiceId)
+	if err != nil {
+		return nil, errors.New("invalid clinic service id")
+	}
+	userUUID, err := uuid.Parse(userId)
+	if err != nil {
+		return nil, errors.New("invalid user id")
+	}
+	clinicId, err := s.repo.GetClinicServiceClinic(serviceId)
+	if err != nil {
+		return nil, err
+	}
+	if clinicId == uuid.Nil {
+		return nil, errors.New("clinic service not found")
+	}
+	if err := s.canEditTemplates(clinicId, userId, role); err != nil {
+		return nil, err
+	}
+	if err := validateTemplate(req); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	return s.repo.CreateTemplate(&models.InstructionTemplate{
+		Id:              uuid.New(),
+		ClinicServiceId: serviceId,
+		Title:           strings.TrimSpace(req.Title),
+		Body:            strings.TrimSpace(req.Body),
+		CreatedBy:       userUUID,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	})
+}
+
+func (s *PrescriptionService) GetTemplatesByClinicService(clinicServiceId string) ([]models.InstructionTemplate, error) {
+	serviceId, err := uuid.Parse(clinicServiceId)
+	if err != nil {
+		return nil, errors.New("invalid clinic service id")
+	}
+	return s.repo.GetTemplatesByClinicService(serviceId)
+}
+
+func (s *PrescriptionService) UpdateTemplate(userId, role, id string, req dto.InstructionTemplateRequest) (*models.InstructionTemplate, error) {
+	templateId, err := uuid.Parse(id)
+	if err != nil {
+		return nil, errors.New("invalid template id")
+	}
+	clinicId, err := s.repo.GetTemplateClinic(templateId)
+	if err != nil {
+		return nil, err
+	}
+	if clinicId == uuid.Nil {
+		return nil, errors.New("instruction template not found")
+	}
+	if err := s.canEditTemplates(clinicId, userId, role); err != nil {
+		return nil, err
+	}
+	if err := validateTemplate(req); err != nil {
+		return nil, err
+	}
+
+	template, err := s.repo.UpdateTemplate(&models.InstructionTemplate{
+		Id:        templateId,
+		Title:     strings.TrimSpace(req.Title),
+		Body:      strings.TrimSpace(req.Body),
+		UpdatedAt: time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if template == nil {
+		return nil, errors.New("instruction template not found")
+	}
+	return template, nil
+}
+
+func (s *PrescriptionService) DeleteTemplate(userId, role, id string) error {
+	templateId, err := uuid.Parse(id)
+	if err != nil {
+		return errors.New("invalid template id")
+	}
+	clinicId, err := s.repo.GetTemplateClinic(templateId)
+	if err != nil {
+		return err
+	}
+	if clinicId == uuid.Nil {
+		return pgx.ErrNoRows
+	}
+	if err := s.canEditTemplates(clinicId, userId, role); err != nil {
+		return err
+	}
+	return s.repo.DeleteTemplate(templateId)
+}
+
+// canEditTemplates allows platform admins, the admins of the clinic and the
+// doctors actively working there.
+func (s *PrescriptionService) canEditTemplates(clinicId uuid.UUID, userId, role string) error {
+	if role == "admin" {
+		return nil
+	}
+	if role != "clinic_admin" && role != "doctor" {
+		return errors.New("do not have rights")
+	}
+	userUUID, err := uuid.Parse(userId)
+	if err != nil {
+		return errors.New("invalid user id")
+	}
+	var allowed bool
+	if role == "clinic_admin" {
+		allowed, err = s.repo.IsClinicAdmin(clinicId, userUUID)
+	} else {
+		allowed, err = s.repo.IsActiveClinicDoctor(clinicId, userUUID)
+	}
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return errors.New("do not have rights")
+	}
+	return nil
+}
+
+func validateTemplate(req dto.InstructionTemplateRequest) error {
+	if strings.TrimSpace(req.Title) == "" {
+		return errors.New("template title is required")
+	}
+	if strings.TrimSpace(req.Body) == "" {
+		return errors.New("template body is required")
+	}
+	return nil
+}
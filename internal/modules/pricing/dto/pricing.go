@@ -0,0 +1,101 @@
+package dto
+
+// PriceRuleRequest sets either a fixed price or a percent change of the list
+// price (negative for a discount). Dates are YYYY-MM-DD, times HH:MM, and
+// weekdays count from 0 = Sunday; empty fields do not restrict the rule.
+type PriceRuleRequest struct {
+	Name      string   `json:"name"`
+	Price     *float64 `json:"price"`
+	Percent   *float64 `json:"percent"`
+	ValidFrom string   `json:"valid_from"`
+	ValidTo   string   `json:"valid_to"`
+	Weekdays  []int    `json:"weekdays"`
+	StartsAt  string   `json:"starts_at"`
+	EndsAt    string   `json:"ends_at"`
+	Priority  int      `json:"priority"`
+	IsActive  *bool    `json:"is_active"`
+}
+
+type PriceRuleResponse struct {
+	Id              string   `json:"id"`
+	ClinicServiceId string   `json:"clinic_service_id"`
+	Name            string   `json:"name"`
+	Price           *float64 `json:"price"`
+	Percent         *float64 `json:"percent"`
+	ValidFrom       string   `json:"valid_from,omitempty"`
+	ValidTo         string   `json:"valid_to,omitempty"`
+	Weekdays        []int    `json:"weekdays"`
+	StartsAt        string   `json:"starts_at,omitempty"`
+	EndsAt          string   `json:"ends_at,omitempty"`
+	Priority        int      `json:"priority"`
+	IsActive        bool     `json:"is_active"`
+	CreatedAt       string   `json:"created_at"`
+}
+
+// PromoCodeRequest creates or updates a promo code. An empty clinic_id makes
+// the code valid at every clinic and is reserved to platform admins; an empty
+// service_id makes it valid for every service. Times are RFC 3339.
+type PromoCodeRequest struct {
+	ClinicId          string  `json:"clinic_id"`
+	Code              string  `json:"code"`
+	Description       string  `json:"description"`
+	DiscountType      string  `json:"discount_type"`
+	DiscountValue     float64 `json:"discount_value"`
+	ServiceId         string  `json:"service_id"`
+	MinPrice          float64 `json:"min_price"`
+	MaxUses           *int    `json:"max_uses"`
+	MaxUsesPerPatient *int    `json:"max_uses_per_patient"`
+	StartsAt          string  `json:"starts_at"`
+	ExpiresAt         string  `json:"expires_at"`
+	IsActive          *bool   `json:"is_active"`
+}
+
+type PromoCodeResponse struct {
+	Id                string  `json:"id"`
+	ClinicId          string  `json:"clinic_id,omitempty"`
+	Code              string  `json:"code"`
+	Description       string  `json:"description"`
+	DiscountType      string  `json:"discount_type"`
+	DiscountValue     float64 `json:"discount_value"`
+	ServiceId         string  `json:"service_id,omitempty"`
+	MinPrice          float64 `json:"min_price"`
+	MaxUses           *int    `json:"max_uses"`
+	MaxUsesPerPatient *int    `json:"max_uses_per_patient"`
+	UsedCount         int     `json:"used_count"`
+	StartsAt          string  `json:"starts_at,omitempty"`
+	ExpiresAt         string  `json:"expires_at,omitempty"`
+	IsActive          bool    `json:"is_active"`
+	CreatedAt         string  `json:"created_at"`
+}
+
+type PricingSettingsRequest struct {
+	FirstVisitDiscountPercent float64 `json:"first_visit_discount_percent"`
+}
+
+type PricingSettingsResponse struct {
+	ClinicId                  string  `json:"clinic_id"`
+	FirstVisitDiscountPercent float64 `json:"first_visit_discount_percent"`
+	UpdatedAt                 string  `json:"updated_at,omitempty"`
+}
+
+type QuoteResponse struct {
+	ListPrice      float64 `json:"list_price"`
+	BasePrice      float64 `json:"base_price"`
+	Discount       float64 `json:"discount"`
+	Total          float64 `json:"total"`
+	PriceRuleId    string  `json:"price_rule_id,omitempty"`
+	PriceRuleName  string  `json:"price_rule_name,omitempty"`
+	PromoCode      string  `json:"promo_code,omitempty"`
+	DiscountReason string  `json:"discount_reason,omitempty"`
+}
+
+// QuoteRequest asks for the price of booking a slot. Email identifies guests
+// for the first-visit discount and promo code limits.
+type QuoteRequest struct {
+	DoctorId        string
+	ClinicAddressId string
+	ServiceId       string
+	SlotId          string
+	PromoCode       string
+	Email           string
+}
@@ -0,0 +1,336 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"dental_clinic/internal/config"
+	"dental_clinic/internal/modules/pricing/dto"
+	"dental_clinic/internal/modules/pricing/services"
+	"dental_clinic/internal/utils"
+
+	"github.com/gorilla/mux"
+)
+
+type PricingHandler struct {
+	service *services.PricingService
+	cfg     config.Config
+}
+
+func NewPricingHandler(service *services.PricingService, cfg config.Config) *PricingHandler {
+	return &PricingHandler{service: service, cfg: cfg}
+}
+
+// GetQuote godoc
+// @Summary Price a slot
+// @Description Returns what booking the slot would cost: the list price, the price after price rules, and the first-visit or promo code discount, whichever is larger. Logged-in patients are recognised from the token, guests by email.
+// @Tags Pricing
+// @Produce json
+// @Param doctor_id query string true "Doctor ID"
+// @Param clinic_address_id query string true "Clinic address ID"
+// @Param service_id query string true "Service ID"
+// @Param slot_id query string true "Slot ID"
+// @Param promo_code query string false "Promo code"
+// @Param email query string false "Guest email"
+// @Success 200 {object} dto.QuoteResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/pricing/quote [get]
+func (h *PricingHandler) GetQuote(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	req := dto.QuoteRequest{
+		DoctorId:        query.Get("doctor_id"),
+		ClinicAddressId: query.Get("clinic_address_id"),
+		ServiceId:       query.Get("service_id"),
+		SlotId:          query.Get("slot_id"),
+		PromoCode:       query.Get("promo_code"),
+		Email:           query.Get("email"),
+	}
+
+	userId, _ := h.currentUser(r)
+	quote, err := h.service.Quote(r.Context(), userId, req)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, services.ToQuoteResponse(*quote))
+}
+
+// GetPriceRules godoc
+// @Summary List price rules
+// @Description Lists the price rules of a clinic service, highest priority first
+// @Tags Pricing
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Clinic service ID"
+// @Success 200 {array} dto.PriceRuleResponse
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/clinic-services/{id}/price-rules [get]
+func (h *PricingHandler) GetPriceRules(w http.ResponseWriter, r *http.Request) {
+	userId, role := h.currentUser(r)
+	rules, err := h.service.GetPriceRules(mux.Vars(r)["id"], userId, role)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, services.ToPriceRuleResponseList(rules))
+}
+
+// CreatePriceRule godoc
+// @Summary Create price rule
+// @Description Adds a fixed price or a percent change for a clinic service, limited to a date range, weekdays or a time of day. The highest priority matching rule prices a booking.
+// @Tags Pricing
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Clinic service ID"
+// @Param request body dto.PriceRuleRequest true "Price rule"
+// @Success 201 {object} dto.PriceRuleResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/clinic-services/{id}/price-rules [post]
+func (h *PricingHandler) CreatePriceRule(w http.ResponseWriter, r *http.Request) {
+	var req dto.PriceRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	userId, role := h.currentUser(r)
+	rule, err := h.service.CreatePriceRule(mux.Vars(r)["id"], userId, role, req)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusCreated, services.ToPriceRuleResponse(*rule))
+}
+
+// UpdatePriceRule godoc
+// @Summary Update price rule
+// @Tags Pricing
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Price rule ID"
+// @Param request body dto.PriceRuleRequest true "Price rule"
+// @Success 200 {object} dto.PriceRuleResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/price-rules/{id} [put]
+func (h *PricingHandler) UpdatePriceRule(w http.ResponseWriter, r *http.Request) {
+	var req dto.PriceRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	userId, role := h.currentUser(r)
+	rule, err := h.service.UpdatePriceRule(mux.Vars(r)["id"], userId, role, req)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, services.ToPriceRuleResponse(*rule))
+}
+
+// DeletePriceRule godoc
+// @Summary Delete price rule
+// @Description Removes a price rule. Appointments already booked keep their price.
+// @Tags Pricing
+// @Security BearerAuth
+// @Param id path string true "Price rule ID"
+// @Success 204
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/price-rules/{id} [delete]
+func (h *PricingHandler) DeletePriceRule(w http.ResponseWriter, r *http.Request) {
+	userId, role := h.currentUser(r)
+	if err := h.service.DeletePriceRule(mux.Vars(r)["id"], userId, role); err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetPromoCodes godoc
+// @Summary List promo codes
+// @Description Lists the promo codes of a clinic with how often they were used. Platform admins may omit clinic_id to see every code.
+// @Tags Pricing
+// @Security BearerAuth
+// @Produce json
+// @Param clinic_id query string false "Clinic ID"
+// @Success 200 {array} dto.PromoCodeResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /api/promo-codes [get]
+func (h *PricingHandler) GetPromoCodes(w http.ResponseWriter, r *http.Request) {
+	userId, role := h.currentUser(r)
+	codes, err := h.service.GetPromoCodes(r.URL.Query().Get("clinic_id"), userId, role)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, services.ToPromoCodeResponseList(codes))
+}
+
+// CreatePromoCode godoc
+// @Summary Create promo code
+// @Description Creates a percent or fixed discount code with optional usage limits, validity window, service and minimum price. Codes without a clinic are valid everywhere and can only be created by platform admins.
+// @Tags Pricing
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body dto.PromoCodeRequest true "Promo code"
+// @Success 201 {object} dto.PromoCodeResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /api/promo-codes [post]
+func (h *PricingHandler) CreatePromoCode(w http.ResponseWriter, r *http.Request) {
+	var req dto.PromoCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	userId, role := h.currentUser(r)
+	code, err := h.service.CreatePromoCode(userId, role, req)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusCreated, services.ToPromoCodeResponse(*code))
+}
+
+// UpdatePromoCode godoc
+// @Summary Update promo code
+// @Description Changes the terms of a promo code. The code and its clinic stay the same.
+// @Tags Pricing
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Promo code ID"
+// @Param request body dto.PromoCodeRequest true "Promo code"
+// @Success 200 {object} dto.PromoCodeResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/promo-codes/{id} [put]
+func (h *PricingHandler) UpdatePromoCode(w http.ResponseWriter, r *http.Request) {
+	var req dto.PromoCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	userId, role := h.currentUser(r)
+	code, err := h.service.UpdatePromoCode(mux.Vars(r)["id"], userId, role, req)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, services.ToPromoCodeResponse(*code))
+}
+
+// DeactivatePromoCode godoc
+// @Summary Deactivate promo code
+// @Description Stops a promo code from being used. The code is kept for the appointments booked with it.
+// @Tags Pricing
+// @Security BearerAuth
+// @Param id path string true "Promo code ID"
+// @Success 204
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/promo-codes/{id} [delete]
+func (h *PricingHandler) DeactivatePromoCode(w http.ResponseWriter, r *http.Request) {
+	userId, role := h.currentUser(r)
+	if err := h.service.DeactivatePromoCode(mux.Vars(r)["id"], userId, role); err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetPricingSettings godoc
+// @Summary Get clinic pricing settings
+// @Tags Pricing
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Clinic ID"
+// @Success 200 {object} dto.PricingSettingsResponse
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/clinics/{id}/pricing-settings [get]
+func (h *PricingHandler) GetPricingSettings(w http.ResponseWriter, r *http.Request) {
+	userId, role := h.currentUser(r)
+	settings, err := h.service.GetPricingSettings(mux.Vars(r)["id"], userId, role)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, services.ToPricingSettingsResponse(*settings))
+}
+
+// UpdatePricingSettings godoc
+// @Summary Update clinic pricing settings
+// @Description Sets the discount given on a patient's first visit to the clinic. It does not stack with promo codes; the larger discount applies.
+// @Tags Pricing
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Clinic ID"
+// @Param request body dto.PricingSettingsRequest true "Pricing settings"
+// @Success 200 {object} dto.PricingSettingsResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/clinics/{id}/pricing-settings [put]
+func (h *PricingHandler) UpdatePricingSettings(w http.ResponseWriter, r *http.Request) {
+	var req dto.PricingSettingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	userId, role := h.currentUser(r)
+	settings, err := h.service.UpdatePricingSettings(mux.Vars(r)["id"], userId, role, req)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, services.ToPricingSettingsResponse(*settings))
+}
+
+func (h *PricingHandler) currentUser(r *http.Request) (string, string) {
+	claims, err := utils.GetClaims(utils.GetToken(r), h.cfg.JWTSecret)
+	if err != nil {
+		return "", ""
+	}
+	userId, _ := claims["user_id"].(string)
+	role, _ := claims["role"].(string)
+	return userId, role
+}
+
+func errorStatus(err error) int {
+	switch {
+	case err.Error() == "do not have rights":
+		return http.StatusForbidden
+	case strings.HasSuffix(err.Error(), "not found"):
+		return http.StatusNotFound
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+func respondJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+func respondError(w http.ResponseWriter, status int, message string) {
+	respondJSON(w, status, map[string]string{"error": message})
+}
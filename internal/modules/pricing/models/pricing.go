@@ -0,0 +1,75 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PriceRule changes the price of a clinic service for a period, for some
+// weekdays or for a time of day. Either Price or Percent is set. Weekdays
+// follow time.Weekday (0 = Sunday); StartsAt and EndsAt are "15:04:05" or
+// empty for the whole day.
+type PriceRule struct {
+	Id              uuid.UUID
+	ClinicServiceId uuid.UUID
+	ClinicId        uuid.UUID
+	Name            string
+	Price           *float64
+	Percent         *float64
+	ValidFrom       *time.Time
+	ValidTo         *time.Time
+	Weekdays        []int32
+	StartsAt        string
+	EndsAt          string
+	Priority        int
+	IsActive        bool
+	CreatedAt       time.Time
+}
+
+type PromoCode struct {
+	Id                uuid.UUID
+	ClinicId          *uuid.UUID
+	Code              string
+	Description       string
+	DiscountType      string
+	DiscountValue     float64
+	ServiceId         *uuid.UUID
+	MinPrice          float64
+	MaxUses           *int
+	MaxUsesPerPatient *int
+	StartsAt          *time.Time
+	ExpiresAt         *time.Time
+	IsActive          bool
+	CreatedAt         time.Time
+
+	// UsedCount counts the appointments booked with the code that were not
+	// cancelled
+	UsedCount int
+}
+
+type PricingSettings struct {
+	ClinicId                  uuid.UUID
+	FirstVisitDiscountPercent float64
+	UpdatedAt                 time.Time
+}
+
+// Patient identifies who is booking. Guests have no UserId and are matched
+// by email. AppointmentId is set when a booked appointment is priced again,
+// so it does not count as a visit or promo code use of its own.
+type Patient struct {
+	UserId        uuid.UUID
+	Email         string
+	AppointmentId uuid.UUID
+}
+
+// Quote is the price of a booking, as locked on the appointment.
+type Quote struct {
+	ListPrice      float64
+	BasePrice      float64
+	Discount       float64
+	Total          float64
+	PriceRule      *PriceRule
+	PromoCode      *PromoCode
+	DiscountReason string
+}
@@ -0,0 +1,412 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"dental_clinic/internal/modules/pricing/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type PricingRepository interface {
+	GetClinicServiceClinic(clinicServiceId uuid.UUID) (uuid.UUID, error)
+	ClinicExists(clinicId uuid.UUID) (bool, error)
+	ServiceExists(serviceId uuid.UUID) (bool, error)
+	IsClinicAdmin(clinicId, userId uuid.UUID) (bool, error)
+	GetSlotStart(slotId uuid.UUID) (*time.Time, error)
+
+	GetPriceRules(clinicServiceId uuid.UUID) ([]models.PriceRule, error)
+	GetPriceRuleByID(id uuid.UUID) (*models.PriceRule, error)
+	CreatePriceRule(rule *models.PriceRule) error
+	UpdatePriceRule(rule *models.PriceRule) error
+	DeletePriceRule(id uuid.UUID) error
+	GetActivePriceRulesTx(clinicServiceId uuid.UUID, tx pgx.Tx) ([]models.PriceRule, error)
+
+	GetPricingSettings(clinicId uuid.UUID) (*models.PricingSettings, error)
+	SavePricingSettings(settings *models.PricingSettings) error
+	GetFirstVisitDiscountTx(clinicId uuid.UUID, tx pgx.Tx) (float64, error)
+	HasVisitedClinicTx(clinicId uuid.UUID, patient models.Patient, tx pgx.Tx) (bool, error)
+
+	GetPromoCodes(clinicId uuid.UUID) ([]models.PromoCode, error)
+	GetPromoCodeByID(id uuid.UUID) (*models.PromoCode, error)
+	CreatePromoCode(code *models.PromoCode) error
+	UpdatePromoCode(code *models.PromoCode) error
+	GetPromoCodeByCodeTx(code string, tx pgx.Tx) (*models.PromoCode, error)
+	CountPromoUsesTx(promoCodeId uuid.UUID, patient models.Patient, tx pgx.Tx) (int, int, error)
+}
+
+type pricingRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewPricingRepository(db *pgxpool.Pool) PricingRepository {
+	return &pricingRepo{db: db}
+}
+
+// GetClinicServiceClinic returns the clinic offering the clinic service, or
+// uuid.Nil when there is no such service.
+func (r *pricingRepo) GetClinicServiceClinic(clinicServiceId uuid.UUID) (uuid.UUID, error) {
+	var clinicId uuid.UUID
+	err := r.db.QueryRow(context.Background(), `SELECT clinic_id FROM clinic_services WHERE id = $1`, clinicServiceId).Scan(&clinicId)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return uuid.Nil, nil
+		}
+		return uuid.Nil, err
+	}
+	return clinicId, nil
+}
+
+func (r *pricingRepo) ClinicExists(clinicId uuid.UUID) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(context.Background(), `SELECT EXISTS (SELECT 1 FROM clinics WHERE id = $1)`, clinicId).Scan(&exists)
+	return exists, err
+}
+
+func (r *pricingRepo) ServiceExists(serviceId uuid.UUID) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(context.Background(), `SELECT EXISTS (SELECT 1 FROM services WHERE id = $1)`, serviceId).Scan(&exists)
+	return exists, err
+}
+
+func (r *pricingRepo) IsClinicAdmin(clinicId, userId uuid.UUID) (bool, error) {
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM clinic_admins WHERE clinic_id = $1 AND user_id = $2)`
+	err := r.db.QueryRow(context.Background(), query, clinicId, userId).Scan(&exists)
+	return exists, err
+}
+
+func (r *pricingRepo) GetSlotStart(slotId uuid.UUID) (*time.Time, error) {
+	var start time.Time
+	err := r.db.QueryRow(context.Background(), `SELECT slot_start FROM doctor_time_slots WHERE id = $1`, slotId).Scan(&start)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &start, nil
+}
+
+const priceRuleSelect = `
+	SELECT
+		r.id, r.clinic_service_id, cs.clinic_id, r.name, r.price::float8, r.percent::float8,
+		r.valid_from, r.valid_to, r.weekdays, COALESCE(r.starts_at::text, ''), COALESCE(r.ends_at::text, ''),
+		r.priority, r.is_active, r.created_at
+	FROM service_price_rules r
+	JOIN clinic_services cs ON cs.id = r.clinic_service_id
+`
+
+func scanPriceRule(row pgx.Row) (models.PriceRule, error) {
+	var rule models.PriceRule
+	err := row.Scan(
+		&rule.Id,
+		&rule.ClinicServiceId,
+		&rule.ClinicId,
+		&rule.Name,
+		&rule.Price,
+		&rule.Percent,
+		&rule.ValidFrom,
+		&rule.ValidTo,
+		&rule.Weekdays,
+		&rule.StartsAt,
+		&rule.EndsAt,
+		&rule.Priority,
+		&rule.IsActive,
+		&rule.CreatedAt,
+	)
+	return rule, err
+}
+
+func collectPriceRules(rows pgx.Rows) ([]models.PriceRule, error) {
+	defer rows.Close()
+
+	rules := make([]models.PriceRule, 0)
+	for rows.Next() {
+		rule, err := scanPriceRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+func (r *pricingRepo) GetPriceRules(clinicServiceId uuid.UUID) ([]models.PriceRule, error) {
+	query := priceRuleSelect + `
+		WHERE r.clinic_service_id = $1
+		ORDER BY r.priority DESC, r.created_at DESC
+	`
+	rows, err := r.db.Query(context.Background(), query, clinicServiceId)
+	if err != nil {
+		return nil, err
+	}
+	return collectPriceRules(rows)
+}
+
+func (r *pricingRepo) GetPriceRuleByID(id uuid.UUID) (*models.PriceRule, error) {
+	rule, err := scanPriceRule(r.db.QueryRow(context.Background(), priceRuleSelect+` WHERE r.id = $1`, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (r *pricingRepo) CreatePriceRule(rule *models.PriceRule) error {
+	query := `
+		INSERT INTO service_price_rules (
+			id, clinic_service_id, name, price, percent, valid_from, valid_to,
+			weekdays, starts_at, ends_at, priority, is_active, created_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, '')::time, NULLIF($10, '')::time, $11, $12, $13)
+	`
+	_, err := r.db.Exec(context.Background(), query,
+		rule.Id, rule.ClinicServiceId, rule.Name, rule.Price, rule.Percent, rule.ValidFrom, rule.ValidTo,
+		rule.Weekdays, rule.StartsAt, rule.EndsAt, rule.Priority, rule.IsActive, rule.CreatedAt,
+	)
+	return err
+}
+
+func (r *pricingRepo) UpdatePriceRule(rule *models.PriceRule) error {
+	query := `
+		UPDATE service_price_rules
+		SET name = $2, price = $3, percent = $4, valid_from = $5, valid_to = $6, weekdays = $7,
+			starts_at = NULLIF($8, '')::time, ends_at = NULLIF($9, '')::time, priority = $10, is_active = $11
+		WHERE id = $1
+	`
+	result, err := r.db.Exec(context.Background(), query,
+		rule.Id, rule.Name, rule.Price, rule.Percent, rule.ValidFrom, rule.ValidTo, rule.Weekdays,
+		rule.StartsAt, rule.EndsAt, rule.Priority, rule.IsActive,
+	)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *pricingRepo) DeletePriceRule(id uuid.UUID) error {
+	result, err := r.db.Exec(context.Background(), `DELETE FROM service_price_rules WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *pricingRepo) GetActivePriceRulesTx(clinicServiceId uuid.UUID, tx pgx.Tx) ([]models.PriceRule, error) {
+	query := priceRuleSelect + `
+		WHERE r.clinic_service_id = $1 AND r.is_active
+		ORDER BY r.priority DESC, r.created_at DESC
+	`
+	rows, err := tx.Query(context.Background(), query, clinicServiceId)
+	if err != nil {
+		return nil, err
+	}
+	return collectPriceRules(rows)
+}
+
+func (r *pricingRepo) GetPricingSettings(clinicId uuid.UUID) (*models.PricingSettings, error) {
+	settings := &models.PricingSettings{}
+	query := `
+		SELECT clinic_id, first_visit_discount_percent::float8, updated_at
+		FROM clinic_pricing_settings
+		WHERE clinic_id = $1
+	`
+	err := r.db.QueryRow(context.Background(), query, clinicId).
+		Scan(&settings.ClinicId, &settings.FirstVisitDiscountPercent, &settings.UpdatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return settings, nil
+}
+
+func (r *pricingRepo) SavePricingSettings(settings *models.PricingSettings) error {
+	query := `
+		INSERT INTO clinic_pricing_settings (clinic_id, first_visit_discount_percent, updated_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (clinic_id) DO UPDATE
+		SET first_visit_discount_percent = EXCLUDED.first_visit_discount_percent,
+			updated_at = EXCLUDED.updated_at
+		RETURNING updated_at
+	`
+	return r.db.QueryRow(context.Background(), query, settings.ClinicId, settings.FirstVisitDiscountPercent).
+		Scan(&settings.UpdatedAt)
+}
+
+func (r *pricingRepo) GetFirstVisitDiscountTx(clinicId uuid.UUID, tx pgx.Tx) (float64, error) {
+	var percent float64
+	query := `SELECT first_visit_discount_percent::float8 FROM clinic_pricing_settings WHERE clinic_id = $1`
+	err := tx.QueryRow(context.Background(), query, clinicId).Scan(&percent)
+	if err != nil && err != pgx.ErrNoRows {
+		return 0, err
+	}
+	return percent, nil
+}
+
+// HasVisitedClinicTx reports whether the patient has any appointment at the
+// clinic that was not cancelled.
+func (r *pricingRepo) HasVisitedClinicTx(clinicId uuid.UUID, patient models.Patient, tx pgx.Tx) (bool, error) {
+	var visited bool
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM appointments a
+			JOIN clinic_addresses ca ON ca.id = a.clinic_address_id
+			WHERE ca.clinic_id = $1
+				AND a.status <> 'cancelled'
+				AND a.id <> $4
+				AND (($2 <> '00000000-0000-0000-0000-000000000000'::uuid AND a.user_id = $2)
+					OR ($3 <> '' AND LOWER(a.email) = LOWER($3)))
+		)
+	`
+	err := tx.QueryRow(context.Background(), query, clinicId, patient.UserId, patient.Email, patient.AppointmentId).Scan(&visited)
+	return visited, err
+}
+
+const promoCodeSelect = `
+	SELECT
+		p.id, p.clinic_id, p.code, p.description, p.discount_type, p.discount_value::float8,
+		p.service_id, p.min_price::float8, p.max_uses, p.max_uses_per_patient,
+		p.starts_at, p.expires_at, p.is_active, p.created_at,
+		(SELECT COUNT(*) FROM appointments a WHERE a.promo_code_id = p.id AND a.status <> 'cancelled')::int
+	FROM promo_codes p
+`
+
+func scanPromoCode(row pgx.Row) (models.PromoCode, error) {
+	var code models.PromoCode
+	err := row.Scan(
+		&code.Id,
+		&code.ClinicId,
+		&code.Code,
+		&code.Description,
+		&code.DiscountType,
+		&code.DiscountValue,
+		&code.ServiceId,
+		&code.MinPrice,
+		&code.MaxUses,
+		&code.MaxUsesPerPatient,
+		&code.StartsAt,
+		&code.ExpiresAt,
+		&code.IsActive,
+		&code.CreatedAt,
+		&code.UsedCount,
+	)
+	return code, err
+}
+
+// GetPromoCodes returns the codes of a clinic, or every code when clinicId
+// is uuid.Nil.
+func (r *pricingRepo) GetPromoCodes(clinicId uuid.UUID) ([]models.PromoCode, error) {
+	query := promoCodeSelect + `
+		WHERE ($1 = '00000000-0000-0000-0000-000000000000'::uuid OR p.clinic_id = $1)
+		ORDER BY p.is_active DESC, p.created_at DESC
+	`
+	rows, err := r.db.Query(context.Background(), query, clinicId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	codes := make([]models.PromoCode, 0)
+	for rows.Next() {
+		code, err := scanPromoCode(rows)
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+	return codes, rows.Err()
+}
+
+func (r *pricingRepo) GetPromoCodeByID(id uuid.UUID) (*models.PromoCode, error) {
+	code, err := scanPromoCode(r.db.QueryRow(context.Background(), promoCodeSelect+` WHERE p.id = $1`, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &code, nil
+}
+
+func (r *pricingRepo) CreatePromoCode(code *models.PromoCode) error {
+	query := `
+		INSERT INTO promo_codes (
+			id, clinic_id, code, description, discount_type, discount_value, service_id, min_price,
+			max_uses, max_uses_per_patient, starts_at, expires_at, is_active, created_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+	`
+	_, err := r.db.Exec(context.Background(), query,
+		code.Id, code.ClinicId, code.Code, code.Description, code.DiscountType, code.DiscountValue, code.ServiceId, code.MinPrice,
+		code.MaxUses, code.MaxUsesPerPatient, code.StartsAt, code.ExpiresAt, code.IsActive, code.CreatedAt,
+	)
+	return err
+}
+
+// UpdatePromoCode changes the terms of a code. The code itself and its
+// clinic stay the same so past redemptions keep their meaning.
+func (r *pricingRepo) UpdatePromoCode(code *models.PromoCode) error {
+	query := `
+		UPDATE promo_codes
+		SET description = $2, discount_type = $3, discount_value = $4, service_id = $5, min_price = $6,
+			max_uses = $7, max_uses_per_patient = $8, starts_at = $9, expires_at = $10, is_active = $11
+		WHERE id = $1
+	`
+	result, err := r.db.Exec(context.Background(), query,
+		code.Id, code.Description, code.DiscountType, code.DiscountValue, code.ServiceId, code.MinPrice,
+		code.MaxUses, code.MaxUsesPerPatient, code.StartsAt, code.ExpiresAt, code.IsActive,
+	)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// GetPromoCodeByCodeTx looks a code up case-insensitively and locks it, so
+// concurrent bookings cannot use it past its limits.
+func (r *pricingRepo) GetPromoCodeByCodeTx(code string, tx pgx.Tx) (*models.PromoCode, error) {
+	query := promoCodeSelect + ` WHERE UPPER(p.code) = UPPER($1) FOR UPDATE OF p`
+	promo, err := scanPromoCode(tx.QueryRow(context.Background(), query, code))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &promo, nil
+}
+
+// CountPromoUsesTx returns how many appointments that were not cancelled used
+// the code, in total and by the patient.
+func (r *pricingRepo) CountPromoUsesTx(promoCodeId uuid.UUID, patient models.Patient, tx pgx.Tx) (int, int, error) {
+	var total, byPatient int
+	query := `
+		SELECT
+			COUNT(*)::int,
+			(COUNT(*) FILTER (
+				WHERE ($2 <> '00000000-0000-0000-0000-000000000000'::uuid AND a.user_id = $2)
+					OR ($3 <> '' AND LOWER(a.email) = LOWER($3))
+			))::int
+		FROM appointments a
+		WHERE a.promo_code_id = $1 AND a.status <> 'cancelled' AND a.id <> $4
+	`
+	err := tx.QueryRow(context.Background(), query, promoCodeId, patient.UserId, patient.Email, patient.AppointmentId).Scan(&total, &byPatient)
+	return total, byPatient, err
+}
@@ -0,0 +1,59 @@
+package pricing
+
+import (
+	"dental_clinic/internal/config"
+	"dental_clinic/internal/modules/pricing/handlers"
+	"dental_clinic/internal/modules/pricing/repository"
+	"dental_clinic/internal/modules/pricing/services"
+
+	addressRepository "dental_clinic/internal/modules/address/repository"
+	addressServices "dental_clinic/internal/modules/address/services"
+
+	clinicRepository "dental_clinic/internal/modules/clinic/repository"
+	clinicServices "dental_clinic/internal/modules/clinic/services"
+
+	serviceRepository "dental_clinic/internal/modules/services/repository"
+	serviceServices "dental_clinic/internal/modules/services/services"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func newHandler(db *pgxpool.Pool, cfg *config.Config) *handlers.PricingHandler {
+	repo := repository.NewPricingRepository(db)
+
+	addressRepo := addressRepository.NewAddressRepository(db)
+	addressService := addressServices.NewAddressService(addressRepo, *cfg)
+
+	clinicRepo := clinicRepository.NewClinicRepository(db)
+	clinicService := clinicServices.NewClinicService(clinicRepo, *cfg, *addressService)
+
+	serviceRepo := serviceRepository.NewServiceRepository(db)
+	serviceService := serviceServices.NewServiceService(serviceRepo, *clinicService)
+
+	service := services.NewPricingService(repo, db, *serviceService, *clinicService)
+	return handlers.NewPricingHandler(service, *cfg)
+}
+
+func RegisterPublicRoutes(r *mux.Router, db *pgxpool.Pool, cfg *config.Config) {
+	handler := newHandler(db, cfg)
+
+	r.HandleFunc("/pricing/quote", handler.GetQuote).Methods("GET")
+}
+
+func RegisterPrivateRoutes(r *mux.Router, db *pgxpool.Pool, cfg *config.Config) {
+	handler := newHandler(db, cfg)
+
+	r.HandleFunc("/clinic-services/{id}/price-rules", handler.GetPriceRules).Methods("GET")
+	r.HandleFunc("/clinic-services/{id}/price-rules", handler.CreatePriceRule).Methods("POST")
+	r.HandleFunc("/price-rules/{id}", handler.UpdatePriceRule).Methods("PUT")
+	r.HandleFunc("/price-rules/{id}", handler.DeletePriceRule).Methods("DELETE")
+
+	r.HandleFunc("/promo-codes", handler.GetPromoCodes).Methods("GET")
+	r.HandleFunc("/promo-codes", handler.CreatePromoCode).Methods("POST")
+	r.HandleFunc("/promo-codes/{id}", handler.UpdatePromoCode).Methods("PUT")
+	r.HandleFunc("/promo-codes/{id}", handler.DeactivatePromoCode).Methods("DELETE")
+
+	r.HandleFunc("/clinics/{id}/pricing-settings", handler.GetPricingSettings).Methods("GET")
+	r.HandleFunc("/clinics/{id}/pricing-settings", handler.UpdatePricingSettings).Methods("PUT")
+}
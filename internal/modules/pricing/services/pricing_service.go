@@ -0,0 +1,561 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"dental_clinic/internal/modules/pricing/dto"
+	"dental_clinic/internal/modules/pricing/models"
+	"dental_clinic/internal/modules/pricing/repository"
+
+	clinicServices "dental_clinic/internal/modules/clinic/services"
+	serviceServices "dental_clinic/internal/modules/services/services"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type PricingService struct {
+	repo       repository.PricingRepository
+	db         *pgxpool.Pool
+	serviceSrv serviceServices.ServiceService
+	clinicSrv  clinicServices.ClinicService
+}
+
+func NewPricingService(repo repository.PricingRepository, db *pgxpool.Pool, serviceSrv serviceServices.ServiceService, clinicSrv clinicServices.ClinicService) *PricingService {
+	return &PricingService{
+		repo:       repo,
+		db:         db,
+		serviceSrv: serviceSrv,
+		clinicSrv:  clinicSrv,
+	}
+}
+
+func (s *PricingService) GetPriceRules(clinicServiceId, userId, role string) ([]models.PriceRule, error) {
+	serviceUUID, err := s.manageableClinicService(clinicServiceId, userId, role)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetPriceRules(serviceUUID)
+}
+
+func (s *PricingService) CreatePriceRule(clinicServiceId, userId, role string, req dto.PriceRuleRequest) (*models.PriceRule, error) {
+	serviceUUID, err := s.manageableClinicService(clinicServiceId, userId, role)
+	if err != nil {
+		return nil, err
+	}
+
+	rule := &models.PriceRule{
+		Id:              uuid.New(),
+		ClinicServiceId: serviceUUID,
+		IsActive:        true,
+		CreatedAt:       time.Now(),
+	}
+	if err := applyPriceRule(rule, req); err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreatePriceRule(rule); err != nil {
+		return nil, err
+	}
+	return s.getPriceRule(rule.Id)
+}
+
+func (s *PricingService) UpdatePriceRule(id, userId, role string, req dto.PriceRuleRequest) (*models.PriceRule, error) {
+	rule, err := s.manageablePriceRule(id, userId, role)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyPriceRule(rule, req); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdatePriceRule(rule); err != nil {
+		return nil, err
+	}
+	return s.getPriceRule(rule.Id)
+}
+
+// DeletePriceRule removes a rule. Appointments priced by it keep their
+// locked price.
+func (s *PricingService) DeletePriceRule(id, userId, role string) error {
+	rule, err := s.manageablePriceRule(id, userId, role)
+	if err != nil {
+		return err
+	}
+	return s.repo.DeletePriceRule(rule.Id)
+}
+
+func (s *PricingService) GetPricingSettings(clinicId, userId, role string) (*models.PricingSettings, error) {
+	clinicUUID, err := s.manageableClinic(clinicId, userId, role)
+	if err != nil {
+		return nil, err
+	}
+	settings, err := s.repo.GetPricingSettings(clinicUUID)
+	if err != nil {
+		return nil, err
+	}
+	if settings == nil {
+		return &models.PricingSettings{ClinicId: clinicUUID}, nil
+	}
+	return settings, nil
+}
+
+func (s *PricingService) UpdatePricingSettings(clinicId, userId, role string, req dto.PricingSettingsRequest) (*models.PricingSettings, error) {
+	clinicUUID, err := s.manageableClinic(clinicId, userId, role)
+	if err != nil {
+		return nil, err
+	}
+	if req.FirstVisitDiscountPercent < 0 || req.FirstVisitDiscountPercent > 100 {
+		return nil, errors.New("first_visit_discount_percent must be between 0 and 100")
+	}
+
+	settings := &models.PricingSettings{
+		ClinicId:                  clinicUUID,
+		FirstVisitDiscountPercent: req.FirstVisitDiscountPercent,
+	}
+	if err := s.repo.SavePricingSettings(settings); err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// GetPromoCodes lists the codes of a clinic. Platform admins may leave the
+// clinic out to list every code.
+func (s *PricingService) GetPromoCodes(clinicId, userId, role string) ([]models.PromoCode, error) {
+	if clinicId == "" {
+		if role != "admin" {
+			return nil, errors.New("clinic_id is required")
+		}
+		return s.repo.GetPromoCodes(uuid.Nil)
+	}
+	clinicUUID, err := s.manageableClinic(clinicId, userId, role)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetPromoCodes(clinicUUID)
+}
+
+func (s *PricingService) CreatePromoCode(userId, role string, req dto.PromoCodeRequest) (*models.PromoCode, error) {
+	code := &models.PromoCode{
+		Id:        uuid.New(),
+		Code:      strings.ToUpper(strings.TrimSpace(req.Code)),
+		IsActive:  true,
+		CreatedAt: time.Now(),
+	}
+	if code.Code == "" {
+		return nil, errors.New("code is required")
+	}
+	if strings.ContainsAny(code.Code, " \t\n") {
+		return nil, errors.New("code cannot contain spaces")
+	}
+
+	if req.ClinicId == "" {
+		if role != "admin" {
+			return nil, errors.New("do not have rights")
+		}
+	} else {
+		clinicUUID, err := s.manageableClinic(req.ClinicId, userId, role)
+		if err != nil {
+			return nil, err
+		}
+		code.ClinicId = &clinicUUID
+	}
+
+	if err := s.applyPromoCode(code, req); err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreatePromoCode(code); err != nil {
+		if strings.Contains(err.Error(), "idx_promo_codes_code") {
+			return nil, errors.New("promo code already exists")
+		}
+		return nil, err
+	}
+	return s.getPromoCode(code.Id)
+}
+
+// UpdatePromoCode changes the terms of a code. The code and its clinic cannot
+// be changed.
+func (s *PricingService) UpdatePromoCode(id, userId, role string, req dto.PromoCodeRequest) (*models.PromoCode, error) {
+	code, err := s.manageablePromoCode(id, userId, role)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyPromoCode(code, req); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdatePromoCode(code); err != nil {
+		return nil, err
+	}
+	return s.getPromoCode(code.Id)
+}
+
+// DeactivatePromoCode stops a code from being used. It is kept so the
+// appointments booked with it still show which code they used.
+func (s *PricingService) DeactivatePromoCode(id, userId, role string) error {
+	code, err := s.manageablePromoCode(id, userId, role)
+	if err != nil {
+		return err
+	}
+	code.IsActive = false
+	return s.repo.UpdatePromoCode(code)
+}
+
+func (s *PricingService) manageableClinicService(clinicServiceId, userId, role string) (uuid.UUID, error) {
+	serviceUUID, err := uuid.Parse(clinicServiceId)
+	if err != nil {
+		return uuid.Nil, errors.New("invalid clinic service id")
+	}
+	clinicId, err := s.repo.GetClinicServiceClinic(serviceUUID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if clinicId == uuid.Nil {
+		return uuid.Nil, errors.New("clinic service not found")
+	}
+	if err := s.canManageClinic(clinicId, userId, role); err != nil {
+		return uuid.Nil, err
+	}
+	return serviceUUID, nil
+}
+
+func (s *PricingService) manageablePriceRule(id, userId, role string) (*models.PriceRule, error) {
+	ruleId, err := uuid.Parse(id)
+	if err != nil {
+		return nil, errors.New("invalid price rule id")
+	}
+	rule, err := s.getPriceRule(ruleId)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.canManageClinic(rule.ClinicId, userId, role); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+func (s *PricingService) getPriceRule(id uuid.UUID) (*models.PriceRule, error) {
+	rule, err := s.repo.GetPriceRuleByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if rule == nil {
+		return nil, errors.New("price rule not found")
+	}
+	return rule, nil
+}
+
+func (s *PricingService) manageableClinic(clinicId, userId, role string) (uuid.UUID, error) {
+	clinicUUID, err := uuid.Parse(clinicId)
+	if err != nil {
+		return uuid.Nil, errors.New("invalid clinic id")
+	}
+	if err := s.canManageClinic(clinicUUID, userId, role); err != nil {
+		return uuid.Nil, err
+	}
+	exists, err := s.repo.ClinicExists(clinicUUID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if !exists {
+		return uuid.Nil, errors.New("clinic not found")
+	}
+	return clinicUUID, nil
+}
+
+// manageablePromoCode returns a code the user may change. Codes valid at
+// every clinic belong to platform admins.
+func (s *PricingService) manageablePromoCode(id, userId, role string) (*models.PromoCode, error) {
+	codeId, err := uuid.Parse(id)
+	if err != nil {
+		return nil, errors.New("invalid promo code id")
+	}
+	code, err := s.getPromoCode(codeId)
+	if err != nil {
+		return nil, err
+	}
+	if code.ClinicId == nil {
+		if role != "admin" {
+			return nil, errors.New("do not have rights")
+		}
+		return code, nil
+	}
+	if err := s.canManageClinic(*code.ClinicId, userId, role); err != nil {
+		return nil, err
+	}
+	return code, nil
+}
+
+func (s *PricingService) getPromoCode(id uuid.UUID) (*models.PromoCode, error) {
+	code, err := s.repo.GetPromoCodeByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if code == nil {
+		return nil, errors.New("promo code not found")
+	}
+	return code, nil
+}
+
+// canManageClinic allows platform admins and the admins of the clinic.
+func (s *PricingService) canManageClinic(clinicId uuid.UUID, userId, role string) error {
+	switch role {
+	case "admin":
+		return nil
+	case "clinic_admin":
+		userUUID, err := uuid.Parse(userId)
+		if err != nil {
+			return errors.New("invalid user id")
+		}
+		isAdmin, err := s.repo.IsClinicAdmin(clinicId, userUUID)
+		if err != nil {
+			return err
+		}
+		if isAdmin {
+			return nil
+		}
+	}
+	return errors.New("do not have rights")
+}
+
+func applyPriceRule(rule *models.PriceRule, req dto.PriceRuleRequest) error {
+	rule.Name = strings.TrimSpace(req.Name)
+	if rule.Name == "" {
+		return errors.New("name is required")
+	}
+	if (req.Price == nil) == (req.Percent == nil) {
+		return errors.New("set either price or percent")
+	}
+	if req.Price != nil && *req.Price < 0 {
+		return errors.New("price cannot be negative")
+	}
+	if req.Percent != nil && *req.Percent <= -100 {
+		return errors.New("percent must be greater than -100")
+	}
+	rule.Price = req.Price
+	rule.Percent = req.Percent
+
+	var err error
+	if rule.ValidFrom, err = parseDate(req.ValidFrom, "valid_from"); err != nil {
+		return err
+	}
+	if rule.ValidTo, err = parseDate(req.ValidTo, "valid_to"); err != nil {
+		return err
+	}
+	if rule.ValidFrom != nil && rule.ValidTo != nil && rule.ValidTo.Before(*rule.ValidFrom) {
+		return errors.New("valid_to must not be before valid_from")
+	}
+
+	seen := make(map[int]bool)
+	rule.Weekdays = make([]int32, 0, len(req.Weekdays))
+	for _, day := range req.Weekdays {
+		if day < 0 || day > 6 {
+			return errors.New("weekdays must be between 0 (Sunday) and 6 (Saturday)")
+		}
+		if !seen[day] {
+			seen[day] = true
+			rule.Weekdays = append(rule.Weekdays, int32(day))
+		}
+	}
+	sort.Slice(rule.Weekdays, func(i, j int) bool { return rule.Weekdays[i] < rule.Weekdays[j] })
+
+	rule.StartsAt, rule.EndsAt = "", ""
+	if req.StartsAt != "" || req.EndsAt != "" {
+		start, err := parseClock(req.StartsAt)
+		if err != nil {
+			return fmt.Errorf("invalid time: %q", req.StartsAt)
+		}
+		end, err := parseClock(req.EndsAt)
+		if err != nil {
+			return fmt.Errorf("invalid time: %q", req.EndsAt)
+		}
+		if !end.After(start) {
+			return errors.New("ends_at must be after starts_at")
+		}
+		rule.StartsAt, rule.EndsAt = start.Format("15:04:05"), end.Format("15:04:05")
+	}
+
+	rule.Priority = req.Priority
+	if req.IsActive != nil {
+		rule.IsActive = *req.IsActive
+	}
+	return nil
+}
+
+func (s *PricingService) applyPromoCode(code *models.PromoCode, req dto.PromoCodeRequest) error {
+	switch req.DiscountType {
+	case "percent":
+		if req.DiscountValue <= 0 || req.DiscountValue > 100 {
+			return errors.New("percent discount must be between 0 and 100")
+		}
+	case "fixed":
+		if req.DiscountValue <= 0 {
+			return errors.New("discount_value must be positive")
+		}
+	default:
+		return errors.New("discount_type must be percent or fixed")
+	}
+	if req.MinPrice < 0 {
+		return errors.New("min_price cannot be negative")
+	}
+	if req.MaxUses != nil && *req.MaxUses <= 0 {
+		return errors.New("max_uses must be positive")
+	}
+	if req.MaxUsesPerPatient != nil && *req.MaxUsesPerPatient <= 0 {
+		return errors.New("max_uses_per_patient must be positive")
+	}
+
+	code.ServiceId = nil
+	if req.ServiceId != "" {
+		serviceId, err := uuid.Parse(req.ServiceId)
+		if err != nil {
+			return errors.New("invalid service_id")
+		}
+		exists, err := s.repo.ServiceExists(serviceId)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return errors.New("service not found")
+		}
+		code.ServiceId = &serviceId
+	}
+
+	var err error
+	if code.StartsAt, err = parseTimestamp(req.StartsAt, "starts_at"); err != nil {
+		return err
+	}
+	if code.ExpiresAt, err = parseTimestamp(req.ExpiresAt, "expires_at"); err != nil {
+		return err
+	}
+	if code.StartsAt != nil && code.ExpiresAt != nil && !code.ExpiresAt.After(*code.StartsAt) {
+		return errors.New("expires_at must be after starts_at")
+	}
+
+	code.Description = strings.TrimSpace(req.Description)
+	code.DiscountType = req.DiscountType
+	code.DiscountValue = req.DiscountValue
+	code.MinPrice = req.MinPrice
+	code.MaxUses = req.MaxUses
+	code.MaxUsesPerPatient = req.MaxUsesPerPatient
+	if req.IsActive != nil {
+		code.IsActive = *req.IsActive
+	}
+	return nil
+}
+
+func parseDate(v, field string) (*time.Time, error) {
+	if v == "" {
+		return nil, nil
+	}
+	t, err := time.Parse("2006-01-02", v)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be YYYY-MM-DD", field)
+	}
+	return &t, nil
+}
+
+func parseTimestamp(v, field string) (*time.Time, error) {
+	if v == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be an RFC 3339 time", field)
+	}
+	t = t.UTC()
+	return &t, nil
+}
+
+func parseClock(v string) (time.Time, error) {
+	v = strings.TrimSpace(v)
+	if t, err := time.Parse("15:04:05", v); err == nil {
+		return t, nil
+	}
+	return time.Parse("15:04", v)
+}
+
+func ToPriceRuleResponse(rule models.PriceRule) dto.PriceRuleResponse {
+	weekdays := make([]int, 0, len(rule.Weekdays))
+	for _, day := range rule.Weekdays {
+		weekdays = append(weekdays, int(day))
+	}
+
+	response := dto.PriceRuleResponse{
+		Id:              rule.Id.String(),
+		ClinicServiceId: rule.ClinicServiceId.String(),
+		Name:            rule.Name,
+		Price:           rule.Price,
+		Percent:         rule.Percent,
+		Weekdays:        weekdays,
+		StartsAt:        rule.StartsAt,
+		EndsAt:          rule.EndsAt,
+		Priority:        rule.Priority,
+		IsActive:        rule.IsActive,
+		CreatedAt:       rule.CreatedAt.Format(time.RFC3339),
+	}
+	if rule.ValidFrom != nil {
+		response.ValidFrom = rule.ValidFrom.Format("2006-01-02")
+	}
+	if rule.ValidTo != nil {
+		response.ValidTo = rule.ValidTo.Format("2006-01-02")
+	}
+	return response
+}
+
+func ToPriceRuleResponseList(rules []models.PriceRule) []dto.PriceRuleResponse {
+	result := make([]dto.PriceRuleResponse, 0, len(rules))
+	for _, rule := range rules {
+		result = append(result, ToPriceRuleResponse(rule))
+	}
+	return result
+}
+
+func ToPromoCodeResponse(code models.PromoCode) dto.PromoCodeResponse {
+	response := dto.PromoCodeResponse{
+		Id:                code.Id.String(),
+		Code:              code.Code,
+		Description:       code.Description,
+		DiscountType:      code.DiscountType,
+		DiscountValue:     code.DiscountValue,
+		MinPrice:          code.MinPrice,
+		MaxUses:           code.MaxUses,
+		MaxUsesPerPatient: code.MaxUsesPerPatient,
+		UsedCount:         code.UsedCount,
+		IsActive:          code.IsActive,
+		CreatedAt:         code.CreatedAt.Format(time.RFC3339),
+	}
+	if code.ClinicId != nil {
+		response.ClinicId = code.ClinicId.String()
+	}
+	if code.ServiceId != nil {
+		response.ServiceId = code.ServiceId.String()
+	}
+	if code.StartsAt != nil {
+		response.StartsAt = code.StartsAt.Format(time.RFC3339)
+	}
+	if code.ExpiresAt != nil {
+		response.ExpiresAt = code.ExpiresAt.Format(time.RFC3339)
+	}
+	return response
+}
+
+func ToPromoCodeResponseList(codes []models.PromoCode) []dto.PromoCodeResponse {
+	result := make([]dto.PromoCodeResponse, 0, len(codes))
+	for _, code := range codes {
+		result = append(result, ToPromoCodeResponse(code))
+	}
+	return result
+}
+
+func ToPricingSettingsResponse(settings models.PricingSettings) dto.PricingSettingsResponse {
+	response := dto.PricingSettingsResponse{
+		ClinicId:                  settings.ClinicId.String(),
+		FirstVisitDiscountPercent: settings.FirstVisitDiscountPercent,
+	}
+	if !settings.UpdatedAt.IsZero() {
+		response.UpdatedAt = settings.UpdatedAt.Format(time.RFC3339)
+	}
+	return response
+}
@@ -0,0 +1,252 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"time"
+
+	"dental_clinic/internal/modules/pricing/dto"
+	"dental_clinic/internal/modules/pricing/models"
+
+	serviceModels "dental_clinic/internal/modules/services/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Quote prices a slot the way booking it would, without booking it.
+func (s *PricingService) Quote(ctx context.Context, userId string, req dto.QuoteRequest) (*models.Quote, error) {
+	clinicAddressId, err := uuid.Parse(req.ClinicAddressId)
+	if err != nil {
+		return nil, errors.New("invalid clinic_address_id")
+	}
+	slotId, err := uuid.Parse(req.SlotId)
+	if err != nil {
+		return nil, errors.New("invalid slot_id")
+	}
+	patient := models.Patient{Email: strings.TrimSpace(req.Email)}
+	if userId != "" {
+		if patient.UserId, err = uuid.Parse(userId); err != nil {
+			return nil, errors.New("invalid user id")
+		}
+	}
+
+	clinicId, err := s.clinicSrv.GetClinicByAddressId(clinicAddressId)
+	if err != nil {
+		return nil, err
+	}
+	offer, err := s.serviceSrv.GetDoctorOffer(req.DoctorId, clinicId, req.ServiceId)
+	if err != nil {
+		return nil, err
+	}
+	start, err := s.repo.GetSlotStart(slotId)
+	if err != nil {
+		return nil, err
+	}
+	if start == nil {
+		return nil, errors.New("slot not found")
+	}
+
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	return s.QuoteTx(*offer, *start, patient, req.PromoCode, tx)
+}
+
+// QuoteTx prices a booking of the doctor's offer starting at start. The
+// highest priority price rule matching the start replaces the list price,
+// then the larger of the first-visit and the promo code discount is taken
+// off; the two never stack. A promo code that cannot be used is an error so
+// the patient is never charged more than they were shown.
+func (s *PricingService) QuoteTx(offer serviceModels.Clinic_Service, start time.Time, patient models.Patient, promoCode string, tx pgx.Tx) (*models.Quote, error) {
+	rules, err := s.repo.GetActivePriceRulesTx(offer.Id, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	quote := &models.Quote{ListPrice: roundPrice(offer.Price)}
+	quote.BasePrice, quote.PriceRule = PriceAt(offer.Price, rules, start)
+
+	var promo *models.PromoCode
+	var promoDiscount float64
+	if code := strings.TrimSpace(promoCode); code != "" {
+		promo, err = s.usablePromoCodeTx(code, offer, quote.BasePrice, start, patient, tx)
+		if err != nil {
+			return nil, err
+		}
+		promoDiscount = PromoDiscount(*promo, quote.BasePrice)
+	}
+
+	var firstVisitDiscount float64
+	percent, err := s.repo.GetFirstVisitDiscountTx(offer.ClinicID, tx)
+	if err != nil {
+		return nil, err
+	}
+	if percent > 0 {
+		visited, err := s.repo.HasVisitedClinicTx(offer.ClinicID, patient, tx)
+		if err != nil {
+			return nil, err
+		}
+		if !visited {
+			firstVisitDiscount = roundPrice(quote.BasePrice * percent / 100)
+		}
+	}
+
+	switch {
+	case promo != nil && promoDiscount >= firstVisitDiscount:
+		quote.Discount = promoDiscount
+		quote.PromoCode = promo
+		quote.DiscountReason = "promo_code"
+	case firstVisitDiscount > 0:
+		quote.Discount = firstVisitDiscount
+		quote.DiscountReason = "first_visit"
+	}
+	quote.Total = roundPrice(quote.BasePrice - quote.Discount)
+	return quote, nil
+}
+
+// RequoteTx prices a booked appointment again after its doctor, clinic
+// address or service changed. The promo code it was booked with is applied
+// again and has to be usable for the new offer.
+func (s *PricingService) RequoteTx(offer serviceModels.Clinic_Service, start time.Time, patient models.Patient, promoCodeId *uuid.UUID, tx pgx.Tx) (*models.Quote, error) {
+	var promoCode string
+	if promoCodeId != nil {
+		promo, err := s.repo.GetPromoCodeByID(*promoCodeId)
+		if err != nil {
+			return nil, err
+		}
+		if promo != nil {
+			promoCode = promo.Code
+		}
+	}
+	return s.QuoteTx(offer, start, patient, promoCode, tx)
+}
+
+func (s *PricingService) usablePromoCodeTx(code string, offer serviceModels.Clinic_Service, price float64, start time.Time, patient models.Patient, tx pgx.Tx) (*models.PromoCode, error) {
+	promo, err := s.repo.GetPromoCodeByCodeTx(code, tx)
+	if err != nil {
+		return nil, err
+	}
+	if promo == nil || !promo.IsActive {
+		return nil, errors.New("promo code not found")
+	}
+
+	now := time.Now().UTC()
+	if promo.StartsAt != nil && now.Before(*promo.StartsAt) {
+		return nil, errors.New("promo code is not active yet")
+	}
+	if promo.ExpiresAt != nil && !now.Before(*promo.ExpiresAt) {
+		return nil, errors.New("promo code has expired")
+	}
+	if promo.ClinicId != nil && *promo.ClinicId != offer.ClinicID {
+		return nil, errors.New("promo code is not valid at this clinic")
+	}
+	if promo.ServiceId != nil && *promo.ServiceId != offer.ServiceID {
+		return nil, errors.New("promo code is not valid for this service")
+	}
+	if price < promo.MinPrice {
+		return nil, errors.New("price is below the promo code minimum")
+	}
+
+	if promo.MaxUses != nil || promo.MaxUsesPerPatient != nil {
+		if promo.MaxUsesPerPatient != nil && patient.UserId == uuid.Nil && patient.Email == "" {
+			return nil, errors.New("log in or give an email to use this promo code")
+		}
+		total, byPatient, err := s.repo.CountPromoUsesTx(promo.Id, patient, tx)
+		if err != nil {
+			return nil, err
+		}
+		if promo.MaxUses != nil && total >= *promo.MaxUses {
+			return nil, errors.New("promo code has been used up")
+		}
+		if promo.MaxUsesPerPatient != nil && byPatient >= *promo.MaxUsesPerPatient {
+			return nil, errors.New("promo code already used")
+		}
+	}
+	return promo, nil
+}
+
+// PriceAt applies the highest priority rule matching start to the list
+// price. Rules come ordered by priority, newest first on ties.
+func PriceAt(listPrice float64, rules []models.PriceRule, start time.Time) (float64, *models.PriceRule) {
+	for i := range rules {
+		rule := rules[i]
+		if !ruleApplies(rule, start) {
+			continue
+		}
+		if rule.Price != nil {
+			return roundPrice(*rule.Price), &rule
+		}
+		return roundPrice(listPrice * (1 + *rule.Percent/100)), &rule
+	}
+	return roundPrice(listPrice), nil
+}
+
+func ruleApplies(rule models.PriceRule, start time.Time) bool {
+	if !rule.IsActive {
+		return false
+	}
+	day := start.Format("2006-01-02")
+	if rule.ValidFrom != nil && day < rule.ValidFrom.Format("2006-01-02") {
+		return false
+	}
+	if rule.ValidTo != nil && day > rule.ValidTo.Format("2006-01-02") {
+		return false
+	}
+	if len(rule.Weekdays) > 0 {
+		matches := false
+		for _, weekday := range rule.Weekdays {
+			if time.Weekday(weekday) == start.Weekday() {
+				matches = true
+				break
+			}
+		}
+		if !matches {
+			return false
+		}
+	}
+	if rule.StartsAt != "" {
+		clock := start.Format("15:04:05")
+		if clock < rule.StartsAt || clock >= rule.EndsAt {
+			return false
+		}
+	}
+	return true
+}
+
+// PromoDiscount is the amount the code takes off price, never more than the
+// price itself.
+func PromoDiscount(promo models.PromoCode, price float64) float64 {
+	discount := promo.DiscountValue
+	if promo.DiscountType == "percent" {
+		discount = price * promo.DiscountValue / 100
+	}
+	return roundPrice(math.Min(discount, price))
+}
+
+func roundPrice(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+func ToQuoteResponse(quote models.Quote) dto.QuoteResponse {
+	response := dto.QuoteResponse{
+		ListPrice:      quote.ListPrice,
+		BasePrice:      quote.BasePrice,
+		Discount:       quote.Discount,
+		Total:          quote.Total,
+		DiscountReason: quote.DiscountReason,
+	}
+	if quote.PriceRule != nil {
+		response.PriceRuleId = quote.PriceRule.Id.String()
+		response.PriceRuleName = quote.PriceRule.Name
+	}
+	if quote.PromoCode != nil {
+		response.PromoCode = quote.PromoCode.Code
+	}
+	return response
+}
@@ -0,0 +1,98 @@
+package dto
+
+type SupplierRequest struct {
+	Name        string `json:"name"`
+	ContactName string `json:"contact_name"`
+	Email       string `json:"email"`
+	Phone       string `json:"phone"`
+	Address     string `json:"address"`
+	IsActive    *bool  `json:"is_active"`
+}
+
+type SupplierResponse struct {
+	Id          string `json:"id"`
+	ClinicId    string `json:"clinic_id"`
+	Name        string `json:"name"`
+	ContactName string `json:"contact_name"`
+	Email       string `json:"email"`
+	Phone       string `json:"phone"`
+	Address     string `json:"address"`
+	IsActive    bool   `json:"is_active"`
+	CreatedAt   string `json:"created_at"`
+}
+
+// PurchaseOrderRequest creates a draft order or replaces the lines of one.
+// Each product appears once.
+type PurchaseOrderRequest struct {
+	SupplierId      string                     `json:"supplier_id"`
+	ClinicAddressId string                     `json:"clinic_address_id"`
+	Notes           string                     `json:"notes"`
+	Items           []PurchaseOrderItemRequest `json:"items"`
+}
+
+type PurchaseOrderItemRequest struct {
+	ProductId string  `json:"product_id"`
+	Quantity  float64 `json:"quantity"`
+	UnitCost  float64 `json:"unit_cost"`
+}
+
+type PurchaseOrderResponse struct {
+	Id              string                      `json:"id"`
+	ClinicId        string                      `json:"clinic_id"`
+	ClinicAddressId string                      `json:"clinic_address_id"`
+	SupplierId      string                      `json:"supplier_id"`
+	SupplierName    string                      `json:"supplier_name"`
+	Status          string                      `json:"status"`
+	Notes           string                      `json:"notes"`
+	Total           float64                     `json:"total"`
+	SentAt          string                      `json:"sent_at,omitempty"`
+	ReceivedAt      string                      `json:"received_at,omitempty"`
+	CreatedAt       string                      `json:"created_at"`
+	Items           []PurchaseOrderItemResponse `json:"items"`
+}
+
+type PurchaseOrderItemResponse struct {
+	Id               string  `json:"id"`
+	ProductId        string  `json:"product_id"`
+	ProductName      string  `json:"product_name"`
+	ProductUnit      string  `json:"product_unit"`
+	Quantity         float64 `json:"quantity"`
+	UnitCost         float64 `json:"unit_cost"`
+	ReceivedQuantity float64 `json:"received_quantity"`
+}
+
+// GoodsReceiptRequest books a delivery against a purchase order into stock.
+// unit_cost defaults to the ordered one; expires_at is a date like
+// 2006-01-02.
+type GoodsReceiptRequest struct {
+	Notes string                    `json:"notes"`
+	Items []GoodsReceiptItemRequest `json:"items"`
+}
+
+type GoodsReceiptItemRequest struct {
+	PurchaseOrderItemId string   `json:"purchase_order_item_id"`
+	Quantity            float64  `json:"quantity"`
+	UnitCost            *float64 `json:"unit_cost"`
+	LotNumber           string   `json:"lot_number"`
+	ExpiresAt           string   `json:"expires_at"`
+}
+
+type GoodsReceiptResponse struct {
+	Id              string                     `json:"id"`
+	PurchaseOrderId string                     `json:"purchase_order_id"`
+	Notes           string                     `json:"notes"`
+	ReceivedAt      string                     `json:"received_at"`
+	Items           []GoodsReceiptItemResponse `json:"items"`
+}
+
+type GoodsReceiptItemResponse struct {
+	Id                  string  `json:"id"`
+	PurchaseOrderItemId string  `json:"purchase_order_item_id"`
+	ProductId           string  `json:"product_id"`
+	ProductName         string  `json:"product_name"`
+	LotId               string  `json:"lot_id,omitempty"`
+	LotNumber           string  `json:"lot_number,omitempty"`
+	ExpiresAt           string  `json:"expires_at,omitempty"`
+	Quantity            float64 `json:"quantity"`
+	UnitCost            float64 `json:"unit_cost"`
+}
@@ -0,0 +1,350 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"dental_clinic/internal/config"
+	"dental_clinic/internal/modules/purchasing/dto"
+	"dental_clinic/internal/modules/purchasing/services"
+	"dental_clinic/internal/utils"
+
+	"github.com/gorilla/mux"
+)
+
+type PurchasingHandler struct {
+	service *services.PurchasingService
+	cfg     config.Config
+}
+
+func NewPurchasingHandler(service *services.PurchasingService, cfg config.Config) *PurchasingHandler {
+	return &PurchasingHandler{service: service, cfg: cfg}
+}
+
+// GetSuppliers godoc
+// @Summary List suppliers
+// @Description Lists the suppliers of a clinic, active ones first
+// @Tags Purchasing
+// @Security BearerAuth
+// @Produce json
+// @Param clinicId path string true "Clinic ID"
+// @Success 200 {array} dto.SupplierResponse
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/clinics/{clinicId}/suppliers [get]
+func (h *PurchasingHandler) GetSuppliers(w http.ResponseWriter, r *http.Request) {
+	userId, role := h.currentUser(r)
+	suppliers, err := h.service.GetSuppliers(mux.Vars(r)["clinicId"], userId, role)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, services.ToSupplierResponseList(suppliers))
+}
+
+// CreateSupplier godoc
+// @Summary Create supplier
+// @Tags Purchasing
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param clinicId path string true "Clinic ID"
+// @Param request body dto.SupplierRequest true "Supplier"
+// @Success 201 {object} dto.SupplierResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/clinics/{clinicId}/suppliers [post]
+func (h *PurchasingHandler) CreateSupplier(w http.ResponseWriter, r *http.Request) {
+	var req dto.SupplierRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	userId, role := h.currentUser(r)
+	supplier, err := h.service.CreateSupplier(mux.Vars(r)["clinicId"], userId, role, req)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusCreated, services.ToSupplierResponse(*supplier))
+}
+
+// UpdateSupplier godoc
+// @Summary Update supplier
+// @Tags Purchasing
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Supplier ID"
+// @Param request body dto.SupplierRequest true "Supplier"
+// @Success 200 {object} dto.SupplierResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/suppliers/{id} [put]
+func (h *PurchasingHandler) UpdateSupplier(w http.ResponseWriter, r *http.Request) {
+	var req dto.SupplierRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	userId, role := h.currentUser(r)
+	supplier, err := h.service.UpdateSupplier(mux.Vars(r)["id"], userId, role, req)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, services.ToSupplierResponse(*supplier))
+}
+
+// DeactivateSupplier godoc
+// @Summary Deactivate supplier
+// @Description Stops new purchase orders going to the supplier. Orders already placed with it are kept.
+// @Tags Purchasing
+// @Security BearerAuth
+// @Param id path string true "Supplier ID"
+// @Success 204
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/suppliers/{id} [delete]
+func (h *PurchasingHandler) DeactivateSupplier(w http.ResponseWriter, r *http.Request) {
+	userId, role := h.currentUser(r)
+	if err := h.service.DeactivateSupplier(mux.Vars(r)["id"], userId, role); err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetPurchaseOrders godoc
+// @Summary List purchase orders
+// @Description Lists the purchase orders of a clinic, newest first
+// @Tags Purchasing
+// @Security BearerAuth
+// @Produce json
+// @Param clinicId path string true "Clinic ID"
+// @Param status query string false "draft, sent, partially_received, received or cancelled"
+// @Success 200 {array} dto.PurchaseOrderResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/clinics/{clinicId}/purchase-orders [get]
+func (h *PurchasingHandler) GetPurchaseOrders(w http.ResponseWriter, r *http.Request) {
+	userId, role := h.currentUser(r)
+	orders, err := h.service.GetPurchaseOrders(mux.Vars(r)["clinicId"], userId, role, r.URL.Query().Get("status"))
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, services.ToPurchaseOrderResponseList(orders))
+}
+
+// CreatePurchaseOrder godoc
+// @Summary Create purchase order
+// @Description Starts a draft order with a supplier of the clinic for delivery to one of its addresses
+// @Tags Purchasing
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param clinicId path string true "Clinic ID"
+// @Param request body dto.PurchaseOrderRequest true "Purchase order"
+// @Success 201 {object} dto.PurchaseOrderResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/clinics/{clinicId}/purchase-orders [post]
+func (h *PurchasingHandler) CreatePurchaseOrder(w http.ResponseWriter, r *http.Request) {
+	var req dto.PurchaseOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	userId, role := h.currentUser(r)
+	order, err := h.service.CreatePurchaseOrder(r.Context(), mux.Vars(r)["clinicId"], userId, role, req)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusCreated, services.ToPurchaseOrderResponse(*order))
+}
+
+// GetPurchaseOrder godoc
+// @Summary Get purchase order
+// @Tags Purchasing
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Purchase order ID"
+// @Success 200 {object} dto.PurchaseOrderResponse
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/purchase-orders/{id} [get]
+func (h *PurchasingHandler) GetPurchaseOrder(w http.ResponseWriter, r *http.Request) {
+	userId, role := h.currentUser(r)
+	order, err := h.service.GetPurchaseOrder(mux.Vars(r)["id"], userId, role)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, services.ToPurchaseOrderResponse(*order))
+}
+
+// UpdatePurchaseOrder godoc
+// @Summary Update purchase order
+// @Description Changes the supplier, address, notes and items of a draft order
+// @Tags Purchasing
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Purchase order ID"
+// @Param request body dto.PurchaseOrderRequest true "Purchase order"
+// @Success 200 {object} dto.PurchaseOrderResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/purchase-orders/{id} [put]
+func (h *PurchasingHandler) UpdatePurchaseOrder(w http.ResponseWriter, r *http.Request) {
+	var req dto.PurchaseOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	userId, role := h.currentUser(r)
+	order, err := h.service.UpdatePurchaseOrder(r.Context(), mux.Vars(r)["id"], userId, role, req)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, services.ToPurchaseOrderResponse(*order))
+}
+
+// SendPurchaseOrder godoc
+// @Summary Send purchase order
+// @Description Places a draft order with the supplier, emailing it when the supplier has an email address
+// @Tags Purchasing
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Purchase order ID"
+// @Success 200 {object} dto.PurchaseOrderResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/purchase-orders/{id}/send [post]
+func (h *PurchasingHandler) SendPurchaseOrder(w http.ResponseWriter, r *http.Request) {
+	userId, role := h.currentUser(r)
+	order, err := h.service.SendPurchaseOrder(r.Context(), mux.Vars(r)["id"], userId, role)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, services.ToPurchaseOrderResponse(*order))
+}
+
+// CancelPurchaseOrder godoc
+// @Summary Cancel purchase order
+// @Description Cancels a draft or sent order nothing has been received against
+// @Tags Purchasing
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Purchase order ID"
+// @Success 200 {object} dto.PurchaseOrderResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/purchase-orders/{id}/cancel [post]
+func (h *PurchasingHandler) CancelPurchaseOrder(w http.ResponseWriter, r *http.Request) {
+	userId, role := h.currentUser(r)
+	order, err := h.service.CancelPurchaseOrder(r.Context(), mux.Vars(r)["id"], userId, role)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, services.ToPurchaseOrderResponse(*order))
+}
+
+// GetReceipts godoc
+// @Summary List goods receipts
+// @Description Lists the deliveries received against a purchase order, newest first
+// @Tags Purchasing
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Purchase order ID"
+// @Success 200 {array} dto.GoodsReceiptResponse
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/purchase-orders/{id}/receipts [get]
+func (h *PurchasingHandler) GetReceipts(w http.ResponseWriter, r *http.Request) {
+	userId, role := h.currentUser(r)
+	receipts, err := h.service.GetReceipts(mux.Vars(r)["id"], userId, role)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, services.ToGoodsReceiptResponseList(receipts))
+}
+
+// ReceiveGoods godoc
+// @Summary Receive goods
+// @Description Books a delivery against a sent purchase order into the stock of its clinic address, by lot and at the unit cost invoiced (the ordered cost by default). More than was ordered cannot be received.
+// @Tags Purchasing
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Purchase order ID"
+// @Param request body dto.GoodsReceiptRequest true "Delivered items"
+// @Success 201 {object} dto.GoodsReceiptResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/purchase-orders/{id}/receipts [post]
+func (h *PurchasingHandler) ReceiveGoods(w http.ResponseWriter, r *http.Request) {
+	var req dto.GoodsReceiptRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	userId, role := h.currentUser(r)
+	receipt, err := h.service.ReceiveGoods(r.Context(), mux.Vars(r)["id"], userId, role, req)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusCreated, services.ToGoodsReceiptResponse(*receipt))
+}
+
+func (h *PurchasingHandler) currentUser(r *http.Request) (string, string) {
+	claims, err := utils.GetClaims(utils.GetToken(r), h.cfg.JWTSecret)
+	if err != nil {
+		return "", ""
+	}
+	userId, _ := claims["user_id"].(string)
+	role, _ := claims["role"].(string)
+	return userId, role
+}
+
+func errorStatus(err error) int {
+	switch {
+	case err.Error() == "do not have rights":
+		return http.StatusForbidden
+	case strings.HasSuffix(err.Error(), "not found"):
+		return http.StatusNotFound
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+func respondJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+func respondError(w http.ResponseWriter, status int, message string) {
+	respondJSON(w, status, map[string]string{"error": message})
+}
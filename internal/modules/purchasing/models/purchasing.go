@@ -0,0 +1,85 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Supplier struct {
+	Id          uuid.UUID
+	ClinicId    uuid.UUID
+	Name        string
+	ContactName string
+	Email       string
+	Phone       string
+	Address     string
+	IsActive    bool
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// PurchaseOrder is stock ordered from a supplier for delivery to a clinic
+// address. Its status moves from "draft" to "sent", "partially_received" and
+// "received"; draft and sent orders can be "cancelled".
+type PurchaseOrder struct {
+	Id              uuid.UUID
+	ClinicId        uuid.UUID
+	ClinicAddressId uuid.UUID
+	SupplierId      uuid.UUID
+	SupplierName    string
+	SupplierEmail   string
+	Status          string
+	Notes           string
+	CreatedBy       *uuid.UUID
+	SentAt          *time.Time
+	ReceivedAt      *time.Time
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Items           []PurchaseOrderItem
+}
+
+// Total is what the order costs at the ordered unit costs.
+func (o PurchaseOrder) Total() float64 {
+	total := 0.0
+	for _, item := range o.Items {
+		total += item.Quantity * item.UnitCost
+	}
+	return total
+}
+
+type PurchaseOrderItem struct {
+	Id               uuid.UUID
+	PurchaseOrderId  uuid.UUID
+	ProductId        uuid.UUID
+	ProductName      string
+	ProductUnit      string
+	Quantity         float64
+	UnitCost         float64
+	ReceivedQuantity float64
+}
+
+// GoodsReceipt is a delivery against a purchase order.
+type GoodsReceipt struct {
+	Id              uuid.UUID
+	PurchaseOrderId uuid.UUID
+	ReceivedBy      *uuid.UUID
+	Notes           string
+	ReceivedAt      time.Time
+	Items           []GoodsReceiptItem
+}
+
+// GoodsReceiptItem is what was delivered of an order line, at the unit cost
+// actually invoiced, and the lot it went into.
+type GoodsReceiptItem struct {
+	Id                  uuid.UUID
+	GoodsReceiptId      uuid.UUID
+	PurchaseOrderItemId uuid.UUID
+	ProductId           uuid.UUID
+	ProductName         string
+	LotId               *uuid.UUID
+	LotNumber           string
+	ExpiresAt           *time.Time
+	Quantity            float64
+	UnitCost            float64
+}
@@ -0,0 +1,483 @@
+package repository
+
+import (
+	"context"
+
+	"dental_clinic/internal/modules/purchasing/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type PurchasingRepository interface {
+	IsClinicAdmin(clinicId, userId uuid.UUID) (bool, error)
+	ClinicExists(clinicId uuid.UUID) (bool, error)
+	GetAddressClinic(clinicAddressId uuid.UUID) (uuid.UUID, error)
+	ProductExists(productId uuid.UUID) (bool, error)
+
+	GetSuppliers(clinicId uuid.UUID) ([]models.Supplier, error)
+	GetSupplierByID(id uuid.UUID) (*models.Supplier, error)
+	CreateSupplier(supplier *models.Supplier) error
+	UpdateSupplier(supplier *models.Supplier) error
+
+	GetPurchaseOrders(clinicId uuid.UUID, status string) ([]models.PurchaseOrder, error)
+	GetPurchaseOrderByID(id uuid.UUID) (*models.PurchaseOrder, error)
+	GetPurchaseOrderForUpdateTx(id uuid.UUID, tx pgx.Tx) (*models.PurchaseOrder, error)
+	CreatePurchaseOrderTx(order *models.PurchaseOrder, tx pgx.Tx) error
+	UpdatePurchaseOrderTx(order *models.PurchaseOrder, tx pgx.Tx) error
+	ReplaceItemsTx(orderId uuid.UUID, items []models.PurchaseOrderItem, tx pgx.Tx) error
+	SetStatusTx(orderId uuid.UUID, status string, tx pgx.Tx) error
+	AddReceivedQuantityTx(itemId uuid.UUID, quantity float64, tx pgx.Tx) error
+
+	CreateReceiptTx(receipt *models.GoodsReceipt, tx pgx.Tx) error
+	CreateReceiptItemTx(item *models.GoodsReceiptItem, tx pgx.Tx) error
+	GetReceipts(orderId uuid.UUID) ([]models.GoodsReceipt, error)
+}
+
+type purchasingRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewPurchasingRepository(db *pgxpool.Pool) PurchasingRepository {
+	return &purchasingRepo{db: db}
+}
+
+func (r *purchasingRepo) IsClinicAdmin(clinicId, userId uuid.UUID) (bool, error) {
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM clinic_admins WHERE clinic_id = $1 AND user_id = $2)`
+	err := r.db.QueryRow(context.Background(), query, clinicId, userId).Scan(&exists)
+	return exists, err
+}
+
+func (r *purchasingRepo) ClinicExists(clinicId uuid.UUID) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(context.Background(), `SELECT EXISTS (SELECT 1 FROM clinics WHERE id = $1)`, clinicId).Scan(&exists)
+	return exists, err
+}
+
+// GetAddressClinic returns the clinic at the address, or uuid.Nil when there
+// is no such address.
+func (r *purchasingRepo) GetAddressClinic(clinicAddressId uuid.UUID) (uuid.UUID, error) {
+	var clinicId uuid.UUID
+	err := r.db.QueryRow(context.Background(), `SELECT clinic_id FROM clinic_addresses WHERE id = $1`, clinicAddressId).Scan(&clinicId)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return uuid.Nil, nil
+		}
+		return uuid.Nil, err
+	}
+	return clinicId, nil
+}
+
+func (r *purchasingRepo) ProductExists(productId uuid.UUID) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(context.Background(), `SELECT EXISTS (SELECT 1 FROM products WHERE id = $1)`, productId).Scan(&exists)
+	return exists, err
+}
+
+const supplierSelect = `
+	SELECT id, clinic_id, name, contact_name, email, phone, address, is_active, created_at, updated_at
+	FROM suppliers`
+
+func scanSupplier(row pgx.Row) (*models.Supplier, error) {
+	var supplier models.Supplier
+	err := row.Scan(
+		&supplier.Id,
+		&supplier.ClinicId,
+		&supplier.Name,
+		&supplier.ContactName,
+		&supplier.Email,
+		&supplier.Phone,
+		&supplier.Address,
+		&supplier.IsActive,
+		&supplier.CreatedAt,
+		&supplier.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &supplier, nil
+}
+
+func (r *purchasingRepo) GetSuppliers(clinicId uuid.UUID) ([]models.Supplier, error) {
+	rows, err := r.db.Query(context.Background(), supplierSelect+` WHERE clinic_id = $1 ORDER BY is_active DESC, name`, clinicId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	suppliers := make([]models.Supplier, 0)
+	for rows.Next() {
+		supplier, err := scanSupplier(rows)
+		if err != nil {
+			return nil, err
+		}
+		suppliers = append(suppliers, *supplier)
+	}
+	return suppliers, rows.Err()
+}
+
+func (r *purchasingRepo) GetSupplierByID(id uuid.UUID) (*models.Supplier, error) {
+	supplier, err := scanSupplier(r.db.QueryRow(context.Background(), supplierSelect+` WHERE id = $1`, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return supplier, nil
+}
+
+func (r *purchasingRepo) CreateSupplier(supplier *models.Supplier) error {
+	query := `
+		INSERT INTO suppliers (id, clinic_id, name, contact_name, email, phone, address, is_active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $9)
+	`
+	_, err := r.db.Exec(context.Background(), query,
+		supplier.Id,
+		supplier.ClinicId,
+		supplier.Name,
+		supplier.ContactName,
+		supplier.Email,
+		supplier.Phone,
+		supplier.Address,
+		supplier.IsActive,
+		supplier.CreatedAt,
+	)
+	return err
+}
+
+func (r *purchasingRepo) UpdateSupplier(supplier *models.Supplier) error {
+	query := `
+		UPDATE suppliers
+		SET name = $2, contact_name = $3, email = $4, phone = $5, address = $6, is_active = $7, updated_at = NOW()
+		WHERE id = $1
+	`
+	tag, err := r.db.Exec(context.Background(), query,
+		supplier.Id,
+		supplier.Name,
+		supplier.ContactName,
+		supplier.Email,
+		supplier.Phone,
+		supplier.Address,
+		supplier.IsActive,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+const purchaseOrderSelect = `
+	SELECT po.id, po.clinic_id, po.clinic_address_id, po.supplier_id, s.name, s.email, po.status, po.notes,
+	       po.created_by, po.sent_at, po.received_at, po.created_at, po.updated_at
+	FROM purchase_orders po
+	JOIN suppliers s ON s.id = po.supplier_id`
+
+func scanPurchaseOrder(row pgx.Row) (*models.PurchaseOrder, error) {
+	var order models.PurchaseOrder
+	err := row.Scan(
+		&order.Id,
+		&order.ClinicId,
+		&order.ClinicAddressId,
+		&order.SupplierId,
+		&order.SupplierName,
+		&order.SupplierEmail,
+		&order.Status,
+		&order.Notes,
+		&order.CreatedBy,
+		&order.SentAt,
+		&order.ReceivedAt,
+		&order.CreatedAt,
+		&order.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// GetPurchaseOrders lists the orders of the clinic, newest first, with
+// their lines. An empty status lists orders in every status.
+func (r *purchasingRepo) GetPurchaseOrders(clinicId uuid.UUID, status string) ([]models.PurchaseOrder, error) {
+	query := purchaseOrderSelect + `
+		WHERE po.clinic_id = $1 AND ($2 = '' OR po.status = $2)
+		ORDER BY po.created_at DESC
+	`
+	rows, err := r.db.Query(context.Background(), query, clinicId, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := make([]models.PurchaseOrder, 0)
+	ids := make([]uuid.UUID, 0)
+	for rows.Next() {
+		order, err := scanPurchaseOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, *order)
+		ids = append(ids, order.Id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	items, err := r.getItems(r.db, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range orders {
+		orders[i].Items = items[orders[i].Id]
+	}
+	return orders, nil
+}
+
+func (r *purchasingRepo) GetPurchaseOrderByID(id uuid.UUID) (*models.PurchaseOrder, error) {
+	return r.getPurchaseOrder(r.db, purchaseOrderSelect+` WHERE po.id = $1`, id)
+}
+
+// GetPurchaseOrderForUpdateTx locks the order so deliveries against it are
+// booked one at a time.
+func (r *purchasingRepo) GetPurchaseOrderForUpdateTx(id uuid.UUID, tx pgx.Tx) (*models.PurchaseOrder, error) {
+	return r.getPurchaseOrder(tx, purchaseOrderSelect+` WHERE po.id = $1 FOR UPDATE OF po`, id)
+}
+
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+func (r *purchasingRepo) getPurchaseOrder(q querier, query string, id uuid.UUID) (*models.PurchaseOrder, error) {
+	order, err := scanPurchaseOrder(q.QueryRow(context.Background(), query, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	items, err := r.getItems(q, []uuid.UUID{order.Id})
+	if err != nil {
+		return nil, err
+	}
+	order.Items = items[order.Id]
+	return order, nil
+}
+
+func (r *purchasingRepo) getItems(q querier, orderIds []uuid.UUID) (map[uuid.UUID][]models.PurchaseOrderItem, error) {
+	items := make(map[uuid.UUID][]models.PurchaseOrderItem)
+	for _, id := range orderIds {
+		items[id] = make([]models.PurchaseOrderItem, 0)
+	}
+	if len(orderIds) == 0 {
+		return items, nil
+	}
+
+	query := `
+		SELECT i.id, i.purchase_order_id, i.product_id, COALESCE(p.name, ''), COALESCE(p.unit, ''),
+		       i.quantity, i.unit_cost, i.received_quantity
+		FROM purchase_order_items i
+		JOIN products p ON p.id = i.product_id
+		WHERE i.purchase_order_id = ANY($1)
+		ORDER BY p.name
+	`
+	rows, err := q.Query(context.Background(), query, orderIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item models.PurchaseOrderItem
+		if err := rows.Scan(
+			&item.Id,
+			&item.PurchaseOrderId,
+			&item.ProductId,
+			&item.ProductName,
+			&item.ProductUnit,
+			&item.Quantity,
+			&item.UnitCost,
+			&item.ReceivedQuantity,
+		); err != nil {
+			return nil, err
+		}
+		items[item.PurchaseOrderId] = append(items[item.PurchaseOrderId], item)
+	}
+	return items, rows.Err()
+}
+
+func (r *purchasingRepo) CreatePurchaseOrderTx(order *models.PurchaseOrder, tx pgx.Tx) error {
+	query := `
+		INSERT INTO purchase_orders (id, clinic_id, clinic_address_id, supplier_id, status, notes, created_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8)
+	`
+	_, err := tx.Exec(context.Background(), query,
+		order.Id,
+		order.ClinicId,
+		order.ClinicAddressId,
+		order.SupplierId,
+		order.Status,
+		order.Notes,
+		order.CreatedBy,
+		order.CreatedAt,
+	)
+	return err
+}
+
+func (r *purchasingRepo) UpdatePurchaseOrderTx(order *models.PurchaseOrder, tx pgx.Tx) error {
+	query := `
+		UPDATE purchase_orders
+		SET clinic_address_id = $2, supplier_id = $3, notes = $4, updated_at = NOW()
+		WHERE id = $1
+	`
+	tag, err := tx.Exec(context.Background(), query, order.Id, order.ClinicAddressId, order.SupplierId, order.Notes)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// ReplaceItemsTx sets the lines of an order that has not been received
+// against yet.
+func (r *purchasingRepo) ReplaceItemsTx(orderId uuid.UUID, items []models.PurchaseOrderItem, tx pgx.Tx) error {
+	if _, err := tx.Exec(context.Background(), `DELETE FROM purchase_order_items WHERE purchase_order_id = $1`, orderId); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO purchase_order_items (id, purchase_order_id, product_id, quantity, unit_cost)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	for _, item := range items {
+		if _, err := tx.Exec(context.Background(), query, item.Id, orderId, item.ProductId, item.Quantity, item.UnitCost); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetStatusTx moves the order to the status, noting when it was sent or
+// fully received.
+func (r *purchasingRepo) SetStatusTx(orderId uuid.UUID, status string, tx pgx.Tx) error {
+	query := `
+		UPDATE purchase_orders
+		SET status = $2,
+		    sent_at = CASE WHEN $2 = 'sent' THEN NOW() ELSE sent_at END,
+		    received_at = CASE WHEN $2 = 'received' THEN NOW() ELSE received_at END,
+		    updated_at = NOW()
+		WHERE id = $1
+	`
+	tag, err := tx.Exec(context.Background(), query, orderId, status)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *purchasingRepo) AddReceivedQuantityTx(itemId uuid.UUID, quantity float64, tx pgx.Tx) error {
+	query := `UPDATE purchase_order_items SET received_quantity = received_quantity + $2 WHERE id = $1`
+	tag, err := tx.Exec(context.Background(), query, itemId, quantity)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *purchasingRepo) CreateReceiptTx(receipt *models.GoodsReceipt, tx pgx.Tx) error {
+	query := `
+		INSERT INTO goods_receipts (id, purchase_order_id, received_by, notes, received_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	_, err := tx.Exec(context.Background(), query,
+		receipt.Id,
+		receipt.PurchaseOrderId,
+		receipt.ReceivedBy,
+		receipt.Notes,
+		receipt.ReceivedAt,
+	)
+	return err
+}
+
+func (r *purchasingRepo) CreateReceiptItemTx(item *models.GoodsReceiptItem, tx pgx.Tx) error {
+	query := `
+		INSERT INTO goods_receipt_items (id, goods_receipt_id, purchase_order_item_id, lot_id, quantity, unit_cost)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	_, err := tx.Exec(context.Background(), query,
+		item.Id,
+		item.GoodsReceiptId,
+		item.PurchaseOrderItemId,
+		item.LotId,
+		item.Quantity,
+		item.UnitCost,
+	)
+	return err
+}
+
+// GetReceipts lists the deliveries against the order, newest first.
+func (r *purchasingRepo) GetReceipts(orderId uuid.UUID) ([]models.GoodsReceipt, error) {
+	query := `
+		SELECT gr.id, gr.purchase_order_id, gr.received_by, gr.notes, gr.received_at,
+		       gri.id, gri.purchase_order_item_id, poi.product_id, COALESCE(p.name, ''),
+		       gri.lot_id, COALESCE(l.lot_number, ''), l.expires_at, gri.quantity, gri.unit_cost
+		FROM goods_receipts gr
+		JOIN goods_receipt_items gri ON gri.goods_receipt_id = gr.id
+		JOIN purchase_order_items poi ON poi.id = gri.purchase_order_item_id
+		JOIN products p ON p.id = poi.product_id
+		LEFT JOIN inventory_lots l ON l.id = gri.lot_id
+		WHERE gr.purchase_order_id = $1
+		ORDER BY gr.received_at DESC, gr.id, p.name
+	`
+	rows, err := r.db.Query(context.Background(), query, orderId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	receipts := make([]models.GoodsReceipt, 0)
+	for rows.Next() {
+		var receipt models.GoodsReceipt
+		var item models.GoodsReceiptItem
+		if err := rows.Scan(
+			&receipt.Id,
+			&receipt.PurchaseOrderId,
+			&receipt.ReceivedBy,
+			&receipt.Notes,
+			&receipt.ReceivedAt,
+			&item.Id,
+			&item.PurchaseOrderItemId,
+			&item.ProductId,
+			&item.ProductName,
+			&item.LotId,
+			&item.LotNumber,
+			&item.ExpiresAt,
+			&item.Quantity,
+			&item.UnitCost,
+		); err != nil {
+			return nil, err
+		}
+		item.GoodsReceiptId = receipt.Id
+
+		if n := len(receipts); n > 0 && receipts[n-1].Id == receipt.Id {
+			receipts[n-1].Items = append(receipts[n-1].Items, item)
+			continue
+		}
+		receipt.Items = []models.GoodsReceiptItem{item}
+		receipts = append(receipts, receipt)
+	}
+	return receipts, rows.Err()
+}
@@ -0,0 +1,38 @@
+package purchasing
+
+import (
+	"dental_clinic/internal/config"
+	"dental_clinic/internal/modules/purchasing/handlers"
+	"dental_clinic/internal/modules/purchasing/repository"
+	"dental_clinic/internal/modules/purchasing/services"
+
+	inventoryRepository "dental_clinic/internal/modules/inventory/repository"
+	inventoryServices "dental_clinic/internal/modules/inventory/services"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func RegisterPrivateRoutes(r *mux.Router, db *pgxpool.Pool, cfg *config.Config) {
+	repo := repository.NewPurchasingRepository(db)
+
+	inventoryRepo := inventoryRepository.NewInventoryRepository(db)
+	inventoryService := inventoryServices.NewInventoryService(inventoryRepo, db)
+
+	service := services.NewPurchasingService(repo, db, inventoryService, *cfg)
+	handler := handlers.NewPurchasingHandler(service, *cfg)
+
+	r.HandleFunc("/clinics/{clinicId}/suppliers", handler.GetSuppliers).Methods("GET")
+	r.HandleFunc("/clinics/{clinicId}/suppliers", handler.CreateSupplier).Methods("POST")
+	r.HandleFunc("/suppliers/{id}", handler.UpdateSupplier).Methods("PUT")
+	r.HandleFunc("/suppliers/{id}", handler.DeactivateSupplier).Methods("DELETE")
+
+	r.HandleFunc("/clinics/{clinicId}/purchase-orders", handler.GetPurchaseOrders).Methods("GET")
+	r.HandleFunc("/clinics/{clinicId}/purchase-orders", handler.CreatePurchaseOrder).Methods("POST")
+	r.HandleFunc("/purchase-orders/{id}", handler.GetPurchaseOrder).Methods("GET")
+	r.HandleFunc("/purchase-orders/{id}", handler.UpdatePurchaseOrder).Methods("PUT")
+	r.HandleFunc("/purchase-orders/{id}/send", handler.SendPurchaseOrder).Methods("POST")
+	r.HandleFunc("/purchase-orders/{id}/cancel", handler.CancelPurchaseOrder).Methods("POST")
+	r.HandleFunc("/purchase-orders/{id}/receipts", handler.GetReceipts).Methods("GET")
+	r.HandleFunc("/purchase-orders/{id}/receipts", handler.ReceiveGoods).Methods("POST")
+}
@@ -0,0 +1,690 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"dental_clinic/internal/config"
+	"dental_clinic/internal/modules/purchasing/dto"
+	"dental_clinic/internal/modules/purchasing/models"
+	"dental_clinic/internal/modules/purchasing/repository"
+	"dental_clinic/internal/queue"
+	"dental_clinic/internal/utils"
+
+	inventoryModels "dental_clinic/internal/modules/inventory/models"
+	inventoryServices "dental_clinic/internal/modules/inventory/services"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type PurchasingService struct {
+	repo         repository.PurchasingRepository
+	db           *pgxpool.Pool
+	inventorySrv *inventoryServices.InventoryService
+	cfx          config.Config
+	queue        *queue.Queue
+}
+
+func NewPurchasingService(repo repository.PurchasingRepository, db *pgxpool.Pool, inventorySrv *inventoryServices.InventoryService, cfx config.Config) *PurchasingService {
+	return &PurchasingService{
+		repo:         repo,
+		db:           db,
+		inventorySrv: inventorySrv,
+		cfx:          cfx,
+		queue:        queue.New(db),
+	}
+}
+
+func (s *PurchasingService) GetSuppliers(clinicId, userId, role string) ([]models.Supplier, error) {
+	clinicUUID, err := s.manageableClinic(clinicId, userId, role)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetSuppliers(clinicUUID)
+}
+
+func (s *PurchasingService) CreateSupplier(clinicId, userId, role string, req dto.SupplierRequest) (*models.Supplier, error) {
+	clinicUUID, err := s.manageableClinic(clinicId, userId, role)
+	if err != nil {
+		return nil, err
+	}
+
+	supplier := &models.Supplier{
+		Id:        uuid.New(),
+		ClinicId:  clinicUUID,
+		IsActive:  true,
+		CreatedAt: time.Now(),
+	}
+	if err := applySupplier(supplier, req); err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateSupplier(supplier); err != nil {
+		return nil, err
+	}
+	return s.getSupplier(supplier.Id)
+}
+
+func (s *PurchasingService) UpdateSupplier(id, userId, role string, req dto.SupplierRequest) (*models.Supplier, error) {
+	supplier, err := s.manageableSupplier(id, userId, role)
+	if err != nil {
+		return nil, err
+	}
+	if err := applySupplier(supplier, req); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateSupplier(supplier); err != nil {
+		return nil, err
+	}
+	return s.getSupplier(supplier.Id)
+}
+
+// DeactivateSupplier stops new orders going to the supplier. It is kept so
+// the orders already placed with it still show who they went to.
+func (s *PurchasingService) DeactivateSupplier(id, userId, role string) error {
+	supplier, err := s.manageableSupplier(id, userId, role)
+	if err != nil {
+		return err
+	}
+	supplier.IsActive = false
+	return s.repo.UpdateSupplier(supplier)
+}
+
+func (s *PurchasingService) GetPurchaseOrders(clinicId, userId, role, status string) ([]models.PurchaseOrder, error) {
+	clinicUUID, err := s.manageableClinic(clinicId, userId, role)
+	if err != nil {
+		return nil, err
+	}
+	switch status {
+	case "", "draft", "sent", "partially_received", "received", "cancelled":
+	default:
+		return nil, errors.New("status must be draft, sent, partially_received, received or cancelled")
+	}
+	return s.repo.GetPurchaseOrders(clinicUUID, status)
+}
+
+func (s *PurchasingService) GetPurchaseOrder(id, userId, role string) (*models.PurchaseOrder, error) {
+	return s.manageablePurchaseOrder(id, userId, role)
+}
+
+// CreatePurchaseOrder starts a draft order with an active supplier of the
+// clinic for delivery to one of its addresses.
+func (s *PurchasingService) CreatePurchaseOrder(ctx context.Context, clinicId, userId, role string, req dto.PurchaseOrderRequest) (*models.PurchaseOrder, error) {
+	clinicUUID, err := s.manageableClinic(clinicId, userId, role)
+	if err != nil {
+		return nil, err
+	}
+
+	order := &models.PurchaseOrder{
+		Id:        uuid.New(),
+		ClinicId:  clinicUUID,
+		Status:    "draft",
+		CreatedAt: time.Now(),
+	}
+	if userUUID, err := uuid.Parse(userId); err == nil {
+		order.CreatedBy = &userUUID
+	}
+	items, err := s.applyPurchaseOrder(order, req)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := s.repo.CreatePurchaseOrderTx(order, tx); err != nil {
+		return nil, err
+	}
+	if err := s.repo.ReplaceItemsTx(order.Id, items, tx); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return s.getPurchaseOrder(order.Id)
+}
+
+// UpdatePurchaseOrder changes the supplier, address, notes and lines of a
+// draft. Orders that have been sent are fixed.
+func (s *PurchasingService) UpdatePurchaseOrder(ctx context.Context, id, userId, role string, req dto.PurchaseOrderRequest) (*models.PurchaseOrder, error) {
+	order, err := s.manageablePurchaseOrder(id, userId, role)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if order, err = s.repo.GetPurchaseOrderForUpdateTx(order.Id, tx); err != nil {
+		return nil, err
+	}
+	if order == nil {
+		return nil, errors.New("purchase order not found")
+	}
+	if order.Status != "draft" {
+		return nil, errors.New("only draft purchase orders can be changed")
+	}
+	items, err := s.applyPurchaseOrder(order, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UpdatePurchaseOrderTx(order, tx); err != nil {
+		return nil, err
+	}
+	if err := s.repo.ReplaceItemsTx(order.Id, items, tx); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return s.getPurchaseOrder(order.Id)
+}
+
+// SendPurchaseOrder places a draft with the supplier. The supplier is
+// emailed the order from the queue when it has an email address; an email
+// that cannot be queued does not undo the send.
+func (s *PurchasingService) SendPurchaseOrder(ctx context.Context, id, userId, role string) (*models.PurchaseOrder, error) {
+	order, err := s.setStatus(ctx, id, userId, role, "sent", "draft")
+	if err != nil {
+		return nil, err
+	}
+	if order.SupplierEmail != "" {
+		_ = utils.QueueEmail(ctx, s.queue, order.SupplierEmail, "Purchase order "+order.Id.String(), purchaseOrderMessage(*order))
+	}
+	return order, nil
+}
+
+// CancelPurchaseOrder withdraws an order nothing has been received against.
+func (s *PurchasingService) CancelPurchaseOrder(ctx context.Context, id, userId, role string) (*models.PurchaseOrder, error) {
+	return s.setStatus(ctx, id, userId, role, "cancelled", "draft", "sent")
+}
+
+func (s *PurchasingService) setStatus(ctx context.Context, id, userId, role, status string, from ...string) (*models.PurchaseOrder, error) {
+	order, err := s.manageablePurchaseOrder(id, userId, role)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if order, err = s.repo.GetPurchaseOrderForUpdateTx(order.Id, tx); err != nil {
+		return nil, err
+	}
+	if order == nil {
+		return nil, errors.New("purchase order not found")
+	}
+	allowed := false
+	for _, current := range from {
+		if order.Status == current {
+			allowed = true
+		}
+	}
+	if !allowed {
+		return nil, fmt.Errorf("a %s purchase order cannot be %s", strings.ReplaceAll(order.Status, "_", " "), status)
+	}
+	if status == "sent" && len(order.Items) == 0 {
+		return nil, errors.New("purchase order has no items")
+	}
+
+	if err := s.repo.SetStatusTx(order.Id, status, tx); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return s.getPurchaseOrder(order.Id)
+}
+
+// ReceiveGoods books a delivery against a sent order into the stock of its
+// address. Each line goes into the lot given, at the unit cost invoiced or
+// else the ordered one. The order is received once every line is delivered
+// in full; nothing more than ordered can be received.
+func (s *PurchasingService) ReceiveGoods(ctx context.Context, id, userId, role string, req dto.GoodsReceiptRequest) (*models.GoodsReceipt, error) {
+	order, err := s.manageablePurchaseOrder(id, userId, role)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.Items) == 0 {
+		return nil, errors.New("items are required")
+	}
+
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if order, err = s.repo.GetPurchaseOrderForUpdateTx(order.Id, tx); err != nil {
+		return nil, err
+	}
+	if order == nil {
+		return nil, errors.New("purchase order not found")
+	}
+	if order.Status != "sent" && order.Status != "partially_received" {
+		return nil, fmt.Errorf("goods cannot be received against a %s purchase order", order.Status)
+	}
+
+	lines := make(map[uuid.UUID]*models.PurchaseOrderItem)
+	for i := range order.Items {
+		lines[order.Items[i].Id] = &order.Items[i]
+	}
+
+	receipt := &models.GoodsReceipt{
+		Id:              uuid.New(),
+		PurchaseOrderId: order.Id,
+		Notes:           strings.TrimSpace(req.Notes),
+		ReceivedAt:      time.Now(),
+	}
+	if userUUID, err := uuid.Parse(userId); err == nil {
+		receipt.ReceivedBy = &userUUID
+	}
+	if err := s.repo.CreateReceiptTx(receipt, tx); err != nil {
+		return nil, err
+	}
+
+	for _, line := range req.Items {
+		itemId, err := uuid.Parse(line.PurchaseOrderItemId)
+		if err != nil {
+			return nil, errors.New("invalid purchase order item id")
+		}
+		item, ok := lines[itemId]
+		if !ok {
+			return nil, errors.New("purchase order item not found")
+		}
+		if line.Quantity <= 0 {
+			return nil, errors.New("quantity must be greater than 0")
+		}
+		if item.ReceivedQuantity+line.Quantity > item.Quantity {
+			return nil, fmt.Errorf("%g %s of %s ordered, %g already received", item.Quantity, item.ProductUnit, item.ProductName, item.ReceivedQuantity)
+		}
+		expiresAt, err := parseDate(line.ExpiresAt, "expires_at")
+		if err != nil {
+			return nil, err
+		}
+		unitCost := item.UnitCost
+		if line.UnitCost != nil {
+			unitCost = *line.UnitCost
+		}
+
+		_, lot, err := s.inventorySrv.ReceiveStockTx(inventoryModels.StockReceipt{
+			ClinicAddressId: order.ClinicAddressId,
+			ProductId:       item.ProductId,
+			Quantity:        line.Quantity,
+			LotNumber:       strings.TrimSpace(line.LotNumber),
+			ExpiresAt:       expiresAt,
+			UnitCost:        &unitCost,
+			GoodsReceiptId:  &receipt.Id,
+		}, tx)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := s.repo.CreateReceiptItemTx(&models.GoodsReceiptItem{
+			Id:                  uuid.New(),
+			GoodsReceiptId:      receipt.Id,
+			PurchaseOrderItemId: item.Id,
+			LotId:               &lot.Id,
+			Quantity:            line.Quantity,
+			UnitCost:            unitCost,
+		}, tx); err != nil {
+			return nil, err
+		}
+		if err := s.repo.AddReceivedQuantityTx(item.Id, line.Quantity, tx); err != nil {
+			return nil, err
+		}
+		item.ReceivedQuantity += line.Quantity
+	}
+
+	status := "received"
+	for _, item := range order.Items {
+		if item.ReceivedQuantity < item.Quantity {
+			status = "partially_received"
+		}
+	}
+	if err := s.repo.SetStatusTx(order.Id, status, tx); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	receipts, err := s.repo.GetReceipts(order.Id)
+	if err != nil {
+		return nil, err
+	}
+	for i := range receipts {
+		if receipts[i].Id == receipt.Id {
+			return &receipts[i], nil
+		}
+	}
+	return nil, errors.New("goods receipt not found")
+}
+
+func (s *PurchasingService) GetReceipts(id, userId, role string) ([]models.GoodsReceipt, error) {
+	order, err := s.manageablePurchaseOrder(id, userId, role)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetReceipts(order.Id)
+}
+
+// applyPurchaseOrder checks the request against the order's clinic and
+// sets the supplier, address and notes. It returns the order lines.
+func (s *PurchasingService) applyPurchaseOrder(order *models.PurchaseOrder, req dto.PurchaseOrderRequest) ([]models.PurchaseOrderItem, error) {
+	supplierId, err := uuid.Parse(req.SupplierId)
+	if err != nil {
+		return nil, errors.New("invalid supplier id")
+	}
+	supplier, err := s.getSupplier(supplierId)
+	if err != nil {
+		return nil, err
+	}
+	if supplier.ClinicId != order.ClinicId {
+		return nil, errors.New("supplier not found")
+	}
+	if !supplier.IsActive {
+		return nil, errors.New("supplier is not active")
+	}
+
+	addressId, err := uuid.Parse(req.ClinicAddressId)
+	if err != nil {
+		return nil, errors.New("invalid clinic address id")
+	}
+	addressClinic, err := s.repo.GetAddressClinic(addressId)
+	if err != nil {
+		return nil, err
+	}
+	if addressClinic != order.ClinicId {
+		return nil, errors.New("clinic address not found")
+	}
+
+	if len(req.Items) == 0 {
+		return nil, errors.New("items are required")
+	}
+	items := make([]models.PurchaseOrderItem, 0, len(req.Items))
+	seen := make(map[uuid.UUID]bool)
+	for _, line := range req.Items {
+		productId, err := uuid.Parse(line.ProductId)
+		if err != nil {
+			return nil, errors.New("invalid product id")
+		}
+		if seen[productId] {
+			return nil, errors.New("each product can be ordered once")
+		}
+		seen[productId] = true
+		if line.Quantity <= 0 {
+			return nil, errors.New("quantity must be greater than 0")
+		}
+		if line.UnitCost < 0 {
+			return nil, errors.New("unit_cost cannot be negative")
+		}
+		exists, err := s.repo.ProductExists(productId)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, errors.New("product not found")
+		}
+		items = append(items, models.PurchaseOrderItem{
+			Id:              uuid.New(),
+			PurchaseOrderId: order.Id,
+			ProductId:       productId,
+			Quantity:        line.Quantity,
+			UnitCost:        line.UnitCost,
+		})
+	}
+
+	order.SupplierId = supplierId
+	order.ClinicAddressId = addressId
+	order.Notes = strings.TrimSpace(req.Notes)
+	return items, nil
+}
+
+func applySupplier(supplier *models.Supplier, req dto.SupplierRequest) error {
+	supplier.Name = strings.TrimSpace(req.Name)
+	if supplier.Name == "" {
+		return errors.New("name is required")
+	}
+	supplier.ContactName = strings.TrimSpace(req.ContactName)
+	supplier.Email = strings.TrimSpace(req.Email)
+	supplier.Phone = strings.TrimSpace(req.Phone)
+	supplier.Address = strings.TrimSpace(req.Address)
+	if req.IsActive != nil {
+		supplier.IsActive = *req.IsActive
+	}
+	return nil
+}
+
+func (s *PurchasingService) manageableSupplier(id, userId, role string) (*models.Supplier, error) {
+	supplierId, err := uuid.Parse(id)
+	if err != nil {
+		return nil, errors.New("invalid supplier id")
+	}
+	supplier, err := s.getSupplier(supplierId)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.canManageClinic(supplier.ClinicId, userId, role); err != nil {
+		return nil, err
+	}
+	return supplier, nil
+}
+
+func (s *PurchasingService) getSupplier(id uuid.UUID) (*models.Supplier, error) {
+	supplier, err := s.repo.GetSupplierByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if supplier == nil {
+		return nil, errors.New("supplier not found")
+	}
+	return supplier, nil
+}
+
+func (s *PurchasingService) manageablePurchaseOrder(id, userId, role string) (*models.PurchaseOrder, error) {
+	orderId, err := uuid.Parse(id)
+	if err != nil {
+		return nil, errors.New("invalid purchase order id")
+	}
+	order, err := s.getPurchaseOrder(orderId)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.canManageClinic(order.ClinicId, userId, role); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+func (s *PurchasingService) getPurchaseOrder(id uuid.UUID) (*models.PurchaseOrder, error) {
+	order, err := s.repo.GetPurchaseOrderByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if order == nil {
+		return nil, errors.New("purchase order not found")
+	}
+	return order, nil
+}
+
+func (s *PurchasingService) manageableClinic(clinicId, userId, role string) (uuid.UUID, error) {
+	clinicUUID, err := uuid.Parse(clinicId)
+	if err != nil {
+		return uuid.Nil, errors.New("invalid clinic id")
+	}
+	if err := s.canManageClinic(clinicUUID, userId, role); err != nil {
+		return uuid.Nil, err
+	}
+	exists, err := s.repo.ClinicExists(clinicUUID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if !exists {
+		return uuid.Nil, errors.New("clinic not found")
+	}
+	return clinicUUID, nil
+}
+
+// canManageClinic allows platform admins and the admins of the clinic.
+func (s *PurchasingService) canManageClinic(clinicId uuid.UUID, userId, role string) error {
+	switch role {
+	case "admin":
+		return nil
+	case "clinic_admin":
+		userUUID, err := uuid.Parse(userId)
+		if err != nil {
+			return errors.New("invalid user id")
+		}
+		isAdmin, err := s.repo.IsClinicAdmin(clinicId, userUUID)
+		if err != nil {
+			return err
+		}
+		if isAdmin {
+			return nil
+		}
+	}
+	return errors.New("do not have rights")
+}
+
+func purchaseOrderMessage(order models.PurchaseOrder) string {
+	var b strings.Builder
+	b.WriteString("<p>Purchase order " + order.Id.String() + "</p><ul>")
+	for _, item := range order.Items {
+		b.WriteString(fmt.Sprintf("<li>%s: %g %s at %.2f</li>", html.EscapeString(item.ProductName), item.Quantity, html.EscapeString(item.ProductUnit), item.UnitCost))
+	}
+	b.WriteString(fmt.Sprintf("</ul><p>Total: %.2f</p>", order.Total()))
+	if order.Notes != "" {
+		b.WriteString("<p>" + html.EscapeString(order.Notes) + "</p>")
+	}
+	return b.String()
+}
+
+func parseDate(v, field string) (*time.Time, error) {
+	if v == "" {
+		return nil, nil
+	}
+	t, err := time.Parse("2006-01-02", v)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be YYYY-MM-DD", field)
+	}
+	return &t, nil
+}
+
+func ToSupplierResponse(supplier models.Supplier) dto.SupplierResponse {
+	return dto.SupplierResponse{
+		Id:          supplier.Id.String(),
+		ClinicId:    supplier.ClinicId.String(),
+		Name:        supplier.Name,
+		ContactName: supplier.ContactName,
+		Email:       supplier.Email,
+		Phone:       supplier.Phone,
+		Address:     supplier.Address,
+		IsActive:    supplier.IsActive,
+		CreatedAt:   supplier.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+func ToSupplierResponseList(suppliers []models.Supplier) []dto.SupplierResponse {
+	result := make([]dto.SupplierResponse, 0, len(suppliers))
+	for _, supplier := range suppliers {
+		result = append(result, ToSupplierResponse(supplier))
+	}
+	return result
+}
+
+func ToPurchaseOrderResponse(order models.PurchaseOrder) dto.PurchaseOrderResponse {
+	items := make([]dto.PurchaseOrderItemResponse, 0, len(order.Items))
+	for _, item := range order.Items {
+		items = append(items, dto.PurchaseOrderItemResponse{
+			Id:               item.Id.String(),
+			ProductId:        item.ProductId.String(),
+			ProductName:      item.ProductName,
+			ProductUnit:      item.ProductUnit,
+			Quantity:         item.Quantity,
+			UnitCost:         item.UnitCost,
+			ReceivedQuantity: item.ReceivedQuantity,
+		})
+	}
+
+	response := dto.PurchaseOrderResponse{
+		Id:              order.Id.String(),
+		ClinicId:        order.ClinicId.String(),
+		ClinicAddressId: order.ClinicAddressId.String(),
+		SupplierId:      order.SupplierId.String(),
+		SupplierName:    order.SupplierName,
+		Status:          order.Status,
+		Notes:           order.Notes,
+		Total:           order.Total(),
+		CreatedAt:       order.CreatedAt.Format(time.RFC3339),
+		Items:           items,
+	}
+	if order.SentAt != nil {
+		response.SentAt = order.SentAt.Format(time.RFC3339)
+	}
+	if order.ReceivedAt != nil {
+		response.ReceivedAt = order.ReceivedAt.Format(time.RFC3339)
+	}
+	return response
+}
+
+func ToPurchaseOrderResponseList(orders []models.PurchaseOrder) []dto.PurchaseOrderResponse {
+	result := make([]dto.PurchaseOrderResponse, 0, len(orders))
+	for _, order := range orders {
+		result = append(result, ToPurchaseOrderResponse(order))
+	}
+	return result
+}
+
+func ToGoodsReceiptResponse(receipt models.GoodsReceipt) dto.GoodsReceiptResponse {
+	items := make([]dto.GoodsReceiptItemResponse, 0, len(receipt.Items))
+	for _, item := range receipt.Items {
+		response := dto.GoodsReceiptItemResponse{
+			Id:                  item.Id.String(),
+			PurchaseOrderItemId: item.PurchaseOrderItemId.String(),
+			ProductId:           item.ProductId.String(),
+			ProductName:         item.ProductName,
+			LotNumber:           item.LotNumber,
+			Quantity:            item.Quantity,
+			UnitCost:            item.UnitCost,
+		}
+		if item.LotId != nil {
+			response.LotId = item.LotId.String()
+		}
+		if item.ExpiresAt != nil {
+			response.ExpiresAt = item.ExpiresAt.Format("2006-01-02")
+		}
+		items = append(items, response)
+	}
+
+	return dto.GoodsReceiptResponse{
+		Id:              receipt.Id.String(),
+		PurchaseOrderId: receipt.PurchaseOrderId.String(),
+		Notes:           receipt.Notes,
+		ReceivedAt:      receipt.ReceivedAt.Format(time.RFC3339),
+		Items:           items,
+	}
+}
+
+func ToGoodsReceiptResponseList(receipts []models.GoodsReceipt) []dto.GoodsReceiptResponse {
+	result := make([]dto.GoodsReceiptResponse, 0, len(receipts))
+	for _, receipt := range receipts {
+		result = append(result, ToGoodsReceiptResponse(receipt))
+	}
+	return result
+}
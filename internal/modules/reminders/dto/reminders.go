@@ -0,0 +1,61 @@
+package dto
+
+// ReminderSettingsRequest replaces the clinic's reminder schedule. Rules
+// are left as they are when omitted.
+type ReminderSettingsRequest struct {
+	Enabled *bool                 `json:"enabled"`
+	Rules   []ReminderRuleRequest `json:"rules"`
+}
+
+type ReminderRuleRequest struct {
+	// HoursBefore is how long before the appointment the reminder goes out,
+	// 1 to 336 (two weeks)
+	HoursBefore int `json:"hours_before"`
+	// Channels are email and/or sms
+	Channels []string `json:"channels"`
+}
+
+type ReminderSettingsResponse struct {
+	ClinicId string                 `json:"clinic_id"`
+	Enabled  bool                   `json:"enabled"`
+	Rules    []ReminderRuleResponse `json:"rules"`
+	// IsDefault is true until the clinic saves its own schedule
+	IsDefault bool   `json:"is_default"`
+	UpdatedAt string `json:"updated_at,omitempty"`
+}
+
+type ReminderRuleResponse struct {
+	HoursBefore int      `json:"hours_before"`
+	Channels    []string `json:"channels"`
+}
+
+// ReminderPreferencesRequest changes how the current user is reminded.
+// Fields left out keep their value. Phone is in international format, as
+// +4915112345678, and is needed for SMS.
+type ReminderPreferencesRequest struct {
+	EmailEnabled *bool   `json:"email_enabled"`
+	SmsEnabled   *bool   `json:"sms_enabled"`
+	Phone        *string `json:"phone"`
+}
+
+type ReminderPreferencesResponse struct {
+	// PushConsent is changed on the user's profile; reminders are only sent
+	// with it
+	PushConsent  bool   `json:"push_consent"`
+	EmailEnabled bool   `json:"email_enabled"`
+	SmsEnabled   bool   `json:"sms_enabled"`
+	Phone        string `json:"phone"`
+	UpdatedAt    string `json:"updated_at,omitempty"`
+}
+
+type ReminderResponse struct {
+	Id          string `json:"id"`
+	HoursBefore int    `json:"hours_before"`
+	Channel     string `json:"channel"`
+	StartTime   string `json:"start_time"`
+	Status      string `json:"status"`
+	Recipient   string `json:"recipient,omitempty"`
+	Error       string `json:"error,omitempty"`
+	CreatedAt   string `json:"created_at"`
+	SentAt      string `json:"sent_at,omitempty"`
+}
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"dental_clinic/internal/config"
+	"dental_clinic/internal/modules/reminders/dto"
+	"dental_clinic/internal/modules/reminders/services"
+	"dental_clinic/internal/utils"
+
+	"github.com/gorilla/mux"
+)
+
+type ReminderHandler struct {
+	service *services.ReminderService
+	cfg     config.Config
+}
+
+func NewReminderHandler(service *services.ReminderService, cfg config.Config) *ReminderHandler {
+	return &ReminderHandler{service: service, cfg: cfg}
+}
+
+// GetReminderSettings godoc
+// @Summary Get clinic reminder settings
+// @Description Returns when and over which channels the clinic's patients are reminded of their appointments. A clinic that has not saved settings gets one email reminder 24 hours before.
+// @Tags Reminders
+// @Security BearerAuth
+// @Produce json
+// @Param clinicId path string true "Clinic ID"
+// @Success 200 {object} dto.ReminderSettingsResponse
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/clinics/{clinicId}/reminder-settings [get]
+func (h *ReminderHandler) GetReminderSettings(w http.ResponseWriter, r *http.Request) {
+	userId, role := h.currentUser(r)
+	settings, err := h.service.GetReminderSettings(mux.Vars(r)["clinicId"], userId, role)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, services.ToReminderSettingsResponse(*settings))
+}
+
+// UpdateReminderSettings godoc
+// @Summary Update clinic reminder settings
+// @Description Turns reminders on or off and replaces the reminder schedule, as 48 hours before by email and 2 hours before by sms. Patients are only reminded with push consent and over the channels they turned on.
+// @Tags Reminders
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param clinicId path string true "Clinic ID"
+// @Param request body dto.ReminderSettingsRequest true "Reminder settings"
+// @Success 200 {object} dto.ReminderSettingsResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/clinics/{clinicId}/reminder-settings [put]
+func (h *ReminderHandler) UpdateReminderSettings(w http.ResponseWriter, r *http.Request) {
+	var req dto.ReminderSettingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	userId, role := h.currentUser(r)
+	settings, err := h.service.UpdateReminderSettings(r.Context(), mux.Vars(r)["clinicId"], userId, role, req)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, services.ToReminderSettingsResponse(*settings))
+}
+
+// GetReminderPreferences godoc
+// @Summary Get my reminder preferences
+// @Description Returns over which channels the current user is reminded of appointments
+// @Tags Reminders
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} dto.ReminderPreferencesResponse
+// @Failure 404 {object} map[string]string
+// @Router /api/users/me/reminder-preferences [get]
+func (h *ReminderHandler) GetReminderPreferences(w http.ResponseWriter, r *http.Request) {
+	userId, _ := h.currentUser(r)
+	prefs, err := h.service.GetReminderPreferences(userId)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, services.ToReminderPreferencesResponse(*prefs))
+}
+
+// UpdateReminderPreferences godoc
+// @Summary Update my reminder preferences
+// @Description Turns email and sms reminders on or off and sets the phone number sms reminders go to. Push consent is changed on the user profile.
+// @Tags Reminders
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body dto.ReminderPreferencesRequest true "Reminder preferences"
+// @Success 200 {object} dto.ReminderPreferencesResponse
+// @Failure 400 {object} map[string]string
+// @Router /api/users/me/reminder-preferences [put]
+func (h *ReminderHandler) UpdateReminderPreferences(w http.ResponseWriter, r *http.Request) {
+	var req dto.ReminderPreferencesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	userId, _ := h.currentUser(r)
+	prefs, err := h.service.UpdateReminderPreferences(userId, req)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, services.ToReminderPreferencesResponse(*prefs))
+}
+
+// GetAppointmentReminders godoc
+// @Summary List appointment reminders
+// @Description Lists the reminders of an appointment, who they went to and why any were skipped or failed
+// @Tags Reminders
+// @Security BearerAuth
+// @Produce json
+// @Param appointmentId path string true "Appointment ID"
+// @Success 200 {array} dto.ReminderResponse
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/appointments/{appointmentId}/reminders [get]
+func (h *ReminderHandler) GetAppointmentReminders(w http.ResponseWriter, r *http.Request) {
+	userId, role := h.currentUser(r)
+	reminders, err := h.service.GetAppointmentReminders(mux.Vars(r)["appointmentId"], userId, role)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, services.ToReminderResponseList(reminders))
+}
+
+func (h *ReminderHandler) currentUser(r *http.Request) (string, string) {
+	claims, err := utils.GetClaims(utils.GetToken(r), h.cfg.JWTSecret)
+	if err != nil {
+		return "", ""
+	}
+	userId, _ := claims["user_id"].(string)
+	role, _ := claims["role"].(string)
+	return userId, role
+}
+
+func errorStatus(err error) int {
+	switch {
+	case err.Error() == "do not have rights":
+		return http.StatusForbidden
+	case strings.HasSuffix(err.Error(), "not found"):
+		return http.StatusNotFound
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+func respondJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+func respondError(w http.ResponseWriter, status int, message string) {
+	respondJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,28 @@
+package reminders
+
+import (
+	"context"
+
+	"dental_clinic/internal/config"
+	"dental_clinic/internal/modules/reminders/repository"
+	"dental_clinic/internal/modules/reminders/services"
+	"dental_clinic/internal/notify"
+	"dental_clinic/internal/queue"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// RegisterJobs lets the worker send appointment reminders and looks for
+// reminders that are due every five minutes.
+func RegisterJobs(w *queue.Worker, db *pgxpool.Pool, cfg *config.Config) {
+	repo := repository.NewReminderRepository(db)
+	service := services.NewReminderService(repo, db, *cfg, notify.NewProviders(cfg))
+
+	queue.Handle(w, services.DispatchRemindersJob, func(ctx context.Context, _ struct{}) error {
+		return service.DispatchDueReminders(ctx)
+	})
+	queue.Handle(w, services.SendReminderJob, func(ctx context.Context, payload services.SendReminderPayload) error {
+		return service.SendReminder(ctx, payload.ReminderId)
+	})
+	_ = w.Schedule("dispatch_reminders", "*/5 * * * *", queue.Job{Type: services.DispatchRemindersJob, Priority: queue.PriorityHigh})
+}
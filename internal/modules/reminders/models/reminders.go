@@ -0,0 +1,72 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ReminderSettings are a clinic's reminder schedule. IsDefault is set when
+// the clinic has not chosen one yet.
+type ReminderSettings struct {
+	ClinicId  uuid.UUID
+	Enabled   bool
+	Rules     []ReminderRule
+	IsDefault bool
+	UpdatedAt time.Time
+}
+
+// ReminderRule sends a reminder over each of Channels HoursBefore the
+// appointment starts.
+type ReminderRule struct {
+	HoursBefore int
+	Channels    []string
+}
+
+// ReminderPreferences are how a patient wants to be reminded. PushConsent
+// comes from the user's profile; without it no reminder is sent at all.
+type ReminderPreferences struct {
+	UserId       uuid.UUID
+	PushConsent  bool
+	EmailEnabled bool
+	SmsEnabled   bool
+	Phone        string
+	UpdatedAt    *time.Time
+}
+
+type Reminder struct {
+	Id            uuid.UUID
+	AppointmentId uuid.UUID
+	HoursBefore   int
+	Channel       string
+	StartTime     time.Time
+	Status        string
+	Recipient     string
+	Error         string
+	CreatedAt     time.Time
+	SentAt        *time.Time
+}
+
+// ReminderDelivery is a reminder with what sending it needs to know about
+// the appointment and the patient. HasAccount is false for guest bookings,
+// which only have the email they booked with.
+type ReminderDelivery struct {
+	Reminder
+
+	AppointmentStatus string
+	AppointmentStart  time.Time
+	ConfirmedAt       *time.Time
+	PatientName       string
+	Email             string
+
+	HasAccount   bool
+	PushConsent  bool
+	EmailEnabled bool
+	SmsEnabled   bool
+	Phone        string
+
+	ClinicName  string
+	Address     string
+	ServiceName string
+	DoctorName  string
+}
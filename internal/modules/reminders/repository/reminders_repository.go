@@ -0,0 +1,316 @@
+package repository
+
+import (
+	"context"
+
+	"dental_clinic/internal/modules/reminders/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ReminderRepository interface {
+	IsClinicAdmin(clinicId, userId uuid.UUID) (bool, error)
+	ClinicExists(clinicId uuid.UUID) (bool, error)
+	GetAppointmentClinic(appointmentId uuid.UUID) (uuid.UUID, error)
+
+	GetSettings(clinicId uuid.UUID) (*models.ReminderSettings, error)
+	SaveSettingsTx(settings *models.ReminderSettings, tx pgx.Tx) error
+	ReplaceRulesTx(clinicId uuid.UUID, rules []models.ReminderRule, tx pgx.Tx) error
+
+	GetPreferences(userId uuid.UUID) (*models.ReminderPreferences, error)
+	SavePreferences(prefs *models.ReminderPreferences) error
+
+	CreateDueRemindersTx(defaultHours int, defaultChannel string, tx pgx.Tx) ([]uuid.UUID, error)
+	GetDelivery(id uuid.UUID) (*models.ReminderDelivery, error)
+	SetStatus(id uuid.UUID, status, recipient, message string) error
+	GetReminders(appointmentId uuid.UUID) ([]models.Reminder, error)
+}
+
+type reminderRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewReminderRepository(db *pgxpool.Pool) ReminderRepository {
+	return &reminderRepo{db: db}
+}
+
+func (r *reminderRepo) IsClinicAdmin(clinicId, userId uuid.UUID) (bool, error) {
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM clinic_admins WHERE clinic_id = $1 AND user_id = $2)`
+	err := r.db.QueryRow(context.Background(), query, clinicId, userId).Scan(&exists)
+	return exists, err
+}
+
+func (r *reminderRepo) ClinicExists(clinicId uuid.UUID) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(context.Background(), `SELECT EXISTS (SELECT 1 FROM clinics WHERE id = $1)`, clinicId).Scan(&exists)
+	return exists, err
+}
+
+// GetAppointmentClinic returns the clinic the appointment is at, or uuid.Nil
+// when there is no such appointment.
+func (r *reminderRepo) GetAppointmentClinic(appointmentId uuid.UUID) (uuid.UUID, error) {
+	query := `
+		SELECT ca.clinic_id
+		FROM appointments a
+		JOIN clinic_addresses ca ON ca.id = a.clinic_address_id
+		WHERE a.id = $1
+	`
+	var clinicId uuid.UUID
+	err := r.db.QueryRow(context.Background(), query, appointmentId).Scan(&clinicId)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return uuid.Nil, nil
+		}
+		return uuid.Nil, err
+	}
+	return clinicId, nil
+}
+
+// GetSettings returns nil when the clinic has not saved reminder settings.
+func (r *reminderRepo) GetSettings(clinicId uuid.UUID) (*models.ReminderSettings, error) {
+	settings := &models.ReminderSettings{ClinicId: clinicId}
+	query := `SELECT enabled, updated_at FROM clinic_reminder_settings WHERE clinic_id = $1`
+	err := r.db.QueryRow(context.Background(), query, clinicId).Scan(&settings.Enabled, &settings.UpdatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	rulesQuery := `
+		SELECT hours_before, channels
+		FROM clinic_reminder_rules
+		WHERE clinic_id = $1
+		ORDER BY hours_before DESC
+	`
+	rows, err := r.db.Query(context.Background(), rulesQuery, clinicId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	settings.Rules = make([]models.ReminderRule, 0)
+	for rows.Next() {
+		var rule models.ReminderRule
+		if err := rows.Scan(&rule.HoursBefore, &rule.Channels); err != nil {
+			return nil, err
+		}
+		settings.Rules = append(settings.Rules, rule)
+	}
+	return settings, rows.Err()
+}
+
+func (r *reminderRepo) SaveSettingsTx(settings *models.ReminderSettings, tx pgx.Tx) error {
+	query := `
+		INSERT INTO clinic_reminder_settings (clinic_id, enabled, updated_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (clinic_id) DO UPDATE
+		SET enabled = EXCLUDED.enabled, updated_at = NOW()
+		RETURNING updated_at
+	`
+	return tx.QueryRow(context.Background(), query, settings.ClinicId, settings.Enabled).Scan(&settings.UpdatedAt)
+}
+
+func (r *reminderRepo) ReplaceRulesTx(clinicId uuid.UUID, rules []models.ReminderRule, tx pgx.Tx) error {
+	if _, err := tx.Exec(context.Background(), `DELETE FROM clinic_reminder_rules WHERE clinic_id = $1`, clinicId); err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		query := `INSERT INTO clinic_reminder_rules (clinic_id, hours_before, channels) VALUES ($1, $2, $3)`
+		if _, err := tx.Exec(context.Background(), query, clinicId, rule.HoursBefore, rule.Channels); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetPreferences returns the user's reminder preferences, the defaults when
+// none are saved, or nil when there is no such user.
+func (r *reminderRepo) GetPreferences(userId uuid.UUID) (*models.ReminderPreferences, error) {
+	query := `
+		SELECT
+			COALESCE(u.push_consent, FALSE),
+			COALESCE(p.email_enabled, TRUE),
+			COALESCE(p.sms_enabled, FALSE),
+			COALESCE(p.phone, ''),
+			p.updated_at
+		FROM users u
+		LEFT JOIN user_reminder_preferences p ON p.user_id = u.id
+		WHERE u.id = $1
+	`
+	prefs := &models.ReminderPreferences{UserId: userId}
+	err := r.db.QueryRow(context.Background(), query, userId).Scan(
+		&prefs.PushConsent, &prefs.EmailEnabled, &prefs.SmsEnabled, &prefs.Phone, &prefs.UpdatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return prefs, nil
+}
+
+func (r *reminderRepo) SavePreferences(prefs *models.ReminderPreferences) error {
+	query := `
+		INSERT INTO user_reminder_preferences (user_id, email_enabled, sms_enabled, phone, updated_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (user_id) DO UPDATE
+		SET email_enabled = EXCLUDED.email_enabled,
+			sms_enabled = EXCLUDED.sms_enabled,
+			phone = EXCLUDED.phone,
+			updated_at = NOW()
+		RETURNING updated_at
+	`
+	return r.db.QueryRow(context.Background(), query, prefs.UserId, prefs.EmailEnabled, prefs.SmsEnabled, prefs.Phone).Scan(&prefs.UpdatedAt)
+}
+
+// CreateDueRemindersTx adds a row for every reminder that is due now and
+// returns their ids. A reminder is due once its time has come and until the
+// appointment starts; one whose time had already passed when the
+// appointment was booked is not sent. Clinics without settings get a
+// reminder defaultHours before over defaultChannel. Reminders already
+// added are left alone.
+func (r *reminderRepo) CreateDueRemindersTx(defaultHours int, defaultChannel string, tx pgx.Tx) ([]uuid.UUID, error) {
+	query := `
+		WITH rules AS (
+			SELECT ca.id AS clinic_address_id, rr.hours_before, unnest(rr.channels) AS channel
+			FROM clinic_reminder_rules rr
+			JOIN clinic_reminder_settings rs ON rs.clinic_id = rr.clinic_id AND rs.enabled
+			JOIN clinic_addresses ca ON ca.clinic_id = rr.clinic_id
+			UNION ALL
+			SELECT ca.id, $1::int, $2::varchar
+			FROM clinic_addresses ca
+			WHERE NOT EXISTS (SELECT 1 FROM clinic_reminder_settings rs WHERE rs.clinic_id = ca.clinic_id)
+		)
+		INSERT INTO appointment_reminders (appointment_id, hours_before, channel, start_time)
+		SELECT a.id, rules.hours_before, rules.channel, a.start_time
+		FROM appointments a
+		JOIN rules ON rules.clinic_address_id = a.clinic_address_id
+		WHERE a.status = 'booked'
+			AND a.start_time > NOW()
+			AND a.start_time - make_interval(hours => rules.hours_before) <= NOW()
+			AND a.created_at < a.start_time - make_interval(hours => rules.hours_before)
+		ON CONFLICT (appointment_id, hours_before, channel, start_time) DO NOTHING
+		RETURNING id
+	`
+	rows, err := tx.Query(context.Background(), query, defaultHours, defaultChannel)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]uuid.UUID, 0)
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// GetDelivery returns nil when the reminder is gone, as it is with its
+// appointment.
+func (r *reminderRepo) GetDelivery(id uuid.UUID) (*models.ReminderDelivery, error) {
+	query := `
+		SELECT
+			ar.id,
+			ar.appointment_id,
+			ar.hours_before,
+			ar.channel,
+			ar.start_time,
+			ar.status,
+			a.status,
+			a.start_time,
+			a.confirmed_at,
+			COALESCE(a.name, ''),
+			COALESCE(a.email, ''),
+			u.id IS NOT NULL,
+			COALESCE(u.push_consent, FALSE),
+			COALESCE(p.email_enabled, TRUE),
+			COALESCE(p.sms_enabled, FALSE),
+			COALESCE(p.phone, ''),
+			COALESCE(c.name, ''),
+			concat_ws(', ', ad.street, ad.building, ad.city),
+			COALESCE(s.name, ''),
+			COALESCE(d.name, '')
+		FROM appointment_reminders ar
+		JOIN appointments a ON a.id = ar.appointment_id
+		LEFT JOIN users u ON u.id = a.user_id
+		LEFT JOIN user_reminder_preferences p ON p.user_id = a.user_id
+		LEFT JOIN clinic_addresses ca ON ca.id = a.clinic_address_id
+		LEFT JOIN clinics c ON c.id = ca.clinic_id
+		LEFT JOIN addresses ad ON ad.id = ca.address_id
+		LEFT JOIN services s ON s.id = a.service_id
+		LEFT JOIN doctors d ON d.id = a.doctor_id
+		WHERE ar.id = $1
+	`
+	var delivery models.ReminderDelivery
+	err := r.db.QueryRow(context.Background(), query, id).Scan(
+		&delivery.Id, &delivery.AppointmentId, &delivery.HoursBefore, &delivery.Channel, &delivery.StartTime, &delivery.Status,
+		&delivery.AppointmentStatus, &delivery.AppointmentStart, &delivery.ConfirmedAt, &delivery.PatientName, &delivery.Email,
+		&delivery.HasAccount, &delivery.PushConsent, &delivery.EmailEnabled, &delivery.SmsEnabled, &delivery.Phone,
+		&delivery.ClinicName, &delivery.Address, &delivery.ServiceName, &delivery.DoctorName,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// SetStatus records how sending the reminder went. message is the error or
+// the reason it was skipped.
+func (r *reminderRepo) SetStatus(id uuid.UUID, status, recipient, message string) error {
+	query := `
+		UPDATE appointment_reminders
+		SET status = $2,
+			recipient = $3,
+			error = $4,
+			sent_at = CASE WHEN $2 = 'sent' THEN NOW() END
+		WHERE id = $1
+	`
+	result, err := r.db.Exec(context.Background(), query, id, status, recipient, message)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *reminderRepo) GetReminders(appointmentId uuid.UUID) ([]models.Reminder, error) {
+	query := `
+		SELECT id, appointment_id, hours_before, channel, start_time, status, recipient, error, created_at, sent_at
+		FROM appointment_reminders
+		WHERE appointment_id = $1
+		ORDER BY created_at DESC, channel
+	`
+	rows, err := r.db.Query(context.Background(), query, appointmentId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reminders := make([]models.Reminder, 0)
+	for rows.Next() {
+		var reminder models.Reminder
+		if err := rows.Scan(
+			&reminder.Id, &reminder.AppointmentId, &reminder.HoursBefore, &reminder.Channel, &reminder.StartTime,
+			&reminder.Status, &reminder.Recipient, &reminder.Error, &reminder.CreatedAt, &reminder.SentAt,
+		); err != nil {
+			return nil, err
+		}
+		reminders = append(reminders, reminder)
+	}
+	return reminders, rows.Err()
+}
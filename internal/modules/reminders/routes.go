@@ -0,0 +1,24 @@
+package reminders
+
+import (
+	"dental_clinic/internal/config"
+	"dental_clinic/internal/modules/reminders/handlers"
+	"dental_clinic/internal/modules/reminders/repository"
+	"dental_clinic/internal/modules/reminders/services"
+	"dental_clinic/internal/notify"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func RegisterPrivateRoutes(r *mux.Router, db *pgxpool.Pool, cfg *config.Config) {
+	repo := repository.NewReminderRepository(db)
+	service := services.NewReminderService(repo, db, *cfg, notify.NewProviders(cfg))
+	handler := handlers.NewReminderHandler(service, *cfg)
+
+	r.HandleFunc("/clinics/{clinicId}/reminder-settings", handler.GetReminderSettings).Methods("GET")
+	r.HandleFunc("/clinics/{clinicId}/reminder-settings", handler.UpdateReminderSettings).Methods("PUT")
+	r.HandleFunc("/users/me/reminder-preferences", handler.GetReminderPreferences).Methods("GET")
+	r.HandleFunc("/users/me/reminder-preferences", handler.UpdateReminderPreferences).Methods("PUT")
+	r.HandleFunc("/appointments/{appointmentId}/reminders", handler.GetAppointmentReminders).Methods("GET")
+}
@@ -0,0 +1,464 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"regexp"
+	"sort"
+	"time"
+
+	"dental_clinic/internal/config"
+	"dental_clinic/internal/modules/reminders/dto"
+	"dental_clinic/internal/modules/reminders/models"
+	"dental_clinic/internal/modules/reminders/repository"
+	"dental_clinic/internal/notify"
+	"dental_clinic/internal/queue"
+	"dental_clinic/internal/utils"
+
+	appointmentServices "dental_clinic/internal/modules/appointment/services"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// DispatchRemindersJob looks for reminders that are due and queues a
+	// SendReminderJob for each
+	DispatchRemindersJob = "reminders.dispatch"
+	SendReminderJob      = "reminders.send"
+
+	// a clinic without settings reminds by email a day before
+	defaultReminderHours = 24
+
+	maxReminderHours = 336
+	maxReminderRules = 5
+)
+
+type SendReminderPayload struct {
+	ReminderId uuid.UUID `json:"reminder_id"`
+}
+
+var phonePattern = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
+type ReminderService struct {
+	repo      repository.ReminderRepository
+	db        *pgxpool.Pool
+	cfg       config.Config
+	queue     *queue.Queue
+	providers map[string]notify.Provider
+}
+
+func NewReminderService(repo repository.ReminderRepository, db *pgxpool.Pool, cfg config.Config, providers map[string]notify.Provider) *ReminderService {
+	return &ReminderService{
+		repo:      repo,
+		db:        db,
+		cfg:       cfg,
+		queue:     queue.New(db),
+		providers: providers,
+	}
+}
+
+// GetReminderSettings returns the clinic's reminder schedule, or the
+// default one when the clinic has not saved its own.
+func (s *ReminderService) GetReminderSettings(clinicId, userId, role string) (*models.ReminderSettings, error) {
+	clinicUUID, err := s.manageableClinic(clinicId, userId, role)
+	if err != nil {
+		return nil, err
+	}
+	settings, err := s.repo.GetSettings(clinicUUID)
+	if err != nil {
+		return nil, err
+	}
+	if settings == nil {
+		return &models.ReminderSettings{
+			ClinicId:  clinicUUID,
+			Enabled:   true,
+			Rules:     []models.ReminderRule{{HoursBefore: defaultReminderHours, Channels: []string{notify.ChannelEmail}}},
+			IsDefault: true,
+		}, nil
+	}
+	return settings, nil
+}
+
+// UpdateReminderSettings saves the clinic's reminder schedule. New rules
+// apply to reminders that become due from now on.
+func (s *ReminderService) UpdateReminderSettings(ctx context.Context, clinicId, userId, role string, req dto.ReminderSettingsRequest) (*models.ReminderSettings, error) {
+	settings, err := s.GetReminderSettings(clinicId, userId, role)
+	if err != nil {
+		return nil, err
+	}
+	if req.Enabled != nil {
+		settings.Enabled = *req.Enabled
+	}
+	if req.Rules != nil {
+		rules, err := reminderRules(req.Rules)
+		if err != nil {
+			return nil, err
+		}
+		settings.Rules = rules
+	}
+
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := s.repo.SaveSettingsTx(settings, tx); err != nil {
+		return nil, err
+	}
+	if err := s.repo.ReplaceRulesTx(settings.ClinicId, settings.Rules, tx); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	settings.IsDefault = false
+	return settings, nil
+}
+
+func reminderRules(requested []dto.ReminderRuleRequest) ([]models.ReminderRule, error) {
+	if len(requested) > maxReminderRules {
+		return nil, fmt.Errorf("at most %d reminders are allowed", maxReminderRules)
+	}
+	rules := make([]models.ReminderRule, 0, len(requested))
+	seenHours := make(map[int]bool)
+	for _, r := range requested {
+		if r.HoursBefore < 1 || r.HoursBefore > maxReminderHours {
+			return nil, fmt.Errorf("hours_before must be between 1 and %d", maxReminderHours)
+		}
+		if seenHours[r.HoursBefore] {
+			return nil, fmt.Errorf("more than one reminder %d hours before", r.HoursBefore)
+		}
+		seenHours[r.HoursBefore] = true
+
+		channels := make([]string, 0, len(r.Channels))
+		seenChannels := make(map[string]bool)
+		for _, channel := range r.Channels {
+			if !notify.IsChannel(channel) {
+				return nil, fmt.Errorf("invalid channel %q, use email or sms", channel)
+			}
+			if !seenChannels[channel] {
+				seenChannels[channel] = true
+				channels = append(channels, channel)
+			}
+		}
+		if len(channels) == 0 {
+			return nil, errors.New("every reminder needs at least one channel")
+		}
+		rules = append(rules, models.ReminderRule{HoursBefore: r.HoursBefore, Channels: channels})
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].HoursBefore > rules[j].HoursBefore })
+	return rules, nil
+}
+
+func (s *ReminderService) GetReminderPreferences(userId string) (*models.ReminderPreferences, error) {
+	userUUID, err := uuid.Parse(userId)
+	if err != nil {
+		return nil, errors.New("invalid user id")
+	}
+	prefs, err := s.repo.GetPreferences(userUUID)
+	if err != nil {
+		return nil, err
+	}
+	if prefs == nil {
+		return nil, errors.New("user not found")
+	}
+	return prefs, nil
+}
+
+func (s *ReminderService) UpdateReminderPreferences(userId string, req dto.ReminderPreferencesRequest) (*models.ReminderPreferences, error) {
+	prefs, err := s.GetReminderPreferences(userId)
+	if err != nil {
+		return nil, err
+	}
+	if req.EmailEnabled != nil {
+		prefs.EmailEnabled = *req.EmailEnabled
+	}
+	if req.SmsEnabled != nil {
+		prefs.SmsEnabled = *req.SmsEnabled
+	}
+	if req.Phone != nil {
+		prefs.Phone = *req.Phone
+	}
+	if prefs.Phone != "" && !phonePattern.MatchString(prefs.Phone) {
+		return nil, errors.New("invalid phone, use the international format, as +4915112345678")
+	}
+	if prefs.SmsEnabled && prefs.Phone == "" {
+		return nil, errors.New("a phone number is needed for sms reminders")
+	}
+
+	if err := s.repo.SavePreferences(prefs); err != nil {
+		return nil, err
+	}
+	return prefs, nil
+}
+
+// GetAppointmentReminders lists the reminders of an appointment and how
+// sending them went.
+func (s *ReminderService) GetAppointmentReminders(appointmentId, userId, role string) ([]models.Reminder, error) {
+	appointmentUUID, err := uuid.Parse(appointmentId)
+	if err != nil {
+		return nil, errors.New("invalid appointment id")
+	}
+	clinicId, err := s.repo.GetAppointmentClinic(appointmentUUID)
+	if err != nil {
+		return nil, err
+	}
+	if clinicId == uuid.Nil {
+		return nil, errors.New("appointment not found")
+	}
+	if err := s.canManageClinic(clinicId, userId, role); err != nil {
+		return nil, err
+	}
+	return s.repo.GetReminders(appointmentUUID)
+}
+
+// DispatchDueReminders queues every reminder that is due. The reminders are
+// recorded in the same transaction as their jobs, so each one is sent once
+// however often this runs.
+func (s *ReminderService) DispatchDueReminders(ctx context.Context) error {
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	ids, err := s.repo.CreateDueRemindersTx(defaultReminderHours, notify.ChannelEmail, tx)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		_, err := s.queue.EnqueueTx(ctx, tx, queue.Job{
+			Type:      SendReminderJob,
+			Payload:   SendReminderPayload{ReminderId: id},
+			Priority:  queue.PriorityHigh,
+			UniqueKey: "reminder:" + id.String(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	if len(ids) > 0 {
+		log.Printf("reminders: queued %d reminders", len(ids))
+	}
+	return nil
+}
+
+// SendReminder delivers a queued reminder. It is skipped when the
+// appointment was cancelled or moved since, or when the patient does not
+// want reminders over its channel. A failed send is retried by the queue
+// and marked failed once it runs out of attempts.
+func (s *ReminderService) SendReminder(ctx context.Context, id uuid.UUID) error {
+	delivery, err := s.repo.GetDelivery(id)
+	if err != nil {
+		return err
+	}
+	if delivery == nil || delivery.Status != "queued" {
+		return nil
+	}
+
+	if delivery.AppointmentStatus != "booked" || !delivery.AppointmentStart.Equal(delivery.StartTime) || !delivery.StartTime.After(time.Now()) {
+		return s.repo.SetStatus(id, "skipped", "", "appointment is no longer booked for this time")
+	}
+	recipient, reason := recipientFor(delivery)
+	if reason != "" {
+		return s.repo.SetStatus(id, "skipped", "", reason)
+	}
+	provider, ok := s.providers[delivery.Channel]
+	if !ok {
+		return s.repo.SetStatus(id, "skipped", "", "channel is not configured")
+	}
+
+	msg, err := s.reminderMessage(delivery)
+	if err != nil {
+		return err
+	}
+	msg.To = recipient
+	if err := provider.Send(ctx, msg); err != nil {
+		if queue.IsLastAttempt(ctx) {
+			_ = s.repo.SetStatus(id, "failed", recipient, err.Error())
+		}
+		return err
+	}
+	return s.repo.SetStatus(id, "sent", recipient, "")
+}
+
+// recipientFor returns where to send the reminder, or why it is not sent.
+// Patients with an account need to have given push consent and to want
+// reminders over the channel; guests only get email.
+func recipientFor(delivery *models.ReminderDelivery) (string, string) {
+	if delivery.HasAccount && !delivery.PushConsent {
+		return "", "patient has not consented to reminders"
+	}
+	switch delivery.Channel {
+	case notify.ChannelEmail:
+		if delivery.HasAccount && !delivery.EmailEnabled {
+			return "", "patient turned off email reminders"
+		}
+		if delivery.Email == "" {
+			return "", "no email address"
+		}
+		return delivery.Email, ""
+	case notify.ChannelSMS:
+		if !delivery.HasAccount || !delivery.SmsEnabled {
+			return "", "patient has not turned on sms reminders"
+		}
+		if delivery.Phone == "" {
+			return "", "no phone number"
+		}
+		return delivery.Phone, ""
+	default:
+		return "", "unknown channel"
+	}
+}
+
+func (s *ReminderService) reminderMessage(delivery *models.ReminderDelivery) (notify.Message, error) {
+	cancelLink, err := s.actionLink(delivery, appointmentServices.ActionCancel)
+	if err != nil {
+		return notify.Message{}, err
+	}
+	confirmLink := ""
+	if delivery.ConfirmedAt == nil {
+		if confirmLink, err = s.actionLink(delivery, appointmentServices.ActionConfirm); err != nil {
+			return notify.Message{}, err
+		}
+	}
+
+	when := delivery.StartTime.Format("Mon, 02 Jan 2006 at 15:04")
+	subject := "Reminder: your appointment on " + when
+	what := delivery.ServiceName
+	if delivery.DoctorName != "" {
+		what += " with " + delivery.DoctorName
+	}
+	where := delivery.ClinicName
+	if delivery.Address != "" {
+		where += ", " + delivery.Address
+	}
+
+	text := fmt.Sprintf("Dental Clinic: %s at %s on %s.", what, where, when)
+	if confirmLink != "" {
+		text += " Confirm: " + confirmLink
+	}
+	text += " Cancel: " + cancelLink
+
+	links := fmt.Sprintf(`<a href="%s">Cancel appointment</a>`, html.EscapeString(cancelLink))
+	if confirmLink != "" {
+		links = fmt.Sprintf(`<a href="%s">Confirm</a> | `, html.EscapeString(confirmLink)) + links
+	}
+	body := fmt.Sprintf(`
+			<h2>Dental Clinic</h2>
+			<p>Hello, %s!</p>
+			<p>This is a reminder of your appointment for %s at %s on %s.</p>
+			<p>%s</p>
+		`, html.EscapeString(delivery.PatientName), html.EscapeString(what), html.EscapeString(where), when, links)
+
+	return notify.Message{Subject: subject, Text: text, HTML: body}, nil
+}
+
+func (s *ReminderService) actionLink(delivery *models.ReminderDelivery, action string) (string, error) {
+	token, err := utils.GenerateAppointmentActionToken(delivery.AppointmentId.String(), action, delivery.StartTime, s.cfg.JWTSecret)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s/appointment-response?token=%s", s.cfg.FrontendURL, token), nil
+}
+
+func (s *ReminderService) manageableClinic(clinicId, userId, role string) (uuid.UUID, error) {
+	clinicUUID, err := uuid.Parse(clinicId)
+	if err != nil {
+		return uuid.Nil, errors.New("invalid clinic id")
+	}
+	if err := s.canManageClinic(clinicUUID, userId, role); err != nil {
+		return uuid.Nil, err
+	}
+	exists, err := s.repo.ClinicExists(clinicUUID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if !exists {
+		return uuid.Nil, errors.New("clinic not found")
+	}
+	return clinicUUID, nil
+}
+
+// canManageClinic allows platform admins and the admins of the clinic.
+func (s *ReminderService) canManageClinic(clinicId uuid.UUID, userId, role string) error {
+	switch role {
+	case "admin":
+		return nil
+	case "clinic_admin":
+		userUUID, err := uuid.Parse(userId)
+		if err != nil {
+			return errors.New("invalid user id")
+		}
+		isAdmin, err := s.repo.IsClinicAdmin(clinicId, userUUID)
+		if err != nil {
+			return err
+		}
+		if !isAdmin {
+			return errors.New("do not have rights")
+		}
+		return nil
+	default:
+		return errors.New("do not have rights")
+	}
+}
+
+func ToReminderSettingsResponse(settings models.ReminderSettings) dto.ReminderSettingsResponse {
+	rules := make([]dto.ReminderRuleResponse, 0, len(settings.Rules))
+	for _, rule := range settings.Rules {
+		rules = append(rules, dto.ReminderRuleResponse{HoursBefore: rule.HoursBefore, Channels: rule.Channels})
+	}
+	response := dto.ReminderSettingsResponse{
+		ClinicId:  settings.ClinicId.String(),
+		Enabled:   settings.Enabled,
+		Rules:     rules,
+		IsDefault: settings.IsDefault,
+	}
+	if !settings.UpdatedAt.IsZero() {
+		response.UpdatedAt = settings.UpdatedAt.Format(time.RFC3339)
+	}
+	return response
+}
+
+func ToReminderPreferencesResponse(prefs models.ReminderPreferences) dto.ReminderPreferencesResponse {
+	response := dto.ReminderPreferencesResponse{
+		PushConsent:  prefs.PushConsent,
+		EmailEnabled: prefs.EmailEnabled,
+		SmsEnabled:   prefs.SmsEnabled,
+		Phone:        prefs.Phone,
+	}
+	if prefs.UpdatedAt != nil {
+		response.UpdatedAt = prefs.UpdatedAt.Format(time.RFC3339)
+	}
+	return response
+}
+
+func ToReminderResponseList(reminders []models.Reminder) []dto.ReminderResponse {
+	result := make([]dto.ReminderResponse, 0, len(reminders))
+	for _, reminder := range reminders {
+		response := dto.ReminderResponse{
+			Id:          reminder.Id.String(),
+			HoursBefore: reminder.HoursBefore,
+			Channel:     reminder.Channel,
+			StartTime:   reminder.StartTime.Format(time.RFC3339),
+			Status:      reminder.Status,
+			Recipient:   reminder.Recipient,
+			Error:       reminder.Error,
+			CreatedAt:   reminder.CreatedAt.Format(time.RFC3339),
+		}
+		if reminder.SentAt != nil {
+			response.SentAt = reminder.SentAt.Format(time.RFC3339)
+		}
+		result = append(result, response)
+	}
+	return result
+}
@@ -7,3 +7,31 @@ type ReportResponse struct {
 	To              string      `json:"to,omitempty"`
 	Data            interface{} `json:"data"`
 }
+
+// ReportExportRequest asks for a report as a file. Report is the name the
+// report's route uses, like revenue or inventory-valuation; method and by
+// apply to the inventory valuation and margin reports.
+type ReportExportRequest struct {
+	Report          string `json:"report"`
+	Format          string `json:"format"`
+	From            string `json:"from"`
+	To              string `json:"to"`
+	ClinicAddressID string `json:"clinic_address_id"`
+	Method          string `json:"method"`
+	By              string `json:"by"`
+}
+
+type ReportExportResponse struct {
+	Id              string `json:"id"`
+	Report          string `json:"report"`
+	Format          string `json:"format"`
+	ClinicAddressID string `json:"clinic_address_id,omitempty"`
+	From            string `json:"from"`
+	To              string `json:"to"`
+	Method          string `json:"method,omitempty"`
+	By              string `json:"by,omitempty"`
+	Status          string `json:"status"`
+	Error           string `json:"error,omitempty"`
+	CreatedAt       string `json:"created_at"`
+	FinishedAt      string `json:"finished_at,omitempty"`
+}
@@ -3,6 +3,8 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
+	"strings"
+	"time"
 
 	"dental_clinic/internal/modules/reports/dto"
 	"dental_clinic/internal/modules/reports/models"
@@ -21,7 +23,7 @@ func NewReportsHandler(service *services.ReportsService) *ReportsHandler {
 
 // GetRevenueReport godoc
 // @Summary Get clinic revenue report
-// @Description Returns clinic revenue report. Use format=csv or format=pdf to export.
+// @Description Returns clinic revenue per service from the prices charged at booking, after price rules and discounts. Cancelled appointments are left out. Use format=csv or format=pdf to export.
 // @Tags Reports
 // @Security BearerAuth
 // @Produce json
@@ -102,7 +104,7 @@ func (h *ReportsHandler) GetDoctorPerformanceReport(w http.ResponseWriter, r *ht
 
 // GetInventoryReport godoc
 // @Summary Get clinic inventory report
-// @Description Returns current stock and transaction quantities by product. Use format=csv or format=pdf to export.
+// @Description Returns current stock and transaction quantities by product, with the variances posted by stock counts and their reasons. Use format=csv or format=pdf to export.
 // @Tags Reports
 // @Security BearerAuth
 // @Produce json
@@ -127,6 +129,167 @@ func (h *ReportsHandler) GetInventoryReport(w http.ResponseWriter, r *http.Reque
 	h.respondReport(w, r, "Inventory Report", filters, data)
 }
 
+// GetReviewDimensionReport godoc
+// @Summary Get clinic review dimensions report
+// @Description Returns average scores per review aspect (cleanliness, wait time, pain management, communication), broken down per doctor for doctor aspects. Use format=csv or format=pdf to export.
+// @Tags Reports
+// @Security BearerAuth
+// @Produce json
+// @Param clinicId path string true "Clinic ID"
+// @Param from query string true "Start date YYYY-MM-DD"
+// @Param to query string true "End date YYYY-MM-DD"
+// @Param clinic_address_id query string false "Clinic address ID"
+// @Param format query string false "Export format: csv or pdf"
+// @Success 200 {object} dto.ReportResponse
+// @Failure 400 {object} map[string]string
+// @Router /api/clinics/{clinicId}/reports/reviews [get]
+func (h *ReportsHandler) GetReviewDimensionReport(w http.ResponseWriter, r *http.Request) {
+	filters, ok := h.filters(w, r)
+	if !ok {
+		return
+	}
+	data, err := h.service.ReviewDimensions(filters)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.respondReport(w, r, "Review Dimensions Report", filters, data)
+}
+
+// GetValuationReport godoc
+// @Summary Get clinic inventory valuation report
+// @Description Values the stock on hand now per address and product, at the average cost it was received at (weighted_average) or at the cost of the lots it is in (fifo). The clinic's valuation method is used unless method is given. Stock without a known cost is counted in unvalued_quantity. Use format=csv or format=pdf to export.
+// @Tags Reports
+// @Security BearerAuth
+// @Produce json
+// @Param clinicId path string true "Clinic ID"
+// @Param clinic_address_id query string false "Clinic address ID"
+// @Param method query string false "Valuation method: weighted_average or fifo"
+// @Param format query string false "Export format: csv or pdf"
+// @Success 200 {object} dto.ReportResponse
+// @Failure 400 {object} map[string]string
+// @Router /api/clinics/{clinicId}/reports/inventory-valuation [get]
+func (h *ReportsHandler) GetValuationReport(w http.ResponseWriter, r *http.Request) {
+	today := time.Now().Format("2006-01-02")
+	filters, err := h.service.BuildFilters(mux.Vars(r)["clinicId"], r.URL.Query().Get("clinic_address_id"), today, today)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	data, err := h.service.Valuation(filters, r.URL.Query().Get("method"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	h.respondReport(w, r, "Inventory Valuation Report", filters, data)
+}
+
+// GetMarginReport godoc
+// @Summary Get clinic margin report
+// @Description Returns the revenue of completed appointments less the cost of the materials they used, per service or per doctor. Materials used without a cost are costed at the average cost of the product at the address. Use format=csv or format=pdf to export.
+// @Tags Reports
+// @Security BearerAuth
+// @Produce json
+// @Param clinicId path string true "Clinic ID"
+// @Param from query string true "Start date YYYY-MM-DD"
+// @Param to query string true "End date YYYY-MM-DD"
+// @Param clinic_address_id query string false "Clinic address ID"
+// @Param by query string false "Group by: service (default) or doctor"
+// @Param format query string false "Export format: csv or pdf"
+// @Success 200 {object} dto.ReportResponse
+// @Failure 400 {object} map[string]string
+// @Router /api/clinics/{clinicId}/reports/margins [get]
+func (h *ReportsHandler) GetMarginReport(w http.ResponseWriter, r *http.Request) {
+	filters, ok := h.filters(w, r)
+	if !ok {
+		return
+	}
+	data, err := h.service.Margins(filters, r.URL.Query().Get("by"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	h.respondReport(w, r, "Margin Report", filters, data)
+}
+
+// CreateReportExport godoc
+// @Summary Export a report in the background
+// @Description Queues the rendering of a report to CSV or PDF and returns the export right away. Poll the export until its status is ready, then download it. report is the name the report's route uses: revenue, appointments, doctors, inventory, reviews, inventory-valuation or margins. The inventory valuation is of today's stock and ignores from and to.
+// @Tags Reports
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param clinicId path string true "Clinic ID"
+// @Param request body dto.ReportExportRequest true "Report export"
+// @Success 202 {object} dto.ReportExportResponse
+// @Failure 400 {object} map[string]string
+// @Router /api/clinics/{clinicId}/reports/exports [post]
+func (h *ReportsHandler) CreateReportExport(w http.ResponseWriter, r *http.Request) {
+	var req dto.ReportExportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	export, err := h.service.QueueExport(r.Context(), mux.Vars(r)["clinicId"], req)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusAccepted, services.ToReportExportResponse(*export))
+}
+
+// GetReportExport godoc
+// @Summary Get report export
+// @Description Returns an export with its status: queued, running, ready or failed
+// @Tags Reports
+// @Security BearerAuth
+// @Produce json
+// @Param clinicId path string true "Clinic ID"
+// @Param id path string true "Export ID"
+// @Success 200 {object} dto.ReportExportResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/clinics/{clinicId}/reports/exports/{id} [get]
+func (h *ReportsHandler) GetReportExport(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	export, err := h.service.GetExport(vars["clinicId"], vars["id"])
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, services.ToReportExportResponse(*export))
+}
+
+// DownloadReportExport godoc
+// @Summary Download report export
+// @Description Returns the file of a ready export
+// @Tags Reports
+// @Security BearerAuth
+// @Produce octet-stream
+// @Param clinicId path string true "Clinic ID"
+// @Param id path string true "Export ID"
+// @Success 200 {file} file
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/clinics/{clinicId}/reports/exports/{id}/download [get]
+func (h *ReportsHandler) DownloadReportExport(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	export, content, err := h.service.GetExportFile(vars["clinicId"], vars["id"])
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	contentType := "text/csv"
+	if export.Format == "pdf" {
+		contentType = "application/pdf"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+services.ExportFilename(*export)+`"`)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(content)
+}
+
 func (h *ReportsHandler) filters(w http.ResponseWriter, r *http.Request) (models.ReportFilters, bool) {
 	vars := mux.Vars(r)
 	query := r.URL.Query()
@@ -175,6 +338,17 @@ func (h *ReportsHandler) respondReport(w http.ResponseWriter, r *http.Request, t
 	}
 }
 
+func errorStatus(err error) int {
+	switch {
+	case strings.HasSuffix(err.Error(), "not found"):
+		return http.StatusNotFound
+	case strings.HasSuffix(err.Error(), "not ready"):
+		return http.StatusConflict
+	default:
+		return http.StatusBadRequest
+	}
+}
+
 func respondJSON(w http.ResponseWriter, status int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
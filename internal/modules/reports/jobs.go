@@ -0,0 +1,21 @@
+package reports
+
+import (
+	"context"
+
+	"dental_clinic/internal/modules/reports/repository"
+	"dental_clinic/internal/modules/reports/services"
+	"dental_clinic/internal/queue"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// RegisterJobs lets the worker render queued report exports.
+func RegisterJobs(w *queue.Worker, db *pgxpool.Pool) {
+	repo := repository.NewReportsRepository(db)
+	service := services.NewReportsService(repo, queue.New(db))
+
+	queue.Handle(w, services.ExportReportJob, func(ctx context.Context, payload services.ExportReportPayload) error {
+		return service.RunExport(ctx, payload.ExportId)
+	})
+}
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type ReportFilters struct {
 	ClinicID        string
 	ClinicAddressID string
@@ -7,11 +9,27 @@ type ReportFilters struct {
 	To              string
 }
 
+// ReportExport is a report rendered to CSV or PDF by the background
+// workers. Status is "queued", "running", "ready" or "failed".
+type ReportExport struct {
+	Id         string
+	Report     string
+	Format     string
+	Filters    ReportFilters
+	Method     string
+	GroupBy    string
+	Status     string
+	Error      string
+	CreatedAt  time.Time
+	FinishedAt *time.Time
+}
+
 type RevenueReportRow struct {
 	ServiceID        string  `json:"service_id"`
 	ServiceName      string  `json:"service_name"`
 	AppointmentCount int     `json:"appointment_count"`
 	UnitPrice        float64 `json:"unit_price"`
+	DiscountTotal    float64 `json:"discount_total"`
 	TotalRevenue     float64 `json:"total_revenue"`
 }
 
@@ -28,6 +46,9 @@ type DoctorPerformanceRow struct {
 	CompletedCount   int     `json:"completed_count"`
 	Revenue          float64 `json:"revenue"`
 	AverageRating    float64 `json:"average_rating"`
+	// Commission is owed to the doctor for completed appointments under the
+	// terms of their clinic membership
+	Commission float64 `json:"commission"`
 }
 
 type InventoryReportRow struct {
@@ -38,4 +59,72 @@ type InventoryReportRow struct {
 	RestockedQuantity  float64 `json:"restocked_quantity"`
 	UsedQuantity       float64 `json:"used_quantity"`
 	AdjustmentQuantity float64 `json:"adjustment_quantity"`
+	TransferQuantity   float64 `json:"transfer_quantity"` // net of transfers in and out
+	VarianceQuantity   float64 `json:"variance_quantity"` // posted by stock counts
+	VarianceReasons    string  `json:"variance_reasons"`  // variance by reason code, like "damaged: -2; found: 1"
+}
+
+// ValuationReportRow values the stock of a product at an address as it is
+// now. Method is how it was valued: weighted_average at the average cost it
+// was received at, fifo at the cost of the lots it is in. Stock whose cost is
+// not known is left out of Value and counted in UnvaluedQuantity.
+type ValuationReportRow struct {
+	ClinicAddressID  string  `json:"clinic_address_id"`
+	Address          string  `json:"address"`
+	ProductID        string  `json:"product_id"`
+	ProductName      string  `json:"product_name"`
+	Unit             string  `json:"unit"`
+	Method           string  `json:"method"`
+	Quantity         float64 `json:"quantity"`
+	UnitCost         float64 `json:"unit_cost"`
+	Value            float64 `json:"value"`
+	UnvaluedQuantity float64 `json:"unvalued_quantity"`
+}
+
+// MarginReportRow is the revenue of completed appointments less the cost of
+// the materials they used, per service or per doctor (GroupBy).
+type MarginReportRow struct {
+	GroupBy          string  `json:"group_by"`
+	ID               string  `json:"id"`
+	Name             string  `json:"name"`
+	AppointmentCount int     `json:"appointment_count"`
+	Revenue          float64 `json:"revenue"`
+	MaterialCost     float64 `json:"material_cost"`
+	Margin           float64 `json:"margin"`
+	MarginPercent    float64 `json:"margin_percent"`
+}
+
+// ReviewDimensionRow aggregates one review aspect. Doctor aspects are broken
+// down per doctor; clinic aspects leave the doctor fields empty.
+type ReviewDimensionRow struct {
+	Dimension     string  `json:"dimension"`
+	DoctorID      string  `json:"doctor_id"`
+	DoctorName    string  `json:"doctor_name"`
+	ReviewCount   int     `json:"review_count"`
+	AverageScore  float64 `json:"average_score"`
+	LowScoreCount int     `json:"low_score_count"`
+}
+
+type PrescriptionDocument struct {
+	Number               string
+	ClinicName           string
+	DoctorName           string
+	DoctorSpecialization string
+	PatientName          string
+	ServiceName          string
+	Diagnosis            string
+	VisitDate            time.Time
+	SignedAt             time.Time
+	ValidUntil           time.Time
+	Instructions         string
+	Items                []PrescriptionDocumentItem
+	Signature            string
+}
+
+type PrescriptionDocumentItem struct {
+	DrugName  string
+	Dose      string
+	Frequency string
+	Duration  string
+	Notes     string
 }
@@ -2,9 +2,11 @@ package repository
 
 import (
 	"context"
+	"math"
 
 	"dental_clinic/internal/modules/reports/models"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -13,6 +15,15 @@ type ReportsRepository interface {
 	GetAppointmentReport(filters models.ReportFilters) ([]models.AppointmentReportRow, error)
 	GetDoctorPerformanceReport(filters models.ReportFilters) ([]models.DoctorPerformanceRow, error)
 	GetInventoryReport(filters models.ReportFilters) ([]models.InventoryReportRow, error)
+	GetReviewDimensionReport(filters models.ReportFilters) ([]models.ReviewDimensionRow, error)
+	GetValuationMethod(clinicID string) (string, error)
+	GetValuationReport(filters models.ReportFilters, method string) ([]models.ValuationReportRow, error)
+	GetMarginReport(filters models.ReportFilters, groupBy string) ([]models.MarginReportRow, error)
+	CreateExport(export *models.ReportExport) (*models.ReportExport, error)
+	GetExportByID(id string) (*models.ReportExport, error)
+	GetExportContent(id string) ([]byte, error)
+	SetExportStatus(id, status, message string) error
+	SaveExportContent(id string, content []byte) error
 }
 
 type reportsRepo struct {
@@ -23,23 +34,26 @@ func NewReportsRepository(db *pgxpool.Pool) ReportsRepository {
 	return &reportsRepo{db: db}
 }
 
+// GetRevenueReport sums the prices locked on the appointments at booking
+// time. Cancelled appointments earn nothing.
 func (r *reportsRepo) GetRevenueReport(filters models.ReportFilters) ([]models.RevenueReportRow, error) {
 	query := `
 		SELECT
 			s.id::text,
 			s.name,
 			COUNT(a.id)::int AS appointment_count,
-			COALESCE(cs.price, 0)::float8 AS unit_price,
-			COALESCE(SUM(cs.price), 0)::float8 AS total_revenue
+			COALESCE(ROUND(AVG(a.charged_price), 2), 0)::float8 AS unit_price,
+			COALESCE(SUM(a.discount_amount), 0)::float8 AS discount_total,
+			COALESCE(SUM(a.charged_price), 0)::float8 AS total_revenue
 		FROM appointments a
 		JOIN clinic_addresses ca ON ca.id = a.clinic_address_id
 		JOIN services s ON s.id = a.service_id
-		LEFT JOIN clinic_services cs ON cs.clinic_id = ca.clinic_id AND cs.service_id = a.service_id
 		WHERE ca.clinic_id = $1::uuid
 			AND ($2 = '' OR a.clinic_address_id = $2::uuid)
 			AND a.start_time >= $3::date
 			AND a.start_time < ($4::date + INTERVAL '1 day')
-		GROUP BY s.id, s.name, cs.price
+			AND a.status <> 'cancelled'
+		GROUP BY s.id, s.name
 		ORDER BY total_revenue DESC, s.name
 	`
 	rows, err := r.db.Query(context.Background(), query, filters.ClinicID, filters.ClinicAddressID, filters.From, filters.To)
@@ -51,7 +65,7 @@ func (r *reportsRepo) GetRevenueReport(filters models.ReportFilters) ([]models.R
 	result := make([]models.RevenueReportRow, 0)
 	for rows.Next() {
 		var row models.RevenueReportRow
-		if err := rows.Scan(&row.ServiceID, &row.ServiceName, &row.AppointmentCount, &row.UnitPrice, &row.TotalRevenue); err != nil {
+		if err := rows.Scan(&row.ServiceID, &row.ServiceName, &row.AppointmentCount, &row.UnitPrice, &row.DiscountTotal, &row.TotalRevenue); err != nil {
 			return nil, err
 		}
 		result = append(result, row)
@@ -88,6 +102,8 @@ func (r *reportsRepo) GetAppointmentReport(filters models.ReportFilters) ([]mode
 	return result, rows.Err()
 }
 
+// GetDoctorPerformanceReport covers every doctor who is or was a member of
+// the clinic, so appointments of doctors who have since left still count.
 func (r *reportsRepo) GetDoctorPerformanceReport(filters models.ReportFilters) ([]models.DoctorPerformanceRow, error) {
 	query := `
 		SELECT
@@ -96,23 +112,26 @@ func (r *reportsRepo) GetDoctorPerformanceReport(filters models.ReportFilters) (
 			d.specialization,
 			COUNT(DISTINCT a.id)::int AS appointment_count,
 			COUNT(DISTINCT a.id) FILTER (WHERE a.status = 'completed')::int AS completed_count,
-			COALESCE(SUM(cs.price), 0)::float8 AS revenue,
-			COALESCE(ratings.average_rating, 0)::float8 AS average_rating
+			COALESCE(SUM(a.charged_price) FILTER (WHERE a.status <> 'cancelled'), 0)::float8 AS revenue,
+			COALESCE(ratings.average_rating, 0)::float8 AS average_rating,
+			(COALESCE(SUM(a.charged_price) FILTER (WHERE a.status = 'completed'), 0) * dc.commission_percent / 100
+				+ COUNT(DISTINCT a.id) FILTER (WHERE a.status = 'completed') * dc.commission_fixed)::float8 AS commission
 		FROM doctors d
-		JOIN clinic_addresses ca ON ca.clinic_id = d.clinic_id
+		JOIN doctor_clinics dc ON dc.doctor_id = d.id
+		JOIN clinic_addresses ca ON ca.clinic_id = dc.clinic_id
 		LEFT JOIN appointments a ON a.doctor_id = d.id AND a.clinic_address_id = ca.id
 			AND a.start_time >= $3::date
 			AND a.start_time < ($4::date + INTERVAL '1 day')
-		LEFT JOIN clinic_services cs ON cs.clinic_id = ca.clinic_id AND cs.service_id = a.service_id
 		LEFT JOIN (
 			SELECT doctor_id, ROUND(AVG(rating)::numeric, 2)::float8 AS average_rating
 			FROM doctor_ratings
+			WHERE status <> 'hidden'
 			GROUP BY doctor_id
 		) ratings ON ratings.doctor_id = d.id
-		WHERE d.clinic_id = $1::uuid
+		WHERE dc.clinic_id = $1::uuid
 			AND d.is_deleted = 0
 			AND ($2 = '' OR ca.id = $2::uuid)
-		GROUP BY d.id, d.name, d.specialization, ratings.average_rating
+		GROUP BY d.id, d.name, d.specialization, ratings.average_rating, dc.commission_percent, dc.commission_fixed
 		ORDER BY revenue DESC, appointment_count DESC, d.name
 	`
 	rows, err := r.db.Query(context.Background(), query, filters.ClinicID, filters.ClinicAddressID, filters.From, filters.To)
@@ -124,7 +143,7 @@ func (r *reportsRepo) GetDoctorPerformanceReport(filters models.ReportFilters) (
 	result := make([]models.DoctorPerformanceRow, 0)
 	for rows.Next() {
 		var row models.DoctorPerformanceRow
-		if err := rows.Scan(&row.DoctorID, &row.DoctorName, &row.Specialization, &row.AppointmentCount, &row.CompletedCount, &row.Revenue, &row.AverageRating); err != nil {
+		if err := rows.Scan(&row.DoctorID, &row.DoctorName, &row.Specialization, &row.AppointmentCount, &row.CompletedCount, &row.Revenue, &row.AverageRating, &row.Commission); err != nil {
 			return nil, err
 		}
 		result = append(result, row)
@@ -141,7 +160,10 @@ func (r *reportsRepo) GetInventoryReport(filters models.ReportFilters) ([]models
 			ABS(COALESCE(inv.current_quantity, 0))::float8,
 			ABS(COALESCE(tx.restocked_quantity, 0))::float8,
 			ABS(COALESCE(tx.used_quantity, 0))::float8,
-			COALESCE(tx.adjustment_quantity, 0)::float8
+			COALESCE(tx.adjustment_quantity, 0)::float8,
+			COALESCE(tx.transfer_quantity, 0)::float8,
+			COALESCE(tx.variance_quantity, 0)::float8,
+			COALESCE(vr.variance_reasons, '')
 		FROM products p
 		JOIN (
 			SELECT ai.product_id, SUM(ai.quantity)::float8 AS current_quantity
@@ -156,7 +178,9 @@ func (r *reportsRepo) GetInventoryReport(filters models.ReportFilters) ([]models
 				it.product_id,
 				SUM(it.quantity) FILTER (WHERE it.transaction_type = 'restocked')::float8 AS restocked_quantity,
 				SUM(it.quantity) FILTER (WHERE it.transaction_type = 'used')::float8 AS used_quantity,
-				SUM(it.quantity) FILTER (WHERE it.transaction_type = 'manual_adjustment')::float8 AS adjustment_quantity
+				SUM(it.quantity) FILTER (WHERE it.transaction_type = 'manual_adjustment')::float8 AS adjustment_quantity,
+				SUM(it.quantity) FILTER (WHERE it.transaction_type IN ('transfer_out', 'transfer_in'))::float8 AS transfer_quantity,
+				SUM(it.quantity) FILTER (WHERE it.transaction_type = 'stock_count')::float8 AS variance_quantity
 			FROM inventory_transactions it
 			JOIN clinic_addresses ca ON ca.id = it.clinic_address_id
 			WHERE ca.clinic_id = $1::uuid
@@ -165,6 +189,21 @@ func (r *reportsRepo) GetInventoryReport(filters models.ReportFilters) ([]models
 				AND it.created_at < ($4::date + INTERVAL '1 day')
 			GROUP BY it.product_id
 		) tx ON tx.product_id = p.id
+		LEFT JOIN (
+			SELECT v.product_id, string_agg(v.reason_code || ': ' || v.quantity::text, '; ' ORDER BY v.reason_code) AS variance_reasons
+			FROM (
+				SELECT it.product_id, COALESCE(it.reason_code, 'other') AS reason_code, SUM(it.quantity)::float8 AS quantity
+				FROM inventory_transactions it
+				JOIN clinic_addresses ca ON ca.id = it.clinic_address_id
+				WHERE ca.clinic_id = $1::uuid
+					AND ($2 = '' OR ca.id = $2::uuid)
+					AND it.transaction_type = 'stock_count'
+					AND it.created_at >= $3::date
+					AND it.created_at < ($4::date + INTERVAL '1 day')
+				GROUP BY it.product_id, COALESCE(it.reason_code, 'other')
+			) v
+			GROUP BY v.product_id
+		) vr ON vr.product_id = p.id
 		ORDER BY p.name
 	`
 	rows, err := r.db.Query(context.Background(), query, filters.ClinicID, filters.ClinicAddressID, filters.From, filters.To)
@@ -176,10 +215,257 @@ func (r *reportsRepo) GetInventoryReport(filters models.ReportFilters) ([]models
 	result := make([]models.InventoryReportRow, 0)
 	for rows.Next() {
 		var row models.InventoryReportRow
-		if err := rows.Scan(&row.ProductID, &row.ProductName, &row.Unit, &row.CurrentQuantity, &row.RestockedQuantity, &row.UsedQuantity, &row.AdjustmentQuantity); err != nil {
+		if err := rows.Scan(&row.ProductID, &row.ProductName, &row.Unit, &row.CurrentQuantity, &row.RestockedQuantity, &row.UsedQuantity, &row.AdjustmentQuantity, &row.TransferQuantity, &row.VarianceQuantity, &row.VarianceReasons); err != nil {
 			return nil, err
 		}
 		result = append(result, row)
 	}
 	return result, rows.Err()
 }
+
+func (r *reportsRepo) GetReviewDimensionReport(filters models.ReportFilters) ([]models.ReviewDimensionRow, error) {
+	query := `
+		SELECT
+			rds.dimension,
+			COALESCE(d.id::text, ''),
+			COALESCE(d.name, ''),
+			COUNT(*)::int AS review_count,
+			ROUND(AVG(rds.score)::numeric, 2)::float8 AS average_score,
+			COUNT(*) FILTER (WHERE rds.score <= 2)::int AS low_score_count
+		FROM review_dimension_scores rds
+		JOIN clinic_reviews cr ON cr.appointment_id = rds.appointment_id AND cr.status <> 'hidden'
+		JOIN appointments a ON a.id = rds.appointment_id
+		LEFT JOIN doctors d ON d.id = rds.doctor_id
+		WHERE rds.clinic_id = $1::uuid
+			AND ($2 = '' OR a.clinic_address_id = $2::uuid)
+			AND cr.created_at >= $3::date
+			AND cr.created_at < ($4::date + INTERVAL '1 day')
+		GROUP BY rds.dimension, d.id, d.name
+		ORDER BY rds.dimension, d.name NULLS FIRST
+	`
+	rows, err := r.db.Query(context.Background(), query, filters.ClinicID, filters.ClinicAddressID, filters.From, filters.To)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]models.ReviewDimensionRow, 0)
+	for rows.Next() {
+		var row models.ReviewDimensionRow
+		if err := rows.Scan(&row.Dimension, &row.DoctorID, &row.DoctorName, &row.ReviewCount, &row.AverageScore, &row.LowScoreCount); err != nil {
+			return nil, err
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
+// GetValuationMethod returns how the clinic values its stock,
+// weighted_average until it is set.
+func (r *reportsRepo) GetValuationMethod(clinicID string) (string, error) {
+	method := "weighted_average"
+	query := `SELECT valuation_method FROM clinic_inventory_settings WHERE clinic_id = $1::uuid`
+	err := r.db.QueryRow(context.Background(), query, clinicID).Scan(&method)
+	if err != nil && err != pgx.ErrNoRows {
+		return "", err
+	}
+	return method, nil
+}
+
+// GetValuationReport values the stock on hand at each address of the clinic.
+// fifo takes the cost of the lots the stock is in; weighted_average the
+// average cost the product was received at the address at.
+func (r *reportsRepo) GetValuationReport(filters models.ReportFilters, method string) ([]models.ValuationReportRow, error) {
+	query := `
+		SELECT
+			ca.id::text,
+			CONCAT_WS(', ', ad.city, ad.street, ad.building),
+			p.id::text,
+			p.name,
+			p.unit,
+			ai.quantity::float8,
+			ROUND(CASE
+				WHEN $3 = 'fifo' THEN COALESCE(lots.value, 0)
+				ELSE COALESCE(ai.quantity * pac.unit_cost, 0)
+			END, 2)::float8 AS value,
+			(CASE
+				WHEN $3 = 'fifo' THEN GREATEST(ai.quantity - COALESCE(lots.valued_quantity, 0), 0)
+				WHEN pac.unit_cost IS NULL THEN ai.quantity
+				ELSE 0
+			END)::float8 AS unvalued_quantity
+		FROM address_inventory ai
+		JOIN clinic_addresses ca ON ca.id = ai.clinic_address_id
+		LEFT JOIN addresses ad ON ad.id = ca.address_id
+		JOIN products p ON p.id = ai.product_id
+		LEFT JOIN (
+			SELECT
+				l.clinic_address_id,
+				l.product_id,
+				SUM(l.quantity * l.unit_cost) AS value,
+				SUM(l.quantity) AS valued_quantity
+			FROM inventory_lots l
+			WHERE l.quantity > 0
+				AND l.unit_cost IS NOT NULL
+			GROUP BY l.clinic_address_id, l.product_id
+		) lots ON lots.clinic_address_id = ai.clinic_address_id AND lots.product_id = ai.product_id
+		LEFT JOIN product_average_costs pac ON pac.clinic_address_id = ai.clinic_address_id AND pac.product_id = ai.product_id
+		WHERE ca.clinic_id = $1::uuid
+			AND ($2 = '' OR ca.id = $2::uuid)
+			AND ai.quantity > 0
+		ORDER BY ad.city, ad.street, ad.building, p.name
+	`
+	rows, err := r.db.Query(context.Background(), query, filters.ClinicID, filters.ClinicAddressID, method)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]models.ValuationReportRow, 0)
+	for rows.Next() {
+		row := models.ValuationReportRow{Method: method}
+		if err := rows.Scan(&row.ClinicAddressID, &row.Address, &row.ProductID, &row.ProductName, &row.Unit, &row.Quantity, &row.Value, &row.UnvaluedQuantity); err != nil {
+			return nil, err
+		}
+		if valued := row.Quantity - row.UnvaluedQuantity; valued > 0 {
+			row.UnitCost = math.Round(row.Value/valued*100) / 100
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
+// GetMarginReport sets the prices charged for completed appointments against
+// the cost of the materials they used, per service or per doctor. Materials
+// used without a cost are costed at the average cost of the product at the
+// address, or not at all when that is not known either.
+func (r *reportsRepo) GetMarginReport(filters models.ReportFilters, groupBy string) ([]models.MarginReportRow, error) {
+	group := "JOIN services g ON g.id = a.service_id"
+	if groupBy == "doctor" {
+		group = "JOIN doctors g ON g.id = a.doctor_id"
+	}
+	query := `
+		SELECT
+			g.id::text,
+			g.name,
+			COUNT(a.id)::int AS appointment_count,
+			COALESCE(SUM(a.charged_price), 0)::float8 AS revenue,
+			ROUND(COALESCE(SUM(materials.cost), 0), 2)::float8 AS material_cost
+		FROM appointments a
+		JOIN clinic_addresses ca ON ca.id = a.clinic_address_id
+		` + group + `
+		LEFT JOIN LATERAL (
+			SELECT SUM(ABS(it.quantity) * COALESCE(it.unit_cost, pac.unit_cost, 0)) AS cost
+			FROM inventory_transactions it
+			LEFT JOIN product_average_costs pac ON pac.clinic_address_id = it.clinic_address_id
+				AND pac.product_id = it.product_id
+			WHERE it.appointment_id = a.id
+				AND it.transaction_type = 'used'
+		) materials ON true
+		WHERE ca.clinic_id = $1::uuid
+			AND ($2 = '' OR a.clinic_address_id = $2::uuid)
+			AND a.start_time >= $3::date
+			AND a.start_time < ($4::date + INTERVAL '1 day')
+			AND a.status = 'completed'
+		GROUP BY g.id, g.name
+		ORDER BY COALESCE(SUM(a.charged_price), 0) - COALESCE(SUM(materials.cost), 0) DESC, g.name
+	`
+	rows, err := r.db.Query(context.Background(), query, filters.ClinicID, filters.ClinicAddressID, filters.From, filters.To)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]models.MarginReportRow, 0)
+	for rows.Next() {
+		row := models.MarginReportRow{GroupBy: groupBy}
+		if err := rows.Scan(&row.ID, &row.Name, &row.AppointmentCount, &row.Revenue, &row.MaterialCost); err != nil {
+			return nil, err
+		}
+		row.Margin = math.Round((row.Revenue-row.MaterialCost)*100) / 100
+		if row.Revenue > 0 {
+			row.MarginPercent = math.Round(row.Margin/row.Revenue*10000) / 100
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
+const exportColumns = `
+	id::text, clinic_id::text, COALESCE(clinic_address_id::text, ''), report, format,
+	to_char(date_from, 'YYYY-MM-DD'), to_char(date_to, 'YYYY-MM-DD'),
+	method, group_by, status, error, created_at, finished_at
+`
+
+func scanExport(row pgx.Row) (*models.ReportExport, error) {
+	var e models.ReportExport
+	err := row.Scan(&e.Id, &e.Filters.ClinicID, &e.Filters.ClinicAddressID, &e.Report, &e.Format,
+		&e.Filters.From, &e.Filters.To, &e.Method, &e.GroupBy, &e.Status, &e.Error, &e.CreatedAt, &e.FinishedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (r *reportsRepo) CreateExport(export *models.ReportExport) (*models.ReportExport, error) {
+	query := `
+		INSERT INTO report_exports (clinic_id, clinic_address_id, report, format, date_from, date_to, method, group_by)
+		VALUES ($1::uuid, NULLIF($2, '')::uuid, $3, $4, $5::date, $6::date, $7, $8)
+		RETURNING ` + exportColumns
+	return scanExport(r.db.QueryRow(context.Background(), query,
+		export.Filters.ClinicID, export.Filters.ClinicAddressID, export.Report, export.Format,
+		export.Filters.From, export.Filters.To, export.Method, export.GroupBy))
+}
+
+func (r *reportsRepo) GetExportByID(id string) (*models.ReportExport, error) {
+	query := `SELECT ` + exportColumns + ` FROM report_exports WHERE id = $1::uuid`
+	return scanExport(r.db.QueryRow(context.Background(), query, id))
+}
+
+func (r *reportsRepo) GetExportContent(id string) ([]byte, error) {
+	var content []byte
+	err := r.db.QueryRow(context.Background(), `SELECT content FROM report_exports WHERE id = $1::uuid`, id).Scan(&content)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return content, nil
+}
+
+// SetExportStatus moves an export to status, keeping message as its error.
+// Failed exports are finished; any other status is not.
+func (r *reportsRepo) SetExportStatus(id, status, message string) error {
+	query := `
+		UPDATE report_exports
+		SET status = $2, error = $3, finished_at = CASE WHEN $2 = 'failed' THEN NOW() END
+		WHERE id = $1::uuid
+	`
+	result, err := r.db.Exec(context.Background(), query, id, status, message)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *reportsRepo) SaveExportContent(id string, content []byte) error {
+	query := `
+		UPDATE report_exports
+		SET status = 'ready', error = '', content = $2, finished_at = NOW()
+		WHERE id = $1::uuid
+	`
+	result, err := r.db.Exec(context.Background(), query, id, content)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
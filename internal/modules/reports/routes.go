@@ -5,6 +5,7 @@ import (
 	"dental_clinic/internal/modules/reports/handlers"
 	"dental_clinic/internal/modules/reports/repository"
 	"dental_clinic/internal/modules/reports/services"
+	"dental_clinic/internal/queue"
 
 	"github.com/gorilla/mux"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -13,11 +14,17 @@ import (
 func RegisterPrivateRoutes(r *mux.Router, db *pgxpool.Pool, cfg *config.Config) {
 	_ = cfg
 	repo := repository.NewReportsRepository(db)
-	service := services.NewReportsService(repo)
+	service := services.NewReportsService(repo, queue.New(db))
 	handler := handlers.NewReportsHandler(service)
 
 	r.HandleFunc("/clinics/{clinicId}/reports/revenue", handler.GetRevenueReport).Methods("GET")
 	r.HandleFunc("/clinics/{clinicId}/reports/appointments", handler.GetAppointmentReport).Methods("GET")
 	r.HandleFunc("/clinics/{clinicId}/reports/doctors", handler.GetDoctorPerformanceReport).Methods("GET")
 	r.HandleFunc("/clinics/{clinicId}/reports/inventory", handler.GetInventoryReport).Methods("GET")
+	r.HandleFunc("/clinics/{clinicId}/reports/reviews", handler.GetReviewDimensionReport).Methods("GET")
+	r.HandleFunc("/clinics/{clinicId}/reports/inventory-valuation", handler.GetValuationReport).Methods("GET")
+	r.HandleFunc("/clinics/{clinicId}/reports/margins", handler.GetMarginReport).Methods("GET")
+	r.HandleFunc("/clinics/{clinicId}/reports/exports", handler.CreateReportExport).Methods("POST")
+	r.HandleFunc("/clinics/{clinicId}/reports/exports/{id}", handler.GetReportExport).Methods("GET")
+	r.HandleFunc("/clinics/{clinicId}/reports/exports/{id}/download", handler.DownloadReportExport).Methods("GET")
 }
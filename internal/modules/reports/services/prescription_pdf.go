@@ -0,0 +1,159 @@
+package services
+
+import (
+	"fmt"
+
+	"dental_clinic/internal/modules/reports/models"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// ── Prescription document ─────────────────────────────────────────────────────
+
+// BuildPrescriptionPDF renders a prescription using the same header, table and
+// footer helpers as the clinic reports. The header date range shows the issue
+// date and the validity end.
+func BuildPrescriptionPDF(doc models.PrescriptionDocument) ([]byte, error) {
+	pdf := newPDF()
+	pdf.SetTitle("Prescription "+doc.Number, false)
+	pdf.SetAuthor(utf8safe(doc.DoctorName), false)
+
+	y := renderHeader(pdf, "Prescription No. "+doc.Number,
+		doc.SignedAt.Format("02 Jan 2006"), doc.ValidUntil.Format("02 Jan 2006"))
+
+	// Patient / doctor cards
+	cardW := (inner - 3) / 2
+	infoCard(pdf, margin, y, cardW, "Patient", []string{
+		utf8safe(doc.PatientName),
+		"Visit: " + doc.VisitDate.Format("02 Jan 2006"),
+		utf8safe(doc.ServiceName),
+	})
+	infoCard(pdf, margin+cardW+3, y, cardW, "Doctor", []string{
+		utf8safe(doc.DoctorName),
+		utf8safe(doc.DoctorSpecialization),
+		utf8safe(doc.ClinicName),
+	})
+	y += 30
+
+	if doc.Diagnosis != "" {
+		y = sectionHeading(pdf, y, "Diagnosis")
+		pdf.SetFont("Helvetica", "", 8.5)
+		text(pdf, cDark)
+		pdf.SetXY(margin, y)
+		pdf.MultiCell(inner, 4.5, utf8safe(doc.Diagnosis), "", "L", false)
+		y = pdf.GetY() + 6
+	}
+
+	// Medication table
+	if len(doc.Items) > 0 {
+		y = sectionHeading(pdf, y, "Medication")
+		cols := []tableCol{
+			{margin + 2, 8, "#", "L"},
+			{margin + 11, 58, "Drug", "L"},
+			{margin + 71, 30, "Dose", "L"},
+			{margin + 103, 42, "Frequency", "L"},
+			{margin + 147, 33, "Duration", "L"},
+		}
+		y = tableHeader(pdf, y, cols)
+		for i, item := range doc.Items {
+			y = prescriptionPageBreak(pdf, y, rowH+5)
+			tableRowBand(pdf, y, i)
+			pdf.SetFont("Helvetica", "", 8)
+			text(pdf, cMuted)
+			pdf.SetXY(cols[0].x, y)
+			pdf.CellFormat(cols[0].w, rowH, fmt.Sprintf("%d", i+1), "", 0, "L", false, 0, "")
+			pdf.SetFont("Helvetica", "B", 8.5)
+			text(pdf, cDark)
+			pdf.SetXY(cols[1].x, y)
+			pdf.CellFormat(cols[1].w, rowH, truncate(utf8safe(item.DrugName), 38), "", 0, "L", false, 0, "")
+			pdf.SetFont("Helvetica", "", 8)
+			pdf.SetXY(cols[2].x, y)
+			pdf.CellFormat(cols[2].w, rowH, truncate(utf8safe(item.Dose), 20), "", 0, "L", false, 0, "")
+			pdf.SetXY(cols[3].x, y)
+			pdf.CellFormat(cols[3].w, rowH, truncate(utf8safe(item.Frequency), 28), "", 0, "L", false, 0, "")
+			pdf.SetXY(cols[4].x, y)
+			pdf.CellFormat(cols[4].w, rowH, truncate(utf8safe(item.Duration), 22), "", 0, "L", false, 0, "")
+			y += rowH
+			if item.Notes != "" {
+				pdf.SetFont("Helvetica", "I", 7.5)
+				text(pdf, cMuted)
+				pdf.SetXY(cols[1].x, y)
+				pdf.MultiCell(inner-15, 4, utf8safe(item.Notes), "", "L", false)
+				y = pdf.GetY() + 1
+			}
+		}
+		y += 8
+	}
+
+	// Instructions
+	if doc.Instructions != "" {
+		y = prescriptionPageBreak(pdf, y, 30)
+		y = sectionHeading(pdf, y, "Instructions")
+		pdf.SetFont("Helvetica", "", 8.5)
+		text(pdf, cDark)
+		pdf.SetXY(margin, y)
+		pdf.MultiCell(inner, 4.5, utf8safe(doc.Instructions), "", "L", false)
+		y = pdf.GetY() + 8
+	}
+
+	// Signature block
+	y = prescriptionPageBreak(pdf, y, 34)
+	y = sectionHeading(pdf, y, "Signature")
+	fill(pdf, cSubtle)
+	draw(pdf, cBorder)
+	pdf.SetLineWidth(0.3)
+	pdf.RoundedRect(margin, y, inner, 22, 3, "1234", "FD")
+	pdf.SetFont("Helvetica", "B", 9)
+	text(pdf, cDark)
+	pdf.SetXY(margin+5, y+3)
+	pdf.Cell(inner/2, 5, utf8safe(doc.DoctorName))
+	pdf.SetFont("Helvetica", "", 7.5)
+	text(pdf, cMuted)
+	pdf.SetXY(margin+5, y+9)
+	pdf.Cell(inner/2, 4, "Signed electronically "+doc.SignedAt.Format("02 Jan 2006  15:04"))
+	pdf.SetFont("Courier", "", 7)
+	pdf.SetXY(margin+5, y+14)
+	pdf.Cell(inner-10, 4, "Signature: "+doc.Signature)
+	badge(pdf, margin+inner-52, y+3, "Signed", cAccent)
+
+	renderFooter(pdf)
+	return pdfBytes(pdf)
+}
+
+// infoCard draws a small titled card with up to three lines of text.
+func infoCard(pdf *gofpdf.Fpdf, x, y, w float64, title string, lines []string) {
+	fill(pdf, cSubtle)
+	draw(pdf, cBorder)
+	pdf.SetLineWidth(0.3)
+	pdf.RoundedRect(x, y, w, 24, 3, "1234", "FD")
+	fill(pdf, cPrimary)
+	pdf.RoundedRect(x, y, 3, 24, 1.5, "1234", "F")
+
+	pdf.SetFont("Helvetica", "B", 6.5)
+	text(pdf, cMuted)
+	pdf.SetXY(x+7, y+3)
+	pdf.Cell(w-10, 4, title)
+
+	for i, line := range lines {
+		if i == 0 {
+			pdf.SetFont("Helvetica", "B", 9.5)
+			text(pdf, cDark)
+		} else {
+			pdf.SetFont("Helvetica", "", 7.5)
+			text(pdf, cMuted)
+		}
+		pdf.SetXY(x+7, y+8+float64(i)*5)
+		pdf.Cell(w-10, 5, truncate(line, 48))
+	}
+}
+
+// prescriptionPageBreak starts a new page when the next block of height h
+// would run into the footer. Returns the Y to continue from.
+func prescriptionPageBreak(pdf *gofpdf.Fpdf, y, h float64) float64 {
+	if y+h < pageH-16 {
+		return y
+	}
+	renderFooter(pdf)
+	pdf.AddPage()
+	return margin
+}
@@ -2,8 +2,11 @@ package services
 
 import (
 	"bytes"
+	"context"
+	"dental_clinic/internal/modules/reports/dto"
 	"dental_clinic/internal/modules/reports/models"
 	"dental_clinic/internal/modules/reports/repository"
+	"dental_clinic/internal/queue"
 	"encoding/csv"
 	"errors"
 	"fmt"
@@ -19,11 +22,12 @@ import (
 // ── Service wiring ────────────────────────────────────────────────────────────
 
 type ReportsService struct {
-	repo repository.ReportsRepository
+	repo  repository.ReportsRepository
+	queue *queue.Queue
 }
 
-func NewReportsService(repo repository.ReportsRepository) *ReportsService {
-	return &ReportsService{repo: repo}
+func NewReportsService(repo repository.ReportsRepository, q *queue.Queue) *ReportsService {
+	return &ReportsService{repo: repo, queue: q}
 }
 
 func utf8safe(s string) string {
@@ -105,6 +109,223 @@ func (s *ReportsService) Inventory(filters models.ReportFilters) ([]models.Inven
 	return s.repo.GetInventoryReport(filters)
 }
 
+func (s *ReportsService) ReviewDimensions(filters models.ReportFilters) ([]models.ReviewDimensionRow, error) {
+	return s.repo.GetReviewDimensionReport(filters)
+}
+
+// Valuation values the stock on hand by method, weighted_average or fifo,
+// or by the clinic's valuation method when method is empty.
+func (s *ReportsService) Valuation(filters models.ReportFilters, method string) ([]models.ValuationReportRow, error) {
+	if method == "" {
+		var err error
+		if method, err = s.repo.GetValuationMethod(filters.ClinicID); err != nil {
+			return nil, err
+		}
+	}
+	switch method {
+	case "weighted_average", "fifo":
+	default:
+		return nil, errors.New("method must be weighted_average or fifo")
+	}
+	return s.repo.GetValuationReport(filters, method)
+}
+
+// Margins reports the margin of completed appointments per service, or per
+// doctor when groupBy is "doctor".
+func (s *ReportsService) Margins(filters models.ReportFilters, groupBy string) ([]models.MarginReportRow, error) {
+	switch groupBy {
+	case "":
+		groupBy = "service"
+	case "service", "doctor":
+	default:
+		return nil, errors.New("by must be service or doctor")
+	}
+	return s.repo.GetMarginReport(filters, groupBy)
+}
+
+// ── Exports ───────────────────────────────────────────────────────────────────
+
+// ExportReportJob is the queue job that renders a report export.
+const ExportReportJob = "reports.export"
+
+type ExportReportPayload struct {
+	ExportId string `json:"export_id"`
+}
+
+// reportTitles are the reports that can be exported, keyed the way their
+// routes name them.
+var reportTitles = map[string]string{
+	"revenue":             "Revenue Report",
+	"appointments":        "Appointment Report",
+	"doctors":             "Doctor Performance Report",
+	"inventory":           "Inventory Report",
+	"reviews":             "Review Dimensions Report",
+	"inventory-valuation": "Inventory Valuation Report",
+	"margins":             "Margin Report",
+}
+
+// QueueExport records an export of a report and hands its rendering to the
+// background workers. The inventory valuation is of the stock on hand, so
+// its dates are always today.
+func (s *ReportsService) QueueExport(ctx context.Context, clinicID string, req dto.ReportExportRequest) (*models.ReportExport, error) {
+	if _, ok := reportTitles[req.Report]; !ok {
+		return nil, errors.New("unknown report")
+	}
+	if req.Format != "csv" && req.Format != "pdf" {
+		return nil, errors.New("format must be csv or pdf")
+	}
+	from, to := req.From, req.To
+	if req.Report == "inventory-valuation" {
+		from = time.Now().Format("2006-01-02")
+		to = from
+	}
+	filters, err := s.BuildFilters(clinicID, req.ClinicAddressID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	switch req.Method {
+	case "", "weighted_average", "fifo":
+	default:
+		return nil, errors.New("method must be weighted_average or fifo")
+	}
+	switch req.By {
+	case "", "service", "doctor":
+	default:
+		return nil, errors.New("by must be service or doctor")
+	}
+
+	export, err := s.repo.CreateExport(&models.ReportExport{
+		Report:  req.Report,
+		Format:  req.Format,
+		Filters: filters,
+		Method:  req.Method,
+		GroupBy: req.By,
+	})
+	if err != nil {
+		return nil, err
+	}
+	job := queue.Job{Type: ExportReportJob, Payload: ExportReportPayload{ExportId: export.Id}}
+	if _, err := s.queue.Enqueue(ctx, job); err != nil {
+		_ = s.repo.SetExportStatus(export.Id, "failed", err.Error())
+		return nil, err
+	}
+	return export, nil
+}
+
+// RunExport renders a queued export. A failed attempt puts the export back
+// in the queue with its error, or fails it when the job is out of attempts.
+func (s *ReportsService) RunExport(ctx context.Context, id string) error {
+	export, err := s.repo.GetExportByID(id)
+	if err != nil {
+		return err
+	}
+	if export == nil {
+		return queue.Permanent(errors.New("report export not found"))
+	}
+	if export.Status == "ready" {
+		return nil
+	}
+	if err := s.repo.SetExportStatus(export.Id, "running", ""); err != nil {
+		return err
+	}
+
+	content, err := s.renderExport(*export)
+	if err != nil {
+		status := "queued"
+		if queue.IsLastAttempt(ctx) {
+			status = "failed"
+		}
+		_ = s.repo.SetExportStatus(export.Id, status, err.Error())
+		return err
+	}
+	return s.repo.SaveExportContent(export.Id, content)
+}
+
+func (s *ReportsService) renderExport(export models.ReportExport) ([]byte, error) {
+	var data interface{}
+	var err error
+	switch export.Report {
+	case "revenue":
+		data, err = s.Revenue(export.Filters)
+	case "appointments":
+		data, err = s.Appointments(export.Filters)
+	case "doctors":
+		data, err = s.Doctors(export.Filters)
+	case "inventory":
+		data, err = s.Inventory(export.Filters)
+	case "reviews":
+		data, err = s.ReviewDimensions(export.Filters)
+	case "inventory-valuation":
+		data, err = s.Valuation(export.Filters, export.Method)
+	case "margins":
+		data, err = s.Margins(export.Filters, export.GroupBy)
+	default:
+		return nil, queue.Permanent(fmt.Errorf("unknown report %q", export.Report))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if export.Format == "pdf" {
+		return ToPDF(reportTitles[export.Report], export.Filters.From, export.Filters.To, data)
+	}
+	return ToCSV(data)
+}
+
+func (s *ReportsService) GetExport(clinicID, id string) (*models.ReportExport, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, errors.New("invalid export id")
+	}
+	export, err := s.repo.GetExportByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if export == nil || !strings.EqualFold(export.Filters.ClinicID, clinicID) {
+		return nil, errors.New("report export not found")
+	}
+	return export, nil
+}
+
+// GetExportFile returns a ready export with the file it was rendered to.
+func (s *ReportsService) GetExportFile(clinicID, id string) (*models.ReportExport, []byte, error) {
+	export, err := s.GetExport(clinicID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if export.Status != "ready" {
+		return nil, nil, errors.New("report export is not ready")
+	}
+	content, err := s.repo.GetExportContent(export.Id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return export, content, nil
+}
+
+// ExportFilename names the file of an export after the report and its dates.
+func ExportFilename(export models.ReportExport) string {
+	return fmt.Sprintf("%s-%s-%s.%s", export.Report, export.Filters.From, export.Filters.To, export.Format)
+}
+
+func ToReportExportResponse(export models.ReportExport) dto.ReportExportResponse {
+	response := dto.ReportExportResponse{
+		Id:              export.Id,
+		Report:          export.Report,
+		Format:          export.Format,
+		ClinicAddressID: export.Filters.ClinicAddressID,
+		From:            export.Filters.From,
+		To:              export.Filters.To,
+		Method:          export.Method,
+		By:              export.GroupBy,
+		Status:          export.Status,
+		Error:           export.Error,
+		CreatedAt:       export.CreatedAt.Format(time.RFC3339),
+	}
+	if export.FinishedAt != nil {
+		response.FinishedAt = export.FinishedAt.Format(time.RFC3339)
+	}
+	return response
+}
+
 // ── CSV (unchanged) ───────────────────────────────────────────────────────────
 
 func ToCSV(data interface{}) ([]byte, error) {
@@ -123,7 +344,7 @@ func ToCSV(data interface{}) ([]byte, error) {
 
 // ── PDF ───────────────────────────────────────────────────────────────────────
 
-// ToPDF generates a branded, formatted PDF for any of the four report types.
+// ToPDF generates a branded, formatted PDF for any of the report types.
 // The from/to strings are displayed in the report header.
 func ToPDF(title, from, to string, data interface{}) ([]byte, error) {
 	switch v := data.(type) {
@@ -135,6 +356,12 @@ func ToPDF(title, from, to string, data interface{}) ([]byte, error) {
 		return buildDoctorPDF(title, from, to, v)
 	case []models.InventoryReportRow:
 		return buildInventoryPDF(title, from, to, v)
+	case []models.ReviewDimensionRow:
+		return buildReviewDimensionPDF(title, from, to, v)
+	case []models.ValuationReportRow:
+		return buildValuationPDF(title, from, to, v)
+	case []models.MarginReportRow:
+		return buildMarginPDF(title, from, to, v)
 	default:
 		return nil, fmt.Errorf("unsupported report data type: %T", data)
 	}
@@ -894,6 +1121,57 @@ func buildInventoryPDF(title, from, to string, rows []models.InventoryReportRow)
 	}
 	y += 10
 
+	// Stock count variances
+	variances := make([]models.InventoryReportRow, 0)
+	for _, r := range rows {
+		if r.VarianceReasons != "" {
+			variances = append(variances, r)
+		}
+	}
+	if len(variances) > 0 {
+		y = sectionHeading(pdf, y, "Stock Count Variances")
+		varianceCols := []tableCol{
+			{margin + 2, 50, "Product", "L"},
+			{margin + 56, 14, "Unit", "C"},
+			{margin + 74, 24, "Variance", "R"},
+			{margin + 104, 76, "By Reason", "L"},
+		}
+		y = tableHeader(pdf, y, varianceCols)
+
+		for i, r := range variances {
+			tableRowBand(pdf, y, i)
+
+			pdf.SetFont("Helvetica", "B", 7.5)
+			text(pdf, cDark)
+			pdf.SetXY(varianceCols[0].x, y)
+			pdf.CellFormat(varianceCols[0].w, rowH, truncate(utf8safe(r.ProductName), 22), "", 0, "L", false, 0, "")
+
+			pdf.SetFont("Helvetica", "", 7.5)
+			text(pdf, cMuted)
+			pdf.SetXY(varianceCols[1].x, y)
+			pdf.CellFormat(varianceCols[1].w, rowH, utf8safe(r.Unit), "", 0, "C", false, 0, "")
+
+			pdf.SetFont("Helvetica", "B", 8)
+			varianceText := fmtFloat(r.VarianceQuantity)
+			if r.VarianceQuantity < 0 {
+				text(pdf, color{239, 68, 68})
+			} else {
+				text(pdf, cAccent)
+				varianceText = "+" + varianceText
+			}
+			pdf.SetXY(varianceCols[2].x, y)
+			pdf.CellFormat(varianceCols[2].w, rowH, varianceText, "", 0, "R", false, 0, "")
+
+			pdf.SetFont("Helvetica", "", 7.5)
+			text(pdf, cMuted)
+			pdf.SetXY(varianceCols[3].x, y)
+			pdf.CellFormat(varianceCols[3].w, rowH, truncate(utf8safe(r.VarianceReasons), 48), "", 0, "L", false, 0, "")
+
+			y += rowH
+		}
+		y += 10
+	}
+
 	// Stock level overview bar chart
 	if len(rows) > 0 && maxStock > 0 {
 		y = sectionHeading(pdf, y, "Current Stock Levels")
@@ -933,6 +1211,236 @@ func buildInventoryPDF(title, from, to string, rows []models.InventoryReportRow)
 
 // ── Utilities ─────────────────────────────────────────────────────────────────
 
+// ── Review dimensions report ───────────────────────────────────────────────────
+
+func buildReviewDimensionPDF(title, from, to string, rows []models.ReviewDimensionRow) ([]byte, error) {
+	pdf := newPDF()
+	y := renderHeader(pdf, title, from, to)
+
+	totalScores := 0
+	totalLow := 0
+	weighted := 0.0
+	for _, r := range rows {
+		totalScores += r.ReviewCount
+		totalLow += r.LowScoreCount
+		weighted += r.AverageScore * float64(r.ReviewCount)
+	}
+	avgScore := 0.0
+	if totalScores > 0 {
+		avgScore = weighted / float64(totalScores)
+	}
+
+	cardW := (inner - 6) / 3
+	kpiCard(pdf, margin, y, cardW, 28, fmt.Sprintf("%d", totalScores), "Scores", "across all aspects", cPrimary)
+	kpiCard(pdf, margin+cardW+3, y, cardW, 28, fmt.Sprintf("%.2f / 5", avgScore), "Avg Score", "weighted by reviews", cAccent)
+	kpiCard(pdf, margin+2*(cardW+3), y, cardW, 28, fmt.Sprintf("%d", totalLow), "Low Scores", fmt.Sprintf("%.0f%% rated 1-2", safePct(totalLow, totalScores)), color{239, 68, 68})
+	y += 36
+
+	y = sectionHeading(pdf, y, "Scores by Aspect")
+	cols := []tableCol{
+		{margin + 2, 40, "Aspect", "L"},
+		{margin + 44, 50, "Doctor", "L"},
+		{margin + 98, 20, "Reviews", "R"},
+		{margin + 122, 22, "Low", "R"},
+		{margin + 150, 32, "Average", "L"},
+	}
+	y = tableHeader(pdf, y, cols)
+
+	for i, r := range rows {
+		tableRowBand(pdf, y, i)
+
+		pdf.SetFont("Helvetica", "B", 7.5)
+		text(pdf, cDark)
+		pdf.SetXY(cols[0].x, y)
+		pdf.CellFormat(cols[0].w, rowH, humanStatus(r.Dimension), "", 0, "L", false, 0, "")
+
+		doctor := r.DoctorName
+		if doctor == "" {
+			doctor = "Clinic"
+		}
+		pdf.SetFont("Helvetica", "", 7.5)
+		text(pdf, cMuted)
+		pdf.SetXY(cols[1].x, y)
+		pdf.CellFormat(cols[1].w, rowH, truncate(utf8safe(doctor), 22), "", 0, "L", false, 0, "")
+
+		pdf.SetFont("Helvetica", "", 8)
+		text(pdf, cDark)
+		pdf.SetXY(cols[2].x, y)
+		pdf.CellFormat(cols[2].w, rowH, fmt.Sprintf("%d", r.ReviewCount), "", 0, "R", false, 0, "")
+
+		lowColor := cMuted
+		if safePct(r.LowScoreCount, r.ReviewCount) >= 25 {
+			lowColor = color{239, 68, 68}
+		}
+		text(pdf, lowColor)
+		pdf.SetXY(cols[3].x, y)
+		pdf.CellFormat(cols[3].w, rowH, fmt.Sprintf("%d", r.LowScoreCount), "", 0, "R", false, 0, "")
+
+		starRating(pdf, cols[4].x, y, r.AverageScore)
+		pdf.SetFont("Helvetica", "", 7)
+		text(pdf, cMuted)
+		pdf.SetXY(cols[4].x+26, y)
+		pdf.Cell(10, rowH, fmt.Sprintf("%.1f", r.AverageScore))
+
+		y += rowH
+	}
+
+	renderFooter(pdf)
+	return pdfBytes(pdf)
+}
+
+// ── Inventory valuation report ────────────────────────────────────────────────
+
+func buildValuationPDF(title, from, to string, rows []models.ValuationReportRow) ([]byte, error) {
+	pdf := newPDF()
+	y := renderHeader(pdf, title, from, to)
+
+	method := "weighted_average"
+	totalValue := 0.0
+	unvalued := 0
+	for _, r := range rows {
+		method = r.Method
+		totalValue += r.Value
+		if r.UnvaluedQuantity > 0 {
+			unvalued++
+		}
+	}
+
+	cardW := (inner - 6) / 3
+	kpiCard(pdf, margin, y, cardW, 28, fmt.Sprintf("KZT %.0f", totalValue), "Stock Value", "on hand now", cPrimary)
+	kpiCard(pdf, margin+cardW+3, y, cardW, 28, fmt.Sprintf("%d", len(rows)), "Stock Lines", "product per address", cAccent)
+	kpiCard(pdf, margin+2*(cardW+3), y, cardW, 28, fmt.Sprintf("%d", unvalued), "Without Cost", humanStatus(method)+" valuation", color{245, 158, 11})
+	y += 36
+
+	y = sectionHeading(pdf, y, "Stock Value by Address and Product")
+	cols := []tableCol{
+		{margin + 2, 44, "Address", "L"},
+		{margin + 48, 44, "Product", "L"},
+		{margin + 94, 22, "Quantity", "R"},
+		{margin + 118, 26, "Unit KZT", "R"},
+		{margin + 146, 34, "KZT Value", "R"},
+	}
+	y = tableHeader(pdf, y, cols)
+
+	for i, r := range rows {
+		if y+rowH > pageH-16 {
+			renderFooter(pdf)
+			pdf.AddPage()
+			y = tableHeader(pdf, margin, cols)
+		}
+		tableRowBand(pdf, y, i)
+
+		pdf.SetFont("Helvetica", "", 7.5)
+		text(pdf, cMuted)
+		pdf.SetXY(cols[0].x, y)
+		pdf.CellFormat(cols[0].w, rowH, truncate(utf8safe(r.Address), 24), "", 0, "L", false, 0, "")
+
+		pdf.SetFont("Helvetica", "B", 7.5)
+		text(pdf, cDark)
+		pdf.SetXY(cols[1].x, y)
+		pdf.CellFormat(cols[1].w, rowH, truncate(utf8safe(r.ProductName), 24), "", 0, "L", false, 0, "")
+
+		pdf.SetFont("Helvetica", "", 8)
+		pdf.SetXY(cols[2].x, y)
+		pdf.CellFormat(cols[2].w, rowH, fmtFloat(r.Quantity)+" "+truncate(utf8safe(r.Unit), 6), "", 0, "R", false, 0, "")
+
+		text(pdf, cMuted)
+		pdf.SetXY(cols[3].x, y)
+		pdf.CellFormat(cols[3].w, rowH, fmt.Sprintf("%.2f", r.UnitCost), "", 0, "R", false, 0, "")
+
+		pdf.SetFont("Helvetica", "B", 8)
+		valueText := fmt.Sprintf("%.2f", r.Value)
+		if r.UnvaluedQuantity > 0 {
+			text(pdf, color{245, 158, 11})
+			valueText += fmt.Sprintf(" (%s no cost)", fmtFloat(r.UnvaluedQuantity))
+		} else {
+			text(pdf, cDark)
+		}
+		pdf.SetXY(cols[4].x, y)
+		pdf.CellFormat(cols[4].w, rowH, valueText, "", 0, "R", false, 0, "")
+
+		y += rowH
+	}
+
+	renderFooter(pdf)
+	return pdfBytes(pdf)
+}
+
+// ── Margin report ─────────────────────────────────────────────────────────────
+
+func buildMarginPDF(title, from, to string, rows []models.MarginReportRow) ([]byte, error) {
+	pdf := newPDF()
+	y := renderHeader(pdf, title, from, to)
+
+	groupBy := "service"
+	totalRevenue := 0.0
+	totalCost := 0.0
+	for _, r := range rows {
+		groupBy = r.GroupBy
+		totalRevenue += r.Revenue
+		totalCost += r.MaterialCost
+	}
+	totalMargin := totalRevenue - totalCost
+
+	cardW := (inner - 6) / 3
+	kpiCard(pdf, margin, y, cardW, 28, fmt.Sprintf("KZT %.0f", totalRevenue), "Revenue", "completed appointments", cPrimary)
+	kpiCard(pdf, margin+cardW+3, y, cardW, 28, fmt.Sprintf("KZT %.0f", totalCost), "Material Cost", "materials used", color{239, 68, 68})
+	kpiCard(pdf, margin+2*(cardW+3), y, cardW, 28, fmt.Sprintf("KZT %.0f", totalMargin), "Margin", fmt.Sprintf("%.1f%% of revenue", safePctF(totalMargin, totalRevenue)), cAccent)
+	y += 36
+
+	y = sectionHeading(pdf, y, "Margin by "+humanStatus(groupBy))
+	barMaxW := 30.0
+	cols := []tableCol{
+		{margin + 2, 50, humanStatus(groupBy), "L"},
+		{margin + 54, 14, "Appts", "R"},
+		{margin + 70, 26, "Revenue", "R"},
+		{margin + 98, 24, "Materials", "R"},
+		{margin + 124, 24, "Margin", "R"},
+		{margin + 152, barMaxW, "Margin %", "L"},
+	}
+	y = tableHeader(pdf, y, cols)
+
+	for i, r := range rows {
+		if y+rowH > pageH-16 {
+			renderFooter(pdf)
+			pdf.AddPage()
+			y = tableHeader(pdf, margin, cols)
+		}
+		tableRowBand(pdf, y, i)
+
+		pdf.SetFont("Helvetica", "B", 7.5)
+		text(pdf, cDark)
+		pdf.SetXY(cols[0].x, y)
+		pdf.CellFormat(cols[0].w, rowH, truncate(utf8safe(r.Name), 26), "", 0, "L", false, 0, "")
+
+		pdf.SetFont("Helvetica", "", 8)
+		pdf.SetXY(cols[1].x, y)
+		pdf.CellFormat(cols[1].w, rowH, fmt.Sprintf("%d", r.AppointmentCount), "", 0, "R", false, 0, "")
+
+		pdf.SetXY(cols[2].x, y)
+		pdf.CellFormat(cols[2].w, rowH, fmt.Sprintf("%.2f", r.Revenue), "", 0, "R", false, 0, "")
+
+		text(pdf, color{239, 68, 68})
+		pdf.SetXY(cols[3].x, y)
+		pdf.CellFormat(cols[3].w, rowH, fmt.Sprintf("%.2f", r.MaterialCost), "", 0, "R", false, 0, "")
+
+		pdf.SetFont("Helvetica", "B", 8)
+		text(pdf, cDark)
+		pdf.SetXY(cols[4].x, y)
+		pdf.CellFormat(cols[4].w, rowH, fmt.Sprintf("%.2f", r.Margin), "", 0, "R", false, 0, "")
+
+		barColor := cAccent
+		if r.MarginPercent < 0 {
+			barColor = color{239, 68, 68}
+		}
+		progressBar(pdf, cols[5].x, y, barMaxW, math.Max(r.MarginPercent, 0), barColor)
+		y += rowH
+	}
+
+	renderFooter(pdf)
+	return pdfBytes(pdf)
+}
+
 func pdfBytes(pdf *gofpdf.Fpdf) ([]byte, error) {
 	var buf bytes.Buffer
 	if err := pdf.Output(&buf); err != nil {
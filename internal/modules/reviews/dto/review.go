@@ -0,0 +1,65 @@
+package dto
+
+type ReviewReplyRequest struct {
+	Reply string `json:"reply"`
+}
+
+type FlagReviewRequest struct {
+	Reason string `json:"reason"`
+}
+
+type ModerateReviewRequest struct {
+	Note string `json:"note"`
+}
+
+type ClinicReviewResponse struct {
+	Id         string `json:"id"`
+	ClinicId   string `json:"clinic_id"`
+	ClinicName string `json:"clinic_name,omitempty"`
+	AuthorName string `json:"author_name"`
+	Rating     int    `json:"rating"`
+	Comment    string `json:"comment"`
+	CreatedAt  string `json:"created_at"`
+	Reply      string `json:"reply,omitempty"`
+	RepliedAt  string `json:"replied_at,omitempty"`
+}
+
+// ModerationReviewResponse is the review as seen in the moderation queue.
+type ModerationReviewResponse struct {
+	ClinicReviewResponse
+	Status         string `json:"status"`
+	FlagReason     string `json:"flag_reason,omitempty"`
+	FlaggedAt      string `json:"flagged_at,omitempty"`
+	ModerationNote string `json:"moderation_note,omitempty"`
+	ModeratedAt    string `json:"moderated_at,omitempty"`
+}
+
+type DoctorReviewResponse struct {
+	Id         string `json:"id"`
+	DoctorId   string `json:"doctor_id"`
+	AuthorName string `json:"author_name"`
+	Rating     int    `json:"rating"`
+	Comment    string `json:"comment,omitempty"`
+	CreatedAt  string `json:"created_at"`
+}
+
+type ClinicReviewPage struct {
+	Items []ClinicReviewResponse `json:"items"`
+	Page  int                    `json:"page"`
+	Limit int                    `json:"limit"`
+	Total int                    `json:"total"`
+}
+
+type DoctorReviewPage struct {
+	Items []DoctorReviewResponse `json:"items"`
+	Page  int                    `json:"page"`
+	Limit int                    `json:"limit"`
+	Total int                    `json:"total"`
+}
+
+type ModerationQueuePage struct {
+	Items []ModerationReviewResponse `json:"items"`
+	Page  int                        `json:"page"`
+	Limit int                        `json:"limit"`
+	Total int                        `json:"total"`
+}
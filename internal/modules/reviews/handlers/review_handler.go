@@ -0,0 +1,310 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"dental_clinic/internal/config"
+	"dental_clinic/internal/modules/reviews/dto"
+	"dental_clinic/internal/modules/reviews/models"
+	"dental_clinic/internal/modules/reviews/services"
+	"dental_clinic/internal/utils"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+type ReviewHandler struct {
+	service *services.ReviewService
+	cfg     config.Config
+}
+
+func NewReviewHandler(service *services.ReviewService, cfg config.Config) *ReviewHandler {
+	return &ReviewHandler{service: service, cfg: cfg}
+}
+
+// GetClinicReviews godoc
+// @Summary Get clinic reviews
+// @Description Returns published reviews of a clinic, newest first, with the clinic's official replies
+// @Tags Reviews
+// @Produce json
+// @Param id path string true "Clinic ID"
+// @Param page query int false "Page number, starting at 1"
+// @Param limit query int false "Page size, up to 100"
+// @Success 200 {object} dto.ClinicReviewPage
+// @Failure 400 {object} map[string]string
+// @Router /api/clinics/{id}/reviews [get]
+func (h *ReviewHandler) GetClinicReviews(w http.ResponseWriter, r *http.Request) {
+	page, limit := pagination(r)
+	reviews, total, err := h.service.GetClinicReviews(mux.Vars(r)["id"], page, limit)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	items := make([]dto.ClinicReviewResponse, 0, len(reviews))
+	for _, review := range reviews {
+		items = append(items, toClinicReviewResponse(review))
+	}
+	respondJSON(w, http.StatusOK, dto.ClinicReviewPage{Items: items, Page: page, Limit: limit, Total: total})
+}
+
+// GetDoctorReviews godoc
+// @Summary Get doctor reviews
+// @Description Returns published ratings of a doctor, newest first, together with the comment left for the visit
+// @Tags Reviews
+// @Produce json
+// @Param id path string true "Doctor ID"
+// @Param page query int false "Page number, starting at 1"
+// @Param limit query int false "Page size, up to 100"
+// @Success 200 {object} dto.DoctorReviewPage
+// @Failure 400 {object} map[string]string
+// @Router /api/doctors/{id}/reviews [get]
+func (h *ReviewHandler) GetDoctorReviews(w http.ResponseWriter, r *http.Request) {
+	page, limit := pagination(r)
+	ratings, total, err := h.service.GetDoctorReviews(mux.Vars(r)["id"], page, limit)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	items := make([]dto.DoctorReviewResponse, 0, len(ratings))
+	for _, rating := range ratings {
+		items = append(items, dto.DoctorReviewResponse{
+			Id:         rating.Id.String(),
+			DoctorId:   rating.DoctorId.String(),
+			AuthorName: rating.AuthorName,
+			Rating:     rating.Rating,
+			Comment:    rating.Comment,
+			CreatedAt:  rating.CreatedAt.Format(time.RFC3339),
+		})
+	}
+	respondJSON(w, http.StatusOK, dto.DoctorReviewPage{Items: items, Page: page, Limit: limit, Total: total})
+}
+
+// GetModerationQueue godoc
+// @Summary Get review moderation queue
+// @Description Returns reviews in the given moderation status (flagged by default). Admins see all clinics, clinic admins only their own.
+// @Tags Reviews
+// @Security BearerAuth
+// @Produce json
+// @Param status query string false "published, flagged or hidden"
+// @Param page query int false "Page number, starting at 1"
+// @Param limit query int false "Page size, up to 100"
+// @Success 200 {object} dto.ModerationQueuePage
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /api/reviews/moderation [get]
+func (h *ReviewHandler) GetModerationQueue(w http.ResponseWriter, r *http.Request) {
+	userId, role := h.currentUser(r)
+	page, limit := pagination(r)
+	reviews, total, err := h.service.GetModerationQueue(userId, role, r.URL.Query().Get("status"), page, limit)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+
+	items := make([]dto.ModerationReviewResponse, 0, len(reviews))
+	for _, review := range reviews {
+		items = append(items, toModerationReviewResponse(review))
+	}
+	respondJSON(w, http.StatusOK, dto.ModerationQueuePage{Items: items, Page: page, Limit: limit, Total: total})
+}
+
+// FlagReview godoc
+// @Summary Flag review
+// @Description Clinic admin reports an abusive review of their clinic for moderation
+// @Tags Reviews
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Review ID"
+// @Param request body dto.FlagReviewRequest true "Reason"
+// @Success 200 {object} dto.ModerationReviewResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /api/reviews/{id}/flag [post]
+func (h *ReviewHandler) FlagReview(w http.ResponseWriter, r *http.Request) {
+	var req dto.FlagReviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	defer r.Body.Close()
+
+	userId, role := h.currentUser(r)
+	review, err := h.service.FlagReview(userId, role, mux.Vars(r)["id"], req.Reason)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, toModerationReviewResponse(*review))
+}
+
+// HideReview godoc
+// @Summary Hide review
+// @Description Admin hides a review; it disappears from listings and rating averages
+// @Tags Reviews
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Review ID"
+// @Param request body dto.ModerateReviewRequest false "Moderation note"
+// @Success 200 {object} dto.ModerationReviewResponse
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/reviews/{id}/hide [post]
+func (h *ReviewHandler) HideReview(w http.ResponseWriter, r *http.Request) {
+	h.moderate(w, r, h.service.HideReview)
+}
+
+// RestoreReview godoc
+// @Summary Restore review
+// @Description Admin publishes a hidden review again or dismisses a flag
+// @Tags Reviews
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Review ID"
+// @Param request body dto.ModerateReviewRequest false "Moderation note"
+// @Success 200 {object} dto.ModerationReviewResponse
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/reviews/{id}/restore [post]
+func (h *ReviewHandler) RestoreReview(w http.ResponseWriter, r *http.Request) {
+	h.moderate(w, r, h.service.RestoreReview)
+}
+
+func (h *ReviewHandler) moderate(w http.ResponseWriter, r *http.Request, action func(userId, role, id, note string) (*models.ClinicReview, error)) {
+	var req dto.ModerateReviewRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+	defer r.Body.Close()
+
+	userId, role := h.currentUser(r)
+	review, err := action(userId, role, mux.Vars(r)["id"], req.Note)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, toModerationReviewResponse(*review))
+}
+
+// ReplyToReview godoc
+// @Summary Reply to review
+// @Description Clinic admin posts or replaces the clinic's official reply. An empty reply removes it.
+// @Tags Reviews
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Review ID"
+// @Param request body dto.ReviewReplyRequest true "Reply"
+// @Success 200 {object} dto.ClinicReviewResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /api/reviews/{id}/reply [put]
+func (h *ReviewHandler) ReplyToReview(w http.ResponseWriter, r *http.Request) {
+	var req dto.ReviewReplyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	defer r.Body.Close()
+
+	userId, role := h.currentUser(r)
+	review, err := h.service.ReplyToReview(userId, role, mux.Vars(r)["id"], req.Reply)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, toClinicReviewResponse(*review))
+}
+
+func (h *ReviewHandler) currentUser(r *http.Request) (string, string) {
+	claims, err := utils.GetClaims(utils.GetToken(r), h.cfg.JWTSecret)
+	if err != nil {
+		return "", ""
+	}
+	userId, _ := claims["user_id"].(string)
+	role, _ := claims["role"].(string)
+	return userId, role
+}
+
+func pagination(r *http.Request) (int, int) {
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit < 1 {
+		limit = defaultPageLimit
+	}
+	if limit > maxPageLimit {
+		limit = maxPageLimit
+	}
+	return page, limit
+}
+
+func errorStatus(err error) int {
+	switch err.Error() {
+	case "do not have rights":
+		return http.StatusForbidden
+	case "review not found":
+		return http.StatusNotFound
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+func respondJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+func respondError(w http.ResponseWriter, status int, message string) {
+	respondJSON(w, status, map[string]string{"error": message})
+}
+
+func toClinicReviewResponse(review models.ClinicReview) dto.ClinicReviewResponse {
+	response := dto.ClinicReviewResponse{
+		Id:         review.Id.String(),
+		ClinicId:   review.ClinicId.String(),
+		ClinicName: review.ClinicName,
+		AuthorName: review.AuthorName,
+		Rating:     review.Rating,
+		Comment:    review.Comment,
+		CreatedAt:  review.CreatedAt.Format(time.RFC3339),
+		Reply:      review.Reply,
+	}
+	if review.RepliedAt.Valid {
+		response.RepliedAt = review.RepliedAt.Time.Format(time.RFC3339)
+	}
+	return response
+}
+
+func toModerationReviewResponse(review models.ClinicReview) dto.ModerationReviewResponse {
+	response := dto.ModerationReviewResponse{
+		ClinicReviewResponse: toClinicReviewResponse(review),
+		Status:               review.Status,
+		FlagReason:           review.FlagReason,
+		ModerationNote:       review.ModerationNote,
+	}
+	if review.FlaggedAt.Valid {
+		response.FlaggedAt = review.FlaggedAt.Time.Format(time.RFC3339)
+	}
+	if review.ModeratedAt.Valid {
+		response.ModeratedAt = review.ModeratedAt.Time.Format(time.RFC3339)
+	}
+	return response
+}
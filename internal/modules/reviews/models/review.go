@@ -1,6 +1,7 @@
 package models
 
 import (
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
@@ -12,7 +13,11 @@ type DoctorRating struct {
 	DoctorId      uuid.UUID
 	UserId        uuid.UUID
 	Rating        int
+	Comment       string
 	CreatedAt     time.Time
+
+	// filled when listing
+	AuthorName string
 }
 
 type ClinicReview struct {
@@ -23,4 +28,58 @@ type ClinicReview struct {
 	Rating        int
 	Comment       string
 	CreatedAt     time.Time
+
+	Status         string
+	FlagReason     string
+	FlaggedBy      uuid.UUID
+	FlaggedAt      sql.NullTime
+	ModerationNote string
+	ModeratedBy    uuid.UUID
+	ModeratedAt    sql.NullTime
+	Reply          string
+	ReplyBy        uuid.UUID
+	RepliedAt      sql.NullTime
+
+	AuthorName string
+	ClinicName string
+}
+
+// ReviewInput is what a patient submits about a visit. Dimensions maps an
+// aspect such as "cleanliness" to a 1-5 score.
+type ReviewInput struct {
+	DoctorRating  int
+	DoctorComment string
+	ClinicRating  int
+	ClinicComment string
+	Dimensions    map[string]int
+}
+
+type DimensionScore struct {
+	Id            uuid.UUID
+	AppointmentId uuid.UUID
+	Dimension     string
+	DoctorId      uuid.UUID
+	ClinicId      uuid.UUID
+	Score         int
+}
+
+// AppointmentReview is the complete review of one visit.
+type AppointmentReview struct {
+	AppointmentId uuid.UUID
+	UserId        uuid.UUID
+	DoctorId      uuid.UUID
+	ClinicId      uuid.UUID
+	ReviewInput
+	CreatedAt time.Time
+	UpdatedAt sql.NullTime
+	Edits     []ReviewEdit
+}
+
+// ReviewEdit is the version of a review that an edit replaced.
+type ReviewEdit struct {
+	Id            uuid.UUID
+	AppointmentId uuid.UUID
+	UserId        uuid.UUID
+	ReviewInput
+	EditedAt time.Time
 }
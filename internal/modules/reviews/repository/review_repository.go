@@ -2,9 +2,12 @@ package repository
 
 import (
 	"context"
+	"fmt"
+	"strconv"
 
 	"dental_clinic/internal/modules/reviews/models"
 
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -12,6 +15,27 @@ import (
 type ReviewRepository interface {
 	CreateDoctorRatingTx(rating *models.DoctorRating, tx pgx.Tx) error
 	CreateClinicReviewTx(review *models.ClinicReview, tx pgx.Tx) error
+
+	GetClinicReviews(clinicId uuid.UUID, limit, offset int) ([]models.ClinicReview, int, error)
+	GetDoctorReviews(doctorId uuid.UUID, limit, offset int) ([]models.DoctorRating, int, error)
+	GetModerationQueue(status string, clinicAdminUserId uuid.UUID, limit, offset int) ([]models.ClinicReview, int, error)
+	GetClinicReviewByID(id uuid.UUID) (*models.ClinicReview, error)
+
+	IsClinicAdmin(clinicId, userId uuid.UUID) (bool, error)
+	FlagClinicReview(id, userId uuid.UUID, reason string) error
+	SetReviewStatus(id uuid.UUID, status string, moderatorId uuid.UUID, note string) error
+	SetReply(id, userId uuid.UUID, reply string) error
+
+	IncrementDoctorStatsTx(doctorId uuid.UUID, rating int, tx pgx.Tx) error
+	IncrementClinicStatsTx(clinicId uuid.UUID, rating int, tx pgx.Tx) error
+	RefreshDoctorStatsTx(doctorId uuid.UUID, tx pgx.Tx) error
+	RefreshClinicStatsTx(clinicId uuid.UUID, tx pgx.Tx) error
+	RefreshRatingStats(ctx context.Context) error
+
+	GetAppointmentReview(appointmentId uuid.UUID) (*models.AppointmentReview, error)
+	UpdateAppointmentReviewTx(appointmentId uuid.UUID, input models.ReviewInput, tx pgx.Tx) error
+	ReplaceDimensionScoresTx(appointmentId uuid.UUID, scores []models.DimensionScore, tx pgx.Tx) error
+	CreateReviewEditTx(edit *models.ReviewEdit, tx pgx.Tx) error
 }
 
 type reviewRepo struct {
@@ -24,8 +48,8 @@ func NewReviewRepository(db *pgxpool.Pool) ReviewRepository {
 
 func (r *reviewRepo) CreateDoctorRatingTx(rating *models.DoctorRating, tx pgx.Tx) error {
 	query := `
-		INSERT INTO doctor_ratings (id, appointment_id, doctor_id, user_id, rating, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO doctor_ratings (id, appointment_id, doctor_id, user_id, rating, comment, created_at)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7)
 	`
 	_, err := tx.Exec(
 		context.Background(),
@@ -35,6 +59,7 @@ func (r *reviewRepo) CreateDoctorRatingTx(rating *models.DoctorRating, tx pgx.Tx
 		rating.DoctorId,
 		rating.UserId,
 		rating.Rating,
+		rating.Comment,
 		rating.CreatedAt,
 	)
 	return err
@@ -58,3 +83,520 @@ func (r *reviewRepo) CreateClinicReviewTx(review *models.ClinicReview, tx pgx.Tx
 	)
 	return err
 }
+
+const clinicReviewColumns = `
+	cr.id,
+	COALESCE(cr.appointment_id, '00000000-0000-0000-0000-000000000000'::uuid),
+	cr.clinic_id,
+	COALESCE(cr.user_id, '00000000-0000-0000-0000-000000000000'::uuid),
+	cr.rating,
+	COALESCE(cr.comment, ''),
+	cr.created_at,
+	cr.status,
+	COALESCE(cr.flag_reason, ''),
+	COALESCE(cr.flagged_by, '00000000-0000-0000-0000-000000000000'::uuid),
+	cr.flagged_at,
+	COALESCE(cr.moderation_note, ''),
+	COALESCE(cr.moderated_by, '00000000-0000-0000-0000-000000000000'::uuid),
+	cr.moderated_at,
+	COALESCE(cr.reply, ''),
+	COALESCE(cr.reply_by, '00000000-0000-0000-0000-000000000000'::uuid),
+	cr.replied_at,
+	split_part(COALESCE(u.name, ''), ' ', 1),
+	COALESCE(c.name, '')
+`
+
+const clinicReviewJoins = `
+	FROM clinic_reviews cr
+	LEFT JOIN users u ON u.id = cr.user_id
+	LEFT JOIN clinics c ON c.id = cr.clinic_id
+`
+
+func scanClinicReview(row pgx.Row, review *models.ClinicReview) error {
+	return row.Scan(
+		&review.Id,
+		&review.AppointmentId,
+		&review.ClinicId,
+		&review.UserId,
+		&review.Rating,
+		&review.Comment,
+		&review.CreatedAt,
+		&review.Status,
+		&review.FlagReason,
+		&review.FlaggedBy,
+		&review.FlaggedAt,
+		&review.ModerationNote,
+		&review.ModeratedBy,
+		&review.ModeratedAt,
+		&review.Reply,
+		&review.ReplyBy,
+		&review.RepliedAt,
+		&review.AuthorName,
+		&review.ClinicName,
+	)
+}
+
+func (r *reviewRepo) listClinicReviews(where string, args []any, limit, offset int) ([]models.ClinicReview, int, error) {
+	var total int
+	countQuery := `SELECT COUNT(*) FROM clinic_reviews cr ` + where
+	if err := r.db.QueryRow(context.Background(), countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	n := len(args)
+	query := `SELECT ` + clinicReviewColumns + clinicReviewJoins + where + `
+		ORDER BY cr.created_at DESC, cr.id
+		LIMIT $` + strconv.Itoa(n+1) + ` OFFSET $` + strconv.Itoa(n+2)
+
+	rows, err := r.db.Query(context.Background(), query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	reviews := make([]models.ClinicReview, 0)
+	for rows.Next() {
+		var review models.ClinicReview
+		if err := scanClinicReview(rows, &review); err != nil {
+			return nil, 0, err
+		}
+		reviews = append(reviews, review)
+	}
+	return reviews, total, rows.Err()
+}
+
+func (r *reviewRepo) GetClinicReviews(clinicId uuid.UUID, limit, offset int) ([]models.ClinicReview, int, error) {
+	return r.listClinicReviews(`WHERE cr.clinic_id = $1 AND cr.status <> 'hidden'`, []any{clinicId}, limit, offset)
+}
+
+// GetModerationQueue lists reviews in the given status. When
+// clinicAdminUserId is set only reviews of the clinics that user manages are
+// returned.
+func (r *reviewRepo) GetModerationQueue(status string, clinicAdminUserId uuid.UUID, limit, offset int) ([]models.ClinicReview, int, error) {
+	where := `WHERE cr.status = $1`
+	args := []any{status}
+	if clinicAdminUserId != uuid.Nil {
+		where += ` AND EXISTS (SELECT 1 FROM clinic_admins cad WHERE cad.clinic_id = cr.clinic_id AND cad.user_id = $2)`
+		args = append(args, clinicAdminUserId)
+	}
+	return r.listClinicReviews(where, args, limit, offset)
+}
+
+func (r *reviewRepo) GetDoctorReviews(doctorId uuid.UUID, limit, offset int) ([]models.DoctorRating, int, error) {
+	var total int
+	countQuery := `SELECT COUNT(*) FROM doctor_ratings WHERE doctor_id = $1 AND status <> 'hidden'`
+	if err := r.db.QueryRow(context.Background(), countQuery, doctorId).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT
+			dr.id,
+			COALESCE(dr.appointment_id, '00000000-0000-0000-0000-000000000000'::uuid),
+			dr.doctor_id,
+			COALESCE(dr.user_id, '00000000-0000-0000-0000-000000000000'::uuid),
+			dr.rating,
+			dr.created_at,
+			split_part(COALESCE(u.name, ''), ' ', 1),
+			COALESCE(NULLIF(dr.comment, ''), cr.comment, '')
+		FROM doctor_ratings dr
+		LEFT JOIN users u ON u.id = dr.user_id
+		LEFT JOIN clinic_reviews cr ON cr.appointment_id = dr.appointment_id AND cr.status <> 'hidden'
+		WHERE dr.doctor_id = $1 AND dr.status <> 'hidden'
+		ORDER BY dr.created_at DESC, dr.id
+		LIMIT $2 OFFSET $3
+	`
+	rows, err := r.db.Query(context.Background(), query, doctorId, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	ratings := make([]models.DoctorRating, 0)
+	for rows.Next() {
+		var rating models.DoctorRating
+		if err := rows.Scan(&rating.Id, &rating.AppointmentId, &rating.DoctorId, &rating.UserId, &rating.Rating, &rating.CreatedAt, &rating.AuthorName, &rating.Comment); err != nil {
+			return nil, 0, err
+		}
+		ratings = append(ratings, rating)
+	}
+	return ratings, total, rows.Err()
+}
+
+func (r *reviewRepo) GetClinicReviewByID(id uuid.UUID) (*models.ClinicReview, error) {
+	query := `SELECT ` + clinicReviewColumns + clinicReviewJoins + ` WHERE cr.id = $1`
+
+	review := &models.ClinicReview{}
+	if err := scanClinicReview(r.db.QueryRow(context.Background(), query, id), review); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return review, nil
+}
+
+func (r *reviewRepo) IsClinicAdmin(clinicId, userId uuid.UUID) (bool, error) {
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM clinic_admins WHERE clinic_id = $1 AND user_id = $2)`
+	err := r.db.QueryRow(context.Background(), query, clinicId, userId).Scan(&exists)
+	return exists, err
+}
+
+func (r *reviewRepo) FlagClinicReview(id, userId uuid.UUID, reason string) error {
+	query := `
+		UPDATE clinic_reviews
+		SET status = 'flagged', flag_reason = $3, flagged_by = $2, flagged_at = NOW()
+		WHERE id = $1 AND status = 'published'
+	`
+	result, err := r.db.Exec(context.Background(), query, id, userId, reason)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// SetReviewStatus applies a moderation decision to the clinic review and the
+// doctor rating left for the same visit, then recomputes the aggregates of
+// the affected clinic and doctor.
+func (r *reviewRepo) SetReviewStatus(id uuid.UUID, status string, moderatorId uuid.UUID, note string) error {
+	ctx := context.Background()
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var clinicId, appointmentId uuid.UUID
+	query := `
+		UPDATE clinic_reviews
+		SET status = $2, moderated_by = $3, moderated_at = NOW(), moderation_note = NULLIF($4, '')
+		WHERE id = $1
+		RETURNING clinic_id, COALESCE(appointment_id, '00000000-0000-0000-0000-000000000000'::uuid)
+	`
+	if err := tx.QueryRow(ctx, query, id, status, moderatorId, note).Scan(&clinicId, &appointmentId); err != nil {
+		return err
+	}
+
+	doctorIds := make([]uuid.UUID, 0, 1)
+	if appointmentId != uuid.Nil {
+		rows, err := tx.Query(ctx, `UPDATE doctor_ratings SET status = $2 WHERE appointment_id = $1 RETURNING doctor_id`, appointmentId, status)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var doctorId uuid.UUID
+			if err := rows.Scan(&doctorId); err != nil {
+				rows.Close()
+				return err
+			}
+			doctorIds = append(doctorIds, doctorId)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(ctx, clinicStatsRefreshQuery, clinicId); err != nil {
+		return err
+	}
+	for _, doctorId := range doctorIds {
+		if _, err := tx.Exec(ctx, doctorStatsRefreshQuery, doctorId); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *reviewRepo) SetReply(id, userId uuid.UUID, reply string) error {
+	query := `
+		UPDATE clinic_reviews
+		SET reply = NULLIF($3, ''),
+			reply_by = CASE WHEN $3 = '' THEN NULL ELSE $2::uuid END,
+			replied_at = CASE WHEN $3 = '' THEN NULL ELSE NOW() END
+		WHERE id = $1
+	`
+	result, err := r.db.Exec(context.Background(), query, id, userId, reply)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *reviewRepo) IncrementDoctorStatsTx(doctorId uuid.UUID, rating int, tx pgx.Tx) error {
+	_, err := tx.Exec(context.Background(), doctorStatsIncrementQuery, doctorId, rating)
+	return err
+}
+
+func (r *reviewRepo) IncrementClinicStatsTx(clinicId uuid.UUID, rating int, tx pgx.Tx) error {
+	_, err := tx.Exec(context.Background(), clinicStatsIncrementQuery, clinicId, rating)
+	return err
+}
+
+func (r *reviewRepo) RefreshDoctorStatsTx(doctorId uuid.UUID, tx pgx.Tx) error {
+	_, err := tx.Exec(context.Background(), doctorStatsRefreshQuery, doctorId)
+	return err
+}
+
+func (r *reviewRepo) RefreshClinicStatsTx(clinicId uuid.UUID, tx pgx.Tx) error {
+	_, err := tx.Exec(context.Background(), clinicStatsRefreshQuery, clinicId)
+	return err
+}
+
+// RefreshRatingStats recomputes every aggregate, which keeps the rolling
+// recent and previous windows current.
+func (r *reviewRepo) RefreshRatingStats(ctx context.Context) error {
+	if _, err := r.db.Exec(ctx, doctorStatsRefreshQuery, nil); err != nil {
+		return err
+	}
+	_, err := r.db.Exec(ctx, clinicStatsRefreshQuery, nil)
+	return err
+}
+
+var (
+	doctorStatsIncrementQuery = statsIncrementQuery("doctor_rating_stats", "doctor_id")
+	clinicStatsIncrementQuery = statsIncrementQuery("clinic_rating_stats", "clinic_id")
+	doctorStatsRefreshQuery   = statsRefreshQuery("doctor_rating_stats", "doctor_id", "doctors", "doctor_ratings")
+	clinicStatsRefreshQuery   = statsRefreshQuery("clinic_rating_stats", "clinic_id", "clinics", "clinic_reviews")
+)
+
+// statsIncrementQuery adds a single new rating ($2) to the aggregate of $1.
+func statsIncrementQuery(table, key string) string {
+	return fmt.Sprintf(`
+		INSERT INTO %[1]s (%[2]s, rating_count, rating_sum, count_1, count_2, count_3, count_4, count_5, recent_count, recent_sum, updated_at)
+		VALUES ($1, 1, $2, ($2 = 1)::int, ($2 = 2)::int, ($2 = 3)::int, ($2 = 4)::int, ($2 = 5)::int, 1, $2, NOW())
+		ON CONFLICT (%[2]s) DO UPDATE SET
+			rating_count = %[1]s.rating_count + 1,
+			rating_sum = %[1]s.rating_sum + EXCLUDED.rating_sum,
+			count_1 = %[1]s.count_1 + EXCLUDED.count_1,
+			count_2 = %[1]s.count_2 + EXCLUDED.count_2,
+			count_3 = %[1]s.count_3 + EXCLUDED.count_3,
+			count_4 = %[1]s.count_4 + EXCLUDED.count_4,
+			count_5 = %[1]s.count_5 + EXCLUDED.count_5,
+			recent_count = %[1]s.recent_count + 1,
+			recent_sum = %[1]s.recent_sum + EXCLUDED.recent_sum,
+			updated_at = NOW()
+	`, table, key)
+}
+
+// statsRefreshQuery recomputes the aggregate of entity $1 from the visible
+// ratings, or of every entity when $1 is NULL.
+func statsRefreshQuery(table, key, entityTable, ratingTable string) string {
+	return fmt.Sprintf(`
+		INSERT INTO %[1]s (%[2]s, rating_count, rating_sum, count_1, count_2, count_3, count_4, count_5, recent_count, recent_sum, previous_count, previous_sum, updated_at)
+		SELECT
+			e.id,
+			COUNT(r.id),
+			COALESCE(SUM(r.rating), 0),
+			COUNT(r.id) FILTER (WHERE r.rating = 1),
+			COUNT(r.id) FILTER (WHERE r.rating = 2),
+			COUNT(r.id) FILTER (WHERE r.rating = 3),
+			COUNT(r.id) FILTER (WHERE r.rating = 4),
+			COUNT(r.id) FILTER (WHERE r.rating = 5),
+			COUNT(r.id) FILTER (WHERE r.created_at >= NOW() - INTERVAL '90 days'),
+			COALESCE(SUM(r.rating) FILTER (WHERE r.created_at >= NOW() - INTERVAL '90 days'), 0),
+			COUNT(r.id) FILTER (WHERE r.created_at >= NOW() - INTERVAL '180 days' AND r.created_at < NOW() - INTERVAL '90 days'),
+			COALESCE(SUM(r.rating) FILTER (WHERE r.created_at >= NOW() - INTERVAL '180 days' AND r.created_at < NOW() - INTERVAL '90 days'), 0),
+			NOW()
+		FROM %[3]s e
+		LEFT JOIN %[4]s r ON r.%[2]s = e.id AND r.status <> 'hidden'
+		WHERE $1::uuid IS NULL OR e.id = $1::uuid
+		GROUP BY e.id
+		ON CONFLICT (%[2]s) DO UPDATE SET
+			rating_count = EXCLUDED.rating_count,
+			rating_sum = EXCLUDED.rating_sum,
+			count_1 = EXCLUDED.count_1,
+			count_2 = EXCLUDED.count_2,
+			count_3 = EXCLUDED.count_3,
+			count_4 = EXCLUDED.count_4,
+			count_5 = EXCLUDED.count_5,
+			recent_count = EXCLUDED.recent_count,
+			recent_sum = EXCLUDED.recent_sum,
+			previous_count = EXCLUDED.previous_count,
+			previous_sum = EXCLUDED.previous_sum,
+			updated_at = NOW()
+	`, table, key, entityTable, ratingTable)
+}
+
+// GetAppointmentReview loads the current review of a visit with its
+// dimension scores and edit history.
+func (r *reviewRepo) GetAppointmentReview(appointmentId uuid.UUID) (*models.AppointmentReview, error) {
+	query := `
+		SELECT
+			cr.appointment_id,
+			COALESCE(cr.user_id, '00000000-0000-0000-0000-000000000000'::uuid),
+			COALESCE(dr.doctor_id, '00000000-0000-0000-0000-000000000000'::uuid),
+			cr.clinic_id,
+			COALESCE(dr.rating, 0),
+			COALESCE(dr.comment, ''),
+			cr.rating,
+			COALESCE(cr.comment, ''),
+			cr.created_at,
+			cr.updated_at
+		FROM clinic_reviews cr
+		LEFT JOIN doctor_ratings dr ON dr.appointment_id = cr.appointment_id
+		WHERE cr.appointment_id = $1
+	`
+	review := &models.AppointmentReview{}
+	err := r.db.QueryRow(context.Background(), query, appointmentId).Scan(
+		&review.AppointmentId,
+		&review.UserId,
+		&review.DoctorId,
+		&review.ClinicId,
+		&review.DoctorRating,
+		&review.DoctorComment,
+		&review.ClinicRating,
+		&review.ClinicComment,
+		&review.CreatedAt,
+		&review.UpdatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	review.Dimensions, err = r.getDimensionScores(appointmentId)
+	if err != nil {
+		return nil, err
+	}
+	review.Edits, err = r.getReviewEdits(appointmentId)
+	if err != nil {
+		return nil, err
+	}
+	return review, nil
+}
+
+func (r *reviewRepo) getDimensionScores(appointmentId uuid.UUID) (map[string]int, error) {
+	rows, err := r.db.Query(context.Background(), `SELECT dimension, score FROM review_dimension_scores WHERE appointment_id = $1`, appointmentId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	scores := make(map[string]int)
+	for rows.Next() {
+		var dimension string
+		var score int
+		if err := rows.Scan(&dimension, &score); err != nil {
+			return nil, err
+		}
+		scores[dimension] = score
+	}
+	return scores, rows.Err()
+}
+
+func (r *reviewRepo) getReviewEdits(appointmentId uuid.UUID) ([]models.ReviewEdit, error) {
+	query := `
+		SELECT
+			id,
+			appointment_id,
+			COALESCE(user_id, '00000000-0000-0000-0000-000000000000'::uuid),
+			COALESCE(doctor_rating, 0),
+			COALESCE(doctor_comment, ''),
+			COALESCE(clinic_rating, 0),
+			COALESCE(clinic_comment, ''),
+			dimensions,
+			edited_at
+		FROM review_edits
+		WHERE appointment_id = $1
+		ORDER BY edited_at DESC
+	`
+	rows, err := r.db.Query(context.Background(), query, appointmentId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	edits := make([]models.ReviewEdit, 0)
+	for rows.Next() {
+		var edit models.ReviewEdit
+		if err := rows.Scan(
+			&edit.Id,
+			&edit.AppointmentId,
+			&edit.UserId,
+			&edit.DoctorRating,
+			&edit.DoctorComment,
+			&edit.ClinicRating,
+			&edit.ClinicComment,
+			&edit.Dimensions,
+			&edit.EditedAt,
+		); err != nil {
+			return nil, err
+		}
+		edits = append(edits, edit)
+	}
+	return edits, rows.Err()
+}
+
+func (r *reviewRepo) UpdateAppointmentReviewTx(appointmentId uuid.UUID, input models.ReviewInput, tx pgx.Tx) error {
+	result, err := tx.Exec(
+		context.Background(),
+		`UPDATE clinic_reviews SET rating = $2, comment = $3, updated_at = NOW() WHERE appointment_id = $1`,
+		appointmentId,
+		input.ClinicRating,
+		input.ClinicComment,
+	)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	_, err = tx.Exec(
+		context.Background(),
+		`UPDATE doctor_ratings SET rating = $2, comment = NULLIF($3, ''), updated_at = NOW() WHERE appointment_id = $1`,
+		appointmentId,
+		input.DoctorRating,
+		input.DoctorComment,
+	)
+	return err
+}
+
+func (r *reviewRepo) ReplaceDimensionScoresTx(appointmentId uuid.UUID, scores []models.DimensionScore, tx pgx.Tx) error {
+	if _, err := tx.Exec(context.Background(), `DELETE FROM review_dimension_scores WHERE appointment_id = $1`, appointmentId); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO review_dimension_scores (id, appointment_id, dimension, doctor_id, clinic_id, score, created_at, updated_at)
+		VALUES ($1, $2, $3, NULLIF($4, '00000000-0000-0000-0000-000000000000'::uuid), $5, $6, NOW(), NOW())
+	`
+	for _, score := range scores {
+		if _, err := tx.Exec(context.Background(), query, score.Id, appointmentId, score.Dimension, score.DoctorId, score.ClinicId, score.Score); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *reviewRepo) CreateReviewEditTx(edit *models.ReviewEdit, tx pgx.Tx) error {
+	query := `
+		INSERT INTO review_edits (id, appointment_id, user_id, doctor_rating, doctor_comment, clinic_rating, clinic_comment, dimensions, edited_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+	_, err := tx.Exec(
+		context.Background(),
+		query,
+		edit.Id,
+		edit.AppointmentId,
+		edit.UserId,
+		edit.DoctorRating,
+		edit.DoctorComment,
+		edit.ClinicRating,
+		edit.ClinicComment,
+		edit.Dimensions,
+		edit.EditedAt,
+	)
+	return err
+}
@@ -0,0 +1,32 @@
+package reviews
+
+import (
+	"dental_clinic/internal/config"
+	"dental_clinic/internal/modules/reviews/handlers"
+	"dental_clinic/internal/modules/reviews/repository"
+	"dental_clinic/internal/modules/reviews/services"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func RegisterPublicRoutes(r *mux.Router, db *pgxpool.Pool, cfg *config.Config) {
+	repo := repository.NewReviewRepository(db)
+	service := services.NewReviewService(repo)
+	handler := handlers.NewReviewHandler(service, *cfg)
+
+	r.HandleFunc("/clinics/{id}/reviews", handler.GetClinicReviews).Methods("GET")
+	r.HandleFunc("/doctors/{id}/reviews", handler.GetDoctorReviews).Methods("GET")
+}
+
+func RegisterPrivateRoutes(r *mux.Router, db *pgxpool.Pool, cfg *config.Config) {
+	repo := repository.NewReviewRepository(db)
+	service := services.NewReviewService(repo)
+	handler := handlers.NewReviewHandler(service, *cfg)
+
+	r.HandleFunc("/reviews/moderation", handler.GetModerationQueue).Methods("GET")
+	r.HandleFunc("/reviews/{id}/flag", handler.FlagReview).Methods("POST")
+	r.HandleFunc("/reviews/{id}/hide", handler.HideReview).Methods("POST")
+	r.HandleFunc("/reviews/{id}/restore", handler.RestoreReview).Methods("POST")
+	r.HandleFunc("/reviews/{id}/reply", handler.ReplyToReview).Methods("PUT")
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"errors"
+	"strings"
 	"time"
 
 	"dental_clinic/internal/modules/reviews/models"
@@ -19,12 +20,22 @@ func NewReviewService(r repository.ReviewRepository) *ReviewService {
 	return &ReviewService{repo: r}
 }
 
-func (s *ReviewService) CreateAppointmentReviewTx(appointmentId, doctorId, clinicId, userId uuid.UUID, doctorRating, clinicRating int, clinicComment string, tx pgx.Tx) error {
-	if doctorRating < 1 || doctorRating > 5 {
-		return errors.New("doctor_rating must be between 1 and 5")
-	}
-	if clinicRating < 1 || clinicRating > 5 {
-		return errors.New("clinic_rating must be between 1 and 5")
+// reviewEditWindow is how long after submitting a patient may still edit
+// their review.
+const reviewEditWindow = 14 * 24 * time.Hour
+
+// reviewDimensions lists the aspects a patient may score and whether each
+// one rates the clinic or the doctor.
+var reviewDimensions = map[string]string{
+	"cleanliness":     "clinic",
+	"wait_time":       "clinic",
+	"pain_management": "doctor",
+	"communication":   "doctor",
+}
+
+func (s *ReviewService) CreateAppointmentReviewTx(appointmentId, doctorId, clinicId, userId uuid.UUID, input models.ReviewInput, tx pgx.Tx) error {
+	if err := validateReviewInput(input); err != nil {
+		return err
 	}
 
 	now := time.Now()
@@ -33,7 +44,8 @@ func (s *ReviewService) CreateAppointmentReviewTx(appointmentId, doctorId, clini
 		AppointmentId: appointmentId,
 		DoctorId:      doctorId,
 		UserId:        userId,
-		Rating:        doctorRating,
+		Rating:        input.DoctorRating,
+		Comment:       strings.TrimSpace(input.DoctorComment),
 		CreatedAt:     now,
 	}
 	review := &models.ClinicReview{
@@ -41,13 +53,282 @@ func (s *ReviewService) CreateAppointmentReviewTx(appointmentId, doctorId, clini
 		AppointmentId: appointmentId,
 		ClinicId:      clinicId,
 		UserId:        userId,
-		Rating:        clinicRating,
-		Comment:       clinicComment,
+		Rating:        input.ClinicRating,
+		Comment:       strings.TrimSpace(input.ClinicComment),
 		CreatedAt:     now,
 	}
 
 	if err := s.repo.CreateDoctorRatingTx(rating, tx); err != nil {
 		return err
 	}
-	return s.repo.CreateClinicReviewTx(review, tx)
+	if err := s.repo.CreateClinicReviewTx(review, tx); err != nil {
+		return err
+	}
+	if err := s.repo.ReplaceDimensionScoresTx(appointmentId, dimensionScores(appointmentId, doctorId, clinicId, input.Dimensions), tx); err != nil {
+		return err
+	}
+
+	if err := s.repo.IncrementDoctorStatsTx(doctorId, input.DoctorRating, tx); err != nil {
+		return err
+	}
+	return s.repo.IncrementClinicStatsTx(clinicId, input.ClinicRating, tx)
+}
+
+// GetAppointmentReview returns the review of a visit with its edit history,
+// or nil when the visit has not been reviewed.
+func (s *ReviewService) GetAppointmentReview(appointmentId uuid.UUID) (*models.AppointmentReview, error) {
+	return s.repo.GetAppointmentReview(appointmentId)
+}
+
+// EditableUntil is the moment after which a review can no longer be edited.
+func EditableUntil(review *models.AppointmentReview) time.Time {
+	return review.CreatedAt.Add(reviewEditWindow)
+}
+
+// UpdateAppointmentReviewTx replaces the review of a visit, keeping the
+// previous version in the edit history. Only the author may edit, and only
+// within reviewEditWindow of the original submission.
+func (s *ReviewService) UpdateAppointmentReviewTx(appointmentId, userId uuid.UUID, input models.ReviewInput, tx pgx.Tx) error {
+	if err := validateReviewInput(input); err != nil {
+		return err
+	}
+
+	current, err := s.repo.GetAppointmentReview(appointmentId)
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		return errors.New("review not found")
+	}
+	if current.UserId != userId {
+		return errors.New("access denied")
+	}
+	if time.Now().After(EditableUntil(current)) {
+		return errors.New("review can no longer be edited")
+	}
+
+	edit := &models.ReviewEdit{
+		Id:            uuid.New(),
+		AppointmentId: appointmentId,
+		UserId:        userId,
+		ReviewInput:   current.ReviewInput,
+		EditedAt:      time.Now(),
+	}
+	if edit.Dimensions == nil {
+		edit.Dimensions = map[string]int{}
+	}
+	if err := s.repo.CreateReviewEditTx(edit, tx); err != nil {
+		return err
+	}
+
+	input.DoctorComment = strings.TrimSpace(input.DoctorComment)
+	input.ClinicComment = strings.TrimSpace(input.ClinicComment)
+	if err := s.repo.UpdateAppointmentReviewTx(appointmentId, input, tx); err != nil {
+		return err
+	}
+	if err := s.repo.ReplaceDimensionScoresTx(appointmentId, dimensionScores(appointmentId, current.DoctorId, current.ClinicId, input.Dimensions), tx); err != nil {
+		return err
+	}
+
+	if current.DoctorId != uuid.Nil {
+		if err := s.repo.RefreshDoctorStatsTx(current.DoctorId, tx); err != nil {
+			return err
+		}
+	}
+	return s.repo.RefreshClinicStatsTx(current.ClinicId, tx)
+}
+
+func validateReviewInput(input models.ReviewInput) error {
+	if input.DoctorRating < 1 || input.DoctorRating > 5 {
+		return errors.New("doctor_rating must be between 1 and 5")
+	}
+	if input.ClinicRating < 1 || input.ClinicRating > 5 {
+		return errors.New("clinic_rating must be between 1 and 5")
+	}
+	for dimension, score := range input.Dimensions {
+		if _, ok := reviewDimensions[dimension]; !ok {
+			return errors.New("unknown review dimension: " + dimension)
+		}
+		if score < 1 || score > 5 {
+			return errors.New(dimension + " must be between 1 and 5")
+		}
+	}
+	return nil
+}
+
+func dimensionScores(appointmentId, doctorId, clinicId uuid.UUID, dimensions map[string]int) []models.DimensionScore {
+	scores := make([]models.DimensionScore, 0, len(dimensions))
+	for dimension, score := range dimensions {
+		ds := models.DimensionScore{
+			Id:            uuid.New(),
+			AppointmentId: appointmentId,
+			Dimension:     dimension,
+			ClinicId:      clinicId,
+			Score:         score,
+		}
+		if reviewDimensions[dimension] == "doctor" {
+			ds.DoctorId = doctorId
+		}
+		scores = append(scores, ds)
+	}
+	return scores
+}
+
+// maxReplyLength keeps official replies to a reasonable size.
+const maxReplyLength = 2000
+
+var moderationStatuses = map[string]bool{
+	"published": true,
+	"flagged":   true,
+	"hidden":    true,
+}
+
+func (s *ReviewService) GetClinicReviews(clinicId string, page, limit int) ([]models.ClinicReview, int, error) {
+	clinicUUID, err := uuid.Parse(clinicId)
+	if err != nil {
+		return nil, 0, errors.New("invalid clinic id")
+	}
+	return s.repo.GetClinicReviews(clinicUUID, limit, (page-1)*limit)
+}
+
+func (s *ReviewService) GetDoctorReviews(doctorId string, page, limit int) ([]models.DoctorRating, int, error) {
+	doctorUUID, err := uuid.Parse(doctorId)
+	if err != nil {
+		return nil, 0, errors.New("invalid doctor id")
+	}
+	return s.repo.GetDoctorReviews(doctorUUID, limit, (page-1)*limit)
+}
+
+// GetModerationQueue returns reviews in the given status, flagged by default.
+// Platform admins see every clinic, clinic admins only their own.
+func (s *ReviewService) GetModerationQueue(userId, role, status string, page, limit int) ([]models.ClinicReview, int, error) {
+	if status == "" {
+		status = "flagged"
+	}
+	if !moderationStatuses[status] {
+		return nil, 0, errors.New("invalid status")
+	}
+
+	switch role {
+	case "admin":
+		return s.repo.GetModerationQueue(status, uuid.Nil, limit, (page-1)*limit)
+	case "clinic_admin":
+		userUUID, err := uuid.Parse(userId)
+		if err != nil {
+			return nil, 0, errors.New("invalid user id")
+		}
+		return s.repo.GetModerationQueue(status, userUUID, limit, (page-1)*limit)
+	default:
+		return nil, 0, errors.New("do not have rights")
+	}
+}
+
+// FlagReview puts a published review into the moderation queue. Clinic
+// admins may flag reviews of their own clinics.
+func (s *ReviewService) FlagReview(userId, role, id, reason string) (*models.ClinicReview, error) {
+	review, userUUID, err := s.getReviewForClinicStaff(userId, role, id, true)
+	if err != nil {
+		return nil, err
+	}
+
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, errors.New("reason is required")
+	}
+	if review.Status != "published" {
+		return nil, errors.New("review is already under moderation")
+	}
+
+	if err := s.repo.FlagClinicReview(review.Id, userUUID, reason); err != nil {
+		return nil, err
+	}
+	return s.repo.GetClinicReviewByID(review.Id)
+}
+
+// HideReview removes a review from listings and rating aggregates.
+func (s *ReviewService) HideReview(userId, role, id, note string) (*models.ClinicReview, error) {
+	return s.moderate(userId, role, id, "hidden", note)
+}
+
+// RestoreReview publishes a hidden review again or dismisses a flag.
+func (s *ReviewService) RestoreReview(userId, role, id, note string) (*models.ClinicReview, error) {
+	return s.moderate(userId, role, id, "published", note)
+}
+
+func (s *ReviewService) moderate(userId, role, id, status, note string) (*models.ClinicReview, error) {
+	if role != "admin" {
+		return nil, errors.New("do not have rights")
+	}
+	reviewId, err := uuid.Parse(id)
+	if err != nil {
+		return nil, errors.New("invalid review id")
+	}
+	userUUID, err := uuid.Parse(userId)
+	if err != nil {
+		return nil, errors.New("invalid user id")
+	}
+
+	if err := s.repo.SetReviewStatus(reviewId, status, userUUID, strings.TrimSpace(note)); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.New("review not found")
+		}
+		return nil, err
+	}
+	return s.repo.GetClinicReviewByID(reviewId)
+}
+
+// ReplyToReview posts or replaces the clinic's official reply. An empty
+// reply removes it.
+func (s *ReviewService) ReplyToReview(userId, role, id, reply string) (*models.ClinicReview, error) {
+	review, userUUID, err := s.getReviewForClinicStaff(userId, role, id, false)
+	if err != nil {
+		return nil, err
+	}
+
+	reply = strings.TrimSpace(reply)
+	if len([]rune(reply)) > maxReplyLength {
+		return nil, errors.New("reply is too long")
+	}
+	if review.Status == "hidden" {
+		return nil, errors.New("cannot reply to a hidden review")
+	}
+
+	if err := s.repo.SetReply(review.Id, userUUID, reply); err != nil {
+		return nil, err
+	}
+	return s.repo.GetClinicReviewByID(review.Id)
+}
+
+func (s *ReviewService) getReviewForClinicStaff(userId, role, id string, allowAdmin bool) (*models.ClinicReview, uuid.UUID, error) {
+	reviewId, err := uuid.Parse(id)
+	if err != nil {
+		return nil, uuid.Nil, errors.New("invalid review id")
+	}
+	userUUID, err := uuid.Parse(userId)
+	if err != nil {
+		return nil, uuid.Nil, errors.New("invalid user id")
+	}
+
+	review, err := s.repo.GetClinicReviewByID(reviewId)
+	if err != nil {
+		return nil, uuid.Nil, err
+	}
+	if review == nil {
+		return nil, uuid.Nil, errors.New("review not found")
+	}
+
+	if role == "admin" && allowAdmin {
+		return review, userUUID, nil
+	}
+	if role != "clinic_admin" {
+		return nil, uuid.Nil, errors.New("do not have rights")
+	}
+	isAdmin, err := s.repo.IsClinicAdmin(review.ClinicId, userUUID)
+	if err != nil {
+		return nil, uuid.Nil, err
+	}
+	if !isAdmin {
+		return nil, uuid.Nil, errors.New("do not have rights")
+	}
+	return review, userUUID, nil
 }
@@ -23,6 +23,12 @@ type GenerateSlotsRequest struct {
 	To_date   string `json:"to_date"`
 }
 
+type GenerateSlotsResponse struct {
+	Success string `json:"success"`
+	Message string `json:"message"`
+	Job_id  string `json:"job_id"`
+}
+
 type ScheduleResponse struct {
 	Success string `json:"success"`
 	Message string `json:"message"`
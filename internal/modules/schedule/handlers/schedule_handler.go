@@ -84,17 +84,17 @@ func (h *ScheduleHandler) CreateDoctorSchedule(w http.ResponseWriter, r *http.Re
 
 // GenerateSlots godoc
 // @Summary Generate new slots
-// @Description Generate a new slots
+// @Description Queues the generation of the slots between from_date and to_date. The slots are generated in the background; the response carries the id of the queued job.
 // @Tags Schedule
 // @Security BearerAuth
 // @Accept  json
 // @Produce  json
 // @Param request body dto.GenerateSlotsRequest true "Generate slots data"
-// @Success 200 {object} dto.ScheduleResponse
-// @Failure 400 {object} dto.ScheduleResponse
+// @Success 202 {object} dto.GenerateSlotsResponse
+// @Failure 400 {object} dto.GenerateSlotsResponse
 // @Router /api/schedule/generate [post]
 func (h *ScheduleHandler) GenerateSlots(w http.ResponseWriter, r *http.Request) {
-	response := dto.ScheduleResponse{
+	response := dto.GenerateSlotsResponse{
 		Success: "0",
 		Message: "",
 	}
@@ -107,7 +107,7 @@ func (h *ScheduleHandler) GenerateSlots(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	err := h.service.GenerateSlots(req)
+	jobId, err := h.service.QueueSlotGeneration(r.Context(), req)
 	if err != nil {
 		response.Message = err.Error()
 		w.WriteHeader(http.StatusBadRequest)
@@ -115,9 +115,10 @@ func (h *ScheduleHandler) GenerateSlots(w http.ResponseWriter, r *http.Request)
 		return
 	}
 	response.Success = "1"
-	response.Message = "successfully generated"
+	response.Message = "slot generation queued"
+	response.Job_id = jobId.String()
 
-	w.WriteHeader(http.StatusOK)
+	w.WriteHeader(http.StatusAccepted)
 	_ = json.NewEncoder(w).Encode(response)
 
 }
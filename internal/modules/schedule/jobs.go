@@ -0,0 +1,45 @@
+package schedule
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"dental_clinic/internal/config"
+	"dental_clinic/internal/queue"
+
+	"dental_clinic/internal/modules/schedule/dto"
+	"dental_clinic/internal/modules/schedule/repository"
+	"dental_clinic/internal/modules/schedule/services"
+
+	serviceRepository "dental_clinic/internal/modules/services/repository"
+	serviceServices "dental_clinic/internal/modules/services/services"
+
+	clinicRepository "dental_clinic/internal/modules/clinic/repository"
+	clinicServices "dental_clinic/internal/modules/clinic/services"
+
+	addressRepository "dental_clinic/internal/modules/address/repository"
+	addressServices "dental_clinic/internal/modules/address/services"
+)
+
+// RegisterJobs lets the worker generate slots and keeps the slots of the
+// next two weeks generated every night.
+func RegisterJobs(w *queue.Worker, db *pgxpool.Pool, cfg *config.Config) {
+	repo := repository.NewScheduleRepository(db)
+
+	addressRepo := addressRepository.NewAddressRepository(db)
+	addressService := addressServices.NewAddressService(addressRepo, *cfg)
+
+	clinicRepo := clinicRepository.NewClinicRepository(db)
+	clinicService := clinicServices.NewClinicService(clinicRepo, *cfg, *addressService)
+
+	serviceRepo := serviceRepository.NewServiceRepository(db)
+	serviceService := serviceServices.NewServiceService(serviceRepo, *clinicService)
+
+	service := services.NewScheduleService(repo, *cfg, *serviceService, *clinicService, queue.New(db))
+
+	queue.Handle(w, services.GenerateSlotsJob, func(ctx context.Context, req dto.GenerateSlotsRequest) error {
+		return service.RunSlotGeneration(req)
+	})
+	_ = w.Schedule("generate_slots", "0 1 * * *", queue.Job{Type: services.GenerateSlotsJob, Priority: queue.PriorityLow})
+}
@@ -45,8 +45,16 @@ func (r *scheduleRepo) Create(schedule *models.Schedule) (*models.Schedule, erro
 	return schedule, err
 }
 
+// GetSchedules returns the working hours slots are generated from: those of
+// doctors at clinics they currently work at.
 func (r *scheduleRepo) GetSchedules() ([]models.Schedule, error) {
-	query := `SELECT id, doctor_id, clinic_address_id, day_of_week, start_time, end_time FROM doctor_working_hours`
+	query := `
+		SELECT wh.id, wh.doctor_id, wh.clinic_address_id, wh.day_of_week, wh.start_time, wh.end_time
+		FROM doctor_working_hours wh
+		JOIN clinic_addresses ca ON ca.id = wh.clinic_address_id
+		JOIN doctor_clinics dc ON dc.doctor_id = wh.doctor_id AND dc.clinic_id = ca.clinic_id
+		WHERE dc.status = 'active'
+	`
 
 	rows, err := r.db.Query(context.Background(), query)
 	if err != nil {
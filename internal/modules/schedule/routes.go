@@ -8,6 +8,7 @@ import (
 	"dental_clinic/internal/modules/schedule/services"
 
 	"dental_clinic/internal/config"
+	"dental_clinic/internal/queue"
 
 	serviceRepository "dental_clinic/internal/modules/services/repository"
 	serviceServices "dental_clinic/internal/modules/services/services"
@@ -22,6 +23,8 @@ import (
 )
 
 func RegisterPublicRoutes(r *mux.Router, db *pgxpool.Pool, cfg *config.Config) {
+	jobQueue := queue.New(db)
+
 	repo := repository.NewScheduleRepository(db)
 
 	addressRepo := addressRepository.NewAddressRepository(db)
@@ -33,7 +36,7 @@ func RegisterPublicRoutes(r *mux.Router, db *pgxpool.Pool, cfg *config.Config) {
 	serviceRepo := serviceRepository.NewServiceRepository(db)
 	serviceService := serviceServices.NewServiceService(serviceRepo, *clinicService)
 
-	service := services.NewScheduleService(repo, *cfg, *serviceService, *clinicService)
+	service := services.NewScheduleService(repo, *cfg, *serviceService, *clinicService, jobQueue)
 	handler := handlers.NewScheduleHandler(service, *cfg)
 
 	scheduleRouter := r.PathPrefix("/schedule").Subrouter()
@@ -42,6 +45,8 @@ func RegisterPublicRoutes(r *mux.Router, db *pgxpool.Pool, cfg *config.Config) {
 }
 
 func RegisterPrivateRoutes(r *mux.Router, db *pgxpool.Pool, cfg *config.Config) {
+	jobQueue := queue.New(db)
+
 	repo := repository.NewScheduleRepository(db)
 
 	addressRepo := addressRepository.NewAddressRepository(db)
@@ -53,7 +58,7 @@ func RegisterPrivateRoutes(r *mux.Router, db *pgxpool.Pool, cfg *config.Config)
 	serviceRepo := serviceRepository.NewServiceRepository(db)
 	serviceService := serviceServices.NewServiceService(serviceRepo, *clinicService)
 
-	service := services.NewScheduleService(repo, *cfg, *serviceService, *clinicService)
+	service := services.NewScheduleService(repo, *cfg, *serviceService, *clinicService, jobQueue)
 	handler := handlers.NewScheduleHandler(service, *cfg)
 
 	scheduleRouter := r.PathPrefix("/schedule").Subrouter()
@@ -1,12 +1,15 @@
 package services
 
 import (
+	"context"
 	"dental_clinic/internal/config"
+	"dental_clinic/internal/queue"
 
 	"dental_clinic/internal/modules/schedule/dto"
 	"dental_clinic/internal/modules/schedule/models"
 	"dental_clinic/internal/modules/schedule/repository"
 
+	clinicModels "dental_clinic/internal/modules/clinic/models"
 	clinicServices "dental_clinic/internal/modules/clinic/services"
 	"dental_clinic/internal/modules/services/services"
 
@@ -25,14 +28,23 @@ type ScheduleService struct {
 	cfx        config.Config
 	serviceSrv services.ServiceService
 	clinicSrv  clinicServices.ClinicService
+	queue      *queue.Queue
 }
 
-func NewScheduleService(r repository.ScheduleRepository, cfx config.Config, serviceSrv services.ServiceService, clinicSrv clinicServices.ClinicService) *ScheduleService {
+// GenerateSlotsJob is the queue job that generates the slots of a date
+// range; an empty range stands for the next slotHorizonDays days.
+const GenerateSlotsJob = "schedule.generate_slots"
+
+// slotHorizonDays is how far ahead the nightly slot generation keeps slots.
+const slotHorizonDays = 14
+
+func NewScheduleService(r repository.ScheduleRepository, cfx config.Config, serviceSrv services.ServiceService, clinicSrv clinicServices.ClinicService, q *queue.Queue) *ScheduleService {
 	return &ScheduleService{
 		repo:       r,
 		cfx:        cfx,
 		serviceSrv: serviceSrv,
 		clinicSrv:  clinicSrv,
+		queue:      q,
 	}
 }
 
@@ -47,6 +59,14 @@ func (s *ScheduleService) CreateSchedule(doctor_id uuid.UUID, req dto.CreateSche
 		return nil, errors.New("invalid clinic_address_id")
 	}
 
+	if err := s.clinicSrv.CheckDoctorAtAddress(doctor_id, clinic_address_id); err != nil {
+		return nil, err
+	}
+
+	if err := s.clinicSrv.CheckWorkingHours(clinic_address_id, req.Day_of_week, req.Start_time, req.End_time); err != nil {
+		return nil, err
+	}
+
 	schedule := &models.Schedule{
 		Id:                uuid.New(),
 		Doctor_id:         doctor_id,
@@ -63,6 +83,38 @@ func (s *ScheduleService) GetSchedules() ([]models.Schedule, error) {
 	return s.repo.GetSchedules()
 }
 
+// QueueSlotGeneration checks the date range and hands the generation of its
+// slots to the background workers. A range already waiting to be generated
+// is not queued twice.
+func (s *ScheduleService) QueueSlotGeneration(ctx context.Context, req dto.GenerateSlotsRequest) (uuid.UUID, error) {
+	fromDate, err := time.Parse("2006-01-02", req.From_date)
+	if err != nil {
+		return uuid.Nil, errors.New("invalid from_date")
+	}
+	toDate, err := time.Parse("2006-01-02", req.To_date)
+	if err != nil {
+		return uuid.Nil, errors.New("invalid to_date")
+	}
+	if toDate.Before(fromDate) {
+		return uuid.Nil, errors.New("to_date must not be before from_date")
+	}
+	return s.queue.Enqueue(ctx, queue.Job{
+		Type:      GenerateSlotsJob,
+		Payload:   req,
+		UniqueKey: fmt.Sprintf("generate_slots:%s:%s", req.From_date, req.To_date),
+	})
+}
+
+// RunSlotGeneration generates the slots of a queued GenerateSlotsJob.
+func (s *ScheduleService) RunSlotGeneration(req dto.GenerateSlotsRequest) error {
+	if req.From_date == "" && req.To_date == "" {
+		today := time.Now().UTC()
+		req.From_date = today.Format("2006-01-02")
+		req.To_date = today.AddDate(0, 0, slotHorizonDays-1).Format("2006-01-02")
+	}
+	return s.GenerateSlots(req)
+}
+
 func (s *ScheduleService) GenerateSlots(req dto.GenerateSlotsRequest) error {
 
 	schedules, err := s.GetSchedules()
@@ -80,8 +132,19 @@ func (s *ScheduleService) GenerateSlots(req dto.GenerateSlotsRequest) error {
 		return err
 	}
 
+	specialHours := make(map[uuid.UUID]map[string]clinicModels.SpecialHours)
+
 	for _, schedule := range schedules {
 
+		special, ok := specialHours[schedule.Clinic_address_id]
+		if !ok {
+			special, err = s.specialHoursByDate(schedule.Clinic_address_id, fromDate, toDate)
+			if err != nil {
+				return err
+			}
+			specialHours[schedule.Clinic_address_id] = special
+		}
+
 		startTime, err := time.Parse("15:04:05", schedule.Start_time)
 		if err != nil {
 			return err
@@ -110,6 +173,17 @@ func (s *ScheduleService) GenerateSlots(req dto.GenerateSlotsRequest) error {
 				0, 0, time.UTC,
 			)
 
+			// special hours of the clinic address override the weekly hours
+			if hours, ok := special[date.Format("2006-01-02")]; ok {
+				if hours.IsClosed {
+					continue
+				}
+				start, end, err = clampToSpecialHours(date, start, end, hours)
+				if err != nil {
+					return err
+				}
+			}
+
 			for t := start; t.Before(end); t = t.Add(30 * time.Minute) {
 
 				slotEnd := t.Add(30 * time.Minute)
@@ -132,6 +206,42 @@ func (s *ScheduleService) GenerateSlots(req dto.GenerateSlotsRequest) error {
 	return nil
 }
 
+// specialHoursByDate returns the special hours of the clinic address between
+// from and to keyed by date.
+func (s *ScheduleService) specialHoursByDate(clinicAddressID uuid.UUID, from, to time.Time) (map[string]clinicModels.SpecialHours, error) {
+	hours, err := s.clinicSrv.GetSpecialHours(clinicAddressID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	byDate := make(map[string]clinicModels.SpecialHours, len(hours))
+	for _, h := range hours {
+		byDate[h.Date] = h
+	}
+	return byDate, nil
+}
+
+// clampToSpecialHours narrows start-end to the special opening hours of date.
+func clampToSpecialHours(date, start, end time.Time, hours clinicModels.SpecialHours) (time.Time, time.Time, error) {
+	opensAt, err := time.Parse("15:04:05", hours.OpensAt)
+	if err != nil {
+		return start, end, err
+	}
+	closesAt, err := time.Parse("15:04:05", hours.ClosesAt)
+	if err != nil {
+		return start, end, err
+	}
+
+	opens := time.Date(date.Year(), date.Month(), date.Day(), opensAt.Hour(), opensAt.Minute(), 0, 0, time.UTC)
+	closes := time.Date(date.Year(), date.Month(), date.Day(), closesAt.Hour(), closesAt.Minute(), 0, 0, time.UTC)
+	if start.Before(opens) {
+		start = opens
+	}
+	if end.After(closes) {
+		end = closes
+	}
+	return start, end, nil
+}
+
 func (s *ScheduleService) GetAvailableSlots(doctorID, serviceID, clinic_addressID uuid.UUID, date time.Time) ([]models.Slot, error) {
 
 	service, err := s.serviceSrv.GetServiceByID(serviceID.String())
@@ -145,7 +255,7 @@ func (s *ScheduleService) GetAvailableSlots(doctorID, serviceID, clinic_addressI
 		return nil, err
 	}
 
-	serviceInfo, err := s.serviceSrv.GetByClinicIDAndServiceID(clinic_id, service.Id.String())
+	serviceInfo, err := s.serviceSrv.GetDoctorOffer(doctorID.String(), clinic_id, service.Id.String())
 	if err != nil {
 		return nil, err
 	}
@@ -350,6 +460,14 @@ func (s *ScheduleService) UpdateSchedule(schedule_id uuid.UUID, req dto.UpdateSc
 		return errors.New("invalid clinic_address_id")
 	}
 
+	if err := s.clinicSrv.CheckDoctorAtAddress(doctor_id, clinic_address_id); err != nil {
+		return err
+	}
+
+	if err := s.clinicSrv.CheckWorkingHours(clinic_address_id, req.Day_of_week, req.Start_time, req.End_time); err != nil {
+		return err
+	}
+
 	schedule := &models.Schedule{
 		Doctor_id:         doctor_id,
 		Clinic_address_id: clinic_address_id,
@@ -16,6 +16,17 @@ type ServiceResponse struct {
 	Description string `json:"description"`
 }
 
+type ServiceOfferResponse struct {
+	Id          string   `json:"id"`
+	Name        string   `json:"name"`
+	NameEn      string   `json:"name_en"`
+	NameKaz     string   `json:"name_kaz"`
+	Description string   `json:"description"`
+	MinPrice    *float64 `json:"min_price"`
+	MaxPrice    *float64 `json:"max_price"`
+	ClinicCount int      `json:"clinic_count"`
+}
+
 type ServiceResponseWithName struct {
 	Id          string  `json:"id"`
 	Name        string  `json:"name"`
@@ -8,6 +8,7 @@ import (
 
 	"dental_clinic/internal/modules/services/dto"
 	"dental_clinic/internal/modules/services/services"
+	"dental_clinic/internal/search"
 
 	"github.com/gorilla/mux"
 )
@@ -173,22 +174,43 @@ func (h *ServiceHandler) DeleteService(w http.ResponseWriter, r *http.Request) {
 }
 
 // GetServices godoc
-// @Summary Get services
-// @Description Returns services
+// @Summary Search services
+// @Description Returns a page of the service catalog with the price range and number of clinics offering each service. q searches all language variants of the name and the description. Pass next_cursor back as cursor to get the following page.
 // @Tags Services
-// @Security BearerAuth
 // @Produce json
-// @Success 200 {array} dto.ServiceResponse
-// @Failure 404 {object} map[string]string
+// @Param q query string false "Text to search for"
+// @Param clinic_id query string false "Only services offered by this clinic"
+// @Param city query string false "Only services offered in this city"
+// @Param price_min query number false "Minimum price"
+// @Param price_max query number false "Maximum price"
+// @Param sort query string false "relevance, name or price"
+// @Param cursor query string false "Cursor returned by the previous page"
+// @Param limit query int false "Page size, at most 100"
+// @Success 200 {object} search.Page
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
 // @Router /api/services [get]
 func (h *ServiceHandler) GetServices(w http.ResponseWriter, r *http.Request) {
+	params, err := search.ParseParams(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	all_services, err := h.service.GetAllServices()
+	result, err := h.service.SearchServices(params)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		if search.IsInvalid(err) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(services.ToServiceResponseList(all_services))
+	_ = json.NewEncoder(w).Encode(search.Page{
+		Items:      services.ToServiceOfferResponseList(result.Items),
+		NextCursor: result.NextCursor,
+		Limit:      params.Limit,
+	})
 }
@@ -20,3 +20,11 @@ type ServiceWithClinicName struct {
 	IsActive    bool
 	ClinicName  string
 }
+
+// ServiceOffer is a catalog service with a summary of the clinics offering it.
+type ServiceOffer struct {
+	Service
+	MinPrice    *float64
+	MaxPrice    *float64
+	ClinicCount int
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 
 	"dental_clinic/internal/modules/services/models"
+	"dental_clinic/internal/search"
 
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -13,12 +15,14 @@ type ServiceRepository interface {
 	Create(service *models.Service) (*models.Service, error)
 	GetByID(id string) (*models.Service, error)
 	GetAll() ([]models.Service, error)
+	Search(p search.Params, sortName string, sort search.Sort) (search.Result[models.ServiceOffer], error)
 	GetByClinicID(clinicID string) ([]models.Clinic_Service, error)
 	Update(id string, service *models.Service) (*models.Service, error)
 	Delete(id string) error
 	AddServiceToClinic(clinic_service *models.Clinic_Service) (*models.Clinic_Service, error)
 	DeleteServiceToClinic(clinicID, serviceID string) error
 	GetByClinicIDAndServiceID(clinicID, serviceID string) (*models.Clinic_Service, error)
+	GetDoctorOffer(doctorID, clinicID, serviceID string) (*models.Clinic_Service, error)
 }
 
 type serviceRepo struct {
@@ -69,6 +73,98 @@ func (r *serviceRepo) GetAll() ([]models.Service, error) {
 	return services, nil
 }
 
+// ServiceSorts are the orderings offered by Search.
+var ServiceSorts = map[string]search.Sort{
+	"relevance": {Expr: "ts_rank(sv.search_vector, query)::float8", Type: "float8", Desc: true},
+	"name":      {Expr: "LOWER(sv.name)", Type: "text"},
+	"price":     {Expr: "COALESCE(offer.min_price, 'Infinity'::float8)", Type: "float8"},
+}
+
+// Search returns one page of catalog services matching p with the price range
+// and number of active clinics offering each. Clinic, city and price filters
+// narrow the offers, and services without a matching offer are left out.
+func (r *serviceRepo) Search(p search.Params, sortName string, sort search.Sort) (search.Result[models.ServiceOffer], error) {
+	b := search.NewBuilder()
+	textQuery := b.TextQuery(p.Query)
+
+	offer := "cs.service_id = sv.id AND cs.is_active = true"
+	if p.ClinicID != uuid.Nil {
+		offer += " AND cs.clinic_id = " + b.Arg(p.ClinicID)
+	}
+	if p.City != "" {
+		offer += ` AND EXISTS (
+				SELECT 1 FROM clinic_addresses ca
+				JOIN addresses a ON a.id = ca.address_id
+				WHERE ca.clinic_id = cs.clinic_id AND LOWER(a.city) = LOWER(` + b.Arg(p.City) + `)
+			)`
+	}
+	if p.PriceMin != nil {
+		offer += " AND cs.price >= " + b.Arg(*p.PriceMin)
+	}
+	if p.PriceMax != nil {
+		offer += " AND cs.price <= " + b.Arg(*p.PriceMax)
+	}
+
+	if p.Query != "" {
+		b.Where("sv.search_vector @@ query")
+	}
+	if p.ClinicID != uuid.Nil || p.City != "" || p.PriceMin != nil || p.PriceMax != nil {
+		b.Where("offer.clinic_count > 0")
+	}
+	page := b.Page(sort, "sv.id", p.Cursor, p.Limit)
+
+	query := `
+		SELECT
+			sv.id,
+			sv.name,
+			COALESCE(sv.name_en, ''),
+			COALESCE(sv.name_kaz, ''),
+			COALESCE(sv.description, ''),
+			offer.min_price,
+			offer.max_price,
+			offer.clinic_count,
+			(` + sort.Expr + `)::text
+		FROM services sv
+		` + textQuery + `
+		LEFT JOIN LATERAL (
+			SELECT
+				MIN(cs.price)::float8 AS min_price,
+				MAX(cs.price)::float8 AS max_price,
+				COUNT(DISTINCT cs.clinic_id)::int AS clinic_count
+			FROM clinic_services cs
+			JOIN clinics c ON c.id = cs.clinic_id AND c.is_active = true
+			WHERE ` + offer + `
+		) offer ON TRUE
+		WHERE ` + b.WhereSQL() + `
+		` + page
+
+	rows, err := r.db.Query(context.Background(), query, b.Args()...)
+	if err != nil {
+		return search.Result[models.ServiceOffer]{}, err
+	}
+	defer rows.Close()
+
+	offers := make([]models.ServiceOffer, 0)
+	keys := make([]string, 0)
+	ids := make([]uuid.UUID, 0)
+	for rows.Next() {
+		var o models.ServiceOffer
+		var key string
+		if err := rows.Scan(&o.Id, &o.Name, &o.NameEn, &o.NameKaz, &o.Description, &o.MinPrice, &o.MaxPrice, &o.ClinicCount, &key); err != nil {
+			return search.Result[models.ServiceOffer]{}, err
+		}
+		offers = append(offers, o)
+		keys = append(keys, key)
+		ids = append(ids, o.Id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return search.Result[models.ServiceOffer]{}, err
+	}
+
+	return search.Trim(offers, keys, ids, sortName, p.Limit), nil
+}
+
 func (r *serviceRepo) GetByClinicID(clinicID string) ([]models.Clinic_Service, error) {
 	query := `SELECT id, clinic_id, service_id, price, duration_minutes, is_active FROM clinic_services WHERE clinic_id = $1`
 
@@ -188,3 +284,27 @@ func (r *serviceRepo) GetByClinicIDAndServiceID(clinicID, serviceID string) (*mo
 	}
 	return &s, nil
 }
+
+// GetDoctorOffer returns the clinic service as performed by the doctor, with
+// the doctor's price and duration overrides applied. It returns nil when the
+// doctor is not assigned to the service or no longer works at the clinic.
+func (r *serviceRepo) GetDoctorOffer(doctorID, clinicID, serviceID string) (*models.Clinic_Service, error) {
+	query := `
+		SELECT cs.id, cs.clinic_id, cs.service_id,
+			COALESCE(ds.price, cs.price)::float8, COALESCE(ds.duration_minutes, cs.duration_minutes), cs.is_active
+		FROM doctor_services ds
+		JOIN clinic_services cs ON cs.id = ds.clinic_service_id
+		JOIN doctor_clinics dc ON dc.doctor_id = ds.doctor_id AND dc.clinic_id = cs.clinic_id
+		WHERE ds.doctor_id = $1 AND cs.clinic_id = $2 AND cs.service_id = $3 AND dc.status = 'active'
+	`
+	var s models.Clinic_Service
+	err := r.db.QueryRow(context.Background(), query, doctorID, clinicID, serviceID).
+		Scan(&s.Id, &s.ClinicID, &s.ServiceID, &s.Price, &s.Duration, &s.IsActive)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &s, nil
+}
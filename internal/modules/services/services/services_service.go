@@ -7,10 +7,15 @@ import (
 	"dental_clinic/internal/modules/services/dto"
 	"dental_clinic/internal/modules/services/models"
 	"dental_clinic/internal/modules/services/repository"
+	"dental_clinic/internal/search"
 
 	"github.com/google/uuid"
 )
 
+// ErrServiceNotAssigned is returned when a doctor is booked for a service they
+// do not perform at the clinic.
+var ErrServiceNotAssigned = errors.New("doctor does not perform this service at this clinic")
+
 type ServiceService struct {
 	repo      repository.ServiceRepository
 	clinicSrv services.ClinicService
@@ -40,6 +45,16 @@ func (s *ServiceService) GetAllServices() ([]models.Service, error) {
 	return s.repo.GetAll()
 }
 
+// SearchServices returns one page of the service catalog. Without a sort the
+// most relevant services come first when searching by text, by name otherwise.
+func (s *ServiceService) SearchServices(p search.Params) (search.Result[models.ServiceOffer], error) {
+	sortName, sort, err := search.ResolveSort(repository.ServiceSorts, p.Sort, "name", p)
+	if err != nil {
+		return search.Result[models.ServiceOffer]{}, err
+	}
+	return s.repo.Search(p, sortName, sort)
+}
+
 func (s *ServiceService) GetClinicNames(services []models.Clinic_Service) ([]models.ServiceWithClinicName, error) {
 	var servicesListWithClinicNames []models.ServiceWithClinicName
 
@@ -141,6 +156,27 @@ func ToServiceResponseList(services []models.Service) []dto.ServiceResponse {
 	return result
 }
 
+func ToServiceOfferResponse(o models.ServiceOffer) dto.ServiceOfferResponse {
+	return dto.ServiceOfferResponse{
+		Id:          o.Id.String(),
+		Name:        o.Name,
+		NameEn:      o.NameEn,
+		NameKaz:     o.NameKaz,
+		Description: o.Description,
+		MinPrice:    o.MinPrice,
+		MaxPrice:    o.MaxPrice,
+		ClinicCount: o.ClinicCount,
+	}
+}
+
+func ToServiceOfferResponseList(offers []models.ServiceOffer) []dto.ServiceOfferResponse {
+	result := make([]dto.ServiceOfferResponse, 0, len(offers))
+	for _, o := range offers {
+		result = append(result, ToServiceOfferResponse(o))
+	}
+	return result
+}
+
 func ToServiceNameResponse(s models.ServiceWithClinicName) dto.ServiceResponseWithName {
 	return dto.ServiceResponseWithName{
 		Id:          s.Id.String(),
@@ -219,3 +255,26 @@ func (s *ServiceService) GetByClinicIDAndServiceID(clinicID, serviceID string) (
 
 	return s.repo.GetByClinicIDAndServiceID(clinicID, serviceID)
 }
+
+// GetDoctorOffer returns the price and duration of the service when booked
+// with the doctor. Doctors can only be booked for services assigned to them.
+func (s *ServiceService) GetDoctorOffer(doctorID, clinicID, serviceID string) (*models.Clinic_Service, error) {
+	if _, err := uuid.Parse(doctorID); err != nil {
+		return nil, errors.New("invalid doctor_id")
+	}
+	if _, err := uuid.Parse(clinicID); err != nil {
+		return nil, errors.New("invalid clinic_id")
+	}
+	if _, err := uuid.Parse(serviceID); err != nil {
+		return nil, errors.New("invalid service_id")
+	}
+
+	offer, err := s.repo.GetDoctorOffer(doctorID, clinicID, serviceID)
+	if err != nil {
+		return nil, err
+	}
+	if offer == nil {
+		return nil, ErrServiceNotAssigned
+	}
+	return offer, nil
+}
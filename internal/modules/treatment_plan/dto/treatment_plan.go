@@ -0,0 +1,59 @@
+package dto
+
+type TreatmentPlanItemRequest struct {
+	ClinicServiceId  string `json:"clinic_service_id"`
+	ToothNumber      string `json:"tooth_number"`
+	AlternativeGroup string `json:"alternative_group"`
+	Notes            string `json:"notes"`
+}
+
+type CreateTreatmentPlanRequest struct {
+	MedicalRecordId string                     `json:"medical_record_id"`
+	Title           string                     `json:"title"`
+	Notes           string                     `json:"notes"`
+	Items           []TreatmentPlanItemRequest `json:"items"`
+}
+
+// AcceptTreatmentPlanRequest lists the items the patient agrees to. It may be
+// left empty to accept every item of a plan without alternative options.
+type AcceptTreatmentPlanRequest struct {
+	ItemIds []string `json:"item_ids"`
+}
+
+type TreatmentPlanItemResponse struct {
+	Id               string  `json:"id"`
+	Position         int     `json:"position"`
+	ClinicServiceId  string  `json:"clinic_service_id"`
+	ServiceId        string  `json:"service_id"`
+	ServiceName      string  `json:"service_name"`
+	ToothNumber      string  `json:"tooth_number,omitempty"`
+	AlternativeGroup string  `json:"alternative_group,omitempty"`
+	Price            float64 `json:"price"`
+	DurationMinutes  int     `json:"duration_minutes"`
+	Notes            string  `json:"notes,omitempty"`
+	Status           string  `json:"status"`
+	AppointmentId    string  `json:"appointment_id,omitempty"`
+	CompletedAt      string  `json:"completed_at,omitempty"`
+}
+
+type TreatmentPlanResponse struct {
+	Id              string                      `json:"id"`
+	MedicalRecordId string                      `json:"medical_record_id"`
+	DoctorId        string                      `json:"doctor_id"`
+	DoctorName      string                      `json:"doctor_name"`
+	PatientId       string                      `json:"patient_id"`
+	ClinicId        string                      `json:"clinic_id"`
+	ClinicName      string                      `json:"clinic_name"`
+	Title           string                      `json:"title"`
+	Notes           string                      `json:"notes,omitempty"`
+	Status          string                      `json:"status"`
+	EstimateMin     float64                     `json:"estimate_min"`
+	EstimateMax     float64                     `json:"estimate_max"`
+	AcceptedTotal   float64                     `json:"accepted_total"`
+	TotalItems      int                         `json:"total_items"`
+	CompletedItems  int                         `json:"completed_items"`
+	ProgressPercent int                         `json:"progress_percent"`
+	Items           []TreatmentPlanItemResponse `json:"items"`
+	RespondedAt     string                      `json:"responded_at,omitempty"`
+	CreatedAt       string                      `json:"created_at"`
+}
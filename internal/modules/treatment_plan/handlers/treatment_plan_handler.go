@@ -0,0 +1,280 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"dental_clinic/internal/config"
+	"dental_clinic/internal/modules/treatment_plan/dto"
+	"dental_clinic/internal/modules/treatment_plan/models"
+	"dental_clinic/internal/modules/treatment_plan/services"
+	"dental_clinic/internal/utils"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type TreatmentPlanHandler struct {
+	service *services.TreatmentPlanService
+	cfg     config.Config
+}
+
+func NewTreatmentPlanHandler(service *services.TreatmentPlanService, cfg config.Config) *TreatmentPlanHandler {
+	return &TreatmentPlanHandler{service: service, cfg: cfg}
+}
+
+// CreateTreatmentPlan godoc
+// @Summary Propose treatment plan
+// @Description Creates a treatment plan for the patient of a medical record. Items reference clinic services and optionally a tooth in FDI notation. Items with the same alternative_group are options the patient chooses between.
+// @Tags Treatment Plans
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body dto.CreateTreatmentPlanRequest true "Treatment plan"
+// @Success 201 {object} dto.TreatmentPlanResponse
+// @Failure 400 {object} map[string]string
+// @Router /api/treatment-plans [post]
+func (h *TreatmentPlanHandler) CreateTreatmentPlan(w http.ResponseWriter, r *http.Request) {
+	var req dto.CreateTreatmentPlanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	defer r.Body.Close()
+
+	userId, _ := h.currentUser(r)
+	plan, err := h.service.CreatePlan(r.Context(), userId, req)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusCreated, toTreatmentPlanResponse(*plan))
+}
+
+// GetMyTreatmentPlans godoc
+// @Summary Get my treatment plans
+// @Description Returns plans proposed to the patient, or proposed by the doctor when called by a doctor
+// @Tags Treatment Plans
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} dto.TreatmentPlanResponse
+// @Failure 400 {object} map[string]string
+// @Router /api/treatment-plans [get]
+func (h *TreatmentPlanHandler) GetMyTreatmentPlans(w http.ResponseWriter, r *http.Request) {
+	userId, role := h.currentUser(r)
+	plans, err := h.service.GetMyPlans(userId, role)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, toTreatmentPlanResponseList(plans))
+}
+
+// GetRecordTreatmentPlans godoc
+// @Summary Get treatment plans of a medical record
+// @Description Returns treatment plans proposed during the visit
+// @Tags Treatment Plans
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Medical record ID"
+// @Success 200 {array} dto.TreatmentPlanResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /api/medical-records/{id}/treatment-plans [get]
+func (h *TreatmentPlanHandler) GetRecordTreatmentPlans(w http.ResponseWriter, r *http.Request) {
+	userId, _ := h.currentUser(r)
+	plans, err := h.service.GetPlansByRecord(userId, mux.Vars(r)["id"])
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, toTreatmentPlanResponseList(plans))
+}
+
+// GetTreatmentPlan godoc
+// @Summary Get treatment plan
+// @Description Returns a treatment plan with its items, estimate and progress
+// @Tags Treatment Plans
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Treatment plan ID"
+// @Success 200 {object} dto.TreatmentPlanResponse
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/treatment-plans/{id} [get]
+func (h *TreatmentPlanHandler) GetTreatmentPlan(w http.ResponseWriter, r *http.Request) {
+	userId, _ := h.currentUser(r)
+	plan, err := h.service.GetPlan(userId, mux.Vars(r)["id"])
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, toTreatmentPlanResponse(*plan))
+}
+
+// AcceptTreatmentPlan godoc
+// @Summary Accept treatment plan
+// @Description Patient accepts the listed items; the remaining items are declined. item_ids may be omitted when the plan has no alternative options.
+// @Tags Treatment Plans
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Treatment plan ID"
+// @Param request body dto.AcceptTreatmentPlanRequest false "Accepted items"
+// @Success 200 {object} dto.TreatmentPlanResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /api/treatment-plans/{id}/accept [post]
+func (h *TreatmentPlanHandler) AcceptTreatmentPlan(w http.ResponseWriter, r *http.Request) {
+	var req dto.AcceptTreatmentPlanRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+	defer r.Body.Close()
+
+	userId, _ := h.currentUser(r)
+	plan, err := h.service.AcceptPlan(r.Context(), userId, mux.Vars(r)["id"], req)
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, toTreatmentPlanResponse(*plan))
+}
+
+// DeclineTreatmentPlan godoc
+// @Summary Decline treatment plan
+// @Description Patient declines the whole treatment plan
+// @Tags Treatment Plans
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Treatment plan ID"
+// @Success 200 {object} dto.TreatmentPlanResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /api/treatment-plans/{id}/decline [post]
+func (h *TreatmentPlanHandler) DeclineTreatmentPlan(w http.ResponseWriter, r *http.Request) {
+	userId, _ := h.currentUser(r)
+	plan, err := h.service.DeclinePlan(r.Context(), userId, mux.Vars(r)["id"])
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, toTreatmentPlanResponse(*plan))
+}
+
+// CompleteTreatmentPlanItem godoc
+// @Summary Complete treatment plan item
+// @Description Treating doctor marks an accepted item as done. Items booked as appointments are completed automatically when the appointment is.
+// @Tags Treatment Plans
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Treatment plan item ID"
+// @Success 200 {object} dto.TreatmentPlanResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /api/treatment-plan-items/{id}/complete [post]
+func (h *TreatmentPlanHandler) CompleteTreatmentPlanItem(w http.ResponseWriter, r *http.Request) {
+	userId, _ := h.currentUser(r)
+	plan, err := h.service.CompleteItem(r.Context(), userId, mux.Vars(r)["id"])
+	if err != nil {
+		respondError(w, errorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, toTreatmentPlanResponse(*plan))
+}
+
+func (h *TreatmentPlanHandler) currentUser(r *http.Request) (string, string) {
+	claims, err := utils.GetClaims(utils.GetToken(r), h.cfg.JWTSecret)
+	if err != nil {
+		return "", ""
+	}
+	userId, _ := claims["user_id"].(string)
+	role, _ := claims["role"].(string)
+	return userId, role
+}
+
+func errorStatus(err error) int {
+	switch err.Error() {
+	case "access denied":
+		return http.StatusForbidden
+	case "treatment plan not found", "treatment plan item not found":
+		return http.StatusNotFound
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+func respondJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+func respondError(w http.ResponseWriter, status int, message string) {
+	respondJSON(w, status, map[string]string{"error": message})
+}
+
+func toTreatmentPlanResponse(plan models.TreatmentPlan) dto.TreatmentPlanResponse {
+	summary := services.Summarize(plan)
+
+	items := make([]dto.TreatmentPlanItemResponse, 0, len(plan.Items))
+	for _, item := range plan.Items {
+		response := dto.TreatmentPlanItemResponse{
+			Id:               item.Id.String(),
+			Position:         item.Position,
+			ClinicServiceId:  item.ClinicServiceId.String(),
+			ServiceId:        item.ServiceId.String(),
+			ServiceName:      item.ServiceName,
+			ToothNumber:      item.ToothNumber,
+			AlternativeGroup: item.AlternativeGroup,
+			Price:            item.Price,
+			DurationMinutes:  item.DurationMinutes,
+			Notes:            item.Notes,
+			Status:           item.Status,
+		}
+		if item.AppointmentId != uuid.Nil {
+			response.AppointmentId = item.AppointmentId.String()
+		}
+		if item.CompletedAt.Valid {
+			response.CompletedAt = item.CompletedAt.Time.Format(time.RFC3339)
+		}
+		items = append(items, response)
+	}
+
+	response := dto.TreatmentPlanResponse{
+		Id:              plan.Id.String(),
+		MedicalRecordId: plan.MedicalRecordId.String(),
+		DoctorId:        plan.DoctorId.String(),
+		DoctorName:      plan.DoctorName,
+		PatientId:       plan.PatientId.String(),
+		ClinicId:        plan.ClinicId.String(),
+		ClinicName:      plan.ClinicName,
+		Title:           plan.Title,
+		Notes:           plan.Notes,
+		Status:          plan.Status,
+		EstimateMin:     summary.EstimateMin,
+		EstimateMax:     summary.EstimateMax,
+		AcceptedTotal:   summary.AcceptedTotal,
+		TotalItems:      summary.TotalItems,
+		CompletedItems:  summary.CompletedItems,
+		ProgressPercent: summary.ProgressPercent,
+		Items:           items,
+		CreatedAt:       plan.CreatedAt.Format(time.RFC3339),
+	}
+	if plan.RespondedAt.Valid {
+		response.RespondedAt = plan.RespondedAt.Time.Format(time.RFC3339)
+	}
+	return response
+}
+
+func toTreatmentPlanResponseList(plans []models.TreatmentPlan) []dto.TreatmentPlanResponse {
+	result := make([]dto.TreatmentPlanResponse, 0, len(plans))
+	for _, plan := range plans {
+		result = append(result, toTreatmentPlanResponse(plan))
+	}
+	return result
+}
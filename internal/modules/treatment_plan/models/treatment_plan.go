@@ -0,0 +1,71 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type TreatmentPlan struct {
+	Id              uuid.UUID
+	MedicalRecordId uuid.UUID
+	DoctorId        uuid.UUID
+	PatientId       uuid.UUID
+	ClinicId        uuid.UUID
+	Title           string
+	Notes           string
+	Status          string
+	RespondedAt     sql.NullTime
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+
+	DoctorName string
+	ClinicName string
+	Items      []TreatmentPlanItem
+}
+
+type TreatmentPlanItem struct {
+	Id               uuid.UUID
+	PlanId           uuid.UUID
+	Position         int
+	ClinicServiceId  uuid.UUID
+	ServiceId        uuid.UUID
+	ServiceName      string
+	ToothNumber      string
+	AlternativeGroup string
+	Price            float64
+	DurationMinutes  int
+	Notes            string
+	Status           string
+	AppointmentId    uuid.UUID
+	CompletedAt      sql.NullTime
+}
+
+// PlanItemContext is an item together with the plan fields needed to decide
+// who may act on it.
+type PlanItemContext struct {
+	TreatmentPlanItem
+	PlanStatus   string
+	PatientId    uuid.UUID
+	DoctorUserId uuid.UUID
+	ClinicId     uuid.UUID
+}
+
+type RecordContext struct {
+	MedicalRecordId uuid.UUID
+	DoctorId        uuid.UUID
+	DoctorUserId    uuid.UUID
+	PatientId       uuid.UUID
+	ClinicId        uuid.UUID
+}
+
+type ClinicServiceInfo struct {
+	Id              uuid.UUID
+	ClinicId        uuid.UUID
+	ServiceId       uuid.UUID
+	ServiceName     string
+	Price           float64
+	DurationMinutes int
+	IsActive        bool
+}
@@ -0,0 +1,423 @@
+package repository
+
+import (
+	"context"
+
+	"dental_clinic/internal/modules/treatment_plan/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type TreatmentPlanRepository interface {
+	GetRecordContext(medicalRecordId uuid.UUID) (*models.RecordContext, error)
+	GetClinicService(id uuid.UUID) (*models.ClinicServiceInfo, error)
+
+	CreatePlanTx(plan *models.TreatmentPlan, tx pgx.Tx) error
+	CreateItemTx(item *models.TreatmentPlanItem, tx pgx.Tx) error
+	GetPlanByID(id uuid.UUID) (*models.TreatmentPlan, error)
+	GetPlansByPatient(patientId uuid.UUID) ([]models.TreatmentPlan, error)
+	GetPlansByDoctorUser(userId uuid.UUID) ([]models.TreatmentPlan, error)
+	GetPlansByRecord(medicalRecordId uuid.UUID) ([]models.TreatmentPlan, error)
+
+	UpdatePlanStatusTx(id uuid.UUID, status string, tx pgx.Tx) error
+	UpdateItemStatusTx(id uuid.UUID, status string, tx pgx.Tx) error
+	GetItemContext(id uuid.UUID) (*models.PlanItemContext, error)
+	LinkAppointmentTx(itemId, appointmentId uuid.UUID, tx pgx.Tx) error
+	CompleteItemTx(id uuid.UUID, tx pgx.Tx) error
+	RefreshPlanStatusTx(planId uuid.UUID, tx pgx.Tx) error
+}
+
+type treatmentPlanRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewTreatmentPlanRepository(db *pgxpool.Pool) TreatmentPlanRepository {
+	return &treatmentPlanRepo{db: db}
+}
+
+func (r *treatmentPlanRepo) GetRecordContext(medicalRecordId uuid.UUID) (*models.RecordContext, error) {
+	query := `
+		SELECT
+			mr.id,
+			mr.doctor_id,
+			COALESCE(d.user_id, '00000000-0000-0000-0000-000000000000'::uuid),
+			COALESCE(mr.patient_id, '00000000-0000-0000-0000-000000000000'::uuid),
+			COALESCE(ca.clinic_id, '00000000-0000-0000-0000-000000000000'::uuid)
+		FROM medical_records mr
+		LEFT JOIN doctors d ON d.id = mr.doctor_id
+		LEFT JOIN appointments a ON a.id = mr.appointment_id
+		LEFT JOIN clinic_addresses ca ON ca.id = a.clinic_address_id
+		WHERE mr.id = $1
+	`
+	record := &models.RecordContext{}
+	err := r.db.QueryRow(context.Background(), query, medicalRecordId).Scan(
+		&record.MedicalRecordId,
+		&record.DoctorId,
+		&record.DoctorUserId,
+		&record.PatientId,
+		&record.ClinicId,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return record, nil
+}
+
+func (r *treatmentPlanRepo) GetClinicService(id uuid.UUID) (*models.ClinicServiceInfo, error) {
+	query := `
+		SELECT cs.id, cs.clinic_id, cs.service_id, COALESCE(s.name, ''), COALESCE(cs.price, 0), COALESCE(cs.duration_minutes, 0), COALESCE(cs.is_active, false)
+		FROM clinic_services cs
+		LEFT JOIN services s ON s.id = cs.service_id
+		WHERE cs.id = $1
+	`
+	service := &models.ClinicServiceInfo{}
+	err := r.db.QueryRow(context.Background(), query, id).Scan(
+		&service.Id,
+		&service.ClinicId,
+		&service.ServiceId,
+		&service.ServiceName,
+		&service.Price,
+		&service.DurationMinutes,
+		&service.IsActive,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return service, nil
+}
+
+func (r *treatmentPlanRepo) CreatePlanTx(plan *models.TreatmentPlan, tx pgx.Tx) error {
+	query := `
+		INSERT INTO treatment_plans (id, medical_record_id, doctor_id, patient_id, clinic_id, title, notes, status, created_at, updated_at)
+		VALUES ($1, $2, $3, NULLIF($4, '00000000-0000-0000-0000-000000000000'::uuid), $5, $6, $7, $8, $9, $10)
+	`
+	_, err := tx.Exec(
+		context.Background(),
+		query,
+		plan.Id,
+		plan.MedicalRecordId,
+		plan.DoctorId,
+		plan.PatientId,
+		plan.ClinicId,
+		plan.Title,
+		plan.Notes,
+		plan.Status,
+		plan.CreatedAt,
+		plan.UpdatedAt,
+	)
+	return err
+}
+
+func (r *treatmentPlanRepo) CreateItemTx(item *models.TreatmentPlanItem, tx pgx.Tx) error {
+	query := `
+		INSERT INTO treatment_plan_items (id, plan_id, position, clinic_service_id, tooth_number, alternative_group, price, duration_minutes, notes, status)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), $7, $8, $9, $10)
+	`
+	_, err := tx.Exec(
+		context.Background(),
+		query,
+		item.Id,
+		item.PlanId,
+		item.Position,
+		item.ClinicServiceId,
+		item.ToothNumber,
+		item.AlternativeGroup,
+		item.Price,
+		item.DurationMinutes,
+		item.Notes,
+		item.Status,
+	)
+	return err
+}
+
+const planColumns = `
+	p.id,
+	p.medical_record_id,
+	p.doctor_id,
+	COALESCE(p.patient_id, '00000000-0000-0000-0000-000000000000'::uuid),
+	COALESCE(p.clinic_id, '00000000-0000-0000-0000-000000000000'::uuid),
+	p.title,
+	COALESCE(p.notes, ''),
+	p.status,
+	p.responded_at,
+	p.created_at,
+	p.updated_at,
+	COALESCE(d.name, ''),
+	COALESCE(c.name, '')
+`
+
+const planJoins = `
+	FROM treatment_plans p
+	LEFT JOIN doctors d ON d.id = p.doctor_id
+	LEFT JOIN clinics c ON c.id = p.clinic_id
+`
+
+func scanPlan(row pgx.Row, p *models.TreatmentPlan) error {
+	return row.Scan(
+		&p.Id,
+		&p.MedicalRecordId,
+		&p.DoctorId,
+		&p.PatientId,
+		&p.ClinicId,
+		&p.Title,
+		&p.Notes,
+		&p.Status,
+		&p.RespondedAt,
+		&p.CreatedAt,
+		&p.UpdatedAt,
+		&p.DoctorName,
+		&p.ClinicName,
+	)
+}
+
+func (r *treatmentPlanRepo) GetPlanByID(id uuid.UUID) (*models.TreatmentPlan, error) {
+	query := `SELECT ` + planColumns + planJoins + ` WHERE p.id = $1`
+
+	plan := &models.TreatmentPlan{}
+	if err := scanPlan(r.db.QueryRow(context.Background(), query, id), plan); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	items, err := r.getItems([]uuid.UUID{plan.Id})
+	if err != nil {
+		return nil, err
+	}
+	plan.Items = items[plan.Id]
+	return plan, nil
+}
+
+func (r *treatmentPlanRepo) GetPlansByPatient(patientId uuid.UUID) ([]models.TreatmentPlan, error) {
+	return r.listPlans(`WHERE p.patient_id = $1`, patientId)
+}
+
+func (r *treatmentPlanRepo) GetPlansByDoctorUser(userId uuid.UUID) ([]models.TreatmentPlan, error) {
+	return r.listPlans(`WHERE d.user_id = $1`, userId)
+}
+
+func (r *treatmentPlanRepo) GetPlansByRecord(medicalRecordId uuid.UUID) ([]models.TreatmentPlan, error) {
+	return r.listPlans(`WHERE p.medical_record_id = $1`, medicalRecordId)
+}
+
+func (r *treatmentPlanRepo) listPlans(where string, arg uuid.UUID) ([]models.TreatmentPlan, error) {
+	query := `SELECT ` + planColumns + planJoins + where + ` ORDER BY p.created_at DESC`
+
+	rows, err := r.db.Query(context.Background(), query, arg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	plans := make([]models.TreatmentPlan, 0)
+	ids := make([]uuid.UUID, 0)
+	for rows.Next() {
+		var plan models.TreatmentPlan
+		if err := scanPlan(rows, &plan); err != nil {
+			return nil, err
+		}
+		plans = append(plans, plan)
+		ids = append(ids, plan.Id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	items, err := r.getItems(ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range plans {
+		plans[i].Items = items[plans[i].Id]
+	}
+	return plans, nil
+}
+
+const itemColumns = `
+	i.id,
+	i.plan_id,
+	i.position,
+	COALESCE(i.clinic_service_id, '00000000-0000-0000-0000-000000000000'::uuid),
+	COALESCE(cs.service_id, '00000000-0000-0000-0000-000000000000'::uuid),
+	COALESCE(s.name, ''),
+	COALESCE(i.tooth_number, ''),
+	COALESCE(i.alternative_group, ''),
+	i.price,
+	i.duration_minutes,
+	COALESCE(i.notes, ''),
+	i.status,
+	COALESCE(i.appointment_id, '00000000-0000-0000-0000-000000000000'::uuid),
+	i.completed_at
+`
+
+const itemJoins = `
+	FROM treatment_plan_items i
+	LEFT JOIN clinic_services cs ON cs.id = i.clinic_service_id
+	LEFT JOIN services s ON s.id = cs.service_id
+`
+
+func itemScanDest(item *models.TreatmentPlanItem) []any {
+	return []any{
+		&item.Id,
+		&item.PlanId,
+		&item.Position,
+		&item.ClinicServiceId,
+		&item.ServiceId,
+		&item.ServiceName,
+		&item.ToothNumber,
+		&item.AlternativeGroup,
+		&item.Price,
+		&item.DurationMinutes,
+		&item.Notes,
+		&item.Status,
+		&item.AppointmentId,
+		&item.CompletedAt,
+	}
+}
+
+func (r *treatmentPlanRepo) getItems(planIds []uuid.UUID) (map[uuid.UUID][]models.TreatmentPlanItem, error) {
+	result := make(map[uuid.UUID][]models.TreatmentPlanItem)
+	if len(planIds) == 0 {
+		return result, nil
+	}
+
+	query := `SELECT ` + itemColumns + itemJoins + ` WHERE i.plan_id = ANY($1) ORDER BY i.position`
+	rows, err := r.db.Query(context.Background(), query, planIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item models.TreatmentPlanItem
+		if err := rows.Scan(itemScanDest(&item)...); err != nil {
+			return nil, err
+		}
+		result[item.PlanId] = append(result[item.PlanId], item)
+	}
+	return result, rows.Err()
+}
+
+func (r *treatmentPlanRepo) UpdatePlanStatusTx(id uuid.UUID, status string, tx pgx.Tx) error {
+	query := `
+		UPDATE treatment_plans
+		SET status = $2, responded_at = NOW(), updated_at = NOW()
+		WHERE id = $1
+	`
+	result, err := tx.Exec(context.Background(), query, id, status)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *treatmentPlanRepo) UpdateItemStatusTx(id uuid.UUID, status string, tx pgx.Tx) error {
+	query := `UPDATE treatment_plan_items SET status = $2 WHERE id = $1`
+	result, err := tx.Exec(context.Background(), query, id, status)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *treatmentPlanRepo) GetItemContext(id uuid.UUID) (*models.PlanItemContext, error) {
+	query := `SELECT ` + itemColumns + `,
+			p.status,
+			COALESCE(p.patient_id, '00000000-0000-0000-0000-000000000000'::uuid),
+			COALESCE(d.user_id, '00000000-0000-0000-0000-000000000000'::uuid),
+			COALESCE(p.clinic_id, '00000000-0000-0000-0000-000000000000'::uuid)
+		` + itemJoins + `
+		JOIN treatment_plans p ON p.id = i.plan_id
+		LEFT JOIN doctors d ON d.id = p.doctor_id
+		WHERE i.id = $1
+	`
+	item := &models.PlanItemContext{}
+	dest := append(itemScanDest(&item.TreatmentPlanItem), &item.PlanStatus, &item.PatientId, &item.DoctorUserId, &item.ClinicId)
+	if err := r.db.QueryRow(context.Background(), query, id).Scan(dest...); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return item, nil
+}
+
+// LinkAppointmentTx attaches a booked appointment to an accepted item. An item
+// whose previous appointment was cancelled or deleted may be booked again.
+func (r *treatmentPlanRepo) LinkAppointmentTx(itemId, appointmentId uuid.UUID, tx pgx.Tx) error {
+	query := `
+		UPDATE treatment_plan_items i
+		SET status = 'scheduled', appointment_id = $2
+		WHERE i.id = $1
+			AND (
+				i.status = 'accepted'
+				OR (i.status = 'scheduled' AND (
+					i.appointment_id IS NULL
+					OR EXISTS (SELECT 1 FROM appointments a WHERE a.id = i.appointment_id AND a.status = 'cancelled')
+				))
+			)
+	`
+	result, err := tx.Exec(context.Background(), query, itemId, appointmentId)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *treatmentPlanRepo) CompleteItemTx(id uuid.UUID, tx pgx.Tx) error {
+	query := `
+		UPDATE treatment_plan_items
+		SET status = 'completed', completed_at = NOW()
+		WHERE id = $1 AND status IN ('accepted', 'scheduled')
+	`
+	result, err := tx.Exec(context.Background(), query, id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// RefreshPlanStatusTx derives the status of an accepted plan from its items.
+func (r *treatmentPlanRepo) RefreshPlanStatusTx(planId uuid.UUID, tx pgx.Tx) error {
+	_, err := tx.Exec(context.Background(), refreshPlanStatusQuery, planId)
+	return err
+}
+
+const refreshPlanStatusQuery = `
+	UPDATE treatment_plans p
+	SET status = CASE
+			WHEN NOT EXISTS (
+				SELECT 1 FROM treatment_plan_items i
+				WHERE i.plan_id = p.id AND i.status IN ('accepted', 'scheduled')
+			) THEN 'completed'
+			WHEN EXISTS (
+				SELECT 1 FROM treatment_plan_items i
+				WHERE i.plan_id = p.id AND i.status IN ('scheduled', 'completed')
+			) THEN 'in_progress'
+			ELSE 'accepted'
+		END,
+		updated_at = NOW()
+	WHERE p.id = $1
+		AND p.status IN ('accepted', 'in_progress', 'completed')
+`
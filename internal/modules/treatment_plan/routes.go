@@ -0,0 +1,41 @@
+package treatment_plan
+
+import (
+	"dental_clinic/internal/config"
+	"dental_clinic/internal/modules/treatment_plan/handlers"
+	"dental_clinic/internal/modules/treatment_plan/repository"
+	"dental_clinic/internal/modules/treatment_plan/services"
+
+	medical_recordRepository "dental_clinic/internal/modules/medical_record/repository"
+	medical_recordServices "dental_clinic/internal/modules/medical_record/services"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func newHandler(db *pgxpool.Pool, cfg *config.Config) *handlers.TreatmentPlanHandler {
+	repo := repository.NewTreatmentPlanRepository(db)
+
+	medical_recordRepo := medical_recordRepository.NewMedicalRecordRepository(db)
+	medical_recordService := medical_recordServices.NewMedicalRecordService(medical_recordRepo)
+
+	service := services.NewTreatmentPlanService(repo, db, *cfg, *medical_recordService)
+	return handlers.NewTreatmentPlanHandler(service, *cfg)
+}
+
+func RegisterPrivateRoutes(r *mux.Router, db *pgxpool.Pool, cfg *config.Config) {
+	handler := newHandler(db, cfg)
+
+	r.HandleFunc("/treatment-plans", handler.GetMyTreatmentPlans).Methods("GET")
+	r.HandleFunc("/treatment-plans/{id}", handler.GetTreatmentPlan).Methods("GET")
+	r.HandleFunc("/treatment-plans/{id}/accept", handler.AcceptTreatmentPlan).Methods("POST")
+	r.HandleFunc("/treatment-plans/{id}/decline", handler.DeclineTreatmentPlan).Methods("POST")
+	r.HandleFunc("/medical-records/{id}/treatment-plans", handler.GetRecordTreatmentPlans).Methods("GET")
+}
+
+func RegisterDoctorRoutes(r *mux.Router, db *pgxpool.Pool, cfg *config.Config) {
+	handler := newHandler(db, cfg)
+
+	r.HandleFunc("/treatment-plans", handler.CreateTreatmentPlan).Methods("POST")
+	r.HandleFunc("/treatment-plan-items/{id}/complete", handler.CompleteTreatmentPlanItem).Methods("POST")
+}
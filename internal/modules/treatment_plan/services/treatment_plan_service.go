@@ -0,0 +1,475 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"dental_clinic/internal/config"
+	"dental_clinic/internal/modules/treatment_plan/dto"
+	"dental_clinic/internal/modules/treatment_plan/models"
+	"dental_clinic/internal/modules/treatment_plan/repository"
+
+	medical_recordServices "dental_clinic/internal/modules/medical_record/services"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type TreatmentPlanService struct {
+	repo              repository.TreatmentPlanRepository
+	db                *pgxpool.Pool
+	cfx               config.Config
+	medical_recordSrv medical_recordServices.MedicalRecordService
+}
+
+func NewTreatmentPlanService(repo repository.TreatmentPlanRepository, db *pgxpool.Pool, cfx config.Config, medical_recordSrv medical_recordServices.MedicalRecordService) *TreatmentPlanService {
+	return &TreatmentPlanService{
+		repo:              repo,
+		db:                db,
+		cfx:               cfx,
+		medical_recordSrv: medical_recordSrv,
+	}
+}
+
+// PlanSummary holds the figures derived from the plan items.
+type PlanSummary struct {
+	EstimateMin     float64
+	EstimateMax     float64
+	AcceptedTotal   float64
+	TotalItems      int
+	CompletedItems  int
+	ProgressPercent int
+}
+
+// CreatePlan proposes a treatment plan for the patient of a medical record.
+// Only the treating doctor may do so; prices are taken from the clinic's
+// current service list.
+func (s *TreatmentPlanService) CreatePlan(ctx context.Context, userId string, req dto.CreateTreatmentPlanRequest) (*models.TreatmentPlan, error) {
+	recordId, err := uuid.Parse(req.MedicalRecordId)
+	if err != nil {
+		return nil, errors.New("invalid medical record id")
+	}
+	userUUID, err := uuid.Parse(userId)
+	if err != nil {
+		return nil, errors.New("invalid user id")
+	}
+
+	record, err := s.repo.GetRecordContext(recordId)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, errors.New("medical record not found")
+	}
+	if record.DoctorUserId != userUUID {
+		return nil, errors.New("only the treating doctor can propose a treatment plan")
+	}
+
+	title := strings.TrimSpace(req.Title)
+	if title == "" {
+		return nil, errors.New("title is required")
+	}
+	if len(req.Items) == 0 {
+		return nil, errors.New("treatment plan must contain at least one item")
+	}
+
+	now := time.Now()
+	plan := &models.TreatmentPlan{
+		Id:              uuid.New(),
+		MedicalRecordId: record.MedicalRecordId,
+		DoctorId:        record.DoctorId,
+		PatientId:       record.PatientId,
+		ClinicId:        record.ClinicId,
+		Title:           title,
+		Notes:           strings.TrimSpace(req.Notes),
+		Status:          "proposed",
+		CreatedAt:       now,
+		UpdatedAt:       now,
+		Items:           make([]models.TreatmentPlanItem, 0, len(req.Items)),
+	}
+
+	groups := make(map[string]int)
+	for i, reqItem := range req.Items {
+		clinicServiceId, err := uuid.Parse(reqItem.ClinicServiceId)
+		if err != nil {
+			return nil, errors.New("invalid clinic service id")
+		}
+		service, err := s.repo.GetClinicService(clinicServiceId)
+		if err != nil {
+			return nil, err
+		}
+		if service == nil || !service.IsActive {
+			return nil, errors.New("clinic service not found")
+		}
+		if service.ClinicId != record.ClinicId {
+			return nil, errors.New("clinic service does not belong to the clinic of the visit")
+		}
+
+		tooth := strings.TrimSpace(reqItem.ToothNumber)
+		if tooth != "" && !validToothNumber(tooth) {
+			return nil, fmt.Errorf("invalid tooth number %q, use FDI notation", tooth)
+		}
+
+		group := strings.TrimSpace(reqItem.AlternativeGroup)
+		if group != "" {
+			groups[group]++
+		}
+
+		plan.Items = append(plan.Items, models.TreatmentPlanItem{
+			Id:               uuid.New(),
+			PlanId:           plan.Id,
+			Position:         i,
+			ClinicServiceId:  service.Id,
+			ServiceId:        service.ServiceId,
+			ServiceName:      service.ServiceName,
+			ToothNumber:      tooth,
+			AlternativeGroup: group,
+			Price:            service.Price,
+			DurationMinutes:  service.DurationMinutes,
+			Notes:            strings.TrimSpace(reqItem.Notes),
+			Status:           "proposed",
+		})
+	}
+	for group, count := range groups {
+		if count < 2 {
+			return nil, fmt.Errorf("alternative group %q must contain at least two options", group)
+		}
+	}
+
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := s.repo.CreatePlanTx(plan, tx); err != nil {
+		return nil, err
+	}
+	for i := range plan.Items {
+		if err := s.repo.CreateItemTx(&plan.Items[i], tx); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return s.repo.GetPlanByID(plan.Id)
+}
+
+func (s *TreatmentPlanService) GetPlan(userId, id string) (*models.TreatmentPlan, error) {
+	planId, err := uuid.Parse(id)
+	if err != nil {
+		return nil, errors.New("invalid treatment plan id")
+	}
+	plan, err := s.repo.GetPlanByID(planId)
+	if err != nil {
+		return nil, err
+	}
+	if plan == nil {
+		return nil, errors.New("treatment plan not found")
+	}
+	if err := s.checkAccess(plan.MedicalRecordId, userId); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// GetMyPlans returns the plans proposed by the doctor or, for any other
+// role, the plans proposed to the user.
+func (s *TreatmentPlanService) GetMyPlans(userId, role string) ([]models.TreatmentPlan, error) {
+	userUUID, err := uuid.Parse(userId)
+	if err != nil {
+		return nil, errors.New("invalid user id")
+	}
+	if role == "doctor" {
+		return s.repo.GetPlansByDoctorUser(userUUID)
+	}
+	return s.repo.GetPlansByPatient(userUUID)
+}
+
+func (s *TreatmentPlanService) GetPlansByRecord(userId, medicalRecordId string) ([]models.TreatmentPlan, error) {
+	recordId, err := uuid.Parse(medicalRecordId)
+	if err != nil {
+		return nil, errors.New("invalid medical record id")
+	}
+	if err := s.checkAccess(recordId, userId); err != nil {
+		return nil, err
+	}
+	return s.repo.GetPlansByRecord(recordId)
+}
+
+// AcceptPlan records the patient's consent. Items that were not chosen are
+// declined; at most one option may be chosen from each alternative group.
+func (s *TreatmentPlanService) AcceptPlan(ctx context.Context, userId, id string, req dto.AcceptTreatmentPlanRequest) (*models.TreatmentPlan, error) {
+	plan, err := s.getPlanForPatient(userId, id)
+	if err != nil {
+		return nil, err
+	}
+
+	accepted := make(map[uuid.UUID]bool)
+	if len(req.ItemIds) == 0 {
+		for _, item := range plan.Items {
+			if item.AlternativeGroup != "" {
+				return nil, errors.New("item_ids are required when the plan has alternative options")
+			}
+			accepted[item.Id] = true
+		}
+	} else {
+		items := make(map[uuid.UUID]models.TreatmentPlanItem, len(plan.Items))
+		for _, item := range plan.Items {
+			items[item.Id] = item
+		}
+		chosenGroups := make(map[string]bool)
+		for _, rawId := range req.ItemIds {
+			itemId, err := uuid.Parse(rawId)
+			if err != nil {
+				return nil, errors.New("invalid item id")
+			}
+			item, ok := items[itemId]
+			if !ok {
+				return nil, errors.New("item does not belong to the treatment plan")
+			}
+			if item.AlternativeGroup != "" {
+				if chosenGroups[item.AlternativeGroup] {
+					return nil, fmt.Errorf("only one option can be accepted from alternative group %q", item.AlternativeGroup)
+				}
+				chosenGroups[item.AlternativeGroup] = true
+			}
+			accepted[itemId] = true
+		}
+	}
+
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	for _, item := range plan.Items {
+		status := "declined"
+		if accepted[item.Id] {
+			status = "accepted"
+		}
+		if err := s.repo.UpdateItemStatusTx(item.Id, status, tx); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.repo.UpdatePlanStatusTx(plan.Id, "accepted", tx); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return s.repo.GetPlanByID(plan.Id)
+}
+
+func (s *TreatmentPlanService) DeclinePlan(ctx context.Context, userId, id string) (*models.TreatmentPlan, error) {
+	plan, err := s.getPlanForPatient(userId, id)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	for _, item := range plan.Items {
+		if err := s.repo.UpdateItemStatusTx(item.Id, "declined", tx); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.repo.UpdatePlanStatusTx(plan.Id, "declined", tx); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return s.repo.GetPlanByID(plan.Id)
+}
+
+func (s *TreatmentPlanService) getPlanForPatient(userId, id string) (*models.TreatmentPlan, error) {
+	planId, err := uuid.Parse(id)
+	if err != nil {
+		return nil, errors.New("invalid treatment plan id")
+	}
+	userUUID, err := uuid.Parse(userId)
+	if err != nil {
+		return nil, errors.New("invalid user id")
+	}
+	plan, err := s.repo.GetPlanByID(planId)
+	if err != nil {
+		return nil, err
+	}
+	if plan == nil {
+		return nil, errors.New("treatment plan not found")
+	}
+	if plan.PatientId != userUUID {
+		return nil, errors.New("access denied")
+	}
+	if plan.Status != "proposed" {
+		return nil, errors.New("treatment plan has already been answered")
+	}
+	return plan, nil
+}
+
+// CompleteItem lets the treating doctor mark an item as done, e.g. when it
+// was performed during a visit booked for something else.
+func (s *TreatmentPlanService) CompleteItem(ctx context.Context, userId, itemId string) (*models.TreatmentPlan, error) {
+	itemUUID, err := uuid.Parse(itemId)
+	if err != nil {
+		return nil, errors.New("invalid item id")
+	}
+	userUUID, err := uuid.Parse(userId)
+	if err != nil {
+		return nil, errors.New("invalid user id")
+	}
+
+	item, err := s.repo.GetItemContext(itemUUID)
+	if err != nil {
+		return nil, err
+	}
+	if item == nil {
+		return nil, errors.New("treatment plan item not found")
+	}
+	if item.DoctorUserId != userUUID {
+		return nil, errors.New("access denied")
+	}
+	if item.Status != "accepted" && item.Status != "scheduled" {
+		return nil, errors.New("only accepted items can be completed")
+	}
+
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := s.repo.CompleteItemTx(item.Id, tx); err != nil {
+		return nil, err
+	}
+	if err := s.repo.RefreshPlanStatusTx(item.PlanId, tx); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return s.repo.GetPlanByID(item.PlanId)
+}
+
+// GetBookableItem checks that the patient may book an appointment for the
+// item and returns it.
+func (s *TreatmentPlanService) GetBookableItem(userId uuid.UUID, itemId string) (*models.PlanItemContext, error) {
+	itemUUID, err := uuid.Parse(itemId)
+	if err != nil {
+		return nil, errors.New("invalid treatment plan item id")
+	}
+	item, err := s.repo.GetItemContext(itemUUID)
+	if err != nil {
+		return nil, err
+	}
+	if item == nil || item.PatientId != userId {
+		return nil, errors.New("treatment plan item not found")
+	}
+	switch item.Status {
+	case "accepted", "scheduled":
+		return item, nil
+	case "completed":
+		return nil, errors.New("treatment plan item is already completed")
+	default:
+		return nil, errors.New("treatment plan item is not accepted")
+	}
+}
+
+// LinkAppointmentTx marks the item as scheduled for the appointment and moves
+// the plan to in progress.
+func (s *TreatmentPlanService) LinkAppointmentTx(item *models.PlanItemContext, appointmentId uuid.UUID, tx pgx.Tx) error {
+	if err := s.repo.LinkAppointmentTx(item.Id, appointmentId, tx); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return errors.New("treatment plan item is already scheduled")
+		}
+		return err
+	}
+	return s.repo.RefreshPlanStatusTx(item.PlanId, tx)
+}
+
+// Summarize computes the estimate range and progress of a plan. The minimum
+// estimate takes the cheapest option of each alternative group, the maximum
+// the most expensive one.
+func Summarize(plan models.TreatmentPlan) PlanSummary {
+	var summary PlanSummary
+
+	groupMin := make(map[string]float64)
+	groupMax := make(map[string]float64)
+	for _, item := range plan.Items {
+		if item.AlternativeGroup == "" {
+			summary.EstimateMin += item.Price
+			summary.EstimateMax += item.Price
+		} else {
+			if current, ok := groupMin[item.AlternativeGroup]; !ok || item.Price < current {
+				groupMin[item.AlternativeGroup] = item.Price
+			}
+			if current, ok := groupMax[item.AlternativeGroup]; !ok || item.Price > current {
+				groupMax[item.AlternativeGroup] = item.Price
+			}
+		}
+
+		switch item.Status {
+		case "accepted", "scheduled":
+			summary.AcceptedTotal += item.Price
+			summary.TotalItems++
+		case "completed":
+			summary.AcceptedTotal += item.Price
+			summary.TotalItems++
+			summary.CompletedItems++
+		}
+	}
+
+	groups := make([]string, 0, len(groupMin))
+	for group := range groupMin {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+	for _, group := range groups {
+		summary.EstimateMin += groupMin[group]
+		summary.EstimateMax += groupMax[group]
+	}
+
+	if summary.TotalItems > 0 {
+		summary.ProgressPercent = summary.CompletedItems * 100 / summary.TotalItems
+	}
+	return summary
+}
+
+func (s *TreatmentPlanService) checkAccess(recordId uuid.UUID, userId string) error {
+	allowed, err := s.medical_recordSrv.CanAccessMedicalRecord(recordId.String(), userId)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return errors.New("access denied")
+	}
+	return nil
+}
+
+// validToothNumber accepts two-digit FDI notation: quadrants 1-4 with teeth
+// 1-8 for permanent and quadrants 5-8 with teeth 1-5 for primary dentition.
+func validToothNumber(tooth string) bool {
+	if len(tooth) != 2 || tooth[0] < '1' || tooth[0] > '8' || tooth[1] < '1' {
+		return false
+	}
+	if tooth[0] <= '4' {
+		return tooth[1] <= '8'
+	}
+	return tooth[1] <= '5'
+}
@@ -7,13 +7,16 @@ import (
 	"dental_clinic/internal/modules/user/handlers"
 	"dental_clinic/internal/modules/user/repository"
 	"dental_clinic/internal/modules/user/services"
+	"dental_clinic/internal/queue"
 
 	"github.com/gorilla/mux"
 )
 
 func RegisterPublicRoutes(r *mux.Router, db *pgxpool.Pool, cfg *config.Config) {
+	jobQueue := queue.New(db)
+
 	repo := repository.NewUserRepository(db)
-	service := services.NewUserService(repo, *cfg)
+	service := services.NewUserService(repo, *cfg, jobQueue)
 	handler := handlers.NewUserHandler(service, *cfg)
 
 	r.HandleFunc("/register", handler.Register).Methods("POST")
@@ -23,8 +26,10 @@ func RegisterPublicRoutes(r *mux.Router, db *pgxpool.Pool, cfg *config.Config) {
 }
 
 func RegisterPrivateRoutes(r *mux.Router, db *pgxpool.Pool, cfg *config.Config) {
+	jobQueue := queue.New(db)
+
 	repo := repository.NewUserRepository(db)
-	service := services.NewUserService(repo, *cfg)
+	service := services.NewUserService(repo, *cfg, jobQueue)
 	handler := handlers.NewUserHandler(service, *cfg)
 
 	r.HandleFunc("/users/{id}", handler.GetUserByID).Methods("GET")
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"regexp"
@@ -9,6 +10,7 @@ import (
 	"dental_clinic/internal/modules/user/dto"
 	"dental_clinic/internal/modules/user/models"
 	"dental_clinic/internal/modules/user/repository"
+	"dental_clinic/internal/queue"
 	"dental_clinic/internal/utils"
 
 	"errors"
@@ -18,14 +20,16 @@ import (
 )
 
 type UserService struct {
-	repo repository.UserRepository
-	cfx  config.Config
+	repo  repository.UserRepository
+	cfx   config.Config
+	queue *queue.Queue
 }
 
-func NewUserService(r repository.UserRepository, cfx config.Config) *UserService {
+func NewUserService(r repository.UserRepository, cfx config.Config, q *queue.Queue) *UserService {
 	return &UserService{
-		repo: r,
-		cfx:  cfx,
+		repo:  r,
+		cfx:   cfx,
+		queue: q,
 	}
 }
 
@@ -62,11 +66,9 @@ func (s *UserService) Register(req dto.RegisterRequest) (*models.User, error) {
 	if err != nil {
 		return created_user, err
 	}
-	go func() {
-		if err := utils.SendVerificationEmail(&s.cfx, user.Email, token); err != nil {
-			log.Printf("send email error: %v", err)
-		}
-	}()
+	if err := utils.QueueVerificationEmail(context.Background(), s.queue, &s.cfx, user.Email, token); err != nil {
+		log.Printf("queue email error: %v", err)
+	}
 
 	s.repo.MarkUserAsVerified(created_user.Id.String())
 
@@ -251,7 +253,7 @@ func (s *UserService) UpdatePassword(tokenStr string, req dto.UpdatePasswordRequ
 	if err != nil {
 		return err
 	}
-	err = utils.SendEmail(&s.cfx, user.Email, "You have updated your Password", "You have updated your Password")
+	err = utils.QueueEmail(context.Background(), s.queue, user.Email, "You have updated your Password", "You have updated your Password")
 	if err != nil {
 		return err
 	}
@@ -281,7 +283,7 @@ func (s *UserService) UpdateEmail(tokenStr string, req dto.UpdateEmailRequest) e
 	)
 	body := fmt.Sprintf("You need to do to the link: %s", link)
 
-	return utils.SendEmail(&s.cfx, req.NewEmail, "Confirm email", body)
+	return utils.QueueEmail(context.Background(), s.queue, req.NewEmail, "Confirm email", body)
 }
 
 func (s *UserService) VerifyEmailToken(token string) error {
@@ -337,7 +339,7 @@ func (s *UserService) UpdatePasswordWithUserId(user_id, password string) error {
 	if err != nil {
 		return err
 	}
-	err = utils.SendEmail(&s.cfx, user.Email, "You have updated your Password", "You have updated your Password")
+	err = utils.QueueEmail(context.Background(), s.queue, user.Email, "You have updated your Password", "You have updated your Password")
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,26 @@
+package notify
+
+import (
+	"context"
+	"html"
+
+	"dental_clinic/internal/config"
+	"dental_clinic/internal/utils"
+)
+
+// ResendEmail sends email through Resend.
+type ResendEmail struct {
+	cfg *config.Config
+}
+
+func NewResendEmail(cfg *config.Config) *ResendEmail {
+	return &ResendEmail{cfg: cfg}
+}
+
+func (e *ResendEmail) Send(ctx context.Context, msg Message) error {
+	body := msg.HTML
+	if body == "" {
+		body = "<p>" + html.EscapeString(msg.Text) + "</p>"
+	}
+	return utils.SendEmail(e.cfg, msg.To, msg.Subject, body)
+}
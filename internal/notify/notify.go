@@ -0,0 +1,54 @@
+// Package notify delivers messages to patients over email and SMS. Each
+// channel has a Provider; which one is used is set in the config, and a
+// channel without a provider is left out so nothing is sent over it.
+package notify
+
+import (
+	"context"
+
+	"dental_clinic/internal/config"
+)
+
+const (
+	ChannelEmail = "email"
+	ChannelSMS   = "sms"
+)
+
+// Channels lists the channels in the order they are shown.
+var Channels = []string{ChannelEmail, ChannelSMS}
+
+// Message is one notification. HTML is used by channels that can show it,
+// Text by the others.
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Provider delivers messages over one channel.
+type Provider interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// NewProviders returns the provider of every channel configured in cfg.
+func NewProviders(cfg *config.Config) map[string]Provider {
+	providers := map[string]Provider{}
+	if cfg.EmailProvider == "resend" {
+		providers[ChannelEmail] = NewResendEmail(cfg)
+	}
+	if cfg.SMSProvider == "twilio" {
+		providers[ChannelSMS] = NewTwilioSMS(cfg.TwilioAccountSID, cfg.TwilioAuthToken, cfg.TwilioFrom)
+	}
+	return providers
+}
+
+// IsChannel reports whether channel is one messages can be sent over.
+func IsChannel(channel string) bool {
+	for _, c := range Channels {
+		if c == channel {
+			return true
+		}
+	}
+	return false
+}
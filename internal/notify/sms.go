@@ -0,0 +1,61 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const twilioMessagesURL = "https://api.twilio.com/2010-04-01/Accounts/%s/Messages.json"
+
+// TwilioSMS sends text messages through the Twilio REST API.
+type TwilioSMS struct {
+	accountSID string
+	authToken  string
+	from       string
+	client     *http.Client
+}
+
+func NewTwilioSMS(accountSID, authToken, from string) *TwilioSMS {
+	return &TwilioSMS{
+		accountSID: accountSID,
+		authToken:  authToken,
+		from:       from,
+		client:     &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+func (t *TwilioSMS) Send(ctx context.Context, msg Message) error {
+	if t.accountSID == "" || t.authToken == "" || t.from == "" {
+		return errors.New("twilio is not configured")
+	}
+
+	form := url.Values{}
+	form.Set("To", msg.To)
+	form.Set("From", t.from)
+	form.Set("Body", msg.Text)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf(twilioMessagesURL, t.accountSID), strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(t.accountSID, t.authToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("twilio returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+// Package queue is a job queue kept in Postgres. Jobs are queued with
+// Enqueue and picked up by a Worker, which runs the handler registered for
+// the job's type, retries failed jobs with backoff and queues the jobs of
+// its cron schedules.
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	PriorityLow     = -10
+	PriorityDefault = 0
+	PriorityHigh    = 10
+
+	// DefaultMaxAttempts is how often a job is tried before it is given up on
+	// unless the job says otherwise.
+	DefaultMaxAttempts = 5
+)
+
+// Job is a piece of work for the workers. Payload is stored as JSON and
+// handed to the handler registered for Type.
+type Job struct {
+	Type     string
+	Payload  interface{}
+	Priority int
+	// RunAt holds the job back until then; the zero time runs it as soon as
+	// a worker is free.
+	RunAt       time.Time
+	MaxAttempts int
+	// UniqueKey, when set, keeps a second job with the same key from being
+	// queued while the first one is waiting or running.
+	UniqueKey string
+}
+
+type querier interface {
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+type Queue struct {
+	db *pgxpool.Pool
+}
+
+func New(db *pgxpool.Pool) *Queue {
+	return &Queue{db: db}
+}
+
+// Enqueue queues the job and returns its id. A job whose unique key is
+// already waiting or running is not queued again; the id of that job is
+// returned instead.
+func (q *Queue) Enqueue(ctx context.Context, job Job) (uuid.UUID, error) {
+	return enqueue(ctx, q.db, job)
+}
+
+// EnqueueTx queues the job in tx, so it only runs if tx commits.
+func (q *Queue) EnqueueTx(ctx context.Context, tx pgx.Tx, job Job) (uuid.UUID, error) {
+	return enqueue(ctx, tx, job)
+}
+
+func enqueue(ctx context.Context, db querier, job Job) (uuid.UUID, error) {
+	if job.Type == "" {
+		return uuid.Nil, errors.New("job type is required")
+	}
+	payload, err := json.Marshal(job.Payload)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if job.MaxAttempts <= 0 {
+		job.MaxAttempts = DefaultMaxAttempts
+	}
+	var runAt *time.Time
+	if !job.RunAt.IsZero() {
+		utc := job.RunAt.UTC()
+		runAt = &utc
+	}
+	var uniqueKey *string
+	if job.UniqueKey != "" {
+		uniqueKey = &job.UniqueKey
+	}
+
+	query := `
+		INSERT INTO queued_jobs (type, payload, priority, max_attempts, unique_key, run_at)
+		VALUES ($1, $2, $3, $4, $5, COALESCE($6::timestamp, NOW()))
+		ON CONFLICT (unique_key) WHERE status IN ('queued', 'running') DO NOTHING
+		RETURNING id
+	`
+	var id uuid.UUID
+	err = db.QueryRow(ctx, query, job.Type, payload, job.Priority, job.MaxAttempts, uniqueKey, runAt).Scan(&id)
+	if err == pgx.ErrNoRows && uniqueKey != nil {
+		existingQuery := `SELECT id FROM queued_jobs WHERE unique_key = $1 AND status IN ('queued', 'running')`
+		err = db.QueryRow(ctx, existingQuery, *uniqueKey).Scan(&id)
+	}
+	return id, err
+}
@@ -0,0 +1,423 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	pollInterval     = 2 * time.Second
+	scheduleInterval = 30 * time.Second
+	// jobTimeout bounds a single attempt of a job. Jobs still marked running
+	// well after it belonged to an instance that stopped and are queued again.
+	jobTimeout = 10 * time.Minute
+	// shutdownTimeout is how long Run waits for running jobs once it is
+	// told to stop before it cancels them and hands them back to the queue.
+	shutdownTimeout = 30 * time.Second
+	// retryBackoff is the wait before the first retry of a failed job; it
+	// doubles with every attempt up to maxRetryBackoff.
+	retryBackoff    = 30 * time.Second
+	maxRetryBackoff = time.Hour
+
+	pruneJob = "queue.prune"
+)
+
+// Handler runs a job of one type. A returned error fails the attempt and the
+// job is retried later, unless the error is Permanent or the job is out of
+// attempts.
+type Handler func(ctx context.Context, payload json.RawMessage) error
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as one a retry cannot fix, such as a payload referring
+// to a row that is gone, so the job is given up on right away.
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+type attemptKey struct{}
+
+type attempt struct {
+	number, max int
+}
+
+// IsLastAttempt reports whether the job a handler is running is out of
+// retries if this attempt fails.
+func IsLastAttempt(ctx context.Context) bool {
+	a, ok := ctx.Value(attemptKey{}).(attempt)
+	return ok && a.number >= a.max
+}
+
+type schedule struct {
+	name string
+	spec string
+	cron cronSpec
+	job  Job
+}
+
+type claimedJob struct {
+	id          uuid.UUID
+	jobType     string
+	payload     json.RawMessage
+	attempts    int
+	maxAttempts int
+}
+
+// Worker runs the jobs of the types registered with it, concurrency at a
+// time, and queues the jobs of its schedules when they are due. Every app
+// instance may run a worker: a job is claimed by one worker only and a
+// schedule is queued once per run.
+type Worker struct {
+	db          *pgxpool.Pool
+	concurrency int
+	instance    string
+	handlers    map[string]Handler
+	schedules   []schedule
+}
+
+func NewWorker(db *pgxpool.Pool, concurrency int) *Worker {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	instance, _ := os.Hostname()
+	w := &Worker{
+		db:          db,
+		concurrency: concurrency,
+		instance:    fmt.Sprintf("%s:%d", instance, os.Getpid()),
+		handlers:    make(map[string]Handler),
+	}
+	w.Register(pruneJob, w.prune)
+	// the spec is a constant, it parses
+	_ = w.Schedule("queue_prune", "30 3 * * *", Job{Type: pruneJob, Priority: PriorityLow})
+	return w
+}
+
+// Register makes the worker run jobs of jobType with handler.
+func (w *Worker) Register(jobType string, handler Handler) {
+	w.handlers[jobType] = handler
+}
+
+// Handle registers fn for jobType with the job's payload decoded into T.
+func Handle[T any](w *Worker, jobType string, fn func(ctx context.Context, payload T) error) {
+	w.Register(jobType, func(ctx context.Context, raw json.RawMessage) error {
+		var payload T
+		if err := json.Unmarshal(raw, &payload); err != nil {
+			return Permanent(fmt.Errorf("decode %s payload: %v", jobType, err))
+		}
+		return fn(ctx, payload)
+	})
+}
+
+// Schedule queues job whenever the cron spec is due, as "0 2 * * *" for
+// daily at 02:00 UTC. Runs missed while no worker was up are queued once
+// when one starts, not once per missed run.
+func (w *Worker) Schedule(name, spec string, job Job) error {
+	cron, err := parseCron(spec)
+	if err != nil {
+		return err
+	}
+	if cron.next(time.Now()).IsZero() {
+		return fmt.Errorf("cron spec %q never runs", spec)
+	}
+	w.schedules = append(w.schedules, schedule{name: name, spec: spec, cron: cron, job: job})
+	return nil
+}
+
+// Run works through the queue until ctx is done. It then stops taking jobs
+// and waits for the running ones; jobs still running after shutdownTimeout
+// are cancelled and queued again without using up an attempt.
+func (w *Worker) Run(ctx context.Context) {
+	jobCtx, cancelJobs := context.WithCancel(context.Background())
+	defer cancelJobs()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		w.runSchedules(ctx)
+	}()
+
+	slots := make(chan struct{}, w.concurrency)
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for ctx.Err() == nil {
+		w.requeueStaleJobs(ctx)
+
+		for ctx.Err() == nil {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				continue
+			}
+			job, err := w.claim(ctx)
+			if err != nil || job == nil {
+				<-slots
+				if err != nil && ctx.Err() == nil {
+					log.Printf("queue: claim: %v", err)
+				}
+				break
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-slots }()
+				w.execute(jobCtx, *job)
+			}()
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-ticker.C:
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(shutdownTimeout):
+		log.Printf("queue: cancelling jobs still running after %s", shutdownTimeout)
+		cancelJobs()
+		<-done
+	}
+}
+
+func (w *Worker) jobTypes() []string {
+	types := make([]string, 0, len(w.handlers))
+	for jobType := range w.handlers {
+		types = append(types, jobType)
+	}
+	return types
+}
+
+// claim takes the next due job this worker has a handler for, or nil when
+// there is none.
+func (w *Worker) claim(ctx context.Context) (*claimedJob, error) {
+	query := `
+		UPDATE queued_jobs
+		SET status = 'running', attempts = attempts + 1, locked_by = $2, locked_at = NOW()
+		WHERE id = (
+			SELECT id FROM queued_jobs
+			WHERE status = 'queued'
+				AND run_at <= NOW()
+				AND type = ANY($1)
+			ORDER BY priority DESC, run_at, created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, type, payload, attempts, max_attempts
+	`
+	var job claimedJob
+	err := w.db.QueryRow(ctx, query, w.jobTypes(), w.instance).Scan(&job.id, &job.jobType, &job.payload, &job.attempts, &job.maxAttempts)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (w *Worker) execute(ctx context.Context, job claimedJob) {
+	runCtx, cancel := context.WithTimeout(ctx, jobTimeout)
+	defer cancel()
+	runCtx = context.WithValue(runCtx, attemptKey{}, attempt{number: job.attempts, max: job.maxAttempts})
+
+	err := runHandler(runCtx, w.handlers[job.jobType], job.payload)
+	switch {
+	case err == nil:
+		w.succeed(job)
+	case ctx.Err() != nil:
+		w.release(job)
+	default:
+		w.fail(job, err)
+	}
+}
+
+func runHandler(ctx context.Context, handler Handler, payload json.RawMessage) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("panic: %v", recovered)
+		}
+	}()
+	return handler(ctx, payload)
+}
+
+func (w *Worker) succeed(job claimedJob) {
+	query := `UPDATE queued_jobs SET status = 'succeeded', last_error = '', finished_at = NOW() WHERE id = $1`
+	if _, err := w.db.Exec(context.Background(), query, job.id); err != nil {
+		log.Printf("queue: %s job %s: %v", job.jobType, job.id, err)
+	}
+}
+
+// fail queues the job again after a backoff that doubles with every attempt,
+// or gives up on it when it is out of attempts or the error is permanent.
+func (w *Worker) fail(job claimedJob, jobErr error) {
+	var permanent *permanentError
+	if job.attempts >= job.maxAttempts || errors.As(jobErr, &permanent) {
+		log.Printf("queue: gave up on %s job %s after %d attempt(s): %v", job.jobType, job.id, job.attempts, jobErr)
+		query := `UPDATE queued_jobs SET status = 'dead', last_error = $2, finished_at = NOW() WHERE id = $1`
+		if _, err := w.db.Exec(context.Background(), query, job.id, jobErr.Error()); err != nil {
+			log.Printf("queue: %s job %s: %v", job.jobType, job.id, err)
+		}
+		return
+	}
+
+	backoff := retryBackoff << (job.attempts - 1)
+	if backoff > maxRetryBackoff {
+		backoff = maxRetryBackoff
+	}
+	log.Printf("queue: %s job %s failed, retrying in %s: %v", job.jobType, job.id, backoff, jobErr)
+	query := `
+		UPDATE queued_jobs
+		SET status = 'queued', last_error = $2, run_at = NOW() + $3 * INTERVAL '1 second'
+		WHERE id = $1
+	`
+	if _, err := w.db.Exec(context.Background(), query, job.id, jobErr.Error(), backoff.Seconds()); err != nil {
+		log.Printf("queue: %s job %s: %v", job.jobType, job.id, err)
+	}
+}
+
+// release hands a job cancelled by shutdown back to the queue.
+func (w *Worker) release(job claimedJob) {
+	query := `
+		UPDATE queued_jobs
+		SET status = 'queued', attempts = attempts - 1, last_error = 'interrupted by shutdown'
+		WHERE id = $1
+	`
+	if _, err := w.db.Exec(context.Background(), query, job.id); err != nil {
+		log.Printf("queue: %s job %s: %v", job.jobType, job.id, err)
+	}
+}
+
+// requeueStaleJobs queues again the jobs left running by an instance that
+// stopped without finishing them.
+func (w *Worker) requeueStaleJobs(ctx context.Context) {
+	query := `
+		UPDATE queued_jobs
+		SET status = CASE WHEN attempts >= max_attempts THEN 'dead' ELSE 'queued' END,
+			finished_at = CASE WHEN attempts >= max_attempts THEN NOW() END,
+			last_error = 'interrupted'
+		WHERE status = 'running'
+			AND locked_at < NOW() - $1 * INTERVAL '1 second'
+	`
+	if _, err := w.db.Exec(ctx, query, (2 * jobTimeout).Seconds()); err != nil && ctx.Err() == nil {
+		log.Printf("queue: requeue stale jobs: %v", err)
+	}
+}
+
+func (w *Worker) runSchedules(ctx context.Context) {
+	if len(w.schedules) == 0 {
+		return
+	}
+	if err := w.syncSchedules(ctx); err != nil {
+		log.Printf("queue: sync schedules: %v", err)
+	}
+
+	ticker := time.NewTicker(scheduleInterval)
+	defer ticker.Stop()
+	for {
+		for _, s := range w.schedules {
+			if err := w.queueIfDue(ctx, s); err != nil && ctx.Err() == nil {
+				log.Printf("queue: schedule %s: %v", s.name, err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// syncSchedules stores the worker's schedules. A schedule whose spec
+// changed starts over from its next run under the new spec.
+func (w *Worker) syncSchedules(ctx context.Context) error {
+	query := `
+		INSERT INTO job_schedules (name, spec, job_type, payload, priority, next_run_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (name) DO UPDATE
+		SET next_run_at = CASE WHEN job_schedules.spec = EXCLUDED.spec THEN job_schedules.next_run_at ELSE EXCLUDED.next_run_at END,
+			spec = EXCLUDED.spec,
+			job_type = EXCLUDED.job_type,
+			payload = EXCLUDED.payload,
+			priority = EXCLUDED.priority,
+			updated_at = NOW()
+	`
+	now := time.Now()
+	for _, s := range w.schedules {
+		payload, err := json.Marshal(s.job.Payload)
+		if err != nil {
+			return err
+		}
+		if _, err := w.db.Exec(ctx, query, s.name, s.spec, s.job.Type, payload, s.job.Priority, s.cron.next(now)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// queueIfDue queues the schedule's job when its run is due. The schedule's
+// row is locked while doing so, so only one instance queues each run.
+func (w *Worker) queueIfDue(ctx context.Context, s schedule) error {
+	tx, err := w.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var nextRunAt time.Time
+	dueQuery := `
+		SELECT next_run_at FROM job_schedules
+		WHERE name = $1 AND next_run_at <= NOW()
+		FOR UPDATE SKIP LOCKED
+	`
+	if err := tx.QueryRow(ctx, dueQuery, s.name).Scan(&nextRunAt); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil
+		}
+		return err
+	}
+
+	job := s.job
+	job.UniqueKey = "schedule:" + s.name
+	if _, err := enqueue(ctx, tx, job); err != nil {
+		return err
+	}
+	updateQuery := `UPDATE job_schedules SET next_run_at = $2, last_run_at = NOW() WHERE name = $1`
+	if _, err := tx.Exec(ctx, updateQuery, s.name, s.cron.next(time.Now())); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// prune deletes finished jobs once they are of no more use for looking into
+// what happened.
+func (w *Worker) prune(ctx context.Context, _ json.RawMessage) error {
+	query := `
+		DELETE FROM queued_jobs
+		WHERE (status = 'succeeded' AND finished_at < NOW() - INTERVAL '14 days')
+			OR (status = 'dead' AND finished_at < NOW() - INTERVAL '90 days')
+	`
+	_, err := w.db.Exec(ctx, query)
+	return err
+}
@@ -14,10 +14,17 @@ import (
 	"dental_clinic/internal/modules/clinic_admin"
 	"dental_clinic/internal/modules/doctor"
 	"dental_clinic/internal/modules/inventory"
+	"dental_clinic/internal/modules/jobs"
 	"dental_clinic/internal/modules/medical_record"
+	"dental_clinic/internal/modules/prescription"
+	"dental_clinic/internal/modules/pricing"
+	"dental_clinic/internal/modules/purchasing"
+	"dental_clinic/internal/modules/reminders"
 	"dental_clinic/internal/modules/reports"
+	"dental_clinic/internal/modules/reviews"
 	"dental_clinic/internal/modules/schedule"
 	dentalservices "dental_clinic/internal/modules/services"
+	"dental_clinic/internal/modules/treatment_plan"
 	"dental_clinic/internal/modules/user"
 
 	_ "dental_clinic/docs"
@@ -30,6 +37,10 @@ import (
 func NewRouter(cfg *config.Config, db *pgxpool.Pool) http.Handler {
 	router := mux.NewRouter()
 
+	// medical files are only served through /api/files/medical-records with access checks
+	router.PathPrefix("/uploads/medical_records/").Handler(http.NotFoundHandler())
+	// credential documents are only served through /api/doctor-credentials/{id}/document
+	router.PathPrefix("/uploads/doctor_credentials/").Handler(http.NotFoundHandler())
 	router.PathPrefix("/uploads/").Handler(http.StripPrefix("/uploads/", http.FileServer(http.Dir("./uploads"))))
 
 	// Swagger documentation
@@ -46,6 +57,8 @@ func NewRouter(cfg *config.Config, db *pgxpool.Pool) http.Handler {
 	dentalservices.RegisterPublicRoutes(public, db, cfg)
 	schedule.RegisterPublicRoutes(public, db, cfg)
 	appointment.RegisterPublicRoutes(public, db, cfg)
+	reviews.RegisterPublicRoutes(public, db, cfg)
+	pricing.RegisterPublicRoutes(public, db, cfg)
 
 	// Private routes
 	private := api.NewRoute().Subrouter()
@@ -59,14 +72,23 @@ func NewRouter(cfg *config.Config, db *pgxpool.Pool) http.Handler {
 	schedule.RegisterPrivateRoutes(private, db, cfg)
 	appointment.RegisterPrivateRoutes(private, db, cfg)
 	ai_assistant.RegisterPrivateRoutes(private, db, cfg)
-	medical_record.RegisterPrivateRoutes(private, db)
+	medical_record.RegisterPrivateRoutes(private, db, cfg)
+	prescription.RegisterPrivateRoutes(private, db, cfg)
+	treatment_plan.RegisterPrivateRoutes(private, db, cfg)
+	pricing.RegisterPrivateRoutes(private, db, cfg)
 	inventory.RegisterPrivateRoutes(private, db, cfg)
+	purchasing.RegisterPrivateRoutes(private, db, cfg)
 	reports.RegisterPrivateRoutes(private, db, cfg)
+	reminders.RegisterPrivateRoutes(private, db, cfg)
+	reviews.RegisterPrivateRoutes(private, db, cfg)
+	jobs.RegisterPrivateRoutes(private, db, cfg)
 
 	doctor_subrouter := api.NewRoute().Subrouter()
 	doctor_subrouter.Use(middleware.JWTAuth(cfg.JWTSecret))
 	doctor_subrouter.Use(middleware.RequireRoles("doctor"))
 	medical_record.RegisterDoctorRoutes(doctor_subrouter, db, cfg)
+	prescription.RegisterDoctorRoutes(doctor_subrouter, db, cfg)
+	treatment_plan.RegisterDoctorRoutes(doctor_subrouter, db, cfg)
 
 	// CORS configuration
 	headersOk := gorilla_handler.AllowedHeaders([]string{"X-Requested-With", "Content-Type", "Authorization"})
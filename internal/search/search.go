@@ -0,0 +1,282 @@
+// Package search holds what the public listings (clinics, doctors, services)
+// share: query parameter parsing, sort definitions and keyset pagination with
+// opaque cursors.
+package search
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+	// MaxAvailableWithin caps the availability look-ahead in days.
+	MaxAvailableWithin = 60
+
+	// TextConfig is the Postgres text search configuration. Names are stored in
+	// Russian, Kazakh and English, so no language-specific stemming is applied.
+	TextConfig = "simple"
+)
+
+// InvalidError reports a bad filter, sort or cursor sent by the client.
+type InvalidError struct {
+	msg string
+}
+
+func (e *InvalidError) Error() string { return e.msg }
+
+func invalid(msg string) error {
+	return &InvalidError{msg: msg}
+}
+
+// IsInvalid tells client mistakes apart from database failures.
+func IsInvalid(err error) bool {
+	var target *InvalidError
+	return errors.As(err, &target)
+}
+
+var ErrInvalidCursor = invalid("invalid cursor")
+
+// Params are the filters, sort and page requested by a client. Zero values
+// mean "not set"; each listing ignores the filters it does not support.
+type Params struct {
+	Query           string
+	City            string
+	ClinicID        uuid.UUID
+	ServiceID       uuid.UUID
+	Specialization  string
+	Language        string
+	PriceMin        *float64
+	PriceMax        *float64
+	MinRating       float64
+	AvailableWithin int
+
+	Sort   string
+	Cursor *Cursor
+	Limit  int
+}
+
+// ParseParams reads Params from the query string:
+// q, city, clinic_id, service_id, specialization, language, price_min,
+// price_max, min_rating, available_within (days), sort, cursor and limit.
+func ParseParams(values url.Values) (Params, error) {
+	p := Params{
+		Query:          strings.TrimSpace(values.Get("q")),
+		City:           strings.TrimSpace(values.Get("city")),
+		Specialization: strings.TrimSpace(values.Get("specialization")),
+		Language:       strings.ToLower(strings.TrimSpace(values.Get("language"))),
+		Sort:           strings.TrimSpace(values.Get("sort")),
+		Limit:          DefaultLimit,
+	}
+
+	var err error
+	if v := values.Get("clinic_id"); v != "" {
+		if p.ClinicID, err = uuid.Parse(v); err != nil {
+			return p, invalid("invalid clinic_id")
+		}
+	}
+	if v := values.Get("service_id"); v != "" {
+		if p.ServiceID, err = uuid.Parse(v); err != nil {
+			return p, invalid("invalid service_id")
+		}
+	}
+	if p.PriceMin, err = parsePrice(values.Get("price_min")); err != nil {
+		return p, invalid("invalid price_min")
+	}
+	if p.PriceMax, err = parsePrice(values.Get("price_max")); err != nil {
+		return p, invalid("invalid price_max")
+	}
+	if p.PriceMin != nil && p.PriceMax != nil && *p.PriceMin > *p.PriceMax {
+		return p, invalid("price_min must not exceed price_max")
+	}
+	if v := values.Get("min_rating"); v != "" {
+		p.MinRating, err = strconv.ParseFloat(v, 64)
+		if err != nil || p.MinRating < 0 || p.MinRating > 5 {
+			return p, invalid("min_rating must be between 0 and 5")
+		}
+	}
+	if v := values.Get("available_within"); v != "" {
+		p.AvailableWithin, err = strconv.Atoi(v)
+		if err != nil || p.AvailableWithin < 1 || p.AvailableWithin > MaxAvailableWithin {
+			return p, invalid("available_within must be between 1 and " + strconv.Itoa(MaxAvailableWithin))
+		}
+	}
+	if v := values.Get("limit"); v != "" {
+		p.Limit, err = strconv.Atoi(v)
+		if err != nil || p.Limit < 1 {
+			return p, invalid("invalid limit")
+		}
+		if p.Limit > MaxLimit {
+			p.Limit = MaxLimit
+		}
+	}
+	if v := values.Get("cursor"); v != "" {
+		if p.Cursor, err = DecodeCursor(v); err != nil {
+			return p, err
+		}
+	}
+	return p, nil
+}
+
+func parsePrice(v string) (*float64, error) {
+	if v == "" {
+		return nil, nil
+	}
+	price, err := strconv.ParseFloat(v, 64)
+	if err != nil || price < 0 {
+		return nil, invalid("invalid price")
+	}
+	return &price, nil
+}
+
+// Cursor points just past the last row of a page. Key is the value of the
+// sort expression of that row rendered as text, Sort the sort it belongs to.
+type Cursor struct {
+	Sort string    `json:"s"`
+	Key  string    `json:"k"`
+	Id   uuid.UUID `json:"id"`
+}
+
+func (c Cursor) Encode() string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func DecodeCursor(s string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(raw, &c); err != nil || c.Id == uuid.Nil {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// Sort is an ordering a listing offers. Expr must never be NULL so that rows
+// compare cleanly in the keyset condition.
+type Sort struct {
+	Expr string
+	// Type is the Postgres type the cursor key is cast back to.
+	Type string
+	Desc bool
+}
+
+// ratingPriorWeight is how many virtual ratings at the platform mean every
+// doctor or clinic starts with when ranked by rating, so a handful of
+// reviews cannot outrank a long track record.
+const ratingPriorWeight = "10"
+
+// RatingScore is the Bayesian average used to rank by rating. It expects the
+// rating stats row as s and the platform mean as prior.mean.
+const RatingScore = `(prior.mean * ` + ratingPriorWeight + ` + COALESCE(s.rating_sum, 0)) / (` + ratingPriorWeight + ` + COALESCE(s.rating_count, 0))`
+
+// ResolveSort picks the requested sort from the ones a listing supports.
+// An empty name falls back to def, or to "relevance" when there is a text
+// query and the listing supports it.
+func ResolveSort(sorts map[string]Sort, name, def string, p Params) (string, Sort, error) {
+	if name == "" {
+		name = def
+		if _, ok := sorts["relevance"]; ok && p.Query != "" {
+			name = "relevance"
+		}
+	}
+	if name == "relevance" && p.Query == "" {
+		return "", Sort{}, invalid("sort=relevance requires q")
+	}
+	sort, ok := sorts[name]
+	if !ok {
+		return "", Sort{}, invalid("invalid sort: " + name)
+	}
+	if p.Cursor != nil && p.Cursor.Sort != name {
+		return "", Sort{}, ErrInvalidCursor
+	}
+	return name, sort, nil
+}
+
+// Builder collects WHERE conditions and their positional arguments.
+type Builder struct {
+	conditions []string
+	args       []any
+}
+
+func NewBuilder(args ...any) *Builder {
+	return &Builder{args: args}
+}
+
+// Arg registers a value and returns its placeholder.
+func (b *Builder) Arg(v any) string {
+	b.args = append(b.args, v)
+	return "$" + strconv.Itoa(len(b.args))
+}
+
+func (b *Builder) Where(condition string) {
+	b.conditions = append(b.conditions, condition)
+}
+
+func (b *Builder) Args() []any {
+	return b.args
+}
+
+// WhereSQL joins the conditions with AND, or returns "TRUE" when there are none.
+func (b *Builder) WhereSQL() string {
+	if len(b.conditions) == 0 {
+		return "TRUE"
+	}
+	return strings.Join(b.conditions, "\n\t\t\tAND ")
+}
+
+// Page adds the keyset condition for cursor and returns the ORDER BY and
+// LIMIT clause. One extra row is requested to tell whether a next page exists.
+func (b *Builder) Page(sort Sort, idExpr string, cursor *Cursor, limit int) string {
+	direction, cmp := "ASC", ">"
+	if sort.Desc {
+		direction, cmp = "DESC", "<"
+	}
+	if cursor != nil {
+		b.Where("(" + sort.Expr + ", " + idExpr + ") " + cmp + " (" + b.Arg(cursor.Key) + "::" + sort.Type + ", " + b.Arg(cursor.Id) + "::uuid)")
+	}
+	return "ORDER BY " + sort.Expr + " " + direction + ", " + idExpr + " " + direction + "\n\t\tLIMIT " + b.Arg(limit+1)
+}
+
+// Result is one page of a listing.
+type Result[T any] struct {
+	Items      []T
+	NextCursor string
+}
+
+// Trim drops the extra row requested by Builder.Page and builds the cursor
+// for the next page from the last row kept.
+func Trim[T any](items []T, keys []string, ids []uuid.UUID, sortName string, limit int) Result[T] {
+	if len(items) <= limit {
+		return Result[T]{Items: items}
+	}
+	return Result[T]{
+		Items:      items[:limit],
+		NextCursor: Cursor{Sort: sortName, Key: keys[limit-1], Id: ids[limit-1]}.Encode(),
+	}
+}
+
+// Page is the JSON envelope of a listing response.
+type Page struct {
+	Items      interface{} `json:"items"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+	Limit      int         `json:"limit"`
+}
+
+// TextQuery returns the FROM item that exposes the parsed text query as
+// "query", or an empty string when q is empty.
+func (b *Builder) TextQuery(q string) string {
+	if q == "" {
+		return ""
+	}
+	return "CROSS JOIN websearch_to_tsquery('" + TextConfig + "', " + b.Arg(q) + ") AS query"
+}
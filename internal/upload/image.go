@@ -0,0 +1,245 @@
+package upload
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+)
+
+const (
+	jpegQuality = 90
+
+	// maxGIFFrames and maxGIFPixels cap animated GIFs, whose frames are all
+	// decoded at once.
+	maxGIFFrames = 500
+	maxGIFPixels = 200_000_000
+)
+
+// MaxImagePixels caps width×height so a small file declaring a huge image
+// cannot make decoding it exhaust memory.
+const MaxImagePixels = 40_000_000
+
+func isImage(mimeType string) bool {
+	return mimeType == MimeJPEG || mimeType == MimePNG || mimeType == MimeGIF
+}
+
+// reencodeImage decodes and encodes the image again. Only pixel data survives,
+// so EXIF (including GPS), XMP, ICC comments and trailing payloads are dropped.
+// The EXIF orientation is applied first so photos keep their rotation.
+func reencodeImage(content []byte, mimeType string) ([]byte, error) {
+	if isImage(mimeType) {
+		if err := checkImageSize(content, mimeType); err != nil {
+			return nil, err
+		}
+	}
+
+	var out bytes.Buffer
+
+	switch mimeType {
+	case MimeJPEG:
+		img, err := jpeg.Decode(bytes.NewReader(content))
+		if err != nil {
+			return nil, ErrInvalidImage
+		}
+		img = applyOrientation(img, jpegOrientation(content))
+		if err := jpeg.Encode(&out, img, &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return nil, ErrInvalidImage
+		}
+	case MimePNG:
+		img, err := png.Decode(bytes.NewReader(content))
+		if err != nil {
+			return nil, ErrInvalidImage
+		}
+		if err := png.Encode(&out, img); err != nil {
+			return nil, ErrInvalidImage
+		}
+	case MimeGIF:
+		g, err := gif.DecodeAll(bytes.NewReader(content))
+		if err != nil {
+			return nil, ErrInvalidImage
+		}
+		if err := gif.EncodeAll(&out, g); err != nil {
+			return nil, ErrInvalidImage
+		}
+	default:
+		return content, nil
+	}
+
+	return out.Bytes(), nil
+}
+
+// checkImageSize reads the dimensions from the image header and rejects
+// images too large to decode safely, before any pixel data is decoded.
+func checkImageSize(content []byte, mimeType string) error {
+	config, _, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		return ErrInvalidImage
+	}
+	pixels := int64(config.Width) * int64(config.Height)
+	if pixels > MaxImagePixels {
+		return ErrImageTooLarge
+	}
+	if mimeType == MimeGIF {
+		frames, ok := gifFrameCount(content)
+		if !ok {
+			return ErrInvalidImage
+		}
+		if frames > maxGIFFrames || int64(frames)*pixels > maxGIFPixels {
+			return ErrImageTooLarge
+		}
+	}
+	return nil
+}
+
+// gifFrameCount counts the image descriptors of a GIF by walking its blocks
+// without decoding them. ok is false when the stream is malformed.
+func gifFrameCount(content []byte) (int, bool) {
+	// header and logical screen descriptor
+	pos := 13
+	if len(content) < pos {
+		return 0, false
+	}
+	if flags := content[10]; flags&0x80 != 0 {
+		pos += 3 << (flags&0x07 + 1)
+	}
+
+	frames := 0
+	for pos < len(content) {
+		switch content[pos] {
+		case 0x3B: // trailer
+			return frames, true
+		case 0x21: // extension: label, then sub-blocks
+			pos += 2
+		case 0x2C: // image descriptor, local color table, LZW code size
+			if pos+10 > len(content) {
+				return 0, false
+			}
+			flags := content[pos+9]
+			pos += 10
+			if flags&0x80 != 0 {
+				pos += 3 << (flags&0x07 + 1)
+			}
+			pos++
+			frames++
+		default:
+			return 0, false
+		}
+		// data sub-blocks end with a zero length block
+		for {
+			if pos >= len(content) {
+				return 0, false
+			}
+			size := int(content[pos])
+			pos++
+			if size == 0 {
+				break
+			}
+			pos += size
+		}
+	}
+	// a missing trailer is tolerated by decoders
+	return frames, true
+}
+
+// jpegOrientation returns the EXIF orientation (1-8) of a JPEG, or 1.
+func jpegOrientation(content []byte) int {
+	if len(content) < 4 || content[0] != 0xFF || content[1] != 0xD8 {
+		return 1
+	}
+
+	pos := 2
+	for pos+4 <= len(content) {
+		if content[pos] != 0xFF {
+			return 1
+		}
+		marker := content[pos+1]
+		size := int(binary.BigEndian.Uint16(content[pos+2:]))
+		if marker == 0xDA || size < 2 || pos+2+size > len(content) {
+			return 1
+		}
+		segment := content[pos+4 : pos+2+size]
+		if marker == 0xE1 && len(segment) > 6 && string(segment[:6]) == "Exif\x00\x00" {
+			return tiffOrientation(segment[6:])
+		}
+		pos += 2 + size
+	}
+	return 1
+}
+
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < entries; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			v := int(order.Uint16(tiff[entry+8:]))
+			if v >= 1 && v <= 8 {
+				return v
+			}
+			return 1
+		}
+	}
+	return 1
+}
+
+// applyOrientation transforms the image so it displays upright without the
+// EXIF orientation tag.
+func applyOrientation(src image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return src
+	}
+
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2:
+				dx, dy = w-1-x, y
+			case 3:
+				dx, dy = w-1-x, h-1-y
+			case 4:
+				dx, dy = x, h-1-y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = h-1-y, x
+			case 7:
+				dx, dy = h-1-y, w-1-x
+			case 8:
+				dx, dy = y, w-1-x
+			}
+			dst.Set(dx, dy, src.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	return dst
+}
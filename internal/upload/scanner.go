@@ -0,0 +1,113 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	"dental_clinic/internal/config"
+)
+
+// Scanner inspects file content before it is stored. Scan returns
+// ErrInfected for rejected content and ErrScanFailed when the scanner itself
+// did not work.
+type Scanner interface {
+	Scan(ctx context.Context, r io.Reader) error
+}
+
+// NewScanner returns a ClamAV scanner when CLAMAV_ADDR is set and the stub
+// otherwise.
+func NewScanner(cfg config.Config) Scanner {
+	if cfg.ClamAVAddr != "" {
+		return &ClamAVScanner{Addr: cfg.ClamAVAddr, Timeout: 30 * time.Second}
+	}
+	return StubScanner{}
+}
+
+// StubScanner is used in development. It only rejects the EICAR test
+// signature, which makes the rejection path easy to exercise.
+type StubScanner struct{}
+
+const eicarSignature = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+
+func (StubScanner) Scan(ctx context.Context, r io.Reader) error {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return ErrScanFailed
+	}
+	if bytes.Contains(content, []byte(eicarSignature)) {
+		return ErrInfected
+	}
+	return nil
+}
+
+// ClamAVScanner talks to clamd over TCP using the INSTREAM command.
+type ClamAVScanner struct {
+	Addr    string
+	Timeout time.Duration
+}
+
+const clamChunkSize = 64 << 10
+
+func (s *ClamAVScanner) Scan(ctx context.Context, r io.Reader) error {
+	dialer := net.Dialer{Timeout: s.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", s.Addr)
+	if err != nil {
+		log.Printf("clamav: dial %s: %v", s.Addr, err)
+		return ErrScanFailed
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(s.Timeout))
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return ErrScanFailed
+	}
+
+	buf := make([]byte, clamChunkSize)
+	size := make([]byte, 4)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			binary.BigEndian.PutUint32(size, uint32(n))
+			if _, werr := conn.Write(size); werr != nil {
+				return ErrScanFailed
+			}
+			if _, werr := conn.Write(buf[:n]); werr != nil {
+				return ErrScanFailed
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return ErrScanFailed
+		}
+	}
+	binary.BigEndian.PutUint32(size, 0)
+	if _, err := conn.Write(size); err != nil {
+		return ErrScanFailed
+	}
+
+	reply, err := io.ReadAll(conn)
+	if err != nil {
+		return ErrScanFailed
+	}
+	result := strings.TrimRight(string(reply), "\x00\n")
+
+	switch {
+	case strings.HasSuffix(result, "OK"):
+		return nil
+	case strings.HasSuffix(result, "FOUND"):
+		signature := strings.TrimSuffix(strings.TrimPrefix(result, "stream: "), " FOUND")
+		return fmt.Errorf("%w: %s", ErrInfected, signature)
+	default:
+		log.Printf("clamav: unexpected reply %q", result)
+		return ErrScanFailed
+	}
+}
@@ -0,0 +1,329 @@
+// Package upload is the single entry point for files sent by clients. It
+// enforces per-type allowlists and size quotas, generates server-side names,
+// strips image metadata by re-encoding and passes content through a scanner
+// before anything is written under ./uploads.
+package upload
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"dental_clinic/internal/config"
+)
+
+const (
+	MimeJPEG  = "image/jpeg"
+	MimePNG   = "image/png"
+	MimeGIF   = "image/gif"
+	MimePDF   = "application/pdf"
+	MimeDICOM = "application/dicom"
+
+	// formOverhead leaves room for the non-file fields of a multipart body.
+	formOverhead = 1 << 20
+	// memoryLimit is how much of a form is kept in memory before spilling to disk.
+	memoryLimit = 10 << 20
+
+	maxFilenameLength = 120
+)
+
+// Policy describes what an endpoint accepts.
+type Policy struct {
+	// AllowedTypes maps detected MIME types to the extension used on disk.
+	AllowedTypes map[string]string
+	MaxFileSize  int64
+	MaxFiles     int
+	MaxTotalSize int64
+	// StripMetadata re-encodes images so EXIF and other metadata are dropped.
+	StripMetadata bool
+}
+
+var (
+	ImagePolicy = Policy{
+		AllowedTypes: map[string]string{
+			MimeJPEG: ".jpg",
+			MimePNG:  ".png",
+			MimeGIF:  ".gif",
+		},
+		MaxFileSize:   5 << 20,
+		MaxFiles:      1,
+		MaxTotalSize:  5 << 20,
+		StripMetadata: true,
+	}
+
+	MedicalFilePolicy = Policy{
+		AllowedTypes: map[string]string{
+			MimeJPEG:  ".jpg",
+			MimePNG:   ".png",
+			MimePDF:   ".pdf",
+			MimeDICOM: ".dcm",
+		},
+		MaxFileSize:   50 << 20,
+		MaxFiles:      20,
+		MaxTotalSize:  200 << 20,
+		StripMetadata: true,
+	}
+
+	// CredentialPolicy is for scans of doctors' licenses and diplomas.
+	CredentialPolicy = Policy{
+		AllowedTypes: map[string]string{
+			MimeJPEG: ".jpg",
+			MimePNG:  ".png",
+			MimePDF:  ".pdf",
+		},
+		MaxFileSize:   10 << 20,
+		MaxFiles:      1,
+		MaxTotalSize:  10 << 20,
+		StripMetadata: true,
+	}
+)
+
+var (
+	ErrTooLarge      = errors.New("file is too large")
+	ErrTooMany       = errors.New("too many files")
+	ErrNotAllowed    = errors.New("file type is not allowed")
+	ErrInfected      = errors.New("file was rejected by the virus scanner")
+	ErrScanFailed    = errors.New("virus scan is unavailable")
+	ErrMissing       = errors.New("file is required")
+	ErrInvalidImage  = errors.New("image could not be decoded")
+	ErrImageTooLarge = errors.New("image dimensions are too large")
+	ErrInvalidBody   = errors.New("invalid request body")
+	ErrStorageFailed = errors.New("failed to save file")
+)
+
+// Error reports which file was rejected and why.
+type Error struct {
+	Field    string
+	Filename string
+	Err      error
+}
+
+func (e *Error) Error() string {
+	if e.Filename != "" {
+		return fmt.Sprintf("%s: %s", e.Filename, e.Err.Error())
+	}
+	if e.Field != "" {
+		return fmt.Sprintf("%s: %s", e.Field, e.Err.Error())
+	}
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// HTTPStatus maps upload errors to response codes.
+func HTTPStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrTooLarge), errors.Is(err, ErrImageTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, ErrNotAllowed):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, ErrScanFailed), errors.Is(err, ErrStorageFailed):
+		return http.StatusInternalServerError
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// SavedFile is a file that passed every check and was written to disk.
+type SavedFile struct {
+	// Name is the sanitized client filename, safe to display and to put in headers.
+	Name     string
+	Path     string
+	URL      string
+	MimeType string
+	Size     int64
+}
+
+type Uploader struct {
+	scanner Scanner
+}
+
+func NewUploader(cfg config.Config) *Uploader {
+	return &Uploader{scanner: NewScanner(cfg)}
+}
+
+// ParseForm limits the request body to the policy quota and parses the
+// multipart form.
+func (u *Uploader) ParseForm(w http.ResponseWriter, r *http.Request, policy Policy) error {
+	r.Body = http.MaxBytesReader(w, r.Body, policy.MaxTotalSize+formOverhead)
+	if err := r.ParseMultipartForm(memoryLimit); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			return &Error{Err: ErrTooLarge}
+		}
+		return &Error{Err: ErrInvalidBody}
+	}
+	return nil
+}
+
+// SaveFormFile parses the form and stores the single file sent in field.
+func (u *Uploader) SaveFormFile(w http.ResponseWriter, r *http.Request, field string, policy Policy, dir, ownerID string) (*SavedFile, error) {
+	if err := u.ParseForm(w, r, policy); err != nil {
+		return nil, err
+	}
+	headers := r.MultipartForm.File[field]
+	if len(headers) == 0 {
+		return nil, &Error{Field: field, Err: ErrMissing}
+	}
+	saved, err := u.SaveFiles(r.Context(), field, headers[:1], policy, dir, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	return &saved[0], nil
+}
+
+// SaveFiles validates and stores every file. It is all-or-nothing: when one
+// file is rejected the files already written are removed.
+func (u *Uploader) SaveFiles(ctx context.Context, field string, headers []*multipart.FileHeader, policy Policy, dir, ownerID string) ([]SavedFile, error) {
+	if policy.MaxFiles > 0 && len(headers) > policy.MaxFiles {
+		return nil, &Error{Field: field, Err: fmt.Errorf("%w: at most %d allowed", ErrTooMany, policy.MaxFiles)}
+	}
+
+	var total int64
+	for _, fh := range headers {
+		total += fh.Size
+	}
+	if policy.MaxTotalSize > 0 && total > policy.MaxTotalSize {
+		return nil, &Error{Field: field, Err: fmt.Errorf("%w: total limit is %d MB", ErrTooLarge, policy.MaxTotalSize>>20)}
+	}
+
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, &Error{Field: field, Err: ErrStorageFailed}
+	}
+
+	saved := make([]SavedFile, 0, len(headers))
+	for _, fh := range headers {
+		file, err := u.saveFile(ctx, fh, policy, dir, ownerID)
+		if err != nil {
+			for _, f := range saved {
+				_ = os.Remove(f.Path)
+			}
+			return nil, &Error{Field: field, Filename: SanitizeFilename(fh.Filename), Err: err}
+		}
+		saved = append(saved, *file)
+	}
+	return saved, nil
+}
+
+func (u *Uploader) saveFile(ctx context.Context, fh *multipart.FileHeader, policy Policy, dir, ownerID string) (*SavedFile, error) {
+	if policy.MaxFileSize > 0 && fh.Size > policy.MaxFileSize {
+		return nil, fmt.Errorf("%w: limit is %d MB", ErrTooLarge, policy.MaxFileSize>>20)
+	}
+
+	src, err := fh.Open()
+	if err != nil {
+		return nil, ErrInvalidBody
+	}
+	defer src.Close()
+
+	content, err := io.ReadAll(io.LimitReader(src, fh.Size+1))
+	if err != nil {
+		return nil, ErrInvalidBody
+	}
+	if len(content) == 0 {
+		return nil, ErrMissing
+	}
+
+	mimeType := DetectType(content)
+	ext, ok := policy.AllowedTypes[mimeType]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotAllowed, mimeType)
+	}
+
+	if policy.StripMetadata && isImage(mimeType) {
+		content, err = reencodeImage(content, mimeType)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := u.scanner.Scan(ctx, bytes.NewReader(content)); err != nil {
+		return nil, err
+	}
+
+	filename := fmt.Sprintf("%s_%d%s", SanitizeFilename(ownerID), time.Now().UnixNano(), ext)
+	path := filepath.Join(dir, filename)
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		return nil, ErrStorageFailed
+	}
+
+	return &SavedFile{
+		Name:     SanitizeFilename(fh.Filename),
+		Path:     path,
+		URL:      MediaURL(path),
+		MimeType: mimeType,
+		Size:     int64(len(content)),
+	}, nil
+}
+
+// DetectType sniffs the content type, recognising DICOM which
+// http.DetectContentType reports as octet-stream.
+func DetectType(content []byte) string {
+	if len(content) >= 132 && string(content[128:132]) == "DICM" {
+		return MimeDICOM
+	}
+	mimeType := http.DetectContentType(content)
+	if i := strings.IndexByte(mimeType, ';'); i >= 0 {
+		mimeType = mimeType[:i]
+	}
+	return mimeType
+}
+
+// SanitizeFilename reduces a client supplied name to a base name made of
+// letters, digits, spaces and ._- so it can be stored and sent back in a
+// Content-Disposition header.
+func SanitizeFilename(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+
+	var b strings.Builder
+	for _, r := range name {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r), r == '.', r == '-', r == '_':
+			b.WriteRune(r)
+		case unicode.IsSpace(r):
+			b.WriteRune(' ')
+		}
+	}
+
+	clean := strings.Trim(strings.TrimSpace(b.String()), ".")
+	if clean == "" {
+		return "file"
+	}
+	if runes := []rune(clean); len(runes) > maxFilenameLength {
+		ext := []rune(filepath.Ext(clean))
+		if len(ext) > 10 {
+			ext = nil
+		}
+		clean = string(runes[:maxFilenameLength-len(ext)]) + string(ext)
+	}
+	return clean
+}
+
+// MediaURL turns a local path like ./uploads/x/y.png into /uploads/x/y.png.
+func MediaURL(filePath string) string {
+	urlPath := filepath.ToSlash(filePath)
+	urlPath = strings.TrimPrefix(urlPath, ".")
+	if !strings.HasPrefix(urlPath, "/") {
+		urlPath = "/" + urlPath
+	}
+	return urlPath
+}
+
+// RemoveByURL deletes a file previously returned as SavedFile.URL.
+func RemoveByURL(fileURL string) {
+	if fileURL == "" || !strings.HasPrefix(fileURL, "/uploads/") || strings.Contains(fileURL, "..") {
+		return
+	}
+	_ = os.Remove(filepath.FromSlash("." + fileURL))
+}
@@ -1,27 +1,56 @@
 package utils
 
 import (
+	"context"
 	"fmt"
 
 	"dental_clinic/internal/config"
+	"dental_clinic/internal/queue"
 
 	"github.com/resend/resend-go/v3"
 )
 
-func SendVerificationEmail(cfx *config.Config, to, token string) error {
-	client := resend.NewClient(cfx.ResendAPIKey)
+// SendEmailJob is the queue job that sends one email.
+const SendEmailJob = "email.send"
+
+type EmailPayload struct {
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Message string `json:"message"`
+}
+
+// RegisterEmailJobs lets the worker send the emails queued with QueueEmail.
+func RegisterEmailJobs(w *queue.Worker, cfx *config.Config) {
+	queue.Handle(w, SendEmailJob, func(ctx context.Context, email EmailPayload) error {
+		return SendEmail(cfx, email.To, email.Subject, email.Message)
+	})
+}
+
+// QueueEmail hands the email to the background workers, which send it and
+// retry it when sending fails.
+func QueueEmail(ctx context.Context, q *queue.Queue, to, subject, message string) error {
+	return queueEmail(ctx, q, queue.PriorityDefault, to, subject, message)
+}
 
+func queueEmail(ctx context.Context, q *queue.Queue, priority int, to, subject, message string) error {
+	_, err := q.Enqueue(ctx, queue.Job{
+		Type:     SendEmailJob,
+		Payload:  EmailPayload{To: to, Subject: subject, Message: message},
+		Priority: priority,
+	})
+	return err
+}
+
+// QueueVerificationEmail queues the email with the link that confirms a new
+// account. It goes before other emails as the user is waiting for it.
+func QueueVerificationEmail(ctx context.Context, q *queue.Queue, cfx *config.Config, to, token string) error {
 	verifyLink := fmt.Sprintf(
 		"%s/verify-email?token=%s",
 		cfx.FrontendURL,
 		token,
 	)
 
-	params := &resend.SendEmailRequest{
-		From:    "Dental Clinic <onboarding@resend.dev>",
-		To:      []string{to},
-		Subject: "Confirm your account",
-		Html: fmt.Sprintf(`
+	return queueEmail(ctx, q, queue.PriorityHigh, to, "Confirm your account", fmt.Sprintf(`
 			<h2>Dental Clinic</h2>
 			<p>Please confirm your email address.</p>
 			<p>
@@ -29,29 +58,17 @@ func SendVerificationEmail(cfx *config.Config, to, token string) error {
 					Confirm Email
 				</a>
 			</p>
-		`, verifyLink),
-	}
-
-	_, err := client.Emails.Send(params)
-	return err
+		`, verifyLink))
 }
 
-func SendDoctorWelcomeEmail(cfx *config.Config, to, name, confirmationCode string) error {
-	client := resend.NewClient(cfx.ResendAPIKey)
-
-	params := &resend.SendEmailRequest{
-		From:    "Dental Clinic <onboarding@resend.dev>",
-		To:      []string{to},
-		Subject: "Welcome to Dental Clinic",
-		Html: fmt.Sprintf(`
+// QueueDoctorWelcomeEmail queues the email telling a doctor an administrator
+// created their account.
+func QueueDoctorWelcomeEmail(ctx context.Context, q *queue.Queue, to, name, confirmationCode string) error {
+	return queueEmail(ctx, q, queue.PriorityHigh, to, "Welcome to Dental Clinic", fmt.Sprintf(`
 			<h2>Welcome, %s!</h2>
 			<p>Your account has been created by the administrator.</p>
 			<p><strong>Confirmation code:</strong> %s</p>
-		`, name, confirmationCode),
-	}
-
-	_, err := client.Emails.Send(params)
-	return err
+		`, name, confirmationCode))
 }
 
 func SendEmail(cfx *config.Config, to, subject, message string) error {
@@ -63,3 +63,49 @@ func GetToken(r *http.Request) string {
 
 	return parts[1]
 }
+
+// AppointmentActionClaims are carried by the confirm and cancel links of
+// appointment reminders. StartTime ties the link to the time it was sent
+// for, so it stops working once the appointment is moved.
+type AppointmentActionClaims struct {
+	AppointmentID string `json:"appointment_id"`
+	Action        string `json:"action"`
+	StartTime     int64  `json:"start_time"`
+	jwt.RegisteredClaims
+}
+
+// action links are signed with their own key so they can never pass for a
+// login token
+func appointmentActionKey(secret string) []byte {
+	return []byte(secret + ":appointment-action")
+}
+
+func GenerateAppointmentActionToken(appointmentID, action string, startTime time.Time, secret string) (string, error) {
+	claims := AppointmentActionClaims{
+		AppointmentID: appointmentID,
+		Action:        action,
+		StartTime:     startTime.Unix(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(startTime),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Issuer:    "dental-clinic",
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(appointmentActionKey(secret))
+}
+
+func ParseAppointmentActionToken(tokenStr, secret string) (*AppointmentActionClaims, error) {
+	claims := &AppointmentActionClaims{}
+	token, err := jwt.ParseWithClaims(tokenStr, claims, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		return appointmentActionKey(secret), nil
+	})
+	if err != nil || !token.Valid {
+		return nil, fmt.Errorf("invalid or expired link")
+	}
+	return claims, nil
+}
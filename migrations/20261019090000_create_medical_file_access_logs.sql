@@ -0,0 +1,21 @@
+-- +goose Up
+CREATE TABLE medical_file_access_logs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+
+    medical_file_id UUID,
+    medical_record_id UUID REFERENCES medical_records(id) ON DELETE CASCADE,
+
+    user_id UUID REFERENCES users(id),
+    role VARCHAR(50),
+
+    action VARCHAR(20) NOT NULL, -- view | download
+    ip_address VARCHAR(45),
+    granted BOOLEAN DEFAULT TRUE,
+
+    accessed_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX idx_medical_file_access_logs_record ON medical_file_access_logs (medical_record_id, accessed_at DESC);
+
+-- +goose Down
+DROP TABLE IF EXISTS medical_file_access_logs;
@@ -0,0 +1,21 @@
+-- +goose Up
+ALTER TABLE medical_files
+    ADD COLUMN IF NOT EXISTS modality TEXT,
+    ADD COLUMN IF NOT EXISTS study_date DATE,
+    ADD COLUMN IF NOT EXISTS study_instance_uid TEXT,
+    ADD COLUMN IF NOT EXISTS series_instance_uid TEXT,
+    ADD COLUMN IF NOT EXISTS instance_number INT,
+    ADD COLUMN IF NOT EXISTS dicom_patient_id TEXT,
+    ADD COLUMN IF NOT EXISTS dicom_patient_name TEXT,
+    ADD COLUMN IF NOT EXISTS preview_url TEXT;
+
+-- +goose Down
+ALTER TABLE medical_files
+DROP COLUMN IF EXISTS modality,
+    DROP COLUMN IF EXISTS study_date,
+    DROP COLUMN IF EXISTS study_instance_uid,
+    DROP COLUMN IF EXISTS series_instance_uid,
+    DROP COLUMN IF EXISTS instance_number,
+    DROP COLUMN IF EXISTS dicom_patient_id,
+    DROP COLUMN IF EXISTS dicom_patient_name,
+    DROP COLUMN IF EXISTS preview_url;
@@ -0,0 +1,16 @@
+-- +goose Up
+CREATE TABLE instruction_templates (
+    id UUID PRIMARY KEY,
+
+    clinic_service_id UUID REFERENCES clinic_services(id) ON DELETE CASCADE,
+
+    title TEXT NOT NULL,
+    body TEXT NOT NULL,
+
+    created_by UUID REFERENCES users(id),
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS instruction_templates;
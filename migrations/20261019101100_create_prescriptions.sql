@@ -0,0 +1,21 @@
+-- +goose Up
+CREATE TABLE prescriptions (
+    id UUID PRIMARY KEY,
+
+    medical_record_id UUID REFERENCES medical_records(id) ON DELETE CASCADE,
+    doctor_id UUID REFERENCES doctors(id),
+    patient_id UUID,
+
+    instructions TEXT,
+    template_id UUID REFERENCES instruction_templates(id) ON DELETE SET NULL,
+
+    signature TEXT NOT NULL,
+    signed_at TIMESTAMP NOT NULL,
+
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX idx_prescriptions_medical_record ON prescriptions (medical_record_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS prescriptions;
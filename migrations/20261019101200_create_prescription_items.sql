@@ -0,0 +1,17 @@
+-- +goose Up
+CREATE TABLE prescription_items (
+    id UUID PRIMARY KEY,
+
+    prescription_id UUID REFERENCES prescriptions(id) ON DELETE CASCADE,
+
+    drug_name TEXT NOT NULL,
+    dose TEXT NOT NULL,
+    frequency TEXT NOT NULL,
+    duration TEXT NOT NULL,
+    notes TEXT,
+
+    position INT NOT NULL DEFAULT 0
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS prescription_items;
@@ -0,0 +1,25 @@
+-- +goose Up
+CREATE TABLE treatment_plans (
+    id UUID PRIMARY KEY,
+
+    medical_record_id UUID REFERENCES medical_records(id) ON DELETE CASCADE,
+    doctor_id UUID REFERENCES doctors(id),
+    patient_id UUID REFERENCES users(id),
+    clinic_id UUID REFERENCES clinics(id),
+
+    title VARCHAR NOT NULL,
+    notes TEXT,
+
+    -- proposed, accepted, declined, in_progress, completed
+    status VARCHAR NOT NULL DEFAULT 'proposed',
+    responded_at TIMESTAMP,
+
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX idx_treatment_plans_patient ON treatment_plans (patient_id);
+CREATE INDEX idx_treatment_plans_doctor ON treatment_plans (doctor_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS treatment_plans;
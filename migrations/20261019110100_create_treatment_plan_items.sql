@@ -0,0 +1,28 @@
+-- +goose Up
+CREATE TABLE treatment_plan_items (
+    id UUID PRIMARY KEY,
+    plan_id UUID NOT NULL REFERENCES treatment_plans(id) ON DELETE CASCADE,
+    position INT NOT NULL DEFAULT 0,
+
+    clinic_service_id UUID REFERENCES clinic_services(id),
+    tooth_number VARCHAR(2),
+
+    -- items sharing a group are alternatives; the patient accepts at most one
+    alternative_group VARCHAR,
+
+    -- price and duration are copied from clinic_services when the plan is made
+    price DECIMAL NOT NULL DEFAULT 0,
+    duration_minutes INT NOT NULL DEFAULT 0,
+    notes TEXT,
+
+    -- proposed, accepted, declined, scheduled, completed
+    status VARCHAR NOT NULL DEFAULT 'proposed',
+    appointment_id UUID REFERENCES appointments(id) ON DELETE SET NULL,
+    completed_at TIMESTAMP
+);
+
+CREATE INDEX idx_treatment_plan_items_plan ON treatment_plan_items (plan_id);
+CREATE INDEX idx_treatment_plan_items_appointment ON treatment_plan_items (appointment_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS treatment_plan_items;
@@ -0,0 +1,39 @@
+-- +goose Up
+-- published, flagged, hidden; hidden reviews are excluded from listings and ratings
+ALTER TABLE clinic_reviews
+    ADD COLUMN IF NOT EXISTS status VARCHAR NOT NULL DEFAULT 'published',
+    ADD COLUMN IF NOT EXISTS flag_reason TEXT,
+    ADD COLUMN IF NOT EXISTS flagged_by UUID REFERENCES users(id),
+    ADD COLUMN IF NOT EXISTS flagged_at TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS moderation_note TEXT,
+    ADD COLUMN IF NOT EXISTS moderated_by UUID REFERENCES users(id),
+    ADD COLUMN IF NOT EXISTS moderated_at TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS reply TEXT,
+    ADD COLUMN IF NOT EXISTS reply_by UUID REFERENCES users(id),
+    ADD COLUMN IF NOT EXISTS replied_at TIMESTAMP;
+
+-- the doctor rating of a visit follows the moderation of its clinic review
+ALTER TABLE doctor_ratings
+    ADD COLUMN IF NOT EXISTS status VARCHAR NOT NULL DEFAULT 'published';
+
+CREATE INDEX IF NOT EXISTS idx_clinic_reviews_clinic_status ON clinic_reviews (clinic_id, status, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_doctor_ratings_doctor_status ON doctor_ratings (doctor_id, status, created_at DESC);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_doctor_ratings_doctor_status;
+DROP INDEX IF EXISTS idx_clinic_reviews_clinic_status;
+
+ALTER TABLE doctor_ratings
+    DROP COLUMN IF EXISTS status;
+
+ALTER TABLE clinic_reviews
+    DROP COLUMN IF EXISTS status,
+    DROP COLUMN IF EXISTS flag_reason,
+    DROP COLUMN IF EXISTS flagged_by,
+    DROP COLUMN IF EXISTS flagged_at,
+    DROP COLUMN IF EXISTS moderation_note,
+    DROP COLUMN IF EXISTS moderated_by,
+    DROP COLUMN IF EXISTS moderated_at,
+    DROP COLUMN IF EXISTS reply,
+    DROP COLUMN IF EXISTS reply_by,
+    DROP COLUMN IF EXISTS replied_at;
@@ -0,0 +1,75 @@
+-- +goose Up
+-- Rating aggregates per doctor and clinic. Counters are bumped when a review
+-- is created and recomputed when one is moderated; the recent and previous
+-- 90-day windows are refreshed periodically.
+CREATE TABLE doctor_rating_stats (
+    doctor_id UUID PRIMARY KEY REFERENCES doctors(id) ON DELETE CASCADE,
+    rating_count INT NOT NULL DEFAULT 0,
+    rating_sum INT NOT NULL DEFAULT 0,
+    count_1 INT NOT NULL DEFAULT 0,
+    count_2 INT NOT NULL DEFAULT 0,
+    count_3 INT NOT NULL DEFAULT 0,
+    count_4 INT NOT NULL DEFAULT 0,
+    count_5 INT NOT NULL DEFAULT 0,
+    recent_count INT NOT NULL DEFAULT 0,
+    recent_sum INT NOT NULL DEFAULT 0,
+    previous_count INT NOT NULL DEFAULT 0,
+    previous_sum INT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE TABLE clinic_rating_stats (
+    clinic_id UUID PRIMARY KEY REFERENCES clinics(id) ON DELETE CASCADE,
+    rating_count INT NOT NULL DEFAULT 0,
+    rating_sum INT NOT NULL DEFAULT 0,
+    count_1 INT NOT NULL DEFAULT 0,
+    count_2 INT NOT NULL DEFAULT 0,
+    count_3 INT NOT NULL DEFAULT 0,
+    count_4 INT NOT NULL DEFAULT 0,
+    count_5 INT NOT NULL DEFAULT 0,
+    recent_count INT NOT NULL DEFAULT 0,
+    recent_sum INT NOT NULL DEFAULT 0,
+    previous_count INT NOT NULL DEFAULT 0,
+    previous_sum INT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+INSERT INTO doctor_rating_stats (doctor_id, rating_count, rating_sum, count_1, count_2, count_3, count_4, count_5, recent_count, recent_sum, previous_count, previous_sum)
+SELECT
+    doctor_id,
+    COUNT(*),
+    SUM(rating),
+    COUNT(*) FILTER (WHERE rating = 1),
+    COUNT(*) FILTER (WHERE rating = 2),
+    COUNT(*) FILTER (WHERE rating = 3),
+    COUNT(*) FILTER (WHERE rating = 4),
+    COUNT(*) FILTER (WHERE rating = 5),
+    COUNT(*) FILTER (WHERE created_at >= NOW() - INTERVAL '90 days'),
+    COALESCE(SUM(rating) FILTER (WHERE created_at >= NOW() - INTERVAL '90 days'), 0),
+    COUNT(*) FILTER (WHERE created_at >= NOW() - INTERVAL '180 days' AND created_at < NOW() - INTERVAL '90 days'),
+    COALESCE(SUM(rating) FILTER (WHERE created_at >= NOW() - INTERVAL '180 days' AND created_at < NOW() - INTERVAL '90 days'), 0)
+FROM doctor_ratings
+WHERE doctor_id IS NOT NULL AND status <> 'hidden'
+GROUP BY doctor_id;
+
+INSERT INTO clinic_rating_stats (clinic_id, rating_count, rating_sum, count_1, count_2, count_3, count_4, count_5, recent_count, recent_sum, previous_count, previous_sum)
+SELECT
+    clinic_id,
+    COUNT(*),
+    SUM(rating),
+    COUNT(*) FILTER (WHERE rating = 1),
+    COUNT(*) FILTER (WHERE rating = 2),
+    COUNT(*) FILTER (WHERE rating = 3),
+    COUNT(*) FILTER (WHERE rating = 4),
+    COUNT(*) FILTER (WHERE rating = 5),
+    COUNT(*) FILTER (WHERE created_at >= NOW() - INTERVAL '90 days'),
+    COALESCE(SUM(rating) FILTER (WHERE created_at >= NOW() - INTERVAL '90 days'), 0),
+    COUNT(*) FILTER (WHERE created_at >= NOW() - INTERVAL '180 days' AND created_at < NOW() - INTERVAL '90 days'),
+    COALESCE(SUM(rating) FILTER (WHERE created_at >= NOW() - INTERVAL '180 days' AND created_at < NOW() - INTERVAL '90 days'), 0)
+FROM clinic_reviews
+WHERE clinic_id IS NOT NULL AND status <> 'hidden'
+GROUP BY clinic_id;
+
+-- +goose Down
+DROP TABLE IF EXISTS clinic_rating_stats;
+DROP TABLE IF EXISTS doctor_rating_stats;
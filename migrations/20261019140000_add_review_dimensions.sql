@@ -0,0 +1,49 @@
+-- +goose Up
+ALTER TABLE doctor_ratings
+    ADD COLUMN IF NOT EXISTS comment TEXT,
+    ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP;
+
+ALTER TABLE clinic_reviews
+    ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP;
+
+-- scores for individual aspects of a visit; doctor_id is set for aspects
+-- that rate the doctor, clinic_id always
+CREATE TABLE review_dimension_scores (
+    id UUID PRIMARY KEY,
+    appointment_id UUID NOT NULL REFERENCES appointments(id) ON DELETE CASCADE,
+    dimension VARCHAR NOT NULL,
+    doctor_id UUID REFERENCES doctors(id),
+    clinic_id UUID REFERENCES clinics(id),
+    score INT NOT NULL CHECK (score >= 1 AND score <= 5),
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE (appointment_id, dimension)
+);
+
+CREATE INDEX idx_review_dimension_scores_clinic ON review_dimension_scores (clinic_id, dimension);
+
+-- previous versions of a review, one row per edit
+CREATE TABLE review_edits (
+    id UUID PRIMARY KEY,
+    appointment_id UUID NOT NULL REFERENCES appointments(id) ON DELETE CASCADE,
+    user_id UUID REFERENCES users(id),
+    doctor_rating INT,
+    doctor_comment TEXT,
+    clinic_rating INT,
+    clinic_comment TEXT,
+    dimensions JSONB NOT NULL DEFAULT '{}',
+    edited_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX idx_review_edits_appointment ON review_edits (appointment_id, edited_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS review_edits;
+DROP TABLE IF EXISTS review_dimension_scores;
+
+ALTER TABLE clinic_reviews
+    DROP COLUMN IF EXISTS updated_at;
+
+ALTER TABLE doctor_ratings
+    DROP COLUMN IF EXISTS comment,
+    DROP COLUMN IF EXISTS updated_at;
@@ -0,0 +1,47 @@
+-- +goose Up
+ALTER TABLE doctors
+    ADD COLUMN IF NOT EXISTS languages TEXT[] NOT NULL DEFAULT '{}';
+
+-- names are stored in Russian, Kazakh and English, so the 'simple'
+-- configuration is used everywhere instead of a language-specific one
+ALTER TABLE clinics
+    ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', COALESCE(name, '')), 'A') ||
+        setweight(to_tsvector('simple', COALESCE(description, '')), 'B')
+    ) STORED;
+
+ALTER TABLE doctors
+    ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', COALESCE(name, '')), 'A') ||
+        setweight(to_tsvector('simple', COALESCE(specialization, '')), 'B') ||
+        setweight(to_tsvector('simple', COALESCE(bio, '')), 'C')
+    ) STORED;
+
+ALTER TABLE services
+    ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', COALESCE(name, '') || ' ' || COALESCE(name_en, '') || ' ' || COALESCE(name_kaz, '')), 'A') ||
+        setweight(to_tsvector('simple', COALESCE(description, '')), 'B')
+    ) STORED;
+
+CREATE INDEX idx_clinics_search ON clinics USING GIN (search_vector);
+CREATE INDEX idx_doctors_search ON doctors USING GIN (search_vector);
+CREATE INDEX idx_services_search ON services USING GIN (search_vector);
+CREATE INDEX idx_doctors_languages ON doctors USING GIN (languages);
+CREATE INDEX IF NOT EXISTS idx_clinic_services_service ON clinic_services (service_id, clinic_id);
+CREATE INDEX IF NOT EXISTS idx_addresses_city ON addresses (LOWER(city));
+CREATE INDEX IF NOT EXISTS idx_doctor_time_slots_available ON doctor_time_slots (doctor_id, slot_start) WHERE status = 'available';
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_doctor_time_slots_available;
+DROP INDEX IF EXISTS idx_addresses_city;
+DROP INDEX IF EXISTS idx_clinic_services_service;
+DROP INDEX IF EXISTS idx_doctors_languages;
+DROP INDEX IF EXISTS idx_services_search;
+DROP INDEX IF EXISTS idx_doctors_search;
+DROP INDEX IF EXISTS idx_clinics_search;
+
+ALTER TABLE services DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE doctors DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE clinics DROP COLUMN IF EXISTS search_vector;
+
+ALTER TABLE doctors DROP COLUMN IF EXISTS languages;
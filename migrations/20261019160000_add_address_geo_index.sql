@@ -0,0 +1,22 @@
+-- +goose Up
+-- bounding-box prefilter for distance searches; the exact haversine distance
+-- is computed only for rows inside the box
+CREATE INDEX IF NOT EXISTS idx_addresses_location ON addresses (latitude, longitude)
+    WHERE latitude IS NOT NULL AND longitude IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_doctor_time_slots_address_available ON doctor_time_slots (clinic_address_id, slot_start)
+    WHERE status = 'available';
+
+-- the patient's location, sent by the app, so the assistant can offer the
+-- closest clinics first
+ALTER TABLE ai_booking_state
+    ADD COLUMN IF NOT EXISTS latitude DOUBLE PRECISION,
+    ADD COLUMN IF NOT EXISTS longitude DOUBLE PRECISION;
+
+-- +goose Down
+ALTER TABLE ai_booking_state
+    DROP COLUMN IF EXISTS latitude,
+    DROP COLUMN IF EXISTS longitude;
+
+DROP INDEX IF EXISTS idx_doctor_time_slots_address_available;
+DROP INDEX IF EXISTS idx_addresses_location;
@@ -0,0 +1,46 @@
+-- +goose Up
+-- what a patient wants to know about a clinic address before visiting
+CREATE TABLE clinic_address_profiles (
+    clinic_address_id UUID PRIMARY KEY REFERENCES clinic_addresses(id) ON DELETE CASCADE,
+    amenities TEXT[] NOT NULL DEFAULT '{}',
+    payment_methods TEXT[] NOT NULL DEFAULT '{}',
+    languages TEXT[] NOT NULL DEFAULT '{}',
+    parking VARCHAR NOT NULL DEFAULT 'unknown',
+    parking_notes TEXT NOT NULL DEFAULT '',
+    wheelchair_accessible BOOLEAN NOT NULL DEFAULT FALSE,
+    accessibility TEXT[] NOT NULL DEFAULT '{}',
+    accessibility_notes TEXT NOT NULL DEFAULT '',
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+-- regular weekly opening hours; day_of_week follows doctor_working_hours
+-- (0 = Sunday) and a day may have several intervals, e.g. around a lunch break
+CREATE TABLE clinic_address_opening_hours (
+    id UUID PRIMARY KEY,
+    clinic_address_id UUID NOT NULL REFERENCES clinic_addresses(id) ON DELETE CASCADE,
+    day_of_week INT NOT NULL CHECK (day_of_week >= 0 AND day_of_week <= 6),
+    opens_at TIME NOT NULL,
+    closes_at TIME NOT NULL,
+    CHECK (closes_at > opens_at)
+);
+
+CREATE INDEX idx_clinic_address_opening_hours_address ON clinic_address_opening_hours (clinic_address_id, day_of_week, opens_at);
+
+-- holidays and other dates that differ from the weekly hours
+CREATE TABLE clinic_address_special_hours (
+    id UUID PRIMARY KEY,
+    clinic_address_id UUID NOT NULL REFERENCES clinic_addresses(id) ON DELETE CASCADE,
+    date DATE NOT NULL,
+    is_closed BOOLEAN NOT NULL DEFAULT FALSE,
+    opens_at TIME,
+    closes_at TIME,
+    note TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE (clinic_address_id, date),
+    CHECK (is_closed OR (opens_at IS NOT NULL AND closes_at IS NOT NULL AND closes_at > opens_at))
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS clinic_address_special_hours;
+DROP TABLE IF EXISTS clinic_address_opening_hours;
+DROP TABLE IF EXISTS clinic_address_profiles;
@@ -0,0 +1,67 @@
+-- +goose Up
+-- every specialization of a doctor; doctors.specialization keeps the primary
+-- one (position 0) for older clients
+CREATE TABLE doctor_specializations (
+    doctor_id UUID NOT NULL REFERENCES doctors(id) ON DELETE CASCADE,
+    specialization VARCHAR NOT NULL,
+    position INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (doctor_id, specialization)
+);
+
+CREATE INDEX idx_doctor_specializations_name ON doctor_specializations (LOWER(specialization));
+
+INSERT INTO doctor_specializations (doctor_id, specialization, position)
+SELECT id, specialization, 0
+FROM doctors
+WHERE COALESCE(specialization, '') <> ''
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE doctor_education (
+    id UUID PRIMARY KEY,
+    doctor_id UUID NOT NULL REFERENCES doctors(id) ON DELETE CASCADE,
+    institution VARCHAR NOT NULL,
+    degree VARCHAR NOT NULL DEFAULT '',
+    field_of_study VARCHAR NOT NULL DEFAULT '',
+    start_year INT,
+    end_year INT,
+    created_at TIMESTAMP DEFAULT NOW(),
+    CHECK (end_year IS NULL OR start_year IS NULL OR end_year >= start_year)
+);
+
+CREATE INDEX idx_doctor_education_doctor ON doctor_education (doctor_id);
+
+-- licenses, certificates and diplomas with the uploaded document; only
+-- verified credentials are shown to patients
+CREATE TABLE doctor_credentials (
+    id UUID PRIMARY KEY,
+    doctor_id UUID NOT NULL REFERENCES doctors(id) ON DELETE CASCADE,
+    type VARCHAR NOT NULL,
+    title VARCHAR NOT NULL,
+    issuer VARCHAR NOT NULL DEFAULT '',
+    number VARCHAR NOT NULL DEFAULT '',
+    issued_at DATE,
+    expires_at DATE,
+    document_path TEXT NOT NULL DEFAULT '',
+    document_name TEXT NOT NULL DEFAULT '',
+    document_type VARCHAR NOT NULL DEFAULT '',
+    status VARCHAR NOT NULL DEFAULT 'pending',
+    reviewed_by UUID REFERENCES users(id),
+    reviewed_at TIMESTAMP,
+    review_note TEXT,
+    -- the smallest "days left" threshold admins were already warned about
+    expiry_warning_days INT,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW(),
+    CHECK (type IN ('license', 'certificate', 'diploma', 'other')),
+    CHECK (status IN ('pending', 'verified', 'rejected'))
+);
+
+CREATE INDEX idx_doctor_credentials_doctor ON doctor_credentials (doctor_id);
+CREATE INDEX idx_doctor_credentials_status ON doctor_credentials (status, created_at);
+CREATE INDEX idx_doctor_credentials_expiry ON doctor_credentials (expires_at)
+    WHERE status = 'verified' AND expires_at IS NOT NULL;
+
+-- +goose Down
+DROP TABLE IF EXISTS doctor_credentials;
+DROP TABLE IF EXISTS doctor_education;
+DROP TABLE IF EXISTS doctor_specializations;
@@ -0,0 +1,35 @@
+-- +goose Up
+CREATE TABLE doctor_clinics (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    doctor_id UUID NOT NULL REFERENCES doctors(id) ON DELETE CASCADE,
+    clinic_id UUID NOT NULL REFERENCES clinics(id) ON DELETE CASCADE,
+    status TEXT NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'suspended', 'ended')),
+    commission_percent NUMERIC(5, 2) NOT NULL DEFAULT 0 CHECK (commission_percent BETWEEN 0 AND 100),
+    commission_fixed NUMERIC(10, 2) NOT NULL DEFAULT 0 CHECK (commission_fixed >= 0),
+    started_at DATE NOT NULL DEFAULT CURRENT_DATE,
+    ended_at DATE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (doctor_id, clinic_id),
+    CHECK (ended_at IS NULL OR ended_at >= started_at)
+);
+
+CREATE INDEX idx_doctor_clinics_clinic_active ON doctor_clinics (clinic_id) WHERE status = 'active';
+
+INSERT INTO doctor_clinics (doctor_id, clinic_id)
+SELECT id, clinic_id FROM doctors WHERE clinic_id IS NOT NULL;
+
+ALTER TABLE doctors DROP COLUMN clinic_id;
+
+-- +goose Down
+ALTER TABLE doctors ADD COLUMN clinic_id UUID REFERENCES clinics(id) ON DELETE SET NULL;
+
+UPDATE doctors d
+SET clinic_id = (
+    SELECT dc.clinic_id FROM doctor_clinics dc
+    WHERE dc.doctor_id = d.id
+    ORDER BY dc.status = 'active' DESC, dc.started_at
+    LIMIT 1
+);
+
+DROP TABLE IF EXISTS doctor_clinics;
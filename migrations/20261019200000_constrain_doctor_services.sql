@@ -0,0 +1,85 @@
+-- +goose Up
+DELETE FROM doctor_services
+WHERE doctor_id IS NULL
+    OR clinic_service_id IS NULL
+    OR NOT EXISTS (SELECT 1 FROM doctors d WHERE d.id = doctor_services.doctor_id)
+    OR NOT EXISTS (SELECT 1 FROM clinic_services cs WHERE cs.id = doctor_services.clinic_service_id);
+
+DELETE FROM doctor_services a
+USING doctor_services b
+WHERE a.doctor_id = b.doctor_id
+    AND a.clinic_service_id = b.clinic_service_id
+    AND a.ctid > b.ctid;
+
+-- services of clinics the doctor does not belong to
+DELETE FROM doctor_services ds
+WHERE NOT EXISTS (
+    SELECT 1 FROM clinic_services cs
+    JOIN doctor_clinics dc ON dc.clinic_id = cs.clinic_id
+    WHERE cs.id = ds.clinic_service_id AND dc.doctor_id = ds.doctor_id AND dc.status <> 'ended'
+);
+
+UPDATE doctor_services SET id = gen_random_uuid() WHERE id IS NULL;
+
+ALTER TABLE doctor_services
+    ALTER COLUMN id SET DEFAULT gen_random_uuid(),
+    ALTER COLUMN id SET NOT NULL,
+    ALTER COLUMN doctor_id SET NOT NULL,
+    ALTER COLUMN clinic_service_id SET NOT NULL,
+    ADD PRIMARY KEY (id),
+    ADD CONSTRAINT doctor_services_doctor_fk FOREIGN KEY (doctor_id) REFERENCES doctors(id) ON DELETE CASCADE,
+    ADD CONSTRAINT doctor_services_clinic_service_fk FOREIGN KEY (clinic_service_id) REFERENCES clinic_services(id) ON DELETE CASCADE,
+    ADD CONSTRAINT doctor_services_unique UNIQUE (doctor_id, clinic_service_id),
+    ADD COLUMN price NUMERIC(10, 2) CHECK (price >= 0),
+    ADD COLUMN duration_minutes INT CHECK (duration_minutes > 0),
+    ADD COLUMN created_at TIMESTAMP NOT NULL DEFAULT NOW();
+
+-- appointments now require an assignment, so doctors without any services at
+-- one of their clinics keep performing all of that clinic's services
+INSERT INTO doctor_services (doctor_id, clinic_service_id)
+SELECT dc.doctor_id, cs.id
+FROM doctor_clinics dc
+JOIN clinic_services cs ON cs.clinic_id = dc.clinic_id
+WHERE dc.status = 'active'
+    AND NOT EXISTS (
+        SELECT 1 FROM doctor_services ds
+        JOIN clinic_services own ON own.id = ds.clinic_service_id
+        WHERE ds.doctor_id = dc.doctor_id AND own.clinic_id = dc.clinic_id
+    );
+
+-- +goose StatementBegin
+CREATE FUNCTION check_doctor_service_clinic() RETURNS trigger AS $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM clinic_services cs
+        JOIN doctor_clinics dc ON dc.clinic_id = cs.clinic_id
+        WHERE cs.id = NEW.clinic_service_id AND dc.doctor_id = NEW.doctor_id AND dc.status <> 'ended'
+    ) THEN
+        RAISE EXCEPTION 'doctor % does not belong to the clinic of service %', NEW.doctor_id, NEW.clinic_service_id
+            USING ERRCODE = 'foreign_key_violation';
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER doctor_services_clinic_check
+    BEFORE INSERT OR UPDATE OF doctor_id, clinic_service_id ON doctor_services
+    FOR EACH ROW EXECUTE FUNCTION check_doctor_service_clinic();
+
+-- +goose Down
+DROP TRIGGER IF EXISTS doctor_services_clinic_check ON doctor_services;
+DROP FUNCTION IF EXISTS check_doctor_service_clinic();
+
+ALTER TABLE doctor_services
+    DROP COLUMN IF EXISTS created_at,
+    DROP COLUMN IF EXISTS duration_minutes,
+    DROP COLUMN IF EXISTS price,
+    DROP CONSTRAINT IF EXISTS doctor_services_unique,
+    DROP CONSTRAINT IF EXISTS doctor_services_clinic_service_fk,
+    DROP CONSTRAINT IF EXISTS doctor_services_doctor_fk,
+    DROP CONSTRAINT IF EXISTS doctor_services_pkey,
+    ALTER COLUMN clinic_service_id DROP NOT NULL,
+    ALTER COLUMN doctor_id DROP NOT NULL,
+    ALTER COLUMN id DROP NOT NULL,
+    ALTER COLUMN id DROP DEFAULT;
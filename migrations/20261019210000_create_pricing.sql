@@ -0,0 +1,93 @@
+-- +goose Up
+CREATE TABLE service_price_rules (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    clinic_service_id UUID NOT NULL REFERENCES clinic_services(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    -- a rule either sets a fixed price or adjusts the list price by a percent
+    price NUMERIC(10, 2) CHECK (price >= 0),
+    percent NUMERIC(6, 2) CHECK (percent > -100),
+    valid_from DATE,
+    valid_to DATE,
+    -- 0 = Sunday; empty means every day
+    weekdays INT[] NOT NULL DEFAULT '{}',
+    starts_at TIME,
+    ends_at TIME,
+    priority INT NOT NULL DEFAULT 0,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK ((price IS NULL) <> (percent IS NULL)),
+    CHECK (valid_from IS NULL OR valid_to IS NULL OR valid_to >= valid_from),
+    CHECK ((starts_at IS NULL) = (ends_at IS NULL)),
+    CHECK (starts_at IS NULL OR ends_at > starts_at),
+    CHECK (weekdays <@ ARRAY[0, 1, 2, 3, 4, 5, 6])
+);
+
+CREATE INDEX idx_service_price_rules_clinic_service ON service_price_rules (clinic_service_id) WHERE is_active;
+
+CREATE TABLE promo_codes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    -- NULL clinic_id: valid at every clinic
+    clinic_id UUID REFERENCES clinics(id) ON DELETE CASCADE,
+    code VARCHAR(64) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    discount_type VARCHAR(16) NOT NULL CHECK (discount_type IN ('percent', 'fixed')),
+    discount_value NUMERIC(10, 2) NOT NULL CHECK (discount_value > 0),
+    -- NULL service_id: valid for every service
+    service_id UUID REFERENCES services(id) ON DELETE CASCADE,
+    min_price NUMERIC(10, 2) NOT NULL DEFAULT 0 CHECK (min_price >= 0),
+    max_uses INT CHECK (max_uses > 0),
+    max_uses_per_patient INT CHECK (max_uses_per_patient > 0),
+    starts_at TIMESTAMP,
+    expires_at TIMESTAMP,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK (discount_type <> 'percent' OR discount_value <= 100),
+    CHECK (starts_at IS NULL OR expires_at IS NULL OR expires_at > starts_at)
+);
+
+CREATE UNIQUE INDEX idx_promo_codes_code ON promo_codes (UPPER(code));
+
+CREATE TABLE clinic_pricing_settings (
+    clinic_id UUID PRIMARY KEY REFERENCES clinics(id) ON DELETE CASCADE,
+    first_visit_discount_percent NUMERIC(5, 2) NOT NULL DEFAULT 0
+        CHECK (first_visit_discount_percent BETWEEN 0 AND 100),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE appointments
+    ADD COLUMN list_price NUMERIC(10, 2),
+    ADD COLUMN discount_amount NUMERIC(10, 2) NOT NULL DEFAULT 0,
+    ADD COLUMN charged_price NUMERIC(10, 2),
+    ADD COLUMN price_rule_id UUID REFERENCES service_price_rules(id) ON DELETE SET NULL,
+    ADD COLUMN promo_code_id UUID REFERENCES promo_codes(id) ON DELETE SET NULL,
+    ADD COLUMN discount_reason VARCHAR(32) CHECK (discount_reason IN ('promo_code', 'first_visit'));
+
+CREATE INDEX idx_appointments_promo_code ON appointments (promo_code_id) WHERE promo_code_id IS NOT NULL;
+
+-- appointments booked before prices were locked are charged the list price
+UPDATE appointments a
+SET list_price = p.price,
+    charged_price = p.price
+FROM (
+    SELECT ap.id, COALESCE(ds.price, cs.price) AS price
+    FROM appointments ap
+    JOIN clinic_addresses ca ON ca.id = ap.clinic_address_id
+    JOIN clinic_services cs ON cs.clinic_id = ca.clinic_id AND cs.service_id = ap.service_id
+    LEFT JOIN doctor_services ds ON ds.clinic_service_id = cs.id AND ds.doctor_id = ap.doctor_id
+) p
+WHERE p.id = a.id;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_appointments_promo_code;
+
+ALTER TABLE appointments
+    DROP COLUMN IF EXISTS discount_reason,
+    DROP COLUMN IF EXISTS promo_code_id,
+    DROP COLUMN IF EXISTS price_rule_id,
+    DROP COLUMN IF EXISTS charged_price,
+    DROP COLUMN IF EXISTS discount_amount,
+    DROP COLUMN IF EXISTS list_price;
+
+DROP TABLE IF EXISTS clinic_pricing_settings;
+DROP TABLE IF EXISTS promo_codes;
+DROP TABLE IF EXISTS service_price_rules;
@@ -0,0 +1,66 @@
+-- +goose Up
+DELETE FROM address_inventory WHERE clinic_address_id IS NULL OR product_id IS NULL;
+
+-- one row per product and address
+UPDATE address_inventory ai
+SET quantity = totals.quantity
+FROM (
+    SELECT clinic_address_id, product_id, SUM(COALESCE(quantity, 0)) AS quantity
+    FROM address_inventory
+    GROUP BY clinic_address_id, product_id
+    HAVING COUNT(*) > 1
+) totals
+WHERE ai.clinic_address_id = totals.clinic_address_id
+    AND ai.product_id = totals.product_id;
+
+DELETE FROM address_inventory a
+USING address_inventory b
+WHERE a.clinic_address_id = b.clinic_address_id
+    AND a.product_id = b.product_id
+    AND a.ctid > b.ctid;
+
+-- consumption used to push stock below zero; what is not there is zero
+UPDATE address_inventory SET quantity = 0 WHERE quantity IS NULL OR quantity < 0;
+UPDATE address_inventory SET updated_at = NOW() WHERE updated_at IS NULL;
+
+ALTER TABLE address_inventory
+    ALTER COLUMN clinic_address_id SET NOT NULL,
+    ALTER COLUMN product_id SET NOT NULL,
+    ALTER COLUMN quantity SET DEFAULT 0,
+    ALTER COLUMN quantity SET NOT NULL,
+    ALTER COLUMN updated_at SET DEFAULT NOW(),
+    ALTER COLUMN updated_at SET NOT NULL,
+    ADD CONSTRAINT address_inventory_quantity_check CHECK (quantity >= 0),
+    ADD CONSTRAINT address_inventory_unique UNIQUE (clinic_address_id, product_id),
+    -- below min_quantity the stock is low, at or below reorder_level it is
+    -- time to order more
+    ADD COLUMN min_quantity NUMERIC NOT NULL DEFAULT 0 CHECK (min_quantity >= 0),
+    ADD COLUMN reorder_level NUMERIC NOT NULL DEFAULT 0 CHECK (reorder_level >= 0),
+    -- severity of the last alert sent, lowered again once stock recovers
+    ADD COLUMN alerted_level SMALLINT NOT NULL DEFAULT 0,
+    ADD CONSTRAINT address_inventory_levels_check CHECK (reorder_level >= min_quantity);
+
+CREATE TABLE inventory_alert_preferences (
+    clinic_id UUID NOT NULL REFERENCES clinics(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    mode VARCHAR(16) NOT NULL DEFAULT 'immediate' CHECK (mode IN ('immediate', 'daily_digest', 'off')),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (clinic_id, user_id)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS inventory_alert_preferences;
+
+ALTER TABLE address_inventory
+    DROP CONSTRAINT IF EXISTS address_inventory_levels_check,
+    DROP COLUMN IF EXISTS alerted_level,
+    DROP COLUMN IF EXISTS reorder_level,
+    DROP COLUMN IF EXISTS min_quantity,
+    DROP CONSTRAINT IF EXISTS address_inventory_unique,
+    DROP CONSTRAINT IF EXISTS address_inventory_quantity_check,
+    ALTER COLUMN updated_at DROP NOT NULL,
+    ALTER COLUMN updated_at DROP DEFAULT,
+    ALTER COLUMN quantity DROP NOT NULL,
+    ALTER COLUMN quantity DROP DEFAULT,
+    ALTER COLUMN product_id DROP NOT NULL,
+    ALTER COLUMN clinic_address_id DROP NOT NULL;
@@ -0,0 +1,44 @@
+-- +goose Up
+-- materials held for a booked appointment until it is completed (consumed)
+-- or cancelled (released)
+CREATE TABLE inventory_reservations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    appointment_id UUID NOT NULL REFERENCES appointments(id) ON DELETE CASCADE,
+    clinic_address_id UUID NOT NULL REFERENCES clinic_addresses(id) ON DELETE CASCADE,
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    quantity NUMERIC NOT NULL CHECK (quantity > 0),
+    status VARCHAR(16) NOT NULL DEFAULT 'reserved' CHECK (status IN ('reserved', 'consumed', 'released')),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (appointment_id, product_id)
+);
+
+CREATE INDEX idx_inventory_reservations_reserved
+    ON inventory_reservations (clinic_address_id, product_id)
+    WHERE status = 'reserved';
+
+-- whether a booking whose materials are not available only warns or is refused
+CREATE TABLE clinic_inventory_settings (
+    clinic_id UUID PRIMARY KEY REFERENCES clinics(id) ON DELETE CASCADE,
+    shortage_policy VARCHAR(8) NOT NULL DEFAULT 'warn' CHECK (shortage_policy IN ('warn', 'block')),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- appointments booked before reservations existed
+INSERT INTO inventory_reservations (appointment_id, clinic_address_id, product_id, quantity)
+SELECT a.id, a.clinic_address_id, sm.product_id, SUM(sm.quantity_required)
+FROM appointments a
+JOIN clinic_addresses ca ON ca.id = a.clinic_address_id
+JOIN clinic_services cs ON cs.clinic_id = ca.clinic_id
+    AND cs.service_id = a.service_id
+    AND cs.is_active = true
+JOIN service_materials sm ON sm.service_id = cs.id
+WHERE a.status = 'booked'
+    AND sm.product_id IS NOT NULL
+    AND sm.quantity_required > 0
+GROUP BY a.id, a.clinic_address_id, sm.product_id
+ON CONFLICT (appointment_id, product_id) DO NOTHING;
+
+-- +goose Down
+DROP TABLE IF EXISTS clinic_inventory_settings;
+DROP TABLE IF EXISTS inventory_reservations;
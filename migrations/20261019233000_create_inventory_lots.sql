@@ -0,0 +1,36 @@
+-- +goose Up
+-- stock of a product at an address broken down by lot; address_inventory
+-- keeps the total. Stock received without a lot number sits in the lot
+-- with an empty lot_number.
+CREATE TABLE inventory_lots (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    clinic_address_id UUID NOT NULL REFERENCES clinic_addresses(id) ON DELETE CASCADE,
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    lot_number VARCHAR(64) NOT NULL DEFAULT '',
+    expires_at DATE,
+    quantity NUMERIC NOT NULL DEFAULT 0 CHECK (quantity >= 0),
+    received_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (clinic_address_id, product_id, lot_number)
+);
+
+CREATE INDEX idx_inventory_lots_expires_at ON inventory_lots (expires_at) WHERE quantity > 0;
+
+INSERT INTO inventory_lots (clinic_address_id, product_id, quantity, received_at)
+SELECT clinic_address_id, product_id, quantity, updated_at
+FROM address_inventory
+WHERE quantity > 0;
+
+ALTER TABLE inventory_transactions
+    ADD COLUMN lot_id UUID REFERENCES inventory_lots(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_inventory_transactions_appointment_id ON inventory_transactions (appointment_id);
+
+-- the order lots are used in: first expired, first out or first in, first out
+ALTER TABLE clinic_inventory_settings
+    ADD COLUMN consumption_order VARCHAR(4) NOT NULL DEFAULT 'fefo' CHECK (consumption_order IN ('fefo', 'fifo'));
+
+-- +goose Down
+ALTER TABLE clinic_inventory_settings DROP COLUMN IF EXISTS consumption_order;
+DROP INDEX IF EXISTS idx_inventory_transactions_appointment_id;
+ALTER TABLE inventory_transactions DROP COLUMN IF EXISTS lot_id;
+DROP TABLE IF EXISTS inventory_lots;
@@ -0,0 +1,80 @@
+-- +goose Up
+CREATE TABLE suppliers (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    clinic_id UUID NOT NULL REFERENCES clinics(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    contact_name VARCHAR(255) NOT NULL DEFAULT '',
+    email VARCHAR(255) NOT NULL DEFAULT '',
+    phone VARCHAR(50) NOT NULL DEFAULT '',
+    address TEXT NOT NULL DEFAULT '',
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_suppliers_clinic_id ON suppliers (clinic_id);
+
+-- draft -> sent -> partially_received -> received; draft and sent orders can
+-- be cancelled
+CREATE TABLE purchase_orders (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    clinic_id UUID NOT NULL REFERENCES clinics(id) ON DELETE CASCADE,
+    clinic_address_id UUID NOT NULL REFERENCES clinic_addresses(id) ON DELETE CASCADE,
+    supplier_id UUID NOT NULL REFERENCES suppliers(id),
+    status VARCHAR(20) NOT NULL DEFAULT 'draft'
+        CHECK (status IN ('draft', 'sent', 'partially_received', 'received', 'cancelled')),
+    notes TEXT NOT NULL DEFAULT '',
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    sent_at TIMESTAMP,
+    received_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_purchase_orders_clinic_id ON purchase_orders (clinic_id, status);
+
+CREATE TABLE purchase_order_items (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    purchase_order_id UUID NOT NULL REFERENCES purchase_orders(id) ON DELETE CASCADE,
+    product_id UUID NOT NULL REFERENCES products(id),
+    quantity NUMERIC NOT NULL CHECK (quantity > 0),
+    unit_cost NUMERIC(12,2) NOT NULL CHECK (unit_cost >= 0),
+    received_quantity NUMERIC NOT NULL DEFAULT 0 CHECK (received_quantity >= 0),
+    UNIQUE (purchase_order_id, product_id)
+);
+
+CREATE TABLE goods_receipts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    purchase_order_id UUID NOT NULL REFERENCES purchase_orders(id) ON DELETE CASCADE,
+    received_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    notes TEXT NOT NULL DEFAULT '',
+    received_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE goods_receipt_items (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    goods_receipt_id UUID NOT NULL REFERENCES goods_receipts(id) ON DELETE CASCADE,
+    purchase_order_item_id UUID NOT NULL REFERENCES purchase_order_items(id) ON DELETE CASCADE,
+    lot_id UUID REFERENCES inventory_lots(id) ON DELETE SET NULL,
+    quantity NUMERIC NOT NULL CHECK (quantity > 0),
+    unit_cost NUMERIC(12,2) NOT NULL CHECK (unit_cost >= 0)
+);
+
+-- what stock cost, averaged over the deliveries of a lot
+ALTER TABLE inventory_lots ADD COLUMN unit_cost NUMERIC(12,4);
+
+ALTER TABLE inventory_transactions
+    ADD COLUMN unit_cost NUMERIC(12,4),
+    ADD COLUMN goods_receipt_id UUID REFERENCES goods_receipts(id) ON DELETE SET NULL;
+
+-- +goose Down
+ALTER TABLE inventory_transactions
+    DROP COLUMN IF EXISTS goods_receipt_id,
+    DROP COLUMN IF EXISTS unit_cost;
+ALTER TABLE inventory_lots DROP COLUMN IF EXISTS unit_cost;
+
+DROP TABLE IF EXISTS goods_receipt_items;
+DROP TABLE IF EXISTS goods_receipts;
+DROP TABLE IF EXISTS purchase_order_items;
+DROP TABLE IF EXISTS purchase_orders;
+DROP TABLE IF EXISTS suppliers;
@@ -0,0 +1,41 @@
+-- +goose Up
+-- in_transit -> received; a transfer still in transit can be cancelled, which
+-- returns the stock to the sending address
+CREATE TABLE stock_transfers (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    clinic_id UUID NOT NULL REFERENCES clinics(id) ON DELETE CASCADE,
+    from_address_id UUID NOT NULL REFERENCES clinic_addresses(id) ON DELETE CASCADE,
+    to_address_id UUID NOT NULL REFERENCES clinic_addresses(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'in_transit'
+        CHECK (status IN ('in_transit', 'received', 'cancelled')),
+    notes TEXT NOT NULL DEFAULT '',
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    received_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    received_at TIMESTAMP,
+    CHECK (from_address_id <> to_address_id)
+);
+
+CREATE INDEX idx_stock_transfers_clinic_id ON stock_transfers (clinic_id, status);
+
+-- one row per lot the stock was taken from, so it arrives in the same lot
+CREATE TABLE stock_transfer_items (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    stock_transfer_id UUID NOT NULL REFERENCES stock_transfers(id) ON DELETE CASCADE,
+    product_id UUID NOT NULL REFERENCES products(id),
+    lot_number VARCHAR(64) NOT NULL DEFAULT '',
+    expires_at DATE,
+    unit_cost NUMERIC(12,4),
+    quantity NUMERIC NOT NULL CHECK (quantity > 0)
+);
+
+CREATE INDEX idx_stock_transfer_items_transfer_id ON stock_transfer_items (stock_transfer_id);
+
+ALTER TABLE inventory_transactions
+    ADD COLUMN stock_transfer_id UUID REFERENCES stock_transfers(id) ON DELETE SET NULL;
+
+-- +goose Down
+ALTER TABLE inventory_transactions DROP COLUMN IF EXISTS stock_transfer_id;
+
+DROP TABLE IF EXISTS stock_transfer_items;
+DROP TABLE IF EXISTS stock_transfers;
@@ -0,0 +1,45 @@
+-- +goose Up
+-- open -> posted; an open count can be cancelled. Expected quantities are
+-- frozen when the count starts.
+CREATE TABLE stock_counts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    clinic_id UUID NOT NULL REFERENCES clinics(id) ON DELETE CASCADE,
+    clinic_address_id UUID NOT NULL REFERENCES clinic_addresses(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'open'
+        CHECK (status IN ('open', 'posted', 'cancelled')),
+    notes TEXT NOT NULL DEFAULT '',
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    posted_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    posted_at TIMESTAMP
+);
+
+CREATE INDEX idx_stock_counts_clinic_id ON stock_counts (clinic_id, status);
+
+-- one count at a time per address
+CREATE UNIQUE INDEX idx_stock_counts_open_address ON stock_counts (clinic_address_id) WHERE status = 'open';
+
+CREATE TABLE stock_count_items (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    stock_count_id UUID NOT NULL REFERENCES stock_counts(id) ON DELETE CASCADE,
+    product_id UUID NOT NULL REFERENCES products(id),
+    expected_quantity NUMERIC NOT NULL DEFAULT 0,
+    counted_quantity NUMERIC CHECK (counted_quantity >= 0),
+    reason_code VARCHAR(30)
+        CHECK (reason_code IN ('damaged', 'expired', 'lost', 'theft', 'found', 'entry_error', 'other')),
+    note TEXT NOT NULL DEFAULT '',
+    counted_at TIMESTAMP,
+    UNIQUE (stock_count_id, product_id)
+);
+
+ALTER TABLE inventory_transactions
+    ADD COLUMN reason_code VARCHAR(30),
+    ADD COLUMN stock_count_id UUID REFERENCES stock_counts(id) ON DELETE SET NULL;
+
+-- +goose Down
+ALTER TABLE inventory_transactions
+    DROP COLUMN IF EXISTS stock_count_id,
+    DROP COLUMN IF EXISTS reason_code;
+
+DROP TABLE IF EXISTS stock_count_items;
+DROP TABLE IF EXISTS stock_counts;
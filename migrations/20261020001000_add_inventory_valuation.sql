@@ -0,0 +1,24 @@
+-- +goose Up
+-- weighted_average values stock at the average cost it was received at,
+-- fifo at the cost of the lots it is still in
+ALTER TABLE clinic_inventory_settings
+    ADD COLUMN valuation_method VARCHAR(20) NOT NULL DEFAULT 'weighted_average'
+        CHECK (valuation_method IN ('weighted_average', 'fifo'));
+
+-- average cost of a product at an address over the stock received there with
+-- a cost, used to cost stock that is not in a lot with a known cost
+CREATE VIEW product_average_costs AS
+SELECT
+    clinic_address_id,
+    product_id,
+    SUM(quantity * unit_cost) / SUM(quantity) AS unit_cost
+FROM inventory_transactions
+WHERE transaction_type IN ('restocked', 'transfer_in')
+    AND unit_cost IS NOT NULL
+    AND quantity > 0
+GROUP BY clinic_address_id, product_id;
+
+-- +goose Down
+DROP VIEW IF EXISTS product_average_costs;
+
+ALTER TABLE clinic_inventory_settings DROP COLUMN IF EXISTS valuation_method;
@@ -0,0 +1,48 @@
+-- +goose Up
+-- one row per run of a background job on whichever app instance ran it
+CREATE TABLE job_runs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    job_name VARCHAR(64) NOT NULL,
+    instance VARCHAR(255) NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL DEFAULT 'running'
+        CHECK (status IN ('running', 'succeeded', 'failed')),
+    processed INT NOT NULL DEFAULT 0,
+    failed INT NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    started_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    finished_at TIMESTAMP
+);
+
+CREATE INDEX idx_job_runs_job_name ON job_runs (job_name, started_at DESC);
+
+-- items a job failed on, retried with backoff until they run out of attempts
+CREATE TABLE job_item_failures (
+    job_name VARCHAR(64) NOT NULL,
+    item_id UUID NOT NULL,
+    attempts INT NOT NULL DEFAULT 1,
+    last_error TEXT NOT NULL DEFAULT '',
+    next_attempt_at TIMESTAMP NOT NULL,
+    first_failed_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (job_name, item_id)
+);
+
+-- items a job gave up on; they are left alone until an admin requeues them
+CREATE TABLE job_dead_letters (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    job_name VARCHAR(64) NOT NULL,
+    item_id UUID NOT NULL,
+    attempts INT NOT NULL,
+    last_error TEXT NOT NULL DEFAULT '',
+    first_failed_at TIMESTAMP NOT NULL,
+    dead_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    requeued_at TIMESTAMP,
+    requeued_by UUID REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE UNIQUE INDEX idx_job_dead_letters_open ON job_dead_letters (job_name, item_id) WHERE requeued_at IS NULL;
+
+-- +goose Down
+DROP TABLE IF EXISTS job_dead_letters;
+DROP TABLE IF EXISTS job_item_failures;
+DROP TABLE IF EXISTS job_runs;
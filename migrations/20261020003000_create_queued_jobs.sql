@@ -0,0 +1,64 @@
+-- +goose Up
+-- work handed to the background workers; higher priority runs first, then
+-- the job that has been due longest
+CREATE TABLE queued_jobs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    type VARCHAR(64) NOT NULL,
+    payload JSONB NOT NULL DEFAULT '{}',
+    priority INT NOT NULL DEFAULT 0,
+    status VARCHAR(20) NOT NULL DEFAULT 'queued'
+        CHECK (status IN ('queued', 'running', 'succeeded', 'dead')),
+    attempts INT NOT NULL DEFAULT 0,
+    max_attempts INT NOT NULL DEFAULT 5 CHECK (max_attempts > 0),
+    unique_key VARCHAR(255),
+    run_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    locked_by VARCHAR(255) NOT NULL DEFAULT '',
+    locked_at TIMESTAMP,
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    finished_at TIMESTAMP
+);
+
+CREATE INDEX idx_queued_jobs_due ON queued_jobs (priority DESC, run_at) WHERE status = 'queued';
+CREATE INDEX idx_queued_jobs_type ON queued_jobs (type, created_at DESC);
+-- a job with a unique key is only queued once while it is waiting or running
+CREATE UNIQUE INDEX idx_queued_jobs_unique_key ON queued_jobs (unique_key) WHERE status IN ('queued', 'running');
+
+-- cron schedules registered by the workers; next_run_at is shared so every
+-- schedule is queued once however many instances there are
+CREATE TABLE job_schedules (
+    name VARCHAR(64) PRIMARY KEY,
+    spec VARCHAR(64) NOT NULL,
+    job_type VARCHAR(64) NOT NULL,
+    payload JSONB NOT NULL DEFAULT '{}',
+    priority INT NOT NULL DEFAULT 0,
+    next_run_at TIMESTAMP NOT NULL,
+    last_run_at TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- report exports rendered by the workers
+CREATE TABLE report_exports (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    clinic_id UUID NOT NULL REFERENCES clinics(id) ON DELETE CASCADE,
+    clinic_address_id UUID REFERENCES clinic_addresses(id) ON DELETE CASCADE,
+    report VARCHAR(32) NOT NULL,
+    format VARCHAR(8) NOT NULL CHECK (format IN ('csv', 'pdf')),
+    date_from DATE NOT NULL,
+    date_to DATE NOT NULL,
+    method VARCHAR(32) NOT NULL DEFAULT '',
+    group_by VARCHAR(32) NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL DEFAULT 'queued'
+        CHECK (status IN ('queued', 'running', 'ready', 'failed')),
+    error TEXT NOT NULL DEFAULT '',
+    content BYTEA,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    finished_at TIMESTAMP
+);
+
+CREATE INDEX idx_report_exports_clinic ON report_exports (clinic_id, created_at DESC);
+
+-- +goose Down
+DROP TABLE IF EXISTS report_exports;
+DROP TABLE IF EXISTS job_schedules;
+DROP TABLE IF EXISTS queued_jobs;
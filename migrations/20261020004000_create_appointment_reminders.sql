@@ -0,0 +1,51 @@
+-- +goose Up
+-- a clinic without settings gets the default reminder, one email a day before
+CREATE TABLE clinic_reminder_settings (
+    clinic_id UUID PRIMARY KEY REFERENCES clinics(id) ON DELETE CASCADE,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- each rule sends a reminder over its channels hours_before the appointment
+CREATE TABLE clinic_reminder_rules (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    clinic_id UUID NOT NULL REFERENCES clinics(id) ON DELETE CASCADE,
+    hours_before INT NOT NULL CHECK (hours_before BETWEEN 1 AND 336),
+    channels VARCHAR(10)[] NOT NULL,
+    UNIQUE (clinic_id, hours_before)
+);
+
+-- how a patient wants to be reminded; reminders also need users.push_consent
+CREATE TABLE user_reminder_preferences (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    email_enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    sms_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    phone VARCHAR(32) NOT NULL DEFAULT '',
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- one row per reminder due; start_time is part of the key so a rescheduled
+-- appointment is reminded again
+CREATE TABLE appointment_reminders (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    appointment_id UUID NOT NULL REFERENCES appointments(id) ON DELETE CASCADE,
+    hours_before INT NOT NULL,
+    channel VARCHAR(10) NOT NULL,
+    start_time TIMESTAMP NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'queued'
+        CHECK (status IN ('queued', 'sent', 'skipped', 'failed')),
+    recipient VARCHAR(255) NOT NULL DEFAULT '',
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    sent_at TIMESTAMP,
+    UNIQUE (appointment_id, hours_before, channel, start_time)
+);
+
+ALTER TABLE appointments ADD COLUMN confirmed_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE appointments DROP COLUMN IF EXISTS confirmed_at;
+DROP TABLE IF EXISTS appointment_reminders;
+DROP TABLE IF EXISTS user_reminder_preferences;
+DROP TABLE IF EXISTS clinic_reminder_rules;
+DROP TABLE IF EXISTS clinic_reminder_settings;